@@ -3,9 +3,12 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"time"
 
+	"github.com/graywrk/timetracker/backend/cmd/server/middleware"
 	"github.com/graywrk/timetracker/backend/internal/models"
 	"github.com/graywrk/timetracker/backend/pkg/auth"
 )
@@ -13,10 +16,26 @@ import (
 // AuthService представляет интерфейс для сервиса аутентификации
 type AuthService interface {
 	Register(ctx context.Context, email, password string) (*models.User, error)
-	Login(ctx context.Context, email, password string) (string, error)
-	LoginWithRememberMe(ctx context.Context, email, password string, rememberMe bool) (string, error)
-	ValidateToken(tokenString string) (uint, error)
-	ChangePassword(ctx context.Context, userID uint, oldPassword, newPassword string) error
+	Login(ctx context.Context, email, password string, client auth.ClientInfo) (*auth.Tokens, error)
+	LoginWithRememberMe(ctx context.Context, email, password string, rememberMe bool, client auth.ClientInfo) (*auth.Tokens, error)
+	Refresh(ctx context.Context, refreshToken string, client auth.ClientInfo) (*auth.Tokens, error)
+	Logout(ctx context.Context, sessionID uint) error
+	LogoutAll(ctx context.Context, userID uint) error
+	GetSessions(ctx context.Context, userID, currentID uint) ([]*models.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID uint) error
+	ChangePassword(ctx context.Context, userID, sessionID uint, oldPassword, newPassword string) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, email string) error
+	CompleteLogin(ctx context.Context, challenge, code string, client auth.ClientInfo) (*auth.Tokens, error)
+	GetTwoFactorStatus(ctx context.Context, userID uint) (*auth.TwoFactorStatus, error)
+	SetupTwoFactor(ctx context.Context, userID uint) (*auth.TwoFactorSetup, error)
+	ConfirmTwoFactor(ctx context.Context, userID uint, code string) ([]string, error)
+	DisableTwoFactor(ctx context.Context, userID uint, password string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID uint, password string) ([]string, error)
+	GetUser(ctx context.Context, userID uint) (*models.User, error)
+	UpdateSettings(ctx context.Context, userID uint, settings auth.UserSettings) (*models.User, error)
 }
 
 // AuthHandler обрабатывает запросы аутентификации
@@ -50,9 +69,82 @@ type ChangePasswordRequest struct {
 	NewPassword string `json:"new_password"`
 }
 
-// TokenResponse представляет ответ с токеном
+// ForgotPasswordRequest представляет запрос ссылки для сброса пароля
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest представляет запрос на сброс пароля по ссылке из письма
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// ResendVerificationRequest представляет запрос повторной отправки ссылки для подтверждения email
+type ResendVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+// TwoFactorLoginRequest представляет второй шаг входа с двухфакторной аутентификацией
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"` // код из приложения или код восстановления
+}
+
+// TwoFactorCodeRequest представляет запрос с кодом из приложения-аутентификатора
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// TwoFactorPasswordRequest представляет запрос, подтвержденный текущим паролем
+type TwoFactorPasswordRequest struct {
+	Password string `json:"password"`
+}
+
+// RefreshRequest представляет запрос на обновление токенов
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RevokeSessionRequest представляет запрос на завершение сессии
+type RevokeSessionRequest struct {
+	ID uint `json:"id"`
+}
+
+// TokenResponse представляет ответ с токенами
 type TokenResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`         // токен доступа
+	RefreshToken string `json:"refresh_token"` // токен обновления, действует один раз
+	ExpiresIn    int64  `json:"expires_in"`    // срок действия токена доступа в секундах
+}
+
+// ChallengeResponse - ответ на вход, если нужен код второго фактора
+type ChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"` // передается в /api/auth/2fa/login вместе с кодом
+	ExpiresIn         int64  `json:"expires_in"`      // сколько секунд действует challenge_token
+}
+
+// RecoveryCodesResponse - ответ с новыми кодами восстановления; коды показываются один раз
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// newTokenResponse формирует ответ с выданными токенами
+func newTokenResponse(tokens *auth.Tokens) TokenResponse {
+	return TokenResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    int64(time.Until(tokens.AccessExpiresAt).Seconds()),
+	}
+}
+
+// clientInfo возвращает сведения об устройстве, с которого выполнен запрос
+func clientInfo(r *http.Request) auth.ClientInfo {
+	return auth.ClientInfo{
+		UserAgent: r.UserAgent(),
+		IP:        middleware.ClientIP(r),
+	}
 }
 
 // Register обрабатывает запрос на регистрацию пользователя
@@ -92,7 +184,18 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 	log.Println("Пользователь успешно зарегистрирован, генерируем токен")
 	// Генерируем токен для пользователя
-	token, err := h.authService.Login(r.Context(), req.Email, req.Password)
+	tokens, err := h.authService.Login(r.Context(), req.Email, req.Password, clientInfo(r))
+	if errors.Is(err, auth.ErrEmailNotVerified) {
+		// Сервер не пускает пользователей с неподтвержденным email: вход после подтверждения
+		log.Println("Регистрация прошла успешно, требуется подтверждение email")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":               "На email отправлена ссылка для подтверждения, перейдите по ней и войдите",
+			"verification_required": true,
+		})
+		return
+	}
 	if err != nil {
 		log.Printf("Ошибка при создании токена: %v", err)
 		http.Error(w, "Ошибка при создании токена: "+err.Error(), http.StatusInternalServerError)
@@ -102,7 +205,7 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	log.Println("Регистрация прошла успешно, отправляем токен")
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(TokenResponse{Token: token})
+	json.NewEncoder(w).Encode(newTokenResponse(tokens))
 }
 
 // Login обрабатывает запрос на вход пользователя
@@ -122,28 +225,205 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	log.Printf("Получен запрос на вход с email: %s, Запомнить меня: %v", req.Email, req.RememberMe)
 
 	// Аутентифицируем пользователя с учетом опции "Запомнить меня"
-	var token string
+	var tokens *auth.Tokens
 	var err error
 
 	if req.RememberMe {
-		token, err = h.authService.LoginWithRememberMe(r.Context(), req.Email, req.Password, true)
+		tokens, err = h.authService.LoginWithRememberMe(r.Context(), req.Email, req.Password, true, clientInfo(r))
 	} else {
-		token, err = h.authService.Login(r.Context(), req.Email, req.Password)
+		tokens, err = h.authService.Login(r.Context(), req.Email, req.Password, clientInfo(r))
 	}
 
 	if err != nil {
+		var locked *auth.LockedError
 		if err == auth.ErrInvalidCredentials {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
+		} else if errors.As(err, &locked) {
+			middleware.TooManyRequests(w, err.Error(), locked.RetryAfter)
+		} else if errors.Is(err, auth.ErrEmailNotVerified) {
+			http.Error(w, "Подтвердите email по ссылке из письма", http.StatusForbidden)
 		} else {
 			http.Error(w, "Ошибка при входе: "+err.Error(), http.StatusInternalServerError)
 		}
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+
+	if tokens.Challenge != "" {
+		log.Printf("Пароль верен для email %s, требуется код второго фактора", req.Email)
+		json.NewEncoder(w).Encode(ChallengeResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    tokens.Challenge,
+			ExpiresIn:         int64(time.Until(tokens.ChallengeExpiresAt).Seconds()),
+		})
+		return
+	}
+
 	log.Printf("Вход успешен для пользователя с email: %s", req.Email)
+	json.NewEncoder(w).Encode(newTokenResponse(tokens))
+}
+
+// LoginTwoFactor завершает вход кодом второго фактора
+func (h *AuthHandler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req TwoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Ошибка при разборе JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.ChallengeToken == "" || req.Code == "" {
+		http.Error(w, "Токен входа и код обязательны", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.authService.CompleteLogin(r.Context(), req.ChallengeToken, req.Code, clientInfo(r))
+	if err != nil {
+		var locked *auth.LockedError
+		if errors.Is(err, auth.ErrInvalidChallenge) || errors.Is(err, auth.ErrInvalidTwoFactorCode) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		} else if errors.As(err, &locked) {
+			middleware.TooManyRequests(w, err.Error(), locked.RetryAfter)
+		} else {
+			log.Printf("Ошибка при проверке второго фактора: %v", err)
+			http.Error(w, "Ошибка при входе: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newTokenResponse(tokens))
+}
+
+// Refresh обменивает токен обновления на новую пару токенов
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Ошибка при разборе JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.RefreshToken == "" {
+		http.Error(w, "Токен обновления обязателен", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.authService.Refresh(r.Context(), req.RefreshToken, clientInfo(r))
+	if err != nil {
+		if err == auth.ErrInvalidRefreshToken {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		} else {
+			log.Printf("Ошибка при обновлении токенов: %v", err)
+			http.Error(w, "Ошибка при обновлении токенов: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newTokenResponse(tokens))
+}
+
+// Logout завершает текущую сессию
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Необходима аутентификация", http.StatusUnauthorized)
+		return
+	}
+	sessionID, _ := r.Context().Value("session_id").(uint)
+
+	if err := h.authService.Logout(r.Context(), sessionID); err != nil {
+		log.Printf("Ошибка при завершении сессии %d пользователя %d: %v", sessionID, userID, err)
+		http.Error(w, "Ошибка при выходе: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Пользователь с ID %d вышел, сессия %d завершена", userID, sessionID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Сессия завершена",
+	})
+}
+
+// LogoutAll завершает все сессии пользователя, включая текущую
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Необходима аутентификация", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.authService.LogoutAll(r.Context(), userID); err != nil {
+		log.Printf("Ошибка при завершении сессий пользователя %d: %v", userID, err)
+		http.Error(w, "Ошибка при выходе: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Пользователь с ID %d вышел на всех устройствах", userID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Все сессии завершены",
+	})
+}
+
+// GetSessions возвращает действующие сессии пользователя
+func (h *AuthHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Необходима аутентификация", http.StatusUnauthorized)
+		return
+	}
+	sessionID, _ := r.Context().Value("session_id").(uint)
+
+	sessions, err := h.authService.GetSessions(r.Context(), userID, sessionID)
+	if err != nil {
+		log.Printf("Ошибка при получении сессий пользователя %d: %v", userID, err)
+		http.Error(w, "Не удалось получить сессии", http.StatusInternalServerError)
+		return
+	}
+	if sessions == nil {
+		sessions = []*models.Session{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// RevokeSession завершает сессию пользователя на другом устройстве
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Необходима аутентификация", http.StatusUnauthorized)
+		return
+	}
+
+	var req RevokeSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Неверный формат запроса", http.StatusBadRequest)
+		return
+	}
+
+	if req.ID == 0 {
+		http.Error(w, "ID сессии не указан", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.RevokeSession(r.Context(), userID, req.ID); err != nil {
+		if errors.Is(err, auth.ErrSessionNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Printf("Ошибка при завершении сессии %d пользователя %d: %v", req.ID, userID, err)
+		http.Error(w, "Не удалось завершить сессию", http.StatusInternalServerError)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(TokenResponse{Token: token})
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Сессия завершена",
+	})
 }
 
 // ChangePassword обрабатывает запрос на изменение пароля
@@ -169,8 +449,9 @@ func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("Получен запрос на смену пароля для пользователя с ID: %d", userID)
 
-	// Меняем пароль
-	err := h.authService.ChangePassword(r.Context(), userID, req.OldPassword, req.NewPassword)
+	// Меняем пароль; остальные сессии пользователя завершаются
+	sessionID, _ := r.Context().Value("session_id").(uint)
+	err := h.authService.ChangePassword(r.Context(), userID, sessionID, req.OldPassword, req.NewPassword)
 	if err != nil {
 		if err == auth.ErrInvalidCredentials {
 			http.Error(w, "Неверный текущий пароль", http.StatusUnauthorized)
@@ -185,3 +466,303 @@ func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(`{"message": "Пароль успешно изменен"}`))
 }
+
+// ForgotPassword отправляет ссылку для сброса пароля. Ответ одинаков для зарегистрированных
+// и незарегистрированных email.
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Ошибка при разборе JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Email == "" {
+		http.Error(w, "Email обязателен", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.ForgotPassword(r.Context(), req.Email); err != nil {
+		log.Printf("Ошибка при запросе сброса пароля: %v", err)
+		http.Error(w, "Не удалось отправить ссылку для сброса пароля", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Если email зарегистрирован, на него отправлена ссылка для сброса пароля",
+	})
+}
+
+// ResetPassword задает новый пароль по токену из письма
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Ошибка при разборе JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Token == "" || req.NewPassword == "" {
+		http.Error(w, "Токен и новый пароль обязательны", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.ResetPassword(r.Context(), req.Token, req.NewPassword); err != nil {
+		if errors.Is(err, auth.ErrInvalidResetToken) || errors.Is(err, auth.ErrInvalidPassword) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			log.Printf("Ошибка при сбросе пароля: %v", err)
+			http.Error(w, "Ошибка при сбросе пароля: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Пароль изменен, войдите с новым паролем",
+	})
+}
+
+// VerifyEmail подтверждает email по токену из ссылки в письме (параметр token)
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, "Токен обязателен", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.VerifyEmail(r.Context(), token); err != nil {
+		if errors.Is(err, auth.ErrInvalidVerificationToken) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			log.Printf("Ошибка при подтверждении email: %v", err)
+			http.Error(w, "Ошибка при подтверждении email: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Email подтвержден",
+	})
+}
+
+// ResendVerification повторно отправляет ссылку для подтверждения email. Ответ одинаков
+// для зарегистрированных, незарегистрированных и уже подтвержденных email.
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req ResendVerificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Ошибка при разборе JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Email == "" {
+		http.Error(w, "Email обязателен", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.ResendVerification(r.Context(), req.Email); err != nil {
+		log.Printf("Ошибка при повторной отправке подтверждения email: %v", err)
+		http.Error(w, "Не удалось отправить ссылку для подтверждения email", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Если email зарегистрирован и не подтвержден, на него отправлена ссылка для подтверждения",
+	})
+}
+
+// writeTwoFactorError отвечает на ошибку управления двухфакторной аутентификацией.
+// Неверный пароль - 403, а не 401, чтобы клиент не принял его за истекший токен доступа.
+func writeTwoFactorError(w http.ResponseWriter, userID uint, err error) {
+	var locked *auth.LockedError
+	switch {
+	case errors.As(err, &locked):
+		middleware.TooManyRequests(w, err.Error(), locked.RetryAfter)
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		http.Error(w, "Неверный текущий пароль", http.StatusForbidden)
+	case errors.Is(err, auth.ErrInvalidTwoFactorCode):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, auth.ErrTwoFactorEnabled), errors.Is(err, auth.ErrTwoFactorNotEnabled),
+		errors.Is(err, auth.ErrTwoFactorSetupNotStarted):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("Ошибка двухфакторной аутентификации пользователя %d: %v", userID, err)
+		http.Error(w, "Ошибка двухфакторной аутентификации: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// GetTwoFactorStatus возвращает состояние двухфакторной аутентификации
+func (h *AuthHandler) GetTwoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Необходима аутентификация", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.authService.GetTwoFactorStatus(r.Context(), userID)
+	if err != nil {
+		writeTwoFactorError(w, userID, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// SetupTwoFactor начинает настройку двухфакторной аутентификации
+func (h *AuthHandler) SetupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Необходима аутентификация", http.StatusUnauthorized)
+		return
+	}
+
+	setup, err := h.authService.SetupTwoFactor(r.Context(), userID)
+	if err != nil {
+		writeTwoFactorError(w, userID, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(setup)
+}
+
+// ConfirmTwoFactor включает двухфакторную аутентификацию по коду из приложения
+func (h *AuthHandler) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Необходима аутентификация", http.StatusUnauthorized)
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Ошибка при разборе JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Code == "" {
+		http.Error(w, "Код обязателен", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.authService.ConfirmTwoFactor(r.Context(), userID, req.Code)
+	if err != nil {
+		writeTwoFactorError(w, userID, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTwoFactor отключает двухфакторную аутентификацию
+func (h *AuthHandler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Необходима аутентификация", http.StatusUnauthorized)
+		return
+	}
+
+	var req TwoFactorPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Ошибка при разборе JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Password == "" {
+		http.Error(w, "Текущий пароль обязателен", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.DisableTwoFactor(r.Context(), userID, req.Password); err != nil {
+		writeTwoFactorError(w, userID, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Двухфакторная аутентификация отключена",
+	})
+}
+
+// RegenerateRecoveryCodes выдает новые коды восстановления вместо прежних
+func (h *AuthHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Необходима аутентификация", http.StatusUnauthorized)
+		return
+	}
+
+	var req TwoFactorPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Ошибка при разборе JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Password == "" {
+		http.Error(w, "Текущий пароль обязателен", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(r.Context(), userID, req.Password)
+	if err != nil {
+		writeTwoFactorError(w, userID, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// GetProfile возвращает профиль текущего пользователя
+func (h *AuthHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста запроса
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Необходима аутентификация", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.authService.GetUser(r.Context(), userID)
+	if err != nil {
+		log.Printf("Ошибка при получении профиля пользователя %d: %v", userID, err)
+		http.Error(w, "Пользователь не найден", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// UpdateSettings обрабатывает запрос на изменение настроек профиля
+func (h *AuthHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста запроса
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Необходима аутентификация", http.StatusUnauthorized)
+		return
+	}
+
+	var req auth.UserSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Ошибка при разборе JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.authService.UpdateSettings(r.Context(), userID, req)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidSettings) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Ошибка при сохранении настроек: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	log.Printf("Настройки пользователя с ID %d обновлены", userID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
@@ -7,7 +7,9 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/graywrk/timetracker/backend/internal/models"
 	"github.com/graywrk/timetracker/backend/pkg/auth"
@@ -16,9 +18,26 @@ import (
 // MockAuthService представляет мок сервиса аутентификации
 type MockAuthService struct {
 	registerFunc            func(ctx context.Context, email, password string) (*models.User, error)
-	loginFunc               func(ctx context.Context, email, password string) (string, error)
-	changePasswordFunc      func(ctx context.Context, userID uint, oldPassword, newPassword string) error
-	loginWithRememberMeFunc func(ctx context.Context, email, password string, rememberMe bool) (string, error)
+	loginFunc               func(ctx context.Context, email, password string) (*auth.Tokens, error)
+	changePasswordFunc      func(ctx context.Context, userID, sessionID uint, oldPassword, newPassword string) error
+	loginWithRememberMeFunc func(ctx context.Context, email, password string, rememberMe bool) (*auth.Tokens, error)
+	refreshFunc             func(ctx context.Context, refreshToken string, client auth.ClientInfo) (*auth.Tokens, error)
+	logoutFunc              func(ctx context.Context, sessionID uint) error
+	logoutAllFunc           func(ctx context.Context, userID uint) error
+	getSessionsFunc         func(ctx context.Context, userID, currentID uint) ([]*models.Session, error)
+	revokeSessionFunc       func(ctx context.Context, userID, sessionID uint) error
+	forgotPasswordFunc      func(ctx context.Context, email string) error
+	resetPasswordFunc       func(ctx context.Context, token, newPassword string) error
+	verifyEmailFunc         func(ctx context.Context, token string) error
+	resendVerificationFunc  func(ctx context.Context, email string) error
+	completeLoginFunc       func(ctx context.Context, challenge, code string) (*auth.Tokens, error)
+	twoFactorStatusFunc     func(ctx context.Context, userID uint) (*auth.TwoFactorStatus, error)
+	setupTwoFactorFunc      func(ctx context.Context, userID uint) (*auth.TwoFactorSetup, error)
+	confirmTwoFactorFunc    func(ctx context.Context, userID uint, code string) ([]string, error)
+	disableTwoFactorFunc    func(ctx context.Context, userID uint, password string) error
+	regenerateCodesFunc     func(ctx context.Context, userID uint, password string) ([]string, error)
+	getUserFunc             func(ctx context.Context, userID uint) (*models.User, error)
+	updateSettingsFunc      func(ctx context.Context, userID uint, settings auth.UserSettings) (*models.User, error)
 }
 
 // Register мок метода
@@ -30,32 +49,172 @@ func (m *MockAuthService) Register(ctx context.Context, email, password string)
 }
 
 // Login мок метода
-func (m *MockAuthService) Login(ctx context.Context, email, password string) (string, error) {
+func (m *MockAuthService) Login(ctx context.Context, email, password string, client auth.ClientInfo) (*auth.Tokens, error) {
 	if m.loginFunc != nil {
 		return m.loginFunc(ctx, email, password)
 	}
-	return "", errors.New("не реализовано")
-}
-
-// ValidateToken мок метода
-func (m *MockAuthService) ValidateToken(tokenString string) (uint, error) {
-	return 1, nil // Всегда возвращаем успех с ID=1
+	return nil, errors.New("не реализовано")
 }
 
 // ChangePassword мок метода
-func (m *MockAuthService) ChangePassword(ctx context.Context, userID uint, oldPassword, newPassword string) error {
+func (m *MockAuthService) ChangePassword(ctx context.Context, userID, sessionID uint, oldPassword, newPassword string) error {
 	if m.changePasswordFunc != nil {
-		return m.changePasswordFunc(ctx, userID, oldPassword, newPassword)
+		return m.changePasswordFunc(ctx, userID, sessionID, oldPassword, newPassword)
 	}
 	return errors.New("не реализовано")
 }
 
 // LoginWithRememberMe мок метода
-func (m *MockAuthService) LoginWithRememberMe(ctx context.Context, email, password string, rememberMe bool) (string, error) {
+func (m *MockAuthService) LoginWithRememberMe(ctx context.Context, email, password string, rememberMe bool, client auth.ClientInfo) (*auth.Tokens, error) {
 	if m.loginWithRememberMeFunc != nil {
 		return m.loginWithRememberMeFunc(ctx, email, password, rememberMe)
 	}
-	return "", errors.New("не реализовано")
+	return nil, errors.New("не реализовано")
+}
+
+// Refresh мок метода
+func (m *MockAuthService) Refresh(ctx context.Context, refreshToken string, client auth.ClientInfo) (*auth.Tokens, error) {
+	if m.refreshFunc != nil {
+		return m.refreshFunc(ctx, refreshToken, client)
+	}
+	return nil, errors.New("не реализовано")
+}
+
+// Logout мок метода
+func (m *MockAuthService) Logout(ctx context.Context, sessionID uint) error {
+	if m.logoutFunc != nil {
+		return m.logoutFunc(ctx, sessionID)
+	}
+	return errors.New("не реализовано")
+}
+
+// LogoutAll мок метода
+func (m *MockAuthService) LogoutAll(ctx context.Context, userID uint) error {
+	if m.logoutAllFunc != nil {
+		return m.logoutAllFunc(ctx, userID)
+	}
+	return errors.New("не реализовано")
+}
+
+// GetSessions мок метода
+func (m *MockAuthService) GetSessions(ctx context.Context, userID, currentID uint) ([]*models.Session, error) {
+	if m.getSessionsFunc != nil {
+		return m.getSessionsFunc(ctx, userID, currentID)
+	}
+	return nil, errors.New("не реализовано")
+}
+
+// RevokeSession мок метода
+func (m *MockAuthService) RevokeSession(ctx context.Context, userID, sessionID uint) error {
+	if m.revokeSessionFunc != nil {
+		return m.revokeSessionFunc(ctx, userID, sessionID)
+	}
+	return errors.New("не реализовано")
+}
+
+// ForgotPassword мок метода
+func (m *MockAuthService) ForgotPassword(ctx context.Context, email string) error {
+	if m.forgotPasswordFunc != nil {
+		return m.forgotPasswordFunc(ctx, email)
+	}
+	return errors.New("не реализовано")
+}
+
+// ResetPassword мок метода
+func (m *MockAuthService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if m.resetPasswordFunc != nil {
+		return m.resetPasswordFunc(ctx, token, newPassword)
+	}
+	return errors.New("не реализовано")
+}
+
+// VerifyEmail мок метода
+func (m *MockAuthService) VerifyEmail(ctx context.Context, token string) error {
+	if m.verifyEmailFunc != nil {
+		return m.verifyEmailFunc(ctx, token)
+	}
+	return errors.New("не реализовано")
+}
+
+// ResendVerification мок метода
+func (m *MockAuthService) ResendVerification(ctx context.Context, email string) error {
+	if m.resendVerificationFunc != nil {
+		return m.resendVerificationFunc(ctx, email)
+	}
+	return errors.New("не реализовано")
+}
+
+// CompleteLogin мок метода
+func (m *MockAuthService) CompleteLogin(ctx context.Context, challenge, code string, client auth.ClientInfo) (*auth.Tokens, error) {
+	if m.completeLoginFunc != nil {
+		return m.completeLoginFunc(ctx, challenge, code)
+	}
+	return nil, errors.New("не реализовано")
+}
+
+// GetTwoFactorStatus мок метода
+func (m *MockAuthService) GetTwoFactorStatus(ctx context.Context, userID uint) (*auth.TwoFactorStatus, error) {
+	if m.twoFactorStatusFunc != nil {
+		return m.twoFactorStatusFunc(ctx, userID)
+	}
+	return nil, errors.New("не реализовано")
+}
+
+// SetupTwoFactor мок метода
+func (m *MockAuthService) SetupTwoFactor(ctx context.Context, userID uint) (*auth.TwoFactorSetup, error) {
+	if m.setupTwoFactorFunc != nil {
+		return m.setupTwoFactorFunc(ctx, userID)
+	}
+	return nil, errors.New("не реализовано")
+}
+
+// ConfirmTwoFactor мок метода
+func (m *MockAuthService) ConfirmTwoFactor(ctx context.Context, userID uint, code string) ([]string, error) {
+	if m.confirmTwoFactorFunc != nil {
+		return m.confirmTwoFactorFunc(ctx, userID, code)
+	}
+	return nil, errors.New("не реализовано")
+}
+
+// DisableTwoFactor мок метода
+func (m *MockAuthService) DisableTwoFactor(ctx context.Context, userID uint, password string) error {
+	if m.disableTwoFactorFunc != nil {
+		return m.disableTwoFactorFunc(ctx, userID, password)
+	}
+	return errors.New("не реализовано")
+}
+
+// RegenerateRecoveryCodes мок метода
+func (m *MockAuthService) RegenerateRecoveryCodes(ctx context.Context, userID uint, password string) ([]string, error) {
+	if m.regenerateCodesFunc != nil {
+		return m.regenerateCodesFunc(ctx, userID, password)
+	}
+	return nil, errors.New("не реализовано")
+}
+
+// GetUser мок метода
+func (m *MockAuthService) GetUser(ctx context.Context, userID uint) (*models.User, error) {
+	if m.getUserFunc != nil {
+		return m.getUserFunc(ctx, userID)
+	}
+	return nil, errors.New("не реализовано")
+}
+
+// UpdateSettings мок метода
+func (m *MockAuthService) UpdateSettings(ctx context.Context, userID uint, settings auth.UserSettings) (*models.User, error) {
+	if m.updateSettingsFunc != nil {
+		return m.updateSettingsFunc(ctx, userID, settings)
+	}
+	return nil, errors.New("не реализовано")
+}
+
+// testTokens возвращает токены, которые выдает мок сервиса при входе
+func testTokens() *auth.Tokens {
+	return &auth.Tokens{
+		AccessToken:     "test-token",
+		RefreshToken:    "test-refresh-token",
+		AccessExpiresAt: time.Now().Add(15 * time.Minute),
+	}
 }
 
 // TestRegister тестирует обработчик Register
@@ -64,7 +223,7 @@ func TestRegister(t *testing.T) {
 		name           string
 		requestBody    RegisterRequest
 		mockRegister   func(ctx context.Context, email, password string) (*models.User, error)
-		mockLogin      func(ctx context.Context, email, password string) (string, error)
+		mockLogin      func(ctx context.Context, email, password string) (*auth.Tokens, error)
 		expectedStatus int
 		expectedToken  bool // true если ожидаем токен в ответе
 	}{
@@ -80,8 +239,8 @@ func TestRegister(t *testing.T) {
 					Email: email,
 				}, nil
 			},
-			mockLogin: func(ctx context.Context, email, password string) (string, error) {
-				return "test-token", nil
+			mockLogin: func(ctx context.Context, email, password string) (*auth.Tokens, error) {
+				return testTokens(), nil
 			},
 			expectedStatus: http.StatusCreated,
 			expectedToken:  true,
@@ -99,6 +258,21 @@ func TestRegister(t *testing.T) {
 			expectedStatus: http.StatusConflict,
 			expectedToken:  false,
 		},
+		{
+			name: "Вход до подтверждения email запрещен",
+			requestBody: RegisterRequest{
+				Email:    "test@example.com",
+				Password: "password123",
+			},
+			mockRegister: func(ctx context.Context, email, password string) (*models.User, error) {
+				return &models.User{ID: 1, Email: email}, nil
+			},
+			mockLogin: func(ctx context.Context, email, password string) (*auth.Tokens, error) {
+				return nil, auth.ErrEmailNotVerified
+			},
+			expectedStatus: http.StatusCreated,
+			expectedToken:  false,
+		},
 		{
 			name: "Внутренняя ошибка сервера",
 			requestBody: RegisterRequest{
@@ -163,7 +337,7 @@ func TestLogin(t *testing.T) {
 	tests := []struct {
 		name           string
 		requestBody    LoginRequest
-		mockLogin      func(ctx context.Context, email, password string) (string, error)
+		mockLogin      func(ctx context.Context, email, password string) (*auth.Tokens, error)
 		expectedStatus int
 		expectedToken  bool // true если ожидаем токен в ответе
 	}{
@@ -173,8 +347,8 @@ func TestLogin(t *testing.T) {
 				Email:    "test@example.com",
 				Password: "password123",
 			},
-			mockLogin: func(ctx context.Context, email, password string) (string, error) {
-				return "test-token", nil
+			mockLogin: func(ctx context.Context, email, password string) (*auth.Tokens, error) {
+				return testTokens(), nil
 			},
 			expectedStatus: http.StatusOK,
 			expectedToken:  true,
@@ -185,20 +359,32 @@ func TestLogin(t *testing.T) {
 				Email:    "test@example.com",
 				Password: "wrongpassword",
 			},
-			mockLogin: func(ctx context.Context, email, password string) (string, error) {
-				return "", auth.ErrInvalidCredentials
+			mockLogin: func(ctx context.Context, email, password string) (*auth.Tokens, error) {
+				return nil, auth.ErrInvalidCredentials
 			},
 			expectedStatus: http.StatusUnauthorized,
 			expectedToken:  false,
 		},
+		{
+			name: "Email не подтвержден",
+			requestBody: LoginRequest{
+				Email:    "test@example.com",
+				Password: "password123",
+			},
+			mockLogin: func(ctx context.Context, email, password string) (*auth.Tokens, error) {
+				return nil, auth.ErrEmailNotVerified
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedToken:  false,
+		},
 		{
 			name: "Внутренняя ошибка сервера",
 			requestBody: LoginRequest{
 				Email:    "test@example.com",
 				Password: "password123",
 			},
-			mockLogin: func(ctx context.Context, email, password string) (string, error) {
-				return "", errors.New("ошибка базы данных")
+			mockLogin: func(ctx context.Context, email, password string) (*auth.Tokens, error) {
+				return nil, errors.New("ошибка базы данных")
 			},
 			expectedStatus: http.StatusInternalServerError,
 			expectedToken:  false,
@@ -253,7 +439,7 @@ func TestChangePassword(t *testing.T) {
 	tests := []struct {
 		name               string
 		requestBody        interface{} // Меняем тип на interface{} для поддержки невалидных запросов
-		mockChangePassword func(ctx context.Context, userID uint, oldPassword, newPassword string) error
+		mockChangePassword func(ctx context.Context, userID, sessionID uint, oldPassword, newPassword string) error
 		expectedStatus     int
 	}{
 		{
@@ -262,7 +448,7 @@ func TestChangePassword(t *testing.T) {
 				OldPassword: "password123",
 				NewPassword: "newpassword123",
 			},
-			mockChangePassword: func(ctx context.Context, userID uint, oldPassword, newPassword string) error {
+			mockChangePassword: func(ctx context.Context, userID, sessionID uint, oldPassword, newPassword string) error {
 				return nil
 			},
 			expectedStatus: http.StatusOK,
@@ -273,7 +459,7 @@ func TestChangePassword(t *testing.T) {
 				OldPassword: "wrongpassword",
 				NewPassword: "newpassword123",
 			},
-			mockChangePassword: func(ctx context.Context, userID uint, oldPassword, newPassword string) error {
+			mockChangePassword: func(ctx context.Context, userID, sessionID uint, oldPassword, newPassword string) error {
 				return auth.ErrInvalidCredentials
 			},
 			expectedStatus: http.StatusUnauthorized,
@@ -284,7 +470,7 @@ func TestChangePassword(t *testing.T) {
 				OldPassword: "password123",
 				NewPassword: "newpassword123",
 			},
-			mockChangePassword: func(ctx context.Context, userID uint, oldPassword, newPassword string) error {
+			mockChangePassword: func(ctx context.Context, userID, sessionID uint, oldPassword, newPassword string) error {
 				return errors.New("ошибка базы данных")
 			},
 			expectedStatus: http.StatusInternalServerError,
@@ -371,3 +557,513 @@ func TestChangePasswordNoUserID(t *testing.T) {
 			status, http.StatusUnauthorized)
 	}
 }
+
+// TestRefresh тестирует обработчик Refresh
+func TestRefresh(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestBody    RefreshRequest
+		mockRefresh    func(ctx context.Context, refreshToken string, client auth.ClientInfo) (*auth.Tokens, error)
+		expectedStatus int
+	}{
+		{
+			name:        "Успешное обновление",
+			requestBody: RefreshRequest{RefreshToken: "old-refresh-token"},
+			mockRefresh: func(ctx context.Context, refreshToken string, client auth.ClientInfo) (*auth.Tokens, error) {
+				if refreshToken != "old-refresh-token" || client.UserAgent != "test-agent" || client.IP != "203.0.113.5" {
+					return nil, errors.New("неверные аргументы")
+				}
+				return testTokens(), nil
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "Недействительный токен обновления",
+			requestBody: RefreshRequest{RefreshToken: "used-refresh-token"},
+			mockRefresh: func(ctx context.Context, refreshToken string, client auth.ClientInfo) (*auth.Tokens, error) {
+				return nil, auth.ErrInvalidRefreshToken
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Пустой токен",
+			requestBody:    RefreshRequest{},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAuthHandler(&MockAuthService{refreshFunc: tt.mockRefresh})
+
+			reqBody, _ := json.Marshal(tt.requestBody)
+			req, _ := http.NewRequest("POST", "/refresh", bytes.NewBuffer(reqBody))
+			req.Header.Set("User-Agent", "test-agent")
+			req.RemoteAddr = "203.0.113.5:54321"
+			rr := httptest.NewRecorder()
+
+			handler.Refresh(rr, req)
+
+			if status := rr.Code; status != tt.expectedStatus {
+				t.Fatalf("Обработчик вернул неверный статус: получили %v, хотели %v (%s)",
+					status, tt.expectedStatus, rr.Body.String())
+			}
+
+			if tt.expectedStatus == http.StatusOK {
+				var response TokenResponse
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Не удалось разобрать JSON ответа: %v", err)
+				}
+				if response.Token != "test-token" || response.RefreshToken != "test-refresh-token" || response.ExpiresIn <= 0 {
+					t.Errorf("Неверный ответ: %+v", response)
+				}
+			}
+		})
+	}
+}
+
+// TestSessions тестирует обработчики выхода и управления сессиями
+func TestSessions(t *testing.T) {
+	var loggedOut, revoked uint
+	var loggedOutAll bool
+	mockService := &MockAuthService{
+		logoutFunc: func(ctx context.Context, sessionID uint) error {
+			loggedOut = sessionID
+			return nil
+		},
+		logoutAllFunc: func(ctx context.Context, userID uint) error {
+			loggedOutAll = userID == 1
+			return nil
+		},
+		getSessionsFunc: func(ctx context.Context, userID, currentID uint) ([]*models.Session, error) {
+			return []*models.Session{
+				{ID: 7, UserID: userID, UserAgent: "Firefox", Current: currentID == 7},
+				{ID: 8, UserID: userID, UserAgent: "Chrome"},
+			}, nil
+		},
+		revokeSessionFunc: func(ctx context.Context, userID, sessionID uint) error {
+			if sessionID != 8 {
+				return auth.ErrSessionNotFound
+			}
+			revoked = sessionID
+			return nil
+		},
+	}
+	handler := NewAuthHandler(mockService)
+
+	// newRequest создает запрос от сессии 7 пользователя 1
+	newRequest := func(method string, body interface{}) *http.Request {
+		reqBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, "/auth", bytes.NewBuffer(reqBody))
+		ctx := context.WithValue(req.Context(), "user_id", uint(1))
+		ctx = context.WithValue(ctx, "session_id", uint(7))
+		return req.WithContext(ctx)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.Logout(rr, newRequest("POST", nil))
+	if rr.Code != http.StatusOK || loggedOut != 7 {
+		t.Errorf("Logout: статус %d, завершена сессия %d, хотели 200 и 7", rr.Code, loggedOut)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.LogoutAll(rr, newRequest("POST", nil))
+	if rr.Code != http.StatusOK || !loggedOutAll {
+		t.Errorf("LogoutAll: статус %d, хотели 200 и завершение всех сессий", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.GetSessions(rr, newRequest("GET", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("GetSessions: статус %d, хотели 200", rr.Code)
+	}
+	var sessions []*models.Session
+	if err := json.Unmarshal(rr.Body.Bytes(), &sessions); err != nil {
+		t.Fatalf("Не удалось разобрать JSON ответа: %v", err)
+	}
+	if len(sessions) != 2 || !sessions[0].Current || sessions[1].Current {
+		t.Errorf("GetSessions вернул неверные сессии: %s", rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	handler.RevokeSession(rr, newRequest("POST", RevokeSessionRequest{ID: 8}))
+	if rr.Code != http.StatusOK || revoked != 8 {
+		t.Errorf("RevokeSession: статус %d, завершена сессия %d, хотели 200 и 8", rr.Code, revoked)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.RevokeSession(rr, newRequest("POST", RevokeSessionRequest{ID: 9}))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("RevokeSession чужой сессии: статус %d, хотели 404", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.RevokeSession(rr, newRequest("POST", RevokeSessionRequest{}))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("RevokeSession без ID: статус %d, хотели 400", rr.Code)
+	}
+}
+
+// TestForgotPassword тестирует обработчик ForgotPassword
+func TestForgotPassword(t *testing.T) {
+	var requested []string
+	handler := NewAuthHandler(&MockAuthService{
+		forgotPasswordFunc: func(ctx context.Context, email string) error {
+			requested = append(requested, email)
+			return nil
+		},
+	})
+
+	// Ответ одинаков для любого email
+	var bodies []string
+	for _, email := range []string{"test@example.com", "unknown@example.com"} {
+		reqBody, _ := json.Marshal(ForgotPasswordRequest{Email: email})
+		req, _ := http.NewRequest("POST", "/forgot-password", bytes.NewBuffer(reqBody))
+		rr := httptest.NewRecorder()
+
+		handler.ForgotPassword(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("ForgotPassword(%s): статус %d, хотели 200", email, rr.Code)
+		}
+		bodies = append(bodies, rr.Body.String())
+	}
+	if bodies[0] != bodies[1] {
+		t.Errorf("Ответы различаются: %q и %q", bodies[0], bodies[1])
+	}
+	if len(requested) != 2 {
+		t.Errorf("Сервис вызван %d раз, хотели 2", len(requested))
+	}
+
+	reqBody, _ := json.Marshal(ForgotPasswordRequest{})
+	req, _ := http.NewRequest("POST", "/forgot-password", bytes.NewBuffer(reqBody))
+	rr := httptest.NewRecorder()
+	handler.ForgotPassword(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("ForgotPassword без email: статус %d, хотели 400", rr.Code)
+	}
+}
+
+// TestResetPassword тестирует обработчик ResetPassword
+func TestResetPassword(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestBody    ResetPasswordRequest
+		mockReset      func(ctx context.Context, token, newPassword string) error
+		expectedStatus int
+	}{
+		{
+			name:        "Успешный сброс",
+			requestBody: ResetPasswordRequest{Token: "reset-token", NewPassword: "newpassword123"},
+			mockReset: func(ctx context.Context, token, newPassword string) error {
+				if token != "reset-token" || newPassword != "newpassword123" {
+					return errors.New("неверные аргументы")
+				}
+				return nil
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "Недействительный токен",
+			requestBody: ResetPasswordRequest{Token: "used-token", NewPassword: "newpassword123"},
+			mockReset: func(ctx context.Context, token, newPassword string) error {
+				return auth.ErrInvalidResetToken
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Пустой пароль",
+			requestBody:    ResetPasswordRequest{Token: "reset-token"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "Внутренняя ошибка сервера",
+			requestBody: ResetPasswordRequest{Token: "reset-token", NewPassword: "newpassword123"},
+			mockReset: func(ctx context.Context, token, newPassword string) error {
+				return errors.New("ошибка базы данных")
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAuthHandler(&MockAuthService{resetPasswordFunc: tt.mockReset})
+
+			reqBody, _ := json.Marshal(tt.requestBody)
+			req, _ := http.NewRequest("POST", "/reset-password", bytes.NewBuffer(reqBody))
+			rr := httptest.NewRecorder()
+
+			handler.ResetPassword(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Обработчик вернул неверный статус: получили %v, хотели %v", rr.Code, tt.expectedStatus)
+			}
+		})
+	}
+}
+
+// TestVerifyEmail тестирует обработчик VerifyEmail
+func TestVerifyEmail(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		mockVerify     func(ctx context.Context, token string) error
+		expectedStatus int
+	}{
+		{
+			name:  "Успешное подтверждение",
+			query: "?token=verify-token",
+			mockVerify: func(ctx context.Context, token string) error {
+				if token != "verify-token" {
+					return errors.New("неверный токен")
+				}
+				return nil
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:  "Недействительный токен",
+			query: "?token=used-token",
+			mockVerify: func(ctx context.Context, token string) error {
+				return auth.ErrInvalidVerificationToken
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Без токена",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "Внутренняя ошибка сервера",
+			query: "?token=verify-token",
+			mockVerify: func(ctx context.Context, token string) error {
+				return errors.New("ошибка базы данных")
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAuthHandler(&MockAuthService{verifyEmailFunc: tt.mockVerify})
+
+			req, _ := http.NewRequest("GET", "/verify"+tt.query, nil)
+			rr := httptest.NewRecorder()
+
+			handler.VerifyEmail(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Обработчик вернул неверный статус: получили %v, хотели %v", rr.Code, tt.expectedStatus)
+			}
+		})
+	}
+}
+
+// TestResendVerification тестирует обработчик ResendVerification
+func TestResendVerification(t *testing.T) {
+	var requested []string
+	handler := NewAuthHandler(&MockAuthService{
+		resendVerificationFunc: func(ctx context.Context, email string) error {
+			requested = append(requested, email)
+			return nil
+		},
+	})
+
+	reqBody, _ := json.Marshal(ResendVerificationRequest{Email: "test@example.com"})
+	req, _ := http.NewRequest("POST", "/verify/resend", bytes.NewBuffer(reqBody))
+	rr := httptest.NewRecorder()
+	handler.ResendVerification(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("ResendVerification: статус %d, хотели 200", rr.Code)
+	}
+	if len(requested) != 1 || requested[0] != "test@example.com" {
+		t.Errorf("Сервис вызван для %v, хотели [test@example.com]", requested)
+	}
+
+	reqBody, _ = json.Marshal(ResendVerificationRequest{})
+	req, _ = http.NewRequest("POST", "/verify/resend", bytes.NewBuffer(reqBody))
+	rr = httptest.NewRecorder()
+	handler.ResendVerification(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("ResendVerification без email: статус %d, хотели 400", rr.Code)
+	}
+}
+
+// TestLoginTwoFactor тестирует двухэтапный вход
+func TestLoginTwoFactor(t *testing.T) {
+	handler := NewAuthHandler(&MockAuthService{
+		loginFunc: func(ctx context.Context, email, password string) (*auth.Tokens, error) {
+			return &auth.Tokens{Challenge: "challenge-token", ChallengeExpiresAt: time.Now().Add(5 * time.Minute)}, nil
+		},
+		completeLoginFunc: func(ctx context.Context, challenge, code string) (*auth.Tokens, error) {
+			if challenge != "challenge-token" {
+				return nil, auth.ErrInvalidChallenge
+			}
+			if code != "123456" {
+				return nil, auth.ErrInvalidTwoFactorCode
+			}
+			return testTokens(), nil
+		},
+	})
+
+	// Первый шаг возвращает промежуточный токен вместо токенов сессии
+	reqBody, _ := json.Marshal(LoginRequest{Email: "test@example.com", Password: "password123"})
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(reqBody))
+	rr := httptest.NewRecorder()
+	handler.Login(rr, req)
+
+	var challenge ChallengeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &challenge); err != nil {
+		t.Fatalf("Не удалось разобрать JSON ответа: %v", err)
+	}
+	if rr.Code != http.StatusOK || !challenge.TwoFactorRequired || challenge.ChallengeToken != "challenge-token" {
+		t.Fatalf("Login: статус %d, ответ %+v", rr.Code, challenge)
+	}
+	if strings.Contains(rr.Body.String(), `"token"`) {
+		t.Errorf("Ответ первого шага содержит токен доступа: %s", rr.Body.String())
+	}
+
+	tests := []struct {
+		name           string
+		requestBody    TwoFactorLoginRequest
+		expectedStatus int
+	}{
+		{"Верный код", TwoFactorLoginRequest{ChallengeToken: "challenge-token", Code: "123456"}, http.StatusOK},
+		{"Неверный код", TwoFactorLoginRequest{ChallengeToken: "challenge-token", Code: "000000"}, http.StatusUnauthorized},
+		{"Неизвестный токен входа", TwoFactorLoginRequest{ChallengeToken: "other", Code: "123456"}, http.StatusUnauthorized},
+		{"Без кода", TwoFactorLoginRequest{ChallengeToken: "challenge-token"}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqBody, _ := json.Marshal(tt.requestBody)
+			req, _ := http.NewRequest("POST", "/2fa/login", bytes.NewBuffer(reqBody))
+			rr := httptest.NewRecorder()
+
+			handler.LoginTwoFactor(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Обработчик вернул неверный статус: получили %v, хотели %v", rr.Code, tt.expectedStatus)
+			}
+		})
+	}
+}
+
+// TestLoginLocked тестирует ответ на вход во время блокировки после неудачных попыток
+func TestLoginLocked(t *testing.T) {
+	locked := &auth.LockedError{RetryAfter: 90*time.Second + time.Millisecond}
+	handler := NewAuthHandler(&MockAuthService{
+		loginFunc: func(ctx context.Context, email, password string) (*auth.Tokens, error) {
+			return nil, locked
+		},
+		completeLoginFunc: func(ctx context.Context, challenge, code string) (*auth.Tokens, error) {
+			return nil, locked
+		},
+		disableTwoFactorFunc: func(ctx context.Context, userID uint, password string) error {
+			return locked
+		},
+	})
+
+	reqBody, _ := json.Marshal(LoginRequest{Email: "test@example.com", Password: "password123"})
+	rr := httptest.NewRecorder()
+	handler.Login(rr, httptest.NewRequest("POST", "/login", bytes.NewBuffer(reqBody)))
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") != "91" {
+		t.Errorf("Login: статус %d, Retry-After %q, хотели 429 и 91", rr.Code, rr.Header().Get("Retry-After"))
+	}
+
+	reqBody, _ = json.Marshal(TwoFactorLoginRequest{ChallengeToken: "challenge-token", Code: "123456"})
+	rr = httptest.NewRecorder()
+	handler.LoginTwoFactor(rr, httptest.NewRequest("POST", "/2fa/login", bytes.NewBuffer(reqBody)))
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") != "91" {
+		t.Errorf("LoginTwoFactor: статус %d, Retry-After %q, хотели 429 и 91", rr.Code, rr.Header().Get("Retry-After"))
+	}
+
+	reqBody, _ = json.Marshal(TwoFactorPasswordRequest{Password: "password123"})
+	req := httptest.NewRequest("POST", "/2fa/disable", bytes.NewBuffer(reqBody))
+	req = req.WithContext(context.WithValue(req.Context(), "user_id", uint(1)))
+	rr = httptest.NewRecorder()
+	handler.DisableTwoFactor(rr, req)
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") != "91" {
+		t.Errorf("DisableTwoFactor: статус %d, Retry-After %q, хотели 429 и 91", rr.Code, rr.Header().Get("Retry-After"))
+	}
+}
+
+// TestTwoFactorSettings тестирует обработчики управления двухфакторной аутентификацией
+func TestTwoFactorSettings(t *testing.T) {
+	enabled := false
+	handler := NewAuthHandler(&MockAuthService{
+		setupTwoFactorFunc: func(ctx context.Context, userID uint) (*auth.TwoFactorSetup, error) {
+			if enabled {
+				return nil, auth.ErrTwoFactorEnabled
+			}
+			return &auth.TwoFactorSetup{Secret: "JBSWY3DPEHPK3PXP", URI: "otpauth://totp/TimeTracker:test@example.com"}, nil
+		},
+		confirmTwoFactorFunc: func(ctx context.Context, userID uint, code string) ([]string, error) {
+			if code != "123456" {
+				return nil, auth.ErrInvalidTwoFactorCode
+			}
+			enabled = true
+			return []string{"AAAA-BBBB-CCCC-DDDD"}, nil
+		},
+		disableTwoFactorFunc: func(ctx context.Context, userID uint, password string) error {
+			if password != "password123" {
+				return auth.ErrInvalidCredentials
+			}
+			if !enabled {
+				return auth.ErrTwoFactorNotEnabled
+			}
+			enabled = false
+			return nil
+		},
+		regenerateCodesFunc: func(ctx context.Context, userID uint, password string) ([]string, error) {
+			if password != "password123" {
+				return nil, auth.ErrInvalidCredentials
+			}
+			return []string{"EEEE-FFFF-GGGG-HHHH"}, nil
+		},
+	})
+
+	call := func(handle http.HandlerFunc, body interface{}) *httptest.ResponseRecorder {
+		reqBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/2fa", bytes.NewBuffer(reqBody))
+		req = req.WithContext(context.WithValue(req.Context(), "user_id", uint(1)))
+		rr := httptest.NewRecorder()
+		handle(rr, req)
+		return rr
+	}
+
+	if rr := call(handler.SetupTwoFactor, nil); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"otpauth_url"`) {
+		t.Errorf("SetupTwoFactor: статус %d, ответ %s", rr.Code, rr.Body.String())
+	}
+	if rr := call(handler.ConfirmTwoFactor, TwoFactorCodeRequest{Code: "000000"}); rr.Code != http.StatusBadRequest {
+		t.Errorf("ConfirmTwoFactor с неверным кодом: статус %d, хотели 400", rr.Code)
+	}
+
+	rr := call(handler.ConfirmTwoFactor, TwoFactorCodeRequest{Code: "123456"})
+	var codes RecoveryCodesResponse
+	json.Unmarshal(rr.Body.Bytes(), &codes)
+	if rr.Code != http.StatusOK || len(codes.RecoveryCodes) != 1 {
+		t.Errorf("ConfirmTwoFactor: статус %d, ответ %s", rr.Code, rr.Body.String())
+	}
+	if rr := call(handler.SetupTwoFactor, nil); rr.Code != http.StatusConflict {
+		t.Errorf("SetupTwoFactor при включенной 2FA: статус %d, хотели 409", rr.Code)
+	}
+
+	if rr := call(handler.RegenerateRecoveryCodes, TwoFactorPasswordRequest{Password: "wrong"}); rr.Code != http.StatusForbidden {
+		t.Errorf("RegenerateRecoveryCodes с неверным паролем: статус %d, хотели 403", rr.Code)
+	}
+	if rr := call(handler.RegenerateRecoveryCodes, TwoFactorPasswordRequest{Password: "password123"}); rr.Code != http.StatusOK {
+		t.Errorf("RegenerateRecoveryCodes: статус %d, хотели 200", rr.Code)
+	}
+
+	if rr := call(handler.DisableTwoFactor, TwoFactorPasswordRequest{}); rr.Code != http.StatusBadRequest {
+		t.Errorf("DisableTwoFactor без пароля: статус %d, хотели 400", rr.Code)
+	}
+	if rr := call(handler.DisableTwoFactor, TwoFactorPasswordRequest{Password: "password123"}); rr.Code != http.StatusOK {
+		t.Errorf("DisableTwoFactor: статус %d, хотели 200", rr.Code)
+	}
+	if rr := call(handler.DisableTwoFactor, TwoFactorPasswordRequest{Password: "password123"}); rr.Code != http.StatusConflict {
+		t.Errorf("DisableTwoFactor повторно: статус %d, хотели 409", rr.Code)
+	}
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/graywrk/timetracker/backend/pkg/calendar"
+)
+
+// CalendarHandler обрабатывает запросы календаря ICS и токенов для него
+type CalendarHandler struct {
+	service *calendar.Service
+}
+
+// NewCalendarHandler создает новый обработчик календаря
+func NewCalendarHandler(service *calendar.Service) *CalendarHandler {
+	return &CalendarHandler{
+		service: service,
+	}
+}
+
+// Feed отдает календарь ICS по секретному токену из пути. Аутентификация по JWT не требуется:
+// ссылку добавляют в календари, которые не умеют передавать заголовки.
+// Поддерживаются параметры start_date и end_date и условный запрос с If-None-Match.
+func (h *CalendarHandler) Feed(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+	feed, err := h.service.GetFeed(r.Context(), token, r.URL.Query().Get("start_date"), r.URL.Query().Get("end_date"))
+	if err != nil {
+		switch {
+		case errors.Is(err, calendar.ErrTokenNotFound):
+			http.Error(w, "Календарь не найден", http.StatusNotFound)
+		case errors.Is(err, calendar.ErrInvalidWindow), errors.Is(err, calendar.ErrWindowTooLong):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			log.Printf("CalendarFeed: Ошибка формирования календаря: %v", err)
+			http.Error(w, "Ошибка при формировании календаря", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("ETag", feed.ETag)
+	// Клиент может хранить календарь, но должен проверять его актуальность при каждом запросе
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if etagMatches(r.Header.Get("If-None-Match"), feed.ETag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="timetracker.ics"`)
+	w.Write(feed.Body)
+}
+
+// etagMatches проверяет заголовок If-None-Match: список тегов через запятую или "*".
+// Слабые теги (W/"...") сравниваются по значению, как требует RFC 9110 для If-None-Match.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// GetTokens возвращает токены календаря пользователя
+func (h *CalendarHandler) GetTokens(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста (установлен middleware)
+	userID := r.Context().Value("user_id").(uint)
+
+	tokens, err := h.service.GetTokens(r.Context(), userID)
+	if err != nil {
+		log.Printf("Ошибка при получении токенов календаря: %v", err)
+		http.Error(w, "Не удалось получить токены календаря", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// CreateToken создает токен календаря и возвращает ссылку на календарь.
+// Токен показывается только в этом ответе.
+func (h *CalendarHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста (установлен middleware)
+	userID := r.Context().Value("user_id").(uint)
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Неверный формат запроса", http.StatusBadRequest)
+		return
+	}
+
+	token, secret, err := h.service.CreateToken(r.Context(), userID, req.Name)
+	if err != nil {
+		log.Printf("Ошибка при создании токена календаря: %v", err)
+		http.Error(w, "Не удалось создать токен календаря", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":        token,
+		"secret":       secret,
+		"calendar_url": "/api/calendar/" + secret + ".ics",
+	})
+}
+
+// RevokeToken отзывает токен календаря
+func (h *CalendarHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста (установлен middleware)
+	userID := r.Context().Value("user_id").(uint)
+
+	var req struct {
+		ID uint `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Неверный формат запроса", http.StatusBadRequest)
+		return
+	}
+
+	if req.ID == 0 {
+		http.Error(w, "ID токена не указан", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RevokeToken(r.Context(), userID, req.ID); err != nil {
+		if errors.Is(err, calendar.ErrTokenNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Printf("Ошибка при отзыве токена календаря: %v", err)
+		http.Error(w, "Не удалось отозвать токен календаря", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Токен календаря отозван",
+	})
+}
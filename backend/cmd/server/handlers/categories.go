@@ -2,9 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 
+	"github.com/graywrk/timetracker/backend/internal/models"
 	"github.com/graywrk/timetracker/backend/pkg/categories"
 )
 
@@ -134,3 +136,44 @@ func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request)
 		"message": "Категория успешно удалена",
 	})
 }
+
+// SetCategoryRate устанавливает почасовую ставку категории
+func (h *CategoryHandler) SetCategoryRate(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста (установлен middleware)
+	userID := r.Context().Value("user_id").(uint)
+
+	// Парсим запрос; hourly_rate = null снимает ставку
+	var req struct {
+		ID         uint          `json:"id"`
+		HourlyRate *models.Money `json:"hourly_rate"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Неверный формат запроса", http.StatusBadRequest)
+		return
+	}
+
+	if req.ID == 0 {
+		http.Error(w, "ID категории не указан", http.StatusBadRequest)
+		return
+	}
+
+	category, err := h.service.SetCategoryRate(r.Context(), req.ID, userID, req.HourlyRate)
+	if err != nil {
+		log.Printf("Ошибка при изменении ставки категории: %v", err)
+		switch {
+		case errors.Is(err, categories.ErrInvalidRate):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, categories.ErrCategoryNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, categories.ErrNotAuthorized):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, "Не удалось изменить ставку категории", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Отправляем ответ
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(category)
+}
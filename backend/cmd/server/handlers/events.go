@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/pkg/events"
+)
+
+// EventsHandler передает клиентам изменения их записей и категорий в реальном времени (Server-Sent Events)
+type EventsHandler struct {
+	bus       events.Bus
+	keepAlive time.Duration
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewEventsHandler создает новый обработчик потока событий. keepAlive - интервал комментариев,
+// которые не дают прокси закрыть соединение без событий.
+func NewEventsHandler(bus events.Bus, keepAlive time.Duration) *EventsHandler {
+	return &EventsHandler{
+		bus:       bus,
+		keepAlive: keepAlive,
+		done:      make(chan struct{}),
+	}
+}
+
+// Close завершает все открытые потоки событий; вызывается при остановке сервера
+func (h *EventsHandler) Close() {
+	h.closeOnce.Do(func() {
+		close(h.done)
+	})
+}
+
+// Stream отправляет события пользователя в формате text/event-stream, пока клиент не отключится.
+// Имя события совпадает с его типом, в data передается событие в JSON.
+func (h *EventsHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(uint)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Поток событий не поддерживается", http.StatusInternalServerError)
+		return
+	}
+
+	// Поток не ограничен таймаутом записи сервера
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("EventsHandler.Stream: Не удалось снять таймаут записи: %v", err)
+	}
+
+	ch, cancel := h.bus.Subscribe(userID)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// Клиент переподключается через 5 секунд после разрыва
+	fmt.Fprint(w, "retry: 5000\n\n")
+	flusher.Flush()
+	log.Printf("EventsHandler.Stream: Пользователь %d подключился к потоку событий", userID)
+
+	ticker := time.NewTicker(h.keepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			log.Printf("EventsHandler.Stream: Пользователь %d отключился от потока событий", userID)
+			return
+		case <-h.done:
+			return
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				log.Printf("Ошибка при кодировании события %s: %v", event.Type, err)
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+		case <-ticker.C:
+			fmt.Fprint(w, ": ping\n\n")
+		}
+		flusher.Flush()
+	}
+}
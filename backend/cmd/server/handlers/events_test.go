@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/pkg/events"
+)
+
+func TestEventsStream(t *testing.T) {
+	hub := events.NewHub()
+	handler := NewEventsHandler(hub, time.Hour)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.Stream(w, r.WithContext(context.WithValue(r.Context(), "user_id", uint(1))))
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("ожидался Content-Type text/event-stream, получен %q", ct)
+	}
+
+	// Ждем, пока обработчик подпишется на события
+	deadline := time.Now().Add(time.Second)
+	for hub.Subscribers(1) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("обработчик не подписался на события")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	hub.Publish(context.Background(), events.Event{Type: events.EntryStarted, UserID: 2, EntryID: 5})
+	hub.Publish(context.Background(), events.Event{Type: events.EntryStarted, UserID: 1, EntryID: 7})
+
+	reader := bufio.NewReader(resp.Body)
+	var name, data string
+	for data == "" {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "event: ") {
+			name = strings.TrimPrefix(line, "event: ")
+		}
+		if strings.HasPrefix(line, "data: ") {
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+
+	if name != events.EntryStarted {
+		t.Errorf("ожидалось событие %s, получено %s", events.EntryStarted, name)
+	}
+	var event events.Event
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.EntryID != 7 {
+		t.Errorf("ожидалось событие записи 7 (события других пользователей не передаются), получено %+v", event)
+	}
+
+	// Остановка сервера завершает поток и отменяет подписку
+	handler.Close()
+	deadline = time.Now().Add(time.Second)
+	for hub.Subscribers(1) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("подписка не отменена после закрытия обработчика")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
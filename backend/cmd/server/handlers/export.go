@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/pkg/export"
+	"github.com/graywrk/timetracker/backend/pkg/statistics"
+)
+
+// exportWriteTimeout - время на передачу файла выгрузки; общий WriteTimeout сервера
+// рассчитан на обычные запросы и прервал бы выгрузку за несколько лет
+const exportWriteTimeout = 10 * time.Minute
+
+// ExportHandler обрабатывает запросы выгрузки записей и отчетов в файлы
+type ExportHandler struct {
+	exportService *export.Service
+	statsService  *statistics.Service
+}
+
+// NewExportHandler создает новый обработчик выгрузки
+func NewExportHandler(exportService *export.Service, statsService *statistics.Service) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+		statsService:  statsService,
+	}
+}
+
+// ExportEntries выгружает записи за период в CSV или XLSX
+func (h *ExportHandler) ExportEntries(w http.ResponseWriter, r *http.Request) {
+	h.serveExport(w, r, "entries", "Записи", h.exportService.ExportEntries)
+}
+
+// ExportReport выгружает отчет по категориям за период в CSV или XLSX
+func (h *ExportHandler) ExportReport(w http.ResponseWriter, r *http.Request) {
+	h.serveExport(w, r, "report", "Отчет", h.exportService.ExportReport)
+}
+
+// serveExport проверяет параметры выгрузки и передает файл клиенту по мере формирования.
+// Параметры: start_date, end_date, tz, format (csv/xlsx), delimiter, locale, decimal.
+func (h *ExportHandler) serveExport(w http.ResponseWriter, r *http.Request, name, sheet string,
+	write func(ctx context.Context, userID uint, period *statistics.Period, w export.RowWriter) error) {
+	// Получаем ID пользователя из контекста запроса
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Необходима аутентификация", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	startDate := query.Get("start_date")
+	endDate := query.Get("end_date")
+	if startDate == "" || endDate == "" {
+		http.Error(w, "Необходимо указать start_date и end_date", http.StatusBadRequest)
+		return
+	}
+
+	opts, err := export.ParseOptions(query.Get("format"), query.Get("delimiter"), query.Get("locale"), query.Get("decimal"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	period, err := h.statsService.ResolvePeriod(r.Context(), userID, startDate, endDate, query.Get("tz"))
+	if err != nil {
+		writeStatsError(w, err, "Ошибка при подготовке выгрузки")
+		return
+	}
+
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(exportWriteTimeout)); err != nil &&
+		!errors.Is(err, http.ErrNotSupported) {
+		log.Printf("Export: Не удалось продлить время записи ответа: %v", err)
+	}
+
+	out := &exportResponse{
+		w:           w,
+		contentType: opts.ContentType(),
+		filename:    fmt.Sprintf("%s_%s_%s.%s", name, startDate, endDate, opts.Extension()),
+	}
+	if err := write(r.Context(), userID, period, export.NewWriter(out, opts, sheet)); err != nil {
+		log.Printf("Export: Ошибка выгрузки %s для пользователя %d: %v", name, userID, err)
+		// Если передача файла уже началась, статус ответа изменить нельзя - клиент получит неполный файл
+		if !out.started {
+			http.Error(w, "Ошибка при формировании выгрузки", http.StatusInternalServerError)
+		}
+	}
+}
+
+// exportResponse откладывает заголовки файла до первой записи данных, чтобы ошибку,
+// возникшую до начала выгрузки, можно было вернуть обычным ответом
+type exportResponse struct {
+	w           http.ResponseWriter
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (e *exportResponse) Write(p []byte) (int, error) {
+	if !e.started {
+		e.started = true
+		e.w.Header().Set("Content-Type", e.contentType)
+		e.w.Header().Set("Content-Disposition", `attachment; filename="`+e.filename+`"`)
+		e.w.WriteHeader(http.StatusOK)
+	}
+	return e.w.Write(p)
+}
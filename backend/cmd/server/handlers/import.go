@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/graywrk/timetracker/backend/pkg/calendar"
+	"github.com/graywrk/timetracker/backend/pkg/importer"
+	"github.com/graywrk/timetracker/backend/pkg/statistics"
+)
+
+// maxImportSize - наибольший размер запроса импорта в байтах
+const maxImportSize = 10 << 20
+
+// ImportHandler обрабатывает запросы импорта записей
+type ImportHandler struct {
+	importService *importer.Service
+	statsService  *statistics.Service
+	// calendarDir - каталог на сервере, из которого можно импортировать файлы .ics по имени;
+	// пустая строка запрещает импорт локальных файлов
+	calendarDir string
+}
+
+// NewImportHandler создает новый обработчик импорта
+func NewImportHandler(importService *importer.Service, statsService *statistics.Service, calendarDir string) *ImportHandler {
+	return &ImportHandler{
+		importService: importService,
+		statsService:  statsService,
+		calendarDir:   calendarDir,
+	}
+}
+
+// Import импортирует записи из CSV. Запрос - multipart/form-data с файлом в поле file и
+// параметрами source (csv, toggl, clockify), mapping (JSON с описанием колонок), delimiter,
+// tz и dry_run; параметры можно передать и в строке запроса.
+func (h *ImportHandler) Import(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста запроса
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Необходима аутентификация", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Файл слишком большой", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Ожидается multipart/form-data с файлом в поле file", http.StatusBadRequest)
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Не передан файл в поле file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	opts := importer.Options{
+		Source:    r.FormValue("source"),
+		Delimiter: r.FormValue("delimiter"),
+		Timezone:  r.FormValue("tz"),
+	}
+	if value := r.FormValue("mapping"); value != "" {
+		if err := json.Unmarshal([]byte(value), &opts.Mapping); err != nil {
+			http.Error(w, "Неверный формат mapping", http.StatusBadRequest)
+			return
+		}
+	}
+	if value := r.FormValue("dry_run"); value != "" {
+		dryRun, err := strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "Неверный параметр dry_run", http.StatusBadRequest)
+			return
+		}
+		opts.DryRun = dryRun
+	}
+
+	report, err := h.importService.Import(r.Context(), userID, file, opts)
+	switch {
+	case errors.Is(err, importer.ErrInvalidRows):
+		// Отчет возвращается вместе с ошибкой, чтобы пользователь мог исправить строки
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(report)
+		return
+	case errors.Is(err, importer.ErrInvalidSource), errors.Is(err, importer.ErrInvalidMapping),
+		errors.Is(err, importer.ErrInvalidDelimiter), errors.Is(err, importer.ErrInvalidTimezone),
+		errors.Is(err, importer.ErrEmptyFile), errors.Is(err, importer.ErrTooManyRows):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Printf("Import: Ошибка импорта для пользователя %d: %v", userID, err)
+		http.Error(w, "Ошибка при импорте записей", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// ImportCalendar импортирует события из файла iCalendar (.ics) как запланированное время или
+// завершенные записи. Файл передается в поле file запроса multipart/form-data или, если на сервере
+// задан каталог календарей, именем файла в этом каталоге в параметре path. Параметры: start_date и
+// end_date (YYYY-MM-DD) - период, в котором разворачиваются повторяющиеся события; target (planned
+// или entries); tz; exclude - ключи экземпляров или UID событий (параметр можно повторять).
+// Без confirm=true ничего не сохраняется: ответ - отчет для подтверждения.
+func (h *ImportHandler) ImportCalendar(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста запроса
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Необходима аутентификация", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	err := r.ParseMultipartForm(maxImportSize)
+	if errors.Is(err, http.ErrNotMultipart) {
+		err = r.ParseForm()
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Файл слишком большой", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Неверный формат запроса", http.StatusBadRequest)
+		return
+	}
+
+	startDate, endDate := r.FormValue("start_date"), r.FormValue("end_date")
+	if startDate == "" || endDate == "" {
+		http.Error(w, "Необходимо указать start_date и end_date", http.StatusBadRequest)
+		return
+	}
+	period, err := h.statsService.ResolvePeriod(r.Context(), userID, startDate, endDate, r.FormValue("tz"))
+	if err != nil {
+		writeStatsError(w, err, "Ошибка при импорте календаря")
+		return
+	}
+
+	opts := importer.CalendarOptions{
+		Target: r.FormValue("target"),
+		Period: period,
+		DryRun: true,
+	}
+	for _, value := range r.Form["exclude"] {
+		opts.Exclude = append(opts.Exclude, strings.Split(value, ",")...)
+	}
+	if value := r.FormValue("confirm"); value != "" {
+		confirm, err := strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "Неверный параметр confirm", http.StatusBadRequest)
+			return
+		}
+		opts.DryRun = !confirm
+	}
+
+	file, status, err := h.openCalendar(r)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	defer file.Close()
+
+	report, err := h.importService.ImportCalendar(r.Context(), userID, file, opts)
+	switch {
+	case errors.Is(err, importer.ErrInvalidRows):
+		// Отчет возвращается вместе с ошибкой, чтобы пользователь мог исключить события с ошибками
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(report)
+		return
+	case errors.Is(err, importer.ErrInvalidTarget), errors.Is(err, importer.ErrRangeTooLong),
+		errors.Is(err, calendar.ErrInvalidCalendar), errors.Is(err, calendar.ErrTooManyEvents):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Printf("ImportCalendar: Ошибка импорта календаря для пользователя %d: %v", userID, err)
+		http.Error(w, "Ошибка при импорте календаря", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// openCalendar открывает файл календаря из поля file или, по параметру path, из каталога календарей.
+// При ошибке возвращает HTTP-статус для ответа.
+func (h *ImportHandler) openCalendar(r *http.Request) (io.ReadCloser, int, error) {
+	name := r.FormValue("path")
+	if name == "" {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, http.StatusBadRequest, errors.New("Не передан файл в поле file или параметр path")
+		}
+		return file, 0, nil
+	}
+
+	if h.calendarDir == "" {
+		return nil, http.StatusForbidden, errors.New("Импорт файлов с сервера не настроен")
+	}
+	// fs.ValidPath не допускает абсолютных путей и выхода за пределы каталога через ".."
+	if !fs.ValidPath(name) {
+		return nil, http.StatusBadRequest, errors.New("Некорректный путь к файлу")
+	}
+	file, err := os.DirFS(h.calendarDir).Open(name)
+	if err != nil {
+		return nil, http.StatusNotFound, errors.New("Файл календаря не найден")
+	}
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		file.Close()
+		return nil, http.StatusNotFound, errors.New("Файл календаря не найден")
+	}
+	if info.Size() > maxImportSize {
+		file.Close()
+		return nil, http.StatusRequestEntityTooLarge, errors.New("Файл слишком большой")
+	}
+	return file, 0, nil
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/graywrk/timetracker/backend/pkg/planning"
+	"github.com/graywrk/timetracker/backend/pkg/statistics"
+)
+
+// PlanningHandler обрабатывает запросы запланированного времени
+type PlanningHandler struct {
+	service      *planning.Service
+	statsService *statistics.Service
+}
+
+// NewPlanningHandler создает новый обработчик запланированного времени
+func NewPlanningHandler(service *planning.Service, statsService *statistics.Service) *PlanningHandler {
+	return &PlanningHandler{
+		service:      service,
+		statsService: statsService,
+	}
+}
+
+// GetBlocks возвращает запланированные интервалы за период start_date - end_date (YYYY-MM-DD)
+func (h *PlanningHandler) GetBlocks(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста (установлен middleware)
+	userID := r.Context().Value("user_id").(uint)
+
+	query := r.URL.Query()
+	startDate, endDate := query.Get("start_date"), query.Get("end_date")
+	if startDate == "" || endDate == "" {
+		http.Error(w, "Необходимо указать start_date и end_date", http.StatusBadRequest)
+		return
+	}
+	period, err := h.statsService.ResolvePeriod(r.Context(), userID, startDate, endDate, query.Get("tz"))
+	if err != nil {
+		writeStatsError(w, err, "Не удалось получить запланированное время")
+		return
+	}
+
+	blocks, err := h.service.GetBlocks(r.Context(), userID, period.From, period.To)
+	if err != nil {
+		log.Printf("Ошибка при получении запланированного времени: %v", err)
+		http.Error(w, "Не удалось получить запланированное время", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(blocks)
+}
+
+// DeleteBlock удаляет запланированный интервал
+func (h *PlanningHandler) DeleteBlock(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста (установлен middleware)
+	userID := r.Context().Value("user_id").(uint)
+
+	var req struct {
+		ID uint `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Неверный формат запроса", http.StatusBadRequest)
+		return
+	}
+
+	if req.ID == 0 {
+		http.Error(w, "ID интервала не указан", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteBlock(r.Context(), req.ID, userID); err != nil {
+		log.Printf("Ошибка при удалении запланированного интервала: %v", err)
+		switch {
+		case errors.Is(err, planning.ErrBlockNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, planning.ErrNotAuthorized):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, "Не удалось удалить запланированный интервал", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Запланированный интервал удален",
+	})
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/graywrk/timetracker/backend/pkg/projects"
+)
+
+// ClientRequest представляет запрос на создание/обновление клиента
+type ClientRequest struct {
+	ID uint `json:"id,omitempty"`
+	projects.ClientInput
+}
+
+// ProjectRequest представляет запрос на создание/обновление проекта
+type ProjectRequest struct {
+	ID uint `json:"id,omitempty"`
+	projects.ProjectInput
+}
+
+// ProjectHandler обрабатывает запросы к API клиентов и проектов
+type ProjectHandler struct {
+	service *projects.Service
+}
+
+// NewProjectHandler создает новый обработчик для клиентов и проектов
+func NewProjectHandler(service *projects.Service) *ProjectHandler {
+	return &ProjectHandler{
+		service: service,
+	}
+}
+
+// GetClients возвращает клиентов пользователя (архивные - при include_archived=true)
+func (h *ProjectHandler) GetClients(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста (установлен middleware)
+	userID := r.Context().Value("user_id").(uint)
+	includeArchived := r.URL.Query().Get("include_archived") == "true"
+
+	clients, err := h.service.GetClientsByUserID(r.Context(), userID, includeArchived)
+	if err != nil {
+		log.Printf("Ошибка при получении клиентов: %v", err)
+		http.Error(w, "Не удалось получить клиентов", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(clients)
+}
+
+// CreateClient создает нового клиента
+func (h *ProjectHandler) CreateClient(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста (установлен middleware)
+	userID := r.Context().Value("user_id").(uint)
+
+	var req ClientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Неверный формат запроса", http.StatusBadRequest)
+		return
+	}
+
+	client, err := h.service.CreateClient(r.Context(), userID, req.ClientInput)
+	if err != nil {
+		log.Printf("Ошибка при создании клиента: %v", err)
+		writeProjectError(w, err, "Не удалось создать клиента")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(client)
+}
+
+// UpdateClient обновляет существующего клиента
+func (h *ProjectHandler) UpdateClient(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста (установлен middleware)
+	userID := r.Context().Value("user_id").(uint)
+
+	var req ClientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Неверный формат запроса", http.StatusBadRequest)
+		return
+	}
+
+	if req.ID == 0 {
+		http.Error(w, "ID клиента не указан", http.StatusBadRequest)
+		return
+	}
+
+	client, err := h.service.UpdateClient(r.Context(), req.ID, userID, req.ClientInput)
+	if err != nil {
+		log.Printf("Ошибка при обновлении клиента: %v", err)
+		writeProjectError(w, err, "Не удалось обновить клиента")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(client)
+}
+
+// DeleteClient удаляет клиента
+func (h *ProjectHandler) DeleteClient(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста (установлен middleware)
+	userID := r.Context().Value("user_id").(uint)
+
+	var req struct {
+		ID uint `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Неверный формат запроса", http.StatusBadRequest)
+		return
+	}
+
+	if req.ID == 0 {
+		http.Error(w, "ID клиента не указан", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteClient(r.Context(), req.ID, userID); err != nil {
+		log.Printf("Ошибка при удалении клиента: %v", err)
+		writeProjectError(w, err, "Не удалось удалить клиента")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Клиент успешно удален",
+	})
+}
+
+// GetProjects возвращает проекты пользователя (архивные - при include_archived=true)
+func (h *ProjectHandler) GetProjects(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста (установлен middleware)
+	userID := r.Context().Value("user_id").(uint)
+	includeArchived := r.URL.Query().Get("include_archived") == "true"
+
+	projectsList, err := h.service.GetProjectsByUserID(r.Context(), userID, includeArchived)
+	if err != nil {
+		log.Printf("Ошибка при получении проектов: %v", err)
+		http.Error(w, "Не удалось получить проекты", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(projectsList)
+}
+
+// CreateProject создает новый проект
+func (h *ProjectHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста (установлен middleware)
+	userID := r.Context().Value("user_id").(uint)
+
+	var req ProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Неверный формат запроса", http.StatusBadRequest)
+		return
+	}
+
+	project, err := h.service.CreateProject(r.Context(), userID, req.ProjectInput)
+	if err != nil {
+		log.Printf("Ошибка при создании проекта: %v", err)
+		writeProjectError(w, err, "Не удалось создать проект")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(project)
+}
+
+// UpdateProject обновляет существующий проект
+func (h *ProjectHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста (установлен middleware)
+	userID := r.Context().Value("user_id").(uint)
+
+	var req ProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Неверный формат запроса", http.StatusBadRequest)
+		return
+	}
+
+	if req.ID == 0 {
+		http.Error(w, "ID проекта не указан", http.StatusBadRequest)
+		return
+	}
+
+	project, err := h.service.UpdateProject(r.Context(), req.ID, userID, req.ProjectInput)
+	if err != nil {
+		log.Printf("Ошибка при обновлении проекта: %v", err)
+		writeProjectError(w, err, "Не удалось обновить проект")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(project)
+}
+
+// DeleteProject удаляет проект
+func (h *ProjectHandler) DeleteProject(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста (установлен middleware)
+	userID := r.Context().Value("user_id").(uint)
+
+	var req struct {
+		ID uint `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Неверный формат запроса", http.StatusBadRequest)
+		return
+	}
+
+	if req.ID == 0 {
+		http.Error(w, "ID проекта не указан", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteProject(r.Context(), req.ID, userID); err != nil {
+		log.Printf("Ошибка при удалении проекта: %v", err)
+		writeProjectError(w, err, "Не удалось удалить проект")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Проект успешно удален",
+	})
+}
+
+// writeProjectError отправляет ответ с HTTP-статусом, соответствующим ошибке сервиса проектов
+func writeProjectError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, projects.ErrEmptyName), errors.Is(err, projects.ErrInvalidRate),
+		errors.Is(err, projects.ErrInvalidBudget):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, projects.ErrClientNotFound), errors.Is(err, projects.ErrProjectNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, projects.ErrNotAuthorized), errors.Is(err, projects.ErrCategoryNotOwned):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
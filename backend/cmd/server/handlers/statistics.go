@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/graywrk/timetracker/backend/pkg/statistics"
 )
@@ -29,10 +31,16 @@ func (h *StatisticsHandler) GetCurrentWeekStats(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	opts, err := parseStatsOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Получаем статистику
-	stats, err := h.statsService.GetWeeklyStats(r.Context(), userID)
+	stats, err := h.statsService.GetWeeklyStatsWithOptions(r.Context(), userID, opts)
 	if err != nil {
-		http.Error(w, "Ошибка при получении статистики: "+err.Error(), http.StatusInternalServerError)
+		writeStatsError(w, err, "Ошибка при получении статистики: "+err.Error())
 		return
 	}
 
@@ -49,10 +57,16 @@ func (h *StatisticsHandler) GetCurrentMonthStats(w http.ResponseWriter, r *http.
 		return
 	}
 
+	opts, err := parseStatsOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Получаем статистику
-	stats, err := h.statsService.GetMonthlyStats(r.Context(), userID)
+	stats, err := h.statsService.GetMonthlyStatsWithOptions(r.Context(), userID, opts)
 	if err != nil {
-		http.Error(w, "Ошибка при получении статистики: "+err.Error(), http.StatusInternalServerError)
+		writeStatsError(w, err, "Ошибка при получении статистики: "+err.Error())
 		return
 	}
 
@@ -84,11 +98,27 @@ func (h *StatisticsHandler) GetCustomStats(w http.ResponseWriter, r *http.Reques
 
 	log.Printf("GetCustomStats: Запрос статистики с параметрами startDate=%s, endDate=%s", startDate, endDate)
 
+	opts, err := parseStatsOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Необязательный фильтр по меткам (tag_id через запятую или несколько параметров)
+	for _, value := range splitQueryValues(r.URL.Query()["tag_id"]) {
+		tagID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			http.Error(w, "Неверный tag_id: "+value, http.StatusBadRequest)
+			return
+		}
+		opts.TagIDs = append(opts.TagIDs, uint(tagID))
+	}
+
 	// Получаем статистику из сервиса
-	stats, err := h.statsService.GetUserStats(r.Context(), userID, startDate, endDate)
+	stats, err := h.statsService.GetUserStatsWithOptions(r.Context(), userID, startDate, endDate, opts)
 	if err != nil {
 		log.Printf("GetCustomStats: Ошибка получения статистики: %v", err)
-		http.Error(w, "Ошибка получения статистики", http.StatusInternalServerError)
+		writeStatsError(w, err, "Ошибка получения статистики")
 		return
 	}
 
@@ -98,3 +128,67 @@ func (h *StatisticsHandler) GetCustomStats(w http.ResponseWriter, r *http.Reques
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(stats)
 }
+
+// GetEarnings возвращает отчет об оплачиваемом времени и заработке за период
+func (h *StatisticsHandler) GetEarnings(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста запроса
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Необходима аутентификация", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	startDate := query.Get("start_date")
+	endDate := query.Get("end_date")
+	if startDate == "" || endDate == "" {
+		http.Error(w, "Необходимо указать start_date и end_date", http.StatusBadRequest)
+		return
+	}
+
+	// Необязательное округление каждой записи: rounding - шаг в минутах, rounding_mode - up/nearest/down
+	opts := statistics.EarningsOptions{RoundingMode: query.Get("rounding_mode"), Timezone: query.Get("tz")}
+	if value := query.Get("rounding"); value != "" {
+		minutes, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Неверный параметр rounding", http.StatusBadRequest)
+			return
+		}
+		opts.RoundingMinutes = minutes
+	}
+
+	report, err := h.statsService.GetEarningsReport(r.Context(), userID, startDate, endDate, opts)
+	if err != nil {
+		log.Printf("GetEarnings: Ошибка получения отчета: %v", err)
+		writeStatsError(w, err, "Ошибка получения отчета о заработке")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// parseStatsOptions читает общие параметры запросов статистики: tz - часовой пояс,
+// переопределяющий часовой пояс из профиля, и include_running - учет незавершенной записи
+func parseStatsOptions(r *http.Request) (statistics.StatsOptions, error) {
+	opts := statistics.StatsOptions{Timezone: r.URL.Query().Get("tz")}
+	if value := r.URL.Query().Get("include_running"); value != "" {
+		includeRunning, err := strconv.ParseBool(value)
+		if err != nil {
+			return opts, errors.New("Неверный параметр include_running")
+		}
+		opts.IncludeRunning = includeRunning
+	}
+	return opts, nil
+}
+
+// writeStatsError отправляет ответ об ошибке расчета статистики: ошибки параметров запроса
+// возвращаются как 400, остальные - как внутренняя ошибка с сообщением message
+func writeStatsError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, statistics.ErrInvalidTimezone) || errors.Is(err, statistics.ErrInvalidPeriod) ||
+		errors.Is(err, statistics.ErrInvalidRounding) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, message, http.StatusInternalServerError)
+}
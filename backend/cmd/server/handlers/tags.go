@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/graywrk/timetracker/backend/pkg/tags"
+)
+
+// TagRequest представляет запрос на создание/обновление метки
+type TagRequest struct {
+	ID    uint   `json:"id,omitempty"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// TagHandler обрабатывает запросы к API меток
+type TagHandler struct {
+	service *tags.Service
+}
+
+// NewTagHandler создает новый обработчик для меток
+func NewTagHandler(service *tags.Service) *TagHandler {
+	return &TagHandler{
+		service: service,
+	}
+}
+
+// GetTags возвращает все метки пользователя
+func (h *TagHandler) GetTags(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста (установлен middleware)
+	userID := r.Context().Value("user_id").(uint)
+
+	tagsList, err := h.service.GetTagsByUserID(r.Context(), userID)
+	if err != nil {
+		log.Printf("Ошибка при получении меток: %v", err)
+		http.Error(w, "Не удалось получить метки", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tagsList)
+}
+
+// CreateTag создает новую метку
+func (h *TagHandler) CreateTag(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста (установлен middleware)
+	userID := r.Context().Value("user_id").(uint)
+
+	var req TagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Неверный формат запроса", http.StatusBadRequest)
+		return
+	}
+
+	tag, err := h.service.CreateTag(r.Context(), userID, req.Name, req.Color)
+	if err != nil {
+		log.Printf("Ошибка при создании метки: %v", err)
+		writeTagError(w, err, "Не удалось создать метку")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(tag)
+}
+
+// UpdateTag обновляет существующую метку
+func (h *TagHandler) UpdateTag(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста (установлен middleware)
+	userID := r.Context().Value("user_id").(uint)
+
+	var req TagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Неверный формат запроса", http.StatusBadRequest)
+		return
+	}
+
+	if req.ID == 0 {
+		http.Error(w, "ID метки не указан", http.StatusBadRequest)
+		return
+	}
+
+	tag, err := h.service.UpdateTag(r.Context(), req.ID, userID, req.Name, req.Color)
+	if err != nil {
+		log.Printf("Ошибка при обновлении метки: %v", err)
+		writeTagError(w, err, "Не удалось обновить метку")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tag)
+}
+
+// DeleteTag удаляет метку
+func (h *TagHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста (установлен middleware)
+	userID := r.Context().Value("user_id").(uint)
+
+	var req struct {
+		ID uint `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Неверный формат запроса", http.StatusBadRequest)
+		return
+	}
+
+	if req.ID == 0 {
+		http.Error(w, "ID метки не указан", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteTag(r.Context(), req.ID, userID); err != nil {
+		log.Printf("Ошибка при удалении метки: %v", err)
+		writeTagError(w, err, "Не удалось удалить метку")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Метка успешно удалена",
+	})
+}
+
+// writeTagError отправляет ответ с HTTP-статусом, соответствующим ошибке сервиса меток
+func writeTagError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, tags.ErrEmptyTagName):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, tags.ErrTagNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, tags.ErrNotAuthorized):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
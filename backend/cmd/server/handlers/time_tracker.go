@@ -4,10 +4,15 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"log"
 
+	"github.com/gorilla/mux"
 	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/pkg/database"
 	"github.com/graywrk/timetracker/backend/pkg/timetracker"
 )
 
@@ -25,13 +30,41 @@ func NewTimeTrackerHandler(timeService *timetracker.Service) *TimeTrackerHandler
 
 // TimeEntryResponse представляет ответ с информацией о записи времени
 type TimeEntryResponse struct {
-	ID          uint   `json:"id"`
-	Status      string `json:"status"`
-	StartTime   string `json:"start_time"`
-	EndTime     string `json:"end_time,omitempty"`
-	PausedAt    string `json:"paused_at,omitempty"`
-	TotalPaused int64  `json:"total_paused"`
-	Duration    int64  `json:"duration"` // Длительность в секундах
+	ID          uint           `json:"id"`
+	Status      string         `json:"status"`
+	StartTime   string         `json:"start_time"`
+	EndTime     string         `json:"end_time,omitempty"`
+	PausedAt    string         `json:"paused_at,omitempty"`
+	TotalPaused int64          `json:"total_paused"`
+	Duration    int64          `json:"duration"` // Длительность в секундах
+	Pauses      []models.Pause `json:"pauses,omitempty"`
+	// Idle - запись приостановлена из-за бездействия; клиент должен предложить выбор в /api/time/idle/resolve
+	Idle bool `json:"idle,omitempty"`
+}
+
+// IdleResolveRequest представляет выбор пользователя после автоматической паузы
+type IdleResolveRequest struct {
+	Action string `json:"action"` // keep, discard или split
+}
+
+// SwitchResponse представляет ответ на переключение работы
+type SwitchResponse struct {
+	Stopped *models.TimeEntry `json:"stopped"` // завершенная запись
+	Started *models.TimeEntry `json:"started"` // начатая в момент завершения запись
+}
+
+// TimeEntryRequest представляет запрос на ручное создание или редактирование записи
+type TimeEntryRequest struct {
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	TotalPaused int64     `json:"total_paused"` // в секундах
+	CategoryID  *uint     `json:"category_id"`
+	ProjectID   *uint     `json:"project_id"`
+	Billable    *bool     `json:"billable"` // отсутствие поля: true при создании, без изменений при редактировании
+	Description string    `json:"description"`
+	TagIDs      []uint    `json:"tag_ids"` // при редактировании отсутствие поля оставляет метки без изменений
+	// Pauses - интервалы перерывов; если заданы, total_paused вычисляется по ним
+	Pauses []models.Pause `json:"pauses"`
 }
 
 // Start начинает новую запись времени
@@ -39,37 +72,22 @@ func (h *TimeTrackerHandler) Start(w http.ResponseWriter, r *http.Request) {
 	// Получаем ID пользователя из контекста (установлен middleware)
 	userID := r.Context().Value("user_id").(uint)
 
-	// Проверяем, есть ли в запросе ID категории
-	var req struct {
-		CategoryID uint `json:"category_id"`
-	}
-
-	var entry *models.TimeEntry
-	var err error
-
-	// Парсим тело запроса, если оно есть
-	if r.ContentLength > 0 {
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Неверный формат запроса", http.StatusBadRequest)
-			return
-		}
-
-		// Если указан ID категории, используем метод с категорией
-		if req.CategoryID > 0 {
-			entry, err = h.timeService.StartWorkWithCategory(r.Context(), userID, req.CategoryID)
-		} else {
-			entry, err = h.timeService.StartWork(r.Context(), userID)
-		}
-	} else {
-		// Если тело запроса пустое, просто начинаем запись без категории
-		entry, err = h.timeService.StartWork(r.Context(), userID)
+	opts, err := decodeStartOptions(r)
+	if err != nil {
+		http.Error(w, "Неверный формат запроса", http.StatusBadRequest)
+		return
 	}
 
+	entry, err := h.timeService.StartWorkWithOptions(r.Context(), userID, opts)
 	if err != nil {
 		if errors.Is(err, timetracker.ErrActiveEntryExists) {
 			http.Error(w, "У вас уже есть активная запись времени", http.StatusConflict)
 			return
 		}
+		if status := entryErrorStatus(err); status != http.StatusInternalServerError {
+			http.Error(w, err.Error(), status)
+			return
+		}
 		log.Printf("Ошибка при начале записи времени: %v", err)
 		http.Error(w, "Не удалось начать запись времени", http.StatusInternalServerError)
 		return
@@ -80,6 +98,67 @@ func (h *TimeTrackerHandler) Start(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(entry)
 }
 
+// Switch завершает текущую запись и в тот же момент начинает новую с указанными категорией,
+// проектом, описанием и метками
+func (h *TimeTrackerHandler) Switch(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(uint)
+
+	opts, err := decodeStartOptions(r)
+	if err != nil {
+		http.Error(w, "Неверный формат запроса", http.StatusBadRequest)
+		return
+	}
+
+	stopped, started, err := h.timeService.SwitchWork(r.Context(), userID, opts)
+	if err != nil {
+		if errors.Is(err, timetracker.ErrNoActiveEntry) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if status := entryErrorStatus(err); status != http.StatusInternalServerError {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		log.Printf("Ошибка при переключении записи времени: %v", err)
+		http.Error(w, "Не удалось переключить запись времени", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SwitchResponse{Stopped: stopped, Started: started})
+}
+
+// decodeStartOptions разбирает необязательные категорию, проект, описание и метки новой записи
+func decodeStartOptions(r *http.Request) (timetracker.StartOptions, error) {
+	var req struct {
+		CategoryID  uint   `json:"category_id"`
+		ProjectID   uint   `json:"project_id"`
+		Billable    *bool  `json:"billable"`
+		Description string `json:"description"`
+		TagIDs      []uint `json:"tag_ids"`
+	}
+
+	// Парсим тело запроса, если оно есть
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return timetracker.StartOptions{}, err
+		}
+	}
+
+	opts := timetracker.StartOptions{
+		Billable:    req.Billable,
+		Description: req.Description,
+		TagIDs:      req.TagIDs,
+	}
+	if req.CategoryID > 0 {
+		opts.CategoryID = &req.CategoryID
+	}
+	if req.ProjectID > 0 {
+		opts.ProjectID = &req.ProjectID
+	}
+	return opts, nil
+}
+
 // Pause обрабатывает запрос на приостановку работы
 func (h *TimeTrackerHandler) Pause(w http.ResponseWriter, r *http.Request) {
 	// Получаем ID пользователя из контекста запроса
@@ -95,7 +174,7 @@ func (h *TimeTrackerHandler) Pause(w http.ResponseWriter, r *http.Request) {
 		status := http.StatusInternalServerError
 		if err == timetracker.ErrNoActiveEntry {
 			status = http.StatusNotFound
-		} else if err == timetracker.ErrEntryAlreadyPaused {
+		} else if err == timetracker.ErrEntryAlreadyPaused || err == timetracker.ErrConflict {
 			status = http.StatusConflict
 		}
 		http.Error(w, err.Error(), status)
@@ -110,6 +189,7 @@ func (h *TimeTrackerHandler) Pause(w http.ResponseWriter, r *http.Request) {
 		PausedAt:    entry.PausedAt.Format("2006-01-02T15:04:05Z07:00"),
 		TotalPaused: entry.TotalPaused,
 		Duration:    entry.CalculateDuration(),
+		Pauses:      entry.Pauses,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -131,7 +211,7 @@ func (h *TimeTrackerHandler) Resume(w http.ResponseWriter, r *http.Request) {
 		status := http.StatusInternalServerError
 		if err == timetracker.ErrNoActiveEntry {
 			status = http.StatusNotFound
-		} else if err == timetracker.ErrEntryNotPaused {
+		} else if err == timetracker.ErrEntryNotPaused || err == timetracker.ErrConflict {
 			status = http.StatusConflict
 		}
 		http.Error(w, err.Error(), status)
@@ -145,6 +225,7 @@ func (h *TimeTrackerHandler) Resume(w http.ResponseWriter, r *http.Request) {
 		StartTime:   entry.StartTime.Format("2006-01-02T15:04:05Z07:00"),
 		TotalPaused: entry.TotalPaused,
 		Duration:    entry.CalculateDuration(),
+		Pauses:      entry.Pauses,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -166,6 +247,8 @@ func (h *TimeTrackerHandler) Stop(w http.ResponseWriter, r *http.Request) {
 		status := http.StatusInternalServerError
 		if err == timetracker.ErrNoActiveEntry {
 			status = http.StatusNotFound
+		} else if err == timetracker.ErrConflict {
+			status = http.StatusConflict
 		}
 		http.Error(w, err.Error(), status)
 		return
@@ -179,6 +262,7 @@ func (h *TimeTrackerHandler) Stop(w http.ResponseWriter, r *http.Request) {
 		EndTime:     entry.EndTime.Format("2006-01-02T15:04:05Z07:00"),
 		TotalPaused: entry.TotalPaused,
 		Duration:    entry.CalculateDuration(),
+		Pauses:      entry.Pauses,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -215,10 +299,83 @@ func (h *TimeTrackerHandler) GetCurrentStatus(w http.ResponseWriter, r *http.Req
 		StartTime:   entry.StartTime.Format("2006-01-02T15:04:05Z07:00"),
 		TotalPaused: entry.TotalPaused,
 		Duration:    entry.CalculateDuration(),
+		Pauses:      entry.Pauses,
+	}
+
+	if entry.Status == models.StatusPaused {
+		resp.PausedAt = entry.PausedAt.Format("2006-01-02T15:04:05Z07:00")
+		resp.Idle = entry.IdlePause() != nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Heartbeat обрабатывает периодический сигнал активности клиента
+func (h *TimeTrackerHandler) Heartbeat(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста запроса
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Необходима аутентификация", http.StatusUnauthorized)
+		return
+	}
+
+	entry, err := h.timeService.Heartbeat(r.Context(), userID)
+	if err != nil {
+		if !errors.Is(err, timetracker.ErrNoActiveEntry) {
+			log.Printf("Ошибка при обработке сигнала активности: %v", err)
+		}
+		writeEntryError(w, err)
+		return
+	}
+
+	resp := TimeEntryResponse{
+		ID:          entry.ID,
+		Status:      string(entry.Status),
+		StartTime:   entry.StartTime.Format("2006-01-02T15:04:05Z07:00"),
+		TotalPaused: entry.TotalPaused,
+		Duration:    entry.CalculateDuration(),
+		Pauses:      entry.Pauses,
 	}
 
 	if entry.Status == models.StatusPaused {
 		resp.PausedAt = entry.PausedAt.Format("2006-01-02T15:04:05Z07:00")
+		resp.Idle = entry.IdlePause() != nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ResolveIdle обрабатывает выбор пользователя, как учесть время бездействия после автоматической паузы
+func (h *TimeTrackerHandler) ResolveIdle(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста запроса
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Необходима аутентификация", http.StatusUnauthorized)
+		return
+	}
+
+	var req IdleResolveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Неверный формат запроса: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.timeService.ResolveIdle(r.Context(), userID, req.Action)
+	if err != nil {
+		log.Printf("Ошибка при учете времени бездействия: %v", err)
+		writeEntryError(w, err)
+		return
+	}
+
+	resp := TimeEntryResponse{
+		ID:          entry.ID,
+		Status:      string(entry.Status),
+		StartTime:   entry.StartTime.Format("2006-01-02T15:04:05Z07:00"),
+		TotalPaused: entry.TotalPaused,
+		Duration:    entry.CalculateDuration(),
+		Pauses:      entry.Pauses,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -267,3 +424,291 @@ func (h *TimeTrackerHandler) DeleteTimeEntry(w http.ResponseWriter, r *http.Requ
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Запись успешно удалена"})
 }
+
+// CreateEntry обрабатывает запрос на ручное создание завершенной записи
+func (h *TimeTrackerHandler) CreateEntry(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста запроса
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Необходима аутентификация", http.StatusUnauthorized)
+		return
+	}
+
+	var req TimeEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Неверный формат запроса: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.timeService.CreateManualEntry(r.Context(), userID, req.toInput())
+	if err != nil {
+		log.Printf("Ошибка при создании записи времени: %v", err)
+		writeEntryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// UpdateEntry обрабатывает запрос на редактирование существующей записи
+func (h *TimeTrackerHandler) UpdateEntry(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста запроса
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Необходима аутентификация", http.StatusUnauthorized)
+		return
+	}
+
+	// Получаем ID записи из пути запроса
+	entryID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil || entryID == 0 {
+		http.Error(w, "Неверный ID записи", http.StatusBadRequest)
+		return
+	}
+
+	var req TimeEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Неверный формат запроса: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.timeService.UpdateTimeEntry(r.Context(), uint(entryID), userID, req.toInput())
+	if err != nil {
+		log.Printf("Ошибка при обновлении записи времени: %v", err)
+		writeEntryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
+
+// UpdateEntryDetails обрабатывает запрос на изменение описания и меток записи
+func (h *TimeTrackerHandler) UpdateEntryDetails(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста запроса
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Необходима аутентификация", http.StatusUnauthorized)
+		return
+	}
+
+	// Получаем ID записи из пути запроса
+	entryID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil || entryID == 0 {
+		http.Error(w, "Неверный ID записи", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Description string `json:"description"`
+		TagIDs      []uint `json:"tag_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Неверный формат запроса: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.timeService.UpdateEntryDetails(r.Context(), uint(entryID), userID, req.Description, req.TagIDs)
+	if err != nil {
+		log.Printf("Ошибка при обновлении описания записи: %v", err)
+		writeEntryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
+
+// ListEntries обрабатывает запрос на получение списка записей с фильтрацией и постраничной выборкой.
+// Параметры: start_date, end_date (YYYY-MM-DD), status, category_id, project_id, tag_id (через запятую), billable,
+// auto_stopped, note (поиск по описанию), sort (asc|desc), limit, cursor.
+func (h *TimeTrackerHandler) ListEntries(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста запроса
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Необходима аутентификация", http.StatusUnauthorized)
+		return
+	}
+
+	// Даты фильтра задаются в часовом поясе пользователя, как в статистике
+	loc, err := h.timeService.UserLocation(r.Context(), userID)
+	if err != nil {
+		log.Printf("Ошибка при получении часового пояса пользователя: %v", err)
+		http.Error(w, "Не удалось получить записи", http.StatusInternalServerError)
+		return
+	}
+
+	filter, err := parseEntryFilter(r, loc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.UserID = userID
+
+	page, err := h.timeService.ListTimeEntries(r.Context(), filter, r.URL.Query().Get("cursor"))
+	if err != nil {
+		if errors.Is(err, timetracker.ErrInvalidCursor) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Ошибка при получении списка записей: %v", err)
+		http.Error(w, "Не удалось получить записи", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// parseEntryFilter разбирает параметры запроса списка записей; даты отсчитываются от полуночи в loc
+func parseEntryFilter(r *http.Request, loc *time.Location) (database.TimeEntryFilter, error) {
+	query := r.URL.Query()
+	var filter database.TimeEntryFilter
+
+	if startDate := query.Get("start_date"); startDate != "" {
+		from, err := time.ParseInLocation("2006-01-02", startDate, loc)
+		if err != nil {
+			return filter, errors.New("неверный формат start_date, ожидается YYYY-MM-DD")
+		}
+		filter.From = from
+	}
+
+	if endDate := query.Get("end_date"); endDate != "" {
+		to, err := time.ParseInLocation("2006-01-02", endDate, loc)
+		if err != nil {
+			return filter, errors.New("неверный формат end_date, ожидается YYYY-MM-DD")
+		}
+		// Конец периода включительно
+		filter.To = to.AddDate(0, 0, 1)
+	}
+
+	for _, value := range splitQueryValues(query["status"]) {
+		status := models.Status(value)
+		if status != models.StatusActive && status != models.StatusPaused && status != models.StatusCompleted {
+			return filter, errors.New("неизвестный статус: " + value)
+		}
+		filter.Statuses = append(filter.Statuses, status)
+	}
+
+	for _, value := range splitQueryValues(query["category_id"]) {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return filter, errors.New("неверный category_id: " + value)
+		}
+		filter.CategoryIDs = append(filter.CategoryIDs, uint(id))
+	}
+
+	for _, value := range splitQueryValues(query["project_id"]) {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return filter, errors.New("неверный project_id: " + value)
+		}
+		filter.ProjectIDs = append(filter.ProjectIDs, uint(id))
+	}
+
+	for _, value := range splitQueryValues(query["tag_id"]) {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return filter, errors.New("неверный tag_id: " + value)
+		}
+		filter.TagIDs = append(filter.TagIDs, uint(id))
+	}
+
+	if value := query.Get("billable"); value != "" {
+		billable, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, errors.New("неверный billable: " + value)
+		}
+		filter.Billable = &billable
+	}
+
+	if value := query.Get("auto_stopped"); value != "" {
+		autoStopped, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, errors.New("неверный auto_stopped: " + value)
+		}
+		filter.AutoStopped = &autoStopped
+	}
+
+	filter.Query = strings.TrimSpace(query.Get("note"))
+
+	switch query.Get("sort") {
+	case "", "desc":
+	case "asc":
+		filter.SortAsc = true
+	default:
+		return filter, errors.New("параметр sort должен быть asc или desc")
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value <= 0 {
+			return filter, errors.New("параметр limit должен быть положительным числом")
+		}
+		filter.Limit = value
+	}
+
+	return filter, nil
+}
+
+// splitQueryValues объединяет повторяющиеся параметры и значения, перечисленные через запятую
+func splitQueryValues(values []string) []string {
+	var result []string
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				result = append(result, part)
+			}
+		}
+	}
+	return result
+}
+
+// toInput преобразует запрос во входные данные сервиса
+func (req TimeEntryRequest) toInput() timetracker.EntryInput {
+	return timetracker.EntryInput{
+		StartTime:   req.StartTime,
+		EndTime:     req.EndTime,
+		TotalPaused: req.TotalPaused,
+		CategoryID:  req.CategoryID,
+		ProjectID:   req.ProjectID,
+		Billable:    req.Billable,
+		Description: req.Description,
+		TagIDs:      req.TagIDs,
+		Pauses:      req.Pauses,
+	}
+}
+
+// entryErrorStatus возвращает HTTP-статус для ошибки создания или редактирования записи
+func entryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, timetracker.ErrEntryNotFound), errors.Is(err, timetracker.ErrNoActiveEntry):
+		return http.StatusNotFound
+	case errors.Is(err, timetracker.ErrNotEntryOwner), errors.Is(err, timetracker.ErrCategoryNotOwned),
+		errors.Is(err, timetracker.ErrTagNotOwned), errors.Is(err, timetracker.ErrProjectNotOwned):
+		return http.StatusForbidden
+	case errors.Is(err, timetracker.ErrEntryOverlap), errors.Is(err, timetracker.ErrActiveEntryExists),
+		errors.Is(err, timetracker.ErrProjectArchived), errors.Is(err, timetracker.ErrConflict),
+		errors.Is(err, timetracker.ErrNotIdle):
+		return http.StatusConflict
+	case errors.Is(err, timetracker.ErrInvalidTimeRange), errors.Is(err, timetracker.ErrInvalidPause),
+		errors.Is(err, timetracker.ErrCategoryNotInProject), errors.Is(err, timetracker.ErrInvalidIdleAction):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeEntryError отвечает на ошибку действия с записью. Текст известных ошибок
+// проверки и конфликтов возвращается клиенту; остальные ошибки (например, базы данных) уже записаны
+// в журнал обработчиком и скрываются за общим сообщением.
+func writeEntryError(w http.ResponseWriter, err error) {
+	status := entryErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		http.Error(w, "Внутренняя ошибка сервера", status)
+		return
+	}
+	http.Error(w, err.Error(), status)
+}
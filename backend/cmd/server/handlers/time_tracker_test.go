@@ -2,27 +2,38 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/internal/testutil"
+	"github.com/graywrk/timetracker/backend/pkg/database"
 	"github.com/graywrk/timetracker/backend/pkg/timetracker"
 )
 
 // MockRepository представляет мок репозитория для тестирования
 type MockRepository struct {
-	entries    map[uint]*models.TimeEntry
-	categories map[uint]*models.Category
-	err        error
+	testutil.Repository
+
+	entries     map[uint]*models.TimeEntry
+	tags        map[uint]*models.Tag
+	categories  map[uint]*models.Category
+	user        *models.User
+	nextPauseID uint
+	err         error
 }
 
 // NewMockRepository создает новый мок репозитория
 func NewMockRepository() *MockRepository {
 	return &MockRepository{
 		entries:    make(map[uint]*models.TimeEntry),
+		tags:       make(map[uint]*models.Tag),
 		categories: make(map[uint]*models.Category),
 	}
 }
@@ -32,25 +43,11 @@ func (m *MockRepository) SetError(err error) {
 	m.err = err
 }
 
-// Методы для работы с пользователями
-func (m *MockRepository) CreateUser(ctx context.Context, user *models.User) error {
-	return m.err
-}
-
 func (m *MockRepository) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
-	return nil, m.err
-}
-
-func (m *MockRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
-	return nil, m.err
-}
-
-func (m *MockRepository) UpdateUser(ctx context.Context, user *models.User) error {
-	return m.err
-}
-
-func (m *MockRepository) DeleteUser(ctx context.Context, id uint) error {
-	return m.err
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.user, nil
 }
 
 // Методы для работы с записями о времени
@@ -98,6 +95,58 @@ func (m *MockRepository) GetActiveTimeEntryForUser(ctx context.Context, userID u
 	return nil, nil
 }
 
+func (m *MockRepository) ListTimeEntries(ctx context.Context, filter database.TimeEntryFilter) ([]*models.TimeEntry, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	var result []*models.TimeEntry
+	for _, entry := range m.entries {
+		if entry.UserID != filter.UserID {
+			continue
+		}
+		if len(filter.Statuses) > 0 && !containsStatus(filter.Statuses, entry.Status) {
+			continue
+		}
+		if !filter.From.IsZero() && !entry.EndTime.IsZero() && !entry.EndTime.After(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && !entry.StartTime.Before(filter.To) {
+			continue
+		}
+		if filter.After != nil {
+			before := entry.StartTime.Before(filter.After.StartTime) ||
+				(entry.StartTime.Equal(filter.After.StartTime) && entry.ID < filter.After.ID)
+			if before == filter.SortAsc || entry.ID == filter.After.ID {
+				continue
+			}
+		}
+		result = append(result, entry)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		less := result[i].StartTime.Before(result[j].StartTime) ||
+			(result[i].StartTime.Equal(result[j].StartTime) && result[i].ID < result[j].ID)
+		return less == filter.SortAsc
+	})
+
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+
+	return result, nil
+}
+
+// containsStatus проверяет, входит ли статус в список
+func containsStatus(statuses []models.Status, status models.Status) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *MockRepository) UpdateTimeEntry(ctx context.Context, entry *models.TimeEntry) error {
 	if m.err != nil {
 		return m.err
@@ -114,7 +163,38 @@ func (m *MockRepository) DeleteTimeEntry(ctx context.Context, id uint) error {
 	return nil
 }
 
-func (m *MockRepository) GetUserStatsByPeriod(ctx context.Context, userID uint, startDate, endDate string) ([]*models.TimeEntry, error) {
+func (m *MockRepository) UpdateHeartbeat(ctx context.Context, entryID uint, at time.Time) error {
+	if m.err != nil {
+		return m.err
+	}
+	if entry, ok := m.entries[entryID]; ok {
+		entry.LastHeartbeat = at
+	}
+	return nil
+}
+
+func (m *MockRepository) CreatePause(ctx context.Context, pause *models.Pause) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.nextPauseID++
+	pause.ID = m.nextPauseID
+	return nil
+}
+
+func (m *MockRepository) ReplacePauses(ctx context.Context, entryID uint, pauses []models.Pause) error {
+	if m.err != nil {
+		return m.err
+	}
+	entry, exists := m.entries[entryID]
+	if !exists {
+		return errors.New("запись не найдена")
+	}
+	entry.Pauses = append([]models.Pause(nil), pauses...)
+	return nil
+}
+
+func (m *MockRepository) GetUserStatsByPeriod(ctx context.Context, userID uint, from, to time.Time, tagIDs []uint) ([]*models.TimeEntry, error) {
 	if m.err != nil {
 		return nil, m.err
 	}
@@ -170,6 +250,57 @@ func (m *MockRepository) DeleteCategory(ctx context.Context, id uint) error {
 	return nil
 }
 
+// Методы для работы с метками
+func (m *MockRepository) CreateTag(ctx context.Context, tag *models.Tag) error {
+	if m.err != nil {
+		return m.err
+	}
+	tag.ID = uint(len(m.tags) + 1)
+	m.tags[tag.ID] = tag
+	return nil
+}
+
+func (m *MockRepository) GetTagByID(ctx context.Context, id uint) (*models.Tag, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.tags[id], nil
+}
+
+func (m *MockRepository) GetTagsByUserID(ctx context.Context, userID uint) ([]*models.Tag, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	var result []*models.Tag
+	for _, tag := range m.tags {
+		if tag.UserID == userID {
+			result = append(result, tag)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockRepository) SetTimeEntryTags(ctx context.Context, entryID uint, tagIDs []uint) error {
+	if m.err != nil {
+		return m.err
+	}
+	entry, exists := m.entries[entryID]
+	if !exists {
+		return errors.New("запись не найдена")
+	}
+	entry.Tags = nil
+	for _, id := range tagIDs {
+		if tag, ok := m.tags[id]; ok {
+			entry.Tags = append(entry.Tags, *tag)
+		}
+	}
+	return nil
+}
+
+func (m *MockRepository) WithTx(ctx context.Context, fn func(repo database.Repository) error) error {
+	return fn(m)
+}
+
 // TestDeleteTimeEntry тестирует обработчик удаления записи о времени
 func TestDeleteTimeEntry(t *testing.T) {
 	// Создаем мок репозитория
@@ -286,3 +417,309 @@ func TestDeleteTimeEntry(t *testing.T) {
 		}
 	})
 }
+
+// TestCreateEntry тестирует обработчик ручного создания записи
+func TestCreateEntry(t *testing.T) {
+	mockRepo := NewMockRepository()
+	handler := NewTimeTrackerHandler(timetracker.NewService(mockRepo))
+
+	// Существующая запись с 10:00 до 12:00
+	mockRepo.entries[1] = &models.TimeEntry{
+		ID:        1,
+		UserID:    1,
+		StartTime: time.Date(2025, 3, 10, 10, 0, 0, 0, time.UTC),
+		EndTime:   time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC),
+		Status:    models.StatusCompleted,
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		repoErr        error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Success",
+			body:           `{"start_time": "2025-03-10T07:00:00Z", "end_time": "2025-03-10T09:00:00Z", "total_paused": 600}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "EndBeforeStart",
+			body:           `{"start_time": "2025-03-10T09:00:00Z", "end_time": "2025-03-10T08:00:00Z"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   timetracker.ErrInvalidTimeRange.Error(),
+		},
+		{
+			name:           "Overlap",
+			body:           `{"start_time": "2025-03-10T11:00:00Z", "end_time": "2025-03-10T13:00:00Z"}`,
+			expectedStatus: http.StatusConflict,
+			expectedBody:   timetracker.ErrEntryOverlap.Error(),
+		},
+		{
+			name:           "InvalidJSON",
+			body:           `{"start_time": `,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			// Подробности ошибки базы данных не передаются клиенту
+			name:           "DatabaseError",
+			body:           `{"start_time": "2025-03-10T14:00:00Z", "end_time": "2025-03-10T15:00:00Z"}`,
+			repoErr:        errors.New("pq: нет соединения с 10.0.0.5:5432"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "Внутренняя ошибка сервера\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo.SetError(tt.repoErr)
+			defer mockRepo.SetError(nil)
+
+			req, err := http.NewRequest("POST", "/api/time/entries", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), "user_id", uint(1)))
+
+			rr := httptest.NewRecorder()
+			handler.CreateEntry(rr, req)
+
+			if status := rr.Code; status != tt.expectedStatus {
+				t.Errorf("ожидался статус %v, получен %v", tt.expectedStatus, status)
+			}
+			if tt.expectedBody != "" && !strings.HasPrefix(rr.Body.String(), tt.expectedBody) {
+				t.Errorf("ответ %q, хотели %q", rr.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
+
+// TestListEntries тестирует обработчик списка записей
+func TestListEntries(t *testing.T) {
+	mockRepo := NewMockRepository()
+	handler := NewTimeTrackerHandler(timetracker.NewService(mockRepo))
+
+	mockRepo.entries[1] = &models.TimeEntry{
+		ID:        1,
+		UserID:    1,
+		StartTime: time.Date(2025, 3, 10, 10, 0, 0, 0, time.UTC),
+		EndTime:   time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC),
+		Status:    models.StatusCompleted,
+	}
+	// В часовом поясе пользователя (UTC+3) запись 10 марта 22:30 UTC относится к 11 марта
+	mockRepo.entries[2] = &models.TimeEntry{
+		ID:        2,
+		UserID:    1,
+		StartTime: time.Date(2025, 3, 10, 22, 30, 0, 0, time.UTC),
+		EndTime:   time.Date(2025, 3, 10, 23, 30, 0, 0, time.UTC),
+		Status:    models.StatusCompleted,
+	}
+	mockRepo.user = &models.User{ID: 1, Timezone: "Europe/Moscow"}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"Success", "?start_date=2025-03-10&end_date=2025-03-10&status=completed", http.StatusOK, `"id":1`},
+		{"UserTimezone", "?start_date=2025-03-11&end_date=2025-03-11", http.StatusOK, `"id":2`},
+		{"OutOfRange", "?start_date=2025-03-12", http.StatusOK, `"entries":[]`},
+		{"InvalidDate", "?start_date=10.03.2025", http.StatusBadRequest, ""},
+		{"InvalidStatus", "?status=unknown", http.StatusBadRequest, ""},
+		{"InvalidCategory", "?category_id=abc", http.StatusBadRequest, ""},
+		{"InvalidSort", "?sort=up", http.StatusBadRequest, ""},
+		{"InvalidCursor", "?cursor=!!!", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/api/time/entries"+tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), "user_id", uint(1)))
+
+			rr := httptest.NewRecorder()
+			handler.ListEntries(rr, req)
+
+			if status := rr.Code; status != tt.expectedStatus {
+				t.Errorf("ожидался статус %v, получен %v", tt.expectedStatus, status)
+			}
+			if tt.expectedBody != "" && !strings.Contains(rr.Body.String(), tt.expectedBody) {
+				t.Errorf("ответ %q не содержит %q", rr.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
+
+// TestResolveIdle тестирует обработчик выбора учета времени бездействия
+func TestResolveIdle(t *testing.T) {
+	idleEntry := func() *models.TimeEntry {
+		start := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+		return &models.TimeEntry{
+			ID:            1,
+			UserID:        1,
+			StartTime:     start,
+			PausedAt:      start.Add(30 * time.Minute),
+			LastHeartbeat: start.Add(30 * time.Minute),
+			Status:        models.StatusPaused,
+			Pauses: []models.Pause{
+				{ID: 1, TimeEntryID: 1, StartTime: start.Add(30 * time.Minute), Reason: models.PauseReasonIdle},
+			},
+		}
+	}
+
+	// Запись приостановлена вручную, а не из-за бездействия
+	pausedEntry := idleEntry()
+	pausedEntry.Pauses[0].Reason = ""
+
+	tests := []struct {
+		name           string
+		entry          *models.TimeEntry
+		body           string
+		repoErr        error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"Discard", idleEntry(), `{"action": "discard"}`, nil, http.StatusOK, ""},
+		{"InvalidAction", idleEntry(), `{"action": "forget"}`, nil, http.StatusBadRequest, ""},
+		{"NoEntry", nil, `{"action": "keep"}`, nil, http.StatusNotFound, ""},
+		{"NotIdle", pausedEntry, `{"action": "keep"}`, nil, http.StatusConflict, ""},
+		{"InvalidJSON", idleEntry(), `{"action": `, nil, http.StatusBadRequest, ""},
+		{"Conflict", idleEntry(), `{"action": "keep"}`, database.ErrConflict, http.StatusConflict, ""},
+		{"DatabaseError", idleEntry(), `{"action": "keep"}`, errors.New("pq: нет соединения с 10.0.0.5:5432"),
+			http.StatusInternalServerError, "Внутренняя ошибка сервера\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := NewMockRepository()
+			if tt.entry != nil {
+				mockRepo.entries[tt.entry.ID] = tt.entry
+			}
+			mockRepo.SetError(tt.repoErr)
+			handler := NewTimeTrackerHandler(timetracker.NewService(mockRepo))
+
+			req, err := http.NewRequest("POST", "/api/time/idle/resolve", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), "user_id", uint(1)))
+
+			rr := httptest.NewRecorder()
+			handler.ResolveIdle(rr, req)
+
+			if status := rr.Code; status != tt.expectedStatus {
+				t.Errorf("ожидался статус %v, получен %v: %s", tt.expectedStatus, status, rr.Body.String())
+			}
+			if tt.expectedBody != "" && rr.Body.String() != tt.expectedBody {
+				t.Errorf("ответ %q, ожидался %q", rr.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
+
+// TestHeartbeat тестирует обработчик сигнала активности
+func TestHeartbeat(t *testing.T) {
+	tests := []struct {
+		name           string
+		entry          *models.TimeEntry
+		repoErr        error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"Active", &models.TimeEntry{ID: 1, UserID: 1, StartTime: time.Now().Add(-time.Hour), Status: models.StatusActive},
+			nil, http.StatusOK, ""},
+		{"NoEntry", nil, nil, http.StatusNotFound, timetracker.ErrNoActiveEntry.Error() + "\n"},
+		{"DatabaseError", nil, errors.New("pq: нет соединения с 10.0.0.5:5432"),
+			http.StatusInternalServerError, "Внутренняя ошибка сервера\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := NewMockRepository()
+			if tt.entry != nil {
+				mockRepo.entries[tt.entry.ID] = tt.entry
+			}
+			mockRepo.SetError(tt.repoErr)
+			handler := NewTimeTrackerHandler(timetracker.NewService(mockRepo))
+
+			req, err := http.NewRequest("POST", "/api/time/heartbeat", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), "user_id", uint(1)))
+
+			rr := httptest.NewRecorder()
+			handler.Heartbeat(rr, req)
+
+			if status := rr.Code; status != tt.expectedStatus {
+				t.Errorf("ожидался статус %v, получен %v: %s", tt.expectedStatus, status, rr.Body.String())
+			}
+			if tt.expectedBody != "" && rr.Body.String() != tt.expectedBody {
+				t.Errorf("ответ %q, ожидался %q", rr.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
+
+func TestSwitch(t *testing.T) {
+	activeEntry := func() *models.TimeEntry {
+		return &models.TimeEntry{
+			ID:        1,
+			UserID:    1,
+			StartTime: time.Now().Add(-time.Hour),
+			Status:    models.StatusActive,
+		}
+	}
+
+	tests := []struct {
+		name           string
+		entry          *models.TimeEntry
+		body           string
+		expectedStatus int
+	}{
+		{"Success", activeEntry(), `{"description": "Ревью"}`, http.StatusOK},
+		{"NoEntry", nil, `{"description": "Ревью"}`, http.StatusNotFound},
+		{"InvalidJSON", activeEntry(), `{"description": `, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := NewMockRepository()
+			if tt.entry != nil {
+				mockRepo.entries[tt.entry.ID] = tt.entry
+			}
+			handler := NewTimeTrackerHandler(timetracker.NewService(mockRepo))
+
+			req, err := http.NewRequest("POST", "/api/time/switch", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), "user_id", uint(1)))
+
+			rr := httptest.NewRecorder()
+			handler.Switch(rr, req)
+
+			if status := rr.Code; status != tt.expectedStatus {
+				t.Errorf("ожидался статус %v, получен %v: %s", tt.expectedStatus, status, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp SwitchResponse
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Stopped.ID != 1 || resp.Stopped.Status != models.StatusCompleted {
+				t.Errorf("ожидалось завершение записи 1, получено %+v", resp.Stopped)
+			}
+			if resp.Started.Description != "Ревью" || !resp.Started.StartTime.Equal(resp.Stopped.EndTime) {
+				t.Errorf("новая запись должна начинаться в момент завершения предыдущей: %+v", resp.Started)
+			}
+		})
+	}
+}
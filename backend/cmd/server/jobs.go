@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// runPeriodically выполняет fn каждые interval до отмены ctx. Ошибки записываются в лог,
+// следующий запуск происходит по расписанию. Нулевой или отрицательный interval отключает задачу.
+func runPeriodically(ctx context.Context, name string, interval time.Duration, fn func(ctx context.Context, now time.Time) error) {
+	if interval <= 0 {
+		log.Printf("Фоновая задача %s отключена", name)
+		return
+	}
+
+	log.Printf("Фоновая задача %s запущена с интервалом %v", name, interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("Фоновая задача %s остановлена", name)
+			return
+		case now := <-ticker.C:
+			if err := fn(ctx, now); err != nil {
+				log.Printf("Фоновая задача %s: ошибка: %v", name, err)
+			}
+		}
+	}
+}
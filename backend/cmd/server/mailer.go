@@ -0,0 +1,29 @@
+package main
+
+import (
+	"log"
+
+	"github.com/graywrk/timetracker/backend/pkg/mail"
+)
+
+// newMailer выбирает способ доставки писем: SMTP, если указан сервер, иначе сохранение
+// писем в каталог dir или в лог (для разработки)
+func newMailer(smtpHost string, smtpPort int, smtpUser, smtpPassword, from, dir string) mail.Mailer {
+	if smtpHost == "" {
+		if dir == "" {
+			log.Printf("SMTP-сервер не настроен, письма записываются в лог")
+		} else {
+			log.Printf("SMTP-сервер не настроен, письма сохраняются в каталог %s", dir)
+		}
+		return mail.NewFileMailer(dir)
+	}
+
+	log.Printf("Письма отправляются через SMTP-сервер %s:%d", smtpHost, smtpPort)
+	return mail.NewSMTPMailer(mail.SMTPConfig{
+		Host:     smtpHost,
+		Port:     smtpPort,
+		Username: smtpUser,
+		Password: smtpPassword,
+		From:     from,
+	})
+}
@@ -10,14 +10,24 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	// Встроенная база часовых поясов: образ сервера может не содержать tzdata
+	_ "time/tzdata"
 
 	"github.com/gorilla/mux"
 	"github.com/graywrk/timetracker/backend/cmd/server/handlers"
 	"github.com/graywrk/timetracker/backend/cmd/server/middleware"
 	"github.com/graywrk/timetracker/backend/pkg/auth"
+	"github.com/graywrk/timetracker/backend/pkg/calendar"
 	"github.com/graywrk/timetracker/backend/pkg/categories"
 	"github.com/graywrk/timetracker/backend/pkg/database"
+	"github.com/graywrk/timetracker/backend/pkg/events"
+	"github.com/graywrk/timetracker/backend/pkg/export"
+	"github.com/graywrk/timetracker/backend/pkg/importer"
+	"github.com/graywrk/timetracker/backend/pkg/planning"
+	"github.com/graywrk/timetracker/backend/pkg/projects"
+	"github.com/graywrk/timetracker/backend/pkg/ratelimit"
 	"github.com/graywrk/timetracker/backend/pkg/statistics"
+	"github.com/graywrk/timetracker/backend/pkg/tags"
 	"github.com/graywrk/timetracker/backend/pkg/timetracker"
 )
 
@@ -62,11 +72,55 @@ func main() {
 		dbPassword         = flag.String("db_password", "postgres", "PostgreSQL password")
 		dbName             = flag.String("db_name", "timetracker", "PostgreSQL database name")
 		jwtSecret          = flag.String("jwt_secret", "super_secret_key", "JWT secret key")
-		jwtExpires         = flag.Duration("jwt_expires", 24*time.Hour, "JWT expiration time")
-		jwtRememberExpires = flag.Duration("jwt_remember_expires", 30*24*time.Hour, "JWT expiration time for 'Remember Me'")
+		jwtExpires         = flag.Duration("jwt_expires", 15*time.Minute, "Access token (JWT) expiration time")
+		sessionExpires     = flag.Duration("session_expires", 24*time.Hour, "Session lifetime without refreshing tokens")
+		jwtRememberExpires = flag.Duration("jwt_remember_expires", 30*24*time.Hour, "Session lifetime without refreshing tokens for 'Remember Me'")
+		sessionCleanup     = flag.Duration("session_cleanup_interval", time.Hour, "How often to delete expired sessions (0 disables)")
+		appURL             = flag.String("app_url", "http://localhost:3000", "Web interface URL used in links sent by email")
+		resetExpires       = flag.Duration("password_reset_expires", time.Hour, "Password reset link lifetime")
+		verifyExpires      = flag.Duration("email_verify_expires", 48*time.Hour, "Email verification link lifetime")
+		unverifiedPolicy   = flag.String("unverified_policy", "allow", "What users with unverified email can do: allow (everything), restrict (no export, import or public calendar links), deny (no login)")
+		smtpHost           = flag.String("smtp_host", "", "SMTP server host (empty saves emails to -mail_dir or the log)")
+		smtpPort           = flag.Int("smtp_port", 587, "SMTP server port")
+		smtpUser           = flag.String("smtp_user", "", "SMTP user (empty disables authentication)")
+		smtpPassword       = flag.String("smtp_password", "", "SMTP password")
+		mailFrom           = flag.String("mail_from", "TimeTracker <noreply@localhost>", "Sender address of emails")
+		mailDir            = flag.String("mail_dir", "", "Directory to save emails to when SMTP is not configured (empty writes them to the log)")
+		calendarImportDir  = flag.String("calendar_import_dir", "", "Directory with .ics files that can be imported by name (empty disables)")
+		idleCheckInterval  = flag.Duration("idle_check_interval", time.Minute, "How often to auto-pause entries without heartbeats (0 disables)")
+		autoStopInterval   = flag.Duration("auto_stop_interval", 5*time.Minute, "How often to stop entries past the user's max duration or daily cutoff (0 disables)")
+		eventsKeepAlive    = flag.Duration("events_keepalive", 30*time.Second, "Interval of keep-alive comments in the event stream")
+		notifyChanges      = flag.Bool("notify_changes", false, "Deliver change events between server instances via PostgreSQL LISTEN/NOTIFY")
+		rateLimitLogin     = flag.String("rate_limit_login", "10/1m", "Login requests allowed per client IP, as N/interval (0 disables)")
+		rateLimitEmail     = flag.String("rate_limit_email", "10/10m", "Registration, password reset and email verification requests allowed per client IP, as N/interval (0 disables)")
+		rateLimitAPI       = flag.String("rate_limit_api", "600/1m", "API requests allowed per user, as N/interval (0 disables)")
+		lockoutThreshold   = flag.Int("login_lockout_threshold", 5, "Failed logins in a row after which an account is locked (0 disables)")
+		lockoutBase        = flag.Duration("login_lockout_base", time.Minute, "First account lockout duration; each further failure doubles it")
+		lockoutMax         = flag.Duration("login_lockout_max", time.Hour, "Maximum account lockout duration")
+		trustedProxyList   = flag.String("trusted_proxies", "", "Comma-separated proxy IPs or CIDRs whose X-Forwarded-For header is trusted (empty uses the connection address)")
 	)
 	flag.Parse()
 
+	policy, err := auth.ParseUnverifiedPolicy(*unverifiedPolicy)
+	if err != nil {
+		log.Fatalf("Invalid -unverified_policy: %v", err)
+	}
+
+	parseLimit := func(name, value string) ratelimit.Limit {
+		limit, err := ratelimit.ParseLimit(value)
+		if err != nil {
+			log.Fatalf("Invalid -%s: %v", name, err)
+		}
+		return limit
+	}
+	loginLimit := parseLimit("rate_limit_login", *rateLimitLogin)
+	emailLimit := parseLimit("rate_limit_email", *rateLimitEmail)
+	apiLimit := parseLimit("rate_limit_api", *rateLimitAPI)
+
+	if err := middleware.SetTrustedProxies(*trustedProxyList); err != nil {
+		log.Fatalf("Invalid -trusted_proxies: %v", err)
+	}
+
 	// Инициализация репозитория базы данных
 	repo, err := database.NewPostgresRepository(*dbHost, *dbPort, *dbUser, *dbPassword, *dbName)
 	if err != nil {
@@ -74,56 +128,162 @@ func main() {
 	}
 	defer repo.Close()
 
+	// Шина событий для обновления клиентов в реальном времени
+	hub := events.NewHub()
+
 	// Инициализация сервисов
-	authService := auth.NewService(repo, *jwtSecret, *jwtExpires, *jwtRememberExpires)
-	timeService := timetracker.NewService(repo)
+	authService := auth.NewService(repo, *jwtSecret, *jwtExpires, *sessionExpires, *jwtRememberExpires)
+	authService.SetMailer(newMailer(*smtpHost, *smtpPort, *smtpUser, *smtpPassword, *mailFrom, *mailDir), auth.MailConfig{
+		AppURL:        *appURL,
+		ResetExpires:  *resetExpires,
+		VerifyExpires: *verifyExpires,
+	})
+	authService.SetUnverifiedPolicy(policy)
+
+	// Счетчики ограничений хранятся в памяти: у каждого экземпляра сервера свои
+	limitStore := ratelimit.NewMemoryStore()
+	authService.SetLockout(ratelimit.NewLockout(limitStore, ratelimit.LockoutPolicy{
+		Threshold: *lockoutThreshold,
+		Base:      *lockoutBase,
+		Max:       *lockoutMax,
+	}))
+	timeService := timetracker.NewServiceWithEvents(repo, hub)
 	statsService := statistics.NewService(repo)
-	categoryService := categories.NewService(repo)
+	categoryService := categories.NewServiceWithEvents(repo, hub)
+	tagService := tags.NewService(repo)
+	projectService := projects.NewService(repo)
+	exportService := export.NewService(repo)
+	importService := importer.NewService(repo)
+	calendarService := calendar.NewService(repo)
+	planningService := planning.NewService(repo)
 
 	// Инициализация обработчиков
 	authHandler := handlers.NewAuthHandler(authService)
 	timeHandler := handlers.NewTimeTrackerHandler(timeService)
 	statsHandler := handlers.NewStatisticsHandler(statsService)
 	categoryHandler := handlers.NewCategoryHandler(categoryService)
+	tagHandler := handlers.NewTagHandler(tagService)
+	projectHandler := handlers.NewProjectHandler(projectService)
+	exportHandler := handlers.NewExportHandler(exportService, statsService)
+	importHandler := handlers.NewImportHandler(importService, statsService, *calendarImportDir)
+	calendarHandler := handlers.NewCalendarHandler(calendarService)
+	planningHandler := handlers.NewPlanningHandler(planningService, statsService)
+	eventsHandler := handlers.NewEventsHandler(hub, *eventsKeepAlive)
 
 	// Middleware для аутентификации
 	authMiddleware := middleware.NewAuthMiddleware(authService)
 
+	// Ограничения частоты запросов по группам маршрутов
+	loginLimiter := middleware.NewRateLimitMiddleware(ratelimit.NewLimiter(limitStore, "login", loginLimit), middleware.ByIP)
+	emailLimiter := middleware.NewRateLimitMiddleware(ratelimit.NewLimiter(limitStore, "email", emailLimit), middleware.ByIP)
+	apiLimiter := middleware.NewRateLimitMiddleware(ratelimit.NewLimiter(limitStore, "api", apiLimit), middleware.ByUser)
+	log.Printf("Ограничения запросов: вход %s, письма %s, API %s", loginLimit, emailLimit, apiLimit)
+
 	// Настройка маршрутов
 	r := mux.NewRouter()
 
 	// Применяем CORS middleware ко всем маршрутам
 	r.Use(corsMiddleware)
 
-	// Публичные маршруты
-	r.HandleFunc("/api/auth/register", authHandler.Register).Methods("POST", "OPTIONS")
-	r.HandleFunc("/api/auth/login", authHandler.Login).Methods("POST", "OPTIONS")
+	// Публичные маршруты: вход, регистрация и ссылки из писем ограничиваются по адресу клиента
+	r.Handle("/api/auth/register", emailLimiter.Limit(http.HandlerFunc(authHandler.Register))).Methods("POST", "OPTIONS")
+	r.Handle("/api/auth/login", loginLimiter.Limit(http.HandlerFunc(authHandler.Login))).Methods("POST", "OPTIONS")
+	r.Handle("/api/auth/2fa/login", loginLimiter.Limit(http.HandlerFunc(authHandler.LoginTwoFactor))).Methods("POST", "OPTIONS")
+	r.Handle("/api/auth/refresh", apiLimiter.Limit(http.HandlerFunc(authHandler.Refresh))).Methods("POST", "OPTIONS")
+	r.Handle("/api/auth/forgot-password", emailLimiter.Limit(http.HandlerFunc(authHandler.ForgotPassword))).Methods("POST", "OPTIONS")
+	r.Handle("/api/auth/reset-password", emailLimiter.Limit(http.HandlerFunc(authHandler.ResetPassword))).Methods("POST", "OPTIONS")
+	r.Handle("/api/auth/verify", emailLimiter.Limit(http.HandlerFunc(authHandler.VerifyEmail))).Methods("GET", "OPTIONS")
+	r.Handle("/api/auth/verify/resend", emailLimiter.Limit(http.HandlerFunc(authHandler.ResendVerification))).Methods("POST", "OPTIONS")
+
+	// Календарь ICS доступен по секретному токену в ссылке, без JWT
+	r.HandleFunc("/api/calendar/{token:[A-Za-z0-9_-]+}.ics", calendarHandler.Feed).Methods("GET", "OPTIONS")
 
 	// Защищенные маршруты
 	api := r.PathPrefix("/api").Subrouter()
-	api.Use(authMiddleware.Authenticate)
+	api.Use(authMiddleware.Authenticate, apiLimiter.Limit)
+
+	// verified закрывает функцию для пользователей с неподтвержденным email при -unverified_policy=restrict
+	verified := func(handler http.HandlerFunc) http.Handler {
+		return authMiddleware.RequireVerifiedEmail(handler)
+	}
 
 	// Маршруты для управления учетными записями
 	api.HandleFunc("/auth/change-password", authHandler.ChangePassword).Methods("POST", "OPTIONS")
+	api.HandleFunc("/auth/me", authHandler.GetProfile).Methods("GET", "OPTIONS")
+	api.HandleFunc("/auth/settings", authHandler.UpdateSettings).Methods("PUT", "OPTIONS")
+	api.HandleFunc("/auth/logout", authHandler.Logout).Methods("POST", "OPTIONS")
+	api.HandleFunc("/auth/logout-all", authHandler.LogoutAll).Methods("POST", "OPTIONS")
+	api.HandleFunc("/auth/sessions", authHandler.GetSessions).Methods("GET", "OPTIONS")
+	api.HandleFunc("/auth/sessions/revoke", authHandler.RevokeSession).Methods("POST", "OPTIONS")
+	api.HandleFunc("/auth/2fa", authHandler.GetTwoFactorStatus).Methods("GET", "OPTIONS")
+	api.HandleFunc("/auth/2fa/setup", authHandler.SetupTwoFactor).Methods("POST", "OPTIONS")
+	api.HandleFunc("/auth/2fa/confirm", authHandler.ConfirmTwoFactor).Methods("POST", "OPTIONS")
+	api.HandleFunc("/auth/2fa/disable", authHandler.DisableTwoFactor).Methods("POST", "OPTIONS")
+	api.HandleFunc("/auth/2fa/recovery-codes", authHandler.RegenerateRecoveryCodes).Methods("POST", "OPTIONS")
 
 	// Маршруты для учета времени
 	api.HandleFunc("/time/start", timeHandler.Start).Methods("POST", "OPTIONS")
 	api.HandleFunc("/time/pause", timeHandler.Pause).Methods("POST", "OPTIONS")
 	api.HandleFunc("/time/resume", timeHandler.Resume).Methods("POST", "OPTIONS")
 	api.HandleFunc("/time/stop", timeHandler.Stop).Methods("POST", "OPTIONS")
+	api.HandleFunc("/time/switch", timeHandler.Switch).Methods("POST", "OPTIONS")
 	api.HandleFunc("/time/status", timeHandler.GetCurrentStatus).Methods("GET", "OPTIONS")
+	api.HandleFunc("/time/heartbeat", timeHandler.Heartbeat).Methods("POST", "OPTIONS")
+	api.HandleFunc("/time/idle/resolve", timeHandler.ResolveIdle).Methods("POST", "OPTIONS")
 	api.HandleFunc("/time/delete", timeHandler.DeleteTimeEntry).Methods("POST", "OPTIONS")
+	api.HandleFunc("/time/entries", timeHandler.ListEntries).Methods("GET", "OPTIONS")
+	api.HandleFunc("/time/entries", timeHandler.CreateEntry).Methods("POST", "OPTIONS")
+	api.HandleFunc("/time/entries/{id:[0-9]+}", timeHandler.UpdateEntry).Methods("PUT", "OPTIONS")
+	api.HandleFunc("/time/entries/{id:[0-9]+}/details", timeHandler.UpdateEntryDetails).Methods("PUT", "OPTIONS")
+
+	// Поток изменений записей и категорий (Server-Sent Events)
+	api.HandleFunc("/events", eventsHandler.Stream).Methods("GET", "OPTIONS")
 
 	// Маршруты для статистики
 	api.HandleFunc("/stats/week", statsHandler.GetCurrentWeekStats).Methods("GET", "OPTIONS")
 	api.HandleFunc("/stats/month", statsHandler.GetCurrentMonthStats).Methods("GET", "OPTIONS")
 	api.HandleFunc("/stats/custom", statsHandler.GetCustomStats).Methods("GET", "OPTIONS")
+	api.HandleFunc("/stats/earnings", statsHandler.GetEarnings).Methods("GET", "OPTIONS")
+
+	// Маршруты для выгрузки в CSV и XLSX
+	api.Handle("/export/entries", verified(exportHandler.ExportEntries)).Methods("GET", "OPTIONS")
+	api.Handle("/export/report", verified(exportHandler.ExportReport)).Methods("GET", "OPTIONS")
+
+	// Маршрут для импорта из CSV и других трекеров
+	api.Handle("/import", verified(importHandler.Import)).Methods("POST", "OPTIONS")
+	api.Handle("/import/calendar", verified(importHandler.ImportCalendar)).Methods("POST", "OPTIONS")
+
+	// Маршруты для запланированного времени
+	api.HandleFunc("/planned", planningHandler.GetBlocks).Methods("GET", "OPTIONS")
+	api.HandleFunc("/planned/delete", planningHandler.DeleteBlock).Methods("POST", "OPTIONS")
+
+	// Маршруты для токенов календаря
+	api.HandleFunc("/calendar/tokens", calendarHandler.GetTokens).Methods("GET", "OPTIONS")
+	api.Handle("/calendar/tokens/create", verified(calendarHandler.CreateToken)).Methods("POST", "OPTIONS")
+	api.HandleFunc("/calendar/tokens/revoke", calendarHandler.RevokeToken).Methods("POST", "OPTIONS")
 
 	// Маршруты для категорий
 	api.HandleFunc("/categories", categoryHandler.GetCategories).Methods("GET", "OPTIONS")
 	api.HandleFunc("/categories/create", categoryHandler.CreateCategory).Methods("POST", "OPTIONS")
 	api.HandleFunc("/categories/update", categoryHandler.UpdateCategory).Methods("POST", "OPTIONS")
 	api.HandleFunc("/categories/delete", categoryHandler.DeleteCategory).Methods("POST", "OPTIONS")
+	api.HandleFunc("/categories/rate", categoryHandler.SetCategoryRate).Methods("POST", "OPTIONS")
+
+	// Маршруты для меток
+	api.HandleFunc("/tags", tagHandler.GetTags).Methods("GET", "OPTIONS")
+	api.HandleFunc("/tags/create", tagHandler.CreateTag).Methods("POST", "OPTIONS")
+	api.HandleFunc("/tags/update", tagHandler.UpdateTag).Methods("POST", "OPTIONS")
+	api.HandleFunc("/tags/delete", tagHandler.DeleteTag).Methods("POST", "OPTIONS")
+
+	// Маршруты для клиентов и проектов
+	api.HandleFunc("/clients", projectHandler.GetClients).Methods("GET", "OPTIONS")
+	api.HandleFunc("/clients/create", projectHandler.CreateClient).Methods("POST", "OPTIONS")
+	api.HandleFunc("/clients/update", projectHandler.UpdateClient).Methods("POST", "OPTIONS")
+	api.HandleFunc("/clients/delete", projectHandler.DeleteClient).Methods("POST", "OPTIONS")
+	api.HandleFunc("/projects", projectHandler.GetProjects).Methods("GET", "OPTIONS")
+	api.HandleFunc("/projects/create", projectHandler.CreateProject).Methods("POST", "OPTIONS")
+	api.HandleFunc("/projects/update", projectHandler.UpdateProject).Methods("POST", "OPTIONS")
+	api.HandleFunc("/projects/delete", projectHandler.DeleteProject).Methods("POST", "OPTIONS")
 
 	// Добавляем эндпоинт для проверки работоспособности
 	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -139,6 +299,55 @@ func main() {
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  120 * time.Second,
 	}
+	// Открытые потоки событий не завершаются сами и задерживали бы остановку сервера
+	srv.RegisterOnShutdown(eventsHandler.Close)
+
+	// Фоновые задачи работают до завершения сервера
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	// Изменения, сделанные другими экземплярами сервера, передаются клиентам этого экземпляра
+	if *notifyChanges {
+		connStr := database.ConnectionString(*dbHost, *dbPort, *dbUser, *dbPassword, *dbName)
+		listener, err := database.NewNotificationListener(connStr, time.Second, time.Minute)
+		if err != nil {
+			log.Fatalf("Failed to listen for database notifications: %v", err)
+		}
+		defer listener.Close()
+
+		go listener.Run(jobsCtx)
+		go events.RelayNotifications(jobsCtx, listener, hub, repo.Origin())
+		log.Printf("Уведомления об изменениях других экземпляров сервера включены")
+	}
+
+	go runPeriodically(jobsCtx, "idle_pause", *idleCheckInterval, func(ctx context.Context, now time.Time) error {
+		paused, err := timeService.PauseIdleEntries(ctx, now)
+		if paused > 0 {
+			log.Printf("Приостановлено записей без активности: %d", paused)
+		}
+		return err
+	})
+
+	go runPeriodically(jobsCtx, "auto_stop", *autoStopInterval, func(ctx context.Context, now time.Time) error {
+		stopped, err := timeService.AutoStopEntries(ctx, now)
+		if stopped > 0 {
+			log.Printf("Автоматически завершено забытых записей: %d", stopped)
+		}
+		return err
+	})
+
+	go runPeriodically(jobsCtx, "rate_limit_cleanup", 10*time.Minute, func(ctx context.Context, now time.Time) error {
+		limitStore.Cleanup(now)
+		return nil
+	})
+
+	go runPeriodically(jobsCtx, "session_cleanup", *sessionCleanup, func(ctx context.Context, now time.Time) error {
+		deleted, err := authService.CleanupSessions(ctx, now)
+		if deleted > 0 {
+			log.Printf("Удалено истекших сессий: %d", deleted)
+		}
+		return err
+	})
 
 	// Запуск сервера в горутине
 	go func() {
@@ -153,6 +362,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down server...")
+	stopJobs()
 
 	// Контекст с таймаутом для завершения работы сервера
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
@@ -21,7 +22,7 @@ func NewAuthMiddleware(authService *auth.Service) *AuthMiddleware {
 	}
 }
 
-// Authenticate проверяет JWT токен в запросе и добавляет ID пользователя в контекст
+// Authenticate проверяет JWT токен в запросе и добавляет ID пользователя и сессии в контекст
 func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		log.Printf("AuthMiddleware: Получен запрос %s %s", r.Method, r.URL.Path)
@@ -47,17 +48,43 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 		log.Printf("AuthMiddleware: Получен токен: %s...", tokenString[:20])
 
 		// Проверяем токен
-		userID, err := m.authService.ValidateToken(tokenString)
+		claims, err := m.authService.ValidateToken(r.Context(), tokenString)
 		if err != nil {
 			log.Printf("AuthMiddleware: Недействительный токен: %v", err)
 			http.Error(w, "Недействительный токен: "+err.Error(), http.StatusUnauthorized)
 			return
 		}
 
-		log.Printf("AuthMiddleware: Токен валиден, userID=%d", userID)
+		log.Printf("AuthMiddleware: Токен валиден, userID=%d, sessionID=%d", claims.UserID, claims.SessionID)
 
-		// Добавляем ID пользователя в контекст запроса
-		ctx := context.WithValue(r.Context(), "user_id", userID)
+		// Добавляем ID пользователя и сессии в контекст запроса
+		ctx := context.WithValue(r.Context(), "user_id", claims.UserID)
+		ctx = context.WithValue(ctx, "session_id", claims.SessionID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// RequireVerifiedEmail закрывает доступ к обработчику пользователям с неподтвержденным email,
+// если этого требует политика сервиса (см. auth.UnverifiedPolicy). Применяется после Authenticate.
+func (m *AuthMiddleware) RequireVerifiedEmail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("user_id").(uint)
+		if !ok {
+			http.Error(w, "Необходима аутентификация", http.StatusUnauthorized)
+			return
+		}
+
+		if err := m.authService.CheckEmailVerified(r.Context(), userID); err != nil {
+			if errors.Is(err, auth.ErrEmailNotVerified) {
+				log.Printf("AuthMiddleware: Пользователь %d не подтвердил email, доступ к %s закрыт", userID, r.URL.Path)
+				http.Error(w, "Подтвердите email, чтобы пользоваться этой функцией", http.StatusForbidden)
+				return
+			}
+			log.Printf("AuthMiddleware: Ошибка при проверке подтверждения email пользователя %d: %v", userID, err)
+			http.Error(w, "Ошибка при проверке подтверждения email", http.StatusInternalServerError)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
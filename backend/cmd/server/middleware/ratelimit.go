@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/pkg/ratelimit"
+)
+
+// trustedProxies - сети прокси, которым разрешено передавать адрес клиента в X-Forwarded-For.
+// Задается один раз при запуске сервера.
+var trustedProxies []*net.IPNet
+
+// SetTrustedProxies задает доверенные прокси списком адресов и подсетей через запятую,
+// например "10.0.0.0/8, 192.0.2.10". Пустой список отключает чтение X-Forwarded-For.
+func SetTrustedProxies(list string) error {
+	var nets []*net.IPNet
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return fmt.Errorf("некорректный адрес прокси %q", item)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(item)
+		if err != nil {
+			return fmt.Errorf("некорректная подсеть прокси %q", item)
+		}
+		nets = append(nets, ipNet)
+	}
+	trustedProxies = nets
+	return nil
+}
+
+// isTrustedProxy сообщает, что адрес принадлежит доверенному прокси
+func isTrustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP возвращает адрес клиента. X-Forwarded-For учитывается, только если соединение
+// пришло от доверенного прокси: тогда адресом клиента считается последний адрес цепочки,
+// не принадлежащий доверенным прокси. Иначе заголовок может подделать сам клиент.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !isTrustedProxy(host) {
+		return host
+	}
+
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		host = hop
+		if !isTrustedProxy(hop) {
+			break
+		}
+	}
+	return host
+}
+
+// TooManyRequests отвечает 429 с заголовком Retry-After (в целых секундах, с округлением вверх)
+func TooManyRequests(w http.ResponseWriter, message string, retryAfter time.Duration) {
+	seconds := int64((retryAfter + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	http.Error(w, message, http.StatusTooManyRequests)
+}
+
+// KeyFunc определяет, чьи запросы считаются вместе
+type KeyFunc func(r *http.Request) string
+
+// ByIP считает запросы по адресу клиента
+func ByIP(r *http.Request) string {
+	return "ip:" + ClientIP(r)
+}
+
+// ByUser считает запросы по пользователю из контекста (после Authenticate), иначе по адресу клиента
+func ByUser(r *http.Request) string {
+	if userID, ok := r.Context().Value("user_id").(uint); ok {
+		return fmt.Sprintf("user:%d", userID)
+	}
+	return ByIP(r)
+}
+
+// RateLimitMiddleware ограничивает частоту запросов к группе маршрутов
+type RateLimitMiddleware struct {
+	limiter *ratelimit.Limiter
+	key     KeyFunc
+}
+
+// NewRateLimitMiddleware создает middleware, которое считает запросы по ключу key
+func NewRateLimitMiddleware(limiter *ratelimit.Limiter, key KeyFunc) *RateLimitMiddleware {
+	return &RateLimitMiddleware{
+		limiter: limiter,
+		key:     key,
+	}
+}
+
+// Limit отклоняет запросы сверх ограничения ответом 429. Если хранилище недоступно,
+// запрос пропускается: ограничение не должно останавливать сервис.
+func (m *RateLimitMiddleware) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := m.key(r)
+		allowed, retryAfter, err := m.limiter.Allow(r.Context(), key, time.Now())
+		if err != nil {
+			log.Printf("RateLimitMiddleware: Ошибка при проверке ограничения %s для %s: %v", m.limiter.Name(), key, err)
+		} else if !allowed {
+			log.Printf("RateLimitMiddleware: Превышено ограничение %s для %s (%s %s)", m.limiter.Name(), key, r.Method, r.URL.Path)
+			TooManyRequests(w, "Слишком много запросов, повторите позже", retryAfter)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
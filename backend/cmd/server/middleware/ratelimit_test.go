@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/pkg/ratelimit"
+)
+
+// setTrustedProxies задает доверенные прокси на время теста
+func setTrustedProxies(t *testing.T, list string) {
+	t.Helper()
+	previous := trustedProxies
+	if err := SetTrustedProxies(list); err != nil {
+		t.Fatalf("SetTrustedProxies(%q) error = %v", list, err)
+	}
+	t.Cleanup(func() { trustedProxies = previous })
+}
+
+// TestClientIP тестирует определение адреса клиента
+func TestClientIP(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.0.2.1:54321"
+	if ip := ClientIP(req); ip != "192.0.2.1" {
+		t.Errorf("ClientIP() = %q, хотели 192.0.2.1", ip)
+	}
+
+	// Без доверенных прокси заголовок игнорируется
+	req.Header.Set("X-Forwarded-For", "203.0.113.5")
+	if ip := ClientIP(req); ip != "192.0.2.1" {
+		t.Errorf("ClientIP() без доверенных прокси = %q, хотели 192.0.2.1", ip)
+	}
+
+	// За доверенным прокси берется последний адрес не из доверенных сетей
+	setTrustedProxies(t, "10.0.0.0/8, 192.0.2.1")
+	req.Header.Set("X-Forwarded-For", " 198.51.100.7, 203.0.113.5 , 10.0.0.1")
+	if ip := ClientIP(req); ip != "203.0.113.5" {
+		t.Errorf("ClientIP() = %q, хотели 203.0.113.5", ip)
+	}
+
+	// Запрос не от доверенного прокси не может подставить адрес
+	req.RemoteAddr = "198.51.100.9:54321"
+	if ip := ClientIP(req); ip != "198.51.100.9" {
+		t.Errorf("ClientIP() от недоверенного адреса = %q, хотели 198.51.100.9", ip)
+	}
+
+	if err := SetTrustedProxies("10.0.0.0/40"); err == nil {
+		t.Error("SetTrustedProxies() с некорректной подсетью должен вернуть ошибку")
+	}
+}
+
+// TestRateLimit тестирует отклонение запросов сверх ограничения
+func TestRateLimit(t *testing.T) {
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), "login", ratelimit.Limit{Requests: 2, Per: time.Hour})
+	handler := NewRateLimitMiddleware(limiter, ByIP).Limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func(method, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/auth/login", nil)
+		req.RemoteAddr = ip + ":54321"
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for i := 0; i < 2; i++ {
+		if rr := request("POST", "192.0.2.1"); rr.Code != http.StatusOK {
+			t.Fatalf("Запрос #%d: статус %d, хотели 200", i+1, rr.Code)
+		}
+	}
+
+	rr := request("POST", "192.0.2.1")
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Запрос сверх ограничения: статус %d, хотели 429", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") != "1800" {
+		t.Errorf("Retry-After = %q, хотели 1800", rr.Header().Get("Retry-After"))
+	}
+
+	// Предварительные запросы CORS и другие клиенты не ограничиваются
+	if rr := request("OPTIONS", "192.0.2.1"); rr.Code != http.StatusOK {
+		t.Errorf("OPTIONS: статус %d, хотели 200", rr.Code)
+	}
+	if rr := request("POST", "192.0.2.2"); rr.Code != http.StatusOK {
+		t.Errorf("Другой клиент: статус %d, хотели 200", rr.Code)
+	}
+
+	// Подделанный X-Forwarded-For не сбрасывает ограничение
+	for _, spoofed := range []string{"203.0.113.1", "203.0.113.2"} {
+		req := httptest.NewRequest("POST", "/api/auth/login", nil)
+		req.RemoteAddr = "192.0.2.1:54321"
+		req.Header.Set("X-Forwarded-For", spoofed)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusTooManyRequests {
+			t.Errorf("Запрос с X-Forwarded-For %s: статус %d, хотели 429", spoofed, rr.Code)
+		}
+	}
+}
+
+// TestByUser тестирует ключ ограничения для аутентифицированных запросов
+func TestByUser(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/time/status", nil)
+	req.RemoteAddr = "192.0.2.1:54321"
+	if key := ByUser(req); key != "ip:192.0.2.1" {
+		t.Errorf("ByUser() без пользователя = %q, хотели ip:192.0.2.1", key)
+	}
+
+	req = req.WithContext(context.WithValue(req.Context(), "user_id", uint(7)))
+	if key := ByUser(req); key != "user:7" {
+		t.Errorf("ByUser() = %q, хотели user:7", key)
+	}
+}
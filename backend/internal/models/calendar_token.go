@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// CalendarToken - секретный токен ссылки на календарь (ICS) с записями пользователя.
+// Сам токен показывается один раз при создании, в базе хранится только его хеш.
+// Токен не связан с JWT и не дает доступа к API; удаление токена отзывает ссылку.
+type CalendarToken struct {
+	ID        uint      `json:"id"`
+	UserID    uint      `json:"user_id"`
+	Name      string    `json:"name"`
+	TokenHash string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
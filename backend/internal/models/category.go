@@ -6,10 +6,11 @@ import (
 
 // Category представляет категорию для записей времени
 type Category struct {
-	ID        uint      `json:"id"`
-	UserID    uint      `json:"user_id"`
-	Name      string    `json:"name"`
-	Color     string    `json:"color"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID         uint      `json:"id"`
+	UserID     uint      `json:"user_id"`
+	Name       string    `json:"name"`
+	Color      string    `json:"color"`
+	HourlyRate *Money    `json:"hourly_rate,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
 }
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// Client представляет клиента, для которого пользователь ведет проекты
+type Client struct {
+	ID         uint      `json:"id"`
+	UserID     uint      `json:"user_id"`
+	Name       string    `json:"name"`
+	HourlyRate *Money    `json:"hourly_rate,omitempty"` // ставка по умолчанию для проектов клиента
+	Archived   bool      `json:"archived"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// EmailVerificationToken - одноразовый токен подтверждения email из письма. Как и для
+// PasswordResetToken, в базе хранится только хеш токена.
+type EmailVerificationToken struct {
+	ID        uint       `json:"id"`
+	UserID    uint       `json:"user_id"`
+	TokenHash string     `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidMoney возникает при разборе некорректной денежной суммы
+var ErrInvalidMoney = errors.New("некорректная денежная сумма")
+
+// Money представляет денежную сумму в минимальных единицах валюты (копейках, центах).
+// Целочисленное представление исключает ошибки округления float64;
+// в JSON и в базе данных сумма передается строкой с двумя знаками после точки, например "1250.50".
+type Money int64
+
+// ParseMoney разбирает сумму вида "1250", "1250.5" или "1250,50".
+// Больше двух знаков после разделителя не допускается.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(strings.Replace(s, ",", ".", 1))
+	if s == "" {
+		return 0, ErrInvalidMoney
+	}
+
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if whole == "" || (hasFrac && (frac == "" || len(frac) > 2)) {
+		return 0, ErrInvalidMoney
+	}
+	for len(frac) < 2 {
+		frac += "0"
+	}
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || strings.ContainsAny(whole, "+-") {
+		return 0, ErrInvalidMoney
+	}
+	cents, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil || strings.ContainsAny(frac, "+-") {
+		return 0, ErrInvalidMoney
+	}
+
+	value := units*100 + cents
+	if negative {
+		value = -value
+	}
+	return Money(value), nil
+}
+
+// String возвращает сумму с двумя знаками после точки
+func (m Money) String() string {
+	sign := ""
+	value := int64(m)
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, value/100, value%100)
+}
+
+// MarshalJSON сериализует сумму строкой
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(m.String())), nil
+}
+
+// UnmarshalJSON принимает сумму как строкой, так и числовым литералом
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+
+	value, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = value
+	return nil
+}
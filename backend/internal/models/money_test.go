@@ -0,0 +1,62 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Money
+		wantErr bool
+	}{
+		{"1250", 125000, false},
+		{"1250.5", 125050, false},
+		{"1250,50", 125050, false},
+		{"0.07", 7, false},
+		{"-3.10", -310, false},
+		{"", 0, true},
+		{"1.234", 0, true},
+		{"abc", 0, true},
+		{"1.", 0, true},
+		{".5", 0, true},
+		{"1.-5", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseMoney(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMoney(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseMoney(%q) = %d, хотели %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	var payload struct {
+		Rate Money `json:"rate"`
+	}
+
+	// Принимаются и строка, и число
+	for _, input := range []string{`{"rate":"85.5"}`, `{"rate":85.50}`} {
+		if err := json.Unmarshal([]byte(input), &payload); err != nil {
+			t.Fatalf("Unmarshal(%s) error = %v", input, err)
+		}
+		if payload.Rate != 8550 {
+			t.Errorf("Unmarshal(%s) = %d, хотели 8550", input, payload.Rate)
+		}
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("Marshal error = %v", err)
+	}
+	if string(data) != `{"rate":"85.50"}` {
+		t.Errorf("Marshal = %s, хотели {\"rate\":\"85.50\"}", data)
+	}
+}
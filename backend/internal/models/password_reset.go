@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// PasswordResetToken - одноразовый токен сброса пароля из письма. Сам токен передается
+// только в ссылке письма, в базе хранится его хеш; токен действует до ExpiresAt
+// и перестает действовать после использования.
+type PasswordResetToken struct {
+	ID        uint       `json:"id"`
+	UserID    uint       `json:"user_id"`
+	TokenHash string     `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
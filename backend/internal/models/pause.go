@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+)
+
+// PauseReasonIdle - причина перерыва, поставленного автоматически из-за бездействия пользователя
+const PauseReasonIdle = "idle"
+
+// Pause представляет один перерыв внутри записи о рабочем времени
+type Pause struct {
+	ID          uint      `json:"id"`
+	TimeEntryID uint      `json:"time_entry_id"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time,omitempty"` // пусто, пока перерыв продолжается
+	Reason      string    `json:"reason,omitempty"`   // пусто для перерывов, поставленных пользователем
+}
+
+// IsOpen сообщает, продолжается ли перерыв
+func (p *Pause) IsOpen() bool {
+	return p.EndTime.IsZero()
+}
+
+// DurationWithin возвращает длительность перерыва в секундах в пределах интервала [from, to].
+// Незавершенный перерыв считается продолжающимся до to.
+func (p *Pause) DurationWithin(from, to time.Time) int64 {
+	start := p.StartTime
+	if start.Before(from) {
+		start = from
+	}
+
+	end := p.EndTime
+	if end.IsZero() || end.After(to) {
+		end = to
+	}
+
+	if !end.After(start) {
+		return 0
+	}
+
+	return int64(end.Sub(start).Seconds())
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// PlannedBlock - запланированный интервал времени (например, встреча из импортированного календаря).
+// В отличие от записи о времени, не учитывается в статистике и отчетах.
+type PlannedBlock struct {
+	ID          uint      `json:"id"`
+	UserID      uint      `json:"user_id"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	CategoryID  *uint     `json:"category_id,omitempty"`
+	Category    *Category `json:"category,omitempty"`
+	Description string    `json:"description,omitempty"`
+	// SourceUID - UID события календаря, из которого создан интервал
+	SourceUID string    `json:"source_uid,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
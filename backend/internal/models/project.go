@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// Project представляет проект, к которому привязываются записи времени.
+// Категории внутри проекта используются как типы задач.
+type Project struct {
+	ID          uint     `json:"id"`
+	UserID      uint     `json:"user_id"`
+	ClientID    *uint    `json:"client_id,omitempty"`
+	Name        string   `json:"name"`
+	Color       string   `json:"color"`
+	HourlyRate  *Money   `json:"hourly_rate,omitempty"`
+	BudgetHours *float64 `json:"budget_hours,omitempty"`
+	Archived    bool     `json:"archived"`
+	// CategoryIDs - категории, допустимые для записей проекта; пустой список разрешает любые
+	CategoryIDs []uint    `json:"category_ids"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// AllowsCategory проверяет, можно ли использовать категорию в записях проекта
+func (p *Project) AllowsCategory(categoryID *uint) bool {
+	if categoryID == nil || len(p.CategoryIDs) == 0 {
+		return true
+	}
+	for _, id := range p.CategoryIDs {
+		if id == *categoryID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// Session - сессия входа пользователя на устройстве. Клиент получает короткоживущий токен доступа (JWT)
+// с идентификатором сессии и токен обновления, который меняется при каждом обновлении.
+// В базе хранится только хеш текущего токена обновления; удаление сессии отзывает оба токена.
+type Session struct {
+	ID               uint      `json:"id"`
+	UserID           uint      `json:"user_id"`
+	RefreshTokenHash string    `json:"-"`
+	UserAgent        string    `json:"user_agent"` // устройство и браузер клиента
+	IP               string    `json:"ip"`         // адрес клиента при последнем использовании
+	RememberMe       bool      `json:"remember_me"`
+	CreatedAt        time.Time `json:"created_at"`
+	LastUsedAt       time.Time `json:"last_used_at"`
+	ExpiresAt        time.Time `json:"expires_at"`
+
+	// Current - сессия, из которой выполнен запрос; не хранится в базе
+	Current bool `json:"current"`
+}
+
+// IsExpired сообщает, что срок действия сессии истек к моменту now
+func (s *Session) IsExpired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// Tag представляет метку, которую можно назначить записям времени
+type Tag struct {
+	ID        uint      `json:"id"`
+	UserID    uint      `json:"user_id"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -32,8 +32,20 @@ type TimeEntry struct {
 	Status      Status    `json:"status"`
 	CategoryID  *uint     `json:"category_id,omitempty"`
 	Category    *Category `json:"category,omitempty"`
+	ProjectID   *uint     `json:"project_id,omitempty"`
+	Billable    bool      `json:"billable"`
+	Description string    `json:"description,omitempty"`
+	Tags        []Tag     `json:"tags,omitempty"`
+	Pauses      []Pause   `json:"pauses,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+
+	// LastHeartbeat - время последнего сигнала активности клиента для незавершенной записи
+	LastHeartbeat time.Time `json:"last_heartbeat,omitempty"`
+	// AutoStopped - запись завершена автоматически (забытый таймер) и требует проверки пользователем
+	AutoStopped bool `json:"auto_stopped"`
+	// Version - версия записи для оптимистической блокировки, увеличивается при каждом изменении
+	Version int `json:"version"`
 }
 
 // CalculateDuration возвращает общее отработанное время в секундах
@@ -57,10 +69,129 @@ func (t *TimeEntry) CalculateDuration() int64 {
 	}
 
 	totalDuration := endTime.Sub(t.StartTime).Seconds()
-	result := int64(totalDuration) - t.TotalPaused
+	paused := t.PausedDurationUntil(endTime)
+	result := int64(totalDuration) - paused
 
 	log.Printf("CalculateDuration: Расчет: %.2f сек - %d сек паузы = %d сек итого",
-		totalDuration, t.TotalPaused, result)
+		totalDuration, paused, result)
 
 	return result
 }
+
+// PausedDurationUntil возвращает длительность перерывов в секундах до момента endTime.
+// Если у записи есть интервалы перерывов, длительность считается по ним,
+// иначе используется накопленное значение TotalPaused (записи, созданные до появления интервалов).
+func (t *TimeEntry) PausedDurationUntil(endTime time.Time) int64 {
+	if len(t.Pauses) == 0 {
+		return t.TotalPaused
+	}
+
+	var paused int64
+	for i := range t.Pauses {
+		paused += t.Pauses[i].DurationWithin(t.StartTime, endTime)
+	}
+
+	return paused
+}
+
+// PausedDuration возвращает общую длительность перерывов записи в секундах
+// (для незавершенной записи - по текущий момент)
+func (t *TimeEntry) PausedDuration() int64 {
+	endTime := t.EndTime
+	if endTime.IsZero() {
+		endTime = timeNow()
+	}
+	return t.PausedDurationUntil(endTime)
+}
+
+// OpenPause возвращает текущий незавершенный перерыв или nil
+func (t *TimeEntry) OpenPause() *Pause {
+	for i := range t.Pauses {
+		if t.Pauses[i].IsOpen() {
+			return &t.Pauses[i]
+		}
+	}
+	return nil
+}
+
+// IdlePause возвращает незавершенный перерыв, поставленный из-за бездействия, или nil
+func (t *TimeEntry) IdlePause() *Pause {
+	pause := t.OpenPause()
+	if pause == nil || pause.Reason != PauseReasonIdle {
+		return nil
+	}
+	return pause
+}
+
+// DaySlice содержит часть записи, пришедшуюся на один календарный день
+type DaySlice struct {
+	Day    string // YYYY-MM-DD
+	Worked int64  // отработанное время в секундах
+	Paused int64  // время перерывов в секундах
+}
+
+// SplitByDay распределяет запись по календарным дням часового пояса loc, которые она пересекает.
+// Отработанное время дня - часть интервала записи за этот день минус перерывы, пришедшиеся на него.
+// У старых записей без интервалов перерывов TotalPaused распределяется пропорционально
+// длительности частей. Сумма Worked по всем дням равна CalculateDuration().
+func (t *TimeEntry) SplitByDay(loc *time.Location) []DaySlice {
+	var endTime time.Time
+	switch {
+	case !t.EndTime.IsZero():
+		endTime = t.EndTime
+	case t.Status == StatusPaused:
+		endTime = t.PausedAt
+	default:
+		endTime = timeNow()
+	}
+
+	total := int64(endTime.Sub(t.StartTime).Seconds()) - t.PausedDurationUntil(endTime)
+	if !endTime.After(t.StartTime) {
+		return []DaySlice{{Day: t.StartTime.In(loc).Format("2006-01-02"), Worked: total}}
+	}
+
+	var slices []DaySlice
+	var elapsed []int64
+	for sliceStart := t.StartTime; sliceStart.Before(endTime); {
+		local := sliceStart.In(loc)
+		sliceEnd := time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, loc)
+		if sliceEnd.After(endTime) {
+			sliceEnd = endTime
+		}
+
+		slice := DaySlice{Day: local.Format("2006-01-02")}
+		for i := range t.Pauses {
+			slice.Paused += t.Pauses[i].DurationWithin(sliceStart, sliceEnd)
+		}
+		slices = append(slices, slice)
+		elapsed = append(elapsed, int64(sliceEnd.Sub(sliceStart).Seconds()))
+		sliceStart = sliceEnd
+	}
+
+	// Перерывы без интервалов: неизвестно, когда они были, поэтому делим пропорционально
+	if len(t.Pauses) == 0 && t.TotalPaused > 0 {
+		totalElapsed := int64(endTime.Sub(t.StartTime).Seconds())
+		var assigned int64
+		for i := range slices {
+			if i == len(slices)-1 {
+				slices[i].Paused = t.TotalPaused - assigned
+				break
+			}
+			if totalElapsed > 0 {
+				slices[i].Paused = t.TotalPaused * elapsed[i] / totalElapsed
+			}
+			assigned += slices[i].Paused
+		}
+	}
+
+	// Доли секунды на границах дней отбрасываются при округлении каждой части;
+	// остаток относим к последнему дню, чтобы сумма совпадала с длительностью записи
+	var sum int64
+	for i := range slices {
+		slices[i].Worked = elapsed[i] - slices[i].Paused
+		sum += slices[i].Worked
+	}
+	slices[len(slices)-1].Worked += total - sum
+
+	return slices
+}
@@ -65,6 +65,46 @@ func TestTimeEntry_CalculateDuration(t *testing.T) {
 			mockNow:          baseTime.Add(90 * time.Minute),
 			expectedDuration: 4800, // 90 минут - 10 минут = 80 минут в секундах
 		},
+		{
+			name: "Завершенная запись с интервалами перерывов",
+			entry: TimeEntry{
+				StartTime:   baseTime,
+				EndTime:     baseTime.Add(4 * time.Hour),
+				Status:      StatusCompleted,
+				TotalPaused: 99999, // игнорируется при наличии интервалов
+				Pauses: []Pause{
+					{StartTime: baseTime.Add(1 * time.Hour), EndTime: baseTime.Add(75 * time.Minute)},
+					{StartTime: baseTime.Add(2 * time.Hour), EndTime: baseTime.Add(150 * time.Minute)},
+				},
+			},
+			expectedDuration: 11700, // 4 часа - 15 минут - 30 минут
+		},
+		{
+			name: "Приостановленная запись с открытым перерывом",
+			entry: TimeEntry{
+				StartTime: baseTime,
+				Status:    StatusPaused,
+				PausedAt:  baseTime.Add(2 * time.Hour),
+				Pauses: []Pause{
+					{StartTime: baseTime.Add(30 * time.Minute), EndTime: baseTime.Add(40 * time.Minute)},
+					{StartTime: baseTime.Add(2 * time.Hour)},
+				},
+			},
+			mockNow:          baseTime.Add(3 * time.Hour),
+			expectedDuration: 6600, // 2 часа до паузы - 10 минут первого перерыва
+		},
+		{
+			name: "Перерыв за пределами записи обрезается",
+			entry: TimeEntry{
+				StartTime: baseTime,
+				EndTime:   baseTime.Add(time.Hour),
+				Status:    StatusCompleted,
+				Pauses: []Pause{
+					{StartTime: baseTime.Add(50 * time.Minute), EndTime: baseTime.Add(2 * time.Hour)},
+				},
+			},
+			expectedDuration: 3000, // 1 час - 10 минут перерыва внутри записи
+		},
 	}
 
 	// Сохраняем оригинальную функцию time.Now
@@ -90,3 +130,38 @@ func TestTimeEntry_CalculateDuration(t *testing.T) {
 		})
 	}
 }
+
+func TestTimeEntry_SplitByDay(t *testing.T) {
+	// Доли секунды на границе дня не должны теряться: сумма по дням равна длительности записи
+	start := time.Date(2023, 1, 1, 23, 59, 59, 600000000, time.UTC)
+	entry := TimeEntry{
+		StartTime: start,
+		EndTime:   start.Add(2*time.Hour + 700*time.Millisecond),
+		Status:    StatusCompleted,
+	}
+
+	slices := entry.SplitByDay(time.UTC)
+	if len(slices) != 2 || slices[0].Day != "2023-01-01" || slices[1].Day != "2023-01-02" {
+		t.Fatalf("SplitByDay() = %+v, ожидались 2023-01-01 и 2023-01-02", slices)
+	}
+
+	var sum int64
+	for _, slice := range slices {
+		sum += slice.Worked
+	}
+	if sum != entry.CalculateDuration() {
+		t.Errorf("Сумма по дням = %d, ожидалось %d", sum, entry.CalculateDuration())
+	}
+
+	// Приостановленная запись считается до момента паузы
+	paused := TimeEntry{
+		StartTime: time.Date(2023, 1, 1, 23, 0, 0, 0, time.UTC),
+		PausedAt:  time.Date(2023, 1, 2, 0, 30, 0, 0, time.UTC),
+		Status:    StatusPaused,
+		Pauses:    []Pause{{StartTime: time.Date(2023, 1, 2, 0, 30, 0, 0, time.UTC)}},
+	}
+	slices = paused.SplitByDay(time.UTC)
+	if len(slices) != 2 || slices[0].Worked != 3600 || slices[1].Worked != 1800 {
+		t.Errorf("SplitByDay() = %+v, ожидалось 3600 и 1800 секунд", slices)
+	}
+}
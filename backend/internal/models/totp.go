@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// TOTP - настройки двухфакторной аутентификации пользователя по одноразовым кодам
+// приложения-аутентификатора. Пока EnabledAt не задан, настройка не подтверждена кодом
+// и при входе не используется.
+type TOTP struct {
+	UserID    uint       `json:"user_id"`
+	Secret    string     `json:"-"`
+	EnabledAt *time.Time `json:"enabled_at,omitempty"`
+	// LastStep - шаг времени последнего принятого кода; коды этого и более ранних шагов
+	// не принимаются, чтобы перехваченный код нельзя было использовать повторно
+	LastStep  int64     `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Enabled сообщает, подтверждена ли двухфакторная аутентификация
+func (t *TOTP) Enabled() bool {
+	return t != nil && t.EnabledAt != nil
+}
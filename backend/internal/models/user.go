@@ -1,14 +1,104 @@
 package models
 
 import (
+	"fmt"
 	"time"
 )
 
+// DefaultTimezone - часовой пояс пользователя, если он не выбран
+const DefaultTimezone = "UTC"
+
+// DefaultIdleTimeout - порог бездействия в минутах, после которого запись приостанавливается автоматически
+const DefaultIdleTimeout = 15
+
+// DefaultMaxEntryDuration - наибольшая длительность записи в минутах, после которой она завершается автоматически
+const DefaultMaxEntryDuration = 24 * 60
+
 // User представляет пользователя системы
 type User struct {
-	ID        uint      `json:"id"`
-	Email     string    `json:"email"`
-	Password  string    `json:"-"` // Пароль не будет отправляться в JSON
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID       uint   `json:"id"`
+	Email    string `json:"email"`
+	Password string `json:"-"` // Пароль не будет отправляться в JSON
+	// HourlyRate - ставка пользователя по умолчанию, если не задана ставка проекта, клиента или категории
+	HourlyRate *Money `json:"hourly_rate,omitempty"`
+	// Timezone - часовой пояс IANA (например, "Europe/Moscow"), в котором считаются границы дней в статистике
+	Timezone string `json:"timezone"`
+	// EmailVerifiedAt - момент подтверждения email по ссылке из письма; nil - адрес не подтвержден
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+
+	// IdleTimeout - через сколько минут без сигналов активности запись приостанавливается; 0 отключает автопаузу
+	IdleTimeout int `json:"idle_timeout"`
+	// MaxEntryDuration - через сколько минут после начала незавершенная запись завершается автоматически; 0 - без ограничения
+	MaxEntryDuration int `json:"max_entry_duration"`
+	// DailyCutoff - время суток ЧЧ:ММ в часовом поясе пользователя, в которое незавершенная запись
+	// завершается автоматически; пустая строка отключает
+	DailyCutoff string `json:"daily_cutoff"`
+}
+
+// EmailVerified сообщает, подтвержден ли email пользователя
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// LoadTimezone возвращает часовой пояс по названию IANA; пустое название означает DefaultTimezone.
+// Название "Local" не принимается: часовой пояс сервера не должен влиять на расчеты пользователя.
+func LoadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		name = DefaultTimezone
+	}
+	if name == "Local" {
+		return nil, fmt.Errorf("неизвестный часовой пояс %q", name)
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("неизвестный часовой пояс %q", name)
+	}
+	return loc, nil
+}
+
+// Location возвращает часовой пояс пользователя; некорректное значение заменяется на DefaultTimezone
+func (u *User) Location() *time.Location {
+	loc, err := LoadTimezone(u.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// ParseDailyCutoff разбирает время суток в формате ЧЧ:ММ и возвращает часы и минуты
+func ParseDailyCutoff(value string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("некорректное время %q: ожидается ЧЧ:ММ", value)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// AutoStopTime возвращает момент, в который незавершенная запись, начатая в start, должна быть
+// завершена автоматически: раньшее из start + MaxEntryDuration и первого наступления DailyCutoff
+// после start в часовом поясе пользователя. Нулевое время означает, что ограничений нет.
+func (u *User) AutoStopTime(start time.Time) time.Time {
+	var stopAt time.Time
+	if u.MaxEntryDuration > 0 {
+		stopAt = start.Add(time.Duration(u.MaxEntryDuration) * time.Minute)
+	}
+
+	if u.DailyCutoff != "" {
+		hour, minute, err := ParseDailyCutoff(u.DailyCutoff)
+		if err == nil {
+			local := start.In(u.Location())
+			cutoff := time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, local.Location())
+			if !cutoff.After(start) {
+				cutoff = time.Date(local.Year(), local.Month(), local.Day()+1, hour, minute, 0, 0, local.Location())
+			}
+			if stopAt.IsZero() || cutoff.Before(stopAt) {
+				stopAt = cutoff
+			}
+		}
+	}
+
+	return stopAt
 }
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUser_AutoStopTime(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatalf("не удалось загрузить часовой пояс: %v", err)
+	}
+	// 9:00 по Москве
+	start := time.Date(2025, 3, 10, 6, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		user User
+		want time.Time
+	}{
+		{
+			name: "БезОграничений",
+			user: User{},
+			want: time.Time{},
+		},
+		{
+			name: "НаибольшаяДлительность",
+			user: User{MaxEntryDuration: 600},
+			want: start.Add(10 * time.Hour),
+		},
+		{
+			name: "ВремяОтсечкиВЧасовомПоясе",
+			user: User{Timezone: "Europe/Moscow", DailyCutoff: "18:30"},
+			want: time.Date(2025, 3, 10, 18, 30, 0, 0, moscow),
+		},
+		{
+			name: "ОтсечкаНаСледующийДень",
+			user: User{Timezone: "Europe/Moscow", DailyCutoff: "08:00"},
+			want: time.Date(2025, 3, 11, 8, 0, 0, 0, moscow),
+		},
+		{
+			name: "РаньшееИзДвух",
+			user: User{Timezone: "Europe/Moscow", MaxEntryDuration: 120, DailyCutoff: "18:30"},
+			want: start.Add(2 * time.Hour),
+		},
+		{
+			name: "НекорректнаяОтсечкаИгнорируется",
+			user: User{DailyCutoff: "25:00"},
+			want: time.Time{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.AutoStopTime(start); !got.Equal(tt.want) {
+				t.Errorf("AutoStopTime() = %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,401 @@
+// Package testutil содержит общие заготовки для тестов сервисов
+package testutil
+
+import (
+	"context"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/pkg/database"
+)
+
+// Repository реализует database.Repository методами, которые ничего не делают и возвращают
+// нулевые значения. Моки в тестах пакетов встраивают его и переопределяют только методы,
+// которые использует тестируемый код.
+type Repository struct{}
+
+// WithTx выполняет fn с этим же репозиторием. Моки, хранящие состояние, переопределяют
+// метод, чтобы fn получал их самих
+func (r *Repository) WithTx(ctx context.Context, fn func(repo database.Repository) error) error {
+	return fn(r)
+}
+
+// CreateUser реализует database.Repository
+func (r *Repository) CreateUser(ctx context.Context, user *models.User) error {
+	return nil
+}
+
+// GetUserByID реализует database.Repository
+func (r *Repository) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	return nil, nil
+}
+
+// GetUserByEmail реализует database.Repository
+func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return nil, nil
+}
+
+// UpdateUser реализует database.Repository
+func (r *Repository) UpdateUser(ctx context.Context, user *models.User) error {
+	return nil
+}
+
+// DeleteUser реализует database.Repository
+func (r *Repository) DeleteUser(ctx context.Context, id uint) error {
+	return nil
+}
+
+// CreateTimeEntry реализует database.Repository
+func (r *Repository) CreateTimeEntry(ctx context.Context, entry *models.TimeEntry) error {
+	return nil
+}
+
+// GetTimeEntryByID реализует database.Repository
+func (r *Repository) GetTimeEntryByID(ctx context.Context, id uint) (*models.TimeEntry, error) {
+	return nil, nil
+}
+
+// GetTimeEntriesByUserID реализует database.Repository
+func (r *Repository) GetTimeEntriesByUserID(ctx context.Context, userID uint) ([]*models.TimeEntry, error) {
+	return nil, nil
+}
+
+// GetActiveTimeEntryForUser реализует database.Repository
+func (r *Repository) GetActiveTimeEntryForUser(ctx context.Context, userID uint) (*models.TimeEntry, error) {
+	return nil, nil
+}
+
+// ListTimeEntries реализует database.Repository
+func (r *Repository) ListTimeEntries(ctx context.Context, filter database.TimeEntryFilter) ([]*models.TimeEntry, error) {
+	return nil, nil
+}
+
+// UpdateTimeEntry реализует database.Repository
+func (r *Repository) UpdateTimeEntry(ctx context.Context, entry *models.TimeEntry) error {
+	return nil
+}
+
+// DeleteTimeEntry реализует database.Repository
+func (r *Repository) DeleteTimeEntry(ctx context.Context, id uint) error {
+	return nil
+}
+
+// UpdateHeartbeat реализует database.Repository
+func (r *Repository) UpdateHeartbeat(ctx context.Context, entryID uint, at time.Time) error {
+	return nil
+}
+
+// ListIdleTimeEntries реализует database.Repository
+func (r *Repository) ListIdleTimeEntries(ctx context.Context, now time.Time) ([]*models.TimeEntry, error) {
+	return nil, nil
+}
+
+// ListUnfinishedTimeEntries реализует database.Repository
+func (r *Repository) ListUnfinishedTimeEntries(ctx context.Context) ([]*models.TimeEntry, error) {
+	return nil, nil
+}
+
+// LockUserEntries реализует database.Repository
+func (r *Repository) LockUserEntries(ctx context.Context, userID uint) error {
+	return nil
+}
+
+// CreatePause реализует database.Repository
+func (r *Repository) CreatePause(ctx context.Context, pause *models.Pause) error {
+	return nil
+}
+
+// UpdatePause реализует database.Repository
+func (r *Repository) UpdatePause(ctx context.Context, pause *models.Pause) error {
+	return nil
+}
+
+// ReplacePauses реализует database.Repository
+func (r *Repository) ReplacePauses(ctx context.Context, entryID uint, pauses []models.Pause) error {
+	return nil
+}
+
+// ImportTimeEntries реализует database.Repository
+func (r *Repository) ImportTimeEntries(ctx context.Context, categories []*models.Category, entries []*models.TimeEntry) error {
+	return nil
+}
+
+// GetUserStatsByPeriod реализует database.Repository
+func (r *Repository) GetUserStatsByPeriod(ctx context.Context, userID uint, from, to time.Time, tagIDs []uint) ([]*models.TimeEntry, error) {
+	return nil, nil
+}
+
+// GetCategoryStatsByPeriod реализует database.Repository
+func (r *Repository) GetCategoryStatsByPeriod(ctx context.Context, filter database.StatsFilter) ([]database.CategoryDuration, error) {
+	return nil, nil
+}
+
+// CreateCategory реализует database.Repository
+func (r *Repository) CreateCategory(ctx context.Context, category *models.Category) error {
+	return nil
+}
+
+// GetCategoryByID реализует database.Repository
+func (r *Repository) GetCategoryByID(ctx context.Context, id uint) (*models.Category, error) {
+	return nil, nil
+}
+
+// GetCategoriesByUserID реализует database.Repository
+func (r *Repository) GetCategoriesByUserID(ctx context.Context, userID uint) ([]*models.Category, error) {
+	return nil, nil
+}
+
+// UpdateCategory реализует database.Repository
+func (r *Repository) UpdateCategory(ctx context.Context, category *models.Category) error {
+	return nil
+}
+
+// DeleteCategory реализует database.Repository
+func (r *Repository) DeleteCategory(ctx context.Context, id uint) error {
+	return nil
+}
+
+// CreateTag реализует database.Repository
+func (r *Repository) CreateTag(ctx context.Context, tag *models.Tag) error {
+	return nil
+}
+
+// GetTagByID реализует database.Repository
+func (r *Repository) GetTagByID(ctx context.Context, id uint) (*models.Tag, error) {
+	return nil, nil
+}
+
+// GetTagsByUserID реализует database.Repository
+func (r *Repository) GetTagsByUserID(ctx context.Context, userID uint) ([]*models.Tag, error) {
+	return nil, nil
+}
+
+// UpdateTag реализует database.Repository
+func (r *Repository) UpdateTag(ctx context.Context, tag *models.Tag) error {
+	return nil
+}
+
+// DeleteTag реализует database.Repository
+func (r *Repository) DeleteTag(ctx context.Context, id uint) error {
+	return nil
+}
+
+// SetTimeEntryTags реализует database.Repository
+func (r *Repository) SetTimeEntryTags(ctx context.Context, entryID uint, tagIDs []uint) error {
+	return nil
+}
+
+// CreateClient реализует database.Repository
+func (r *Repository) CreateClient(ctx context.Context, client *models.Client) error {
+	return nil
+}
+
+// GetClientByID реализует database.Repository
+func (r *Repository) GetClientByID(ctx context.Context, id uint) (*models.Client, error) {
+	return nil, nil
+}
+
+// GetClientsByUserID реализует database.Repository
+func (r *Repository) GetClientsByUserID(ctx context.Context, userID uint) ([]*models.Client, error) {
+	return nil, nil
+}
+
+// UpdateClient реализует database.Repository
+func (r *Repository) UpdateClient(ctx context.Context, client *models.Client) error {
+	return nil
+}
+
+// DeleteClient реализует database.Repository
+func (r *Repository) DeleteClient(ctx context.Context, id uint) error {
+	return nil
+}
+
+// CreateProject реализует database.Repository
+func (r *Repository) CreateProject(ctx context.Context, project *models.Project) error {
+	return nil
+}
+
+// GetProjectByID реализует database.Repository
+func (r *Repository) GetProjectByID(ctx context.Context, id uint) (*models.Project, error) {
+	return nil, nil
+}
+
+// GetProjectsByUserID реализует database.Repository
+func (r *Repository) GetProjectsByUserID(ctx context.Context, userID uint) ([]*models.Project, error) {
+	return nil, nil
+}
+
+// UpdateProject реализует database.Repository
+func (r *Repository) UpdateProject(ctx context.Context, project *models.Project) error {
+	return nil
+}
+
+// DeleteProject реализует database.Repository
+func (r *Repository) DeleteProject(ctx context.Context, id uint) error {
+	return nil
+}
+
+// SetProjectCategories реализует database.Repository
+func (r *Repository) SetProjectCategories(ctx context.Context, projectID uint, categoryIDs []uint) error {
+	return nil
+}
+
+// CreateCalendarToken реализует database.Repository
+func (r *Repository) CreateCalendarToken(ctx context.Context, token *models.CalendarToken) error {
+	return nil
+}
+
+// GetCalendarTokensByUserID реализует database.Repository
+func (r *Repository) GetCalendarTokensByUserID(ctx context.Context, userID uint) ([]*models.CalendarToken, error) {
+	return nil, nil
+}
+
+// GetCalendarTokenByHash реализует database.Repository
+func (r *Repository) GetCalendarTokenByHash(ctx context.Context, tokenHash string) (*models.CalendarToken, error) {
+	return nil, nil
+}
+
+// DeleteCalendarToken реализует database.Repository
+func (r *Repository) DeleteCalendarToken(ctx context.Context, id uint) error {
+	return nil
+}
+
+// CreateSession реализует database.Repository
+func (r *Repository) CreateSession(ctx context.Context, session *models.Session) error {
+	return nil
+}
+
+// GetSessionByID реализует database.Repository
+func (r *Repository) GetSessionByID(ctx context.Context, id uint) (*models.Session, error) {
+	return nil, nil
+}
+
+// GetSessionByTokenHash реализует database.Repository
+func (r *Repository) GetSessionByTokenHash(ctx context.Context, tokenHash string) (*models.Session, error) {
+	return nil, nil
+}
+
+// GetSessionsByUserID реализует database.Repository
+func (r *Repository) GetSessionsByUserID(ctx context.Context, userID uint, now time.Time) ([]*models.Session, error) {
+	return nil, nil
+}
+
+// RotateSession реализует database.Repository
+func (r *Repository) RotateSession(ctx context.Context, session *models.Session, previousHash string) error {
+	return nil
+}
+
+// DeleteSession реализует database.Repository
+func (r *Repository) DeleteSession(ctx context.Context, id uint) error {
+	return nil
+}
+
+// DeleteUserSessions реализует database.Repository
+func (r *Repository) DeleteUserSessions(ctx context.Context, userID, exceptID uint) error {
+	return nil
+}
+
+// DeleteExpiredSessions реализует database.Repository
+func (r *Repository) DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error) {
+	return 0, nil
+}
+
+// CreatePasswordResetToken реализует database.Repository
+func (r *Repository) CreatePasswordResetToken(ctx context.Context, token *models.PasswordResetToken) error {
+	return nil
+}
+
+// UsePasswordResetToken реализует database.Repository
+func (r *Repository) UsePasswordResetToken(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordResetToken, error) {
+	return nil, nil
+}
+
+// DeletePasswordResetTokens реализует database.Repository
+func (r *Repository) DeletePasswordResetTokens(ctx context.Context, userID uint) error {
+	return nil
+}
+
+// CreateEmailVerificationToken реализует database.Repository
+func (r *Repository) CreateEmailVerificationToken(ctx context.Context, token *models.EmailVerificationToken) error {
+	return nil
+}
+
+// UseEmailVerificationToken реализует database.Repository
+func (r *Repository) UseEmailVerificationToken(ctx context.Context, tokenHash string, now time.Time) (*models.EmailVerificationToken, error) {
+	return nil, nil
+}
+
+// DeleteEmailVerificationTokens реализует database.Repository
+func (r *Repository) DeleteEmailVerificationTokens(ctx context.Context, userID uint) error {
+	return nil
+}
+
+// MarkEmailVerified реализует database.Repository
+func (r *Repository) MarkEmailVerified(ctx context.Context, userID uint, at time.Time) error {
+	return nil
+}
+
+// GetTOTP реализует database.Repository
+func (r *Repository) GetTOTP(ctx context.Context, userID uint) (*models.TOTP, error) {
+	return nil, nil
+}
+
+// SaveTOTP реализует database.Repository
+func (r *Repository) SaveTOTP(ctx context.Context, totp *models.TOTP) error {
+	return nil
+}
+
+// AdvanceTOTPStep реализует database.Repository
+func (r *Repository) AdvanceTOTPStep(ctx context.Context, userID uint, step int64) error {
+	return nil
+}
+
+// DeleteTOTP реализует database.Repository
+func (r *Repository) DeleteTOTP(ctx context.Context, userID uint) error {
+	return nil
+}
+
+// ReplaceRecoveryCodes реализует database.Repository
+func (r *Repository) ReplaceRecoveryCodes(ctx context.Context, userID uint, codeHashes []string) error {
+	return nil
+}
+
+// UseRecoveryCode реализует database.Repository
+func (r *Repository) UseRecoveryCode(ctx context.Context, userID uint, codeHash string, now time.Time) (bool, error) {
+	return false, nil
+}
+
+// UseLoginChallenge реализует database.Repository
+func (r *Repository) UseLoginChallenge(ctx context.Context, challengeID string, userID uint, expiresAt time.Time) (bool, error) {
+	return false, nil
+}
+
+// DeleteExpiredLoginChallenges реализует database.Repository
+func (r *Repository) DeleteExpiredLoginChallenges(ctx context.Context, now time.Time) error {
+	return nil
+}
+
+// CountRecoveryCodes реализует database.Repository
+func (r *Repository) CountRecoveryCodes(ctx context.Context, userID uint) (int, error) {
+	return 0, nil
+}
+
+// ImportPlannedBlocks реализует database.Repository
+func (r *Repository) ImportPlannedBlocks(ctx context.Context, blocks []*models.PlannedBlock) error {
+	return nil
+}
+
+// ListPlannedBlocks реализует database.Repository
+func (r *Repository) ListPlannedBlocks(ctx context.Context, userID uint, from, to time.Time) ([]*models.PlannedBlock, error) {
+	return nil, nil
+}
+
+// GetPlannedBlockByID реализует database.Repository
+func (r *Repository) GetPlannedBlockByID(ctx context.Context, id uint) (*models.PlannedBlock, error) {
+	return nil, nil
+}
+
+// DeletePlannedBlock реализует database.Repository
+func (r *Repository) DeletePlannedBlock(ctx context.Context, id uint) error {
+	return nil
+}
@@ -0,0 +1,11 @@
+-- Признак записи, завершенной автоматически из-за забытого таймера
+ALTER TABLE time_entries ADD COLUMN IF NOT EXISTS auto_stopped BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Наибольшая длительность записи в минутах, после которой она завершается автоматически (0 - без ограничения)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS max_entry_duration INTEGER NOT NULL DEFAULT 1440 CHECK (max_entry_duration >= 0);
+
+-- Время суток (ЧЧ:ММ в часовом поясе пользователя), в которое незавершенная запись завершается автоматически
+ALTER TABLE users ADD COLUMN IF NOT EXISTS daily_cutoff VARCHAR(5) NOT NULL DEFAULT '';
+
+-- Индекс для выборки автоматически завершенных записей на проверку
+CREATE INDEX IF NOT EXISTS idx_time_entries_auto_stopped ON time_entries(user_id, start_time) WHERE auto_stopped;
@@ -0,0 +1,6 @@
+-- Почасовые ставки пользователя и категорий (ставки клиентов и проектов - в projects.sql)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS hourly_rate NUMERIC(12, 2) NULL;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS hourly_rate NUMERIC(12, 2) NULL;
+
+-- Признак оплачиваемой записи; существующие записи считаются оплачиваемыми
+ALTER TABLE time_entries ADD COLUMN IF NOT EXISTS billable BOOLEAN NOT NULL DEFAULT TRUE;
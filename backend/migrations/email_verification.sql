@@ -0,0 +1,25 @@
+-- Момент подтверждения email; NULL - адрес не подтвержден.
+-- Пользователи, зарегистрированные до появления подтверждения, считаются подтвердившими email,
+-- чтобы -unverified_policy не закрыл им доступ. Отметка выполняется только при добавлении столбца.
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'users' AND column_name = 'email_verified_at'
+    ) THEN
+        ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+        UPDATE users SET email_verified_at = created_at;
+    END IF;
+END $$;
+
+-- Одноразовые токены подтверждения email; хранится только SHA-256 токена
+CREATE TABLE IF NOT EXISTS email_verification_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_verification_tokens_user_id ON email_verification_tokens(user_id);
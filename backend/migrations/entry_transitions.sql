@@ -0,0 +1,28 @@
+-- Версия записи для оптимистической блокировки: увеличивается при каждом изменении
+ALTER TABLE time_entries ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+-- Если у пользователя уже несколько незавершенных записей, незавершенной остается только последняя,
+-- а остальные завершаются и отмечаются как завершенные автоматически для проверки пользователем
+WITH duplicates AS (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY created_at DESC, id DESC) AS n
+        FROM time_entries
+        WHERE status <> 'completed'
+    ) ranked
+    WHERE n > 1
+)
+UPDATE time_entries
+SET status = 'completed',
+    end_time = COALESCE(paused_at, NOW() AT TIME ZONE 'UTC'),
+    paused_at = NULL,
+    auto_stopped = TRUE,
+    version = version + 1
+WHERE id IN (SELECT id FROM duplicates);
+
+UPDATE time_entry_pauses p
+SET end_time = te.end_time
+FROM time_entries te
+WHERE p.time_entry_id = te.id AND te.status = 'completed' AND p.end_time IS NULL;
+
+-- У пользователя может быть не больше одной незавершенной (активной или приостановленной) записи
+CREATE UNIQUE INDEX IF NOT EXISTS idx_time_entries_single_unfinished ON time_entries(user_id) WHERE status <> 'completed';
@@ -0,0 +1,11 @@
+-- Время последнего сигнала активности клиента для незавершенной записи
+ALTER TABLE time_entries ADD COLUMN IF NOT EXISTS last_heartbeat_at TIMESTAMP;
+
+-- Причина перерыва: пусто - перерыв поставлен пользователем, 'idle' - автоматическая пауза по бездействию
+ALTER TABLE time_entry_pauses ADD COLUMN IF NOT EXISTS reason VARCHAR(32) NOT NULL DEFAULT '';
+
+-- Порог бездействия пользователя в минутах, после которого запись приостанавливается (0 - не приостанавливать)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS idle_timeout INTEGER NOT NULL DEFAULT 15 CHECK (idle_timeout >= 0);
+
+-- Индекс для поиска записей, переставших присылать сигналы активности
+CREATE INDEX IF NOT EXISTS idx_time_entries_heartbeat ON time_entries(last_heartbeat_at) WHERE status = 'active';
@@ -0,0 +1,14 @@
+-- Запланированное время, импортированное из календарей (ICS)
+CREATE TABLE IF NOT EXISTS planned_blocks (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    start_time TIMESTAMP NOT NULL,
+    end_time TIMESTAMP NOT NULL,
+    category_id INTEGER NULL REFERENCES categories(id) ON DELETE SET NULL,
+    description TEXT NOT NULL DEFAULT '',
+    source_uid VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    CHECK (end_time > start_time)
+);
+
+CREATE INDEX IF NOT EXISTS idx_planned_blocks_user_start ON planned_blocks(user_id, start_time);
@@ -0,0 +1,40 @@
+-- Создание таблицы клиентов
+CREATE TABLE IF NOT EXISTS clients (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    hourly_rate NUMERIC(12, 2) NULL,
+    archived BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    UNIQUE (user_id, name)
+);
+
+-- Создание таблицы проектов
+CREATE TABLE IF NOT EXISTS projects (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    client_id INTEGER NULL REFERENCES clients(id) ON DELETE SET NULL,
+    name VARCHAR(255) NOT NULL,
+    color VARCHAR(20) NOT NULL,
+    hourly_rate NUMERIC(12, 2) NULL,
+    budget_hours NUMERIC(10, 2) NULL,
+    archived BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_projects_user_id ON projects(user_id);
+CREATE INDEX IF NOT EXISTS idx_projects_client_id ON projects(client_id);
+
+-- Категории, используемые как типы задач внутри проекта
+CREATE TABLE IF NOT EXISTS project_categories (
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    category_id INTEGER NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    PRIMARY KEY (project_id, category_id)
+);
+
+-- Привязка записей времени к проекту
+ALTER TABLE time_entries ADD COLUMN IF NOT EXISTS project_id INTEGER NULL REFERENCES projects(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_time_entries_project_id ON time_entries(project_id);
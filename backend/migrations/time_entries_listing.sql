@@ -0,0 +1,8 @@
+-- Описание записи о времени (используется для поиска по тексту)
+ALTER TABLE time_entries ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+
+-- Индекс для постраничной выборки записей пользователя в порядке (start_time, id)
+CREATE INDEX IF NOT EXISTS idx_time_entries_user_start_id ON time_entries(user_id, start_time, id);
+
+-- Индекс для быстрого поиска незавершенной записи пользователя
+CREATE INDEX IF NOT EXISTS idx_time_entries_user_unfinished ON time_entries(user_id) WHERE status <> 'completed';
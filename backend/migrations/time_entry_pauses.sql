@@ -0,0 +1,11 @@
+-- Создание таблицы для хранения интервалов перерывов внутри записей о времени
+CREATE TABLE IF NOT EXISTS time_entry_pauses (
+    id SERIAL PRIMARY KEY,
+    time_entry_id INTEGER NOT NULL REFERENCES time_entries(id) ON DELETE CASCADE,
+    start_time TIMESTAMP NOT NULL,
+    end_time TIMESTAMP, -- NULL, пока перерыв продолжается
+    CHECK (end_time IS NULL OR end_time >= start_time)
+);
+
+-- Индекс для загрузки перерывов записи
+CREATE INDEX IF NOT EXISTS idx_time_entry_pauses_entry_id ON time_entry_pauses(time_entry_id, start_time);
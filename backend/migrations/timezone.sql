@@ -0,0 +1,2 @@
+-- Часовой пояс пользователя (IANA), в котором считаются границы дней в статистике
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
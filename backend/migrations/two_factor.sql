@@ -0,0 +1,27 @@
+-- Двухфакторная аутентификация (TOTP); enabled_at NULL - настройка начата, но не подтверждена кодом
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret VARCHAR(64) NOT NULL,
+    enabled_at TIMESTAMP,
+    last_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL
+);
+
+-- Одноразовые коды восстановления для входа без приложения-аутентификатора; хранится только SHA-256 кода
+CREATE TABLE IF NOT EXISTS totp_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_totp_recovery_codes_user_id ON totp_recovery_codes(user_id);
+
+-- Использованные промежуточные токены входа: токен срабатывает один раз; строки после expires_at удаляются
+CREATE TABLE IF NOT EXISTS used_login_challenges (
+    challenge_id VARCHAR(64) PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_used_login_challenges_expires_at ON used_login_challenges(expires_at);
@@ -3,12 +3,15 @@ package auth
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/graywrk/timetracker/backend/internal/models"
 	"github.com/graywrk/timetracker/backend/pkg/database"
+	"github.com/graywrk/timetracker/backend/pkg/mail"
+	"github.com/graywrk/timetracker/backend/pkg/ratelimit"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -17,29 +20,74 @@ var (
 	ErrInvalidCredentials = errors.New("неверные учетные данные")
 	// ErrEmailAlreadyExists возникает при попытке регистрации с существующим email
 	ErrEmailAlreadyExists = errors.New("пользователь с таким email уже существует")
+	// ErrInvalidSettings возникает при передаче некорректных настроек пользователя
+	ErrInvalidSettings = errors.New("некорректные настройки пользователя")
 )
 
+// UserSettings содержит изменяемые пользователем настройки профиля
+type UserSettings struct {
+	HourlyRate *models.Money `json:"hourly_rate"` // nil снимает ставку
+	// Timezone - часовой пояс IANA; пустая строка оставляет текущий часовой пояс
+	Timezone string `json:"timezone"`
+	// IdleTimeout - порог бездействия в минутах (0 отключает автопаузу); nil оставляет текущее значение
+	IdleTimeout *int `json:"idle_timeout"`
+	// MaxEntryDuration - наибольшая длительность записи в минутах (0 - без ограничения); nil оставляет текущее значение
+	MaxEntryDuration *int `json:"max_entry_duration"`
+	// DailyCutoff - время автоматического завершения записей ЧЧ:ММ (пустая строка отключает); nil оставляет текущее значение
+	DailyCutoff *string `json:"daily_cutoff"`
+}
+
+// MaxIdleTimeout - наибольший порог бездействия в минутах (сутки)
+const MaxIdleTimeout = 24 * 60
+
 // Service предоставляет методы для аутентификации и авторизации
 type Service struct {
-	repo               database.Repository
-	jwtSecret          []byte
-	jwtExpires         time.Duration
-	jwtRememberExpires time.Duration
+	repo            database.Repository
+	jwtSecret       []byte
+	accessExpires   time.Duration
+	sessionExpires  time.Duration
+	rememberExpires time.Duration
+	mailer          mail.Mailer
+	mailConfig      MailConfig
+	// unverifiedPolicy - ограничения для пользователей с неподтвержденным email
+	unverifiedPolicy UnverifiedPolicy
+	// lockout блокирует вход после серии неудачных попыток; nil отключает блокировку
+	lockout *ratelimit.Lockout
 }
 
-// NewService создает новый сервис аутентификации
-func NewService(repo database.Repository, jwtSecret string, jwtExpires time.Duration, jwtRememberExpires time.Duration) *Service {
+// MailConfig - настройки писем пользователям
+type MailConfig struct {
+	// AppURL - адрес веб-интерфейса, на который ведут ссылки из писем
+	AppURL string
+	// ResetExpires - срок действия ссылки для сброса пароля
+	ResetExpires time.Duration
+	// VerifyExpires - срок действия ссылки для подтверждения email
+	VerifyExpires time.Duration
+}
+
+// NewService создает новый сервис аутентификации. accessExpires - срок действия токена доступа,
+// sessionExpires и rememberExpires - срок действия сессии без обновления токенов
+// при обычном входе и с опцией "Запомнить меня".
+func NewService(repo database.Repository, jwtSecret string, accessExpires, sessionExpires, rememberExpires time.Duration) *Service {
 	return &Service{
-		repo:               repo,
-		jwtSecret:          []byte(jwtSecret),
-		jwtExpires:         jwtExpires,
-		jwtRememberExpires: jwtRememberExpires,
+		repo:            repo,
+		jwtSecret:       []byte(jwtSecret),
+		accessExpires:   accessExpires,
+		sessionExpires:  sessionExpires,
+		rememberExpires: rememberExpires,
 	}
 }
 
+// SetMailer включает отправку писем пользователям (сброс пароля, подтверждение email)
+func (s *Service) SetMailer(mailer mail.Mailer, config MailConfig) {
+	s.mailer = mailer
+	s.mailConfig = config
+}
+
 // Claims представляет данные для JWT токена
 type Claims struct {
-	UserID uint `json:"user_id"`
+	UserID    uint `json:"user_id"`
+	SessionID uint `json:"sid"`
 	jwt.StandardClaims
 }
 
@@ -79,73 +127,110 @@ func (s *Service) Register(ctx context.Context, email, password string) (*models
 	}
 
 	log.Printf("Пользователь успешно зарегистрирован (ID: %d)", user.ID)
+
+	// Попытки подобрать пароль к еще не существовавшему адресу не должны мешать новому пользователю
+	s.resetFailures(ctx, passwordLockoutKey(email))
+
+	// Ошибка отправки не отменяет регистрацию: письмо можно запросить повторно (ResendVerification)
+	if s.mailer != nil {
+		if err := s.sendVerification(ctx, user); err != nil {
+			log.Printf("Не удалось отправить подтверждение email пользователю %d: %v", user.ID, err)
+		}
+	}
+
 	return user, nil
 }
 
-// Login аутентифицирует пользователя
-func (s *Service) Login(ctx context.Context, email, password string) (string, error) {
-	return s.LoginWithRememberMe(ctx, email, password, false)
+// Login аутентифицирует пользователя и открывает для него сессию
+func (s *Service) Login(ctx context.Context, email, password string, client ClientInfo) (*Tokens, error) {
+	return s.LoginWithRememberMe(ctx, email, password, false, client)
 }
 
-// LoginWithRememberMe аутентифицирует пользователя с опцией "Запомнить меня"
-func (s *Service) LoginWithRememberMe(ctx context.Context, email, password string, rememberMe bool) (string, error) {
+// LoginWithRememberMe аутентифицирует пользователя с опцией "Запомнить меня".
+// С этой опцией сессия действует дольше без обновления токенов.
+func (s *Service) LoginWithRememberMe(ctx context.Context, email, password string, rememberMe bool, client ClientInfo) (*Tokens, error) {
+	// Во время блокировки пароль не проверяется вовсе, иначе подбор продолжался бы
+	lockoutKey := passwordLockoutKey(email)
+	if err := s.checkLockout(ctx, lockoutKey); err != nil {
+		return nil, err
+	}
+
 	// Получаем пользователя по email
 	user, err := s.repo.GetUserByEmail(ctx, email)
 	if err != nil {
-		return "", ErrInvalidCredentials
+		s.recordFailure(ctx, lockoutKey)
+		return nil, ErrInvalidCredentials
 	}
 
 	// Проверяем пароль
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
 	if err != nil {
-		return "", ErrInvalidCredentials
+		s.recordFailure(ctx, lockoutKey)
+		return nil, ErrInvalidCredentials
 	}
+	s.resetFailures(ctx, lockoutKey)
 
-	// Выбираем срок действия токена в зависимости от флага "Запомнить меня"
-	expirationDuration := s.jwtExpires
-	if rememberMe {
-		expirationDuration = s.jwtRememberExpires
+	// Проверяется после пароля, чтобы ответ не раскрывал состояние чужого email
+	if s.unverifiedPolicy == UnverifiedDeny && !user.EmailVerified() {
+		return nil, ErrEmailNotVerified
 	}
 
-	// Создаем JWT токен
-	expirationTime := time.Now().Add(expirationDuration)
-	claims := &Claims{
-		UserID: user.ID,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: expirationTime.Unix(),
-		},
+	settings, err := s.repo.GetTOTP(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if settings.Enabled() {
+		log.Printf("Пароль пользователя %d верен, ожидается код второго фактора", user.ID)
+		return s.issueChallenge(user.ID, rememberMe)
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(s.jwtSecret)
+	tokens, err := s.createSession(ctx, user.ID, rememberMe, client)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return tokenString, nil
+	log.Printf("Открыта сессия %d пользователя %d", tokens.Session.ID, user.ID)
+	return tokens, nil
 }
 
-// ValidateToken проверяет JWT токен и возвращает ID пользователя
-func (s *Service) ValidateToken(tokenString string) (uint, error) {
+// ValidateToken проверяет JWT токен и действие его сессии
+func (s *Service) ValidateToken(ctx context.Context, tokenString string) (*Claims, error) {
 	claims := &Claims{}
 
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("неожиданный алгоритм подписи: %v", token.Header["alg"])
+		}
 		return s.jwtSecret, nil
 	})
 
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+
+	// Промежуточный токен входа с двухфакторной аутентификацией не дает доступа
+	if !token.Valid || claims.Audience != "" {
+		return nil, errors.New("недействительный токен")
 	}
 
-	if !token.Valid {
-		return 0, errors.New("недействительный токен")
+	// Токены без сессии выданы до появления сессий и не могут быть отозваны
+	if claims.SessionID == 0 {
+		return nil, ErrSessionRevoked
 	}
 
-	return claims.UserID, nil
+	session, err := s.repo.GetSessionByID(ctx, claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil || session.UserID != claims.UserID || session.IsExpired(timeNow()) {
+		return nil, ErrSessionRevoked
+	}
+
+	return claims, nil
 }
 
-// ChangePassword изменяет пароль пользователя
-func (s *Service) ChangePassword(ctx context.Context, userID uint, oldPassword, newPassword string) error {
+// ChangePassword изменяет пароль пользователя и завершает все его сессии, кроме sessionID
+func (s *Service) ChangePassword(ctx context.Context, userID, sessionID uint, oldPassword, newPassword string) error {
 	// Получаем пользователя по ID
 	user, err := s.repo.GetUserByID(ctx, userID)
 	if err != nil {
@@ -166,5 +251,67 @@ func (s *Service) ChangePassword(ctx context.Context, userID uint, oldPassword,
 
 	// Обновляем пароль пользователя
 	user.Password = string(hashedPassword)
-	return s.repo.UpdateUser(ctx, user)
+	if err := s.repo.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+
+	// Старый пароль мог быть известен на других устройствах
+	if err := s.repo.DeleteUserSessions(ctx, userID, sessionID); err != nil {
+		return err
+	}
+
+	log.Printf("Пароль пользователя %d изменен, остальные сессии завершены", userID)
+	return nil
+}
+
+// GetUser возвращает профиль пользователя
+func (s *Service) GetUser(ctx context.Context, userID uint) (*models.User, error) {
+	return s.repo.GetUserByID(ctx, userID)
+}
+
+// UpdateSettings сохраняет настройки профиля пользователя
+func (s *Service) UpdateSettings(ctx context.Context, userID uint, settings UserSettings) (*models.User, error) {
+	if settings.HourlyRate != nil && *settings.HourlyRate < 0 {
+		return nil, ErrInvalidSettings
+	}
+	if settings.Timezone != "" {
+		if _, err := models.LoadTimezone(settings.Timezone); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSettings, err)
+		}
+	}
+	if settings.IdleTimeout != nil && (*settings.IdleTimeout < 0 || *settings.IdleTimeout > MaxIdleTimeout) {
+		return nil, fmt.Errorf("%w: порог бездействия должен быть от 0 до %d минут", ErrInvalidSettings, MaxIdleTimeout)
+	}
+	if settings.MaxEntryDuration != nil && *settings.MaxEntryDuration < 0 {
+		return nil, fmt.Errorf("%w: наибольшая длительность записи не может быть отрицательной", ErrInvalidSettings)
+	}
+	if settings.DailyCutoff != nil && *settings.DailyCutoff != "" {
+		if _, _, err := models.ParseDailyCutoff(*settings.DailyCutoff); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSettings, err)
+		}
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user.HourlyRate = settings.HourlyRate
+	if settings.Timezone != "" {
+		user.Timezone = settings.Timezone
+	}
+	if settings.IdleTimeout != nil {
+		user.IdleTimeout = *settings.IdleTimeout
+	}
+	if settings.MaxEntryDuration != nil {
+		user.MaxEntryDuration = *settings.MaxEntryDuration
+	}
+	if settings.DailyCutoff != nil {
+		user.DailyCutoff = *settings.DailyCutoff
+	}
+	if err := s.repo.UpdateUser(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
 }
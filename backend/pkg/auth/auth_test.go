@@ -7,20 +7,42 @@ import (
 	"time"
 
 	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/internal/testutil"
+	"github.com/graywrk/timetracker/backend/pkg/database"
 )
 
 // MockRepository представляет мок репозитория для тестирования
 type MockRepository struct {
-	users  map[string]*models.User
-	nextID uint
-	err    error
+	testutil.Repository
+
+	users         map[string]*models.User
+	sessions      map[uint]*models.Session
+	resetTokens   map[uint]*models.PasswordResetToken
+	verifyTokens  map[uint]*models.EmailVerificationToken
+	totps         map[uint]*models.TOTP
+	recoveryCodes map[uint]map[string]bool // хеш кода -> использован
+	challenges    map[string]time.Time     // использованные токены входа -> срок действия
+	nextID        uint
+	nextSessionID uint
+	nextResetID   uint
+	nextVerifyID  uint
+	err           error
 }
 
 // NewMockRepository создает новый мок репозитория
 func NewMockRepository() *MockRepository {
 	return &MockRepository{
-		users:  make(map[string]*models.User),
-		nextID: 1,
+		users:         make(map[string]*models.User),
+		sessions:      make(map[uint]*models.Session),
+		resetTokens:   make(map[uint]*models.PasswordResetToken),
+		verifyTokens:  make(map[uint]*models.EmailVerificationToken),
+		totps:         make(map[uint]*models.TOTP),
+		recoveryCodes: make(map[uint]map[string]bool),
+		challenges:    make(map[string]time.Time),
+		nextID:        1,
+		nextSessionID: 1,
+		nextResetID:   1,
+		nextVerifyID:  1,
 	}
 }
 
@@ -92,61 +114,326 @@ func (m *MockRepository) DeleteUser(ctx context.Context, id uint) error {
 	return errors.New("пользователь не найден")
 }
 
-// Остальные методы интерфейса Repository, которые не используются в тестах
-
-func (m *MockRepository) CreateTimeEntry(ctx context.Context, entry *models.TimeEntry) error {
+// CreateSession мок метода
+func (m *MockRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	if m.err != nil {
+		return m.err
+	}
+	session.ID = m.nextSessionID
+	m.nextSessionID++
+	stored := *session
+	m.sessions[session.ID] = &stored
 	return nil
 }
 
-func (m *MockRepository) GetTimeEntryByID(ctx context.Context, id uint) (*models.TimeEntry, error) {
-	return nil, nil
+// GetSessionByID мок метода
+func (m *MockRepository) GetSessionByID(ctx context.Context, id uint) (*models.Session, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	session, exists := m.sessions[id]
+	if !exists {
+		return nil, nil
+	}
+	copied := *session
+	return &copied, nil
 }
 
-func (m *MockRepository) GetTimeEntriesByUserID(ctx context.Context, userID uint) ([]*models.TimeEntry, error) {
+// GetSessionByTokenHash мок метода
+func (m *MockRepository) GetSessionByTokenHash(ctx context.Context, tokenHash string) (*models.Session, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	for _, session := range m.sessions {
+		if session.RefreshTokenHash == tokenHash {
+			copied := *session
+			return &copied, nil
+		}
+	}
 	return nil, nil
 }
 
-func (m *MockRepository) GetActiveTimeEntryForUser(ctx context.Context, userID uint) (*models.TimeEntry, error) {
-	return nil, nil
+// GetSessionsByUserID мок метода
+func (m *MockRepository) GetSessionsByUserID(ctx context.Context, userID uint, now time.Time) ([]*models.Session, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	var sessions []*models.Session
+	for id := uint(1); id < m.nextSessionID; id++ {
+		session, exists := m.sessions[id]
+		if exists && session.UserID == userID && !session.IsExpired(now) {
+			copied := *session
+			sessions = append(sessions, &copied)
+		}
+	}
+	return sessions, nil
 }
 
-func (m *MockRepository) UpdateTimeEntry(ctx context.Context, entry *models.TimeEntry) error {
+// RotateSession мок метода
+func (m *MockRepository) RotateSession(ctx context.Context, session *models.Session, previousHash string) error {
+	if m.err != nil {
+		return m.err
+	}
+	stored, exists := m.sessions[session.ID]
+	if !exists || stored.RefreshTokenHash != previousHash {
+		return database.ErrConflict
+	}
+	updated := *session
+	m.sessions[session.ID] = &updated
 	return nil
 }
 
-func (m *MockRepository) DeleteTimeEntry(ctx context.Context, id uint) error {
+// DeleteSession мок метода
+func (m *MockRepository) DeleteSession(ctx context.Context, id uint) error {
+	if m.err != nil {
+		return m.err
+	}
+	delete(m.sessions, id)
 	return nil
 }
 
-func (m *MockRepository) GetUserStatsByPeriod(ctx context.Context, userID uint, startDate, endDate string) ([]*models.TimeEntry, error) {
-	return nil, nil
+// DeleteUserSessions мок метода
+func (m *MockRepository) DeleteUserSessions(ctx context.Context, userID, exceptID uint) error {
+	if m.err != nil {
+		return m.err
+	}
+	for id, session := range m.sessions {
+		if session.UserID == userID && id != exceptID {
+			delete(m.sessions, id)
+		}
+	}
+	return nil
 }
 
-// Методы для работы с категориями
-func (m *MockRepository) CreateCategory(ctx context.Context, category *models.Category) error {
+// DeleteExpiredSessions мок метода
+func (m *MockRepository) DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+	var deleted int64
+	for id, session := range m.sessions {
+		if session.IsExpired(now) {
+			delete(m.sessions, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// CreatePasswordResetToken мок метода
+func (m *MockRepository) CreatePasswordResetToken(ctx context.Context, token *models.PasswordResetToken) error {
+	if m.err != nil {
+		return m.err
+	}
+	token.ID = m.nextResetID
+	m.nextResetID++
+	stored := *token
+	m.resetTokens[token.ID] = &stored
 	return nil
 }
 
-func (m *MockRepository) GetCategoryByID(ctx context.Context, id uint) (*models.Category, error) {
+// UsePasswordResetToken мок метода
+func (m *MockRepository) UsePasswordResetToken(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordResetToken, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	for _, token := range m.resetTokens {
+		if token.TokenHash == tokenHash && token.UsedAt == nil && now.Before(token.ExpiresAt) {
+			usedAt := now
+			token.UsedAt = &usedAt
+			copied := *token
+			return &copied, nil
+		}
+	}
 	return nil, nil
 }
 
-func (m *MockRepository) GetCategoriesByUserID(ctx context.Context, userID uint) ([]*models.Category, error) {
+// DeletePasswordResetTokens мок метода
+func (m *MockRepository) DeletePasswordResetTokens(ctx context.Context, userID uint) error {
+	if m.err != nil {
+		return m.err
+	}
+	for id, token := range m.resetTokens {
+		if token.UserID == userID {
+			delete(m.resetTokens, id)
+		}
+	}
+	return nil
+}
+
+// CreateEmailVerificationToken мок метода
+func (m *MockRepository) CreateEmailVerificationToken(ctx context.Context, token *models.EmailVerificationToken) error {
+	if m.err != nil {
+		return m.err
+	}
+	token.ID = m.nextVerifyID
+	m.nextVerifyID++
+	stored := *token
+	m.verifyTokens[token.ID] = &stored
+	return nil
+}
+
+// UseEmailVerificationToken мок метода
+func (m *MockRepository) UseEmailVerificationToken(ctx context.Context, tokenHash string, now time.Time) (*models.EmailVerificationToken, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	for _, token := range m.verifyTokens {
+		if token.TokenHash == tokenHash && token.UsedAt == nil && now.Before(token.ExpiresAt) {
+			usedAt := now
+			token.UsedAt = &usedAt
+			copied := *token
+			return &copied, nil
+		}
+	}
 	return nil, nil
 }
 
-func (m *MockRepository) UpdateCategory(ctx context.Context, category *models.Category) error {
+// DeleteEmailVerificationTokens мок метода
+func (m *MockRepository) DeleteEmailVerificationTokens(ctx context.Context, userID uint) error {
+	if m.err != nil {
+		return m.err
+	}
+	for id, token := range m.verifyTokens {
+		if token.UserID == userID {
+			delete(m.verifyTokens, id)
+		}
+	}
+	return nil
+}
+
+// MarkEmailVerified мок метода
+func (m *MockRepository) MarkEmailVerified(ctx context.Context, userID uint, at time.Time) error {
+	if m.err != nil {
+		return m.err
+	}
+	for _, user := range m.users {
+		if user.ID == userID && user.EmailVerifiedAt == nil {
+			verifiedAt := at
+			user.EmailVerifiedAt = &verifiedAt
+		}
+	}
+	return nil
+}
+
+// GetTOTP мок метода
+func (m *MockRepository) GetTOTP(ctx context.Context, userID uint) (*models.TOTP, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	totp, exists := m.totps[userID]
+	if !exists {
+		return nil, nil
+	}
+	copied := *totp
+	return &copied, nil
+}
+
+// SaveTOTP мок метода
+func (m *MockRepository) SaveTOTP(ctx context.Context, totp *models.TOTP) error {
+	if m.err != nil {
+		return m.err
+	}
+	stored := *totp
+	m.totps[totp.UserID] = &stored
+	return nil
+}
+
+// AdvanceTOTPStep мок метода
+func (m *MockRepository) AdvanceTOTPStep(ctx context.Context, userID uint, step int64) error {
+	if m.err != nil {
+		return m.err
+	}
+	totp, exists := m.totps[userID]
+	if !exists || totp.LastStep >= step {
+		return database.ErrConflict
+	}
+	totp.LastStep = step
+	return nil
+}
+
+// DeleteTOTP мок метода
+func (m *MockRepository) DeleteTOTP(ctx context.Context, userID uint) error {
+	if m.err != nil {
+		return m.err
+	}
+	delete(m.totps, userID)
+	delete(m.recoveryCodes, userID)
 	return nil
 }
 
-func (m *MockRepository) DeleteCategory(ctx context.Context, id uint) error {
+// ReplaceRecoveryCodes мок метода
+func (m *MockRepository) ReplaceRecoveryCodes(ctx context.Context, userID uint, codeHashes []string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.recoveryCodes[userID] = make(map[string]bool)
+	for _, codeHash := range codeHashes {
+		m.recoveryCodes[userID][codeHash] = false
+	}
 	return nil
 }
 
+// UseRecoveryCode мок метода
+func (m *MockRepository) UseRecoveryCode(ctx context.Context, userID uint, codeHash string, now time.Time) (bool, error) {
+	if m.err != nil {
+		return false, m.err
+	}
+	used, exists := m.recoveryCodes[userID][codeHash]
+	if !exists || used {
+		return false, nil
+	}
+	m.recoveryCodes[userID][codeHash] = true
+	return true, nil
+}
+
+// UseLoginChallenge мок метода
+func (m *MockRepository) UseLoginChallenge(ctx context.Context, challengeID string, userID uint, expiresAt time.Time) (bool, error) {
+	if m.err != nil {
+		return false, m.err
+	}
+	if _, used := m.challenges[challengeID]; used {
+		return false, nil
+	}
+	m.challenges[challengeID] = expiresAt
+	return true, nil
+}
+
+// DeleteExpiredLoginChallenges мок метода
+func (m *MockRepository) DeleteExpiredLoginChallenges(ctx context.Context, now time.Time) error {
+	if m.err != nil {
+		return m.err
+	}
+	for id, expiresAt := range m.challenges {
+		if expiresAt.Before(now) {
+			delete(m.challenges, id)
+		}
+	}
+	return nil
+}
+
+// CountRecoveryCodes мок метода
+func (m *MockRepository) CountRecoveryCodes(ctx context.Context, userID uint) (int, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+	count := 0
+	for _, used := range m.recoveryCodes[userID] {
+		if !used {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *MockRepository) WithTx(ctx context.Context, fn func(repo database.Repository) error) error {
+	return fn(m)
+}
+
 // TestRegister тестирует функцию Register
 func TestRegister(t *testing.T) {
 	mockRepo := NewMockRepository()
-	service := NewService(mockRepo, "test-secret", 24*time.Hour, 30*24*time.Hour)
+	service := NewService(mockRepo, "test-secret", 15*time.Minute, 24*time.Hour, 30*24*time.Hour)
 	ctx := context.Background()
 
 	// Тест 1: Успешная регистрация
@@ -178,7 +465,7 @@ func TestRegister(t *testing.T) {
 // TestLogin тестирует функцию Login
 func TestLogin(t *testing.T) {
 	mockRepo := NewMockRepository()
-	service := NewService(mockRepo, "test-secret", 24*time.Hour, 30*24*time.Hour)
+	service := NewService(mockRepo, "test-secret", 15*time.Minute, 24*time.Hour, 30*24*time.Hour)
 	ctx := context.Background()
 
 	// Подготовка: Регистрируем пользователя
@@ -189,29 +476,29 @@ func TestLogin(t *testing.T) {
 	}
 
 	// Тест 1: Успешный вход
-	token, err := service.Login(ctx, "test@example.com", "password123")
+	tokens, err := service.Login(ctx, "test@example.com", "password123", ClientInfo{})
 	if err != nil {
-		t.Errorf("Login() error = %v, хотели nil", err)
+		t.Fatalf("Login() error = %v, хотели nil", err)
 	}
-	if token == "" {
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
 		t.Error("Login() вернул пустой токен")
 	}
 
 	// Тест 2: Неверный пароль
-	_, err = service.Login(ctx, "test@example.com", "wrongpassword")
+	_, err = service.Login(ctx, "test@example.com", "wrongpassword", ClientInfo{})
 	if err != ErrInvalidCredentials {
 		t.Errorf("Login() error = %v, хотели %v", err, ErrInvalidCredentials)
 	}
 
 	// Тест 3: Несуществующий пользователь
-	_, err = service.Login(ctx, "nonexistent@example.com", "password123")
+	_, err = service.Login(ctx, "nonexistent@example.com", "password123", ClientInfo{})
 	if err == nil {
 		t.Error("Login() не вернул ошибку для несуществующего пользователя")
 	}
 
 	// Тест 4: Ошибка репозитория
 	mockRepo.SetError(errors.New("ошибка базы данных"))
-	_, err = service.Login(ctx, "test@example.com", "password123")
+	_, err = service.Login(ctx, "test@example.com", "password123", ClientInfo{})
 	if err == nil {
 		t.Error("Login() не вернул ошибку при ошибке репозитория")
 	}
@@ -220,7 +507,7 @@ func TestLogin(t *testing.T) {
 // TestValidateToken тестирует функцию ValidateToken
 func TestValidateToken(t *testing.T) {
 	mockRepo := NewMockRepository()
-	service := NewService(mockRepo, "test-secret", 24*time.Hour, 30*24*time.Hour)
+	service := NewService(mockRepo, "test-secret", 15*time.Minute, 24*time.Hour, 30*24*time.Hour)
 	ctx := context.Background()
 
 	// Подготовка: Регистрируем пользователя и получаем токен
@@ -230,29 +517,30 @@ func TestValidateToken(t *testing.T) {
 		t.Fatalf("Не удалось зарегистрировать пользователя для теста: %v", err)
 	}
 
-	token, err := service.Login(ctx, "test@example.com", "password123")
+	tokens, err := service.Login(ctx, "test@example.com", "password123", ClientInfo{})
 	if err != nil {
 		t.Fatalf("Не удалось войти для теста: %v", err)
 	}
+	token := tokens.AccessToken
 
 	// Тест 1: Валидный токен
-	userID, err := service.ValidateToken(token)
+	claims, err := service.ValidateToken(ctx, token)
 	if err != nil {
-		t.Errorf("ValidateToken() error = %v, хотели nil", err)
+		t.Fatalf("ValidateToken() error = %v, хотели nil", err)
 	}
-	if userID != user.ID {
-		t.Errorf("ValidateToken() вернул userID = %v, хотели %v", userID, user.ID)
+	if claims.UserID != user.ID || claims.SessionID != tokens.Session.ID {
+		t.Errorf("ValidateToken() вернул %+v, хотели пользователя %v и сессию %v", claims, user.ID, tokens.Session.ID)
 	}
 
 	// Тест 2: Невалидный токен
-	_, err = service.ValidateToken("invalid-token")
+	_, err = service.ValidateToken(ctx, "invalid-token")
 	if err == nil {
 		t.Error("ValidateToken() не вернул ошибку для невалидного токена")
 	}
 
 	// Тест 3: Токен с неверной подписью
-	serviceWithDifferentSecret := NewService(mockRepo, "different-secret", 24*time.Hour, 30*24*time.Hour)
-	_, err = serviceWithDifferentSecret.ValidateToken(token)
+	serviceWithDifferentSecret := NewService(mockRepo, "different-secret", 15*time.Minute, 24*time.Hour, 30*24*time.Hour)
+	_, err = serviceWithDifferentSecret.ValidateToken(ctx, token)
 	if err == nil {
 		t.Error("ValidateToken() не вернул ошибку для токена с неверной подписью")
 	}
@@ -261,7 +549,7 @@ func TestValidateToken(t *testing.T) {
 // TestChangePassword тестирует функцию ChangePassword
 func TestChangePassword(t *testing.T) {
 	mockRepo := NewMockRepository()
-	service := NewService(mockRepo, "test-secret", 24*time.Hour, 30*24*time.Hour)
+	service := NewService(mockRepo, "test-secret", 15*time.Minute, 24*time.Hour, 30*24*time.Hour)
 	ctx := context.Background()
 
 	// Подготовка: Регистрируем пользователя
@@ -272,33 +560,71 @@ func TestChangePassword(t *testing.T) {
 	}
 
 	// Тест 1: Успешная смена пароля
-	err = service.ChangePassword(ctx, user.ID, "password123", "newpassword123")
+	err = service.ChangePassword(ctx, user.ID, 0, "password123", "newpassword123")
 	if err != nil {
 		t.Errorf("ChangePassword() error = %v, хотели nil", err)
 	}
 
 	// Проверяем, что новый пароль работает
-	_, err = service.Login(ctx, "test@example.com", "newpassword123")
+	_, err = service.Login(ctx, "test@example.com", "newpassword123", ClientInfo{})
 	if err != nil {
 		t.Errorf("Не удалось войти с новым паролем: %v", err)
 	}
 
 	// Тест 2: Неверный старый пароль
-	err = service.ChangePassword(ctx, user.ID, "wrongpassword", "anotherpassword")
+	err = service.ChangePassword(ctx, user.ID, 0, "wrongpassword", "anotherpassword")
 	if err == nil {
 		t.Error("ChangePassword() не вернул ошибку при неверном старом пароле")
 	}
 
 	// Тест 3: Несуществующий пользователь
-	err = service.ChangePassword(ctx, 999, "password123", "newpassword123")
+	err = service.ChangePassword(ctx, 999, 0, "password123", "newpassword123")
 	if err == nil {
 		t.Error("ChangePassword() не вернул ошибку для несуществующего пользователя")
 	}
 
 	// Тест 4: Ошибка репозитория
 	mockRepo.SetError(errors.New("ошибка базы данных"))
-	err = service.ChangePassword(ctx, user.ID, "newpassword123", "anotherpassword")
+	err = service.ChangePassword(ctx, user.ID, 0, "newpassword123", "anotherpassword")
 	if err == nil {
 		t.Error("ChangePassword() не вернул ошибку при ошибке репозитория")
 	}
 }
+
+// TestUpdateSettingsIdleTimeout тестирует изменение порога бездействия в настройках
+func TestUpdateSettingsIdleTimeout(t *testing.T) {
+	mockRepo := NewMockRepository()
+	service := NewService(mockRepo, "test-secret", 15*time.Minute, 24*time.Hour, 30*24*time.Hour)
+	ctx := context.Background()
+
+	user, err := service.Register(ctx, "test@example.com", "password123")
+	if err != nil {
+		t.Fatalf("Не удалось зарегистрировать пользователя для теста: %v", err)
+	}
+
+	timeout := 30
+	updated, err := service.UpdateSettings(ctx, user.ID, UserSettings{IdleTimeout: &timeout})
+	if err != nil {
+		t.Fatalf("UpdateSettings() error = %v, хотели nil", err)
+	}
+	if updated.IdleTimeout != 30 {
+		t.Errorf("IdleTimeout = %d, хотели 30", updated.IdleTimeout)
+	}
+
+	// Отсутствие поля оставляет порог без изменений
+	updated, err = service.UpdateSettings(ctx, user.ID, UserSettings{})
+	if err != nil {
+		t.Fatalf("UpdateSettings() error = %v, хотели nil", err)
+	}
+	if updated.IdleTimeout != 30 {
+		t.Errorf("IdleTimeout = %d, хотели 30", updated.IdleTimeout)
+	}
+
+	for _, invalid := range []int{-1, MaxIdleTimeout + 1} {
+		invalid := invalid
+		_, err = service.UpdateSettings(ctx, user.ID, UserSettings{IdleTimeout: &invalid})
+		if !errors.Is(err, ErrInvalidSettings) {
+			t.Errorf("UpdateSettings(%d) error = %v, хотели ErrInvalidSettings", invalid, err)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/pkg/database"
+	"github.com/graywrk/timetracker/backend/pkg/mail"
+)
+
+var (
+	// ErrInvalidVerificationToken возникает, если токен подтверждения email неизвестен, уже использован или истек
+	ErrInvalidVerificationToken = errors.New("ссылка для подтверждения email недействительна или устарела")
+	// ErrEmailNotVerified возникает, если действие недоступно до подтверждения email (см. UnverifiedPolicy)
+	ErrEmailNotVerified = errors.New("email не подтвержден")
+)
+
+// UnverifiedPolicy определяет, что доступно пользователю, не подтвердившему email
+type UnverifiedPolicy string
+
+const (
+	// UnverifiedAllow - ограничений нет, подтверждение email необязательно
+	UnverifiedAllow UnverifiedPolicy = "allow"
+	// UnverifiedRestrict - вход разрешен, но действия, для которых вызывается
+	// CheckEmailVerified, недоступны до подтверждения
+	UnverifiedRestrict UnverifiedPolicy = "restrict"
+	// UnverifiedDeny - вход запрещен до подтверждения email
+	UnverifiedDeny UnverifiedPolicy = "deny"
+)
+
+// ParseUnverifiedPolicy разбирает название политики для пользователей с неподтвержденным email
+func ParseUnverifiedPolicy(value string) (UnverifiedPolicy, error) {
+	switch policy := UnverifiedPolicy(value); policy {
+	case UnverifiedAllow, UnverifiedRestrict, UnverifiedDeny:
+		return policy, nil
+	}
+	return "", fmt.Errorf("неизвестная политика %q: ожидается allow, restrict или deny", value)
+}
+
+// SetUnverifiedPolicy задает ограничения для пользователей с неподтвержденным email
+func (s *Service) SetUnverifiedPolicy(policy UnverifiedPolicy) {
+	s.unverifiedPolicy = policy
+}
+
+// CheckEmailVerified возвращает ErrEmailNotVerified, если политика ограничивает пользователей
+// с неподтвержденным email, а email пользователя не подтвержден
+func (s *Service) CheckEmailVerified(ctx context.Context, userID uint) error {
+	if s.unverifiedPolicy != UnverifiedRestrict && s.unverifiedPolicy != UnverifiedDeny {
+		return nil
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.EmailVerified() {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+// sendVerification отправляет пользователю ссылку для подтверждения email
+func (s *Service) sendVerification(ctx context.Context, user *models.User) error {
+	token, tokenHash, err := newSecretToken()
+	if err != nil {
+		return err
+	}
+
+	now := timeNow()
+	verification := &models.EmailVerificationToken{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.mailConfig.VerifyExpires),
+	}
+	if err := s.repo.CreateEmailVerificationToken(ctx, verification); err != nil {
+		return err
+	}
+
+	link := strings.TrimRight(s.mailConfig.AppURL, "/") + "/verify-email?token=" + url.QueryEscape(token)
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "Подтверждение email в TimeTracker",
+		Body: fmt.Sprintf("Здравствуйте!\n\n"+
+			"Чтобы подтвердить email, перейдите по ссылке:\n%s\n\n"+
+			"Ссылка действует до %s.\n"+
+			"Если вы не регистрировались в TimeTracker, просто проигнорируйте это письмо.\n",
+			link, verification.ExpiresAt.UTC().Format("02.01.2006 15:04 UTC")),
+	}
+
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("ошибка при отправке письма для подтверждения email: %w", err)
+	}
+
+	log.Printf("Пользователю %d отправлена ссылка для подтверждения email", user.ID)
+	return nil
+}
+
+// ResendVerification повторно отправляет ссылку для подтверждения email. Как и ForgotPassword,
+// не возвращает ошибку для незарегистрированного или уже подтвержденного email.
+func (s *Service) ResendVerification(ctx context.Context, email string) error {
+	if s.mailer == nil {
+		return ErrMailNotConfigured
+	}
+
+	user, err := s.repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		log.Printf("Запрошено подтверждение email %s, пользователь не найден: %v", email, err)
+		return nil
+	}
+	if user.EmailVerified() {
+		log.Printf("Запрошено подтверждение email пользователя %d, email уже подтвержден", user.ID)
+		return nil
+	}
+
+	if err := s.sendVerification(ctx, user); err != nil {
+		log.Printf("Ошибка при повторной отправке подтверждения email пользователю %d: %v", user.ID, err)
+	}
+	return nil
+}
+
+// VerifyEmail подтверждает email по токену из письма. Токен действует один раз;
+// после подтверждения остальные токены пользователя удаляются.
+func (s *Service) VerifyEmail(ctx context.Context, token string) error {
+	if token == "" {
+		return ErrInvalidVerificationToken
+	}
+
+	var userID uint
+	err := s.repo.WithTx(ctx, func(repo database.Repository) error {
+		verification, err := repo.UseEmailVerificationToken(ctx, hashToken(token), timeNow())
+		if err != nil {
+			return err
+		}
+		if verification == nil {
+			return ErrInvalidVerificationToken
+		}
+		userID = verification.UserID
+
+		if err := repo.MarkEmailVerified(ctx, verification.UserID, timeNow()); err != nil {
+			return err
+		}
+		return repo.DeleteEmailVerificationTokens(ctx, verification.UserID)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Email пользователя %d подтвержден", userID)
+	return nil
+}
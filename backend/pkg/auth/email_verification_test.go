@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/pkg/mail"
+)
+
+// verifyTokenFromMessage извлекает токен подтверждения email из ссылки в письме
+func verifyTokenFromMessage(t *testing.T, msg mail.Message) string {
+	t.Helper()
+	return tokenFromMessage(t, msg, "/verify-email")
+}
+
+// newVerificationTestService создает сервис с отправкой писем и регистрирует test@example.com
+func newVerificationTestService(t *testing.T) (*Service, *MockRepository, *MockMailer, uint) {
+	mockRepo := NewMockRepository()
+	service := NewService(mockRepo, "test-secret", 15*time.Minute, 24*time.Hour, 30*24*time.Hour)
+	mailer := &MockMailer{}
+	service.SetMailer(mailer, MailConfig{AppURL: "https://tracker.example.com", VerifyExpires: 24 * time.Hour})
+
+	user, err := service.Register(context.Background(), "test@example.com", "password123")
+	if err != nil {
+		t.Fatalf("Не удалось зарегистрировать пользователя для теста: %v", err)
+	}
+	return service, mockRepo, mailer, user.ID
+}
+
+// TestVerifyEmail тестирует подтверждение email по ссылке, отправленной при регистрации
+func TestVerifyEmail(t *testing.T) {
+	now := time.Now()
+	setTimeNow(t, now)
+	service, mockRepo, mailer, userID := newVerificationTestService(t)
+	ctx := context.Background()
+
+	if len(mailer.messages) != 1 || mailer.messages[0].To != "test@example.com" {
+		t.Fatalf("Отправлены письма %+v, хотели одно на test@example.com", mailer.messages)
+	}
+	token := verifyTokenFromMessage(t, mailer.messages[0])
+
+	user, _ := service.GetUser(ctx, userID)
+	if user.EmailVerified() {
+		t.Fatal("Email нового пользователя не должен быть подтвержден")
+	}
+
+	if err := service.VerifyEmail(ctx, "unknown-token"); err != ErrInvalidVerificationToken {
+		t.Errorf("VerifyEmail() неизвестным токеном error = %v, хотели %v", err, ErrInvalidVerificationToken)
+	}
+
+	if err := service.VerifyEmail(ctx, token); err != nil {
+		t.Fatalf("VerifyEmail() error = %v, хотели nil", err)
+	}
+	user, _ = service.GetUser(ctx, userID)
+	if user.EmailVerifiedAt == nil || !user.EmailVerifiedAt.Equal(now) {
+		t.Errorf("EmailVerifiedAt = %v, хотели %v", user.EmailVerifiedAt, now)
+	}
+	if len(mockRepo.verifyTokens) != 0 {
+		t.Errorf("После подтверждения осталось %d токенов, хотели 0", len(mockRepo.verifyTokens))
+	}
+
+	// Токен одноразовый
+	if err := service.VerifyEmail(ctx, token); err != ErrInvalidVerificationToken {
+		t.Errorf("VerifyEmail() повторно error = %v, хотели %v", err, ErrInvalidVerificationToken)
+	}
+
+	// Для подтвержденного email повторное письмо не отправляется
+	if err := service.ResendVerification(ctx, "test@example.com"); err != nil {
+		t.Errorf("ResendVerification() error = %v, хотели nil", err)
+	}
+	if len(mailer.messages) != 1 {
+		t.Errorf("Отправлено %d писем, хотели 1", len(mailer.messages))
+	}
+}
+
+// TestResendVerification тестирует повторную отправку ссылки для подтверждения email
+func TestResendVerification(t *testing.T) {
+	now := time.Now()
+	setTimeNow(t, now)
+	service, _, mailer, _ := newVerificationTestService(t)
+	ctx := context.Background()
+
+	if err := service.ResendVerification(ctx, "test@example.com"); err != nil {
+		t.Fatalf("ResendVerification() error = %v, хотели nil", err)
+	}
+	if len(mailer.messages) != 2 {
+		t.Fatalf("Отправлено %d писем, хотели 2", len(mailer.messages))
+	}
+	expired := verifyTokenFromMessage(t, mailer.messages[0])
+	fresh := verifyTokenFromMessage(t, mailer.messages[1])
+
+	// Для неизвестного email письмо не отправляется, но ответ тот же
+	if err := service.ResendVerification(ctx, "unknown@example.com"); err != nil {
+		t.Errorf("ResendVerification() для неизвестного email error = %v, хотели nil", err)
+	}
+	// Ошибка отправки тоже не раскрывается
+	mailer.err = errors.New("SMTP недоступен")
+	if err := service.ResendVerification(ctx, "test@example.com"); err != nil {
+		t.Errorf("ResendVerification() при ошибке отправки error = %v, хотели nil", err)
+	}
+	mailer.err = nil
+	if len(mailer.messages) != 2 {
+		t.Errorf("Отправлено %d писем, хотели 2", len(mailer.messages))
+	}
+
+	// Истекший токен не действует, более новый еще действует
+	setTimeNow(t, now.Add(25*time.Hour))
+	if err := service.VerifyEmail(ctx, expired); err != ErrInvalidVerificationToken {
+		t.Errorf("VerifyEmail() истекшим токеном error = %v, хотели %v", err, ErrInvalidVerificationToken)
+	}
+	setTimeNow(t, now.Add(time.Hour))
+	if err := service.VerifyEmail(ctx, fresh); err != nil {
+		t.Errorf("VerifyEmail() error = %v, хотели nil", err)
+	}
+
+	service.SetMailer(nil, MailConfig{})
+	if err := service.ResendVerification(ctx, "test@example.com"); err != ErrMailNotConfigured {
+		t.Errorf("ResendVerification() без отправителя error = %v, хотели %v", err, ErrMailNotConfigured)
+	}
+}
+
+// TestUnverifiedPolicy тестирует ограничения для пользователей с неподтвержденным email
+func TestUnverifiedPolicy(t *testing.T) {
+	service, _, mailer, userID := newVerificationTestService(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		policy     UnverifiedPolicy
+		loginErr   error
+		restricted bool
+	}{
+		{UnverifiedAllow, nil, false},
+		{UnverifiedRestrict, nil, true},
+		{UnverifiedDeny, ErrEmailNotVerified, true},
+	}
+	for _, tt := range tests {
+		service.SetUnverifiedPolicy(tt.policy)
+
+		if _, err := service.Login(ctx, "test@example.com", "password123", ClientInfo{}); err != tt.loginErr {
+			t.Errorf("%s: Login() error = %v, хотели %v", tt.policy, err, tt.loginErr)
+		}
+		// Неверный пароль не раскрывает, что email не подтвержден
+		if _, err := service.Login(ctx, "test@example.com", "wrong", ClientInfo{}); err != ErrInvalidCredentials {
+			t.Errorf("%s: Login() с неверным паролем error = %v, хотели %v", tt.policy, err, ErrInvalidCredentials)
+		}
+		if err := service.CheckEmailVerified(ctx, userID); (err == ErrEmailNotVerified) != tt.restricted {
+			t.Errorf("%s: CheckEmailVerified() error = %v, ограничение %v", tt.policy, err, tt.restricted)
+		}
+	}
+
+	// После подтверждения ограничений нет
+	if err := service.VerifyEmail(ctx, verifyTokenFromMessage(t, mailer.messages[0])); err != nil {
+		t.Fatalf("VerifyEmail() error = %v, хотели nil", err)
+	}
+	if _, err := service.Login(ctx, "test@example.com", "password123", ClientInfo{}); err != nil {
+		t.Errorf("Login() после подтверждения error = %v, хотели nil", err)
+	}
+	if err := service.CheckEmailVerified(ctx, userID); err != nil {
+		t.Errorf("CheckEmailVerified() после подтверждения error = %v, хотели nil", err)
+	}
+
+	if _, err := ParseUnverifiedPolicy("strict"); err == nil {
+		t.Error("ParseUnverifiedPolicy() не вернул ошибку для неизвестной политики")
+	}
+	if policy, err := ParseUnverifiedPolicy("deny"); err != nil || policy != UnverifiedDeny {
+		t.Errorf("ParseUnverifiedPolicy(\"deny\") = %q, %v", policy, err)
+	}
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/pkg/ratelimit"
+)
+
+// ErrTooManyAttempts возникает, если вход в учетную запись временно заблокирован
+// после серии неудачных попыток
+var ErrTooManyAttempts = errors.New("слишком много неудачных попыток входа")
+
+// LockedError сообщает, через сколько можно повторить попытку входа.
+// errors.Is(err, ErrTooManyAttempts) возвращает true.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+// Error реализует error
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s, повторите через %s", ErrTooManyAttempts, e.RetryAfter.Round(time.Second))
+}
+
+// Unwrap позволяет проверять ошибку через errors.Is(err, ErrTooManyAttempts)
+func (e *LockedError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+// SetLockout включает блокировку входа после серии неверных паролей или кодов второго фактора
+func (s *Service) SetLockout(lockout *ratelimit.Lockout) {
+	s.lockout = lockout
+}
+
+// passwordLockoutKey возвращает ключ счетчика неверных паролей. Попытки считаются и для
+// несуществующих email, чтобы блокировка не раскрывала, зарегистрирован ли адрес.
+func passwordLockoutKey(email string) string {
+	return "password:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// twoFactorLockoutKey возвращает ключ счетчика неверных кодов второго фактора
+func twoFactorLockoutKey(userID uint) string {
+	return fmt.Sprintf("2fa:%d", userID)
+}
+
+// checkLockout возвращает LockedError, если попытки по ключу key временно заблокированы.
+// Ошибка хранилища не мешает входу: недоступность общего хранилища не должна закрывать сервис.
+func (s *Service) checkLockout(ctx context.Context, key string) error {
+	if s.lockout == nil {
+		return nil
+	}
+
+	wait, err := s.lockout.Check(ctx, key, timeNow())
+	if err != nil {
+		log.Printf("Ошибка при проверке блокировки входа %s: %v", key, err)
+		return nil
+	}
+	if wait > 0 {
+		log.Printf("Вход %s заблокирован еще на %v", key, wait)
+		return &LockedError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// recordFailure учитывает неудачную попытку по ключу key
+func (s *Service) recordFailure(ctx context.Context, key string) {
+	if s.lockout == nil {
+		return
+	}
+
+	lock, err := s.lockout.Fail(ctx, key, timeNow())
+	if err != nil {
+		log.Printf("Ошибка при учете неудачной попытки входа %s: %v", key, err)
+		return
+	}
+	if lock > 0 {
+		log.Printf("Вход %s заблокирован на %v после серии неудачных попыток", key, lock)
+	}
+}
+
+// resetFailures сбрасывает счетчик неудачных попыток по ключу key после успешной проверки
+func (s *Service) resetFailures(ctx context.Context, key string) {
+	if s.lockout == nil {
+		return
+	}
+
+	if err := s.lockout.Reset(ctx, key); err != nil {
+		log.Printf("Ошибка при сбросе счетчика неудачных попыток входа %s: %v", key, err)
+	}
+}
@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/pkg/ratelimit"
+)
+
+// enableLockout включает блокировку после трех неудачных попыток на минуту, с удвоением до 4 минут
+func enableLockout(service *Service) {
+	service.SetLockout(ratelimit.NewLockout(ratelimit.NewMemoryStore(), ratelimit.LockoutPolicy{
+		Threshold: 3,
+		Base:      time.Minute,
+		Max:       4 * time.Minute,
+	}))
+}
+
+// TestLoginLockout тестирует блокировку входа после серии неверных паролей
+func TestLoginLockout(t *testing.T) {
+	service, _, _ := newSessionTestService(t)
+	enableLockout(service)
+	ctx := context.Background()
+	now := time.Now()
+	setTimeNow(t, now)
+
+	for i := 0; i < 3; i++ {
+		if _, err := service.Login(ctx, "test@example.com", "wrong", ClientInfo{}); err != ErrInvalidCredentials {
+			t.Fatalf("Login() #%d error = %v, хотели %v", i+1, err, ErrInvalidCredentials)
+		}
+	}
+
+	// Во время блокировки не принимается даже верный пароль, в том числе в другом регистре email
+	_, err := service.Login(ctx, "Test@Example.com", "password123", ClientInfo{})
+	var locked *LockedError
+	if !errors.As(err, &locked) || !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("Login() во время блокировки error = %v, хотели %v", err, ErrTooManyAttempts)
+	}
+	if locked.RetryAfter != time.Minute {
+		t.Errorf("RetryAfter = %v, хотели 1m", locked.RetryAfter)
+	}
+
+	// После блокировки следующая неудача блокирует вдвое дольше
+	setTimeNow(t, now.Add(time.Minute))
+	if _, err := service.Login(ctx, "test@example.com", "wrong", ClientInfo{}); err != ErrInvalidCredentials {
+		t.Fatalf("Login() после блокировки error = %v, хотели %v", err, ErrInvalidCredentials)
+	}
+	if _, err := service.Login(ctx, "test@example.com", "password123", ClientInfo{}); !errors.As(err, &locked) || locked.RetryAfter != 2*time.Minute {
+		t.Fatalf("Login() после повторной неудачи error = %v, хотели блокировку на 2m", err)
+	}
+
+	// Успешный вход сбрасывает счетчик
+	setTimeNow(t, now.Add(3*time.Minute))
+	if _, err := service.Login(ctx, "test@example.com", "password123", ClientInfo{}); err != nil {
+		t.Fatalf("Login() после окончания блокировки error = %v, хотели nil", err)
+	}
+	if _, err := service.Login(ctx, "test@example.com", "wrong", ClientInfo{}); err != ErrInvalidCredentials {
+		t.Fatalf("Login() error = %v, хотели %v", err, ErrInvalidCredentials)
+	}
+	if _, err := service.Login(ctx, "test@example.com", "password123", ClientInfo{}); err != nil {
+		t.Errorf("Login() после сброса счетчика error = %v, хотели nil", err)
+	}
+
+	// Несуществующий email блокируется так же, как существующий
+	for i := 0; i < 3; i++ {
+		service.Login(ctx, "unknown@example.com", "wrong", ClientInfo{})
+	}
+	if _, err := service.Login(ctx, "unknown@example.com", "wrong", ClientInfo{}); !errors.Is(err, ErrTooManyAttempts) {
+		t.Errorf("Login() несуществующего email error = %v, хотели %v", err, ErrTooManyAttempts)
+	}
+}
+
+// TestResetPasswordUnlocks проверяет, что сброс пароля снимает блокировку входа
+func TestResetPasswordUnlocks(t *testing.T) {
+	service, _, _ := newSessionTestService(t)
+	enableLockout(service)
+	ctx := context.Background()
+	setTimeNow(t, time.Now())
+	runSync(t)
+
+	mailer := &MockMailer{}
+	service.SetMailer(mailer, MailConfig{AppURL: "https://tracker.example.com", ResetExpires: time.Hour})
+
+	for i := 0; i < 3; i++ {
+		service.Login(ctx, "test@example.com", "wrong", ClientInfo{})
+	}
+	if _, err := service.Login(ctx, "test@example.com", "password123", ClientInfo{}); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("Login() во время блокировки error = %v, хотели %v", err, ErrTooManyAttempts)
+	}
+
+	if err := service.ForgotPassword(ctx, "test@example.com"); err != nil {
+		t.Fatalf("ForgotPassword() error = %v, хотели nil", err)
+	}
+	if err := service.ResetPassword(ctx, resetTokenFromMessage(t, mailer.messages[0]), "newpassword123"); err != nil {
+		t.Fatalf("ResetPassword() error = %v, хотели nil", err)
+	}
+	if _, err := service.Login(ctx, "test@example.com", "newpassword123", ClientInfo{}); err != nil {
+		t.Errorf("Login() после сброса пароля error = %v, хотели nil", err)
+	}
+}
+
+// TestTwoFactorPasswordLockout проверяет, что пароль при отключении 2FA и замене кодов
+// подбирается не быстрее, чем при входе
+func TestTwoFactorPasswordLockout(t *testing.T) {
+	service, _, userID := newSessionTestService(t)
+	enableLockout(service)
+	ctx := context.Background()
+	now := time.Now()
+	setTimeNow(t, now)
+	enableTwoFactor(t, service, userID, now)
+
+	for i := 0; i < 3; i++ {
+		if err := service.DisableTwoFactor(ctx, userID, "wrong"); err != ErrInvalidCredentials {
+			t.Fatalf("DisableTwoFactor() #%d error = %v, хотели %v", i+1, err, ErrInvalidCredentials)
+		}
+	}
+	if _, err := service.RegenerateRecoveryCodes(ctx, userID, "password123"); !errors.Is(err, ErrTooManyAttempts) {
+		t.Errorf("RegenerateRecoveryCodes() во время блокировки error = %v, хотели %v", err, ErrTooManyAttempts)
+	}
+	if _, err := service.Login(ctx, "test@example.com", "password123", ClientInfo{}); !errors.Is(err, ErrTooManyAttempts) {
+		t.Errorf("Login() во время блокировки error = %v, хотели %v", err, ErrTooManyAttempts)
+	}
+
+	setTimeNow(t, now.Add(time.Minute))
+	if err := service.DisableTwoFactor(ctx, userID, "password123"); err != nil {
+		t.Errorf("DisableTwoFactor() после окончания блокировки error = %v, хотели nil", err)
+	}
+}
+
+// TestTwoFactorLockout тестирует блокировку после серии неверных кодов второго фактора
+func TestTwoFactorLockout(t *testing.T) {
+	service, _, userID := newSessionTestService(t)
+	enableLockout(service)
+	ctx := context.Background()
+	now := time.Now()
+	setTimeNow(t, now)
+
+	secret, _ := enableTwoFactor(t, service, userID, now)
+	later := now.Add(time.Minute)
+	setTimeNow(t, later)
+
+	tokens, err := service.Login(ctx, "test@example.com", "password123", ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login() error = %v, хотели nil", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := service.CompleteLogin(ctx, tokens.Challenge, "wrong-code", ClientInfo{}); err != ErrInvalidTwoFactorCode {
+			t.Fatalf("CompleteLogin() #%d error = %v, хотели %v", i+1, err, ErrInvalidTwoFactorCode)
+		}
+	}
+	if _, err := service.CompleteLogin(ctx, tokens.Challenge, totpCode(t, secret, later), ClientInfo{}); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("CompleteLogin() во время блокировки error = %v, хотели %v", err, ErrTooManyAttempts)
+	}
+
+	// Блокировка кодов не мешает проверке пароля
+	if _, err := service.Login(ctx, "test@example.com", "password123", ClientInfo{}); err != nil {
+		t.Errorf("Login() во время блокировки кодов error = %v, хотели nil", err)
+	}
+
+	setTimeNow(t, later.Add(time.Minute))
+	if _, err := service.CompleteLogin(ctx, tokens.Challenge, totpCode(t, secret, later.Add(time.Minute)), ClientInfo{}); err != nil {
+		t.Errorf("CompleteLogin() после окончания блокировки error = %v, хотели nil", err)
+	}
+}
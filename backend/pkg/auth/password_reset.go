@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/pkg/database"
+	"github.com/graywrk/timetracker/backend/pkg/mail"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrInvalidResetToken возникает, если токен сброса пароля неизвестен, уже использован или истек
+	ErrInvalidResetToken = errors.New("ссылка для сброса пароля недействительна или устарела")
+	// ErrInvalidPassword возникает при недопустимом новом пароле
+	ErrInvalidPassword = errors.New("недопустимый пароль")
+	// ErrMailNotConfigured возникает, если отправка писем не настроена (см. SetMailer)
+	ErrMailNotConfigured = errors.New("отправка писем не настроена")
+)
+
+// runAsync запускает fn в фоне; подменяется в тестах
+var runAsync = func(fn func()) { go fn() }
+
+// ForgotPassword отправляет на email ссылку для сброса пароля. Если пользователя нет,
+// письмо не отправляется, но ошибка не возвращается: ответ не должен раскрывать,
+// зарегистрирован ли email. Поэтому токен создается и письмо отправляется в фоне:
+// иначе зарегистрированный email выдавало бы время ответа.
+func (s *Service) ForgotPassword(ctx context.Context, email string) error {
+	if s.mailer == nil {
+		return ErrMailNotConfigured
+	}
+
+	user, err := s.repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		log.Printf("Запрошен сброс пароля для email %s, пользователь не найден: %v", email, err)
+		return nil
+	}
+
+	// Отправка не должна прерываться вместе с запросом
+	ctx = context.WithoutCancel(ctx)
+	runAsync(func() {
+		s.sendPasswordReset(ctx, user)
+	})
+	return nil
+}
+
+// sendPasswordReset создает токен сброса пароля и отправляет ссылку пользователю.
+// Ошибки только записываются в журнал: ответ на запрос уже отправлен.
+func (s *Service) sendPasswordReset(ctx context.Context, user *models.User) {
+	token, tokenHash, err := newSecretToken()
+	if err != nil {
+		log.Printf("Ошибка при создании токена сброса пароля пользователя %d: %v", user.ID, err)
+		return
+	}
+
+	now := timeNow()
+	reset := &models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.mailConfig.ResetExpires),
+	}
+	if err := s.repo.CreatePasswordResetToken(ctx, reset); err != nil {
+		log.Printf("Ошибка при сохранении токена сброса пароля пользователя %d: %v", user.ID, err)
+		return
+	}
+
+	link := strings.TrimRight(s.mailConfig.AppURL, "/") + "/reset-password?token=" + url.QueryEscape(token)
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "Сброс пароля TimeTracker",
+		Body: fmt.Sprintf("Здравствуйте!\n\n"+
+			"Чтобы задать новый пароль, перейдите по ссылке:\n%s\n\n"+
+			"Ссылка действует до %s и только один раз.\n"+
+			"Если вы не запрашивали сброс пароля, просто проигнорируйте это письмо.\n",
+			link, reset.ExpiresAt.UTC().Format("02.01.2006 15:04 UTC")),
+	}
+
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		log.Printf("Ошибка при отправке письма для сброса пароля пользователю %d: %v", user.ID, err)
+		return
+	}
+
+	log.Printf("Пользователю %d отправлена ссылка для сброса пароля", user.ID)
+}
+
+// ResetPassword задает новый пароль по токену из письма. Токен действует один раз;
+// после сброса остальные токены сброса и все сессии пользователя удаляются,
+// а блокировка входа после неверных паролей снимается.
+func (s *Service) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if token == "" {
+		return ErrInvalidResetToken
+	}
+	if newPassword == "" {
+		return fmt.Errorf("%w: пароль не может быть пустым", ErrInvalidPassword)
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	var userID uint
+	var email string
+	err = s.repo.WithTx(ctx, func(repo database.Repository) error {
+		reset, err := repo.UsePasswordResetToken(ctx, hashToken(token), timeNow())
+		if err != nil {
+			return err
+		}
+		if reset == nil {
+			return ErrInvalidResetToken
+		}
+		userID = reset.UserID
+
+		user, err := repo.GetUserByID(ctx, reset.UserID)
+		if err != nil {
+			return err
+		}
+		email = user.Email
+		user.Password = string(hashedPassword)
+		if err := repo.UpdateUser(ctx, user); err != nil {
+			return err
+		}
+
+		if err := repo.DeletePasswordResetTokens(ctx, user.ID); err != nil {
+			return err
+		}
+		// Старый пароль мог быть скомпрометирован, поэтому завершаем все сессии
+		return repo.DeleteUserSessions(ctx, user.ID, 0)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Пользователь подтвердил владение почтой, поэтому может войти с новым паролем сразу
+	s.resetFailures(ctx, passwordLockoutKey(email))
+
+	log.Printf("Пароль пользователя %d сброшен по ссылке из письма", userID)
+	return nil
+}
@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/pkg/mail"
+)
+
+// MockMailer запоминает отправленные письма
+type MockMailer struct {
+	messages []mail.Message
+	err      error
+}
+
+// Send мок метода
+func (m *MockMailer) Send(ctx context.Context, msg mail.Message) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// runSync выполняет фоновые задачи сервиса сразу, чтобы тест видел их результат
+func runSync(t *testing.T) {
+	previous := runAsync
+	runAsync = func(fn func()) { fn() }
+	t.Cleanup(func() { runAsync = previous })
+}
+
+// blockingMailer не завершает отправку, пока тест не разрешит
+type blockingMailer struct {
+	release chan struct{}
+	sent    chan mail.Message
+}
+
+// Send мок метода
+func (m *blockingMailer) Send(ctx context.Context, msg mail.Message) error {
+	<-m.release
+	m.sent <- msg
+	return nil
+}
+
+// tokenFromMessage извлекает токен из ссылки на страницу path в письме
+func tokenFromMessage(t *testing.T, msg mail.Message, path string) string {
+	t.Helper()
+
+	link := regexp.MustCompile(`https?://\S+`).FindString(msg.Body)
+	parsed, err := url.Parse(link)
+	if err != nil || parsed.Path != path {
+		t.Fatalf("В письме нет ссылки на %s: %q", path, msg.Body)
+	}
+	return parsed.Query().Get("token")
+}
+
+// resetTokenFromMessage извлекает токен сброса пароля из ссылки в письме
+func resetTokenFromMessage(t *testing.T, msg mail.Message) string {
+	t.Helper()
+	return tokenFromMessage(t, msg, "/reset-password")
+}
+
+// TestForgotPassword тестирует отправку ссылки для сброса пароля
+func TestForgotPassword(t *testing.T) {
+	service, mockRepo, _ := newSessionTestService(t)
+	ctx := context.Background()
+	runSync(t)
+
+	if err := service.ForgotPassword(ctx, "test@example.com"); err != ErrMailNotConfigured {
+		t.Errorf("ForgotPassword() без отправителя error = %v, хотели %v", err, ErrMailNotConfigured)
+	}
+
+	mailer := &MockMailer{}
+	service.SetMailer(mailer, MailConfig{AppURL: "https://tracker.example.com/", ResetExpires: time.Hour})
+
+	if err := service.ForgotPassword(ctx, "test@example.com"); err != nil {
+		t.Fatalf("ForgotPassword() error = %v, хотели nil", err)
+	}
+	if len(mailer.messages) != 1 || mailer.messages[0].To != "test@example.com" {
+		t.Fatalf("Отправлены письма %+v, хотели одно на test@example.com", mailer.messages)
+	}
+	token := resetTokenFromMessage(t, mailer.messages[0])
+	if len(mockRepo.resetTokens) != 1 || mockRepo.resetTokens[1].TokenHash != hashToken(token) {
+		t.Error("В базе должен храниться хеш токена из письма")
+	}
+
+	// Для неизвестного email письмо не отправляется, но ответ тот же
+	if err := service.ForgotPassword(ctx, "unknown@example.com"); err != nil {
+		t.Errorf("ForgotPassword() для неизвестного email error = %v, хотели nil", err)
+	}
+	if len(mailer.messages) != 1 {
+		t.Errorf("Отправлено %d писем, хотели 1", len(mailer.messages))
+	}
+
+	// Ошибка отправки тоже не раскрывается
+	mailer.err = errors.New("SMTP недоступен")
+	if err := service.ForgotPassword(ctx, "test@example.com"); err != nil {
+		t.Errorf("ForgotPassword() при ошибке отправки error = %v, хотели nil", err)
+	}
+}
+
+// TestForgotPasswordAsync проверяет, что ответ не ждет отправки письма
+// и по времени ответа нельзя узнать, зарегистрирован ли email
+func TestForgotPasswordAsync(t *testing.T) {
+	service, _, _ := newSessionTestService(t)
+	mailer := &blockingMailer{release: make(chan struct{}), sent: make(chan mail.Message, 1)}
+	service.SetMailer(mailer, MailConfig{AppURL: "https://tracker.example.com", ResetExpires: time.Hour})
+
+	// Запрос отменяется сразу после ответа, но письмо все равно отправляется
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := service.ForgotPassword(ctx, "test@example.com"); err != nil {
+		t.Fatalf("ForgotPassword() error = %v, хотели nil", err)
+	}
+	cancel()
+	close(mailer.release)
+
+	select {
+	case msg := <-mailer.sent:
+		if msg.To != "test@example.com" {
+			t.Errorf("Письмо отправлено на %s, хотели test@example.com", msg.To)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Письмо для сброса пароля не отправлено")
+	}
+}
+
+// TestResetPassword тестирует сброс пароля по ссылке из письма
+func TestResetPassword(t *testing.T) {
+	service, _, _ := newSessionTestService(t)
+	ctx := context.Background()
+	now := time.Now()
+	setTimeNow(t, now)
+	runSync(t)
+
+	mailer := &MockMailer{}
+	service.SetMailer(mailer, MailConfig{AppURL: "https://tracker.example.com", ResetExpires: time.Hour})
+
+	session, err := service.Login(ctx, "test@example.com", "password123", ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login() error = %v, хотели nil", err)
+	}
+
+	if err := service.ForgotPassword(ctx, "test@example.com"); err != nil {
+		t.Fatalf("ForgotPassword() error = %v, хотели nil", err)
+	}
+	if err := service.ForgotPassword(ctx, "test@example.com"); err != nil {
+		t.Fatalf("ForgotPassword() error = %v, хотели nil", err)
+	}
+	first := resetTokenFromMessage(t, mailer.messages[0])
+	second := resetTokenFromMessage(t, mailer.messages[1])
+
+	if err := service.ResetPassword(ctx, "unknown-token", "newpassword123"); err != ErrInvalidResetToken {
+		t.Errorf("ResetPassword() неизвестным токеном error = %v, хотели %v", err, ErrInvalidResetToken)
+	}
+	if err := service.ResetPassword(ctx, first, ""); !errors.Is(err, ErrInvalidPassword) {
+		t.Errorf("ResetPassword() с пустым паролем error = %v, хотели %v", err, ErrInvalidPassword)
+	}
+
+	if err := service.ResetPassword(ctx, first, "newpassword123"); err != nil {
+		t.Fatalf("ResetPassword() error = %v, хотели nil", err)
+	}
+	if _, err := service.Login(ctx, "test@example.com", "newpassword123", ClientInfo{}); err != nil {
+		t.Errorf("Не удалось войти с новым паролем: %v", err)
+	}
+	if _, err := service.Login(ctx, "test@example.com", "password123", ClientInfo{}); err != ErrInvalidCredentials {
+		t.Errorf("Вход со старым паролем error = %v, хотели %v", err, ErrInvalidCredentials)
+	}
+
+	// Сброс завершает все сессии
+	if _, err := service.ValidateToken(ctx, session.AccessToken); err != ErrSessionRevoked {
+		t.Errorf("ValidateToken() после сброса пароля error = %v, хотели %v", err, ErrSessionRevoked)
+	}
+
+	// Токен одноразовый, остальные токены пользователя отзываются
+	if err := service.ResetPassword(ctx, first, "anotherpassword"); err != ErrInvalidResetToken {
+		t.Errorf("ResetPassword() повторно error = %v, хотели %v", err, ErrInvalidResetToken)
+	}
+	if err := service.ResetPassword(ctx, second, "anotherpassword"); err != ErrInvalidResetToken {
+		t.Errorf("ResetPassword() другим токеном после сброса error = %v, хотели %v", err, ErrInvalidResetToken)
+	}
+
+	// Истекший токен не действует
+	if err := service.ForgotPassword(ctx, "test@example.com"); err != nil {
+		t.Fatalf("ForgotPassword() error = %v, хотели nil", err)
+	}
+	expired := resetTokenFromMessage(t, mailer.messages[2])
+	setTimeNow(t, now.Add(2*time.Hour))
+	if err := service.ResetPassword(ctx, expired, "anotherpassword"); err != ErrInvalidResetToken {
+		t.Errorf("ResetPassword() истекшим токеном error = %v, хотели %v", err, ErrInvalidResetToken)
+	}
+}
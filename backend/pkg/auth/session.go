@@ -0,0 +1,247 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/pkg/database"
+)
+
+var (
+	// ErrInvalidRefreshToken возникает, если токен обновления неизвестен, уже использован или истек
+	ErrInvalidRefreshToken = errors.New("недействительный токен обновления")
+	// ErrSessionRevoked возникает, если сессия токена доступа завершена или истекла
+	ErrSessionRevoked = errors.New("сессия завершена")
+	// ErrSessionNotFound возникает, если сессия не найдена или принадлежит другому пользователю
+	ErrSessionNotFound = errors.New("сессия не найдена")
+)
+
+// Ограничения длины сведений об устройстве (см. migrations/sessions.sql)
+const (
+	maxUserAgentLength = 512
+	maxIPLength        = 64
+)
+
+// timeNow возвращает текущее время; подменяется в тестах
+var timeNow = time.Now
+
+// ClientInfo описывает устройство, с которого выполняется вход или обновление токена
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+// Tokens - пара токенов, выдаваемая при входе и обновлении
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+	// AccessExpiresAt - момент, после которого токен доступа нужно обновить
+	AccessExpiresAt time.Time
+	Session         *models.Session
+
+	// Challenge - промежуточный токен входа, если у пользователя включена двухфакторная
+	// аутентификация: остальные поля пусты, сессия открывается в CompleteLogin с кодом
+	Challenge          string
+	ChallengeExpiresAt time.Time
+}
+
+// newSecretToken создает случайный токен (обновления, сброса пароля) и его хеш для хранения в базе
+func newSecretToken() (token, tokenHash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("ошибка при создании токена: %w", err)
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+// hashToken возвращает hex SHA-256 токена
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// truncate обрезает строку до max байт, не разрывая символы UTF-8
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
+
+// applyClient сохраняет в сессии сведения об устройстве; пустые значения не заменяют сохраненные
+func applyClient(session *models.Session, client ClientInfo) {
+	if client.UserAgent != "" {
+		session.UserAgent = truncate(client.UserAgent, maxUserAgentLength)
+	}
+	if client.IP != "" {
+		session.IP = truncate(client.IP, maxIPLength)
+	}
+}
+
+// sessionLifetime возвращает срок действия сессии без обновления токена
+func (s *Service) sessionLifetime(rememberMe bool) time.Duration {
+	if rememberMe {
+		return s.rememberExpires
+	}
+	return s.sessionExpires
+}
+
+// createSession создает сессию пользователя и выдает для нее токены
+func (s *Service) createSession(ctx context.Context, userID uint, rememberMe bool, client ClientInfo) (*Tokens, error) {
+	refreshToken, refreshHash, err := newSecretToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := timeNow()
+	session := &models.Session{
+		UserID:           userID,
+		RefreshTokenHash: refreshHash,
+		RememberMe:       rememberMe,
+		CreatedAt:        now,
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(s.sessionLifetime(rememberMe)),
+	}
+	applyClient(session, client)
+
+	if err := s.repo.CreateSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(session, refreshToken)
+}
+
+// issueTokens подписывает токен доступа для сессии
+func (s *Service) issueTokens(session *models.Session, refreshToken string) (*Tokens, error) {
+	expiresAt := timeNow().Add(s.accessExpires)
+	if expiresAt.After(session.ExpiresAt) {
+		expiresAt = session.ExpiresAt
+	}
+
+	claims := &Claims{
+		UserID:    session.UserID,
+		SessionID: session.ID,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: expiresAt.Unix(),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString(s.jwtSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tokens{
+		AccessToken:     tokenString,
+		RefreshToken:    refreshToken,
+		AccessExpiresAt: expiresAt,
+		Session:         session,
+	}, nil
+}
+
+// Refresh выдает новую пару токенов по токену обновления. Предыдущий токен обновления
+// перестает действовать, срок действия сессии продлевается.
+func (s *Service) Refresh(ctx context.Context, refreshToken string, client ClientInfo) (*Tokens, error) {
+	if refreshToken == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	previousHash := hashToken(refreshToken)
+	session, err := s.repo.GetSessionByTokenHash(ctx, previousHash)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	now := timeNow()
+	if session.IsExpired(now) {
+		if err := s.repo.DeleteSession(ctx, session.ID); err != nil {
+			log.Printf("Ошибка при удалении истекшей сессии %d: %v", session.ID, err)
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+
+	newToken, newHash, err := newSecretToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session.RefreshTokenHash = newHash
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(s.sessionLifetime(session.RememberMe))
+	applyClient(session, client)
+
+	if err := s.repo.RotateSession(ctx, session, previousHash); err != nil {
+		if errors.Is(err, database.ErrConflict) {
+			// Токен уже обменян параллельным запросом
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	log.Printf("Токены сессии %d пользователя %d обновлены", session.ID, session.UserID)
+	return s.issueTokens(session, newToken)
+}
+
+// Logout завершает сессию; ее токены доступа и обновления перестают действовать
+func (s *Service) Logout(ctx context.Context, sessionID uint) error {
+	return s.repo.DeleteSession(ctx, sessionID)
+}
+
+// LogoutAll завершает все сессии пользователя, включая текущую
+func (s *Service) LogoutAll(ctx context.Context, userID uint) error {
+	return s.repo.DeleteUserSessions(ctx, userID, 0)
+}
+
+// GetSessions возвращает действующие сессии пользователя; сессия currentID отмечается как текущая
+func (s *Service) GetSessions(ctx context.Context, userID, currentID uint) ([]*models.Session, error) {
+	sessions, err := s.repo.GetSessionsByUserID(ctx, userID, timeNow())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		session.Current = session.ID == currentID
+	}
+	return sessions, nil
+}
+
+// RevokeSession завершает сессию пользователя на другом устройстве
+func (s *Service) RevokeSession(ctx context.Context, userID, sessionID uint) error {
+	session, err := s.repo.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if session == nil || session.UserID != userID {
+		return ErrSessionNotFound
+	}
+
+	return s.repo.DeleteSession(ctx, sessionID)
+}
+
+// CleanupSessions удаляет сессии, срок действия которых истек к моменту now, и отметки
+// об использовании истекших промежуточных токенов входа
+func (s *Service) CleanupSessions(ctx context.Context, now time.Time) (int64, error) {
+	deleted, err := s.repo.DeleteExpiredSessions(ctx, now)
+	if err != nil {
+		return deleted, err
+	}
+	return deleted, s.repo.DeleteExpiredLoginChallenges(ctx, now)
+}
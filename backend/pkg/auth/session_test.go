@@ -0,0 +1,262 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// newSessionTestService создает сервис с зарегистрированным пользователем test@example.com
+func newSessionTestService(t *testing.T) (*Service, *MockRepository, uint) {
+	mockRepo := NewMockRepository()
+	service := NewService(mockRepo, "test-secret", 15*time.Minute, 24*time.Hour, 30*24*time.Hour)
+
+	user, err := service.Register(context.Background(), "test@example.com", "password123")
+	if err != nil {
+		t.Fatalf("Не удалось зарегистрировать пользователя для теста: %v", err)
+	}
+	return service, mockRepo, user.ID
+}
+
+// setTimeNow подменяет текущее время сервиса до конца теста
+func setTimeNow(t *testing.T, now time.Time) {
+	previous := timeNow
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = previous })
+}
+
+// TestLoginCreatesSession тестирует создание сессии при входе
+func TestLoginCreatesSession(t *testing.T) {
+	service, _, userID := newSessionTestService(t)
+	ctx := context.Background()
+	now := time.Now()
+	setTimeNow(t, now)
+
+	tokens, err := service.LoginWithRememberMe(ctx, "test@example.com", "password123", true,
+		ClientInfo{UserAgent: "Firefox", IP: "10.0.0.1"})
+	if err != nil {
+		t.Fatalf("LoginWithRememberMe() error = %v, хотели nil", err)
+	}
+
+	session := tokens.Session
+	if session.UserID != userID || session.UserAgent != "Firefox" || session.IP != "10.0.0.1" || !session.RememberMe {
+		t.Errorf("Сессия сохранена неверно: %+v", session)
+	}
+	if !session.ExpiresAt.Equal(now.Add(30 * 24 * time.Hour)) {
+		t.Errorf("ExpiresAt = %v, хотели %v", session.ExpiresAt, now.Add(30*24*time.Hour))
+	}
+	if !tokens.AccessExpiresAt.Equal(now.Add(15 * time.Minute)) {
+		t.Errorf("AccessExpiresAt = %v, хотели %v", tokens.AccessExpiresAt, now.Add(15*time.Minute))
+	}
+	if session.RefreshTokenHash != hashToken(tokens.RefreshToken) {
+		t.Error("В сессии должен храниться хеш токена обновления")
+	}
+}
+
+// TestRefresh тестирует обмен токена обновления
+func TestRefresh(t *testing.T) {
+	service, _, _ := newSessionTestService(t)
+	ctx := context.Background()
+	now := time.Now()
+	setTimeNow(t, now)
+
+	tokens, err := service.Login(ctx, "test@example.com", "password123", ClientInfo{UserAgent: "Firefox", IP: "10.0.0.1"})
+	if err != nil {
+		t.Fatalf("Login() error = %v, хотели nil", err)
+	}
+
+	later := now.Add(time.Hour)
+	setTimeNow(t, later)
+	refreshed, err := service.Refresh(ctx, tokens.RefreshToken, ClientInfo{IP: "10.0.0.2"})
+	if err != nil {
+		t.Fatalf("Refresh() error = %v, хотели nil", err)
+	}
+	if refreshed.RefreshToken == tokens.RefreshToken {
+		t.Error("Refresh() должен выдать новый токен обновления")
+	}
+	if refreshed.Session.ID != tokens.Session.ID {
+		t.Errorf("Refresh() сменил сессию: %d, хотели %d", refreshed.Session.ID, tokens.Session.ID)
+	}
+	if refreshed.Session.UserAgent != "Firefox" || refreshed.Session.IP != "10.0.0.2" {
+		t.Errorf("Сведения об устройстве обновлены неверно: %+v", refreshed.Session)
+	}
+	if !refreshed.Session.ExpiresAt.Equal(later.Add(24 * time.Hour)) {
+		t.Errorf("Срок действия сессии не продлен: %v", refreshed.Session.ExpiresAt)
+	}
+
+	if _, err := service.ValidateToken(ctx, refreshed.AccessToken); err != nil {
+		t.Errorf("ValidateToken() error = %v для обновленного токена", err)
+	}
+
+	// Использованный токен обновления больше не действует
+	if _, err := service.Refresh(ctx, tokens.RefreshToken, ClientInfo{}); err != ErrInvalidRefreshToken {
+		t.Errorf("Refresh() использованным токеном error = %v, хотели %v", err, ErrInvalidRefreshToken)
+	}
+	if _, err := service.Refresh(ctx, "", ClientInfo{}); err != ErrInvalidRefreshToken {
+		t.Errorf("Refresh() пустым токеном error = %v, хотели %v", err, ErrInvalidRefreshToken)
+	}
+
+	// Истекшая сессия не обновляется и удаляется
+	setTimeNow(t, later.Add(25*time.Hour))
+	if _, err := service.Refresh(ctx, refreshed.RefreshToken, ClientInfo{}); err != ErrInvalidRefreshToken {
+		t.Errorf("Refresh() истекшей сессии error = %v, хотели %v", err, ErrInvalidRefreshToken)
+	}
+	if _, err := service.ValidateToken(ctx, refreshed.AccessToken); err == nil {
+		t.Error("ValidateToken() не вернул ошибку для токена удаленной сессии")
+	}
+}
+
+// TestLogout тестирует отзыв токенов при выходе
+func TestLogout(t *testing.T) {
+	service, _, userID := newSessionTestService(t)
+	ctx := context.Background()
+
+	first, err := service.Login(ctx, "test@example.com", "password123", ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login() error = %v, хотели nil", err)
+	}
+	second, err := service.Login(ctx, "test@example.com", "password123", ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login() error = %v, хотели nil", err)
+	}
+
+	if err := service.Logout(ctx, first.Session.ID); err != nil {
+		t.Fatalf("Logout() error = %v, хотели nil", err)
+	}
+	if _, err := service.ValidateToken(ctx, first.AccessToken); err != ErrSessionRevoked {
+		t.Errorf("ValidateToken() после выхода error = %v, хотели %v", err, ErrSessionRevoked)
+	}
+	if _, err := service.Refresh(ctx, first.RefreshToken, ClientInfo{}); err != ErrInvalidRefreshToken {
+		t.Errorf("Refresh() после выхода error = %v, хотели %v", err, ErrInvalidRefreshToken)
+	}
+	if _, err := service.ValidateToken(ctx, second.AccessToken); err != nil {
+		t.Errorf("Выход не должен завершать другие сессии: %v", err)
+	}
+
+	if err := service.LogoutAll(ctx, userID); err != nil {
+		t.Fatalf("LogoutAll() error = %v, хотели nil", err)
+	}
+	if _, err := service.ValidateToken(ctx, second.AccessToken); err != ErrSessionRevoked {
+		t.Errorf("ValidateToken() после выхода на всех устройствах error = %v, хотели %v", err, ErrSessionRevoked)
+	}
+}
+
+// TestValidateTokenWithoutSession тестирует отклонение токенов, выданных без сессии
+func TestValidateTokenWithoutSession(t *testing.T) {
+	service, _, userID := newSessionTestService(t)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+		UserID: userID,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		},
+	})
+	tokenString, err := token.SignedString([]byte("test-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.ValidateToken(context.Background(), tokenString); err != ErrSessionRevoked {
+		t.Errorf("ValidateToken() error = %v, хотели %v", err, ErrSessionRevoked)
+	}
+}
+
+// TestChangePasswordRevokesSessions тестирует завершение других сессий при смене пароля
+func TestChangePasswordRevokesSessions(t *testing.T) {
+	service, _, userID := newSessionTestService(t)
+	ctx := context.Background()
+
+	current, err := service.Login(ctx, "test@example.com", "password123", ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login() error = %v, хотели nil", err)
+	}
+	other, err := service.Login(ctx, "test@example.com", "password123", ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login() error = %v, хотели nil", err)
+	}
+
+	if err := service.ChangePassword(ctx, userID, current.Session.ID, "password123", "newpassword123"); err != nil {
+		t.Fatalf("ChangePassword() error = %v, хотели nil", err)
+	}
+
+	if _, err := service.ValidateToken(ctx, current.AccessToken); err != nil {
+		t.Errorf("Текущая сессия не должна завершаться: %v", err)
+	}
+	if _, err := service.ValidateToken(ctx, other.AccessToken); err != ErrSessionRevoked {
+		t.Errorf("ValidateToken() другой сессии error = %v, хотели %v", err, ErrSessionRevoked)
+	}
+}
+
+// TestGetSessions тестирует список сессий и отзыв сессии на другом устройстве
+func TestGetSessions(t *testing.T) {
+	service, mockRepo, userID := newSessionTestService(t)
+	ctx := context.Background()
+
+	first, err := service.Login(ctx, "test@example.com", "password123", ClientInfo{UserAgent: "Firefox"})
+	if err != nil {
+		t.Fatalf("Login() error = %v, хотели nil", err)
+	}
+	second, err := service.Login(ctx, "test@example.com", "password123", ClientInfo{UserAgent: "Chrome"})
+	if err != nil {
+		t.Fatalf("Login() error = %v, хотели nil", err)
+	}
+
+	sessions, err := service.GetSessions(ctx, userID, second.Session.ID)
+	if err != nil {
+		t.Fatalf("GetSessions() error = %v, хотели nil", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("GetSessions() вернул %d сессий, хотели 2", len(sessions))
+	}
+	for _, session := range sessions {
+		if session.Current != (session.ID == second.Session.ID) {
+			t.Errorf("Неверный признак текущей сессии: %+v", session)
+		}
+	}
+
+	// Чужую сессию отозвать нельзя
+	if err := service.RevokeSession(ctx, userID+1, first.Session.ID); err != ErrSessionNotFound {
+		t.Errorf("RevokeSession() чужой сессии error = %v, хотели %v", err, ErrSessionNotFound)
+	}
+	if err := service.RevokeSession(ctx, userID, 999); err != ErrSessionNotFound {
+		t.Errorf("RevokeSession() несуществующей сессии error = %v, хотели %v", err, ErrSessionNotFound)
+	}
+
+	if err := service.RevokeSession(ctx, userID, first.Session.ID); err != nil {
+		t.Fatalf("RevokeSession() error = %v, хотели nil", err)
+	}
+	if _, err := service.ValidateToken(ctx, first.AccessToken); err != ErrSessionRevoked {
+		t.Errorf("ValidateToken() отозванной сессии error = %v, хотели %v", err, ErrSessionRevoked)
+	}
+
+	mockRepo.SetError(errors.New("ошибка базы данных"))
+	if _, err := service.GetSessions(ctx, userID, 0); err == nil {
+		t.Error("GetSessions() не вернул ошибку при ошибке репозитория")
+	}
+}
+
+// TestCleanupSessions тестирует удаление истекших сессий
+func TestCleanupSessions(t *testing.T) {
+	service, _, _ := newSessionTestService(t)
+	ctx := context.Background()
+	now := time.Now()
+	setTimeNow(t, now)
+
+	if _, err := service.Login(ctx, "test@example.com", "password123", ClientInfo{}); err != nil {
+		t.Fatalf("Login() error = %v, хотели nil", err)
+	}
+	if _, err := service.LoginWithRememberMe(ctx, "test@example.com", "password123", true, ClientInfo{}); err != nil {
+		t.Fatalf("LoginWithRememberMe() error = %v, хотели nil", err)
+	}
+
+	deleted, err := service.CleanupSessions(ctx, now.Add(48*time.Hour))
+	if err != nil {
+		t.Fatalf("CleanupSessions() error = %v, хотели nil", err)
+	}
+	if deleted != 1 {
+		t.Errorf("CleanupSessions() удалил %d сессий, хотели 1 (сессия с \"Запомнить меня\" еще действует)", deleted)
+	}
+}
@@ -0,0 +1,377 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/pkg/database"
+	"github.com/graywrk/timetracker/backend/pkg/totp"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrInvalidChallenge возникает, если промежуточный токен входа неизвестен или истек
+	ErrInvalidChallenge = errors.New("время на ввод кода истекло, войдите заново")
+	// ErrInvalidTwoFactorCode возникает при неверном, уже использованном или устаревшем коде
+	ErrInvalidTwoFactorCode = errors.New("неверный код подтверждения")
+	// ErrTwoFactorEnabled возникает при попытке повторно настроить включенную двухфакторную аутентификацию
+	ErrTwoFactorEnabled = errors.New("двухфакторная аутентификация уже включена")
+	// ErrTwoFactorNotEnabled возникает, если двухфакторная аутентификация не включена
+	ErrTwoFactorNotEnabled = errors.New("двухфакторная аутентификация не включена")
+	// ErrTwoFactorSetupNotStarted возникает при подтверждении кода без начатой настройки
+	ErrTwoFactorSetupNotStarted = errors.New("настройка двухфакторной аутентификации не начата")
+)
+
+const (
+	// TOTPIssuer - название сервиса в приложении-аутентификаторе
+	TOTPIssuer = "TimeTracker"
+	// RecoveryCodeCount - сколько кодов восстановления выдается за раз
+	RecoveryCodeCount = 10
+	// challengeExpires - сколько действует промежуточный токен входа
+	challengeExpires = 5 * time.Minute
+	// challengeAudience отличает промежуточный токен входа от токена доступа
+	challengeAudience = "2fa"
+)
+
+// TwoFactorSetup - данные для добавления секрета в приложение-аутентификатор
+type TwoFactorSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_url"`
+}
+
+// TwoFactorStatus - состояние двухфакторной аутентификации пользователя
+type TwoFactorStatus struct {
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+}
+
+// challengeClaims - данные промежуточного токена входа, который выдается после проверки
+// пароля, если у пользователя включена двухфакторная аутентификация
+type challengeClaims struct {
+	UserID     uint `json:"user_id"`
+	RememberMe bool `json:"remember_me"`
+	jwt.StandardClaims
+}
+
+// issueChallenge выдает промежуточный токен входа вместо токенов сессии
+func (s *Service) issueChallenge(userID uint, rememberMe bool) (*Tokens, error) {
+	// Идентификатор позволяет отметить токен использованным после успешного входа
+	challengeID, _, err := newSecretToken()
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := timeNow().Add(challengeExpires)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &challengeClaims{
+		UserID:     userID,
+		RememberMe: rememberMe,
+		StandardClaims: jwt.StandardClaims{
+			Id:        challengeID,
+			Audience:  challengeAudience,
+			ExpiresAt: expiresAt.Unix(),
+		},
+	})
+
+	challenge, err := token.SignedString(s.jwtSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tokens{
+		Challenge:          challenge,
+		ChallengeExpiresAt: expiresAt,
+	}, nil
+}
+
+// parseChallenge проверяет подпись, срок и назначение промежуточного токена входа
+func (s *Service) parseChallenge(challenge string) (*challengeClaims, error) {
+	claims := &challengeClaims{}
+	token, err := jwt.ParseWithClaims(challenge, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("неожиданный алгоритм подписи: %v", token.Header["alg"])
+		}
+		return s.jwtSecret, nil
+	})
+	if err != nil || !token.Valid || !claims.VerifyAudience(challengeAudience, true) ||
+		!claims.VerifyExpiresAt(timeNow().Unix(), true) || claims.Id == "" {
+		return nil, ErrInvalidChallenge
+	}
+	return claims, nil
+}
+
+// CompleteLogin завершает вход с двухфакторной аутентификацией: по промежуточному токену
+// из LoginWithRememberMe и коду из приложения или коду восстановления открывает сессию
+func (s *Service) CompleteLogin(ctx context.Context, challenge, code string, client ClientInfo) (*Tokens, error) {
+	claims, err := s.parseChallenge(challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	// Промежуточный токен действует несколько минут, но за это время можно перебрать много кодов
+	lockoutKey := twoFactorLockoutKey(claims.UserID)
+	if err := s.checkLockout(ctx, lockoutKey); err != nil {
+		return nil, err
+	}
+
+	settings, err := s.repo.GetTOTP(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !settings.Enabled() {
+		// Двухфакторная аутентификация отключена после выдачи промежуточного токена
+		return nil, ErrInvalidChallenge
+	}
+
+	if err := s.checkSecondFactor(ctx, settings, code); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			s.recordFailure(ctx, lockoutKey)
+		}
+		return nil, err
+	}
+	s.resetFailures(ctx, lockoutKey)
+
+	// Промежуточный токен одноразовый: с ним и другим верным кодом вторую сессию не открыть
+	used, err := s.repo.UseLoginChallenge(ctx, claims.Id, claims.UserID, time.Unix(claims.ExpiresAt, 0))
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, ErrInvalidChallenge
+	}
+
+	tokens, err := s.createSession(ctx, claims.UserID, claims.RememberMe, client)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Открыта сессия %d пользователя %d после проверки второго фактора", tokens.Session.ID, claims.UserID)
+	return tokens, nil
+}
+
+// checkSecondFactor проверяет код из приложения-аутентификатора или код восстановления
+func (s *Service) checkSecondFactor(ctx context.Context, settings *models.TOTP, code string) error {
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		return s.useTOTPCode(ctx, s.repo, settings, code)
+	}
+
+	used, err := s.repo.UseRecoveryCode(ctx, settings.UserID, hashToken(normalizeRecoveryCode(code)), timeNow())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+
+	log.Printf("Пользователь %d вошел с кодом восстановления", settings.UserID)
+	return nil
+}
+
+// useTOTPCode проверяет код из приложения и запоминает его шаг, чтобы код нельзя было использовать повторно
+func (s *Service) useTOTPCode(ctx context.Context, repo database.Repository, settings *models.TOTP, code string) error {
+	step, ok := totp.Validate(settings.Secret, code, timeNow())
+	if !ok || step <= settings.LastStep {
+		return ErrInvalidTwoFactorCode
+	}
+
+	if err := repo.AdvanceTOTPStep(ctx, settings.UserID, step); err != nil {
+		if errors.Is(err, database.ErrConflict) {
+			return ErrInvalidTwoFactorCode
+		}
+		return err
+	}
+	settings.LastStep = step
+	return nil
+}
+
+// GetTwoFactorStatus возвращает состояние двухфакторной аутентификации пользователя
+func (s *Service) GetTwoFactorStatus(ctx context.Context, userID uint) (*TwoFactorStatus, error) {
+	settings, err := s.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !settings.Enabled() {
+		return &TwoFactorStatus{}, nil
+	}
+
+	left, err := s.repo.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &TwoFactorStatus{
+		Enabled:           true,
+		EnabledAt:         settings.EnabledAt,
+		RecoveryCodesLeft: left,
+	}, nil
+}
+
+// SetupTwoFactor начинает настройку двухфакторной аутентификации: создает секрет, который
+// пользователь добавляет в приложение-аутентификатор. Аутентификация включается после
+// подтверждения кодом из приложения (ConfirmTwoFactor); повторный вызов заменяет секрет.
+func (s *Service) SetupTwoFactor(ctx context.Context, userID uint) (*TwoFactorSetup, error) {
+	settings, err := s.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if settings.Enabled() {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SaveTOTP(ctx, &models.TOTP{UserID: userID, Secret: secret, CreatedAt: timeNow()}); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Пользователь %d начал настройку двухфакторной аутентификации", userID)
+	return &TwoFactorSetup{
+		Secret: secret,
+		URI:    totp.URI(TOTPIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTwoFactor включает двухфакторную аутентификацию после проверки кода из приложения
+// и возвращает коды восстановления. Коды показываются только один раз, в базе хранятся их хеши.
+func (s *Service) ConfirmTwoFactor(ctx context.Context, userID uint, code string) ([]string, error) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.WithTx(ctx, func(repo database.Repository) error {
+		settings, err := repo.GetTOTP(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if settings == nil {
+			return ErrTwoFactorSetupNotStarted
+		}
+		if settings.Enabled() {
+			return ErrTwoFactorEnabled
+		}
+
+		step, ok := totp.Validate(settings.Secret, code, timeNow())
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+		now := timeNow()
+		settings.EnabledAt = &now
+		settings.LastStep = step
+		if err := repo.SaveTOTP(ctx, settings); err != nil {
+			return err
+		}
+		return repo.ReplaceRecoveryCodes(ctx, userID, hashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Пользователь %d включил двухфакторную аутентификацию", userID)
+	return codes, nil
+}
+
+// DisableTwoFactor отключает двухфакторную аутентификацию после проверки текущего пароля
+func (s *Service) DisableTwoFactor(ctx context.Context, userID uint, password string) error {
+	if err := s.checkPassword(ctx, userID, password); err != nil {
+		return err
+	}
+
+	settings, err := s.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if settings == nil {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if err := s.repo.DeleteTOTP(ctx, userID); err != nil {
+		return err
+	}
+
+	log.Printf("Пользователь %d отключил двухфакторную аутентификацию", userID)
+	return nil
+}
+
+// RegenerateRecoveryCodes заменяет коды восстановления новыми после проверки текущего пароля;
+// прежние коды перестают действовать
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID uint, password string) ([]string, error) {
+	if err := s.checkPassword(ctx, userID, password); err != nil {
+		return nil, err
+	}
+
+	settings, err := s.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !settings.Enabled() {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Пользователь %d получил новые коды восстановления", userID)
+	return codes, nil
+}
+
+// checkPassword возвращает ErrInvalidCredentials, если пароль пользователя не совпадает.
+// Неудачные попытки учитываются в той же блокировке, что и при входе, иначе украденный
+// токен доступа позволял бы подбирать пароль без ограничений
+func (s *Service) checkPassword(ctx context.Context, userID uint, password string) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	lockoutKey := passwordLockoutKey(user.Email)
+	if err := s.checkLockout(ctx, lockoutKey); err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		s.recordFailure(ctx, lockoutKey)
+		return ErrInvalidCredentials
+	}
+	s.resetFailures(ctx, lockoutKey)
+	return nil
+}
+
+// newRecoveryCodes создает коды восстановления вида XXXX-XXXX-XXXX-XXXX (80 бит) и их хеши
+func newRecoveryCodes() (codes, hashes []string, err error) {
+	for i := 0; i < RecoveryCodeCount; i++ {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("ошибка при создании кода восстановления: %w", err)
+		}
+		raw := base32.StdEncoding.EncodeToString(b)
+		codes = append(codes, raw[0:4]+"-"+raw[4:8]+"-"+raw[8:12]+"-"+raw[12:16])
+		hashes = append(hashes, hashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode приводит введенный код восстановления к виду, от которого считается хеш
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/pkg/totp"
+)
+
+// totpCode возвращает код приложения-аутентификатора на момент t
+func totpCode(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+	code, err := totp.Code(secret, totp.Step(at))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// enableTwoFactor включает двухфакторную аутентификацию на момент now и возвращает секрет и коды восстановления
+func enableTwoFactor(t *testing.T, service *Service, userID uint, now time.Time) (string, []string) {
+	t.Helper()
+	ctx := context.Background()
+
+	setup, err := service.SetupTwoFactor(ctx, userID)
+	if err != nil {
+		t.Fatalf("SetupTwoFactor() error = %v, хотели nil", err)
+	}
+	codes, err := service.ConfirmTwoFactor(ctx, userID, totpCode(t, setup.Secret, now))
+	if err != nil {
+		t.Fatalf("ConfirmTwoFactor() error = %v, хотели nil", err)
+	}
+	return setup.Secret, codes
+}
+
+// TestSetupTwoFactor тестирует настройку двухфакторной аутентификации
+func TestSetupTwoFactor(t *testing.T) {
+	service, _, userID := newSessionTestService(t)
+	ctx := context.Background()
+	now := time.Now()
+	setTimeNow(t, now)
+
+	if _, err := service.ConfirmTwoFactor(ctx, userID, "123456"); err != ErrTwoFactorSetupNotStarted {
+		t.Errorf("ConfirmTwoFactor() без настройки error = %v, хотели %v", err, ErrTwoFactorSetupNotStarted)
+	}
+
+	setup, err := service.SetupTwoFactor(ctx, userID)
+	if err != nil {
+		t.Fatalf("SetupTwoFactor() error = %v, хотели nil", err)
+	}
+	if !strings.HasPrefix(setup.URI, "otpauth://totp/TimeTracker:test@example.com?") || !strings.Contains(setup.URI, setup.Secret) {
+		t.Errorf("URI = %q", setup.URI)
+	}
+
+	// До подтверждения кодом вход не меняется
+	tokens, err := service.Login(ctx, "test@example.com", "password123", ClientInfo{})
+	if err != nil || tokens.AccessToken == "" {
+		t.Fatalf("Login() до подтверждения = %+v, %v", tokens, err)
+	}
+
+	if _, err := service.ConfirmTwoFactor(ctx, userID, "000000"); err != ErrInvalidTwoFactorCode {
+		t.Errorf("ConfirmTwoFactor() неверным кодом error = %v, хотели %v", err, ErrInvalidTwoFactorCode)
+	}
+	codes, err := service.ConfirmTwoFactor(ctx, userID, totpCode(t, setup.Secret, now))
+	if err != nil {
+		t.Fatalf("ConfirmTwoFactor() error = %v, хотели nil", err)
+	}
+	if len(codes) != RecoveryCodeCount {
+		t.Errorf("Выдано %d кодов восстановления, хотели %d", len(codes), RecoveryCodeCount)
+	}
+
+	status, err := service.GetTwoFactorStatus(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetTwoFactorStatus() error = %v, хотели nil", err)
+	}
+	if !status.Enabled || status.RecoveryCodesLeft != RecoveryCodeCount {
+		t.Errorf("GetTwoFactorStatus() = %+v", status)
+	}
+
+	if _, err := service.SetupTwoFactor(ctx, userID); err != ErrTwoFactorEnabled {
+		t.Errorf("SetupTwoFactor() повторно error = %v, хотели %v", err, ErrTwoFactorEnabled)
+	}
+}
+
+// TestTwoFactorLogin тестирует двухэтапный вход
+func TestTwoFactorLogin(t *testing.T) {
+	service, _, userID := newSessionTestService(t)
+	ctx := context.Background()
+	now := time.Now()
+	setTimeNow(t, now)
+	secret, codes := enableTwoFactor(t, service, userID, now)
+
+	challenge, err := service.LoginWithRememberMe(ctx, "test@example.com", "password123", true, ClientInfo{})
+	if err != nil {
+		t.Fatalf("LoginWithRememberMe() error = %v, хотели nil", err)
+	}
+	if challenge.Challenge == "" || challenge.AccessToken != "" || challenge.RefreshToken != "" {
+		t.Fatalf("LoginWithRememberMe() = %+v, хотели только промежуточный токен", challenge)
+	}
+	if _, err := service.ValidateToken(ctx, challenge.Challenge); err == nil {
+		t.Error("ValidateToken() принял промежуточный токен входа")
+	}
+
+	// Код, которым подтверждена настройка, уже использован
+	if _, err := service.CompleteLogin(ctx, challenge.Challenge, totpCode(t, secret, now), ClientInfo{}); err != ErrInvalidTwoFactorCode {
+		t.Errorf("CompleteLogin() использованным кодом error = %v, хотели %v", err, ErrInvalidTwoFactorCode)
+	}
+
+	later := now.Add(totp.Period)
+	setTimeNow(t, later)
+	code := totpCode(t, secret, later)
+	tokens, err := service.CompleteLogin(ctx, challenge.Challenge, code, ClientInfo{UserAgent: "Firefox"})
+	if err != nil {
+		t.Fatalf("CompleteLogin() error = %v, хотели nil", err)
+	}
+	if !tokens.Session.RememberMe || tokens.Session.UserAgent != "Firefox" {
+		t.Errorf("Сессия открыта неверно: %+v", tokens.Session)
+	}
+	if _, err := service.ValidateToken(ctx, tokens.AccessToken); err != nil {
+		t.Errorf("ValidateToken() error = %v для токена после второго фактора", err)
+	}
+	if _, err := service.CompleteLogin(ctx, challenge.Challenge, code, ClientInfo{}); err != ErrInvalidTwoFactorCode {
+		t.Errorf("CompleteLogin() повторно тем же кодом error = %v, хотели %v", err, ErrInvalidTwoFactorCode)
+	}
+
+	// Промежуточный токен одноразовый: со следующим верным кодом сессия не открывается
+	later = later.Add(totp.Period)
+	setTimeNow(t, later)
+	if _, err := service.CompleteLogin(ctx, challenge.Challenge, totpCode(t, secret, later), ClientInfo{}); err != ErrInvalidChallenge {
+		t.Errorf("CompleteLogin() повторно тем же токеном входа error = %v, хотели %v", err, ErrInvalidChallenge)
+	}
+
+	// Код восстановления принимается без учета регистра и дефисов, но только один раз
+	challenge, err = service.Login(ctx, "test@example.com", "password123", ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login() error = %v, хотели nil", err)
+	}
+	recovery := strings.ToLower(strings.ReplaceAll(codes[0], "-", ""))
+	if _, err := service.CompleteLogin(ctx, challenge.Challenge, recovery, ClientInfo{}); err != nil {
+		t.Errorf("CompleteLogin() кодом восстановления error = %v, хотели nil", err)
+	}
+	if _, err := service.CompleteLogin(ctx, challenge.Challenge, codes[0], ClientInfo{}); err != ErrInvalidTwoFactorCode {
+		t.Errorf("CompleteLogin() использованным кодом восстановления error = %v, хотели %v", err, ErrInvalidTwoFactorCode)
+	}
+	status, _ := service.GetTwoFactorStatus(ctx, userID)
+	if status.RecoveryCodesLeft != RecoveryCodeCount-1 {
+		t.Errorf("Осталось %d кодов восстановления, хотели %d", status.RecoveryCodesLeft, RecoveryCodeCount-1)
+	}
+
+	// Токен доступа не заменяет промежуточный токен, истекший промежуточный токен не действует
+	if _, err := service.CompleteLogin(ctx, tokens.AccessToken, codes[1], ClientInfo{}); err != ErrInvalidChallenge {
+		t.Errorf("CompleteLogin() с токеном доступа error = %v, хотели %v", err, ErrInvalidChallenge)
+	}
+	setTimeNow(t, now.Add(10*time.Minute))
+	if _, err := service.CompleteLogin(ctx, challenge.Challenge, codes[1], ClientInfo{}); err != ErrInvalidChallenge {
+		t.Errorf("CompleteLogin() с истекшим токеном error = %v, хотели %v", err, ErrInvalidChallenge)
+	}
+}
+
+// TestDisableTwoFactor тестирует отключение двухфакторной аутентификации и замену кодов восстановления
+func TestDisableTwoFactor(t *testing.T) {
+	service, _, userID := newSessionTestService(t)
+	ctx := context.Background()
+	now := time.Now()
+	setTimeNow(t, now)
+
+	if _, err := service.RegenerateRecoveryCodes(ctx, userID, "password123"); err != ErrTwoFactorNotEnabled {
+		t.Errorf("RegenerateRecoveryCodes() без 2FA error = %v, хотели %v", err, ErrTwoFactorNotEnabled)
+	}
+
+	_, oldCodes := enableTwoFactor(t, service, userID, now)
+
+	if _, err := service.RegenerateRecoveryCodes(ctx, userID, "wrong"); err != ErrInvalidCredentials {
+		t.Errorf("RegenerateRecoveryCodes() с неверным паролем error = %v, хотели %v", err, ErrInvalidCredentials)
+	}
+	newCodes, err := service.RegenerateRecoveryCodes(ctx, userID, "password123")
+	if err != nil {
+		t.Fatalf("RegenerateRecoveryCodes() error = %v, хотели nil", err)
+	}
+
+	challenge, err := service.Login(ctx, "test@example.com", "password123", ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login() error = %v, хотели nil", err)
+	}
+	if _, err := service.CompleteLogin(ctx, challenge.Challenge, oldCodes[0], ClientInfo{}); err != ErrInvalidTwoFactorCode {
+		t.Errorf("CompleteLogin() прежним кодом восстановления error = %v, хотели %v", err, ErrInvalidTwoFactorCode)
+	}
+	if _, err := service.CompleteLogin(ctx, challenge.Challenge, newCodes[0], ClientInfo{}); err != nil {
+		t.Errorf("CompleteLogin() новым кодом восстановления error = %v, хотели nil", err)
+	}
+
+	if err := service.DisableTwoFactor(ctx, userID, "wrong"); err != ErrInvalidCredentials {
+		t.Errorf("DisableTwoFactor() с неверным паролем error = %v, хотели %v", err, ErrInvalidCredentials)
+	}
+	if err := service.DisableTwoFactor(ctx, userID, "password123"); err != nil {
+		t.Fatalf("DisableTwoFactor() error = %v, хотели nil", err)
+	}
+
+	// Выданный до отключения промежуточный токен больше не действует, вход снова без кода
+	if _, err := service.CompleteLogin(ctx, challenge.Challenge, newCodes[1], ClientInfo{}); err != ErrInvalidChallenge {
+		t.Errorf("CompleteLogin() после отключения error = %v, хотели %v", err, ErrInvalidChallenge)
+	}
+	tokens, err := service.Login(ctx, "test@example.com", "password123", ClientInfo{})
+	if err != nil || tokens.AccessToken == "" {
+		t.Errorf("Login() после отключения = %+v, %v", tokens, err)
+	}
+	if err := service.DisableTwoFactor(ctx, userID, "password123"); err != ErrTwoFactorNotEnabled {
+		t.Errorf("DisableTwoFactor() повторно error = %v, хотели %v", err, ErrTwoFactorNotEnabled)
+	}
+}
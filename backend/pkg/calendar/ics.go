@@ -0,0 +1,113 @@
+package calendar
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// icsTimeFormat - формат времени UTC в iCalendar (RFC 5545, 3.3.5)
+const icsTimeFormat = "20060102T150405Z"
+
+// maxLineOctets - наибольшая длина строки iCalendar в байтах без учета CRLF (RFC 5545, 3.1)
+const maxLineOctets = 75
+
+// icsWriter записывает строки iCalendar с экранированием и переносом длинных строк
+type icsWriter struct {
+	buf bytes.Buffer
+}
+
+// property записывает свойство с уже подготовленным значением. Строки длиннее 75 байт
+// переносятся: продолжение начинается с пробела, символы UTF-8 не разрываются.
+func (w *icsWriter) property(name, value string) {
+	line := name + ":" + value
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.buf.WriteString(line[:cut])
+		w.buf.WriteString("\r\n ")
+		line = line[cut:]
+		// Пробел в начале продолжения занимает один байт из лимита
+		limit = maxLineOctets - 1
+	}
+	w.buf.WriteString(line)
+	w.buf.WriteString("\r\n")
+}
+
+// text записывает текстовое свойство с экранированием
+func (w *icsWriter) text(name, value string) {
+	w.property(name, escapeText(value))
+}
+
+// time записывает свойство даты и времени в UTC
+func (w *icsWriter) time(name string, value time.Time) {
+	w.property(name, value.UTC().Format(icsTimeFormat))
+}
+
+// textEscaper экранирует символы текстовых значений iCalendar (RFC 5545, 3.3.11)
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// escapeText экранирует текстовое значение и удаляет управляющие символы, недопустимые в iCalendar
+func escapeText(value string) string {
+	value = strings.Map(func(r rune) rune {
+		if r < 0x20 && r != '\n' && r != '\r' && r != '\t' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, value)
+	return textEscaper.Replace(value)
+}
+
+// cssColors - именованные цвета CSS3 для свойства COLOR (RFC 7986), которое принимает только названия
+var cssColors = []struct {
+	name    string
+	r, g, b int
+}{
+	{"black", 0x00, 0x00, 0x00}, {"gray", 0x80, 0x80, 0x80}, {"silver", 0xc0, 0xc0, 0xc0},
+	{"white", 0xff, 0xff, 0xff}, {"maroon", 0x80, 0x00, 0x00}, {"red", 0xff, 0x00, 0x00},
+	{"crimson", 0xdc, 0x14, 0x3c}, {"coral", 0xff, 0x7f, 0x50}, {"orange", 0xff, 0xa5, 0x00},
+	{"gold", 0xff, 0xd7, 0x00}, {"yellow", 0xff, 0xff, 0x00}, {"olive", 0x80, 0x80, 0x00},
+	{"lime", 0x00, 0xff, 0x00}, {"green", 0x00, 0x80, 0x00}, {"seagreen", 0x2e, 0x8b, 0x57},
+	{"teal", 0x00, 0x80, 0x80}, {"turquoise", 0x40, 0xe0, 0xd0}, {"aqua", 0x00, 0xff, 0xff},
+	{"dodgerblue", 0x1e, 0x90, 0xff}, {"royalblue", 0x41, 0x69, 0xe1}, {"blue", 0x00, 0x00, 0xff},
+	{"navy", 0x00, 0x00, 0x80}, {"slateblue", 0x6a, 0x5a, 0xcd}, {"indigo", 0x4b, 0x00, 0x82},
+	{"purple", 0x80, 0x00, 0x80}, {"violet", 0xee, 0x82, 0xee}, {"fuchsia", 0xff, 0x00, 0xff},
+	{"pink", 0xff, 0xc0, 0xcb}, {"brown", 0xa5, 0x2a, 0x2a}, {"chocolate", 0xd2, 0x69, 0x1e},
+}
+
+// colorName возвращает ближайший именованный цвет CSS3 для цвета в формате #rrggbb или #rgb;
+// пустая строка - цвет не распознан
+func colorName(hex string) string {
+	hex = strings.TrimPrefix(strings.TrimSpace(hex), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return ""
+	}
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return ""
+	}
+	r, g, b := int(value>>16), int(value>>8&0xff), int(value&0xff)
+
+	best, bestDistance := "", -1
+	for _, color := range cssColors {
+		distance := (r-color.r)*(r-color.r) + (g-color.g)*(g-color.g) + (b-color.b)*(b-color.b)
+		if bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = color.name, distance
+		}
+	}
+	return best
+}
+
+// eventUID возвращает постоянный идентификатор события записи
+func eventUID(entryID uint) string {
+	return fmt.Sprintf("time-entry-%d@timetracker", entryID)
+}
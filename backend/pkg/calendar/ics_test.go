@@ -0,0 +1,62 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// TestPropertyFolding проверяет перенос длинных строк без разрыва символов UTF-8
+func TestPropertyFolding(t *testing.T) {
+	w := &icsWriter{}
+	w.text("DESCRIPTION", strings.Repeat("Длинное описание записи, ", 10))
+
+	lines := strings.Split(strings.TrimSuffix(w.buf.String(), "\r\n"), "\r\n")
+	if len(lines) < 2 {
+		t.Fatalf("длинная строка не перенесена: %q", w.buf.String())
+	}
+	var unfolded strings.Builder
+	for i, line := range lines {
+		if len(line) > maxLineOctets {
+			t.Errorf("строка %d длиной %d байт превышает %d", i, len(line), maxLineOctets)
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("строка %d разрывает символ UTF-8: %q", i, line)
+		}
+		if i > 0 {
+			if !strings.HasPrefix(line, " ") {
+				t.Errorf("продолжение %d не начинается с пробела: %q", i, line)
+			}
+			line = line[1:]
+		}
+		unfolded.WriteString(line)
+	}
+	if want := "DESCRIPTION:" + escapeText(strings.Repeat("Длинное описание записи, ", 10)); unfolded.String() != want {
+		t.Errorf("после склейки получено %q, хотели %q", unfolded.String(), want)
+	}
+}
+
+// TestEscapeText проверяет экранирование текстовых значений
+func TestEscapeText(t *testing.T) {
+	got := escapeText("a\\b;c,d\r\ne\nf\x00")
+	if want := `a\\b\;c\,d\ne\nf`; got != want {
+		t.Errorf("escapeText() = %q, хотели %q", got, want)
+	}
+}
+
+// TestColorName проверяет подбор названия цвета
+func TestColorName(t *testing.T) {
+	tests := map[string]string{
+		"#ff0000": "red",
+		"#F00":    "red",
+		"#4a6bff": "royalblue",
+		"#010101": "black",
+		"":        "",
+		"#zzzzzz": "",
+	}
+	for hex, want := range tests {
+		if got := colorName(hex); got != want {
+			t.Errorf("colorName(%q) = %q, хотели %q", hex, got, want)
+		}
+	}
+}
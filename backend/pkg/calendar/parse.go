@@ -0,0 +1,826 @@
+package calendar
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ошибки разбора файла iCalendar
+var (
+	ErrInvalidCalendar = errors.New("некорректный файл iCalendar")
+	ErrTooManyEvents   = fmt.Errorf("календарь содержит больше %d событий за период", MaxOccurrences)
+)
+
+const (
+	// MaxOccurrences - наибольшее количество экземпляров событий, получаемых из календаря за период
+	MaxOccurrences = 20000
+	// maxRuleIterations ограничивает перебор периодов правила повторения, которое никогда не срабатывает
+	maxRuleIterations = 100000
+	// maxLineLength - наибольшая длина строки файла после склейки продолжений
+	maxLineLength = 1 << 20
+)
+
+// Event - событие VEVENT из файла iCalendar
+type Event struct {
+	// Line - номер строки BEGIN:VEVENT в файле
+	Line        int
+	UID         string
+	Summary     string
+	Description string
+	Categories  []string
+	Start       time.Time
+	End         time.Time
+	// AllDay - событие на весь день (DTSTART со значением DATE)
+	AllDay    bool
+	Cancelled bool
+	// Rule - правило повторения RRULE; nil - событие не повторяется
+	Rule    *Rule
+	RDates  []time.Time
+	ExDates []time.Time
+	// RecurrenceID - экземпляр повторяющегося события, который заменяет это событие
+	RecurrenceID time.Time
+	// Err - ошибка разбора события; такое событие не разворачивается
+	Err error
+
+	// duration - длительность из свойства DURATION, если оно указано вместо DTEND
+	duration *time.Duration
+}
+
+// Occurrence - экземпляр события с конкретным временем
+type Occurrence struct {
+	Event *Event
+	Start time.Time
+	End   time.Time
+}
+
+// Key возвращает идентификатор экземпляра: UID события и начало экземпляра в UTC
+func (o Occurrence) Key() string {
+	return o.Event.UID + "/" + o.Start.UTC().Format(icsTimeFormat)
+}
+
+// property - строка содержимого iCalendar: название, параметры и значение
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// ParseEvents читает события VEVENT из файла iCalendar. Время без часового пояса (floating)
+// считается временем в loc. Ошибки отдельных событий сохраняются в Event.Err.
+func ParseEvents(r io.Reader, loc *time.Location) ([]*Event, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineLength)
+
+	var events []*Event
+	var stack []string
+	var current *Event
+	seenCalendar := false
+
+	handle := func(line int, text string) error {
+		if text == "" {
+			return nil
+		}
+		prop, err := parseProperty(text)
+		if err != nil {
+			if current != nil && len(stack) > 0 && stack[len(stack)-1] == "VEVENT" && current.Err == nil {
+				current.Err = fmt.Errorf("строка %d: %w", line, err)
+			}
+			return nil
+		}
+
+		switch prop.name {
+		case "BEGIN":
+			component := strings.ToUpper(prop.value)
+			if component == "VCALENDAR" {
+				seenCalendar = true
+			}
+			if component == "VEVENT" && len(stack) > 0 && stack[len(stack)-1] == "VCALENDAR" {
+				current = &Event{Line: line}
+			}
+			stack = append(stack, component)
+			return nil
+		case "END":
+			component := strings.ToUpper(prop.value)
+			if len(stack) == 0 || stack[len(stack)-1] != component {
+				return fmt.Errorf("%w: строка %d: END:%s без соответствующего BEGIN", ErrInvalidCalendar, line, prop.value)
+			}
+			stack = stack[:len(stack)-1]
+			if component == "VEVENT" && current != nil {
+				events = append(events, finishEvent(current))
+				current = nil
+			}
+			return nil
+		}
+
+		// Свойства вложенных компонентов (например, VALARM) не относятся к событию
+		if current == nil || stack[len(stack)-1] != "VEVENT" {
+			return nil
+		}
+		if err := current.apply(prop, loc); err != nil && current.Err == nil {
+			current.Err = fmt.Errorf("%s: %w", prop.name, err)
+		}
+		return nil
+	}
+
+	// Строки, начинающиеся с пробела или табуляции, продолжают предыдущую (RFC 5545, 3.1)
+	var logical strings.Builder
+	logicalLine, lineNumber := 0, 0
+	for scanner.Scan() {
+		lineNumber++
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		if lineNumber == 1 {
+			text = strings.TrimPrefix(text, "\uFEFF")
+		}
+		if len(text) > 0 && (text[0] == ' ' || text[0] == '\t') {
+			if logical.Len()+len(text) > maxLineLength {
+				return nil, fmt.Errorf("%w: строка %d слишком длинная", ErrInvalidCalendar, logicalLine)
+			}
+			logical.WriteString(text[1:])
+			continue
+		}
+		if err := handle(logicalLine, logical.String()); err != nil {
+			return nil, err
+		}
+		logical.Reset()
+		logical.WriteString(text)
+		logicalLine = lineNumber
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении календаря: %w", err)
+	}
+	if err := handle(logicalLine, logical.String()); err != nil {
+		return nil, err
+	}
+
+	if !seenCalendar {
+		return nil, fmt.Errorf("%w: нет BEGIN:VCALENDAR", ErrInvalidCalendar)
+	}
+	return events, nil
+}
+
+// parseProperty разбирает строку вида NAME;PARAM=value;PARAM="quoted":value
+func parseProperty(line string) (property, error) {
+	prop := property{params: make(map[string]string)}
+
+	end := strings.IndexAny(line, ";:")
+	if end <= 0 {
+		return prop, errors.New("некорректная строка")
+	}
+	prop.name = strings.ToUpper(line[:end])
+	rest := line[end:]
+
+	for strings.HasPrefix(rest, ";") {
+		rest = rest[1:]
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return prop, errors.New("некорректный параметр")
+		}
+		name := strings.ToUpper(rest[:eq])
+		rest = rest[eq+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			closing := strings.IndexByte(rest[1:], '"')
+			if closing < 0 {
+				return prop, errors.New("незакрытая кавычка в параметре")
+			}
+			value = rest[1 : closing+1]
+			rest = rest[closing+2:]
+		} else {
+			stop := strings.IndexAny(rest, ";:")
+			if stop < 0 {
+				return prop, errors.New("нет значения свойства")
+			}
+			value = rest[:stop]
+			rest = rest[stop:]
+		}
+		prop.params[name] = value
+	}
+
+	if !strings.HasPrefix(rest, ":") {
+		return prop, errors.New("нет значения свойства")
+	}
+	prop.value = rest[1:]
+	return prop, nil
+}
+
+// apply заполняет поле события по свойству
+func (e *Event) apply(prop property, loc *time.Location) error {
+	switch prop.name {
+	case "UID":
+		e.UID = strings.TrimSpace(prop.value)
+	case "SUMMARY":
+		e.Summary = strings.TrimSpace(unescapeText(prop.value))
+	case "DESCRIPTION":
+		e.Description = strings.TrimSpace(unescapeText(prop.value))
+	case "CATEGORIES":
+		for _, category := range splitText(prop.value) {
+			if category = strings.TrimSpace(category); category != "" {
+				e.Categories = append(e.Categories, category)
+			}
+		}
+	case "STATUS":
+		e.Cancelled = strings.EqualFold(strings.TrimSpace(prop.value), "CANCELLED")
+	case "DTSTART":
+		start, allDay, err := parseDateTime(prop.value, prop.params, loc)
+		if err != nil {
+			return err
+		}
+		e.Start, e.AllDay = start, allDay
+	case "DTEND":
+		end, _, err := parseDateTime(prop.value, prop.params, loc)
+		if err != nil {
+			return err
+		}
+		e.End = end
+	case "DURATION":
+		duration, err := parseICSDuration(prop.value)
+		if err != nil {
+			return err
+		}
+		// Окончание вычисляется после разбора всех свойств, когда известно начало
+		e.duration = &duration
+	case "RRULE":
+		rule, err := parseRule(prop.value, loc)
+		if err != nil {
+			return err
+		}
+		e.Rule = rule
+	case "RDATE", "EXDATE":
+		if strings.EqualFold(prop.params["VALUE"], "PERIOD") {
+			return errors.New("значения PERIOD не поддерживаются")
+		}
+		for _, value := range strings.Split(prop.value, ",") {
+			t, _, err := parseDateTime(strings.TrimSpace(value), prop.params, loc)
+			if err != nil {
+				return err
+			}
+			if prop.name == "RDATE" {
+				e.RDates = append(e.RDates, t)
+			} else {
+				e.ExDates = append(e.ExDates, t)
+			}
+		}
+	case "RECURRENCE-ID":
+		t, _, err := parseDateTime(prop.value, prop.params, loc)
+		if err != nil {
+			return err
+		}
+		e.RecurrenceID = t
+	}
+	return nil
+}
+
+// finishEvent проверяет обязательные свойства и вычисляет окончание события
+func finishEvent(e *Event) *Event {
+	if e.Err != nil {
+		return e
+	}
+	if e.UID == "" {
+		// Без UID экземпляры нельзя сопоставить при повторном импорте, но событие можно использовать
+		e.UID = fmt.Sprintf("line-%d", e.Line)
+	}
+	if e.Start.IsZero() {
+		e.Err = errors.New("не указано начало события (DTSTART)")
+		return e
+	}
+
+	switch {
+	case e.duration != nil && e.End.IsZero():
+		e.End = e.Start.Add(*e.duration)
+	case e.duration != nil:
+		e.Err = errors.New("DTEND и DURATION нельзя указывать вместе")
+		return e
+	case e.End.IsZero() && e.AllDay:
+		// Событие на весь день без окончания длится один день
+		e.End = e.Start.AddDate(0, 0, 1)
+	case e.End.IsZero():
+		e.End = e.Start
+	}
+	if e.End.Before(e.Start) {
+		e.Err = errors.New("окончание события раньше начала")
+	}
+	return e
+}
+
+// parseDateTime разбирает значение DATE или DATE-TIME с учетом параметров TZID и VALUE.
+// Возвращает признак значения DATE (событие на весь день).
+func parseDateTime(value string, params map[string]string, loc *time.Location) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	if strings.EqualFold(params["VALUE"], "DATE") || len(value) == 8 {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("некорректная дата %q", value)
+		}
+		return t, true, nil
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(icsTimeFormat, value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("некорректное время %q", value)
+		}
+		return t, false, nil
+	}
+
+	if tzid := params["TZID"]; tzid != "" {
+		// Некоторые программы добавляют к названию часового пояса префикс "/"
+		tzLoc, err := time.LoadLocation(strings.TrimPrefix(tzid, "/"))
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("неизвестный часовой пояс %q", tzid)
+		}
+		loc = tzLoc
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("некорректное время %q", value)
+	}
+	return t, false, nil
+}
+
+// parseICSDuration разбирает длительность вида P1W, P1DT2H30M, PT45M (RFC 5545, 3.3.6)
+func parseICSDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	value = strings.TrimPrefix(value, "+")
+	if strings.HasPrefix(value, "-") {
+		return 0, errors.New("отрицательная длительность")
+	}
+	if !strings.HasPrefix(value, "P") || len(value) < 3 {
+		return 0, fmt.Errorf("некорректная длительность %q", value)
+	}
+
+	var total time.Duration
+	inTime := false
+	number := ""
+	for _, r := range value[1:] {
+		switch {
+		case r >= '0' && r <= '9':
+			number += string(r)
+			continue
+		case r == 'T' && number == "" && !inTime:
+			inTime = true
+			continue
+		}
+
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return 0, fmt.Errorf("некорректная длительность %q", value)
+		}
+		number = ""
+
+		var unit time.Duration
+		switch {
+		case r == 'W' && !inTime:
+			unit = 7 * 24 * time.Hour
+		case r == 'D' && !inTime:
+			unit = 24 * time.Hour
+		case r == 'H' && inTime:
+			unit = time.Hour
+		case r == 'M' && inTime:
+			unit = time.Minute
+		case r == 'S' && inTime:
+			unit = time.Second
+		default:
+			return 0, fmt.Errorf("некорректная длительность %q", value)
+		}
+		total += time.Duration(n) * unit
+	}
+	if number != "" {
+		return 0, fmt.Errorf("некорректная длительность %q", value)
+	}
+	return total, nil
+}
+
+// textUnescaper снимает экранирование текстовых значений iCalendar
+var textUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+// unescapeText снимает экранирование текстового значения
+func unescapeText(value string) string {
+	return textUnescaper.Replace(value)
+}
+
+// splitText разбивает список текстовых значений по неэкранированным запятым
+func splitText(value string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case ',':
+			parts = append(parts, unescapeText(value[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, unescapeText(value[start:]))
+}
+
+// weekdays - дни недели в обозначениях RFC 5545
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// weekdayNum - элемент BYDAY: день недели и, для MONTHLY и YEARLY, его номер в месяце или году
+// (отрицательный - с конца; 0 - все такие дни)
+type weekdayNum struct {
+	n   int
+	day time.Weekday
+}
+
+// Rule - правило повторения RRULE. Поддерживаются частоты DAILY, WEEKLY, MONTHLY и YEARLY
+// с частями INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY, BYMONTH и WKST.
+type Rule struct {
+	freq       string
+	interval   int
+	count      int
+	until      time.Time
+	byDay      []weekdayNum
+	byMonthDay []int
+	byMonth    []time.Month
+	weekStart  time.Weekday
+}
+
+// parseRule разбирает значение RRULE
+func parseRule(value string, loc *time.Location) (*Rule, error) {
+	rule := &Rule{interval: 1, weekStart: time.Monday}
+	for _, part := range strings.Split(value, ";") {
+		if part == "" {
+			continue
+		}
+		eq := strings.IndexByte(part, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("некорректная часть правила %q", part)
+		}
+		name, val := strings.ToUpper(part[:eq]), strings.ToUpper(part[eq+1:])
+
+		var err error
+		switch name {
+		case "FREQ":
+			switch val {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				rule.freq = val
+			default:
+				return nil, fmt.Errorf("частота %s не поддерживается", val)
+			}
+		case "INTERVAL":
+			rule.interval, err = strconv.Atoi(val)
+			if err == nil && rule.interval < 1 {
+				err = errors.New("интервал должен быть положительным")
+			}
+		case "COUNT":
+			rule.count, err = strconv.Atoi(val)
+			if err == nil && rule.count < 1 {
+				err = errors.New("количество повторений должно быть положительным")
+			}
+		case "UNTIL":
+			rule.until, _, err = parseDateTime(val, nil, loc)
+			if err == nil && len(val) == 8 {
+				// Дата без времени включает весь день
+				rule.until = rule.until.AddDate(0, 0, 1).Add(-time.Second)
+			}
+		case "BYDAY":
+			for _, item := range strings.Split(val, ",") {
+				if len(item) < 2 {
+					return nil, fmt.Errorf("некорректный день недели %q", item)
+				}
+				day, ok := weekdays[item[len(item)-2:]]
+				if !ok {
+					return nil, fmt.Errorf("некорректный день недели %q", item)
+				}
+				n := 0
+				if prefix := item[:len(item)-2]; prefix != "" {
+					n, err = strconv.Atoi(strings.TrimPrefix(prefix, "+"))
+					if err != nil || n == 0 || n < -53 || n > 53 {
+						return nil, fmt.Errorf("некорректный день недели %q", item)
+					}
+				}
+				rule.byDay = append(rule.byDay, weekdayNum{n: n, day: day})
+			}
+		case "BYMONTHDAY":
+			for _, item := range strings.Split(val, ",") {
+				day, convErr := strconv.Atoi(item)
+				if convErr != nil || day == 0 || day < -31 || day > 31 {
+					return nil, fmt.Errorf("некорректный день месяца %q", item)
+				}
+				rule.byMonthDay = append(rule.byMonthDay, day)
+			}
+		case "BYMONTH":
+			for _, item := range strings.Split(val, ",") {
+				month, convErr := strconv.Atoi(item)
+				if convErr != nil || month < 1 || month > 12 {
+					return nil, fmt.Errorf("некорректный месяц %q", item)
+				}
+				rule.byMonth = append(rule.byMonth, time.Month(month))
+			}
+		case "WKST":
+			day, ok := weekdays[val]
+			if !ok {
+				return nil, fmt.Errorf("некорректный день недели %q", val)
+			}
+			rule.weekStart = day
+		default:
+			return nil, fmt.Errorf("часть правила %s не поддерживается", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	if rule.freq == "" {
+		return nil, errors.New("не указана частота повторения (FREQ)")
+	}
+	if rule.count > 0 && !rule.until.IsZero() {
+		return nil, errors.New("COUNT и UNTIL нельзя указывать вместе")
+	}
+	for _, day := range rule.byDay {
+		if day.n != 0 && rule.freq != "MONTHLY" && rule.freq != "YEARLY" {
+			return nil, errors.New("номер дня недели в BYDAY допустим только для MONTHLY и YEARLY")
+		}
+	}
+	return rule, nil
+}
+
+// Expand разворачивает события в экземпляры, начинающиеся в [from, to), по возрастанию начала.
+// Повторяющиеся события разворачиваются по RRULE и RDATE за вычетом EXDATE; экземпляры,
+// измененные отдельными событиями с RECURRENCE-ID, заменяются ими. Отмененные события
+// (STATUS:CANCELLED) пропускаются. Ошибка правила повторения сохраняется в Event.Err.
+func Expand(events []*Event, from, to time.Time) ([]Occurrence, error) {
+	// Измененные экземпляры повторяющихся событий: UID -> начало исходного экземпляра
+	overridden := make(map[string]map[int64]bool)
+	for _, event := range events {
+		if event.Err == nil && !event.RecurrenceID.IsZero() {
+			if overridden[event.UID] == nil {
+				overridden[event.UID] = make(map[int64]bool)
+			}
+			overridden[event.UID][event.RecurrenceID.Unix()] = true
+		}
+	}
+
+	var occurrences []Occurrence
+	for _, event := range events {
+		if event.Err != nil {
+			continue
+		}
+		duration := event.End.Sub(event.Start)
+
+		var starts []time.Time
+		if event.Rule == nil || !event.RecurrenceID.IsZero() {
+			starts = append([]time.Time{event.Start}, event.RDates...)
+		} else {
+			var err error
+			starts, err = event.Rule.expand(event.Start, from, to)
+			if err != nil {
+				event.Err = err
+				continue
+			}
+			starts = append(starts, event.RDates...)
+		}
+
+		excluded := make(map[int64]bool, len(event.ExDates))
+		for _, exdate := range event.ExDates {
+			excluded[exdate.Unix()] = true
+		}
+		seen := make(map[int64]bool, len(starts))
+		for _, start := range starts {
+			key := start.Unix()
+			if seen[key] || excluded[key] || start.Before(from) || !start.Before(to) {
+				continue
+			}
+			seen[key] = true
+			if event.RecurrenceID.IsZero() && overridden[event.UID][key] {
+				continue
+			}
+			if event.Cancelled {
+				continue
+			}
+			occurrences = append(occurrences, Occurrence{Event: event, Start: start, End: start.Add(duration)})
+			if len(occurrences) > MaxOccurrences {
+				return nil, ErrTooManyEvents
+			}
+		}
+	}
+
+	sort.SliceStable(occurrences, func(i, j int) bool { return occurrences[i].Start.Before(occurrences[j].Start) })
+	return occurrences, nil
+}
+
+// expand возвращает начала экземпляров правила в [from, to]. Экземпляры до from перебираются,
+// чтобы учесть COUNT. Время суток экземпляров сохраняется в часовом поясе dtstart,
+// в том числе при переходе на летнее время.
+func (rule *Rule) expand(dtstart, from, to time.Time) ([]time.Time, error) {
+	loc := dtstart.Location()
+	hour, minute, second := dtstart.Clock()
+	until := rule.until
+	if until.IsZero() || until.After(to) {
+		until = to
+	}
+
+	// Первое событие - всегда DTSTART, даже если оно не подходит под правило (RFC 5545, 3.8.5.3)
+	var starts []time.Time
+	if !dtstart.Before(from) {
+		starts = append(starts, dtstart)
+	}
+	count := 1
+	if rule.count > 0 && count >= rule.count {
+		return starts, nil
+	}
+
+	year, month, day := dtstart.Date()
+	for i := 0; ; i++ {
+		if i == maxRuleIterations {
+			return nil, errors.New("правило повторения дает слишком много периодов до выбранного интервала")
+		}
+		var days []time.Time
+		var periodStart time.Time
+		switch rule.freq {
+		case "DAILY":
+			periodStart = time.Date(year, month, day+i*rule.interval, 0, 0, 0, 0, loc)
+			if rule.matchesDay(periodStart) {
+				days = append(days, periodStart)
+			}
+		case "WEEKLY":
+			offset := (int(dtstart.Weekday()) - int(rule.weekStart) + 7) % 7
+			periodStart = time.Date(year, month, day-offset+7*i*rule.interval, 0, 0, 0, 0, loc)
+			for d := 0; d < 7; d++ {
+				date := periodStart.AddDate(0, 0, d)
+				if rule.matchesWeekly(date, dtstart.Weekday()) {
+					days = append(days, date)
+				}
+			}
+		case "MONTHLY":
+			periodStart = time.Date(year, month+time.Month(i*rule.interval), 1, 0, 0, 0, 0, loc)
+			if len(rule.byMonth) == 0 || containsMonth(rule.byMonth, periodStart.Month()) {
+				days = rule.monthDays(periodStart, day)
+			}
+		case "YEARLY":
+			periodStart = time.Date(year+i*rule.interval, 1, 1, 0, 0, 0, 0, loc)
+			days = rule.yearDays(periodStart, month, day)
+		}
+		if !periodStart.Before(until) {
+			break
+		}
+
+		sort.Slice(days, func(a, b int) bool { return days[a].Before(days[b]) })
+		for _, date := range days {
+			start := time.Date(date.Year(), date.Month(), date.Day(), hour, minute, second, 0, loc)
+			if !start.After(dtstart) {
+				continue
+			}
+			if start.After(until) {
+				return starts, nil
+			}
+			if !start.Before(from) {
+				starts = append(starts, start)
+			}
+			count++
+			if rule.count > 0 && count >= rule.count {
+				return starts, nil
+			}
+			if len(starts) > MaxOccurrences {
+				return nil, ErrTooManyEvents
+			}
+		}
+	}
+	return starts, nil
+}
+
+// matchesDay проверяет день по BYMONTH, BYMONTHDAY и BYDAY для частоты DAILY
+func (rule *Rule) matchesDay(date time.Time) bool {
+	if len(rule.byMonth) > 0 && !containsMonth(rule.byMonth, date.Month()) {
+		return false
+	}
+	if len(rule.byMonthDay) > 0 && !matchesMonthDay(rule.byMonthDay, date) {
+		return false
+	}
+	if len(rule.byDay) > 0 {
+		for _, day := range rule.byDay {
+			if day.day == date.Weekday() {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// matchesWeekly проверяет день недели для частоты WEEKLY: без BYDAY повторяется день недели DTSTART
+func (rule *Rule) matchesWeekly(date time.Time, startDay time.Weekday) bool {
+	if len(rule.byMonth) > 0 && !containsMonth(rule.byMonth, date.Month()) {
+		return false
+	}
+	if len(rule.byDay) == 0 {
+		return date.Weekday() == startDay
+	}
+	for _, day := range rule.byDay {
+		if day.day == date.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// monthDays возвращает дни месяца monthStart по BYMONTHDAY и BYDAY; без них - день startDay,
+// если он есть в месяце
+func (rule *Rule) monthDays(monthStart time.Time, startDay int) []time.Time {
+	last := daysIn(monthStart)
+	var days []time.Time
+	switch {
+	case len(rule.byDay) > 0:
+		days = weekdaysIn(monthStart, last, rule.byDay)
+		if len(rule.byMonthDay) > 0 {
+			filtered := days[:0]
+			for _, date := range days {
+				if matchesMonthDay(rule.byMonthDay, date) {
+					filtered = append(filtered, date)
+				}
+			}
+			days = filtered
+		}
+	case len(rule.byMonthDay) > 0:
+		for _, monthDay := range rule.byMonthDay {
+			if monthDay < 0 {
+				monthDay = last + monthDay + 1
+			}
+			if monthDay >= 1 && monthDay <= last {
+				days = append(days, monthStart.AddDate(0, 0, monthDay-1))
+			}
+		}
+	case startDay <= last:
+		days = append(days, monthStart.AddDate(0, 0, startDay-1))
+	}
+	return days
+}
+
+// yearDays возвращает дни года yearStart. С BYMONTH дни выбираются в каждом указанном месяце;
+// без BYMONTH номер дня недели в BYDAY отсчитывается от начала или конца года.
+func (rule *Rule) yearDays(yearStart time.Time, startMonth time.Month, startDay int) []time.Time {
+	if len(rule.byMonth) == 0 && len(rule.byDay) > 0 && len(rule.byMonthDay) == 0 {
+		yearDays := time.Date(yearStart.Year(), 12, 31, 0, 0, 0, 0, time.UTC).YearDay()
+		return weekdaysIn(yearStart, yearDays, rule.byDay)
+	}
+
+	months := rule.byMonth
+	if len(months) == 0 {
+		months = []time.Month{startMonth}
+	}
+	var days []time.Time
+	for _, month := range months {
+		monthStart := time.Date(yearStart.Year(), month, 1, 0, 0, 0, 0, yearStart.Location())
+		days = append(days, rule.monthDays(monthStart, startDay)...)
+	}
+	return days
+}
+
+// weekdaysIn возвращает дни из length дней, начиная с periodStart, подходящие под BYDAY
+func weekdaysIn(periodStart time.Time, length int, byDay []weekdayNum) []time.Time {
+	var days []time.Time
+	for _, item := range byDay {
+		var matches []time.Time
+		first := (int(item.day) - int(periodStart.Weekday()) + 7) % 7
+		for d := first; d < length; d += 7 {
+			matches = append(matches, periodStart.AddDate(0, 0, d))
+		}
+		switch {
+		case item.n == 0:
+			days = append(days, matches...)
+		case item.n > 0 && item.n <= len(matches):
+			days = append(days, matches[item.n-1])
+		case item.n < 0 && -item.n <= len(matches):
+			days = append(days, matches[len(matches)+item.n])
+		}
+	}
+	return days
+}
+
+// matchesMonthDay проверяет день месяца по BYMONTHDAY (отрицательные значения - с конца месяца)
+func matchesMonthDay(byMonthDay []int, date time.Time) bool {
+	last := daysIn(date)
+	for _, monthDay := range byMonthDay {
+		if monthDay == date.Day() || monthDay < 0 && last+monthDay+1 == date.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+// containsMonth сообщает, есть ли месяц в списке
+func containsMonth(months []time.Month, month time.Month) bool {
+	for _, m := range months {
+		if m == month {
+			return true
+		}
+	}
+	return false
+}
+
+// daysIn возвращает количество дней в месяце даты
+func daysIn(date time.Time) int {
+	return time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
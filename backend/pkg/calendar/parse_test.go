@@ -0,0 +1,213 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// icsFile собирает календарь из строк с переводами строк CRLF
+func icsFile(lines ...string) string {
+	return strings.Join(append(append([]string{"BEGIN:VCALENDAR", "VERSION:2.0"}, lines...), "END:VCALENDAR"), "\r\n") + "\r\n"
+}
+
+// TestParseEvents проверяет разбор свойств, параметров и продолжений строк
+func TestParseEvents(t *testing.T) {
+	data := icsFile(
+		"BEGIN:VEVENT",
+		"UID:a@example.com",
+		"SUMMARY:Планирование\\, спринт",
+		"DESCRIPTION:Первая строка\\nвторая",
+		"  строка",
+		"CATEGORIES:Работа,Встречи",
+		`DTSTART;TZID="Europe/Moscow":20240305T100000`,
+		"DURATION:PT1H30M",
+		"BEGIN:VALARM",
+		"DESCRIPTION:Напоминание",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:b@example.com",
+		"DTSTART:20240306T070000Z",
+		"DTEND:20240306T080000Z",
+		"RRULE:FREQ=HOURLY",
+		"END:VEVENT",
+	)
+
+	events, err := ParseEvents(strings.NewReader("\uFEFF"+data), time.UTC)
+	if err != nil {
+		t.Fatalf("ParseEvents() error = %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("ParseEvents() вернул %d событий, хотели 2", len(events))
+	}
+
+	event := events[0]
+	if event.Err != nil {
+		t.Fatalf("первое событие с ошибкой: %v", event.Err)
+	}
+	if event.Summary != "Планирование, спринт" {
+		t.Errorf("Summary = %q", event.Summary)
+	}
+	if event.Description != "Первая строка\nвторая строка" {
+		t.Errorf("Description = %q, продолжение строки и вложенный VALARM должны обрабатываться", event.Description)
+	}
+	if len(event.Categories) != 2 || event.Categories[1] != "Встречи" {
+		t.Errorf("Categories = %v", event.Categories)
+	}
+	moscow, _ := time.LoadLocation("Europe/Moscow")
+	if want := time.Date(2024, 3, 5, 10, 0, 0, 0, moscow); !event.Start.Equal(want) || !event.End.Equal(want.Add(90*time.Minute)) {
+		t.Errorf("событие %v - %v, хотели начало %v и длительность 1ч30м", event.Start, event.End, want)
+	}
+	if event.Line != 3 {
+		t.Errorf("Line = %d, хотели 3", event.Line)
+	}
+
+	if events[1].Err == nil {
+		t.Errorf("событие с FREQ=HOURLY должно содержать ошибку")
+	}
+
+	if _, err := ParseEvents(strings.NewReader("SUMMARY:не календарь\r\n"), time.UTC); err == nil {
+		t.Errorf("ParseEvents() без VCALENDAR должен вернуть ошибку")
+	}
+}
+
+// expandOne разбирает одно событие и разворачивает его за период
+func expandOne(t *testing.T, from, to time.Time, lines ...string) []Occurrence {
+	t.Helper()
+	events, err := ParseEvents(strings.NewReader(icsFile(lines...)), time.UTC)
+	if err != nil {
+		t.Fatalf("ParseEvents() error = %v", err)
+	}
+	occurrences, err := Expand(events, from, to)
+	if err != nil {
+		t.Fatalf("Expand() error = %v", err)
+	}
+	for _, event := range events {
+		if event.Err != nil {
+			t.Fatalf("событие с ошибкой: %v", event.Err)
+		}
+	}
+	return occurrences
+}
+
+// starts возвращает начала экземпляров в формате ICS
+func starts(occurrences []Occurrence) []string {
+	result := make([]string, len(occurrences))
+	for i, occurrence := range occurrences {
+		result[i] = occurrence.Start.UTC().Format(icsTimeFormat)
+	}
+	return result
+}
+
+// TestExpand проверяет разворачивание правил повторения
+func TestExpand(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		lines []string
+		want  []string
+	}{
+		{
+			name: "еженедельно по будням с исключением",
+			lines: []string{
+				"DTSTART:20240101T090000Z", "DTEND:20240101T093000Z",
+				"RRULE:FREQ=WEEKLY;BYDAY=MO,WE,FR;COUNT=5", "EXDATE:20240103T090000Z",
+			},
+			want: []string{"20240101T090000Z", "20240105T090000Z", "20240108T090000Z", "20240110T090000Z"},
+		},
+		{
+			name:  "каждый второй день до даты",
+			lines: []string{"DTSTART:20240228T120000Z", "RRULE:FREQ=DAILY;INTERVAL=2;UNTIL=20240305"},
+			want:  []string{"20240228T120000Z", "20240301T120000Z", "20240303T120000Z", "20240305T120000Z"},
+		},
+		{
+			name:  "последняя пятница месяца",
+			lines: []string{"DTSTART:20240126T150000Z", "RRULE:FREQ=MONTHLY;BYDAY=-1FR;COUNT=3"},
+			want:  []string{"20240126T150000Z", "20240223T150000Z", "20240329T150000Z"},
+		},
+		{
+			name:  "31 число пропускает короткие месяцы",
+			lines: []string{"DTSTART:20240131T080000Z", "RRULE:FREQ=MONTHLY;COUNT=3"},
+			want:  []string{"20240131T080000Z", "20240331T080000Z", "20240531T080000Z"},
+		},
+		{
+			name:  "ежегодно 29 февраля",
+			lines: []string{"DTSTART:20200229T100000Z", "RRULE:FREQ=YEARLY"},
+			want:  []string{"20240229T100000Z"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := append(append([]string{"BEGIN:VEVENT", "UID:x"}, tt.lines...), "END:VEVENT")
+			got := starts(expandOne(t, from, to, lines...))
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Expand() = %v, хотели %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestExpandTimezoneAndOverrides проверяет сохранение местного времени при переходе на летнее время
+// и замену экземпляров событиями с RECURRENCE-ID
+func TestExpandTimezoneAndOverrides(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC)
+
+	occurrences := expandOne(t, from, to,
+		"BEGIN:VEVENT",
+		"UID:standup",
+		"DTSTART;TZID=Europe/Berlin:20240329T090000",
+		"DTEND;TZID=Europe/Berlin:20240329T091500",
+		"RRULE:FREQ=DAILY;COUNT=4",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:standup",
+		"RECURRENCE-ID;TZID=Europe/Berlin:20240330T090000",
+		"DTSTART;TZID=Europe/Berlin:20240330T110000",
+		"DTEND;TZID=Europe/Berlin:20240330T120000",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:standup",
+		"RECURRENCE-ID;TZID=Europe/Berlin:20240401T090000",
+		"DTSTART;TZID=Europe/Berlin:20240401T090000",
+		"STATUS:CANCELLED",
+		"END:VEVENT",
+	)
+
+	// 31 марта Берлин переходит на летнее время: 09:00 - это 08:00 UTC до перехода и 07:00 UTC после
+	want := []string{"20240329T080000Z", "20240330T100000Z", "20240331T070000Z"}
+	if got := starts(occurrences); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("Expand() = %v, хотели %v", got, want)
+	}
+	if duration := occurrences[1].End.Sub(occurrences[1].Start); duration != time.Hour {
+		t.Errorf("измененный экземпляр длится %v, хотели 1ч", duration)
+	}
+	if key := occurrences[0].Key(); key != "standup/20240329T080000Z" {
+		t.Errorf("Key() = %q", key)
+	}
+}
+
+// TestParseICSDuration проверяет длительности iCalendar
+func TestParseICSDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"PT45M":     45 * time.Minute,
+		"P1DT2H":    26 * time.Hour,
+		"P1W":       7 * 24 * time.Hour,
+		"+PT1H0M5S": time.Hour + 5*time.Second,
+	}
+	for value, want := range tests {
+		got, err := parseICSDuration(value)
+		if err != nil || got != want {
+			t.Errorf("parseICSDuration(%q) = %v, %v, хотели %v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"PT", "P1H", "-PT1H", "1H"} {
+		if _, err := parseICSDuration(value); err == nil {
+			t.Errorf("parseICSDuration(%q) без ошибки", value)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package calendar
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/pkg/database"
+	"github.com/graywrk/timetracker/backend/pkg/statistics"
+)
+
+// Определение типовых ошибок
+var (
+	ErrTokenNotFound = errors.New("токен календаря не найден")
+	ErrInvalidWindow = errors.New("некорректный период: ожидаются даты в формате YYYY-MM-DD, начало не позже окончания")
+	ErrWindowTooLong = fmt.Errorf("период календаря не может быть длиннее %d дней", MaxWindowDays)
+)
+
+const (
+	// DefaultWindowDays - период календаря по умолчанию: столько дней до текущего дня включительно
+	DefaultWindowDays = 90
+	// MaxWindowDays - наибольшая длина периода календаря в днях
+	MaxWindowDays = 731
+	// tokenBytes - длина случайной части токена
+	tokenBytes = 32
+	// pageSize - количество записей, загружаемых за один запрос при формировании календаря
+	pageSize = 500
+)
+
+// Service выдает и отзывает токены календаря и формирует календарь ICS по токену
+type Service struct {
+	repo database.Repository
+}
+
+// NewService создает новый сервис календаря
+func NewService(repo database.Repository) *Service {
+	return &Service{
+		repo: repo,
+	}
+}
+
+// Feed - сформированный календарь
+type Feed struct {
+	Body []byte
+	// ETag - строгий тег содержимого в кавычках, меняется при любом изменении календаря
+	ETag string
+}
+
+// hashToken возвращает хеш токена, под которым он хранится в базе
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateToken создает токен календаря. Возвращает запись о токене и сам токен:
+// он показывается только один раз, в базе хранится его хеш.
+func (s *Service) CreateToken(ctx context.Context, userID uint, name string) (*models.CalendarToken, string, error) {
+	random := make([]byte, tokenBytes)
+	if _, err := rand.Read(random); err != nil {
+		return nil, "", fmt.Errorf("ошибка при создании токена календаря: %w", err)
+	}
+	secret := base64.RawURLEncoding.EncodeToString(random)
+
+	token := &models.CalendarToken{
+		UserID:    userID,
+		Name:      strings.TrimSpace(name),
+		TokenHash: hashToken(secret),
+	}
+	if err := s.repo.CreateCalendarToken(ctx, token); err != nil {
+		return nil, "", err
+	}
+
+	return token, secret, nil
+}
+
+// GetTokens возвращает токены календаря пользователя (без самих токенов)
+func (s *Service) GetTokens(ctx context.Context, userID uint) ([]*models.CalendarToken, error) {
+	tokens, err := s.repo.GetCalendarTokensByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tokens == nil {
+		tokens = []*models.CalendarToken{}
+	}
+	return tokens, nil
+}
+
+// RevokeToken отзывает токен календаря пользователя: ссылка с ним перестает работать
+func (s *Service) RevokeToken(ctx context.Context, userID, tokenID uint) error {
+	tokens, err := s.repo.GetCalendarTokensByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		if token.ID == tokenID {
+			return s.repo.DeleteCalendarToken(ctx, tokenID)
+		}
+	}
+	return ErrTokenNotFound
+}
+
+// GetFeed формирует календарь завершенных записей владельца токена. startDate и endDate
+// (YYYY-MM-DD, включительно, в часовом поясе пользователя) задают период; без них
+// календарь содержит DefaultWindowDays последних дней.
+func (s *Service) GetFeed(ctx context.Context, secret, startDate, endDate string) (*Feed, error) {
+	token, err := s.repo.GetCalendarTokenByHash(ctx, hashToken(secret))
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, ErrTokenNotFound
+	}
+
+	user, err := s.repo.GetUserByID(ctx, token.UserID)
+	if err != nil {
+		return nil, err
+	}
+	loc := time.UTC
+	if user != nil {
+		loc = user.Location()
+	}
+
+	from, to, err := feedWindow(startDate, endDate, time.Now().In(loc))
+	if err != nil {
+		return nil, err
+	}
+
+	w := &icsWriter{}
+	w.property("BEGIN", "VCALENDAR")
+	w.property("VERSION", "2.0")
+	w.property("PRODID", "-//TimeTracker//Calendar//RU")
+	w.property("CALSCALE", "GREGORIAN")
+	w.property("METHOD", "PUBLISH")
+	w.text("X-WR-CALNAME", "TimeTracker")
+	w.property("X-WR-TIMEZONE", loc.String())
+
+	filter := database.TimeEntryFilter{
+		UserID:   token.UserID,
+		From:     from.UTC(),
+		To:       to.UTC(),
+		Statuses: []models.Status{models.StatusCompleted},
+		SortAsc:  true,
+		Limit:    pageSize,
+	}
+	for {
+		entries, err := s.repo.ListTimeEntries(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при получении записей: %w", err)
+		}
+		for _, entry := range entries {
+			writeEvent(w, entry)
+		}
+		if len(entries) < pageSize {
+			break
+		}
+		last := entries[len(entries)-1]
+		filter.After = &database.TimeEntryCursor{StartTime: last.StartTime, ID: last.ID}
+	}
+	w.property("END", "VCALENDAR")
+
+	body := w.buf.Bytes()
+	sum := sha256.Sum256(body)
+	return &Feed{Body: body, ETag: `"` + hex.EncodeToString(sum[:16]) + `"`}, nil
+}
+
+// writeEvent записывает запись как событие: название - категория, описание - заметка, цвет - цвет категории.
+// DTSTAMP берется из времени изменения записи, чтобы неизменный календарь давал тот же ETag.
+func writeEvent(w *icsWriter, entry *models.TimeEntry) {
+	summary := statistics.UncategorizedName
+	color := ""
+	if entry.Category != nil {
+		summary = entry.Category.Name
+		color = colorName(entry.Category.Color)
+	}
+	stamp := entry.UpdatedAt
+	if stamp.IsZero() {
+		stamp = entry.EndTime
+	}
+
+	w.property("BEGIN", "VEVENT")
+	w.property("UID", eventUID(entry.ID))
+	w.time("DTSTAMP", stamp)
+	w.time("DTSTART", entry.StartTime)
+	w.time("DTEND", entry.EndTime)
+	w.text("SUMMARY", summary)
+	if entry.Description != "" {
+		w.text("DESCRIPTION", entry.Description)
+	}
+	if entry.Category != nil {
+		w.text("CATEGORIES", entry.Category.Name)
+	}
+	if color != "" {
+		w.property("COLOR", color)
+	}
+	w.property("TRANSP", "TRANSPARENT")
+	w.property("END", "VEVENT")
+}
+
+// feedWindow возвращает границы периода календаря [from, to) в часовом поясе now
+func feedWindow(startDate, endDate string, now time.Time) (time.Time, time.Time, error) {
+	loc := now.Location()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	to := today.AddDate(0, 0, 1)
+	if endDate != "" {
+		end, err := time.ParseInLocation("2006-01-02", endDate, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, ErrInvalidWindow
+		}
+		to = end.AddDate(0, 0, 1)
+	}
+
+	from := to.AddDate(0, 0, -DefaultWindowDays)
+	if startDate != "" {
+		start, err := time.ParseInLocation("2006-01-02", startDate, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, ErrInvalidWindow
+		}
+		from = start
+	}
+
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, ErrInvalidWindow
+	}
+	if from.AddDate(0, 0, MaxWindowDays).Before(to) {
+		return time.Time{}, time.Time{}, ErrWindowTooLong
+	}
+	return from, to, nil
+}
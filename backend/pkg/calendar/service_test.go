@@ -0,0 +1,241 @@
+package calendar
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/internal/testutil"
+	"github.com/graywrk/timetracker/backend/pkg/database"
+)
+
+// MockRepository представляет мок репозитория для тестирования
+type MockRepository struct {
+	testutil.Repository
+
+	user   *models.User
+	tokens []*models.CalendarToken
+	// pages - страницы записей, которые по очереди возвращает ListTimeEntries
+	pages [][]*models.TimeEntry
+	// filters - условия всех вызовов ListTimeEntries
+	filters []database.TimeEntryFilter
+	deleted []uint
+	nextID  uint
+	err     error
+}
+
+// Реализация методов интерфейса Repository
+
+// GetUserByID мок метода
+func (m *MockRepository) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	return m.user, m.err
+}
+
+// ListTimeEntries мок метода: возвращает следующую страницу записей
+func (m *MockRepository) ListTimeEntries(ctx context.Context, filter database.TimeEntryFilter) ([]*models.TimeEntry, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.filters = append(m.filters, filter)
+	if len(m.pages) == 0 {
+		return nil, nil
+	}
+	page := m.pages[0]
+	m.pages = m.pages[1:]
+	return page, nil
+}
+
+// CreateCalendarToken мок метода
+func (m *MockRepository) CreateCalendarToken(ctx context.Context, token *models.CalendarToken) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.nextID++
+	token.ID = m.nextID
+	m.tokens = append(m.tokens, token)
+	return nil
+}
+
+// GetCalendarTokensByUserID мок метода
+func (m *MockRepository) GetCalendarTokensByUserID(ctx context.Context, userID uint) ([]*models.CalendarToken, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	var tokens []*models.CalendarToken
+	for _, token := range m.tokens {
+		if token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+// GetCalendarTokenByHash мок метода
+func (m *MockRepository) GetCalendarTokenByHash(ctx context.Context, tokenHash string) (*models.CalendarToken, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	for _, token := range m.tokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+	return nil, nil
+}
+
+// DeleteCalendarToken мок метода
+func (m *MockRepository) DeleteCalendarToken(ctx context.Context, id uint) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.deleted = append(m.deleted, id)
+	return nil
+}
+
+// WithTx мок метода: fn выполняется без транзакции
+func (m *MockRepository) WithTx(ctx context.Context, fn func(repo database.Repository) error) error {
+	return fn(m)
+}
+
+// TestTokens проверяет создание, поиск по хешу и отзыв токенов
+func TestTokens(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+	ctx := context.Background()
+
+	token, secret, err := service.CreateToken(ctx, 1, " Телефон ")
+	if err != nil {
+		t.Fatalf("CreateToken() error = %v", err)
+	}
+	if token.Name != "Телефон" {
+		t.Errorf("CreateToken() Name = %q, хотели %q", token.Name, "Телефон")
+	}
+	if token.TokenHash == secret || token.TokenHash != hashToken(secret) {
+		t.Errorf("CreateToken() должен хранить хеш токена, а не сам токен")
+	}
+	if len(secret) != 43 || strings.ContainsAny(secret, "+/=") {
+		t.Errorf("CreateToken() токен %q не подходит для ссылки", secret)
+	}
+
+	if err := service.RevokeToken(ctx, 2, token.ID); err != ErrTokenNotFound {
+		t.Errorf("RevokeToken() чужого токена error = %v, хотели %v", err, ErrTokenNotFound)
+	}
+	if err := service.RevokeToken(ctx, 1, token.ID); err != nil {
+		t.Fatalf("RevokeToken() error = %v", err)
+	}
+	if len(repo.deleted) != 1 || repo.deleted[0] != token.ID {
+		t.Errorf("RevokeToken() удалены %v, хотели [%d]", repo.deleted, token.ID)
+	}
+
+	if _, err := service.GetFeed(ctx, "неизвестный", "", ""); err != ErrTokenNotFound {
+		t.Errorf("GetFeed() с неизвестным токеном error = %v, хотели %v", err, ErrTokenNotFound)
+	}
+}
+
+// TestGetFeed проверяет события календаря, постраничную загрузку и ETag
+func TestGetFeed(t *testing.T) {
+	start := time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)
+	page := make([]*models.TimeEntry, pageSize)
+	for i := range page {
+		page[i] = &models.TimeEntry{
+			ID:        uint(i + 1),
+			UserID:    1,
+			StartTime: start.Add(time.Duration(i) * time.Minute),
+			EndTime:   start.Add(time.Duration(i)*time.Minute + 30*time.Second),
+			Status:    models.StatusCompleted,
+		}
+	}
+	last := &models.TimeEntry{
+		ID:          1000,
+		UserID:      1,
+		StartTime:   time.Date(2024, 3, 6, 10, 0, 0, 0, time.UTC),
+		EndTime:     time.Date(2024, 3, 6, 11, 30, 0, 0, time.UTC),
+		Description: "Созвон; план, итоги",
+		Status:      models.StatusCompleted,
+		Category:    &models.Category{ID: 3, Name: "Работа", Color: "#ff0000"},
+	}
+
+	newRepo := func() *MockRepository {
+		repo := &MockRepository{
+			user:  &models.User{ID: 1, Timezone: "Europe/Moscow"},
+			pages: [][]*models.TimeEntry{page, {last}},
+		}
+		repo.tokens = []*models.CalendarToken{{ID: 7, UserID: 1, TokenHash: hashToken("secret")}}
+		return repo
+	}
+
+	repo := newRepo()
+	feed, err := NewService(repo).GetFeed(context.Background(), "secret", "2024-03-01", "2024-03-31")
+	if err != nil {
+		t.Fatalf("GetFeed() error = %v", err)
+	}
+
+	if len(repo.filters) != 2 {
+		t.Fatalf("ListTimeEntries вызван %d раз, хотели 2", len(repo.filters))
+	}
+	moscow, _ := time.LoadLocation("Europe/Moscow")
+	first := repo.filters[0]
+	if !first.From.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, moscow)) || !first.To.Equal(time.Date(2024, 4, 1, 0, 0, 0, 0, moscow)) {
+		t.Errorf("период = [%v, %v), хотели март по Москве", first.From, first.To)
+	}
+	if after := repo.filters[1].After; after == nil || after.ID != pageSize {
+		t.Errorf("вторая страница должна начинаться после записи %d, курсор %+v", pageSize, after)
+	}
+
+	body := string(feed.Body)
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"X-WR-TIMEZONE:Europe/Moscow\r\n",
+		"UID:time-entry-1000@timetracker\r\n",
+		"DTSTART:20240306T100000Z\r\n",
+		"DTEND:20240306T113000Z\r\n",
+		"SUMMARY:Работа\r\n",
+		"DESCRIPTION:Созвон\\; план\\, итоги\r\n",
+		"COLOR:red\r\n",
+		"SUMMARY:Без категории\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("в календаре нет строки %q", want)
+		}
+	}
+	if count := strings.Count(body, "BEGIN:VEVENT"); count != pageSize+1 {
+		t.Errorf("событий в календаре %d, хотели %d", count, pageSize+1)
+	}
+
+	again, err := NewService(newRepo()).GetFeed(context.Background(), "secret", "2024-03-01", "2024-03-31")
+	if err != nil {
+		t.Fatalf("GetFeed() error = %v", err)
+	}
+	if again.ETag != feed.ETag {
+		t.Errorf("ETag неизменного календаря изменился: %s != %s", again.ETag, feed.ETag)
+	}
+}
+
+// TestFeedWindow проверяет границы периода календаря
+func TestFeedWindow(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	from, to, err := feedWindow("", "", now)
+	if err != nil {
+		t.Fatalf("feedWindow() error = %v", err)
+	}
+	if want := time.Date(2024, 3, 16, 0, 0, 0, 0, time.UTC); !to.Equal(want) {
+		t.Errorf("feedWindow() to = %v, хотели %v", to, want)
+	}
+	if days := to.Sub(from).Hours() / 24; days != DefaultWindowDays {
+		t.Errorf("feedWindow() длина периода %v дней, хотели %d", days, DefaultWindowDays)
+	}
+
+	if _, _, err := feedWindow("2024-03-10", "2024-03-01", now); err != ErrInvalidWindow {
+		t.Errorf("feedWindow() с обратным периодом error = %v, хотели %v", err, ErrInvalidWindow)
+	}
+	if _, _, err := feedWindow("01.03.2024", "", now); err != ErrInvalidWindow {
+		t.Errorf("feedWindow() с неверной датой error = %v, хотели %v", err, ErrInvalidWindow)
+	}
+	if _, _, err := feedWindow("2020-01-01", "2024-03-01", now); err != ErrWindowTooLong {
+		t.Errorf("feedWindow() с длинным периодом error = %v, хотели %v", err, ErrWindowTooLong)
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/graywrk/timetracker/backend/internal/models"
 	"github.com/graywrk/timetracker/backend/pkg/database"
+	"github.com/graywrk/timetracker/backend/pkg/events"
 )
 
 // Определение типовых ошибок
@@ -14,11 +17,16 @@ var (
 	ErrCategoryNotFound  = errors.New("категория не найдена")
 	ErrNotAuthorized     = errors.New("у пользователя нет прав на эту категорию")
 	ErrEmptyCategoryName = errors.New("название категории не может быть пустым")
+	ErrInvalidRate       = errors.New("почасовая ставка не может быть отрицательной")
 )
 
+// DefaultColor - цвет категории по умолчанию
+const DefaultColor = "#4a6bff"
+
 // Service предоставляет методы для работы с категориями
 type Service struct {
-	repo database.Repository
+	repo   database.Repository
+	events events.Publisher
 }
 
 // NewService создает новый сервис категорий
@@ -28,6 +36,14 @@ func NewService(repo database.Repository) *Service {
 	}
 }
 
+// NewServiceWithEvents создает сервис категорий, который сообщает об изменениях категорий через publisher
+func NewServiceWithEvents(repo database.Repository, publisher events.Publisher) *Service {
+	return &Service{
+		repo:   repo,
+		events: publisher,
+	}
+}
+
 // CreateCategory создает новую категорию для пользователя
 func (s *Service) CreateCategory(ctx context.Context, userID uint, name, color string) (*models.Category, error) {
 	if name == "" {
@@ -35,7 +51,7 @@ func (s *Service) CreateCategory(ctx context.Context, userID uint, name, color s
 	}
 
 	if color == "" {
-		color = "#4a6bff" // Цвет по умолчанию
+		color = DefaultColor
 	}
 
 	category := &models.Category{
@@ -89,18 +105,20 @@ func (s *Service) UpdateCategory(ctx context.Context, id, userID uint, name, col
 		color = existingCategory.Color // Оставляем текущий цвет
 	}
 
-	// Обновляем категорию
+	// Обновляем категорию, сохраняя ее почасовую ставку
 	category := &models.Category{
-		ID:     id,
-		UserID: userID,
-		Name:   name,
-		Color:  color,
+		ID:         id,
+		UserID:     userID,
+		Name:       name,
+		Color:      color,
+		HourlyRate: existingCategory.HourlyRate,
 	}
 
 	if err := s.repo.UpdateCategory(ctx, category); err != nil {
 		return nil, fmt.Errorf("ошибка при обновлении категории: %w", err)
 	}
 
+	s.publish(ctx, events.Event{Type: events.CategoryUpdated, UserID: userID, CategoryID: id, Category: category})
 	return category, nil
 }
 
@@ -121,5 +139,42 @@ func (s *Service) DeleteCategory(ctx context.Context, id, userID uint) error {
 		return fmt.Errorf("ошибка при удалении категории: %w", err)
 	}
 
+	s.publish(ctx, events.Event{Type: events.CategoryDeleted, UserID: userID, CategoryID: id})
 	return nil
 }
+
+// SetCategoryRate устанавливает почасовую ставку категории (nil снимает ставку)
+func (s *Service) SetCategoryRate(ctx context.Context, id, userID uint, rate *models.Money) (*models.Category, error) {
+	if rate != nil && *rate < 0 {
+		return nil, ErrInvalidRate
+	}
+
+	category, err := s.repo.GetCategoryByID(ctx, id)
+	if err != nil {
+		return nil, ErrCategoryNotFound
+	}
+
+	if category.UserID != userID {
+		return nil, ErrNotAuthorized
+	}
+
+	category.HourlyRate = rate
+	if err := s.repo.UpdateCategory(ctx, category); err != nil {
+		return nil, fmt.Errorf("ошибка при обновлении ставки категории: %w", err)
+	}
+
+	s.publish(ctx, events.Event{Type: events.CategoryUpdated, UserID: userID, CategoryID: id, Category: category})
+	return category, nil
+}
+
+// publish сообщает клиентам пользователя об изменении категории, если сервис создан с шиной событий
+func (s *Service) publish(ctx context.Context, event events.Event) {
+	if s.events == nil {
+		return
+	}
+
+	event.Time = time.Now()
+	if err := s.events.Publish(ctx, event); err != nil {
+		log.Printf("Service.publish: Ошибка при публикации события %s пользователя %d: %v", event.Type, event.UserID, err)
+	}
+}
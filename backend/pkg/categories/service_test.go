@@ -7,10 +7,14 @@ import (
 	"time"
 
 	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/internal/testutil"
+	"github.com/graywrk/timetracker/backend/pkg/database"
 )
 
 // Mock репозитория для тестирования сервиса
 type MockCategoryRepo struct {
+	testutil.Repository
+
 	categories map[uint]*models.Category
 	nextID     uint
 }
@@ -68,53 +72,8 @@ func (m *MockCategoryRepo) DeleteCategory(ctx context.Context, id uint) error {
 	return nil
 }
 
-// Заглушки для других методов Repository
-func (m *MockCategoryRepo) CreateUser(ctx context.Context, user *models.User) error {
-	return nil
-}
-
-func (m *MockCategoryRepo) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
-	return nil, nil
-}
-
-func (m *MockCategoryRepo) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
-	return nil, nil
-}
-
-func (m *MockCategoryRepo) UpdateUser(ctx context.Context, user *models.User) error {
-	return nil
-}
-
-func (m *MockCategoryRepo) DeleteUser(ctx context.Context, id uint) error {
-	return nil
-}
-
-func (m *MockCategoryRepo) CreateTimeEntry(ctx context.Context, entry *models.TimeEntry) error {
-	return nil
-}
-
-func (m *MockCategoryRepo) GetTimeEntryByID(ctx context.Context, id uint) (*models.TimeEntry, error) {
-	return nil, nil
-}
-
-func (m *MockCategoryRepo) GetTimeEntriesByUserID(ctx context.Context, userID uint) ([]*models.TimeEntry, error) {
-	return nil, nil
-}
-
-func (m *MockCategoryRepo) GetActiveTimeEntryForUser(ctx context.Context, userID uint) (*models.TimeEntry, error) {
-	return nil, nil
-}
-
-func (m *MockCategoryRepo) UpdateTimeEntry(ctx context.Context, entry *models.TimeEntry) error {
-	return nil
-}
-
-func (m *MockCategoryRepo) DeleteTimeEntry(ctx context.Context, id uint) error {
-	return nil
-}
-
-func (m *MockCategoryRepo) GetUserStatsByPeriod(ctx context.Context, userID uint, startDate, endDate string) ([]*models.TimeEntry, error) {
-	return nil, nil
+func (m *MockCategoryRepo) WithTx(ctx context.Context, fn func(repo database.Repository) error) error {
+	return fn(m)
 }
 
 // Тесты
@@ -234,6 +193,49 @@ func TestUpdateCategory(t *testing.T) {
 	}
 }
 
+func TestSetCategoryRate(t *testing.T) {
+	repo := NewMockCategoryRepo()
+	service := NewService(repo)
+	ctx := context.Background()
+
+	category, _ := service.CreateCategory(ctx, 1, "Консультации", "#ff0000")
+	rate := models.Money(250050)
+
+	updated, err := service.SetCategoryRate(ctx, category.ID, 1, &rate)
+	if err != nil {
+		t.Fatalf("Ошибка при установке ставки: %v", err)
+	}
+	if updated.HourlyRate == nil || *updated.HourlyRate != rate {
+		t.Errorf("Ожидалась ставка 2500.50, получено %v", updated.HourlyRate)
+	}
+
+	// Изменение названия не должно сбрасывать ставку
+	updated, err = service.UpdateCategory(ctx, category.ID, 1, "Консультации (новое)", "#00ff00")
+	if err != nil {
+		t.Fatalf("Ошибка при обновлении категории: %v", err)
+	}
+	if updated.HourlyRate == nil || *updated.HourlyRate != rate {
+		t.Errorf("Ставка потерялась при обновлении категории: %v", updated.HourlyRate)
+	}
+
+	// Снятие ставки
+	updated, err = service.SetCategoryRate(ctx, category.ID, 1, nil)
+	if err != nil {
+		t.Fatalf("Ошибка при снятии ставки: %v", err)
+	}
+	if updated.HourlyRate != nil {
+		t.Errorf("Ожидалось отсутствие ставки, получено %v", updated.HourlyRate)
+	}
+
+	negative := models.Money(-100)
+	if _, err := service.SetCategoryRate(ctx, category.ID, 1, &negative); err != ErrInvalidRate {
+		t.Errorf("Ожидалась ошибка ErrInvalidRate, получено %v", err)
+	}
+	if _, err := service.SetCategoryRate(ctx, category.ID, 2, &rate); err != ErrNotAuthorized {
+		t.Errorf("Ожидалась ошибка ErrNotAuthorized, получено %v", err)
+	}
+}
+
 func TestDeleteCategory(t *testing.T) {
 	repo := NewMockCategoryRepo()
 	service := NewService(repo)
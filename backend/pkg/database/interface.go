@@ -2,12 +2,25 @@ package database
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/graywrk/timetracker/backend/internal/models"
 )
 
+// ErrConflict возникает, если запись была изменена параллельно (не совпала версия)
+// или нарушено ограничение единственной незавершенной записи пользователя
+var ErrConflict = errors.New("конфликт параллельного изменения")
+
+// ErrNotFound возникает, если запрошенной записи нет в базе
+var ErrNotFound = errors.New("запись не найдена")
+
 // Repository представляет интерфейс для работы с базой данных
 type Repository interface {
+	// WithTx выполняет fn в транзакции: методы переданного fn репозитория работают в ней.
+	// Если fn возвращает ошибку, транзакция откатывается. Вложенный вызов использует текущую транзакцию.
+	WithTx(ctx context.Context, fn func(repo Repository) error) error
+
 	// Методы для работы с пользователями
 	CreateUser(ctx context.Context, user *models.User) error
 	GetUserByID(ctx context.Context, id uint) (*models.User, error)
@@ -20,11 +33,42 @@ type Repository interface {
 	GetTimeEntryByID(ctx context.Context, id uint) (*models.TimeEntry, error)
 	GetTimeEntriesByUserID(ctx context.Context, userID uint) ([]*models.TimeEntry, error)
 	GetActiveTimeEntryForUser(ctx context.Context, userID uint) (*models.TimeEntry, error)
+	ListTimeEntries(ctx context.Context, filter TimeEntryFilter) ([]*models.TimeEntry, error)
+	// UpdateTimeEntry сохраняет запись, если ее версия в базе совпадает с entry.Version, и увеличивает версию;
+	// иначе возвращает ErrConflict
 	UpdateTimeEntry(ctx context.Context, entry *models.TimeEntry) error
 	DeleteTimeEntry(ctx context.Context, id uint) error
+	// UpdateHeartbeat сохраняет время последнего сигнала активности незавершенной записи
+	UpdateHeartbeat(ctx context.Context, entryID uint, at time.Time) error
+	// ListIdleTimeEntries возвращает активные записи, от которых дольше порога бездействия
+	// их владельца (idle_timeout) до момента now не было сигналов активности
+	ListIdleTimeEntries(ctx context.Context, now time.Time) ([]*models.TimeEntry, error)
+	// ListUnfinishedTimeEntries возвращает активные и приостановленные записи всех пользователей
+	ListUnfinishedTimeEntries(ctx context.Context) ([]*models.TimeEntry, error)
+	// LockUserEntries до конца транзакции WithTx не дает другим транзакциям, вызвавшим этот же метод,
+	// изменять записи пользователя, чтобы проверка пересечений и сохранение записи были атомарны.
+	// Вне транзакции ничего не делает.
+	LockUserEntries(ctx context.Context, userID uint) error
+
+	// Методы для работы с перерывами
+	CreatePause(ctx context.Context, pause *models.Pause) error
+	UpdatePause(ctx context.Context, pause *models.Pause) error
+	ReplacePauses(ctx context.Context, entryID uint, pauses []models.Pause) error
+
+	// ImportTimeEntries в одной транзакции создает категории и завершенные записи.
+	// Запись без CategoryID, у которой Category указывает на одну из создаваемых категорий,
+	// получает ее идентификатор. При ошибке не сохраняется ничего.
+	ImportTimeEntries(ctx context.Context, categories []*models.Category, entries []*models.TimeEntry) error
 
 	// Методы для статистики
-	GetUserStatsByPeriod(ctx context.Context, userID uint, startDate, endDate string) ([]*models.TimeEntry, error)
+	// GetUserStatsByPeriod возвращает завершенные записи, пересекающиеся с периодом [from, to);
+	// границы дней вычисляет вызывающий код.
+	// tagIDs ограничивает выборку записями, имеющими хотя бы одну из меток (nil - без ограничения)
+	GetUserStatsByPeriod(ctx context.Context, userID uint, from, to time.Time, tagIDs []uint) ([]*models.TimeEntry, error)
+	// GetCategoryStatsByPeriod возвращает длительность записей, сгруппированную по категориям
+	// за каждый день периода, и итоги по категориям за весь период.
+	// Записи, пересекающие полночь, делятся между днями; учитывается только время внутри периода.
+	GetCategoryStatsByPeriod(ctx context.Context, filter StatsFilter) ([]CategoryDuration, error)
 
 	// Методы для работы с категориями
 	CreateCategory(ctx context.Context, category *models.Category) error
@@ -32,4 +76,160 @@ type Repository interface {
 	GetCategoriesByUserID(ctx context.Context, userID uint) ([]*models.Category, error)
 	UpdateCategory(ctx context.Context, category *models.Category) error
 	DeleteCategory(ctx context.Context, id uint) error
+
+	// Методы для работы с метками
+	CreateTag(ctx context.Context, tag *models.Tag) error
+	GetTagByID(ctx context.Context, id uint) (*models.Tag, error)
+	GetTagsByUserID(ctx context.Context, userID uint) ([]*models.Tag, error)
+	UpdateTag(ctx context.Context, tag *models.Tag) error
+	DeleteTag(ctx context.Context, id uint) error
+	SetTimeEntryTags(ctx context.Context, entryID uint, tagIDs []uint) error
+
+	// Методы для работы с клиентами
+	CreateClient(ctx context.Context, client *models.Client) error
+	GetClientByID(ctx context.Context, id uint) (*models.Client, error)
+	GetClientsByUserID(ctx context.Context, userID uint) ([]*models.Client, error)
+	UpdateClient(ctx context.Context, client *models.Client) error
+	DeleteClient(ctx context.Context, id uint) error
+
+	// Методы для работы с проектами
+	CreateProject(ctx context.Context, project *models.Project) error
+	GetProjectByID(ctx context.Context, id uint) (*models.Project, error)
+	GetProjectsByUserID(ctx context.Context, userID uint) ([]*models.Project, error)
+	UpdateProject(ctx context.Context, project *models.Project) error
+	DeleteProject(ctx context.Context, id uint) error
+	SetProjectCategories(ctx context.Context, projectID uint, categoryIDs []uint) error
+
+	// Методы для работы с токенами календаря
+	CreateCalendarToken(ctx context.Context, token *models.CalendarToken) error
+	GetCalendarTokensByUserID(ctx context.Context, userID uint) ([]*models.CalendarToken, error)
+	// GetCalendarTokenByHash возвращает токен по хешу или nil, если токена нет
+	GetCalendarTokenByHash(ctx context.Context, tokenHash string) (*models.CalendarToken, error)
+	DeleteCalendarToken(ctx context.Context, id uint) error
+
+	// Методы для работы с сессиями входа
+	CreateSession(ctx context.Context, session *models.Session) error
+	// GetSessionByID и GetSessionByTokenHash возвращают сессию или nil, если сессии нет
+	GetSessionByID(ctx context.Context, id uint) (*models.Session, error)
+	GetSessionByTokenHash(ctx context.Context, tokenHash string) (*models.Session, error)
+	// GetSessionsByUserID возвращает сессии пользователя, срок действия которых не истек к моменту now
+	GetSessionsByUserID(ctx context.Context, userID uint, now time.Time) ([]*models.Session, error)
+	// RotateSession сохраняет новый токен обновления, устройство и сроки сессии, если ее текущий
+	// токен все еще previousHash; иначе возвращает ErrConflict (токен уже использован)
+	RotateSession(ctx context.Context, session *models.Session, previousHash string) error
+	DeleteSession(ctx context.Context, id uint) error
+	// DeleteUserSessions удаляет все сессии пользователя, кроме exceptID (0 - удалить все)
+	DeleteUserSessions(ctx context.Context, userID, exceptID uint) error
+	// DeleteExpiredSessions удаляет сессии, срок действия которых истек к моменту now
+	DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error)
+
+	// Методы для работы с токенами сброса пароля
+	CreatePasswordResetToken(ctx context.Context, token *models.PasswordResetToken) error
+	// UsePasswordResetToken отмечает токен использованным и возвращает его; nil, если токена нет,
+	// он уже использован или истек к моменту now
+	UsePasswordResetToken(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordResetToken, error)
+	// DeletePasswordResetTokens удаляет все токены сброса пароля пользователя
+	DeletePasswordResetTokens(ctx context.Context, userID uint) error
+
+	// Методы для подтверждения email
+	CreateEmailVerificationToken(ctx context.Context, token *models.EmailVerificationToken) error
+	// UseEmailVerificationToken отмечает токен использованным и возвращает его; nil, если токена нет,
+	// он уже использован или истек к моменту now
+	UseEmailVerificationToken(ctx context.Context, tokenHash string, now time.Time) (*models.EmailVerificationToken, error)
+	// DeleteEmailVerificationTokens удаляет все токены подтверждения email пользователя
+	DeleteEmailVerificationTokens(ctx context.Context, userID uint) error
+	// MarkEmailVerified отмечает email пользователя подтвержденным в момент at; уже
+	// подтвержденный email не изменяется
+	MarkEmailVerified(ctx context.Context, userID uint, at time.Time) error
+
+	// Методы для двухфакторной аутентификации
+	// GetTOTP возвращает настройки TOTP пользователя или nil, если их нет
+	GetTOTP(ctx context.Context, userID uint) (*models.TOTP, error)
+	// SaveTOTP создает или заменяет настройки TOTP пользователя
+	SaveTOTP(ctx context.Context, totp *models.TOTP) error
+	// AdvanceTOTPStep запоминает шаг принятого кода, если он больше последнего принятого;
+	// иначе возвращает ErrConflict (код уже использован)
+	AdvanceTOTPStep(ctx context.Context, userID uint, step int64) error
+	// DeleteTOTP удаляет настройки TOTP и коды восстановления пользователя
+	DeleteTOTP(ctx context.Context, userID uint) error
+	// ReplaceRecoveryCodes заменяет коды восстановления пользователя новыми (хешами кодов)
+	ReplaceRecoveryCodes(ctx context.Context, userID uint, codeHashes []string) error
+	// UseRecoveryCode отмечает неиспользованный код восстановления использованным;
+	// false, если такого кода нет или он уже использован
+	UseRecoveryCode(ctx context.Context, userID uint, codeHash string, now time.Time) (bool, error)
+	// UseLoginChallenge отмечает промежуточный токен входа использованным;
+	// false, если он уже был использован
+	UseLoginChallenge(ctx context.Context, challengeID string, userID uint, expiresAt time.Time) (bool, error)
+	// DeleteExpiredLoginChallenges удаляет отметки о токенах, срок действия которых истек к моменту now
+	DeleteExpiredLoginChallenges(ctx context.Context, now time.Time) error
+	// CountRecoveryCodes возвращает количество неиспользованных кодов восстановления
+	CountRecoveryCodes(ctx context.Context, userID uint) (int, error)
+
+	// Методы для работы с запланированным временем
+	// ImportPlannedBlocks создает интервалы в одной транзакции
+	ImportPlannedBlocks(ctx context.Context, blocks []*models.PlannedBlock) error
+	// ListPlannedBlocks возвращает интервалы пользователя, пересекающиеся с [from, to), с категориями
+	ListPlannedBlocks(ctx context.Context, userID uint, from, to time.Time) ([]*models.PlannedBlock, error)
+	GetPlannedBlockByID(ctx context.Context, id uint) (*models.PlannedBlock, error)
+	DeletePlannedBlock(ctx context.Context, id uint) error
+}
+
+// TimeEntryFilter задает условия выборки записей о времени
+type TimeEntryFilter struct {
+	UserID uint
+	// From и To ограничивают выборку записями, пересекающимися с интервалом [From, To).
+	// Незавершенные записи считаются продолжающимися. Нулевое значение снимает ограничение.
+	From        time.Time
+	To          time.Time
+	Statuses    []models.Status
+	CategoryIDs []uint
+	ProjectIDs  []uint
+	// Billable ограничивает выборку оплачиваемыми (true) или неоплачиваемыми (false) записями
+	Billable *bool
+	// AutoStopped ограничивает выборку автоматически завершенными (true) или остальными (false) записями
+	AutoStopped *bool
+	// TagIDs ограничивает выборку записями, имеющими хотя бы одну из меток
+	TagIDs []uint
+	// Query - подстрока для поиска в описании записи без учета регистра
+	Query string
+	// SortAsc включает сортировку от старых записей к новым (по умолчанию - от новых к старым)
+	SortAsc bool
+	// After - позиция, после которой продолжается выборка в выбранном порядке сортировки
+	After *TimeEntryCursor
+	// Limit ограничивает количество записей, 0 - без ограничения
+	Limit int
+}
+
+// TimeEntryCursor указывает позицию записи в выборке, упорядоченной по (start_time, id)
+type TimeEntryCursor struct {
+	StartTime time.Time
+	ID        uint
+}
+
+// StatsFilter задает условия выборки для агрегированной статистики
+type StatsFilter struct {
+	UserID uint
+	// From и To задают период [From, To); Location - часовой пояс, в котором определяются дни
+	From     time.Time
+	To       time.Time
+	Location *time.Location
+	// TagIDs ограничивает выборку записями, имеющими хотя бы одну из меток
+	TagIDs []uint
+	// RunningUntil, если задано, добавляет к завершенным записям активную и приостановленную:
+	// активная считается продолжающейся до RunningUntil, приостановленная - до момента паузы
+	RunningUntil time.Time
+}
+
+// CategoryDuration содержит суммарную длительность записей одной категории за день или за весь период
+type CategoryDuration struct {
+	// Day - день в формате YYYY-MM-DD; пустая строка означает итог за весь период
+	Day string
+	// CategoryID = nil - записи без категории
+	CategoryID *uint
+	Name       string
+	Color      string
+	Duration   int64 // в секундах
+	EntryCount int
+	// Percent - доля категории в общей длительности за тот же день (для итога - за период), в процентах
+	Percent float64
 }
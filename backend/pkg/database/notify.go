@@ -0,0 +1,205 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// NotifyChannel - канал PostgreSQL, в который репозиторий отправляет уведомления об изменениях
+const NotifyChannel = "timetracker_changes"
+
+// Сущности, об изменении которых отправляются уведомления
+const (
+	EntityTimeEntry = "time_entry"
+	EntityCategory  = "category"
+)
+
+// Действия с сущностями
+const (
+	ActionCreated = "created"
+	ActionUpdated = "updated"
+	ActionDeleted = "deleted"
+	// ActionResync - соединение слушателя восстановлено после разрыва. Уведомления за время разрыва
+	// потеряны, поэтому подписчикам нужно заново прочитать данные всех пользователей.
+	ActionResync = "resync"
+)
+
+// notificationBuffer - сколько уведомлений может ожидать доставки одному подписчику
+const notificationBuffer = 64
+
+// listenerPingInterval - как часто проверять соединение слушателя, если уведомлений нет
+const listenerPingInterval = 90 * time.Second
+
+// Notification представляет изменение сущности пользователя
+type Notification struct {
+	Entity   string `json:"entity"`
+	Action   string `json:"action"`
+	UserID   uint   `json:"user_id"`
+	EntityID uint   `json:"entity_id"`
+	// Origin - идентификатор экземпляра сервера, изменившего данные (PostgresRepository.Origin)
+	Origin string `json:"origin"`
+}
+
+// Notifications - источник уведомлений об изменениях, сделанных всеми экземплярами сервера
+type Notifications interface {
+	// Subscribe подписывает на уведомления. Канал закрывается после вызова функции отмены.
+	Subscribe() (<-chan Notification, func())
+}
+
+// newOrigin создает случайный идентификатор экземпляра сервера
+func newOrigin() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
+
+// Origin возвращает идентификатор этого экземпляра сервера в отправляемых уведомлениях
+func (r *PostgresRepository) Origin() string {
+	return r.origin
+}
+
+// notify отправляет уведомление об изменении сущности через q. Внутри транзакции уведомление
+// доставляется при ее фиксации и отбрасывается при откате. Ошибка отправки не отменяет
+// изменение и только записывается в лог: подписчики перечитают данные при следующем запросе.
+func (r *PostgresRepository) notify(ctx context.Context, q querier, entity, action string, userID, entityID uint) {
+	payload, err := json.Marshal(Notification{
+		Entity:   entity,
+		Action:   action,
+		UserID:   userID,
+		EntityID: entityID,
+		Origin:   r.origin,
+	})
+	if err != nil {
+		log.Printf("PostgresRepository.notify: Ошибка при кодировании уведомления: %v", err)
+		return
+	}
+
+	if _, err := q.ExecContext(ctx, `SELECT pg_notify($1, $2)`, NotifyChannel, string(payload)); err != nil {
+		log.Printf("PostgresRepository.notify: Ошибка при отправке уведомления %s %s %d: %v", entity, action, entityID, err)
+	}
+}
+
+// NotificationListener получает уведомления канала NotifyChannel через LISTEN
+// и рассылает их подписчикам внутри процесса
+type NotificationListener struct {
+	listener    *pq.Listener
+	mu          sync.Mutex
+	subscribers map[chan Notification]struct{}
+}
+
+// NewNotificationListener подключается к каналу уведомлений. Потерянное соединение
+// восстанавливается автоматически: задержка перед попыткой начинается с minReconnect
+// и удваивается после каждой неудачи, но не превышает maxReconnect.
+func NewNotificationListener(connStr string, minReconnect, maxReconnect time.Duration) (*NotificationListener, error) {
+	l := &NotificationListener{
+		subscribers: make(map[chan Notification]struct{}),
+	}
+	l.listener = pq.NewListener(connStr, minReconnect, maxReconnect, func(event pq.ListenerEventType, err error) {
+		switch event {
+		case pq.ListenerEventDisconnected:
+			log.Printf("NotificationListener: Соединение потеряно: %v", err)
+		case pq.ListenerEventReconnected:
+			log.Printf("NotificationListener: Соединение восстановлено")
+		case pq.ListenerEventConnectionAttemptFailed:
+			log.Printf("NotificationListener: Не удалось подключиться: %v", err)
+		}
+	})
+
+	if err := l.listener.Listen(NotifyChannel); err != nil {
+		l.listener.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Run получает уведомления и рассылает их подписчикам до отмены ctx или закрытия слушателя
+func (l *NotificationListener) Run(ctx context.Context) {
+	ticker := time.NewTicker(listenerPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n, ok := <-l.listener.Notify:
+			if !ok {
+				return
+			}
+			l.handle(n)
+		case <-ticker.C:
+			// Обрыв соединения без уведомлений обнаруживается только при обращении к серверу
+			go func() {
+				if err := l.listener.Ping(); err != nil {
+					log.Printf("NotificationListener: Ошибка проверки соединения: %v", err)
+				}
+			}()
+		}
+	}
+}
+
+// handle разбирает уведомление PostgreSQL и рассылает его подписчикам.
+// nil означает, что соединение восстановлено и уведомления могли быть потеряны.
+func (l *NotificationListener) handle(n *pq.Notification) {
+	if n == nil {
+		l.dispatch(Notification{Action: ActionResync})
+		return
+	}
+
+	var notification Notification
+	if err := json.Unmarshal([]byte(n.Extra), &notification); err != nil {
+		log.Printf("NotificationListener: Неверное уведомление %q: %v", n.Extra, err)
+		return
+	}
+	l.dispatch(notification)
+}
+
+// dispatch рассылает уведомление подписчикам, не дожидаясь их; подписчик, который
+// не успевает читать уведомления, пропускает их
+func (l *NotificationListener) dispatch(notification Notification) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for ch := range l.subscribers {
+		select {
+		case ch <- notification:
+		default:
+			log.Printf("NotificationListener: Подписчик не успевает читать уведомления, уведомление %s %s отброшено",
+				notification.Entity, notification.Action)
+		}
+	}
+}
+
+// Subscribe подписывает на уведомления
+func (l *NotificationListener) Subscribe() (<-chan Notification, func()) {
+	ch := make(chan Notification, notificationBuffer)
+
+	l.mu.Lock()
+	l.subscribers[ch] = struct{}{}
+	l.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			delete(l.subscribers, ch)
+			close(ch)
+		})
+	}
+	return ch, cancel
+}
+
+// Close закрывает соединение слушателя
+func (l *NotificationListener) Close() error {
+	return l.listener.Close()
+}
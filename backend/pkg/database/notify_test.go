@@ -0,0 +1,40 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestNotificationListenerHandle(t *testing.T) {
+	l := &NotificationListener{subscribers: make(map[chan Notification]struct{})}
+	ch, cancel := l.Subscribe()
+
+	l.handle(&pq.Notification{
+		Channel: NotifyChannel,
+		Extra:   `{"entity":"time_entry","action":"updated","user_id":1,"entity_id":5,"origin":"a1"}`,
+	})
+	n := <-ch
+	if n.Entity != EntityTimeEntry || n.Action != ActionUpdated || n.UserID != 1 || n.EntityID != 5 || n.Origin != "a1" {
+		t.Errorf("неверно разобрано уведомление: %+v", n)
+	}
+
+	// Неверные уведомления пропускаются
+	l.handle(&pq.Notification{Channel: NotifyChannel, Extra: "не json"})
+	if len(ch) != 0 {
+		t.Errorf("неверное уведомление не должно доставляться")
+	}
+
+	// nil - соединение восстановлено
+	l.handle(nil)
+	if n := <-ch; n.Action != ActionResync {
+		t.Errorf("ожидалось уведомление %s, получено %+v", ActionResync, n)
+	}
+
+	cancel()
+	cancel()
+	if _, ok := <-ch; ok {
+		t.Errorf("канал должен быть закрыт после отмены подписки")
+	}
+	l.handle(nil)
+}
@@ -6,21 +6,60 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/graywrk/timetracker/backend/internal/models"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // PostgresRepository представляет реализацию Repository для PostgreSQL
 type PostgresRepository struct {
-	db *sql.DB
+	db     querier // пул соединений или текущая транзакция
+	conn   *sql.DB
+	tx     *sql.Tx // транзакция WithTx или nil
+	origin string  // идентификатор экземпляра сервера в уведомлениях об изменениях
+}
+
+// querier - общие методы *sql.DB и *sql.Tx, через которые выполняются запросы
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// scopedTx - транзакция метода репозитория. Внутри WithTx метод работает в транзакции WithTx:
+// Commit и Rollback тогда ничего не делают, транзакцию завершает WithTx.
+type scopedTx struct {
+	*sql.Tx
+	nested bool
+}
+
+// Commit фиксирует транзакцию метода, если она не вложена в WithTx
+func (t scopedTx) Commit() error {
+	if t.nested {
+		return nil
+	}
+	return t.Tx.Commit()
+}
+
+// Rollback откатывает транзакцию метода, если она не вложена в WithTx
+func (t scopedTx) Rollback() error {
+	if t.nested {
+		return nil
+	}
+	return t.Tx.Rollback()
+}
+
+// ConnectionString возвращает строку подключения к PostgreSQL
+func ConnectionString(host, port, user, password, dbname string) string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		host, port, user, password, dbname)
 }
 
 // NewPostgresRepository создает новое подключение к PostgreSQL
 func NewPostgresRepository(host, port, user, password, dbname string) (*PostgresRepository, error) {
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		host, port, user, password, dbname)
+	connStr := ConnectionString(host, port, user, password, dbname)
 
 	db, err := sql.Open("postgres", connStr)
 	if err != nil {
@@ -32,12 +71,47 @@ func NewPostgresRepository(host, port, user, password, dbname string) (*Postgres
 		return nil, err
 	}
 
-	return &PostgresRepository{db: db}, nil
+	return &PostgresRepository{db: db, conn: db, origin: newOrigin()}, nil
 }
 
 // Close закрывает соединение с базой
 func (r *PostgresRepository) Close() error {
-	return r.db.Close()
+	return r.conn.Close()
+}
+
+// WithTx выполняет fn в транзакции; вложенный вызов использует текущую транзакцию
+func (r *PostgresRepository) WithTx(ctx context.Context, fn func(repo Repository) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+
+	tx, err := r.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(&PostgresRepository{db: tx, conn: r.conn, tx: tx, origin: r.origin}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// beginTx начинает транзакцию метода из нескольких запросов или продолжает транзакцию WithTx
+func (r *PostgresRepository) beginTx(ctx context.Context) (scopedTx, error) {
+	if r.tx != nil {
+		return scopedTx{Tx: r.tx, nested: true}, nil
+	}
+
+	tx, err := r.conn.BeginTx(ctx, nil)
+	return scopedTx{Tx: tx}, err
+}
+
+// isUniqueViolation сообщает, что запрос нарушил ограничение уникальности
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
 }
 
 // Методы для работы с пользователями
@@ -47,17 +121,20 @@ func (r *PostgresRepository) CreateUser(ctx context.Context, user *models.User)
 	log.Printf("PostgresRepository: Создание пользователя с email: %s", user.Email)
 
 	query := `
-		INSERT INTO users (email, password, created_at, updated_at)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id
+		INSERT INTO users (email, password, timezone, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, idle_timeout, max_entry_duration
 	`
 
-	now := time.Now()
+	now := time.Now().UTC()
 	user.CreatedAt = now
 	user.UpdatedAt = now
+	if user.Timezone == "" {
+		user.Timezone = models.DefaultTimezone
+	}
 
 	log.Printf("PostgresRepository: Выполняем запрос на вставку пользователя")
-	err := r.db.QueryRowContext(ctx, query, user.Email, user.Password, user.CreatedAt, user.UpdatedAt).Scan(&user.ID)
+	err := r.db.QueryRowContext(ctx, query, user.Email, user.Password, user.Timezone, user.CreatedAt, user.UpdatedAt).Scan(&user.ID, &user.IdleTimeout, &user.MaxEntryDuration)
 
 	if err != nil {
 		log.Printf("PostgresRepository: Ошибка при создании пользователя: %v", err)
@@ -68,15 +145,34 @@ func (r *PostgresRepository) CreateUser(ctx context.Context, user *models.User)
 	return nil
 }
 
-// GetUserByID возвращает пользователя по ID
-func (r *PostgresRepository) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
-	query := `SELECT id, email, password, created_at, updated_at FROM users WHERE id = $1`
+// userColumns - список столбцов пользователя в порядке, ожидаемом scanUser
+const userColumns = `id, email, password, hourly_rate, timezone, idle_timeout, max_entry_duration, daily_cutoff,
+	email_verified_at, created_at, updated_at`
 
+// scanUser читает пользователя из строки результата
+func scanUser(row interface{ Scan(...interface{}) error }) (*models.User, error) {
 	user := &models.User{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&user.ID, &user.Email, &user.Password, &user.CreatedAt, &user.UpdatedAt,
-	)
+	var hourlyRate sql.NullString
+	if err := row.Scan(
+		&user.ID, &user.Email, &user.Password, &hourlyRate, &user.Timezone, &user.IdleTimeout,
+		&user.MaxEntryDuration, &user.DailyCutoff, &user.EmailVerifiedAt, &user.CreatedAt, &user.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if user.HourlyRate, err = scanMoney(hourlyRate); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// GetUserByID возвращает пользователя по ID
+func (r *PostgresRepository) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
 
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("пользователь с ID %d не найден", id)
@@ -91,13 +187,9 @@ func (r *PostgresRepository) GetUserByID(ctx context.Context, id uint) (*models.
 func (r *PostgresRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	log.Printf("PostgresRepository: Поиск пользователя по email: %s", email)
 
-	query := `SELECT id, email, password, created_at, updated_at FROM users WHERE email = $1`
-
-	user := &models.User{}
-	err := r.db.QueryRowContext(ctx, query, email).Scan(
-		&user.ID, &user.Email, &user.Password, &user.CreatedAt, &user.UpdatedAt,
-	)
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = $1`
 
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, email))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			log.Printf("PostgresRepository: Пользователь с email %s не найден", email)
@@ -115,13 +207,16 @@ func (r *PostgresRepository) GetUserByEmail(ctx context.Context, email string) (
 func (r *PostgresRepository) UpdateUser(ctx context.Context, user *models.User) error {
 	query := `
 		UPDATE users
-		SET email = $1, password = $2, updated_at = $3
-		WHERE id = $4
+		SET email = $1, password = $2, hourly_rate = $3, timezone = $4, idle_timeout = $5,
+		    max_entry_duration = $6, daily_cutoff = $7, updated_at = $8
+		WHERE id = $9
 	`
 
-	user.UpdatedAt = time.Now()
+	user.UpdatedAt = time.Now().UTC()
 
-	_, err := r.db.ExecContext(ctx, query, user.Email, user.Password, user.UpdatedAt, user.ID)
+	_, err := r.db.ExecContext(ctx, query,
+		user.Email, user.Password, moneyValue(user.HourlyRate), user.Timezone, user.IdleTimeout,
+		user.MaxEntryDuration, user.DailyCutoff, user.UpdatedAt, user.ID)
 	return err
 }
 
@@ -137,37 +232,47 @@ func (r *PostgresRepository) DeleteUser(ctx context.Context, id uint) error {
 
 // CreateTimeEntry создает новую запись о времени
 func (r *PostgresRepository) CreateTimeEntry(ctx context.Context, entry *models.TimeEntry) error {
-	// Проверяем, есть ли уже активная запись для пользователя
-	activeEntry, err := r.GetActiveTimeEntryForUser(ctx, entry.UserID)
-	if err != nil {
-		return fmt.Errorf("ошибка при проверке активных записей: %w", err)
-	}
-
-	if activeEntry != nil {
-		return fmt.Errorf("у пользователя уже есть активная запись времени")
-	}
-
 	// Устанавливаем время создания и обновления
-	now := time.Now()
+	now := time.Now().UTC()
 	entry.CreatedAt = now
 	entry.UpdatedAt = now
 
-	// Устанавливаем начальные значения
-	entry.StartTime = now
-	entry.Status = models.StatusActive
-	entry.TotalPaused = 0
+	// Завершенные записи (созданные вручную) сохраняем как есть,
+	// для новых активных записей устанавливаем начальные значения
+	if entry.Status != models.StatusCompleted {
+		// Проверяем, есть ли уже активная запись для пользователя
+		activeEntry, err := r.GetActiveTimeEntryForUser(ctx, entry.UserID)
+		if err != nil {
+			return fmt.Errorf("ошибка при проверке активных записей: %w", err)
+		}
+
+		if activeEntry != nil {
+			return fmt.Errorf("%w: у пользователя уже есть активная запись времени", ErrConflict)
+		}
+
+		// Время начала задает сервис (например, при переключении - момент завершения предыдущей записи)
+		if entry.StartTime.IsZero() {
+			entry.StartTime = now
+		}
+		entry.Status = models.StatusActive
+		entry.TotalPaused = 0
+	}
 
 	// SQL запрос для создания записи
 	query := `
 		INSERT INTO time_entries (
 			user_id, start_time, end_time, paused_at, resumed_at,
-			total_paused, status, category_id, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-		RETURNING id
+			total_paused, status, category_id, project_id, billable, description, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id, version
 	`
 
 	// Обрабатываем NULL значения для времени
 	var endTime, pausedAt, resumedAt sql.NullTime
+	if !entry.EndTime.IsZero() {
+		endTime.Time = entry.EndTime.UTC()
+		endTime.Valid = true
+	}
 
 	// Обрабатываем NULL значение для category_id
 	var categoryID sql.NullInt64
@@ -177,25 +282,33 @@ func (r *PostgresRepository) CreateTimeEntry(ctx context.Context, entry *models.
 	}
 
 	// Выполняем запрос
-	err = r.db.QueryRowContext(
+	err := r.db.QueryRowContext(
 		ctx,
 		query,
 		entry.UserID,
-		entry.StartTime,
+		entry.StartTime.UTC(),
 		endTime,
 		pausedAt,
 		resumedAt,
 		entry.TotalPaused,
 		entry.Status,
 		categoryID,
+		nullUint(entry.ProjectID),
+		entry.Billable,
+		entry.Description,
 		entry.CreatedAt,
 		entry.UpdatedAt,
-	).Scan(&entry.ID)
+	).Scan(&entry.ID, &entry.Version)
 
 	if err != nil {
+		// Незавершенная запись пользователя создана параллельным запросом
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: у пользователя уже есть активная запись времени", ErrConflict)
+		}
 		return fmt.Errorf("ошибка при создании записи времени: %w", err)
 	}
 
+	r.notify(ctx, r.db, EntityTimeEntry, ActionCreated, entry.UserID, entry.ID)
 	return nil
 }
 
@@ -205,14 +318,14 @@ func (r *PostgresRepository) GetTimeEntryByID(ctx context.Context, id uint) (*mo
 		SELECT 
 			id, user_id, start_time, end_time, 
 			paused_at, resumed_at, total_paused, status, 
-			category_id, created_at, updated_at
+			category_id, project_id, billable, description, created_at, updated_at, last_heartbeat_at, auto_stopped, version
 		FROM time_entries
 		WHERE id = $1
 	`
 
 	entry := &models.TimeEntry{}
-	var categoryID sql.NullInt64
-	var endTime, pausedAt, resumedAt sql.NullTime
+	var categoryID, projectID sql.NullInt64
+	var endTime, pausedAt, resumedAt, lastHeartbeat sql.NullTime
 	var status string
 
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
@@ -225,13 +338,19 @@ func (r *PostgresRepository) GetTimeEntryByID(ctx context.Context, id uint) (*mo
 		&entry.TotalPaused,
 		&status,
 		&categoryID,
+		&projectID,
+		&entry.Billable,
+		&entry.Description,
 		&entry.CreatedAt,
 		&entry.UpdatedAt,
+		&lastHeartbeat,
+		&entry.AutoStopped,
+		&entry.Version,
 	)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("запись времени с id=%d не найдена", id)
+			return nil, fmt.Errorf("запись времени с id=%d: %w", id, ErrNotFound)
 		}
 		return nil, fmt.Errorf("ошибка при получении записи времени: %w", err)
 	}
@@ -246,9 +365,13 @@ func (r *PostgresRepository) GetTimeEntryByID(ctx context.Context, id uint) (*mo
 	if resumedAt.Valid {
 		entry.ResumedAt = resumedAt.Time
 	}
+	if lastHeartbeat.Valid {
+		entry.LastHeartbeat = lastHeartbeat.Time
+	}
 
-	// Устанавливаем статус
+	// Устанавливаем статус и проект
 	entry.Status = models.Status(status)
+	entry.ProjectID = uintPtr(projectID)
 
 	// Устанавливаем category_id и загружаем категорию, если она есть
 	if categoryID.Valid {
@@ -262,17 +385,25 @@ func (r *PostgresRepository) GetTimeEntryByID(ctx context.Context, id uint) (*mo
 		}
 	}
 
+	// Загружаем интервалы перерывов
+	if err := r.attachEntryDetails(ctx, []*models.TimeEntry{entry}); err != nil {
+		return nil, err
+	}
+
 	return entry, nil
 }
 
+// timeEntryWithCategoryColumns - список колонок записи о времени вместе с данными категории
+const timeEntryWithCategoryColumns = `
+			te.id, te.user_id, te.start_time, te.end_time, 
+			te.paused_at, te.resumed_at, te.total_paused, te.status, 
+			te.category_id, te.project_id, te.billable, te.description, te.created_at, te.updated_at, te.last_heartbeat_at, te.auto_stopped, te.version,
+			COALESCE(c.id, 0), COALESCE(c.user_id, 0), c.name, c.color, c.created_at, c.updated_at`
+
 // GetTimeEntriesByUserID возвращает все записи о времени для пользователя
 func (r *PostgresRepository) GetTimeEntriesByUserID(ctx context.Context, userID uint) ([]*models.TimeEntry, error) {
 	query := `
-		SELECT 
-			te.id, te.user_id, te.start_time, te.end_time, 
-			te.paused_at, te.resumed_at, te.total_paused, te.status, 
-			te.category_id, te.created_at, te.updated_at,
-			COALESCE(c.id, 0), COALESCE(c.user_id, 0), c.name, c.color, c.created_at, c.updated_at
+		SELECT ` + timeEntryWithCategoryColumns + `
 		FROM time_entries te
 		LEFT JOIN categories c ON te.category_id = c.id
 		WHERE te.user_id = $1
@@ -287,97 +418,267 @@ func (r *PostgresRepository) GetTimeEntriesByUserID(ctx context.Context, userID
 
 	entries := []*models.TimeEntry{}
 	for rows.Next() {
-		entry := &models.TimeEntry{}
-		var categoryID sql.NullInt64
-		var categoryFields struct {
-			ID        uint
-			UserID    uint
-			Name      sql.NullString
-			Color     sql.NullString
-			CreatedAt sql.NullTime
-			UpdatedAt sql.NullTime
+		entry, err := scanTimeEntryWithCategory(rows)
+		if err != nil {
+			return nil, err
 		}
+		entries = append(entries, entry)
+	}
 
-		var endTime, pausedAt, resumedAt sql.NullTime
-		var status string
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-		err := rows.Scan(
-			&entry.ID,
-			&entry.UserID,
-			&entry.StartTime,
-			&endTime,
-			&pausedAt,
-			&resumedAt,
-			&entry.TotalPaused,
-			&status,
-			&categoryID,
-			&entry.CreatedAt,
-			&entry.UpdatedAt,
-			&categoryFields.ID,
-			&categoryFields.UserID,
-			&categoryFields.Name,
-			&categoryFields.Color,
-			&categoryFields.CreatedAt,
-			&categoryFields.UpdatedAt,
-		)
+	if err := r.attachEntryDetails(ctx, entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// LockUserEntries блокирует строку пользователя до конца транзакции WithTx. FOR NO KEY UPDATE
+// не мешает вставке записей по внешнему ключу, но выстраивает в очередь транзакции,
+// проверяющие пересечения записей этого пользователя.
+func (r *PostgresRepository) LockUserEntries(ctx context.Context, userID uint) error {
+	if r.tx == nil {
+		return nil
+	}
+
+	var id uint
+	err := r.db.QueryRowContext(ctx, "SELECT id FROM users WHERE id = $1 FOR NO KEY UPDATE", userID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("пользователь с ID %d не найден", userID)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка при блокировке записей пользователя: %w", err)
+	}
+	return nil
+}
+
+// ListUnfinishedTimeEntries возвращает активные и приостановленные записи всех пользователей
+func (r *PostgresRepository) ListUnfinishedTimeEntries(ctx context.Context) ([]*models.TimeEntry, error) {
+	query := `
+		SELECT ` + timeEntryWithCategoryColumns + `
+		FROM time_entries te
+		LEFT JOIN categories c ON te.category_id = c.id
+		WHERE te.status <> 'completed'
+		ORDER BY te.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении незавершенных записей: %w", err)
+	}
+	defer rows.Close()
 
+	entries := []*models.TimeEntry{}
+	for rows.Next() {
+		entry, err := scanTimeEntryWithCategory(rows)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("ошибка при сканировании записи: %w", err)
 		}
+		entries = append(entries, entry)
+	}
 
-		// Устанавливаем значения времени
-		if endTime.Valid {
-			entry.EndTime = endTime.Time
-		}
-		if pausedAt.Valid {
-			entry.PausedAt = pausedAt.Time
-		}
-		if resumedAt.Valid {
-			entry.ResumedAt = resumedAt.Time
-		}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при обработке результатов: %w", err)
+	}
 
-		// Устанавливаем статус
-		entry.Status = models.Status(status)
+	if err := r.attachEntryDetails(ctx, entries); err != nil {
+		return nil, err
+	}
 
-		// Устанавливаем категорию, если она есть
-		if categoryID.Valid {
-			categoryIDUint := uint(categoryID.Int64)
-			entry.CategoryID = &categoryIDUint
+	return entries, nil
+}
 
-			// Создаем объект категории только если все необходимые поля присутствуют
-			if categoryFields.ID > 0 && categoryFields.UserID > 0 {
-				category := &models.Category{
-					ID:     categoryFields.ID,
-					UserID: categoryFields.UserID,
-				}
+// ListTimeEntries возвращает записи о времени пользователя с фильтрацией, сортировкой и постраничной выборкой
+func (r *PostgresRepository) ListTimeEntries(ctx context.Context, filter TimeEntryFilter) ([]*models.TimeEntry, error) {
+	conditions := []string{"te.user_id = $1"}
+	args := []interface{}{filter.UserID}
 
-				if categoryFields.Name.Valid {
-					category.Name = categoryFields.Name.String
-				}
-				if categoryFields.Color.Valid {
-					category.Color = categoryFields.Color.String
-				}
-				if categoryFields.CreatedAt.Valid {
-					category.CreatedAt = categoryFields.CreatedAt.Time
-				}
-				if categoryFields.UpdatedAt.Valid {
-					category.UpdatedAt = categoryFields.UpdatedAt.Time
-				}
+	// addArg добавляет параметр запроса и возвращает его плейсхолдер
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
 
-				entry.Category = category
-			}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "(te.end_time IS NULL OR te.end_time > "+addArg(filter.From.UTC())+")")
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "te.start_time < "+addArg(filter.To.UTC()))
+	}
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, len(filter.Statuses))
+		for i, status := range filter.Statuses {
+			statuses[i] = string(status)
 		}
+		conditions = append(conditions, "te.status = ANY("+addArg(pq.Array(statuses))+")")
+	}
+	if len(filter.CategoryIDs) > 0 {
+		conditions = append(conditions, "te.category_id = ANY("+addArg(uintArray(filter.CategoryIDs))+")")
+	}
+	if len(filter.ProjectIDs) > 0 {
+		conditions = append(conditions, "te.project_id = ANY("+addArg(uintArray(filter.ProjectIDs))+")")
+	}
+	if filter.Billable != nil {
+		conditions = append(conditions, "te.billable = "+addArg(*filter.Billable))
+	}
+	if filter.AutoStopped != nil {
+		conditions = append(conditions, "te.auto_stopped = "+addArg(*filter.AutoStopped))
+	}
+	if len(filter.TagIDs) > 0 {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM time_entry_tags tet WHERE tet.time_entry_id = te.id AND tet.tag_id = ANY("+addArg(uintArray(filter.TagIDs))+"))")
+	}
+	if filter.Query != "" {
+		conditions = append(conditions, "strpos(lower(te.description), lower("+addArg(filter.Query)+")) > 0")
+	}
+
+	// Keyset-пагинация по паре (start_time, id)
+	order := "DESC"
+	comparison := "<"
+	if filter.SortAsc {
+		order = "ASC"
+		comparison = ">"
+	}
+	if filter.After != nil {
+		conditions = append(conditions, fmt.Sprintf("(te.start_time, te.id) %s (%s, %s)",
+			comparison, addArg(filter.After.StartTime.UTC()), addArg(filter.After.ID)))
+	}
+
+	query := `
+		SELECT ` + timeEntryWithCategoryColumns + `
+		FROM time_entries te
+		LEFT JOIN categories c ON te.category_id = c.id
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY te.start_time ` + order + `, te.id ` + order
+
+	if filter.Limit > 0 {
+		query += " LIMIT " + addArg(filter.Limit)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении записей времени: %w", err)
+	}
+	defer rows.Close()
 
+	entries := []*models.TimeEntry{}
+	for rows.Next() {
+		entry, err := scanTimeEntryWithCategory(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании записи: %w", err)
+		}
 		entries = append(entries, entry)
 	}
 
 	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при обработке результатов: %w", err)
+	}
+
+	if err := r.attachEntryDetails(ctx, entries); err != nil {
 		return nil, err
 	}
 
 	return entries, nil
 }
 
+// scanTimeEntryWithCategory сканирует строку, выбранную по timeEntryWithCategoryColumns
+func scanTimeEntryWithCategory(rows *sql.Rows) (*models.TimeEntry, error) {
+	entry := &models.TimeEntry{}
+	var categoryID, projectID sql.NullInt64
+	var categoryFields struct {
+		ID        uint
+		UserID    uint
+		Name      sql.NullString
+		Color     sql.NullString
+		CreatedAt sql.NullTime
+		UpdatedAt sql.NullTime
+	}
+
+	var endTime, pausedAt, resumedAt, lastHeartbeat sql.NullTime
+	var status string
+
+	err := rows.Scan(
+		&entry.ID,
+		&entry.UserID,
+		&entry.StartTime,
+		&endTime,
+		&pausedAt,
+		&resumedAt,
+		&entry.TotalPaused,
+		&status,
+		&categoryID,
+		&projectID,
+		&entry.Billable,
+		&entry.Description,
+		&entry.CreatedAt,
+		&entry.UpdatedAt,
+		&lastHeartbeat,
+		&entry.AutoStopped,
+		&entry.Version,
+		&categoryFields.ID,
+		&categoryFields.UserID,
+		&categoryFields.Name,
+		&categoryFields.Color,
+		&categoryFields.CreatedAt,
+		&categoryFields.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Устанавливаем значения времени
+	if endTime.Valid {
+		entry.EndTime = endTime.Time
+	}
+	if pausedAt.Valid {
+		entry.PausedAt = pausedAt.Time
+	}
+	if resumedAt.Valid {
+		entry.ResumedAt = resumedAt.Time
+	}
+	if lastHeartbeat.Valid {
+		entry.LastHeartbeat = lastHeartbeat.Time
+	}
+
+	// Устанавливаем статус и проект
+	entry.Status = models.Status(status)
+	entry.ProjectID = uintPtr(projectID)
+
+	// Устанавливаем категорию, если она есть
+	if categoryID.Valid {
+		categoryIDUint := uint(categoryID.Int64)
+		entry.CategoryID = &categoryIDUint
+
+		// Создаем объект категории только если все необходимые поля присутствуют
+		if categoryFields.ID > 0 && categoryFields.UserID > 0 {
+			category := &models.Category{
+				ID:     categoryFields.ID,
+				UserID: categoryFields.UserID,
+			}
+
+			if categoryFields.Name.Valid {
+				category.Name = categoryFields.Name.String
+			}
+			if categoryFields.Color.Valid {
+				category.Color = categoryFields.Color.String
+			}
+			if categoryFields.CreatedAt.Valid {
+				category.CreatedAt = categoryFields.CreatedAt.Time
+			}
+			if categoryFields.UpdatedAt.Valid {
+				category.UpdatedAt = categoryFields.UpdatedAt.Time
+			}
+
+			entry.Category = category
+		}
+	}
+
+	return entry, nil
+}
+
 // GetActiveTimeEntryForUser получает активную запись времени для пользователя
 func (r *PostgresRepository) GetActiveTimeEntryForUser(ctx context.Context, userID uint) (*models.TimeEntry, error) {
 	// Сначала получаем только запись времени без JOIN с категориями
@@ -385,7 +686,7 @@ func (r *PostgresRepository) GetActiveTimeEntryForUser(ctx context.Context, user
 		SELECT 
 			id, user_id, start_time, end_time, 
 			paused_at, resumed_at, total_paused, status, 
-			category_id, created_at, updated_at
+			category_id, project_id, billable, description, created_at, updated_at, last_heartbeat_at, auto_stopped, version
 		FROM time_entries 
 		WHERE user_id = $1 AND status != 'completed'
 		ORDER BY created_at DESC
@@ -393,8 +694,8 @@ func (r *PostgresRepository) GetActiveTimeEntryForUser(ctx context.Context, user
 	`
 
 	entry := &models.TimeEntry{}
-	var categoryID sql.NullInt64
-	var endTime, pausedAt, resumedAt sql.NullTime
+	var categoryID, projectID sql.NullInt64
+	var endTime, pausedAt, resumedAt, lastHeartbeat sql.NullTime
 	var status string
 
 	err := r.db.QueryRowContext(ctx, query, userID).Scan(
@@ -407,8 +708,14 @@ func (r *PostgresRepository) GetActiveTimeEntryForUser(ctx context.Context, user
 		&entry.TotalPaused,
 		&status,
 		&categoryID,
+		&projectID,
+		&entry.Billable,
+		&entry.Description,
 		&entry.CreatedAt,
 		&entry.UpdatedAt,
+		&lastHeartbeat,
+		&entry.AutoStopped,
+		&entry.Version,
 	)
 
 	if err != nil {
@@ -428,9 +735,13 @@ func (r *PostgresRepository) GetActiveTimeEntryForUser(ctx context.Context, user
 	if resumedAt.Valid {
 		entry.ResumedAt = resumedAt.Time
 	}
+	if lastHeartbeat.Valid {
+		entry.LastHeartbeat = lastHeartbeat.Time
+	}
 
-	// Устанавливаем статус
+	// Устанавливаем статус и проект
 	entry.Status = models.Status(status)
+	entry.ProjectID = uintPtr(projectID)
 
 	// Устанавливаем category_id только если оно не NULL
 	if categoryID.Valid {
@@ -447,6 +758,11 @@ func (r *PostgresRepository) GetActiveTimeEntryForUser(ctx context.Context, user
 		entry.CategoryID = nil
 	}
 
+	// Загружаем интервалы перерывов
+	if err := r.attachEntryDetails(ctx, []*models.TimeEntry{entry}); err != nil {
+		return nil, err
+	}
+
 	return entry, nil
 }
 
@@ -454,67 +770,419 @@ func (r *PostgresRepository) GetActiveTimeEntryForUser(ctx context.Context, user
 func (r *PostgresRepository) UpdateTimeEntry(ctx context.Context, entry *models.TimeEntry) error {
 	query := `
 		UPDATE time_entries
-		SET end_time = $1, paused_at = $2, resumed_at = $3,
-		    total_paused = $4, status = $5, updated_at = $6
-		WHERE id = $7
+		SET start_time = $1, end_time = $2, paused_at = $3, resumed_at = $4,
+		    total_paused = $5, status = $6, category_id = $7, project_id = $8, billable = $9,
+		    description = $10, auto_stopped = $11, updated_at = $12, version = version + 1
+		WHERE id = $13 AND version = $14
 	`
 
-	entry.UpdatedAt = time.Now()
+	entry.UpdatedAt = time.Now().UTC()
 
 	// Обрабатываем NULL значения для времени
 	var endTime, pausedAt, resumedAt sql.NullTime
 
 	if !entry.EndTime.IsZero() {
-		endTime.Time = entry.EndTime
+		endTime.Time = entry.EndTime.UTC()
 		endTime.Valid = true
 	}
 
 	if !entry.PausedAt.IsZero() {
-		pausedAt.Time = entry.PausedAt
+		pausedAt.Time = entry.PausedAt.UTC()
 		pausedAt.Valid = true
 	}
 
 	if !entry.ResumedAt.IsZero() {
-		resumedAt.Time = entry.ResumedAt
+		resumedAt.Time = entry.ResumedAt.UTC()
 		resumedAt.Valid = true
 	}
 
-	_, err := r.db.ExecContext(
+	// Обрабатываем NULL значение для category_id
+	var categoryID sql.NullInt64
+	if entry.CategoryID != nil {
+		categoryID.Int64 = int64(*entry.CategoryID)
+		categoryID.Valid = true
+	}
+
+	result, err := r.db.ExecContext(
 		ctx, query,
-		endTime, pausedAt, resumedAt,
-		entry.TotalPaused, entry.Status, entry.UpdatedAt, entry.ID,
+		entry.StartTime.UTC(), endTime, pausedAt, resumedAt,
+		entry.TotalPaused, entry.Status, categoryID, nullUint(entry.ProjectID), entry.Billable,
+		entry.Description, entry.AutoStopped, entry.UpdatedAt, entry.ID, entry.Version,
 	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: у пользователя уже есть активная запись времени", ErrConflict)
+		}
+		return fmt.Errorf("ошибка при обновлении записи времени: %w", err)
+	}
 
-	return err
+	// Запись не обновлена: она удалена или изменена параллельно после чтения
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка при обновлении записи времени: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%w: запись %d изменена или удалена параллельно", ErrConflict, entry.ID)
+	}
+	entry.Version++
+
+	r.notify(ctx, r.db, EntityTimeEntry, ActionUpdated, entry.UserID, entry.ID)
+	return nil
 }
 
 // DeleteTimeEntry удаляет запись о времени
 func (r *PostgresRepository) DeleteTimeEntry(ctx context.Context, id uint) error {
-	query := `DELETE FROM time_entries WHERE id = $1`
+	query := `DELETE FROM time_entries WHERE id = $1 RETURNING user_id`
 
-	_, err := r.db.ExecContext(ctx, query, id)
-	return err
+	var userID uint
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	r.notify(ctx, r.db, EntityTimeEntry, ActionDeleted, userID, id)
+	return nil
+}
+
+// UpdateHeartbeat сохраняет время последнего сигнала активности незавершенной записи
+func (r *PostgresRepository) UpdateHeartbeat(ctx context.Context, entryID uint, at time.Time) error {
+	query := `
+		UPDATE time_entries
+		SET last_heartbeat_at = $1
+		WHERE id = $2 AND status <> 'completed'
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, at.UTC(), entryID); err != nil {
+		return fmt.Errorf("ошибка при сохранении сигнала активности: %w", err)
+	}
+
+	return nil
+}
+
+// ListIdleTimeEntries возвращает активные записи, от которых дольше порога бездействия
+// их владельца до момента now не было сигналов активности. Записи без сигналов не учитываются:
+// клиент, не присылающий сигналы, не должен приводить к автоматической паузе.
+func (r *PostgresRepository) ListIdleTimeEntries(ctx context.Context, now time.Time) ([]*models.TimeEntry, error) {
+	query := `
+		SELECT te.id
+		FROM time_entries te
+		JOIN users u ON u.id = te.user_id
+		WHERE te.status = 'active'
+		AND te.last_heartbeat_at IS NOT NULL
+		AND u.idle_timeout > 0
+		AND te.last_heartbeat_at + u.idle_timeout * INTERVAL '1 minute' < $1
+		ORDER BY te.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при поиске записей без активности: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uint
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании записи: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при обработке результатов: %w", err)
+	}
+
+	entries := make([]*models.TimeEntry, 0, len(ids))
+	for _, id := range ids {
+		entry, err := r.GetTimeEntryByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// Методы для работы с перерывами
+
+// CreatePause сохраняет новый интервал перерыва
+func (r *PostgresRepository) CreatePause(ctx context.Context, pause *models.Pause) error {
+	query := `
+		INSERT INTO time_entry_pauses (time_entry_id, start_time, end_time, reason)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+
+	err := r.db.QueryRowContext(ctx, query, pause.TimeEntryID, pause.StartTime.UTC(), nullTime(pause.EndTime), pause.Reason).Scan(&pause.ID)
+	if err != nil {
+		return fmt.Errorf("ошибка при создании перерыва: %w", err)
+	}
+
+	return nil
+}
+
+// UpdatePause обновляет время начала и окончания перерыва
+func (r *PostgresRepository) UpdatePause(ctx context.Context, pause *models.Pause) error {
+	query := `
+		UPDATE time_entry_pauses
+		SET start_time = $1, end_time = $2
+		WHERE id = $3
+	`
+
+	_, err := r.db.ExecContext(ctx, query, pause.StartTime.UTC(), nullTime(pause.EndTime), pause.ID)
+	if err != nil {
+		return fmt.Errorf("ошибка при обновлении перерыва: %w", err)
+	}
+
+	return nil
+}
+
+// ReplacePauses заменяет все интервалы перерывов записи переданным списком
+func (r *PostgresRepository) ReplacePauses(ctx context.Context, entryID uint, pauses []models.Pause) error {
+	tx, err := r.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM time_entry_pauses WHERE time_entry_id = $1`, entryID); err != nil {
+		return fmt.Errorf("ошибка при удалении перерывов: %w", err)
+	}
+
+	for i := range pauses {
+		pauses[i].TimeEntryID = entryID
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO time_entry_pauses (time_entry_id, start_time, end_time, reason)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id
+		`, entryID, pauses[i].StartTime.UTC(), nullTime(pauses[i].EndTime), pauses[i].Reason).Scan(&pauses[i].ID)
+		if err != nil {
+			return fmt.Errorf("ошибка при создании перерыва: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ImportTimeEntries в одной транзакции создает категории и завершенные записи
+func (r *PostgresRepository) ImportTimeEntries(ctx context.Context, categories []*models.Category, entries []*models.TimeEntry) error {
+	tx, err := r.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	for _, category := range categories {
+		category.CreatedAt = now
+		category.UpdatedAt = now
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO categories (user_id, name, color, hourly_rate, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id
+		`, category.UserID, category.Name, category.Color, moneyValue(category.HourlyRate),
+			category.CreatedAt, category.UpdatedAt).Scan(&category.ID)
+		if err != nil {
+			return fmt.Errorf("ошибка при создании категории %q: %w", category.Name, err)
+		}
+		r.notify(ctx, tx, EntityCategory, ActionCreated, category.UserID, category.ID)
+	}
+
+	for _, entry := range entries {
+		if entry.CategoryID == nil && entry.Category != nil {
+			categoryID := entry.Category.ID
+			entry.CategoryID = &categoryID
+		}
+		entry.CreatedAt = now
+		entry.UpdatedAt = now
+
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO time_entries (
+				user_id, start_time, end_time, total_paused, status,
+				category_id, project_id, billable, description, created_at, updated_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			RETURNING id
+		`, entry.UserID, entry.StartTime.UTC(), entry.EndTime.UTC(), entry.TotalPaused, models.StatusCompleted,
+			nullUint(entry.CategoryID), nullUint(entry.ProjectID), entry.Billable, entry.Description,
+			entry.CreatedAt, entry.UpdatedAt).Scan(&entry.ID)
+		if err != nil {
+			return fmt.Errorf("ошибка при создании записи времени: %w", err)
+		}
+		r.notify(ctx, tx, EntityTimeEntry, ActionCreated, entry.UserID, entry.ID)
+	}
+
+	return tx.Commit()
+}
+
+// attachPauses загружает интервалы перерывов для переданных записей одним запросом
+func (r *PostgresRepository) attachPauses(ctx context.Context, entries []*models.TimeEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(entries))
+	byID := make(map[uint]*models.TimeEntry, len(entries))
+	for i, entry := range entries {
+		ids[i] = int64(entry.ID)
+		byID[entry.ID] = entry
+	}
+
+	query := `
+		SELECT id, time_entry_id, start_time, end_time, reason
+		FROM time_entry_pauses
+		WHERE time_entry_id = ANY($1)
+		ORDER BY start_time ASC, id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("ошибка при получении перерывов: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pause models.Pause
+		var endTime sql.NullTime
+		if err := rows.Scan(&pause.ID, &pause.TimeEntryID, &pause.StartTime, &endTime, &pause.Reason); err != nil {
+			return fmt.Errorf("ошибка при сканировании перерыва: %w", err)
+		}
+		if endTime.Valid {
+			pause.EndTime = endTime.Time
+		}
+
+		if entry, ok := byID[pause.TimeEntryID]; ok {
+			entry.Pauses = append(entry.Pauses, pause)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("ошибка при обработке перерывов: %w", err)
+	}
+
+	return nil
+}
+
+// attachEntryDetails загружает перерывы и метки для переданных записей
+func (r *PostgresRepository) attachEntryDetails(ctx context.Context, entries []*models.TimeEntry) error {
+	if err := r.attachPauses(ctx, entries); err != nil {
+		return err
+	}
+	return r.attachTags(ctx, entries)
+}
+
+// attachTags загружает метки для переданных записей одним запросом
+func (r *PostgresRepository) attachTags(ctx context.Context, entries []*models.TimeEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(entries))
+	byID := make(map[uint]*models.TimeEntry, len(entries))
+	for i, entry := range entries {
+		ids[i] = int64(entry.ID)
+		byID[entry.ID] = entry
+	}
+
+	query := `
+		SELECT tet.time_entry_id, t.id, t.user_id, t.name, t.color, t.created_at, t.updated_at
+		FROM time_entry_tags tet
+		JOIN tags t ON t.id = tet.tag_id
+		WHERE tet.time_entry_id = ANY($1)
+		ORDER BY t.name ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("ошибка при получении меток записей: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entryID uint
+		var tag models.Tag
+		if err := rows.Scan(&entryID, &tag.ID, &tag.UserID, &tag.Name, &tag.Color, &tag.CreatedAt, &tag.UpdatedAt); err != nil {
+			return fmt.Errorf("ошибка при сканировании метки: %w", err)
+		}
+
+		if entry, ok := byID[entryID]; ok {
+			entry.Tags = append(entry.Tags, tag)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("ошибка при обработке меток: %w", err)
+	}
+
+	return nil
+}
+
+// uintArray преобразует список идентификаторов в массив PostgreSQL
+func uintArray(ids []uint) interface{} {
+	values := make([]int64, len(ids))
+	for i, id := range ids {
+		values[i] = int64(id)
+	}
+	return pq.Array(values)
+}
+
+// nullTime преобразует нулевое время в NULL
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t.UTC(), Valid: !t.IsZero()}
+}
+
+// nullUint преобразует необязательный идентификатор в значение для SQL (nil - NULL)
+func nullUint(id *uint) sql.NullInt64 {
+	if id == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(*id), Valid: true}
+}
+
+// uintPtr преобразует NULL-совместимый идентификатор из базы в указатель
+func uintPtr(v sql.NullInt64) *uint {
+	if !v.Valid {
+		return nil
+	}
+	id := uint(v.Int64)
+	return &id
 }
 
 // GetUserStatsByPeriod возвращает статистику за период
-func (r *PostgresRepository) GetUserStatsByPeriod(ctx context.Context, userID uint, startDate, endDate string) ([]*models.TimeEntry, error) {
-	log.Printf("GetUserStatsByPeriod: НАЧАЛО ВЫПОЛНЕНИЯ МЕТОДА с параметрами userID=%d, startDate=%s, endDate=%s", userID, startDate, endDate)
+func (r *PostgresRepository) GetUserStatsByPeriod(ctx context.Context, userID uint, from, to time.Time, tagIDs []uint) ([]*models.TimeEntry, error) {
+	log.Printf("GetUserStatsByPeriod: НАЧАЛО ВЫПОЛНЕНИЯ МЕТОДА с параметрами userID=%d, from=%v, to=%v", userID, from, to)
 
-	// Формируем SQL запрос с использованием DATE() для корректного сравнения дат
+	// Границы периода уже рассчитаны в часовом поясе пользователя; время записей хранится
+	// без часового пояса и читается как UTC, поэтому сравниваем с границами в UTC.
+	// Выбираются записи, пересекающиеся с периодом, в том числе начатые накануне.
 	query := `
-		SELECT id, user_id, start_time, end_time, status, total_paused
+		SELECT id, user_id, start_time, end_time, status, total_paused,
+		       category_id, project_id, billable, auto_stopped
 		FROM time_entries
 		WHERE user_id = $1 
-		AND DATE(start_time) >= DATE($2)
-		AND DATE(start_time) <= DATE($3)
+		AND start_time < $3
+		AND end_time > $2
 		AND status = 'completed'
-		ORDER BY start_time DESC
 	`
+	args := []interface{}{userID, from.UTC(), to.UTC()}
+
+	// Фильтр по меткам: запись должна иметь хотя бы одну из указанных меток
+	if len(tagIDs) > 0 {
+		query += ` AND EXISTS (
+			SELECT 1 FROM time_entry_tags tet
+			WHERE tet.time_entry_id = time_entries.id AND tet.tag_id = ANY($4)
+		)`
+		args = append(args, uintArray(tagIDs))
+	}
+	query += ` ORDER BY start_time DESC`
 
-	log.Printf("GetUserStatsByPeriod: Выполняем SQL запрос: %s с параметрами: %d, %s, %s",
-		query, userID, startDate, endDate)
+	log.Printf("GetUserStatsByPeriod: Выполняем SQL запрос: %s с параметрами: %d, %v, %v, метки: %v",
+		query, userID, from, to, tagIDs)
 
-	rows, err := r.db.QueryContext(ctx, query, userID, startDate, endDate)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		log.Printf("GetUserStatsByPeriod: Ошибка при выполнении запроса: %v", err)
 		return nil, fmt.Errorf("ошибка при получении записей: %w", err)
@@ -526,6 +1194,7 @@ func (r *PostgresRepository) GetUserStatsByPeriod(ctx context.Context, userID ui
 	for rows.Next() {
 		entry := &models.TimeEntry{}
 		var status string
+		var categoryID, projectID sql.NullInt64
 
 		err := rows.Scan(
 			&entry.ID,
@@ -534,6 +1203,10 @@ func (r *PostgresRepository) GetUserStatsByPeriod(ctx context.Context, userID ui
 			&entry.EndTime,
 			&status,
 			&entry.TotalPaused,
+			&categoryID,
+			&projectID,
+			&entry.Billable,
+			&entry.AutoStopped,
 		)
 
 		if err != nil {
@@ -542,6 +1215,8 @@ func (r *PostgresRepository) GetUserStatsByPeriod(ctx context.Context, userID ui
 		}
 
 		entry.Status = models.Status(status)
+		entry.CategoryID = uintPtr(categoryID)
+		entry.ProjectID = uintPtr(projectID)
 		entries = append(entries, entry)
 	}
 
@@ -552,6 +1227,10 @@ func (r *PostgresRepository) GetUserStatsByPeriod(ctx context.Context, userID ui
 
 	log.Printf("GetUserStatsByPeriod: Найдено %d записей", len(entries))
 
+	if err := r.attachEntryDetails(ctx, entries); err != nil {
+		return nil, err
+	}
+
 	// Если записей нет, выводим дополнительную информацию для отладки
 	if len(entries) == 0 {
 		var count int
@@ -583,15 +1262,129 @@ func (r *PostgresRepository) GetUserStatsByPeriod(ctx context.Context, userID ui
 	return entries, nil
 }
 
+// GetCategoryStatsByPeriod возвращает длительность записей по категориям за каждый день и за весь период.
+// Агрегация выполняется в базе: запись, пересекающая полночь в часовом поясе loc, делится на части
+// по дням (generate_series), из каждой части вычитаются пришедшиеся на нее перерывы (у записей без
+// интервалов перерывов total_paused делится пропорционально), части обрезаются границами периода.
+// GROUPING SETS дает в одном запросе и строки по дням (day не NULL), и итоги за период (day = NULL),
+// а оконная функция считает долю категории внутри каждой группы.
+func (r *PostgresRepository) GetCategoryStatsByPeriod(ctx context.Context, filter StatsFilter) ([]CategoryDuration, error) {
+	loc := filter.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	args := []interface{}{filter.UserID, filter.From.UTC(), filter.To.UTC(), loc.String()}
+
+	// Конец записи: для завершенной - end_time, для приостановленной - момент паузы,
+	// для активной - RunningUntil
+	endExpr := "te.end_time"
+	statusFilter := "te.status = 'completed'"
+	if !filter.RunningUntil.IsZero() {
+		args = append(args, filter.RunningUntil.UTC())
+		endExpr = fmt.Sprintf(
+			"COALESCE(te.end_time, CASE WHEN te.status = 'paused' THEN te.paused_at ELSE $%d::TIMESTAMP END)", len(args))
+		statusFilter = "te.status IN ('completed', 'active', 'paused')"
+	}
+
+	tagFilter := ""
+	if len(filter.TagIDs) > 0 {
+		args = append(args, uintArray(filter.TagIDs))
+		tagFilter = fmt.Sprintf(` AND EXISTS (
+			SELECT 1 FROM time_entry_tags tet
+			WHERE tet.time_entry_id = te.id AND tet.tag_id = ANY($%d)
+		)`, len(args))
+	}
+
+	query := `
+		WITH entries AS (
+			SELECT te.id, te.category_id, te.total_paused,
+			       te.start_time AT TIME ZONE 'UTC' AS start_at,
+			       ` + endExpr + ` AT TIME ZONE 'UTC' AS end_at
+			FROM time_entries te
+			WHERE te.user_id = $1
+			AND te.start_time < $3::TIMESTAMP
+			AND ` + endExpr + ` > $2::TIMESTAMP
+			AND ` + statusFilter + tagFilter + `
+		),
+		slices AS (
+			SELECT e.id, e.category_id, e.total_paused, e.start_at, e.end_at,
+			       d.day::DATE AS day,
+			       GREATEST(e.start_at, d.day AT TIME ZONE $4, $2::TIMESTAMP AT TIME ZONE 'UTC') AS slice_start,
+			       LEAST(e.end_at, (d.day + INTERVAL '1 day') AT TIME ZONE $4, $3::TIMESTAMP AT TIME ZONE 'UTC') AS slice_end
+			FROM entries e
+			CROSS JOIN LATERAL generate_series(
+				DATE_TRUNC('day', e.start_at AT TIME ZONE $4),
+				DATE_TRUNC('day', e.end_at AT TIME ZONE $4),
+				INTERVAL '1 day'
+			) AS d(day)
+		),
+		durations AS (
+			SELECT s.id, s.category_id, s.day,
+			       (EXTRACT(EPOCH FROM (s.slice_end - s.slice_start)) - CASE
+			           WHEN EXISTS (SELECT 1 FROM time_entry_pauses p WHERE p.time_entry_id = s.id) THEN COALESCE((
+			               SELECT SUM(EXTRACT(EPOCH FROM (
+			                   LEAST(s.slice_end, COALESCE(p.end_time AT TIME ZONE 'UTC', s.end_at))
+			                   - GREATEST(s.slice_start, p.start_time AT TIME ZONE 'UTC')
+			               )))
+			               FROM time_entry_pauses p
+			               WHERE p.time_entry_id = s.id
+			               AND p.start_time AT TIME ZONE 'UTC' < s.slice_end
+			               AND COALESCE(p.end_time AT TIME ZONE 'UTC', s.end_at) > s.slice_start
+			           ), 0)
+			           ELSE s.total_paused * EXTRACT(EPOCH FROM (s.slice_end - s.slice_start))
+			                / NULLIF(EXTRACT(EPOCH FROM (s.end_at - s.start_at)), 0)
+			       END)::NUMERIC AS duration
+			FROM slices s
+			WHERE s.slice_end > s.slice_start
+		)
+		SELECT TO_CHAR(d.day, 'YYYY-MM-DD'), d.category_id,
+		       COALESCE(c.name, ''), COALESCE(c.color, ''),
+		       ROUND(SUM(d.duration))::BIGINT, COUNT(DISTINCT d.id),
+		       COALESCE(ROUND(100.0 * SUM(d.duration) / NULLIF(SUM(SUM(d.duration)) OVER (PARTITION BY d.day), 0), 2), 0)
+		FROM durations d
+		LEFT JOIN categories c ON c.id = d.category_id
+		GROUP BY GROUPING SETS ((d.day, d.category_id, c.name, c.color), (d.category_id, c.name, c.color))
+		ORDER BY d.day NULLS FIRST, 5 DESC, 3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Printf("GetCategoryStatsByPeriod: Ошибка при выполнении запроса: %v", err)
+		return nil, fmt.Errorf("ошибка при получении статистики по категориям: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []CategoryDuration
+	for rows.Next() {
+		var stat CategoryDuration
+		var day sql.NullString
+		var categoryID sql.NullInt64
+
+		if err := rows.Scan(&day, &categoryID, &stat.Name, &stat.Color, &stat.Duration, &stat.EntryCount, &stat.Percent); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании статистики по категориям: %w", err)
+		}
+
+		stat.Day = day.String
+		stat.CategoryID = uintPtr(categoryID)
+		stats = append(stats, stat)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при обработке результатов: %w", err)
+	}
+
+	return stats, nil
+}
+
 // CreateCategory создает новую категорию в базе данных
 func (r *PostgresRepository) CreateCategory(ctx context.Context, category *models.Category) error {
-	now := time.Now()
+	now := time.Now().UTC()
 	category.CreatedAt = now
 	category.UpdatedAt = now
 
 	query := `
-		INSERT INTO categories (user_id, name, color, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO categories (user_id, name, color, hourly_rate, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id
 	`
 
@@ -601,6 +1394,7 @@ func (r *PostgresRepository) CreateCategory(ctx context.Context, category *model
 		category.UserID,
 		category.Name,
 		category.Color,
+		moneyValue(category.HourlyRate),
 		category.CreatedAt,
 		category.UpdatedAt,
 	).Scan(&category.ID)
@@ -609,23 +1403,26 @@ func (r *PostgresRepository) CreateCategory(ctx context.Context, category *model
 		return fmt.Errorf("ошибка при создании категории: %w", err)
 	}
 
+	r.notify(ctx, r.db, EntityCategory, ActionCreated, category.UserID, category.ID)
 	return nil
 }
 
 // GetCategoryByID получает категорию по ID
 func (r *PostgresRepository) GetCategoryByID(ctx context.Context, id uint) (*models.Category, error) {
 	query := `
-		SELECT id, user_id, name, color, created_at, updated_at
+		SELECT id, user_id, name, color, hourly_rate, created_at, updated_at
 		FROM categories
 		WHERE id = $1
 	`
 
 	category := &models.Category{}
+	var hourlyRate sql.NullString
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&category.ID,
 		&category.UserID,
 		&category.Name,
 		&category.Color,
+		&hourlyRate,
 		&category.CreatedAt,
 		&category.UpdatedAt,
 	)
@@ -637,13 +1434,17 @@ func (r *PostgresRepository) GetCategoryByID(ctx context.Context, id uint) (*mod
 		return nil, fmt.Errorf("ошибка при получении категории: %w", err)
 	}
 
+	if category.HourlyRate, err = scanMoney(hourlyRate); err != nil {
+		return nil, err
+	}
+
 	return category, nil
 }
 
 // GetCategoriesByUserID получает все категории пользователя
 func (r *PostgresRepository) GetCategoriesByUserID(ctx context.Context, userID uint) ([]*models.Category, error) {
 	query := `
-		SELECT id, user_id, name, color, created_at, updated_at
+		SELECT id, user_id, name, color, hourly_rate, created_at, updated_at
 		FROM categories
 		WHERE user_id = $1
 		ORDER BY name ASC
@@ -659,11 +1460,13 @@ func (r *PostgresRepository) GetCategoriesByUserID(ctx context.Context, userID u
 
 	for rows.Next() {
 		category := &models.Category{}
+		var hourlyRate sql.NullString
 		err := rows.Scan(
 			&category.ID,
 			&category.UserID,
 			&category.Name,
 			&category.Color,
+			&hourlyRate,
 			&category.CreatedAt,
 			&category.UpdatedAt,
 		)
@@ -672,6 +1475,10 @@ func (r *PostgresRepository) GetCategoriesByUserID(ctx context.Context, userID u
 			return nil, fmt.Errorf("ошибка при сканировании категории: %w", err)
 		}
 
+		if category.HourlyRate, err = scanMoney(hourlyRate); err != nil {
+			return nil, err
+		}
+
 		categories = append(categories, category)
 	}
 
@@ -684,13 +1491,13 @@ func (r *PostgresRepository) GetCategoriesByUserID(ctx context.Context, userID u
 
 // UpdateCategory обновляет существующую категорию
 func (r *PostgresRepository) UpdateCategory(ctx context.Context, category *models.Category) error {
-	now := time.Now()
+	now := time.Now().UTC()
 	category.UpdatedAt = now
 
 	query := `
 		UPDATE categories
-		SET name = $1, color = $2, updated_at = $3
-		WHERE id = $4 AND user_id = $5
+		SET name = $1, color = $2, hourly_rate = $3, updated_at = $4
+		WHERE id = $5 AND user_id = $6
 	`
 
 	result, err := r.db.ExecContext(
@@ -698,6 +1505,7 @@ func (r *PostgresRepository) UpdateCategory(ctx context.Context, category *model
 		query,
 		category.Name,
 		category.Color,
+		moneyValue(category.HourlyRate),
 		category.UpdatedAt,
 		category.ID,
 		category.UserID,
@@ -716,6 +1524,7 @@ func (r *PostgresRepository) UpdateCategory(ctx context.Context, category *model
 		return fmt.Errorf("категория с id=%d не найдена или не принадлежит пользователю", category.ID)
 	}
 
+	r.notify(ctx, r.db, EntityCategory, ActionUpdated, category.UserID, category.ID)
 	return nil
 }
 
@@ -724,20 +1533,1225 @@ func (r *PostgresRepository) DeleteCategory(ctx context.Context, id uint) error
 	query := `
 		DELETE FROM categories
 		WHERE id = $1
+		RETURNING user_id
 	`
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	var userID uint
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("категория с id=%d не найдена", id)
+	}
 	if err != nil {
 		return fmt.Errorf("ошибка при удалении категории: %w", err)
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	r.notify(ctx, r.db, EntityCategory, ActionDeleted, userID, id)
+	return nil
+}
+
+// Методы для работы с метками
+
+// CreateTag создает новую метку в базе данных
+func (r *PostgresRepository) CreateTag(ctx context.Context, tag *models.Tag) error {
+	now := time.Now().UTC()
+	tag.CreatedAt = now
+	tag.UpdatedAt = now
+
+	query := `
+		INSERT INTO tags (user_id, name, color, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		tag.UserID,
+		tag.Name,
+		tag.Color,
+		tag.CreatedAt,
+		tag.UpdatedAt,
+	).Scan(&tag.ID)
+
 	if err != nil {
-		return fmt.Errorf("ошибка при получении количества удаленных строк: %w", err)
+		return fmt.Errorf("ошибка при создании метки: %w", err)
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("категория с id=%d не найдена", id)
+	return nil
+}
+
+// GetTagByID получает метку по ID
+func (r *PostgresRepository) GetTagByID(ctx context.Context, id uint) (*models.Tag, error) {
+	query := `
+		SELECT id, user_id, name, color, created_at, updated_at
+		FROM tags
+		WHERE id = $1
+	`
+
+	tag := &models.Tag{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&tag.ID,
+		&tag.UserID,
+		&tag.Name,
+		&tag.Color,
+		&tag.CreatedAt,
+		&tag.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("метка с id=%d не найдена", id)
+		}
+		return nil, fmt.Errorf("ошибка при получении метки: %w", err)
+	}
+
+	return tag, nil
+}
+
+// GetTagsByUserID получает все метки пользователя
+func (r *PostgresRepository) GetTagsByUserID(ctx context.Context, userID uint) ([]*models.Tag, error) {
+	query := `
+		SELECT id, user_id, name, color, created_at, updated_at
+		FROM tags
+		WHERE user_id = $1
+		ORDER BY name ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении меток пользователя: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []*models.Tag
+
+	for rows.Next() {
+		tag := &models.Tag{}
+		err := rows.Scan(
+			&tag.ID,
+			&tag.UserID,
+			&tag.Name,
+			&tag.Color,
+			&tag.CreatedAt,
+			&tag.UpdatedAt,
+		)
+
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании метки: %w", err)
+		}
+
+		tags = append(tags, tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при обработке результатов: %w", err)
+	}
+
+	return tags, nil
+}
+
+// UpdateTag обновляет существующую метку
+func (r *PostgresRepository) UpdateTag(ctx context.Context, tag *models.Tag) error {
+	tag.UpdatedAt = time.Now().UTC()
+
+	query := `
+		UPDATE tags
+		SET name = $1, color = $2, updated_at = $3
+		WHERE id = $4 AND user_id = $5
+	`
+
+	result, err := r.db.ExecContext(ctx, query, tag.Name, tag.Color, tag.UpdatedAt, tag.ID, tag.UserID)
+	if err != nil {
+		return fmt.Errorf("ошибка при обновлении метки: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка при получении количества измененных строк: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("метка с id=%d не найдена или не принадлежит пользователю", tag.ID)
+	}
+
+	return nil
+}
+
+// DeleteTag удаляет метку (связи с записями удаляются каскадно)
+func (r *PostgresRepository) DeleteTag(ctx context.Context, id uint) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM tags WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("ошибка при удалении метки: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка при получении количества удаленных строк: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("метка с id=%d не найдена", id)
+	}
+
+	return nil
+}
+
+// SetTimeEntryTags заменяет набор меток записи о времени
+func (r *PostgresRepository) SetTimeEntryTags(ctx context.Context, entryID uint, tagIDs []uint) error {
+	tx, err := r.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM time_entry_tags WHERE time_entry_id = $1`, entryID); err != nil {
+		return fmt.Errorf("ошибка при удалении меток записи: %w", err)
+	}
+
+	if len(tagIDs) > 0 {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO time_entry_tags (time_entry_id, tag_id)
+			SELECT $1, UNNEST($2::int[])
+			ON CONFLICT DO NOTHING
+		`, entryID, uintArray(tagIDs))
+		if err != nil {
+			return fmt.Errorf("ошибка при назначении меток записи: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Методы для работы с клиентами
+
+// scanMoney преобразует NUMERIC-значение из базы в денежную сумму (NULL - nil)
+func scanMoney(v sql.NullString) (*models.Money, error) {
+	if !v.Valid {
+		return nil, nil
+	}
+	money, err := models.ParseMoney(v.String)
+	if err != nil {
+		return nil, fmt.Errorf("некорректная сумма %q в базе данных: %w", v.String, err)
+	}
+	return &money, nil
+}
+
+// moneyValue преобразует необязательную денежную сумму в значение для SQL
+func moneyValue(m *models.Money) interface{} {
+	if m == nil {
+		return nil
+	}
+	return m.String()
+}
+
+// CreateClient создает нового клиента
+func (r *PostgresRepository) CreateClient(ctx context.Context, client *models.Client) error {
+	now := time.Now().UTC()
+	client.CreatedAt = now
+	client.UpdatedAt = now
+
+	query := `
+		INSERT INTO clients (user_id, name, hourly_rate, archived, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		client.UserID,
+		client.Name,
+		moneyValue(client.HourlyRate),
+		client.Archived,
+		client.CreatedAt,
+		client.UpdatedAt,
+	).Scan(&client.ID)
+
+	if err != nil {
+		return fmt.Errorf("ошибка при создании клиента: %w", err)
+	}
+
+	return nil
+}
+
+// clientColumns - список колонок клиента для выборки
+const clientColumns = `id, user_id, name, hourly_rate, archived, created_at, updated_at`
+
+// scanClient сканирует строку, выбранную по clientColumns
+func scanClient(row interface{ Scan(...interface{}) error }) (*models.Client, error) {
+	client := &models.Client{}
+	var hourlyRate sql.NullString
+
+	err := row.Scan(
+		&client.ID,
+		&client.UserID,
+		&client.Name,
+		&hourlyRate,
+		&client.Archived,
+		&client.CreatedAt,
+		&client.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if client.HourlyRate, err = scanMoney(hourlyRate); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// GetClientByID получает клиента по ID
+func (r *PostgresRepository) GetClientByID(ctx context.Context, id uint) (*models.Client, error) {
+	query := `SELECT ` + clientColumns + ` FROM clients WHERE id = $1`
+
+	client, err := scanClient(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("клиент с id=%d не найден", id)
+		}
+		return nil, fmt.Errorf("ошибка при получении клиента: %w", err)
+	}
+
+	return client, nil
+}
+
+// GetClientsByUserID получает всех клиентов пользователя, включая архивных
+func (r *PostgresRepository) GetClientsByUserID(ctx context.Context, userID uint) ([]*models.Client, error) {
+	query := `SELECT ` + clientColumns + ` FROM clients WHERE user_id = $1 ORDER BY name ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении клиентов пользователя: %w", err)
+	}
+	defer rows.Close()
+
+	var clients []*models.Client
+	for rows.Next() {
+		client, err := scanClient(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании клиента: %w", err)
+		}
+		clients = append(clients, client)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при обработке результатов: %w", err)
+	}
+
+	return clients, nil
+}
+
+// UpdateClient обновляет существующего клиента
+func (r *PostgresRepository) UpdateClient(ctx context.Context, client *models.Client) error {
+	client.UpdatedAt = time.Now().UTC()
+
+	query := `
+		UPDATE clients
+		SET name = $1, hourly_rate = $2, archived = $3, updated_at = $4
+		WHERE id = $5 AND user_id = $6
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		client.Name, moneyValue(client.HourlyRate), client.Archived, client.UpdatedAt, client.ID, client.UserID)
+	if err != nil {
+		return fmt.Errorf("ошибка при обновлении клиента: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка при получении количества измененных строк: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("клиент с id=%d не найден или не принадлежит пользователю", client.ID)
+	}
+
+	return nil
+}
+
+// DeleteClient удаляет клиента; его проекты остаются без клиента
+func (r *PostgresRepository) DeleteClient(ctx context.Context, id uint) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM clients WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("ошибка при удалении клиента: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка при получении количества удаленных строк: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("клиент с id=%d не найден", id)
+	}
+
+	return nil
+}
+
+// Методы для работы с проектами
+
+// projectColumns - список колонок проекта для выборки вместе с допустимыми категориями
+const projectColumns = `
+			p.id, p.user_id, p.client_id, p.name, p.color, p.hourly_rate, p.budget_hours,
+			p.archived, p.created_at, p.updated_at,
+			COALESCE((SELECT array_agg(pc.category_id ORDER BY pc.category_id)
+			          FROM project_categories pc WHERE pc.project_id = p.id), '{}')`
+
+// scanProject сканирует строку, выбранную по projectColumns
+func scanProject(row interface{ Scan(...interface{}) error }) (*models.Project, error) {
+	project := &models.Project{}
+	var clientID sql.NullInt64
+	var hourlyRate sql.NullString
+	var budgetHours sql.NullFloat64
+	var categoryIDs pq.Int64Array
+
+	err := row.Scan(
+		&project.ID,
+		&project.UserID,
+		&clientID,
+		&project.Name,
+		&project.Color,
+		&hourlyRate,
+		&budgetHours,
+		&project.Archived,
+		&project.CreatedAt,
+		&project.UpdatedAt,
+		&categoryIDs,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	project.ClientID = uintPtr(clientID)
+	if project.HourlyRate, err = scanMoney(hourlyRate); err != nil {
+		return nil, err
+	}
+	if budgetHours.Valid {
+		project.BudgetHours = &budgetHours.Float64
+	}
+
+	project.CategoryIDs = make([]uint, len(categoryIDs))
+	for i, id := range categoryIDs {
+		project.CategoryIDs[i] = uint(id)
+	}
+
+	return project, nil
+}
+
+// CreateProject создает новый проект
+func (r *PostgresRepository) CreateProject(ctx context.Context, project *models.Project) error {
+	now := time.Now().UTC()
+	project.CreatedAt = now
+	project.UpdatedAt = now
+
+	query := `
+		INSERT INTO projects (
+			user_id, client_id, name, color, hourly_rate, budget_hours,
+			archived, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`
+
+	var budgetHours sql.NullFloat64
+	if project.BudgetHours != nil {
+		budgetHours = sql.NullFloat64{Float64: *project.BudgetHours, Valid: true}
+	}
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		project.UserID,
+		nullUint(project.ClientID),
+		project.Name,
+		project.Color,
+		moneyValue(project.HourlyRate),
+		budgetHours,
+		project.Archived,
+		project.CreatedAt,
+		project.UpdatedAt,
+	).Scan(&project.ID)
+
+	if err != nil {
+		return fmt.Errorf("ошибка при создании проекта: %w", err)
+	}
+
+	return nil
+}
+
+// GetProjectByID получает проект по ID
+func (r *PostgresRepository) GetProjectByID(ctx context.Context, id uint) (*models.Project, error) {
+	query := `SELECT ` + projectColumns + ` FROM projects p WHERE p.id = $1`
+
+	project, err := scanProject(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("проект с id=%d не найден", id)
+		}
+		return nil, fmt.Errorf("ошибка при получении проекта: %w", err)
+	}
+
+	return project, nil
+}
+
+// GetProjectsByUserID получает все проекты пользователя, включая архивные
+func (r *PostgresRepository) GetProjectsByUserID(ctx context.Context, userID uint) ([]*models.Project, error) {
+	query := `SELECT ` + projectColumns + ` FROM projects p WHERE p.user_id = $1 ORDER BY p.name ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении проектов пользователя: %w", err)
+	}
+	defer rows.Close()
+
+	var projects []*models.Project
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании проекта: %w", err)
+		}
+		projects = append(projects, project)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при обработке результатов: %w", err)
+	}
+
+	return projects, nil
+}
+
+// UpdateProject обновляет существующий проект (без списка категорий, см. SetProjectCategories)
+func (r *PostgresRepository) UpdateProject(ctx context.Context, project *models.Project) error {
+	project.UpdatedAt = time.Now().UTC()
+
+	query := `
+		UPDATE projects
+		SET client_id = $1, name = $2, color = $3, hourly_rate = $4, budget_hours = $5,
+		    archived = $6, updated_at = $7
+		WHERE id = $8 AND user_id = $9
+	`
+
+	var budgetHours sql.NullFloat64
+	if project.BudgetHours != nil {
+		budgetHours = sql.NullFloat64{Float64: *project.BudgetHours, Valid: true}
+	}
+
+	result, err := r.db.ExecContext(ctx, query,
+		nullUint(project.ClientID), project.Name, project.Color, moneyValue(project.HourlyRate), budgetHours,
+		project.Archived, project.UpdatedAt, project.ID, project.UserID)
+	if err != nil {
+		return fmt.Errorf("ошибка при обновлении проекта: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка при получении количества измененных строк: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("проект с id=%d не найден или не принадлежит пользователю", project.ID)
+	}
+
+	return nil
+}
+
+// DeleteProject удаляет проект; записи времени остаются без проекта
+func (r *PostgresRepository) DeleteProject(ctx context.Context, id uint) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM projects WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("ошибка при удалении проекта: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка при получении количества удаленных строк: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("проект с id=%d не найден", id)
+	}
+
+	return nil
+}
+
+// SetProjectCategories заменяет набор категорий, допустимых в проекте
+func (r *PostgresRepository) SetProjectCategories(ctx context.Context, projectID uint, categoryIDs []uint) error {
+	tx, err := r.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM project_categories WHERE project_id = $1`, projectID); err != nil {
+		return fmt.Errorf("ошибка при удалении категорий проекта: %w", err)
+	}
+
+	if len(categoryIDs) > 0 {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO project_categories (project_id, category_id)
+			SELECT $1, UNNEST($2::int[])
+			ON CONFLICT DO NOTHING
+		`, projectID, uintArray(categoryIDs))
+		if err != nil {
+			return fmt.Errorf("ошибка при назначении категорий проекта: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// CreateCalendarToken сохраняет токен календаря
+func (r *PostgresRepository) CreateCalendarToken(ctx context.Context, token *models.CalendarToken) error {
+	token.CreatedAt = time.Now().UTC()
+
+	query := `
+		INSERT INTO calendar_tokens (user_id, name, token_hash, created_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+
+	err := r.db.QueryRowContext(ctx, query, token.UserID, token.Name, token.TokenHash, token.CreatedAt).Scan(&token.ID)
+	if err != nil {
+		return fmt.Errorf("ошибка при создании токена календаря: %w", err)
+	}
+
+	return nil
+}
+
+// GetCalendarTokensByUserID получает все токены календаря пользователя
+func (r *PostgresRepository) GetCalendarTokensByUserID(ctx context.Context, userID uint) ([]*models.CalendarToken, error) {
+	query := `
+		SELECT id, user_id, name, token_hash, created_at
+		FROM calendar_tokens
+		WHERE user_id = $1
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении токенов календаря: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []*models.CalendarToken
+	for rows.Next() {
+		token := &models.CalendarToken{}
+		if err := rows.Scan(&token.ID, &token.UserID, &token.Name, &token.TokenHash, &token.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании токена календаря: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при обработке результатов: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// GetCalendarTokenByHash получает токен календаря по хешу
+func (r *PostgresRepository) GetCalendarTokenByHash(ctx context.Context, tokenHash string) (*models.CalendarToken, error) {
+	query := `
+		SELECT id, user_id, name, token_hash, created_at
+		FROM calendar_tokens
+		WHERE token_hash = $1
+	`
+
+	token := &models.CalendarToken{}
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(&token.ID, &token.UserID, &token.Name, &token.TokenHash, &token.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка при получении токена календаря: %w", err)
+	}
+
+	return token, nil
+}
+
+// DeleteCalendarToken удаляет токен календаря
+func (r *PostgresRepository) DeleteCalendarToken(ctx context.Context, id uint) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM calendar_tokens WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("ошибка при удалении токена календаря: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка при получении количества удаленных строк: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("токен календаря с id=%d не найден", id)
+	}
+
+	return nil
+}
+
+// sessionColumns - столбцы сессии в порядке сканирования scanSession
+const sessionColumns = `id, user_id, refresh_token_hash, user_agent, ip, remember_me, created_at, last_used_at, expires_at`
+
+// scanSession считывает сессию из строки результата
+func scanSession(row interface{ Scan(...interface{}) error }) (*models.Session, error) {
+	session := &models.Session{}
+	err := row.Scan(&session.ID, &session.UserID, &session.RefreshTokenHash, &session.UserAgent, &session.IP,
+		&session.RememberMe, &session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// CreateSession сохраняет сессию входа
+func (r *PostgresRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	query := `
+		INSERT INTO sessions (user_id, refresh_token_hash, user_agent, ip, remember_me, created_at, last_used_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		session.UserID, session.RefreshTokenHash, session.UserAgent, session.IP, session.RememberMe,
+		session.CreatedAt.UTC(), session.LastUsedAt.UTC(), session.ExpiresAt.UTC(),
+	).Scan(&session.ID)
+	if err != nil {
+		return fmt.Errorf("ошибка при создании сессии: %w", err)
+	}
+
+	return nil
+}
+
+// GetSessionByID получает сессию по ID
+func (r *PostgresRepository) GetSessionByID(ctx context.Context, id uint) (*models.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE id = $1`
+
+	session, err := scanSession(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка при получении сессии: %w", err)
+	}
+
+	return session, nil
+}
+
+// GetSessionByTokenHash получает сессию по хешу токена обновления
+func (r *PostgresRepository) GetSessionByTokenHash(ctx context.Context, tokenHash string) (*models.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE refresh_token_hash = $1`
+
+	session, err := scanSession(r.db.QueryRowContext(ctx, query, tokenHash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка при получении сессии: %w", err)
+	}
+
+	return session, nil
+}
+
+// GetSessionsByUserID получает действующие сессии пользователя, начиная с последней использованной
+func (r *PostgresRepository) GetSessionsByUserID(ctx context.Context, userID uint, now time.Time) ([]*models.Session, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE user_id = $1 AND expires_at > $2
+		ORDER BY last_used_at DESC, id DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении сессий: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*models.Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании сессии: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при обработке результатов: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// RotateSession заменяет токен обновления сессии. Условие на предыдущий хеш не дает
+// использовать один токен обновления дважды при параллельных запросах.
+func (r *PostgresRepository) RotateSession(ctx context.Context, session *models.Session, previousHash string) error {
+	query := `
+		UPDATE sessions
+		SET refresh_token_hash = $1, user_agent = $2, ip = $3, last_used_at = $4, expires_at = $5
+		WHERE id = $6 AND refresh_token_hash = $7
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		session.RefreshTokenHash, session.UserAgent, session.IP, session.LastUsedAt.UTC(), session.ExpiresAt.UTC(),
+		session.ID, previousHash,
+	)
+	if err != nil {
+		return fmt.Errorf("ошибка при обновлении сессии: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка при обновлении сессии: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%w: токен обновления сессии %d уже использован", ErrConflict, session.ID)
+	}
+
+	return nil
+}
+
+// DeleteSession удаляет сессию
+func (r *PostgresRepository) DeleteSession(ctx context.Context, id uint) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("ошибка при удалении сессии: %w", err)
+	}
+	return nil
+}
+
+// DeleteUserSessions удаляет сессии пользователя, кроме exceptID
+func (r *PostgresRepository) DeleteUserSessions(ctx context.Context, userID, exceptID uint) error {
+	query := `DELETE FROM sessions WHERE user_id = $1 AND id <> $2`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, exceptID); err != nil {
+		return fmt.Errorf("ошибка при удалении сессий пользователя: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredSessions удаляет сессии с истекшим сроком действия
+func (r *PostgresRepository) DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at <= $1`, now.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при удалении истекших сессий: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// CreatePasswordResetToken сохраняет токен сброса пароля
+func (r *PostgresRepository) CreatePasswordResetToken(ctx context.Context, token *models.PasswordResetToken) error {
+	query := `
+		INSERT INTO password_reset_tokens (user_id, token_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		token.UserID, token.TokenHash, token.CreatedAt.UTC(), token.ExpiresAt.UTC(),
+	).Scan(&token.ID)
+	if err != nil {
+		return fmt.Errorf("ошибка при создании токена сброса пароля: %w", err)
+	}
+
+	return nil
+}
+
+// UsePasswordResetToken отмечает действующий токен сброса пароля использованным. Отметка
+// и проверка выполняются одним запросом, поэтому токен нельзя использовать дважды.
+func (r *PostgresRepository) UsePasswordResetToken(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordResetToken, error) {
+	query := `
+		UPDATE password_reset_tokens
+		SET used_at = $2
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2
+		RETURNING id, user_id, token_hash, created_at, expires_at, used_at
+	`
+
+	token := &models.PasswordResetToken{}
+	err := r.db.QueryRowContext(ctx, query, tokenHash, now.UTC()).Scan(
+		&token.ID, &token.UserID, &token.TokenHash, &token.CreatedAt, &token.ExpiresAt, &token.UsedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка при использовании токена сброса пароля: %w", err)
+	}
+
+	return token, nil
+}
+
+// DeletePasswordResetTokens удаляет все токены сброса пароля пользователя
+func (r *PostgresRepository) DeletePasswordResetTokens(ctx context.Context, userID uint) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM password_reset_tokens WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("ошибка при удалении токенов сброса пароля: %w", err)
+	}
+	return nil
+}
+
+// CreateEmailVerificationToken сохраняет токен подтверждения email
+func (r *PostgresRepository) CreateEmailVerificationToken(ctx context.Context, token *models.EmailVerificationToken) error {
+	query := `
+		INSERT INTO email_verification_tokens (user_id, token_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		token.UserID, token.TokenHash, token.CreatedAt.UTC(), token.ExpiresAt.UTC(),
+	).Scan(&token.ID)
+	if err != nil {
+		return fmt.Errorf("ошибка при создании токена подтверждения email: %w", err)
+	}
+
+	return nil
+}
+
+// UseEmailVerificationToken отмечает действующий токен подтверждения email использованным
+// одним запросом, как UsePasswordResetToken
+func (r *PostgresRepository) UseEmailVerificationToken(ctx context.Context, tokenHash string, now time.Time) (*models.EmailVerificationToken, error) {
+	query := `
+		UPDATE email_verification_tokens
+		SET used_at = $2
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2
+		RETURNING id, user_id, token_hash, created_at, expires_at, used_at
+	`
+
+	token := &models.EmailVerificationToken{}
+	err := r.db.QueryRowContext(ctx, query, tokenHash, now.UTC()).Scan(
+		&token.ID, &token.UserID, &token.TokenHash, &token.CreatedAt, &token.ExpiresAt, &token.UsedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка при использовании токена подтверждения email: %w", err)
+	}
+
+	return token, nil
+}
+
+// DeleteEmailVerificationTokens удаляет все токены подтверждения email пользователя
+func (r *PostgresRepository) DeleteEmailVerificationTokens(ctx context.Context, userID uint) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM email_verification_tokens WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("ошибка при удалении токенов подтверждения email: %w", err)
+	}
+	return nil
+}
+
+// MarkEmailVerified отмечает email пользователя подтвержденным. UpdateUser не изменяет
+// email_verified_at, поэтому сохранение настроек не может снять отметку.
+func (r *PostgresRepository) MarkEmailVerified(ctx context.Context, userID uint, at time.Time) error {
+	query := `UPDATE users SET email_verified_at = $2 WHERE id = $1 AND email_verified_at IS NULL`
+	if _, err := r.db.ExecContext(ctx, query, userID, at.UTC()); err != nil {
+		return fmt.Errorf("ошибка при подтверждении email: %w", err)
+	}
+	return nil
+}
+
+// GetTOTP возвращает настройки TOTP пользователя
+func (r *PostgresRepository) GetTOTP(ctx context.Context, userID uint) (*models.TOTP, error) {
+	query := `SELECT user_id, secret, enabled_at, last_step, created_at FROM user_totp WHERE user_id = $1`
+
+	totp := &models.TOTP{}
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&totp.UserID, &totp.Secret, &totp.EnabledAt, &totp.LastStep, &totp.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка при получении настроек TOTP: %w", err)
+	}
+
+	return totp, nil
+}
+
+// SaveTOTP создает или заменяет настройки TOTP пользователя
+func (r *PostgresRepository) SaveTOTP(ctx context.Context, totp *models.TOTP) error {
+	query := `
+		INSERT INTO user_totp (user_id, secret, enabled_at, last_step, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, enabled_at = EXCLUDED.enabled_at,
+		    last_step = EXCLUDED.last_step, created_at = EXCLUDED.created_at
+	`
+
+	var enabledAt sql.NullTime
+	if totp.EnabledAt != nil {
+		enabledAt = sql.NullTime{Time: totp.EnabledAt.UTC(), Valid: true}
+	}
+
+	_, err := r.db.ExecContext(ctx, query, totp.UserID, totp.Secret, enabledAt, totp.LastStep, totp.CreatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("ошибка при сохранении настроек TOTP: %w", err)
+	}
+	return nil
+}
+
+// AdvanceTOTPStep запоминает шаг принятого кода. Проверка и обновление выполняются одним
+// запросом, поэтому параллельные запросы с одним кодом не пройдут оба.
+func (r *PostgresRepository) AdvanceTOTPStep(ctx context.Context, userID uint, step int64) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE user_totp SET last_step = $2 WHERE user_id = $1 AND last_step < $2`, userID, step)
+	if err != nil {
+		return fmt.Errorf("ошибка при сохранении шага TOTP: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+// DeleteTOTP удаляет настройки TOTP и коды восстановления пользователя
+func (r *PostgresRepository) DeleteTOTP(ctx context.Context, userID uint) error {
+	tx, err := r.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM totp_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("ошибка при удалении кодов восстановления: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("ошибка при удалении настроек TOTP: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// ReplaceRecoveryCodes заменяет коды восстановления пользователя в одной транзакции
+func (r *PostgresRepository) ReplaceRecoveryCodes(ctx context.Context, userID uint, codeHashes []string) error {
+	tx, err := r.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM totp_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("ошибка при удалении кодов восстановления: %w", err)
+	}
+	for _, codeHash := range codeHashes {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO totp_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, codeHash)
+		if err != nil {
+			return fmt.Errorf("ошибка при сохранении кода восстановления: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UseRecoveryCode отмечает код восстановления использованным одним запросом,
+// поэтому код нельзя использовать дважды
+func (r *PostgresRepository) UseRecoveryCode(ctx context.Context, userID uint, codeHash string, now time.Time) (bool, error) {
+	query := `
+		UPDATE totp_recovery_codes
+		SET used_at = $3
+		WHERE id = (
+			SELECT id FROM totp_recovery_codes
+			WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+			LIMIT 1
+			FOR UPDATE
+		)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, userID, codeHash, now.UTC())
+	if err != nil {
+		return false, fmt.Errorf("ошибка при использовании кода восстановления: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// UseLoginChallenge запоминает идентификатор промежуточного токена входа; повторная
+// вставка того же идентификатора ничего не меняет, поэтому токен срабатывает один раз
+func (r *PostgresRepository) UseLoginChallenge(ctx context.Context, challengeID string, userID uint, expiresAt time.Time) (bool, error) {
+	query := `
+		INSERT INTO used_login_challenges (challenge_id, user_id, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`
+
+	result, err := r.db.ExecContext(ctx, query, challengeID, userID, expiresAt.UTC())
+	if err != nil {
+		return false, fmt.Errorf("ошибка при использовании токена входа: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// DeleteExpiredLoginChallenges удаляет отметки о промежуточных токенах входа с истекшим сроком
+func (r *PostgresRepository) DeleteExpiredLoginChallenges(ctx context.Context, now time.Time) error {
+	query := `DELETE FROM used_login_challenges WHERE expires_at < $1`
+
+	if _, err := r.db.ExecContext(ctx, query, now.UTC()); err != nil {
+		return fmt.Errorf("ошибка при удалении истекших токенов входа: %w", err)
+	}
+	return nil
+}
+
+// CountRecoveryCodes возвращает количество неиспользованных кодов восстановления
+func (r *PostgresRepository) CountRecoveryCodes(ctx context.Context, userID uint) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM totp_recovery_codes WHERE user_id = $1 AND used_at IS NULL`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при подсчете кодов восстановления: %w", err)
+	}
+	return count, nil
+}
+
+// ImportPlannedBlocks создает запланированные интервалы в одной транзакции
+func (r *PostgresRepository) ImportPlannedBlocks(ctx context.Context, blocks []*models.PlannedBlock) error {
+	tx, err := r.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	for _, block := range blocks {
+		if block.CategoryID == nil && block.Category != nil {
+			categoryID := block.Category.ID
+			block.CategoryID = &categoryID
+		}
+		block.CreatedAt = now
+
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO planned_blocks (user_id, start_time, end_time, category_id, description, source_uid, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING id
+		`, block.UserID, block.StartTime.UTC(), block.EndTime.UTC(), nullUint(block.CategoryID),
+			block.Description, block.SourceUID, block.CreatedAt).Scan(&block.ID)
+		if err != nil {
+			return fmt.Errorf("ошибка при создании запланированного интервала: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// plannedBlockColumns - колонки запланированного интервала с категорией для scanPlannedBlock
+const plannedBlockColumns = `
+	pb.id, pb.user_id, pb.start_time, pb.end_time, pb.category_id, pb.description, pb.source_uid, pb.created_at,
+	c.name, c.color
+`
+
+// scanPlannedBlock сканирует строку, выбранную по plannedBlockColumns
+func scanPlannedBlock(row interface{ Scan(...interface{}) error }) (*models.PlannedBlock, error) {
+	block := &models.PlannedBlock{}
+	var categoryID sql.NullInt64
+	var categoryName, categoryColor sql.NullString
+
+	err := row.Scan(
+		&block.ID,
+		&block.UserID,
+		&block.StartTime,
+		&block.EndTime,
+		&categoryID,
+		&block.Description,
+		&block.SourceUID,
+		&block.CreatedAt,
+		&categoryName,
+		&categoryColor,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	block.CategoryID = uintPtr(categoryID)
+	if block.CategoryID != nil {
+		block.Category = &models.Category{
+			ID:     *block.CategoryID,
+			UserID: block.UserID,
+			Name:   categoryName.String,
+			Color:  categoryColor.String,
+		}
+	}
+
+	return block, nil
+}
+
+// ListPlannedBlocks получает запланированные интервалы пользователя, пересекающиеся с [from, to)
+func (r *PostgresRepository) ListPlannedBlocks(ctx context.Context, userID uint, from, to time.Time) ([]*models.PlannedBlock, error) {
+	query := `
+		SELECT ` + plannedBlockColumns + `
+		FROM planned_blocks pb
+		LEFT JOIN categories c ON pb.category_id = c.id
+		WHERE pb.user_id = $1 AND pb.end_time > $2 AND pb.start_time < $3
+		ORDER BY pb.start_time ASC, pb.id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении запланированных интервалов: %w", err)
+	}
+	defer rows.Close()
+
+	blocks := []*models.PlannedBlock{}
+	for rows.Next() {
+		block, err := scanPlannedBlock(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании запланированного интервала: %w", err)
+		}
+		blocks = append(blocks, block)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при обработке результатов: %w", err)
+	}
+
+	return blocks, nil
+}
+
+// GetPlannedBlockByID получает запланированный интервал по ID
+func (r *PostgresRepository) GetPlannedBlockByID(ctx context.Context, id uint) (*models.PlannedBlock, error) {
+	query := `
+		SELECT ` + plannedBlockColumns + `
+		FROM planned_blocks pb
+		LEFT JOIN categories c ON pb.category_id = c.id
+		WHERE pb.id = $1
+	`
+
+	block, err := scanPlannedBlock(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("запланированный интервал с id=%d не найден", id)
+		}
+		return nil, fmt.Errorf("ошибка при получении запланированного интервала: %w", err)
+	}
+
+	return block, nil
+}
+
+// DeletePlannedBlock удаляет запланированный интервал
+func (r *PostgresRepository) DeletePlannedBlock(ctx context.Context, id uint) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM planned_blocks WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("ошибка при удалении запланированного интервала: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка при получении количества удаленных строк: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("запланированный интервал с id=%d не найден", id)
 	}
 
 	return nil
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/internal/models"
+)
+
+// recordingDriver запоминает параметры запросов вместо обращения к базе
+type recordingDriver struct {
+	mu   sync.Mutex
+	args [][]driver.Value
+}
+
+func (d *recordingDriver) Open(name string) (driver.Conn, error) {
+	return recordingConn{d}, nil
+}
+
+func (d *recordingDriver) Connect(ctx context.Context) (driver.Conn, error) {
+	return recordingConn{d}, nil
+}
+
+func (d *recordingDriver) Driver() driver.Driver {
+	return d
+}
+
+func (d *recordingDriver) record(args []driver.Value) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.args = append(d.args, args)
+}
+
+type recordingConn struct {
+	d *recordingDriver
+}
+
+func (c recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return recordingStmt{c.d}, nil
+}
+
+func (c recordingConn) Close() error {
+	return nil
+}
+
+func (c recordingConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("транзакции не поддерживаются")
+}
+
+type recordingStmt struct {
+	d *recordingDriver
+}
+
+func (s recordingStmt) Close() error {
+	return nil
+}
+
+func (s recordingStmt) NumInput() int {
+	return -1
+}
+
+func (s recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.record(args)
+	return driver.RowsAffected(1), nil
+}
+
+// Query запоминает параметры и возвращает ошибку: тесту нужны только параметры
+func (s recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.record(args)
+	return nil, errors.New("выборка не поддерживается")
+}
+
+// newRecordingRepository создает репозиторий, запоминающий параметры запросов
+func newRecordingRepository(t *testing.T) (*PostgresRepository, *recordingDriver) {
+	t.Helper()
+	d := &recordingDriver{}
+	db := sql.OpenDB(d)
+	t.Cleanup(func() { db.Close() })
+	return &PostgresRepository{db: db, conn: db}, d
+}
+
+// TestTimestampsWrittenInUTC проверяет, что время пишется в колонки TIMESTAMP в UTC
+// независимо от часового пояса сервера
+func TestTimestampsWrittenInUTC(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("UTC+3", 3*60*60)
+	t.Cleanup(func() { time.Local = local })
+
+	repo, d := newRecordingRepository(t)
+	ctx := context.Background()
+	start := time.Date(2025, 3, 10, 9, 0, 0, 0, time.Local)
+
+	repo.UpdateUser(ctx, &models.User{ID: 1, Email: "test@example.com"})
+	repo.CreateTimeEntry(ctx, &models.TimeEntry{
+		UserID:    1,
+		StartTime: start,
+		EndTime:   start.Add(time.Hour),
+		Status:    models.StatusCompleted,
+	})
+	repo.UpdateTimeEntry(ctx, &models.TimeEntry{
+		ID:        1,
+		UserID:    1,
+		StartTime: start,
+		PausedAt:  start.Add(time.Hour),
+		ResumedAt: start.Add(2 * time.Hour),
+		Status:    models.StatusActive,
+	})
+	repo.CreatePause(ctx, &models.Pause{TimeEntryID: 1, StartTime: start, EndTime: start.Add(time.Minute)})
+	repo.UpdatePause(ctx, &models.Pause{ID: 1, StartTime: start, EndTime: start.Add(time.Minute)})
+	repo.ListTimeEntries(ctx, TimeEntryFilter{UserID: 1, From: start, To: start.Add(24 * time.Hour)})
+
+	times := 0
+	for _, args := range d.args {
+		for _, arg := range args {
+			if tm, ok := arg.(time.Time); ok {
+				times++
+				if tm.Location() != time.UTC {
+					t.Errorf("время %v передано не в UTC", tm)
+				}
+			}
+		}
+	}
+	if times < 12 {
+		t.Errorf("передано %d значений времени, ожидалось не меньше 12", times)
+	}
+}
@@ -0,0 +1,137 @@
+package events
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/internal/models"
+)
+
+// Типы событий
+const (
+	EntryStarted    = "started"          // начата новая запись
+	EntryCreated    = "created"          // вручную добавлена завершенная запись
+	EntryPaused     = "paused"           // запись приостановлена
+	EntryResumed    = "resumed"          // работа возобновлена
+	EntryStopped    = "stopped"          // запись завершена
+	EntryDeleted    = "deleted"          // запись удалена
+	EntryUpdated    = "updated"          // изменены время, категория, проект, описание или метки записи
+	CategoryUpdated = "category_updated" // изменены название, цвет или ставка категории
+	CategoryDeleted = "category_deleted" // категория удалена
+	Resync          = "resync"           // события могли быть потеряны, клиенту нужно перечитать данные
+)
+
+// SubscriberBuffer - сколько событий может ожидать доставки одному подписчику.
+// События для подписчика, который не успевает их читать, отбрасываются.
+const SubscriberBuffer = 16
+
+// Event представляет изменение данных пользователя, о котором сообщается его клиентам
+type Event struct {
+	Type       string            `json:"type"`
+	UserID     uint              `json:"user_id"`
+	EntryID    uint              `json:"entry_id,omitempty"`
+	CategoryID uint              `json:"category_id,omitempty"`
+	Entry      *models.TimeEntry `json:"entry,omitempty"`    // состояние записи после изменения
+	Category   *models.Category  `json:"category,omitempty"` // состояние категории после изменения
+	Time       time.Time         `json:"time"`
+}
+
+// Publisher публикует события; сервисы сообщают через него об изменениях
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// Bus доставляет опубликованные события подписчикам. Hub доставляет события внутри процесса;
+// для нескольких экземпляров сервера нужна реализация, пересылающая события между ними.
+type Bus interface {
+	Publisher
+	// Subscribe подписывает на события пользователя. Канал закрывается после вызова
+	// функции отмены, которую нужно вызвать, когда подписчик больше не читает события.
+	Subscribe(userID uint) (<-chan Event, func())
+}
+
+// Hub - шина событий внутри одного процесса
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[uint]map[chan Event]struct{}
+}
+
+// NewHub создает новую шину событий внутри процесса
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[uint]map[chan Event]struct{}),
+	}
+}
+
+// Publish доставляет событие подписчикам пользователя
+func (h *Hub) Publish(ctx context.Context, event Event) error {
+	h.Dispatch(event)
+	return nil
+}
+
+// Dispatch доставляет событие подписчикам пользователя в этом процессе, не дожидаясь их.
+// Реализации Bus для нескольких экземпляров вызывают его для событий, полученных от других экземпляров.
+func (h *Hub) Dispatch(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subscribers[event.UserID] {
+		select {
+		case ch <- event:
+		default:
+			log.Printf("Hub.Dispatch: Подписчик пользователя %d не успевает читать события, событие %s отброшено",
+				event.UserID, event.Type)
+		}
+	}
+}
+
+// Broadcast доставляет событие всем подписчикам в этом процессе; UserID события
+// заменяется пользователем подписчика
+func (h *Hub) Broadcast(event Event) {
+	h.mu.Lock()
+	users := make([]uint, 0, len(h.subscribers))
+	for userID := range h.subscribers {
+		users = append(users, userID)
+	}
+	h.mu.Unlock()
+
+	for _, userID := range users {
+		event.UserID = userID
+		h.Dispatch(event)
+	}
+}
+
+// Subscribe подписывает на события пользователя
+func (h *Hub) Subscribe(userID uint) (<-chan Event, func()) {
+	ch := make(chan Event, SubscriberBuffer)
+
+	h.mu.Lock()
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[chan Event]struct{})
+	}
+	h.subscribers[userID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			delete(h.subscribers[userID], ch)
+			if len(h.subscribers[userID]) == 0 {
+				delete(h.subscribers, userID)
+			}
+			close(ch)
+		})
+	}
+	return ch, cancel
+}
+
+// Subscribers возвращает количество подписчиков пользователя
+func (h *Hub) Subscribers(userID uint) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers[userID])
+}
@@ -0,0 +1,47 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHub(t *testing.T) {
+	ctx := context.Background()
+	hub := NewHub()
+
+	first, cancelFirst := hub.Subscribe(1)
+	second, cancelSecond := hub.Subscribe(1)
+	other, cancelOther := hub.Subscribe(2)
+	defer cancelOther()
+	assert.Equal(t, 2, hub.Subscribers(1))
+
+	assert.NoError(t, hub.Publish(ctx, Event{Type: EntryStarted, UserID: 1, EntryID: 10}))
+
+	// Событие получают все подписчики пользователя и только они
+	for _, ch := range []<-chan Event{first, second} {
+		event := <-ch
+		assert.Equal(t, EntryStarted, event.Type)
+		assert.Equal(t, uint(10), event.EntryID)
+	}
+	assert.Len(t, other, 0)
+
+	// После отмены канал закрыт, повторная отмена безопасна
+	cancelFirst()
+	cancelFirst()
+	_, ok := <-first
+	assert.False(t, ok)
+	assert.Equal(t, 1, hub.Subscribers(1))
+
+	t.Run("SlowSubscriber", func(t *testing.T) {
+		for i := 0; i < SubscriberBuffer+5; i++ {
+			hub.Dispatch(Event{Type: EntryUpdated, UserID: 1})
+		}
+		assert.Len(t, second, SubscriberBuffer, "лишние события отбрасываются, публикация не блокируется")
+	})
+
+	cancelSecond()
+	assert.Equal(t, 0, hub.Subscribers(1))
+	assert.NoError(t, hub.Publish(ctx, Event{Type: EntryStopped, UserID: 1}))
+}
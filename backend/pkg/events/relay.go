@@ -0,0 +1,63 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/pkg/database"
+)
+
+// RelayNotifications передает подписчикам hub изменения, сделанные другими экземплярами сервера,
+// пока не будет отменен ctx. Уведомления с идентификатором origin пропускаются: о своих изменениях
+// сервисы этого экземпляра сообщают hub сами, вместе с состоянием записи или категории.
+func RelayNotifications(ctx context.Context, source database.Notifications, hub *Hub, origin string) {
+	notifications, cancel := source.Subscribe()
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n, ok := <-notifications:
+			if !ok {
+				return
+			}
+			if n.Action == database.ActionResync {
+				hub.Broadcast(Event{Type: Resync, Time: time.Now()})
+				continue
+			}
+			if n.Origin == origin {
+				continue
+			}
+			if event, ok := eventFromNotification(n); ok {
+				hub.Dispatch(event)
+			}
+		}
+	}
+}
+
+// eventFromNotification преобразует уведомление другого экземпляра в событие. Уведомление
+// не содержит состояния сущности и вида перехода записи (начало, пауза, завершение),
+// поэтому изменения записи передаются как EntryUpdated, и клиент перечитывает запись сам.
+func eventFromNotification(n database.Notification) (Event, bool) {
+	event := Event{UserID: n.UserID, Time: time.Now()}
+
+	switch n.Entity {
+	case database.EntityTimeEntry:
+		event.EntryID = n.EntityID
+		event.Type = EntryUpdated
+		if n.Action == database.ActionDeleted {
+			event.Type = EntryDeleted
+		}
+	case database.EntityCategory:
+		event.CategoryID = n.EntityID
+		event.Type = CategoryUpdated
+		if n.Action == database.ActionDeleted {
+			event.Type = CategoryDeleted
+		}
+	default:
+		return Event{}, false
+	}
+
+	return event, true
+}
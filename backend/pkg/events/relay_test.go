@@ -0,0 +1,64 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/pkg/database"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeNotifications - источник уведомлений для тестов
+type fakeNotifications struct {
+	ch chan database.Notification
+}
+
+// Subscribe мок метода
+func (f *fakeNotifications) Subscribe() (<-chan database.Notification, func()) {
+	return f.ch, func() {}
+}
+
+func TestRelayNotifications(t *testing.T) {
+	source := &fakeNotifications{ch: make(chan database.Notification)}
+	hub := NewHub()
+	received, cancel := hub.Subscribe(1)
+	defer cancel()
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go RelayNotifications(ctx, source, hub, "local")
+
+	receive := func(t *testing.T) Event {
+		t.Helper()
+		select {
+		case event := <-received:
+			return event
+		case <-time.After(time.Second):
+			t.Fatal("событие не получено")
+			return Event{}
+		}
+	}
+
+	// Свои изменения пропускаются, изменения других экземпляров передаются
+	source.ch <- database.Notification{Entity: database.EntityTimeEntry, Action: database.ActionUpdated, UserID: 1, EntityID: 5, Origin: "local"}
+	source.ch <- database.Notification{Entity: database.EntityTimeEntry, Action: database.ActionCreated, UserID: 1, EntityID: 6, Origin: "remote"}
+	event := receive(t)
+	assert.Equal(t, EntryUpdated, event.Type)
+	assert.Equal(t, uint(6), event.EntryID)
+	assert.Nil(t, event.Entry)
+
+	source.ch <- database.Notification{Entity: database.EntityTimeEntry, Action: database.ActionDeleted, UserID: 1, EntityID: 6, Origin: "remote"}
+	assert.Equal(t, EntryDeleted, receive(t).Type)
+
+	source.ch <- database.Notification{Entity: database.EntityCategory, Action: database.ActionDeleted, UserID: 1, EntityID: 3, Origin: "remote"}
+	event = receive(t)
+	assert.Equal(t, CategoryDeleted, event.Type)
+	assert.Equal(t, uint(3), event.CategoryID)
+
+	// После восстановления соединения все клиенты перечитывают данные
+	source.ch <- database.Notification{Action: database.ActionResync}
+	event = receive(t)
+	assert.Equal(t, Resync, event.Type)
+	assert.Equal(t, uint(1), event.UserID)
+}
@@ -0,0 +1,196 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/pkg/database"
+	"github.com/graywrk/timetracker/backend/pkg/statistics"
+)
+
+// pageSize - количество записей, загружаемых из базы данных за один запрос при выгрузке
+const pageSize = 500
+
+// Service выгружает записи и отчеты в файлы
+type Service struct {
+	repo  database.Repository
+	stats *statistics.Service
+}
+
+// NewService создает новый сервис выгрузки
+func NewService(repo database.Repository) *Service {
+	return &Service{
+		repo:  repo,
+		stats: statistics.NewService(repo),
+	}
+}
+
+// ExportEntries выгружает завершенные записи, пересекающиеся с периодом, в порядке начала.
+// Записи читаются из базы данных страницами по pageSize и сразу передаются в w,
+// поэтому выгрузка за несколько лет не загружает все записи в память.
+// Время в файле указывается в часовом поясе периода.
+func (s *Service) ExportEntries(ctx context.Context, userID uint, period *statistics.Period, w RowWriter) error {
+	projects, err := s.repo.GetProjectsByUserID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("ошибка при получении проектов: %w", err)
+	}
+	projectNames := make(map[uint]string, len(projects))
+	for _, project := range projects {
+		projectNames[project.ID] = project.Name
+	}
+
+	filter := database.TimeEntryFilter{
+		UserID:   userID,
+		From:     period.From.UTC(),
+		To:       period.To.UTC(),
+		Statuses: []models.Status{models.StatusCompleted},
+		SortAsc:  true,
+		Limit:    pageSize,
+	}
+
+	total := 0
+	for page := 0; ; page++ {
+		entries, err := s.repo.ListTimeEntries(ctx, filter)
+		if err != nil {
+			return fmt.Errorf("ошибка при получении записей: %w", err)
+		}
+
+		// Заголовок пишется после первого запроса, чтобы ошибку базы данных можно было вернуть клиенту
+		if page == 0 {
+			err := w.WriteRow(
+				Text("Дата"), Text("Начало"), Text("Окончание"), Text("Категория"), Text("Проект"),
+				Text("Описание"), Text("Метки"), Text("Длительность"), Text("Часы"),
+				Text("Перерывы, ч"), Text("Количество перерывов"), Text("Оплачиваемая"),
+				Text("Завершена автоматически"),
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, entry := range entries {
+			if err := w.WriteRow(entryRow(entry, projectNames, period)...); err != nil {
+				return err
+			}
+		}
+		total += len(entries)
+
+		if len(entries) < pageSize {
+			break
+		}
+		last := entries[len(entries)-1]
+		filter.After = &database.TimeEntryCursor{StartTime: last.StartTime, ID: last.ID}
+
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Service.ExportEntries: Выгружено %d записей пользователя %d", total, userID)
+	return w.Close()
+}
+
+// entryRow формирует строку выгрузки для записи
+func entryRow(entry *models.TimeEntry, projectNames map[uint]string, period *statistics.Period) []Cell {
+	start := entry.StartTime.In(period.Location)
+	end := entry.EndTime.In(period.Location)
+
+	category := statistics.UncategorizedName
+	if entry.Category != nil {
+		category = entry.Category.Name
+	}
+	project := ""
+	if entry.ProjectID != nil {
+		project = projectNames[*entry.ProjectID]
+	}
+	tags := make([]string, 0, len(entry.Tags))
+	for _, tag := range entry.Tags {
+		tags = append(tags, tag.Name)
+	}
+	billable := "нет"
+	if entry.Billable {
+		billable = "да"
+	}
+	autoStopped := "нет"
+	if entry.AutoStopped {
+		autoStopped = "да"
+	}
+
+	// Длительность считается так же, как CalculateDuration, но без отладочного логирования каждой записи
+	paused := entry.PausedDurationUntil(entry.EndTime)
+	duration := int64(entry.EndTime.Sub(entry.StartTime).Seconds()) - paused
+
+	return []Cell{
+		Text(start.Format("2006-01-02")),
+		Text(start.Format("2006-01-02 15:04:05")),
+		Text(end.Format("2006-01-02 15:04:05")),
+		Text(category),
+		Text(project),
+		Text(entry.Description),
+		Text(strings.Join(tags, ", ")),
+		Text(formatDuration(duration)),
+		Number(float64(duration)/3600, 2),
+		Number(float64(paused)/3600, 2),
+		Number(float64(len(entry.Pauses)), 0),
+		Text(billable),
+		Text(autoStopped),
+	}
+}
+
+// ExportReport выгружает отчет за период: время по категориям за каждый день и итоги за период.
+// Отчет рассчитывается в базе данных без загрузки записей.
+func (s *Service) ExportReport(ctx context.Context, userID uint, period *statistics.Period, w RowWriter) error {
+	breakdown, err := s.stats.GetCategoryBreakdown(ctx, userID, period, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка при расчете отчета: %w", err)
+	}
+
+	err = w.WriteRow(Text("Дата"), Text("Категория"), Text("Часы"), Text("Доля, %"), Text("Количество записей"))
+	if err != nil {
+		return err
+	}
+
+	days := make([]string, 0, len(breakdown.Daily))
+	for day := range breakdown.Daily {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+
+	for _, day := range days {
+		for _, stat := range breakdown.Daily[day] {
+			if err := w.WriteRow(reportRow(day, stat)...); err != nil {
+				return err
+			}
+		}
+	}
+	for _, stat := range breakdown.Total {
+		if err := w.WriteRow(reportRow("Итого", stat)...); err != nil {
+			return err
+		}
+	}
+
+	return w.Close()
+}
+
+// reportRow формирует строку отчета по категории
+func reportRow(day string, stat statistics.CategoryStat) []Cell {
+	return []Cell{
+		Text(day),
+		Text(stat.Name),
+		Number(float64(stat.Duration)/3600, 2),
+		Number(stat.Percent, 2),
+		Number(float64(stat.EntryCount), 0),
+	}
+}
+
+// formatDuration форматирует длительность в секундах как Ч:ММ:СС без ограничения в 24 часа
+func formatDuration(seconds int64) string {
+	if seconds < 0 {
+		seconds = 0
+	}
+	return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
@@ -0,0 +1,239 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/internal/testutil"
+	"github.com/graywrk/timetracker/backend/pkg/database"
+	"github.com/graywrk/timetracker/backend/pkg/statistics"
+)
+
+// MockRepository представляет мок репозитория для тестирования
+type MockRepository struct {
+	testutil.Repository
+
+	// entries - записи, упорядоченные по началу, которые постранично возвращает ListTimeEntries
+	entries  []*models.TimeEntry
+	projects []*models.Project
+	// categoryStats возвращается методом GetCategoryStatsByPeriod
+	categoryStats []database.CategoryDuration
+	// filters - условия всех вызовов ListTimeEntries
+	filters []database.TimeEntryFilter
+	user    *models.User
+	err     error
+}
+
+// Реализация методов интерфейса Repository
+
+// GetUserByID мок метода
+func (m *MockRepository) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	return m.user, m.err
+}
+
+// GetTimeEntriesByUserID мок метода
+func (m *MockRepository) GetTimeEntriesByUserID(ctx context.Context, userID uint) ([]*models.TimeEntry, error) {
+	return m.entries, m.err
+}
+
+// ListTimeEntries мок метода: возвращает страницу записей после курсора filter.After
+func (m *MockRepository) ListTimeEntries(ctx context.Context, filter database.TimeEntryFilter) ([]*models.TimeEntry, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.filters = append(m.filters, filter)
+
+	page := make([]*models.TimeEntry, 0)
+	for _, entry := range m.entries {
+		if filter.After != nil && !entry.StartTime.After(filter.After.StartTime) &&
+			!(entry.StartTime.Equal(filter.After.StartTime) && entry.ID > filter.After.ID) {
+			continue
+		}
+		if filter.Limit > 0 && len(page) == filter.Limit {
+			break
+		}
+		page = append(page, entry)
+	}
+	return page, nil
+}
+
+// GetCategoryStatsByPeriod мок метода
+func (m *MockRepository) GetCategoryStatsByPeriod(ctx context.Context, filter database.StatsFilter) ([]database.CategoryDuration, error) {
+	return m.categoryStats, m.err
+}
+
+// GetProjectsByUserID мок метода
+func (m *MockRepository) GetProjectsByUserID(ctx context.Context, userID uint) ([]*models.Project, error) {
+	return m.projects, m.err
+}
+
+// WithTx мок метода: fn выполняется без транзакции
+func (m *MockRepository) WithTx(ctx context.Context, fn func(repo database.Repository) error) error {
+	return fn(m)
+}
+
+// readCSV разбирает выгрузку в формате CSV с разделителем ";"
+func readCSV(t *testing.T, data []byte) [][]string {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte("\uFEFF")) {
+		t.Fatalf("CSV не начинается с BOM")
+	}
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\uFEFF"))))
+	reader.Comma = ';'
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("Ошибка разбора CSV: %v", err)
+	}
+	return records
+}
+
+// TestExportEntries проверяет постраничную выгрузку записей и содержимое строк
+func TestExportEntries(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatalf("Не удалось загрузить часовой пояс: %v", err)
+	}
+	period := &statistics.Period{
+		From:     time.Date(2025, 1, 1, 0, 0, 0, 0, moscow),
+		To:       time.Date(2025, 1, 8, 0, 0, 0, 0, moscow),
+		Location: moscow,
+	}
+
+	projectID := uint(7)
+	mockRepo := &MockRepository{projects: []*models.Project{{ID: projectID, UserID: 1, Name: "Сайт"}}}
+	start := time.Date(2025, 1, 1, 6, 0, 0, 0, time.UTC)
+	mockRepo.entries = append(mockRepo.entries, &models.TimeEntry{
+		ID:          1,
+		UserID:      1,
+		StartTime:   start,
+		EndTime:     start.Add(2 * time.Hour),
+		Status:      models.StatusCompleted,
+		Category:    &models.Category{ID: 3, Name: "Разработка"},
+		ProjectID:   &projectID,
+		Billable:    true,
+		Description: "=SUM(A1:A9); ревью",
+		Tags:        []models.Tag{{ID: 1, Name: "срочно"}, {ID: 2, Name: "клиент"}},
+		Pauses: []models.Pause{{
+			StartTime: start.Add(30 * time.Minute),
+			EndTime:   start.Add(45 * time.Minute),
+		}},
+	})
+	// Записей больше двух страниц, в том числе с одинаковым началом
+	for i := 2; i <= 2*pageSize+1; i++ {
+		entryStart := start.Add(time.Duration(i/2) * time.Minute)
+		mockRepo.entries = append(mockRepo.entries, &models.TimeEntry{
+			ID:        uint(i),
+			UserID:    1,
+			StartTime: entryStart,
+			EndTime:   entryStart.Add(time.Minute),
+			Status:    models.StatusCompleted,
+		})
+	}
+
+	opts, err := ParseOptions(FormatCSV, "", "ru", "")
+	if err != nil {
+		t.Fatalf("ParseOptions() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := NewService(mockRepo).ExportEntries(context.Background(), 1, period, NewWriter(&buf, opts, "Записи")); err != nil {
+		t.Fatalf("ExportEntries() error = %v", err)
+	}
+
+	records := readCSV(t, buf.Bytes())
+	if len(records) != len(mockRepo.entries)+1 {
+		t.Fatalf("Выгружено %d строк, хотели %d", len(records), len(mockRepo.entries)+1)
+	}
+	want := []string{
+		"2025-01-01", "2025-01-01 09:00:00", "2025-01-01 11:00:00", "Разработка", "Сайт",
+		"'=SUM(A1:A9); ревью", "срочно, клиент", "1:45:00", "1,75", "0,25", "1", "да", "нет",
+	}
+	for i, value := range want {
+		if records[1][i] != value {
+			t.Errorf("Столбец %q = %q, хотели %q", records[0][i], records[1][i], value)
+		}
+	}
+	if got := records[2][3]; got != statistics.UncategorizedName {
+		t.Errorf("Категория записи без категории = %q, хотели %q", got, statistics.UncategorizedName)
+	}
+	if len(mockRepo.filters) != 3 {
+		t.Fatalf("ListTimeEntries вызван %d раз, хотели 3", len(mockRepo.filters))
+	}
+	first := mockRepo.filters[0]
+	if first.Limit != pageSize || !first.SortAsc || first.After != nil {
+		t.Errorf("Первый запрос = %+v, хотели первую страницу по %d записей по возрастанию", first, pageSize)
+	}
+	if first.From.Location() != time.UTC || !first.From.Equal(period.From) || !first.To.Equal(period.To) {
+		t.Errorf("Границы периода = [%v, %v), хотели [%v, %v) в UTC", first.From, first.To, period.From, period.To)
+	}
+	if len(first.Statuses) != 1 || first.Statuses[0] != models.StatusCompleted {
+		t.Errorf("Statuses = %v, хотели только завершенные записи", first.Statuses)
+	}
+	if last := mockRepo.filters[2].After; last == nil || last.ID != uint(2*pageSize) {
+		t.Errorf("Курсор третьего запроса = %+v, хотели запись %d", last, 2*pageSize)
+	}
+}
+
+// TestExportEntries_Error проверяет, что при ошибке базы данных в поток ничего не пишется
+func TestExportEntries_Error(t *testing.T) {
+	mockRepo := &MockRepository{err: errors.New("соединение потеряно")}
+	period := &statistics.Period{From: time.Now().Add(-time.Hour), To: time.Now(), Location: time.UTC}
+
+	for _, format := range []string{FormatCSV, FormatXLSX} {
+		opts, _ := ParseOptions(format, "", "", "")
+		var buf bytes.Buffer
+		if err := NewService(mockRepo).ExportEntries(context.Background(), 1, period, NewWriter(&buf, opts, "Записи")); err == nil {
+			t.Errorf("ExportEntries(%s) без ошибки, хотели ошибку базы данных", format)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("ExportEntries(%s) записал %d байт до ошибки", format, buf.Len())
+		}
+	}
+}
+
+// TestExportReport проверяет выгрузку отчета по категориям: дни по порядку, затем итоги
+func TestExportReport(t *testing.T) {
+	categoryID := uint(3)
+	mockRepo := &MockRepository{
+		user: &models.User{ID: 1},
+		categoryStats: []database.CategoryDuration{
+			{Day: "2025-01-02", CategoryID: &categoryID, Name: "Разработка", Duration: 5400, EntryCount: 2, Percent: 100},
+			{Day: "2025-01-01", CategoryID: nil, Duration: 1800, EntryCount: 1, Percent: 100},
+			{Day: "", CategoryID: &categoryID, Name: "Разработка", Duration: 5400, EntryCount: 2, Percent: 75},
+			{Day: "", CategoryID: nil, Duration: 1800, EntryCount: 1, Percent: 25},
+		},
+	}
+	period := &statistics.Period{
+		From:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:       time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC),
+		Location: time.UTC,
+	}
+
+	opts, _ := ParseOptions(FormatCSV, ";", "", "")
+	var buf bytes.Buffer
+	if err := NewService(mockRepo).ExportReport(context.Background(), 1, period, NewWriter(&buf, opts, "Отчет")); err != nil {
+		t.Fatalf("ExportReport() error = %v", err)
+	}
+
+	records := readCSV(t, buf.Bytes())
+	want := [][]string{
+		{"Дата", "Категория", "Часы", "Доля, %", "Количество записей"},
+		{"2025-01-01", statistics.UncategorizedName, "0.50", "100.00", "1"},
+		{"2025-01-02", "Разработка", "1.50", "100.00", "2"},
+		{"Итого", "Разработка", "1.50", "75.00", "2"},
+		{"Итого", statistics.UncategorizedName, "0.50", "25.00", "1"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("Выгружено %d строк, хотели %d: %v", len(records), len(want), records)
+	}
+	for i := range want {
+		if strings.Join(records[i], ";") != strings.Join(want[i], ";") {
+			t.Errorf("Строка %d = %v, хотели %v", i, records[i], want[i])
+		}
+	}
+}
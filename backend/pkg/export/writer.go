@@ -0,0 +1,354 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Форматы выгрузки
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// Определение типовых ошибок
+var (
+	ErrInvalidFormat    = errors.New("неизвестный формат выгрузки: ожидается csv или xlsx")
+	ErrInvalidDelimiter = errors.New("некорректный разделитель: ожидается один символ или tab")
+	ErrInvalidDecimal   = errors.New("некорректный десятичный разделитель: ожидается . или ,")
+)
+
+// commaDecimalLanguages - языки, в которых дробная часть числа отделяется запятой
+var commaDecimalLanguages = map[string]bool{
+	"ru": true, "uk": true, "be": true, "kk": true, "de": true, "fr": true, "es": true,
+	"it": true, "pt": true, "nl": true, "pl": true, "cs": true, "sk": true, "sv": true,
+	"fi": true, "da": true, "nb": true, "no": true, "tr": true,
+}
+
+// Options определяет формат файла выгрузки
+type Options struct {
+	Format string
+	// Delimiter - разделитель полей CSV
+	Delimiter rune
+	// DecimalSeparator - разделитель дробной части чисел в CSV ("." или ",").
+	// В XLSX числа хранятся как числа, и их отображение определяет табличный редактор.
+	DecimalSeparator string
+}
+
+// ParseOptions разбирает параметры выгрузки. Пустые значения заменяются значениями по умолчанию:
+// формат csv, десятичный разделитель по языку locale (например, "ru" или "de-DE" - запятая),
+// разделитель полей ";" при десятичной запятой и "," в остальных случаях, как ожидает Excel.
+func ParseOptions(format, delimiter, locale, decimal string) (Options, error) {
+	opts := Options{Format: strings.ToLower(format), DecimalSeparator: "."}
+	if opts.Format == "" {
+		opts.Format = FormatCSV
+	}
+	if opts.Format != FormatCSV && opts.Format != FormatXLSX {
+		return opts, ErrInvalidFormat
+	}
+
+	language := strings.ToLower(locale)
+	if i := strings.IndexAny(language, "-_"); i >= 0 {
+		language = language[:i]
+	}
+	if commaDecimalLanguages[language] {
+		opts.DecimalSeparator = ","
+	}
+	switch decimal {
+	case "":
+	case ".", ",":
+		opts.DecimalSeparator = decimal
+	default:
+		return opts, ErrInvalidDecimal
+	}
+
+	switch {
+	case delimiter == "":
+		opts.Delimiter = ','
+		if opts.DecimalSeparator == "," {
+			opts.Delimiter = ';'
+		}
+	case strings.EqualFold(delimiter, "tab") || delimiter == "\t":
+		opts.Delimiter = '\t'
+	case utf8.RuneCountInString(delimiter) == 1:
+		opts.Delimiter, _ = utf8.DecodeRuneInString(delimiter)
+		if opts.Delimiter == '"' || opts.Delimiter == '\r' || opts.Delimiter == '\n' || opts.Delimiter == utf8.RuneError {
+			return opts, ErrInvalidDelimiter
+		}
+	default:
+		return opts, ErrInvalidDelimiter
+	}
+
+	return opts, nil
+}
+
+// ContentType возвращает MIME-тип файла выгрузки
+func (o Options) ContentType() string {
+	if o.Format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Extension возвращает расширение файла выгрузки
+func (o Options) Extension() string {
+	return o.Format
+}
+
+// Cell - значение ячейки: строка или число
+type Cell struct {
+	Text     string
+	Number   float64
+	IsNumber bool
+	// Precision - количество знаков после запятой числа в CSV
+	Precision int
+}
+
+// Text создает строковую ячейку
+func Text(value string) Cell {
+	return Cell{Text: value}
+}
+
+// Number создает числовую ячейку с precision знаками после запятой
+func Number(value float64, precision int) Cell {
+	return Cell{Number: value, IsNumber: true, Precision: precision}
+}
+
+// RowWriter построчно записывает таблицу в поток, не накапливая строки в памяти.
+// Ничего не пишется в поток до первой строки, поэтому ошибку, возникшую до начала
+// выгрузки, еще можно вернуть обычным ответом.
+type RowWriter interface {
+	WriteRow(cells ...Cell) error
+	// Flush передает записанные строки в поток
+	Flush() error
+	// Close завершает файл
+	Close() error
+}
+
+// NewWriter создает RowWriter для формата opts; sheet - название листа XLSX
+func NewWriter(w io.Writer, opts Options, sheet string) RowWriter {
+	if opts.Format == FormatXLSX {
+		return &xlsxWriter{out: w, sheet: sheet}
+	}
+	return &csvWriter{out: w, opts: opts}
+}
+
+// csvWriter записывает CSV в UTF-8 с BOM, чтобы Excel правильно определил кодировку
+type csvWriter struct {
+	out     io.Writer
+	opts    Options
+	w       *csv.Writer
+	started bool
+	record  []string
+}
+
+func (c *csvWriter) start() error {
+	if c.started {
+		return nil
+	}
+	c.started = true
+	if _, err := io.WriteString(c.out, "\uFEFF"); err != nil {
+		return err
+	}
+	c.w = csv.NewWriter(c.out)
+	c.w.Comma = c.opts.Delimiter
+	return nil
+}
+
+func (c *csvWriter) WriteRow(cells ...Cell) error {
+	if err := c.start(); err != nil {
+		return err
+	}
+
+	c.record = c.record[:0]
+	for _, cell := range cells {
+		if cell.IsNumber {
+			value := strconv.FormatFloat(cell.Number, 'f', cell.Precision, 64)
+			c.record = append(c.record, strings.Replace(value, ".", c.opts.DecimalSeparator, 1))
+		} else {
+			c.record = append(c.record, escapeFormula(cell.Text))
+		}
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter) Flush() error {
+	if !c.started {
+		return nil
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error {
+	if err := c.start(); err != nil {
+		return err
+	}
+	return c.Flush()
+}
+
+// escapeFormula защищает от выполнения текста как формулы при открытии CSV в табличном редакторе
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// Неизменяемые части книги XLSX с одним листом
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`</Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`</Relationships>`
+	xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<fonts count="1"><font><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/></cellXfs>` +
+		`</styleSheet>`
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+// maxSheetNameLength - ограничение Excel на длину названия листа
+const maxSheetNameLength = 31
+
+// xlsxWorkbook возвращает описание книги с листом sheet
+func xlsxWorkbook(sheet string) string {
+	if sheet == "" {
+		sheet = "Sheet1"
+	}
+	if runes := []rune(sheet); len(runes) > maxSheetNameLength {
+		sheet = string(runes[:maxSheetNameLength])
+	}
+	var name strings.Builder
+	xml.EscapeText(&name, []byte(sheet))
+
+	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="` + name.String() + `" sheetId="1" r:id="rId1"/></sheets></workbook>`
+}
+
+// xlsxWriter записывает книгу XLSX с одним листом. Строки листа сжимаются и пишутся в поток
+// по мере поступления, строки хранятся как встроенные (inlineStr), без общей таблицы строк.
+type xlsxWriter struct {
+	out   io.Writer
+	sheet string
+	zip   *zip.Writer
+	rows  *bufio.Writer
+	row   int
+}
+
+func (x *xlsxWriter) start() error {
+	if x.zip != nil {
+		return nil
+	}
+	x.zip = zip.NewWriter(x.out)
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook(x.sheet)},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, part := range parts {
+		f, err := x.zip.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return err
+		}
+	}
+
+	f, err := x.zip.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	x.rows = bufio.NewWriter(f)
+	_, err = x.rows.WriteString(xlsxSheetStart)
+	return err
+}
+
+func (x *xlsxWriter) WriteRow(cells ...Cell) error {
+	if err := x.start(); err != nil {
+		return err
+	}
+
+	x.row++
+	fmt.Fprintf(x.rows, `<row r="%d">`, x.row)
+	for i, cell := range cells {
+		ref := columnName(i) + strconv.Itoa(x.row)
+		if cell.IsNumber {
+			fmt.Fprintf(x.rows, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(cell.Number, 'f', -1, 64))
+			continue
+		}
+		if cell.Text == "" {
+			continue
+		}
+		fmt.Fprintf(x.rows, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+		xml.EscapeText(x.rows, []byte(cell.Text))
+		x.rows.WriteString(`</t></is></c>`)
+	}
+	_, err := x.rows.WriteString(`</row>`)
+	return err
+}
+
+func (x *xlsxWriter) Flush() error {
+	if x.zip == nil {
+		return nil
+	}
+	if err := x.rows.Flush(); err != nil {
+		return err
+	}
+	return x.zip.Flush()
+}
+
+func (x *xlsxWriter) Close() error {
+	if err := x.start(); err != nil {
+		return err
+	}
+	if _, err := x.rows.WriteString(xlsxSheetEnd); err != nil {
+		return err
+	}
+	if err := x.rows.Flush(); err != nil {
+		return err
+	}
+	return x.zip.Close()
+}
+
+// columnName возвращает буквенное обозначение столбца по номеру от нуля: A, B, ..., Z, AA, ...
+func columnName(index int) string {
+	name := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = string(rune('A'+(index-1)%26)) + name
+	}
+	return name
+}
@@ -0,0 +1,146 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+)
+
+// TestParseOptions проверяет значения по умолчанию и разбор параметров выгрузки
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		name                               string
+		format, delimiter, locale, decimal string
+		want                               Options
+		wantErr                            error
+	}{
+		{"по умолчанию", "", "", "", "", Options{Format: FormatCSV, Delimiter: ',', DecimalSeparator: "."}, nil},
+		{"русская локаль", "csv", "", "ru-RU", "", Options{Format: FormatCSV, Delimiter: ';', DecimalSeparator: ","}, nil},
+		{"немецкая локаль с табуляцией", "CSV", "tab", "de_DE", "", Options{Format: FormatCSV, Delimiter: '\t', DecimalSeparator: ","}, nil},
+		{"явный десятичный разделитель", "xlsx", "|", "ru", ".", Options{Format: FormatXLSX, Delimiter: '|', DecimalSeparator: "."}, nil},
+		{"неизвестный формат", "pdf", "", "", "", Options{}, ErrInvalidFormat},
+		{"длинный разделитель", "csv", ";;", "", "", Options{}, ErrInvalidDelimiter},
+		{"кавычка как разделитель", "csv", `"`, "", "", Options{}, ErrInvalidDelimiter},
+		{"неизвестный десятичный разделитель", "csv", "", "", "·", Options{}, ErrInvalidDecimal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOptions(tt.format, tt.delimiter, tt.locale, tt.decimal)
+			if err != tt.wantErr {
+				t.Fatalf("ParseOptions() error = %v, хотели %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("ParseOptions() = %+v, хотели %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCSVWriter проверяет десятичный разделитель и защиту от формул
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, Options{Format: FormatCSV, Delimiter: ';', DecimalSeparator: ","}, "")
+	if err := w.WriteRow(Text("@cmd"), Text("-"), Number(1.25, 2), Number(12, 0), Text("a;b")); err != nil {
+		t.Fatalf("WriteRow() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	want := "\uFEFF'@cmd;'-;1,25;12;\"a;b\"\n"
+	if buf.String() != want {
+		t.Errorf("CSV = %q, хотели %q", buf.String(), want)
+	}
+}
+
+// xlsxCell - ячейка листа XLSX для проверки содержимого
+type xlsxCell struct {
+	Ref    string `xml:"r,attr"`
+	Type   string `xml:"t,attr"`
+	Value  string `xml:"v"`
+	Inline string `xml:"is>t"`
+}
+
+// TestXLSXWriter проверяет структуру книги и содержимое листа
+func TestXLSXWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, Options{Format: FormatXLSX}, "Записи <2025>")
+	if err := w.WriteRow(Text("Описание"), Text(""), Text("Часы")); err != nil {
+		t.Fatalf("WriteRow() error = %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if err := w.WriteRow(Text("<b>ревью</b> & \"тесты\"\x01"), Text(""), Number(1.75, 2)); err != nil {
+		t.Fatalf("WriteRow() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Файл не является zip-архивом: %v", err)
+	}
+	parts := make(map[string][]byte)
+	for _, f := range archive.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("Ошибка чтения %s: %v", f.Name, err)
+		}
+		parts[f.Name], _ = io.ReadAll(r)
+		r.Close()
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+		if err := xml.Unmarshal(parts[name], new(struct{})); err != nil {
+			t.Errorf("Часть %s некорректна: %v", name, err)
+		}
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal(parts["xl/workbook.xml"], &workbook); err != nil || len(workbook.Sheets) != 1 || workbook.Sheets[0].Name != "Записи <2025>" {
+		t.Errorf("Листы книги = %+v (%v), хотели один лист \"Записи <2025>\"", workbook.Sheets, err)
+	}
+
+	var sheet struct {
+		Rows []struct {
+			Ref   string     `xml:"r,attr"`
+			Cells []xlsxCell `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(parts["xl/worksheets/sheet1.xml"], &sheet); err != nil {
+		t.Fatalf("Лист некорректен: %v", err)
+	}
+	if len(sheet.Rows) != 2 || sheet.Rows[1].Ref != "2" {
+		t.Fatalf("Строки листа = %+v, хотели 2 строки", sheet.Rows)
+	}
+	cells := sheet.Rows[1].Cells
+	want := []xlsxCell{
+		{Ref: "A2", Type: "inlineStr", Inline: "<b>ревью</b> & \"тесты\"\uFFFD"},
+		{Ref: "C2", Value: "1.75"},
+	}
+	if len(cells) != len(want) {
+		t.Fatalf("Ячейки второй строки = %+v, хотели %+v", cells, want)
+	}
+	for i := range want {
+		if cells[i] != want[i] {
+			t.Errorf("Ячейка %d = %+v, хотели %+v", i, cells[i], want[i])
+		}
+	}
+}
+
+// TestColumnName проверяет буквенные обозначения столбцов
+func TestColumnName(t *testing.T) {
+	for index, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(index); got != want {
+			t.Errorf("columnName(%d) = %q, хотели %q", index, got, want)
+		}
+	}
+}
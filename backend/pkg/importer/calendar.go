@@ -0,0 +1,269 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/pkg/calendar"
+	"github.com/graywrk/timetracker/backend/pkg/statistics"
+)
+
+// Цели импорта календаря
+const (
+	TargetPlanned = "planned" // запланированное время
+	TargetEntries = "entries" // завершенные записи о времени
+)
+
+// MaxCalendarDays - наибольшая длина периода импорта календаря в днях
+const MaxCalendarDays = 366
+
+// Ошибки импорта календаря
+var (
+	ErrInvalidTarget = errors.New("неизвестная цель импорта календаря: ожидается planned или entries")
+	ErrRangeTooLong  = fmt.Errorf("период импорта календаря не может быть длиннее %d дней", MaxCalendarDays)
+)
+
+// CalendarOptions содержит параметры импорта календаря
+type CalendarOptions struct {
+	// Target - что создается из событий: TargetPlanned (по умолчанию) или TargetEntries
+	Target string
+	// Period - повторяющиеся события разворачиваются в экземпляры, начинающиеся в этом периоде;
+	// время без часового пояса в файле считается временем в Period.Location
+	Period *statistics.Period
+	// Exclude - ключи экземпляров (поле occurrence отчета) или UID событий, которые не импортируются
+	Exclude []string
+	// DryRun - только разобрать календарь и вернуть отчет для подтверждения, ничего не сохраняя
+	DryRun bool
+}
+
+// ImportCalendar разбирает файл iCalendar и создает из событий периода запланированные интервалы
+// или завершенные записи. Категория события определяется по CATEGORIES, а если там нет подходящей -
+// по названию события среди категорий пользователя; новые категории не создаются.
+// События на весь день, исключенные и (для записей) еще не закончившиеся экземпляры пропускаются.
+// Как и при импорте CSV, при ошибках не сохраняется ничего и возвращается ErrInvalidRows с отчетом.
+func (s *Service) ImportCalendar(ctx context.Context, userID uint, data io.Reader, opts CalendarOptions) (*Report, error) {
+	target := opts.Target
+	if target == "" {
+		target = TargetPlanned
+	}
+	if target != TargetPlanned && target != TargetEntries {
+		return nil, ErrInvalidTarget
+	}
+	period := opts.Period
+	if period.From.AddDate(0, 0, MaxCalendarDays).Before(period.To) {
+		return nil, ErrRangeTooLong
+	}
+
+	events, err := calendar.ParseEvents(data, period.Location)
+	if err != nil {
+		return nil, err
+	}
+	occurrences, err := calendar.Expand(events, period.From, period.To)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.GetCategoriesByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении категорий: %w", err)
+	}
+	byName := make(map[string]*models.Category, len(existing))
+	for _, category := range existing {
+		byName[strings.ToLower(category.Name)] = category
+	}
+
+	excluded := make(map[string]bool, len(opts.Exclude))
+	for _, key := range opts.Exclude {
+		excluded[strings.TrimSpace(key)] = true
+	}
+
+	var rows []*parsedRow
+	for _, event := range events {
+		if event.Err == nil {
+			continue
+		}
+		row := &parsedRow{report: &RowReport{Row: event.Line, Event: event.Summary, Occurrence: event.UID}}
+		if excluded[event.UID] {
+			row.report.Status = RowSkipped
+			row.report.Message = "событие исключено"
+		} else {
+			row.report.Status = RowError
+			row.report.Errors = []string{event.Err.Error()}
+		}
+		rows = append(rows, row)
+	}
+
+	now := time.Now()
+	for _, occurrence := range occurrences {
+		event := occurrence.Event
+		start, end := occurrence.Start, occurrence.End
+		row := &parsedRow{report: &RowReport{
+			Row:        event.Line,
+			Status:     RowOK,
+			StartTime:  &start,
+			EndTime:    &end,
+			Event:      event.Summary,
+			Occurrence: occurrence.Key(),
+		}}
+		rows = append(rows, row)
+
+		switch {
+		case excluded[occurrence.Key()] || excluded[event.UID]:
+			row.report.Message = "событие исключено"
+		case event.AllDay:
+			row.report.Message = "события на весь день не импортируются"
+		case !end.After(start):
+			row.report.Message = "событие без длительности"
+		case target == TargetEntries && end.After(now):
+			row.report.Message = "событие еще не закончилось"
+		}
+		if row.report.Message != "" {
+			row.report.Status = RowSkipped
+			continue
+		}
+
+		description := event.Summary
+		if description == "" {
+			description = event.Description
+		}
+		category := matchCategory(event, byName)
+		var categoryID *uint
+		if category != nil {
+			row.report.Category = category.Name
+			id := category.ID
+			categoryID = &id
+		}
+
+		if target == TargetEntries {
+			row.entry = &models.TimeEntry{
+				StartTime:   start,
+				EndTime:     end,
+				Status:      models.StatusCompleted,
+				CategoryID:  categoryID,
+				Billable:    true,
+				Description: description,
+			}
+		} else {
+			row.block = &models.PlannedBlock{
+				UserID:      userID,
+				StartTime:   start,
+				EndTime:     end,
+				CategoryID:  categoryID,
+				Description: description,
+				SourceUID:   event.UID,
+			}
+		}
+	}
+
+	if target == TargetEntries {
+		err = s.checkConflicts(ctx, userID, rows)
+	} else {
+		err = s.checkPlannedDuplicates(ctx, userID, period, rows)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{DryRun: opts.DryRun, Total: len(rows), CreatedCategories: []string{}, Rows: []RowReport{}}
+	var entries []*models.TimeEntry
+	var blocks []*models.PlannedBlock
+	for _, row := range rows {
+		switch row.report.Status {
+		case RowOK:
+			if row.entry != nil {
+				entries = append(entries, row.entry)
+			} else {
+				blocks = append(blocks, row.block)
+			}
+			report.Imported++
+		case RowDuplicate:
+			report.Duplicates++
+		case RowError:
+			report.Errors++
+		case RowSkipped:
+			report.Skipped++
+		}
+	}
+
+	if report.Errors > 0 && !opts.DryRun {
+		report.Imported = 0
+	}
+	if report.Errors == 0 && !opts.DryRun {
+		if len(entries) > 0 {
+			if err := s.repo.ImportTimeEntries(ctx, nil, entries); err != nil {
+				return nil, fmt.Errorf("ошибка при сохранении записей: %w", err)
+			}
+		}
+		if len(blocks) > 0 {
+			if err := s.repo.ImportPlannedBlocks(ctx, blocks); err != nil {
+				return nil, fmt.Errorf("ошибка при сохранении запланированного времени: %w", err)
+			}
+		}
+	}
+
+	for _, row := range rows {
+		if row.report.Status == RowOK && !opts.DryRun && report.Errors == 0 {
+			if row.entry != nil {
+				row.report.EntryID = row.entry.ID
+			} else {
+				row.report.BlockID = row.block.ID
+			}
+		}
+		report.Rows = append(report.Rows, *row.report)
+	}
+
+	log.Printf("Service.ImportCalendar: Пользователь %d, цель=%s, событий=%d, импортировано=%d, дубликатов=%d, пропущено=%d, ошибок=%d, проверка=%v",
+		userID, target, report.Total, report.Imported, report.Duplicates, report.Skipped, report.Errors, opts.DryRun)
+
+	if report.Errors > 0 && !opts.DryRun {
+		return report, ErrInvalidRows
+	}
+	return report, nil
+}
+
+// checkPlannedDuplicates отмечает как дубликаты экземпляры, уже импортированные из того же события:
+// интервал с тем же UID, началом и окончанием. Пересечения запланированных интервалов допустимы.
+func (s *Service) checkPlannedDuplicates(ctx context.Context, userID uint, period *statistics.Period, rows []*parsedRow) error {
+	existing, err := s.repo.ListPlannedBlocks(ctx, userID, period.From.UTC(), period.To.UTC())
+	if err != nil {
+		return fmt.Errorf("ошибка при получении запланированного времени: %w", err)
+	}
+
+	type key struct {
+		uid        string
+		start, end int64
+	}
+	known := make(map[key]uint, len(existing))
+	for _, block := range existing {
+		known[key{block.SourceUID, block.StartTime.Unix(), block.EndTime.Unix()}] = block.ID
+	}
+
+	for _, row := range rows {
+		if row.report.Status != RowOK || row.block == nil {
+			continue
+		}
+		if id, ok := known[key{row.block.SourceUID, row.block.StartTime.Unix(), row.block.EndTime.Unix()}]; ok {
+			row.report.Status = RowDuplicate
+			row.report.Message = fmt.Sprintf("уже импортировано как интервал #%d", id)
+			row.report.BlockID = id
+		}
+	}
+	return nil
+}
+
+// matchCategory сопоставляет событие с категорией пользователя по названию без учета регистра:
+// сначала по категориям события (CATEGORIES), затем по названию события
+func matchCategory(event *calendar.Event, byName map[string]*models.Category) *models.Category {
+	for _, name := range event.Categories {
+		if category, ok := byName[strings.ToLower(name)]; ok {
+			return category
+		}
+	}
+	return byName[strings.ToLower(strings.TrimSpace(event.Summary))]
+}
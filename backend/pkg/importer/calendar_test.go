@@ -0,0 +1,170 @@
+package importer
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/pkg/statistics"
+)
+
+// weeklyCalendar - еженедельная встреча по понедельникам, событие на весь день и отдельная встреча
+const weeklyCalendar = "BEGIN:VCALENDAR\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:sync@example.com\r\n" +
+	"SUMMARY:Синхронизация\r\n" +
+	"CATEGORIES:Встречи\r\n" +
+	"DTSTART:20240304T100000\r\n" +
+	"DTEND:20240304T110000\r\n" +
+	"RRULE:FREQ=WEEKLY;COUNT=3\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:holiday@example.com\r\n" +
+	"SUMMARY:Праздник\r\n" +
+	"DTSTART;VALUE=DATE:20240308\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:review@example.com\r\n" +
+	"SUMMARY:разработка\r\n" +
+	"DTSTART:20240305T140000Z\r\n" +
+	"DTEND:20240305T150000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+// marchPeriod возвращает март 2024 года по Москве
+func marchPeriod(t *testing.T) *statistics.Period {
+	t.Helper()
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatalf("Не удалось загрузить часовой пояс: %v", err)
+	}
+	return &statistics.Period{
+		From:     time.Date(2024, 3, 1, 0, 0, 0, 0, moscow),
+		To:       time.Date(2024, 4, 1, 0, 0, 0, 0, moscow),
+		Location: moscow,
+	}
+}
+
+// TestImportCalendar_Planned проверяет предварительный отчет, исключение экземпляра и сохранение интервалов
+func TestImportCalendar_Planned(t *testing.T) {
+	mockRepo := &MockRepository{
+		categories: []*models.Category{{ID: 3, UserID: 1, Name: "встречи"}, {ID: 4, UserID: 1, Name: "Разработка"}},
+		planned: []*models.PlannedBlock{{
+			ID:        50,
+			UserID:    1,
+			SourceUID: "review@example.com",
+			StartTime: time.Date(2024, 3, 5, 14, 0, 0, 0, time.UTC),
+			EndTime:   time.Date(2024, 3, 5, 15, 0, 0, 0, time.UTC),
+		}},
+		nextID: 100,
+	}
+	service := NewService(mockRepo)
+	opts := CalendarOptions{Period: marchPeriod(t), DryRun: true}
+
+	report, err := service.ImportCalendar(context.Background(), 1, strings.NewReader(weeklyCalendar), opts)
+	if err != nil {
+		t.Fatalf("ImportCalendar() error = %v", err)
+	}
+	if report.Total != 5 || report.Imported != 3 || report.Duplicates != 1 || report.Skipped != 1 || report.Errors != 0 {
+		t.Fatalf("Report = %+v, хотели 3 экземпляра встречи, дубликат и пропущенный праздник", report)
+	}
+	if len(mockRepo.importedBlocks) != 0 {
+		t.Fatalf("при проверке ничего не должно сохраняться")
+	}
+	first := report.Rows[0]
+	if first.Occurrence != "sync@example.com/20240304T070000Z" || first.Category != "встречи" {
+		t.Errorf("первый экземпляр %+v: хотели начало 10:00 по Москве и категорию из CATEGORIES", first)
+	}
+
+	// Подтверждение без второго экземпляра встречи
+	opts.DryRun = false
+	opts.Exclude = []string{"sync@example.com/20240311T070000Z"}
+	report, err = service.ImportCalendar(context.Background(), 1, strings.NewReader(weeklyCalendar), opts)
+	if err != nil {
+		t.Fatalf("ImportCalendar() error = %v", err)
+	}
+	if report.Imported != 2 || len(mockRepo.importedBlocks) != 2 {
+		t.Fatalf("сохранено %d интервалов, в отчете %d, хотели 2", len(mockRepo.importedBlocks), report.Imported)
+	}
+	block := mockRepo.importedBlocks[1]
+	if block.SourceUID != "sync@example.com" || block.CategoryID == nil || *block.CategoryID != 3 ||
+		block.Description != "Синхронизация" || !block.StartTime.Equal(time.Date(2024, 3, 18, 7, 0, 0, 0, time.UTC)) {
+		t.Errorf("интервал = %+v", block)
+	}
+	for _, row := range report.Rows {
+		if row.Status == RowOK && row.BlockID == 0 {
+			t.Errorf("строка %+v без block_id", row)
+		}
+	}
+}
+
+// TestImportCalendar_Entries проверяет создание записей: категория по названию, будущие события пропускаются
+func TestImportCalendar_Entries(t *testing.T) {
+	mockRepo := &MockRepository{
+		categories: []*models.Category{{ID: 4, UserID: 1, Name: "Разработка"}},
+		nextID:     100,
+	}
+	data := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nUID:past\r\nSUMMARY:Разработка\r\nDTSTART:20240305T140000Z\r\nDURATION:PT2H\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:future\r\nSUMMARY:Планы\r\nDTSTART:20990305T140000Z\r\nDURATION:PT2H\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	period := &statistics.Period{
+		From:     time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:       time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, MaxCalendarDays),
+		Location: time.UTC,
+	}
+
+	_, err := NewService(mockRepo).ImportCalendar(context.Background(), 1, strings.NewReader(data),
+		CalendarOptions{Target: TargetEntries, Period: &statistics.Period{From: period.From, To: period.To.AddDate(0, 0, 1), Location: time.UTC}})
+	if err != ErrRangeTooLong {
+		t.Fatalf("ImportCalendar() с длинным периодом error = %v, хотели %v", err, ErrRangeTooLong)
+	}
+
+	report, err := NewService(mockRepo).ImportCalendar(context.Background(), 1, strings.NewReader(data),
+		CalendarOptions{Target: TargetEntries, Period: period})
+	if err != nil {
+		t.Fatalf("ImportCalendar() error = %v", err)
+	}
+	if report.Imported != 1 || len(mockRepo.imported) != 1 {
+		t.Fatalf("Report = %+v, хотели одну запись", report)
+	}
+	entry := mockRepo.imported[0]
+	if entry.Status != models.StatusCompleted || entry.CategoryID == nil || *entry.CategoryID != 4 || entry.UserID != 1 ||
+		entry.EndTime.Sub(entry.StartTime) != 2*time.Hour {
+		t.Errorf("запись = %+v", entry)
+	}
+}
+
+// TestImportCalendar_Errors проверяет, что при ошибках события ничего не сохраняется
+func TestImportCalendar_Errors(t *testing.T) {
+	mockRepo := &MockRepository{}
+	data := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nUID:ok\r\nDTSTART:20240305T140000Z\r\nDURATION:PT1H\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:bad\r\nDTSTART:20240305T140000Z\r\nRRULE:FREQ=MINUTELY\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	opts := CalendarOptions{Period: marchPeriod(t)}
+
+	report, err := NewService(mockRepo).ImportCalendar(context.Background(), 1, strings.NewReader(data), opts)
+	if err != ErrInvalidRows {
+		t.Fatalf("ImportCalendar() error = %v, хотели %v", err, ErrInvalidRows)
+	}
+	if report.Errors != 1 || report.Imported != 0 || len(mockRepo.importedBlocks) != 0 {
+		t.Fatalf("Report = %+v, хотели одну ошибку и ничего не сохранять", report)
+	}
+
+	// Событие с ошибкой можно исключить по UID
+	opts.Exclude = []string{"bad"}
+	report, err = NewService(mockRepo).ImportCalendar(context.Background(), 1, strings.NewReader(data), opts)
+	if err != nil {
+		t.Fatalf("ImportCalendar() error = %v", err)
+	}
+	if report.Imported != 1 || report.Skipped != 1 || len(mockRepo.importedBlocks) != 1 {
+		t.Errorf("Report = %+v, хотели один интервал и одно исключенное событие", report)
+	}
+
+	if _, err := NewService(mockRepo).ImportCalendar(context.Background(), 1, strings.NewReader(data), CalendarOptions{Target: "past", Period: opts.Period}); err != ErrInvalidTarget {
+		t.Errorf("ImportCalendar() с неизвестной целью error = %v, хотели %v", err, ErrInvalidTarget)
+	}
+}
@@ -0,0 +1,252 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Источники файлов импорта
+const (
+	SourceCSV      = "csv"      // произвольный CSV с описанием колонок
+	SourceToggl    = "toggl"    // выгрузка Toggl Track (Reports -> Detailed -> CSV)
+	SourceClockify = "clockify" // выгрузка Clockify (Reports -> Detailed -> CSV)
+)
+
+// Mapping описывает, в каких колонках CSV находятся поля записи. Значения - названия колонок
+// из строки заголовка без учета регистра. Начало задается колонкой Start с датой и временем
+// или парой StartDate и StartTime; окончание - так же, либо длительностью в колонке Duration.
+type Mapping struct {
+	Start       string `json:"start"`
+	StartDate   string `json:"start_date"`
+	StartTime   string `json:"start_time"`
+	End         string `json:"end"`
+	EndDate     string `json:"end_date"`
+	EndTime     string `json:"end_time"`
+	Duration    string `json:"duration"` // Ч:ММ:СС, Ч:ММ или часы десятичной дробью
+	Category    string `json:"category"`
+	Description string `json:"description"`
+	Billable    string `json:"billable"` // yes/no, true/false, 1/0, да/нет
+	// DateFormat и TimeFormat - формат даты и времени из обозначений YYYY, MM, DD, HH (24 часа),
+	// hh (12 часов), mm, ss и A (AM/PM). По умолчанию YYYY-MM-DD и HH:mm:ss или HH:mm.
+	// Значение колонки Start без заданных форматов может быть также в формате RFC 3339.
+	DateFormat string `json:"date_format"`
+	TimeFormat string `json:"time_format"`
+}
+
+// presets - встроенные описания колонок выгрузок других трекеров. Проект в них соответствует категории.
+var presets = map[string]Mapping{
+	SourceToggl: {
+		StartDate:   "Start date",
+		StartTime:   "Start time",
+		EndDate:     "End date",
+		EndTime:     "End time",
+		Duration:    "Duration",
+		Category:    "Project",
+		Description: "Description",
+		Billable:    "Billable",
+		DateFormat:  "YYYY-MM-DD",
+		TimeFormat:  "HH:mm:ss",
+	},
+	SourceClockify: {
+		StartDate:   "Start Date",
+		StartTime:   "Start Time",
+		EndDate:     "End Date",
+		EndTime:     "End Time",
+		Duration:    "Duration (h)",
+		Category:    "Project",
+		Description: "Description",
+		Billable:    "Billable",
+		DateFormat:  "MM/DD/YYYY",
+		TimeFormat:  "hh:mm:ss A",
+	},
+}
+
+// resolveMapping возвращает описание колонок для источника: встроенное описание,
+// поля которого можно переопределить в custom, или custom для произвольного CSV
+func resolveMapping(source string, custom Mapping) (Mapping, error) {
+	if source == "" {
+		source = SourceCSV
+	}
+	if source == SourceCSV {
+		return custom, nil
+	}
+
+	mapping, ok := presets[source]
+	if !ok {
+		return Mapping{}, ErrInvalidSource
+	}
+	override := func(field *string, value string) {
+		if value != "" {
+			*field = value
+		}
+	}
+	override(&mapping.Start, custom.Start)
+	override(&mapping.StartDate, custom.StartDate)
+	override(&mapping.StartTime, custom.StartTime)
+	override(&mapping.End, custom.End)
+	override(&mapping.EndDate, custom.EndDate)
+	override(&mapping.EndTime, custom.EndTime)
+	override(&mapping.Duration, custom.Duration)
+	override(&mapping.Category, custom.Category)
+	override(&mapping.Description, custom.Description)
+	override(&mapping.Billable, custom.Billable)
+	override(&mapping.DateFormat, custom.DateFormat)
+	override(&mapping.TimeFormat, custom.TimeFormat)
+	return mapping, nil
+}
+
+// columns - номера колонок CSV для полей записи, -1 - колонки нет
+type columns struct {
+	start, startDate, startTime    int
+	end, endDate, endTime          int
+	duration, category, desc, bill int
+}
+
+// bindColumns находит колонки описания в строке заголовка. Отсутствие колонки начала или окончания -
+// ошибка; отсутствие остальных колонок - ошибка только при strict (колонки указаны пользователем явно).
+func bindColumns(header []string, mapping Mapping, strict bool) (columns, error) {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\uFEFF")))
+		if _, exists := index[name]; !exists {
+			index[name] = i
+		}
+	}
+
+	var missing []string
+	find := func(name string, required bool) int {
+		if name == "" {
+			return -1
+		}
+		i, ok := index[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			if required {
+				missing = append(missing, name)
+			}
+			return -1
+		}
+		return i
+	}
+
+	cols := columns{
+		start:     find(mapping.Start, true),
+		startDate: find(mapping.StartDate, true),
+		startTime: find(mapping.StartTime, true),
+		end:       find(mapping.End, true),
+		endDate:   find(mapping.EndDate, true),
+		endTime:   find(mapping.EndTime, true),
+		duration:  find(mapping.Duration, strict),
+		category:  find(mapping.Category, strict),
+		desc:      find(mapping.Description, strict),
+		bill:      find(mapping.Billable, strict),
+	}
+	if len(missing) > 0 {
+		return cols, fmt.Errorf("%w: в файле нет колонок %s", ErrInvalidMapping, strings.Join(missing, ", "))
+	}
+
+	if cols.start < 0 && (cols.startDate < 0 || cols.startTime < 0) {
+		return cols, fmt.Errorf("%w: не указана колонка начала (start или start_date и start_time)", ErrInvalidMapping)
+	}
+	if cols.end < 0 && cols.endTime < 0 && cols.duration < 0 {
+		return cols, fmt.Errorf("%w: не указана колонка окончания (end, end_time) или длительности (duration)", ErrInvalidMapping)
+	}
+	return cols, nil
+}
+
+// layoutReplacer переводит обозначения формата даты и времени в формат пакета time.
+// Месяц, день и час допускают запись без ведущего нуля.
+var layoutReplacer = strings.NewReplacer(
+	"YYYY", "2006", "MM", "1", "DD", "2",
+	"HH", "15", "hh", "3", "mm", "04", "ss", "05", "A", "PM",
+)
+
+// timeParser разбирает дату и время из колонок CSV в часовом поясе loc
+type timeParser struct {
+	dateLayouts []string
+	timeLayouts []string
+	loc         *time.Location
+}
+
+func newTimeParser(mapping Mapping, loc *time.Location) timeParser {
+	parser := timeParser{
+		dateLayouts: []string{"2006-01-02"},
+		timeLayouts: []string{"15:04:05", "15:04"},
+		loc:         loc,
+	}
+	if mapping.DateFormat != "" {
+		parser.dateLayouts = []string{layoutReplacer.Replace(mapping.DateFormat)}
+	}
+	if mapping.TimeFormat != "" {
+		parser.timeLayouts = []string{layoutReplacer.Replace(mapping.TimeFormat)}
+	}
+	return parser
+}
+
+// parseDateTime разбирает дату и время из одной колонки
+func (p timeParser) parseDateTime(value string, allowRFC3339 bool) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if allowRFC3339 {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t, nil
+		}
+	}
+	for _, dateLayout := range p.dateLayouts {
+		for _, timeLayout := range p.timeLayouts {
+			if t, err := time.ParseInLocation(dateLayout+" "+timeLayout, value, p.loc); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("не удалось разобрать дату и время %q", value)
+}
+
+// parseDateAndTime разбирает дату и время из отдельных колонок
+func (p timeParser) parseDateAndTime(date, clock string) (time.Time, error) {
+	return p.parseDateTime(strings.TrimSpace(date)+" "+strings.TrimSpace(clock), false)
+}
+
+// parseDuration разбирает длительность: Ч:ММ:СС, Ч:ММ или часы десятичной дробью
+func parseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, ":") {
+		parts := strings.Split(value, ":")
+		if len(parts) > 3 {
+			return 0, fmt.Errorf("некорректная длительность %q", value)
+		}
+		var seconds int64
+		for i, part := range parts {
+			n, err := strconv.ParseInt(part, 10, 64)
+			if err != nil || n < 0 || (i > 0 && n >= 60) {
+				return 0, fmt.Errorf("некорректная длительность %q", value)
+			}
+			seconds = seconds*60 + n
+		}
+		if len(parts) == 2 {
+			seconds *= 60
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	hours, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+	if err != nil || hours < 0 {
+		return 0, fmt.Errorf("некорректная длительность %q", value)
+	}
+	return time.Duration(hours * float64(time.Hour)).Round(time.Second), nil
+}
+
+// errInvalidBillable возникает при нераспознанном значении признака оплаты
+var errInvalidBillable = errors.New("некорректное значение признака оплаты")
+
+// parseBillable разбирает признак оплаты; пустое значение - оплачиваемая запись
+func parseBillable(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "yes", "y", "true", "1", "да":
+		return true, nil
+	case "no", "n", "false", "0", "нет":
+		return false, nil
+	}
+	return false, fmt.Errorf("%w %q", errInvalidBillable, value)
+}
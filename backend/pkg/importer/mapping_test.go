@@ -0,0 +1,56 @@
+package importer
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseDuration проверяет форматы длительности
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"1:30:15", time.Hour + 30*time.Minute + 15*time.Second, false},
+		{"26:00:00", 26 * time.Hour, false},
+		{"0:45", 45 * time.Minute, false},
+		{"1.25", 75 * time.Minute, false},
+		{"1,5", 90 * time.Minute, false},
+		{"1:75:00", 0, true},
+		{"-1", 0, true},
+		{"полтора", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseDuration(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseDuration(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseDuration(%q) = %v, хотели %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+// TestTimeParser проверяет пользовательские форматы даты и времени
+func TestTimeParser(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatalf("Не удалось загрузить часовой пояс: %v", err)
+	}
+	parser := newTimeParser(Mapping{DateFormat: "DD.MM.YYYY", TimeFormat: "hh:mm A"}, moscow)
+
+	got, err := parser.parseDateAndTime("5.03.2024", "7:15 PM")
+	if err != nil {
+		t.Fatalf("parseDateAndTime() error = %v", err)
+	}
+	if want := time.Date(2024, 3, 5, 19, 15, 0, 0, moscow); !got.Equal(want) {
+		t.Errorf("parseDateAndTime() = %v, хотели %v", got, want)
+	}
+
+	if _, err := parser.parseDateAndTime("2024-03-05", "19:15"); err == nil {
+		t.Errorf("parseDateAndTime() без ошибки для даты в другом формате")
+	}
+}
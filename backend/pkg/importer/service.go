@@ -0,0 +1,475 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/pkg/categories"
+	"github.com/graywrk/timetracker/backend/pkg/database"
+)
+
+// Определение типовых ошибок
+var (
+	ErrInvalidSource    = errors.New("неизвестный источник импорта: ожидается csv, toggl или clockify")
+	ErrInvalidMapping   = errors.New("некорректное описание колонок")
+	ErrInvalidDelimiter = errors.New("некорректный разделитель: ожидается один символ или tab")
+	ErrInvalidTimezone  = errors.New("неизвестный часовой пояс")
+	ErrEmptyFile        = errors.New("файл не содержит строки заголовка")
+	ErrTooManyRows      = fmt.Errorf("файл содержит больше %d строк", MaxRows)
+	// ErrInvalidRows возникает, если в файле есть строки с ошибками: в этом случае не импортируется ничего
+	ErrInvalidRows = errors.New("файл содержит строки с ошибками, записи не импортированы")
+)
+
+// MaxRows - наибольшее количество строк данных в одном файле импорта
+const MaxRows = 20000
+
+// Статусы строк в отчете об импорте
+const (
+	RowOK        = "ok"        // запись создана (при проверке - будет создана)
+	RowDuplicate = "duplicate" // запись с тем же началом и окончанием уже есть, строка пропускается
+	RowError     = "error"     // строку нельзя импортировать
+	RowSkipped   = "skipped"   // строка пропускается без ошибки (например, событие календаря на весь день)
+)
+
+// Options содержит параметры импорта
+type Options struct {
+	// Source - источник файла: csv (по умолчанию), toggl или clockify
+	Source string
+	// Mapping - описание колонок для csv; для toggl и clockify заполненные поля переопределяют встроенные
+	Mapping Mapping
+	// Delimiter - разделитель полей (один символ или tab); пустая строка - определяется по заголовку
+	Delimiter string
+	// Timezone - часовой пояс IANA для времени в файле; пустая строка - часовой пояс из профиля
+	Timezone string
+	// DryRun - только проверить файл и вернуть отчет, ничего не сохраняя
+	DryRun bool
+}
+
+// RowReport содержит результат обработки строки файла
+type RowReport struct {
+	Row       int        `json:"row"` // номер строки в файле, заголовок - строка 1
+	Status    string     `json:"status"`
+	Errors    []string   `json:"errors,omitempty"`
+	Message   string     `json:"message,omitempty"`
+	StartTime *time.Time `json:"start_time,omitempty"`
+	EndTime   *time.Time `json:"end_time,omitempty"`
+	Category  string     `json:"category,omitempty"`
+	EntryID   uint       `json:"entry_id,omitempty"` // созданная запись или существующая запись для дубликата
+	// Поля импорта календаря: название события, ключ экземпляра для исключения и созданный интервал
+	Event      string `json:"event,omitempty"`
+	Occurrence string `json:"occurrence,omitempty"`
+	BlockID    uint   `json:"block_id,omitempty"`
+}
+
+// Report содержит итоги импорта
+type Report struct {
+	DryRun            bool        `json:"dry_run"`
+	Total             int         `json:"total"`      // строк данных в файле
+	Imported          int         `json:"imported"`   // создано записей (при проверке - будет создано)
+	Duplicates        int         `json:"duplicates"` // пропущено повторяющихся строк
+	Errors            int         `json:"errors"`     // строк с ошибками
+	Skipped           int         `json:"skipped"`    // пропущено строк без ошибки
+	CreatedCategories []string    `json:"created_categories"`
+	Rows              []RowReport `json:"rows"`
+}
+
+// Service импортирует записи о времени из CSV
+type Service struct {
+	repo database.Repository
+}
+
+// NewService создает новый сервис импорта
+func NewService(repo database.Repository) *Service {
+	return &Service{
+		repo: repo,
+	}
+}
+
+// parsedRow - разобранная строка файла
+type parsedRow struct {
+	report   *RowReport
+	entry    *models.TimeEntry
+	block    *models.PlannedBlock
+	category string
+}
+
+// Import разбирает CSV из data и создает завершенные записи пользователя. Категории, которых
+// у пользователя нет, создаются по названию. Строки, повторяющие существующую запись или
+// предыдущую строку (то же начало и окончание), пропускаются. Строки с ошибками разбора или
+// пересекающиеся с другими записями не импортируются; если такие строки есть, не сохраняется
+// ничего и возвращается ErrInvalidRows вместе с отчетом. Все записи и категории создаются
+// в одной транзакции.
+func (s *Service) Import(ctx context.Context, userID uint, data io.Reader, opts Options) (*Report, error) {
+	mapping, err := resolveMapping(opts.Source, opts.Mapping)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := s.location(ctx, userID, opts.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при чтении файла: %w", err)
+	}
+	content = bytes.TrimPrefix(content, []byte("\uFEFF"))
+
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.FieldsPerRecord = -1
+	reader.Comma, err = parseDelimiter(opts.Delimiter, content)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, ErrEmptyFile
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMapping, err)
+	}
+	cols, err := bindColumns(header, mapping, opts.Source == "" || opts.Source == SourceCSV)
+	if err != nil {
+		return nil, err
+	}
+	parser := newTimeParser(mapping, loc)
+	allowRFC3339 := mapping.DateFormat == "" && mapping.TimeFormat == ""
+
+	report := &Report{DryRun: opts.DryRun, CreatedCategories: []string{}, Rows: []RowReport{}}
+	var rows []*parsedRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rows = append(rows, &parsedRow{report: &RowReport{Row: parseErr.StartLine, Status: RowError, Errors: []string{parseErr.Err.Error()}}})
+		} else if err != nil {
+			return nil, fmt.Errorf("ошибка при чтении файла: %w", err)
+		} else if !isBlank(record) {
+			line, _ := reader.FieldPos(0)
+			rows = append(rows, parseRow(line, record, cols, parser, allowRFC3339))
+		}
+
+		if len(rows) > MaxRows {
+			return nil, ErrTooManyRows
+		}
+	}
+	report.Total = len(rows)
+
+	if err := s.checkConflicts(ctx, userID, rows); err != nil {
+		return nil, err
+	}
+
+	// Категории сопоставляются по названию без учета регистра, недостающие создаются
+	existing, err := s.repo.GetCategoriesByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении категорий: %w", err)
+	}
+	byName := make(map[string]*models.Category, len(existing))
+	for _, category := range existing {
+		byName[strings.ToLower(category.Name)] = category
+	}
+
+	var newCategories []*models.Category
+	var entries []*models.TimeEntry
+	for _, row := range rows {
+		switch row.report.Status {
+		case RowOK:
+			if row.category != "" {
+				category, ok := byName[strings.ToLower(row.category)]
+				if !ok {
+					category = &models.Category{UserID: userID, Name: row.category, Color: categories.DefaultColor}
+					byName[strings.ToLower(row.category)] = category
+					newCategories = append(newCategories, category)
+					report.CreatedCategories = append(report.CreatedCategories, row.category)
+				}
+				row.entry.Category = category
+				if category.ID != 0 {
+					categoryID := category.ID
+					row.entry.CategoryID = &categoryID
+				}
+			}
+			entries = append(entries, row.entry)
+			report.Imported++
+		case RowDuplicate:
+			report.Duplicates++
+		case RowError:
+			report.Errors++
+		}
+	}
+
+	if report.Errors > 0 && !opts.DryRun {
+		report.Imported = 0
+		report.CreatedCategories = []string{}
+	}
+	if report.Errors == 0 && !opts.DryRun && len(entries) > 0 {
+		if err := s.repo.ImportTimeEntries(ctx, newCategories, entries); err != nil {
+			return nil, fmt.Errorf("ошибка при сохранении записей: %w", err)
+		}
+	}
+
+	for _, row := range rows {
+		if row.entry != nil && row.report.Status == RowOK && !opts.DryRun {
+			row.report.EntryID = row.entry.ID
+		}
+		report.Rows = append(report.Rows, *row.report)
+	}
+
+	log.Printf("Service.Import: Пользователь %d, строк=%d, импортировано=%d, дубликатов=%d, ошибок=%d, проверка=%v",
+		userID, report.Total, report.Imported, report.Duplicates, report.Errors, opts.DryRun)
+
+	if report.Errors > 0 && !opts.DryRun {
+		return report, ErrInvalidRows
+	}
+	return report, nil
+}
+
+// parseRow разбирает строку данных файла
+func parseRow(line int, record []string, cols columns, parser timeParser, allowRFC3339 bool) *parsedRow {
+	row := &parsedRow{report: &RowReport{Row: line, Status: RowOK}}
+	value := func(i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	fail := func(err error) {
+		row.report.Status = RowError
+		row.report.Errors = append(row.report.Errors, err.Error())
+	}
+
+	var start, end time.Time
+	var err error
+	if cols.start >= 0 {
+		start, err = parser.parseDateTime(value(cols.start), allowRFC3339)
+	} else {
+		start, err = parser.parseDateAndTime(value(cols.startDate), value(cols.startTime))
+	}
+	if err != nil {
+		fail(fmt.Errorf("начало: %w", err))
+	}
+
+	switch {
+	case value(cols.end) != "":
+		end, err = parser.parseDateTime(value(cols.end), allowRFC3339)
+	case value(cols.endTime) != "" && value(cols.endDate) != "":
+		end, err = parser.parseDateAndTime(value(cols.endDate), value(cols.endTime))
+	case value(cols.endTime) != "" && !start.IsZero():
+		// Без даты окончания запись заканчивается в день начала или, если время меньше, на следующий день
+		end, err = parser.parseDateAndTime(start.Format("2006-01-02"), value(cols.endTime))
+		if err == nil && !end.After(start) {
+			end = end.AddDate(0, 0, 1)
+		}
+	case value(cols.duration) != "":
+		var duration time.Duration
+		duration, err = parseDuration(value(cols.duration))
+		end = start.Add(duration)
+	default:
+		err = errors.New("не указано время окончания или длительность")
+	}
+	if err != nil {
+		fail(fmt.Errorf("окончание: %w", err))
+	}
+
+	billable, err := parseBillable(value(cols.bill))
+	if err != nil {
+		fail(err)
+	}
+
+	if row.report.Status == RowOK && !end.After(start) {
+		fail(errors.New("время окончания должно быть позже времени начала"))
+	}
+	if row.report.Status != RowOK {
+		return row
+	}
+
+	row.category = value(cols.category)
+	row.report.StartTime = &start
+	row.report.EndTime = &end
+	row.report.Category = row.category
+	row.entry = &models.TimeEntry{
+		StartTime:   start,
+		EndTime:     end,
+		Status:      models.StatusCompleted,
+		Billable:    billable,
+		Description: value(cols.desc),
+	}
+	return row
+}
+
+// checkConflicts отмечает строки, повторяющие или пересекающие существующие записи и другие строки файла.
+// Строки с тем же началом и окончанием считаются дубликатами, остальные пересечения - ошибками.
+func (s *Service) checkConflicts(ctx context.Context, userID uint, rows []*parsedRow) error {
+	var valid []*parsedRow
+	for _, row := range rows {
+		if row.report.Status == RowOK {
+			row.entry.UserID = userID
+			valid = append(valid, row)
+		}
+	}
+	if len(valid) == 0 {
+		return nil
+	}
+	sort.SliceStable(valid, func(i, j int) bool { return valid[i].entry.StartTime.Before(valid[j].entry.StartTime) })
+
+	from, to := valid[0].entry.StartTime, valid[0].entry.EndTime
+	for _, row := range valid {
+		if row.entry.EndTime.After(to) {
+			to = row.entry.EndTime
+		}
+	}
+	existing, err := s.repo.ListTimeEntries(ctx, database.TimeEntryFilter{UserID: userID, From: from.UTC(), To: to.UTC(), SortAsc: true})
+	if err != nil {
+		return fmt.Errorf("ошибка при получении записей времени: %w", err)
+	}
+	index := newEntryIndex(existing)
+
+	type interval struct{ start, end int64 }
+	accepted := make(map[interval]int)
+	var lastEnd time.Time
+	lastRow := 0
+	for _, row := range valid {
+		start, end := row.entry.StartTime, row.entry.EndTime
+		key := interval{start.Unix(), end.Unix()}
+
+		if entry := index.find(start, end, true); entry != nil {
+			row.report.Status = RowDuplicate
+			row.report.Message = fmt.Sprintf("повторяет существующую запись #%d", entry.ID)
+			row.report.EntryID = entry.ID
+			continue
+		}
+		if other, ok := accepted[key]; ok {
+			row.report.Status = RowDuplicate
+			row.report.Message = fmt.Sprintf("повторяет строку %d", other)
+			continue
+		}
+		if entry := index.find(start, end, false); entry != nil {
+			row.report.Status = RowError
+			row.report.Errors = append(row.report.Errors, fmt.Sprintf("пересекается с существующей записью #%d", entry.ID))
+			continue
+		}
+		if start.Before(lastEnd) {
+			row.report.Status = RowError
+			row.report.Errors = append(row.report.Errors, fmt.Sprintf("пересекается со строкой %d", lastRow))
+			continue
+		}
+
+		accepted[key] = row.report.Row
+		lastEnd, lastRow = end, row.report.Row
+	}
+	return nil
+}
+
+// entryIndex ищет существующие записи, пересекающиеся с интервалом
+type entryIndex struct {
+	entries []*models.TimeEntry // по возрастанию начала
+	maxEnd  []time.Time         // наибольшее окончание среди entries[0..i]
+}
+
+func newEntryIndex(entries []*models.TimeEntry) *entryIndex {
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].StartTime.Before(entries[j].StartTime) })
+	index := &entryIndex{entries: entries, maxEnd: make([]time.Time, len(entries))}
+	for i, entry := range entries {
+		end := entry.EndTime
+		if end.IsZero() {
+			// Незавершенная запись продолжается
+			end = time.Unix(1<<62, 0)
+		}
+		index.maxEnd[i] = end
+		if i > 0 && index.maxEnd[i-1].After(end) {
+			index.maxEnd[i] = index.maxEnd[i-1]
+		}
+	}
+	return index
+}
+
+// find возвращает запись, пересекающуюся с [start, end), или при exact - запись с тем же началом и окончанием
+func (x *entryIndex) find(start, end time.Time, exact bool) *models.TimeEntry {
+	i := sort.Search(len(x.entries), func(i int) bool { return !x.entries[i].StartTime.Before(end) })
+	for i--; i >= 0 && x.maxEnd[i].After(start); i-- {
+		entry := x.entries[i]
+		if exact {
+			if entry.StartTime.Unix() == start.Unix() && !entry.EndTime.IsZero() && entry.EndTime.Unix() == end.Unix() {
+				return entry
+			}
+			continue
+		}
+		if entry.EndTime.IsZero() || entry.EndTime.After(start) {
+			return entry
+		}
+	}
+	return nil
+}
+
+// parseDelimiter возвращает разделитель полей; без явного значения выбирается
+// самый частый из ",", ";" и табуляции в строке заголовка
+func parseDelimiter(delimiter string, content []byte) (rune, error) {
+	switch {
+	case delimiter == "":
+		header := content
+		if i := bytes.IndexByte(content, '\n'); i >= 0 {
+			header = content[:i]
+		}
+		best, bestCount := ',', 0
+		for _, candidate := range []rune{',', ';', '\t'} {
+			if count := bytes.Count(header, []byte(string(candidate))); count > bestCount {
+				best, bestCount = candidate, count
+			}
+		}
+		return best, nil
+	case strings.EqualFold(delimiter, "tab"):
+		return '\t', nil
+	case utf8.RuneCountInString(delimiter) == 1:
+		r, _ := utf8.DecodeRuneInString(delimiter)
+		if r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+			return 0, ErrInvalidDelimiter
+		}
+		return r, nil
+	}
+	return 0, ErrInvalidDelimiter
+}
+
+// isBlank сообщает, что все поля строки пустые
+func isBlank(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// location определяет часовой пояс времени в файле: явно переданный tz,
+// иначе часовой пояс из профиля пользователя
+func (s *Service) location(ctx context.Context, userID uint, tz string) (*time.Location, error) {
+	if tz != "" {
+		loc, err := models.LoadTimezone(tz)
+		if err != nil {
+			return nil, ErrInvalidTimezone
+		}
+		return loc, nil
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return time.UTC, nil
+	}
+	return user.Location(), nil
+}
@@ -0,0 +1,275 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/internal/testutil"
+	"github.com/graywrk/timetracker/backend/pkg/database"
+)
+
+// MockRepository представляет мок репозитория для тестирования
+type MockRepository struct {
+	testutil.Repository
+
+	user       *models.User
+	categories []*models.Category
+	// entries - существующие записи, которые возвращает ListTimeEntries
+	entries []*models.TimeEntry
+	// filter - условия последнего вызова ListTimeEntries
+	filter database.TimeEntryFilter
+	// imported - записи, сохраненные ImportTimeEntries; imports - количество вызовов
+	imported []*models.TimeEntry
+	imports  int
+	// planned - существующие запланированные интервалы; importedBlocks - сохраненные ImportPlannedBlocks
+	planned        []*models.PlannedBlock
+	importedBlocks []*models.PlannedBlock
+	nextID         uint
+	err            error
+}
+
+// Реализация методов интерфейса Repository
+
+// GetUserByID мок метода
+func (m *MockRepository) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	return m.user, m.err
+}
+
+// GetTimeEntriesByUserID мок метода
+func (m *MockRepository) GetTimeEntriesByUserID(ctx context.Context, userID uint) ([]*models.TimeEntry, error) {
+	return m.entries, m.err
+}
+
+// ListTimeEntries мок метода
+func (m *MockRepository) ListTimeEntries(ctx context.Context, filter database.TimeEntryFilter) ([]*models.TimeEntry, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.filter = filter
+	return m.entries, nil
+}
+
+// ImportTimeEntries мок метода: сохраняет категории и записи, назначая идентификаторы
+func (m *MockRepository) ImportTimeEntries(ctx context.Context, categories []*models.Category, entries []*models.TimeEntry) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.imports++
+	for _, category := range categories {
+		m.nextID++
+		category.ID = m.nextID
+		m.categories = append(m.categories, category)
+	}
+	for _, entry := range entries {
+		if entry.CategoryID == nil && entry.Category != nil {
+			categoryID := entry.Category.ID
+			entry.CategoryID = &categoryID
+		}
+		m.nextID++
+		entry.ID = m.nextID
+		m.imported = append(m.imported, entry)
+	}
+	return nil
+}
+
+// GetCategoriesByUserID мок метода
+func (m *MockRepository) GetCategoriesByUserID(ctx context.Context, userID uint) ([]*models.Category, error) {
+	return m.categories, m.err
+}
+
+// ImportPlannedBlocks мок метода: сохраняет интервалы, назначая идентификаторы
+func (m *MockRepository) ImportPlannedBlocks(ctx context.Context, blocks []*models.PlannedBlock) error {
+	if m.err != nil {
+		return m.err
+	}
+	for _, block := range blocks {
+		m.nextID++
+		block.ID = m.nextID
+		m.importedBlocks = append(m.importedBlocks, block)
+	}
+	return nil
+}
+
+// ListPlannedBlocks мок метода
+func (m *MockRepository) ListPlannedBlocks(ctx context.Context, userID uint, from, to time.Time) ([]*models.PlannedBlock, error) {
+	return m.planned, m.err
+}
+
+// WithTx мок метода: fn выполняется без транзакции
+func (m *MockRepository) WithTx(ctx context.Context, fn func(repo database.Repository) error) error {
+	return fn(m)
+}
+
+// rowStatuses возвращает статусы строк отчета по номерам строк
+func rowStatuses(report *Report) map[int]string {
+	statuses := make(map[int]string, len(report.Rows))
+	for _, row := range report.Rows {
+		statuses[row.Row] = row.Status
+	}
+	return statuses
+}
+
+// TestImport_Toggl проверяет импорт выгрузки Toggl: часовой пояс профиля, категории и признак оплаты
+func TestImport_Toggl(t *testing.T) {
+	mockRepo := &MockRepository{
+		user:       &models.User{ID: 1, Timezone: "Europe/Moscow"},
+		categories: []*models.Category{{ID: 5, UserID: 1, Name: "Разработка"}},
+		nextID:     100,
+	}
+	data := "\uFEFFUser,Email,Client,Project,Task,Description,Billable,Start date,Start time,End date,End time,Duration,Tags\n" +
+		"Анна,anna@example.com,,разработка,,Ревью кода,Yes,2024-03-01,09:00:00,2024-03-01,10:30:00,01:30:00,\n" +
+		"Анна,anna@example.com,ООО Ромашка,Сайт,,\"Верстка, правки\",No,2024-03-01,23:00:00,2024-03-02,01:00:00,02:00:00,\n" +
+		"\n"
+
+	report, err := NewService(mockRepo).Import(context.Background(), 1, strings.NewReader(data), Options{Source: SourceToggl})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if report.Total != 2 || report.Imported != 2 || report.Errors != 0 || report.Duplicates != 0 {
+		t.Fatalf("Report = %+v, хотели 2 импортированные строки", report)
+	}
+	if len(report.CreatedCategories) != 1 || report.CreatedCategories[0] != "Сайт" {
+		t.Errorf("CreatedCategories = %v, хотели [Сайт]", report.CreatedCategories)
+	}
+	if mockRepo.imports != 1 || len(mockRepo.imported) != 2 {
+		t.Fatalf("ImportTimeEntries вызван %d раз с %d записями, хотели один вызов с 2 записями", mockRepo.imports, len(mockRepo.imported))
+	}
+
+	first, second := mockRepo.imported[0], mockRepo.imported[1]
+	if want := time.Date(2024, 3, 1, 6, 0, 0, 0, time.UTC); !first.StartTime.Equal(want) {
+		t.Errorf("StartTime = %v, хотели %v (09:00 по Москве)", first.StartTime, want)
+	}
+	if first.CategoryID == nil || *first.CategoryID != 5 || !first.Billable || first.Description != "Ревью кода" {
+		t.Errorf("Первая запись = %+v, хотели существующую категорию 5 и оплачиваемую запись", first)
+	}
+	if second.EndTime.Sub(second.StartTime) != 2*time.Hour || second.Billable || second.Description != "Верстка, правки" {
+		t.Errorf("Вторая запись = %+v, хотели 2 часа через полночь без оплаты", second)
+	}
+	if second.CategoryID == nil || *second.CategoryID != mockRepo.categories[1].ID || mockRepo.categories[1].Name != "Сайт" {
+		t.Errorf("Вторая запись должна получить созданную категорию Сайт")
+	}
+	if report.Rows[0].Row != 2 || report.Rows[0].EntryID != first.ID || report.Rows[1].Row != 3 {
+		t.Errorf("Rows = %+v, хотели строки 2 и 3 с идентификаторами записей", report.Rows)
+	}
+}
+
+// TestImport_Duplicates проверяет пропуск строк, повторяющих существующую запись или другую строку
+func TestImport_Duplicates(t *testing.T) {
+	existingStart := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	mockRepo := &MockRepository{
+		user: &models.User{ID: 1},
+		entries: []*models.TimeEntry{
+			{ID: 7, UserID: 1, StartTime: existingStart, EndTime: existingStart.Add(time.Hour), Status: models.StatusCompleted},
+		},
+	}
+	data := "Project;Description;Billable;Start Date;Start Time;End Date;End Time;Duration (h)\n" +
+		"Сайт;Созвон;Yes;03/04/2024;09:00:00 AM;03/04/2024;10:00:00 AM;01:00:00\n" +
+		"Сайт;Верстка;Yes;03/04/2024;1:00:00 PM;03/04/2024;3:30:00 PM;02:30:00\n" +
+		"Сайт;Верстка;Yes;03/04/2024;01:00:00 PM;03/04/2024;03:30:00 PM;02:30:00\n"
+
+	report, err := NewService(mockRepo).Import(context.Background(), 1, strings.NewReader(data), Options{Source: SourceClockify})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if report.Imported != 1 || report.Duplicates != 2 {
+		t.Errorf("Report = %+v, хотели 1 импортированную строку и 2 дубликата", report)
+	}
+	statuses := rowStatuses(report)
+	if statuses[2] != RowDuplicate || statuses[3] != RowOK || statuses[4] != RowDuplicate {
+		t.Errorf("Статусы строк = %v, хотели 2 и 4 - дубликаты", statuses)
+	}
+	if report.Rows[0].EntryID != 7 {
+		t.Errorf("Дубликат существующей записи указывает на %d, хотели 7", report.Rows[0].EntryID)
+	}
+	if len(mockRepo.imported) != 1 || mockRepo.imported[0].EndTime.Sub(mockRepo.imported[0].StartTime) != 150*time.Minute {
+		t.Errorf("Сохранено %d записей, хотели одну запись на 2:30", len(mockRepo.imported))
+	}
+	if !mockRepo.filter.From.Equal(existingStart) || mockRepo.filter.From.Location() != time.UTC {
+		t.Errorf("Пересечения проверялись с %v, хотели %v в UTC", mockRepo.filter.From, existingStart)
+	}
+}
+
+// TestImport_DryRunErrors проверяет отчет об ошибках и отказ от импорта файла с ошибками
+func TestImport_DryRunErrors(t *testing.T) {
+	existingStart := time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC)
+	mapping := Mapping{Start: "Начало", Duration: "Часы", Category: "Тип", Description: "Комментарий"}
+	data := "Начало\tЧасы\tТип\tКомментарий\n" +
+		"2024-03-05 09:00\t1,5\tАналитика\tТребования\n" + // строка 2: корректна
+		"05.03.2024 09:00\t1\t\tПлан\n" + // строка 3: неизвестный формат даты
+		"2024-03-05T11:30:00Z\t1\t\tОбед\n" + // строка 4: пересекает существующую запись
+		"2024-03-05 10:00\t0:45\t\tЗвонок\n" + // строка 5: пересекает строку 2
+		"2024-03-05 16:00\t0\t\tПусто\n" // строка 6: нулевая длительность
+
+	for _, dryRun := range []bool{true, false} {
+		mockRepo := &MockRepository{
+			user: &models.User{ID: 1},
+			entries: []*models.TimeEntry{
+				{ID: 9, UserID: 1, StartTime: existingStart, EndTime: existingStart.Add(time.Hour), Status: models.StatusCompleted},
+			},
+		}
+		report, err := NewService(mockRepo).Import(context.Background(), 1, strings.NewReader(data), Options{Mapping: mapping, DryRun: dryRun})
+		if dryRun && err != nil {
+			t.Fatalf("Import(dry_run) error = %v", err)
+		}
+		if !dryRun && err != ErrInvalidRows {
+			t.Fatalf("Import() error = %v, хотели ErrInvalidRows", err)
+		}
+		if mockRepo.imports != 0 {
+			t.Errorf("dry_run=%v: ImportTimeEntries вызван, хотели без сохранения", dryRun)
+		}
+
+		want := map[int]string{2: RowOK, 3: RowError, 4: RowError, 5: RowError, 6: RowError}
+		statuses := rowStatuses(report)
+		for row, status := range want {
+			if statuses[row] != status {
+				t.Errorf("dry_run=%v: строка %d = %q, хотели %q", dryRun, row, statuses[row], status)
+			}
+		}
+		if report.Errors != 4 {
+			t.Errorf("dry_run=%v: Errors = %d, хотели 4", dryRun, report.Errors)
+		}
+		if dryRun && (report.Imported != 1 || len(report.CreatedCategories) != 1) {
+			t.Errorf("Проверка: Imported = %d, CreatedCategories = %v, хотели 1 запись и категорию Аналитика",
+				report.Imported, report.CreatedCategories)
+		}
+		if !dryRun && (report.Imported != 0 || len(report.CreatedCategories) != 0) {
+			t.Errorf("Импорт с ошибками: Imported = %d, CreatedCategories = %v, хотели ничего", report.Imported, report.CreatedCategories)
+		}
+		if got := report.Rows[2].Errors; len(got) != 1 || !strings.Contains(got[0], "#9") {
+			t.Errorf("Ошибки строки 4 = %v, хотели пересечение с записью #9", got)
+		}
+		if got := report.Rows[3].Errors; len(got) != 1 || !strings.Contains(got[0], "строкой 2") {
+			t.Errorf("Ошибки строки 5 = %v, хотели пересечение со строкой 2", got)
+		}
+	}
+}
+
+// TestImport_InvalidOptions проверяет ошибки параметров импорта
+func TestImport_InvalidOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		opts    Options
+		wantErr error
+	}{
+		{"неизвестный источник", "a\n", Options{Source: "harvest"}, ErrInvalidSource},
+		{"пустой файл", "", Options{Source: SourceToggl}, ErrEmptyFile},
+		{"нет колонки", "Start,End\n", Options{Mapping: Mapping{Start: "Start", End: "End", Category: "Project"}}, ErrInvalidMapping},
+		{"нет окончания", "Start\n", Options{Mapping: Mapping{Start: "Start"}}, ErrInvalidMapping},
+		{"неизвестный часовой пояс", "Start,End\n", Options{Mapping: Mapping{Start: "Start", End: "End"}, Timezone: "Mars/Olympus"}, ErrInvalidTimezone},
+		{"неверный разделитель", "Start,End\n", Options{Mapping: Mapping{Start: "Start", End: "End"}, Delimiter: "::"}, ErrInvalidDelimiter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewService(&MockRepository{user: &models.User{ID: 1}})
+			if _, err := service.Import(context.Background(), 1, strings.NewReader(tt.data), tt.opts); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Import() error = %v, хотели %v", err, tt.wantErr)
+			}
+		})
+	}
+}
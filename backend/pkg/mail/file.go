@@ -0,0 +1,52 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileMailer не отправляет письма, а сохраняет их в каталог или пишет в лог.
+// Используется при разработке и в тестах, когда SMTP-сервер не настроен.
+type FileMailer struct {
+	dir string
+	mu  sync.Mutex
+	seq int
+}
+
+// NewFileMailer создает отправителя, сохраняющего письма в каталог dir.
+// Пустой dir означает запись писем в лог.
+func NewFileMailer(dir string) *FileMailer {
+	return &FileMailer{dir: dir}
+}
+
+// Send сохраняет письмо
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	to, err := parseAddress(msg.To)
+	if err != nil {
+		return err
+	}
+
+	text := fmt.Sprintf("To: %s\nSubject: %s\n\n%s\n", to, msg.Subject, msg.Body)
+	if m.dir == "" {
+		log.Printf("FileMailer: Письмо не отправлено, SMTP-сервер не настроен:\n%s", text)
+		return nil
+	}
+
+	m.mu.Lock()
+	m.seq++
+	name := fmt.Sprintf("%s-%03d.txt", time.Now().Format("20060102-150405"), m.seq)
+	m.mu.Unlock()
+
+	path := filepath.Join(m.dir, name)
+	if err := os.WriteFile(path, []byte(text), 0o600); err != nil {
+		return fmt.Errorf("ошибка при сохранении письма: %w", err)
+	}
+
+	log.Printf("FileMailer: Письмо %q для %s сохранено в %s", msg.Subject, to, path)
+	return nil
+}
@@ -0,0 +1,198 @@
+package mail
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"net"
+	netmail "net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readMessage разбирает письмо и возвращает тему и декодированный текст
+func readMessage(t *testing.T, raw []byte) (*netmail.Message, string, string) {
+	t.Helper()
+
+	parsed, err := netmail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Не удалось разобрать письмо: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("Не удалось декодировать тему: %v", err)
+	}
+	encoded, err := io.ReadAll(parsed.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+	if err != nil {
+		t.Fatalf("Не удалось декодировать текст: %v", err)
+	}
+	return parsed, subject, string(body)
+}
+
+func TestBuildMessage(t *testing.T) {
+	body := strings.Repeat("Ссылка для сброса пароля: https://example.com/reset?token=abc\n", 3)
+	raw := buildMessage("TimeTracker <noreply@example.com>", Message{
+		To:      "user@example.com",
+		Subject: "Сброс пароля",
+		Body:    body,
+	}, time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC))
+
+	for _, line := range strings.Split(string(raw), "\r\n") {
+		if len(line) > 78 {
+			t.Errorf("Строка письма длиннее 78 символов: %q", line)
+		}
+	}
+
+	parsed, subject, text := readMessage(t, raw)
+	if subject != "Сброс пароля" {
+		t.Errorf("Тема = %q, хотели %q", subject, "Сброс пароля")
+	}
+	if parsed.Header.Get("To") != "user@example.com" {
+		t.Errorf("To = %q", parsed.Header.Get("To"))
+	}
+	if text != strings.ReplaceAll(body, "\n", "\r\n") {
+		t.Errorf("Текст письма = %q", text)
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	mailer := NewFileMailer(dir)
+
+	for i := 0; i < 2; i++ {
+		if err := mailer.Send(context.Background(), Message{To: "user@example.com", Subject: "Тема", Body: "Текст"}); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Сохранено %d писем, хотели 2", len(files))
+	}
+	content, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "To: user@example.com") || !strings.Contains(string(content), "Текст") {
+		t.Errorf("Неверное содержимое письма: %q", content)
+	}
+
+	// Адрес с переводом строки не должен попасть в заголовки
+	err = mailer.Send(context.Background(), Message{To: "user@example.com\r\nBcc: other@example.com", Subject: "Тема"})
+	if err == nil {
+		t.Error("Send() не вернул ошибку для некорректного адреса")
+	}
+
+	// Без каталога письмо только записывается в лог
+	if err := NewFileMailer("").Send(context.Background(), Message{To: "user@example.com", Subject: "Тема"}); err != nil {
+		t.Errorf("Send() error = %v", err)
+	}
+}
+
+// fakeSMTPServer принимает одно письмо и передает его данные в канал
+func fakeSMTPServer(t *testing.T) (string, <-chan string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+		reply("220 localhost ESMTP")
+
+		var envelope []string
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+			switch command {
+			case "EHLO", "HELO":
+				reply("250 localhost")
+			case "MAIL", "RCPT":
+				envelope = append(envelope, line)
+				reply("250 OK")
+			case "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					dataLine, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if dataLine == ".\r\n" {
+						break
+					}
+					data.WriteString(dataLine)
+				}
+				received <- strings.Join(envelope, "\n") + "\n\n" + data.String()
+				reply("250 OK")
+			case "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("502 Command not implemented")
+			}
+		}
+	}()
+
+	return listener.Addr().String(), received
+}
+
+func TestSMTPMailer(t *testing.T) {
+	addr, received := fakeSMTPServer(t)
+	host, port, _ := net.SplitHostPort(addr)
+	portNumber, _ := net.LookupPort("tcp", port)
+
+	mailer := NewSMTPMailer(SMTPConfig{Host: host, Port: portNumber, From: "TimeTracker <noreply@example.com>"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := mailer.Send(ctx, Message{To: "user@example.com", Subject: "Сброс пароля", Body: "Текст письма"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	select {
+	case data := <-received:
+		parts := strings.SplitN(data, "\n\n", 2)
+		if !strings.Contains(parts[0], "MAIL FROM:<noreply@example.com>") || !strings.Contains(parts[0], "RCPT TO:<user@example.com>") {
+			t.Errorf("Неверный конверт письма: %q", parts[0])
+		}
+		_, subject, body := readMessage(t, []byte(parts[1]))
+		if subject != "Сброс пароля" || body != "Текст письма" {
+			t.Errorf("Получено письмо %q: %q", subject, body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Сервер не получил письмо")
+	}
+
+	if err := mailer.Send(ctx, Message{To: "не адрес"}); err == nil {
+		t.Error("Send() не вернул ошибку для некорректного адреса")
+	}
+}
@@ -0,0 +1,62 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	netmail "net/mail"
+	"strings"
+	"time"
+)
+
+// ErrInvalidAddress возникает при некорректном адресе получателя или отправителя
+var ErrInvalidAddress = errors.New("некорректный адрес электронной почты")
+
+// Message - письмо пользователю
+type Message struct {
+	To      string
+	Subject string
+	Body    string // текст письма без разметки
+}
+
+// Mailer отправляет письма пользователям
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// parseAddress проверяет адрес и возвращает его без отображаемого имени
+func parseAddress(address string) (string, error) {
+	parsed, err := netmail.ParseAddress(address)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q", ErrInvalidAddress, address)
+	}
+	return parsed.Address, nil
+}
+
+// buildMessage формирует письмо в формате RFC 5322. Заголовок темы кодируется для
+// не-ASCII символов, текст передается в base64, поэтому письмо проходит любой SMTP-сервер.
+func buildMessage(from string, msg Message, now time.Time) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.BEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n")
+	buf.WriteString("\r\n")
+
+	body := base64.StdEncoding.EncodeToString([]byte(strings.ReplaceAll(msg.Body, "\n", "\r\n")))
+	for len(body) > 76 {
+		buf.WriteString(body[:76])
+		buf.WriteString("\r\n")
+		body = body[76:]
+	}
+	buf.WriteString(body)
+	buf.WriteString("\r\n")
+
+	return buf.Bytes()
+}
@@ -0,0 +1,105 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTPConfig - параметры SMTP-сервера
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string // пустое имя отключает аутентификацию
+	Password string
+	From     string // адрес отправителя, например "TimeTracker <noreply@example.com>"
+}
+
+// SMTPMailer отправляет письма через SMTP-сервер. Если сервер поддерживает STARTTLS,
+// соединение шифруется до передачи учетных данных.
+type SMTPMailer struct {
+	config SMTPConfig
+}
+
+// NewSMTPMailer создает отправителя писем через SMTP
+func NewSMTPMailer(config SMTPConfig) *SMTPMailer {
+	return &SMTPMailer{config: config}
+}
+
+// Send отправляет письмо. Соединение прерывается при отмене ctx или по его сроку.
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	from, err := parseAddress(m.config.From)
+	if err != nil {
+		return err
+	}
+	to, err := parseAddress(msg.To)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("ошибка подключения к SMTP-серверу %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// Отмена ctx прерывает операции с соединением
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("ошибка подключения к SMTP-серверу %s: %w", addr, err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.config.Host}); err != nil {
+			return fmt.Errorf("ошибка STARTTLS: %w", err)
+		}
+	}
+
+	if m.config.Username != "" {
+		auth := smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("ошибка аутентификации на SMTP-сервере: %w", err)
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("ошибка SMTP MAIL FROM: %w", err)
+	}
+	if err := client.Rcpt(to); err != nil {
+		return fmt.Errorf("ошибка SMTP RCPT TO: %w", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("ошибка SMTP DATA: %w", err)
+	}
+	if _, err := w.Write(buildMessage(m.config.From, msg, time.Now())); err != nil {
+		return fmt.Errorf("ошибка при передаче письма: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("ошибка при передаче письма: %w", err)
+	}
+
+	if err := client.Quit(); err != nil {
+		log.Printf("SMTPMailer: Ошибка при завершении сеанса: %v", err)
+	}
+
+	log.Printf("SMTPMailer: Письмо %q отправлено на %s", msg.Subject, to)
+	return nil
+}
@@ -0,0 +1,60 @@
+package planning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/pkg/database"
+)
+
+// Определение типовых ошибок
+var (
+	ErrBlockNotFound = errors.New("запланированный интервал не найден")
+	ErrNotAuthorized = errors.New("у пользователя нет прав на этот интервал")
+)
+
+// Service предоставляет методы для работы с запланированным временем.
+// Интервалы создаются импортом календаря (пакет importer).
+type Service struct {
+	repo database.Repository
+}
+
+// NewService создает новый сервис запланированного времени
+func NewService(repo database.Repository) *Service {
+	return &Service{
+		repo: repo,
+	}
+}
+
+// GetBlocks возвращает запланированные интервалы пользователя, пересекающиеся с [from, to)
+func (s *Service) GetBlocks(ctx context.Context, userID uint, from, to time.Time) ([]*models.PlannedBlock, error) {
+	blocks, err := s.repo.ListPlannedBlocks(ctx, userID, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении запланированного времени: %w", err)
+	}
+	if blocks == nil {
+		blocks = []*models.PlannedBlock{}
+	}
+	return blocks, nil
+}
+
+// DeleteBlock удаляет запланированный интервал пользователя
+func (s *Service) DeleteBlock(ctx context.Context, id, userID uint) error {
+	block, err := s.repo.GetPlannedBlockByID(ctx, id)
+	if err != nil || block == nil {
+		return ErrBlockNotFound
+	}
+
+	if block.UserID != userID {
+		return ErrNotAuthorized
+	}
+
+	if err := s.repo.DeletePlannedBlock(ctx, id); err != nil {
+		return fmt.Errorf("ошибка при удалении запланированного интервала: %w", err)
+	}
+
+	return nil
+}
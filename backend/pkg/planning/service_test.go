@@ -0,0 +1,113 @@
+package planning
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/internal/testutil"
+	"github.com/graywrk/timetracker/backend/pkg/database"
+)
+
+// Mock репозитория для тестирования сервиса
+type MockPlannedRepo struct {
+	testutil.Repository
+
+	blocks  map[uint]*models.PlannedBlock
+	deleted []uint
+	// from и to - период последнего вызова ListPlannedBlocks
+	from, to time.Time
+}
+
+func NewMockPlannedRepo() *MockPlannedRepo {
+	return &MockPlannedRepo{
+		blocks: make(map[uint]*models.PlannedBlock),
+	}
+}
+
+func (m *MockPlannedRepo) ListPlannedBlocks(ctx context.Context, userID uint, from, to time.Time) ([]*models.PlannedBlock, error) {
+	m.from, m.to = from, to
+	var result []*models.PlannedBlock
+	for _, block := range m.blocks {
+		if block.UserID == userID && block.EndTime.After(from) && block.StartTime.Before(to) {
+			result = append(result, block)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockPlannedRepo) GetPlannedBlockByID(ctx context.Context, id uint) (*models.PlannedBlock, error) {
+	block, exists := m.blocks[id]
+	if !exists {
+		return nil, errors.New("запланированный интервал не найден")
+	}
+	return block, nil
+}
+
+func (m *MockPlannedRepo) DeletePlannedBlock(ctx context.Context, id uint) error {
+	if _, exists := m.blocks[id]; !exists {
+		return errors.New("запланированный интервал не найден")
+	}
+	delete(m.blocks, id)
+	m.deleted = append(m.deleted, id)
+	return nil
+}
+
+func (m *MockPlannedRepo) WithTx(ctx context.Context, fn func(repo database.Repository) error) error {
+	return fn(m)
+}
+
+// Тесты
+
+func TestGetBlocks(t *testing.T) {
+	repo := NewMockPlannedRepo()
+	service := NewService(repo)
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatalf("Не удалось загрузить часовой пояс: %v", err)
+	}
+
+	day := time.Date(2024, 3, 5, 0, 0, 0, 0, moscow)
+	repo.blocks[1] = &models.PlannedBlock{ID: 1, UserID: 1, StartTime: day.Add(10 * time.Hour), EndTime: day.Add(11 * time.Hour)}
+	repo.blocks[2] = &models.PlannedBlock{ID: 2, UserID: 2, StartTime: day.Add(10 * time.Hour), EndTime: day.Add(11 * time.Hour)}
+
+	blocks, err := service.GetBlocks(context.Background(), 1, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("GetBlocks() error = %v", err)
+	}
+	if len(blocks) != 1 || blocks[0].ID != 1 {
+		t.Errorf("GetBlocks() вернул %d интервалов, ожидался только интервал пользователя", len(blocks))
+	}
+	if repo.from.Location() != time.UTC || !repo.from.Equal(day) {
+		t.Errorf("GetBlocks() должен передавать границы периода в UTC, получено %v", repo.from)
+	}
+
+	blocks, err = service.GetBlocks(context.Background(), 3, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("GetBlocks() error = %v", err)
+	}
+	if blocks == nil || len(blocks) != 0 {
+		t.Errorf("GetBlocks() без интервалов должен вернуть пустой список, получено %v", blocks)
+	}
+}
+
+func TestDeleteBlock(t *testing.T) {
+	repo := NewMockPlannedRepo()
+	service := NewService(repo)
+	repo.blocks[1] = &models.PlannedBlock{ID: 1, UserID: 1}
+
+	if err := service.DeleteBlock(context.Background(), 1, 2); err != ErrNotAuthorized {
+		t.Errorf("DeleteBlock() чужого интервала error = %v, ожидалось %v", err, ErrNotAuthorized)
+	}
+	if err := service.DeleteBlock(context.Background(), 5, 1); err != ErrBlockNotFound {
+		t.Errorf("DeleteBlock() несуществующего интервала error = %v, ожидалось %v", err, ErrBlockNotFound)
+	}
+	if err := service.DeleteBlock(context.Background(), 1, 1); err != nil {
+		t.Fatalf("DeleteBlock() error = %v", err)
+	}
+	if len(repo.deleted) != 1 || repo.deleted[0] != 1 {
+		t.Errorf("DeleteBlock() удалены %v, ожидалось [1]", repo.deleted)
+	}
+}
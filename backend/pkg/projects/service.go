@@ -0,0 +1,283 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/pkg/database"
+)
+
+// Определение типовых ошибок
+var (
+	ErrClientNotFound   = errors.New("клиент не найден")
+	ErrProjectNotFound  = errors.New("проект не найден")
+	ErrNotAuthorized    = errors.New("у пользователя нет прав на этот объект")
+	ErrEmptyName        = errors.New("название не может быть пустым")
+	ErrInvalidRate      = errors.New("почасовая ставка не может быть отрицательной")
+	ErrInvalidBudget    = errors.New("бюджет часов должен быть положительным")
+	ErrCategoryNotOwned = errors.New("категория не найдена или принадлежит другому пользователю")
+)
+
+// DefaultColor - цвет проекта по умолчанию
+const DefaultColor = "#2e9e6b"
+
+// ClientInput содержит данные для создания или обновления клиента
+type ClientInput struct {
+	Name       string        `json:"name"`
+	HourlyRate *models.Money `json:"hourly_rate"`
+	Archived   bool          `json:"archived"`
+}
+
+// ProjectInput содержит данные для создания или обновления проекта
+type ProjectInput struct {
+	ClientID    *uint         `json:"client_id"`
+	Name        string        `json:"name"`
+	Color       string        `json:"color"`
+	HourlyRate  *models.Money `json:"hourly_rate"`
+	BudgetHours *float64      `json:"budget_hours"`
+	Archived    bool          `json:"archived"`
+	CategoryIDs []uint        `json:"category_ids"`
+}
+
+// Service предоставляет методы для работы с клиентами и проектами
+type Service struct {
+	repo database.Repository
+}
+
+// NewService создает новый сервис проектов
+func NewService(repo database.Repository) *Service {
+	return &Service{
+		repo: repo,
+	}
+}
+
+// CreateClient создает нового клиента пользователя
+func (s *Service) CreateClient(ctx context.Context, userID uint, input ClientInput) (*models.Client, error) {
+	client := &models.Client{UserID: userID}
+	if err := applyClientInput(client, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateClient(ctx, client); err != nil {
+		return nil, fmt.Errorf("ошибка при создании клиента: %w", err)
+	}
+
+	return client, nil
+}
+
+// GetClientsByUserID возвращает клиентов пользователя; архивные - только при includeArchived
+func (s *Service) GetClientsByUserID(ctx context.Context, userID uint, includeArchived bool) ([]*models.Client, error) {
+	clients, err := s.repo.GetClientsByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении клиентов пользователя: %w", err)
+	}
+
+	result := []*models.Client{}
+	for _, client := range clients {
+		if includeArchived || !client.Archived {
+			result = append(result, client)
+		}
+	}
+	return result, nil
+}
+
+// UpdateClient обновляет существующего клиента
+func (s *Service) UpdateClient(ctx context.Context, id, userID uint, input ClientInput) (*models.Client, error) {
+	client, err := s.getOwnedClient(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyClientInput(client, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateClient(ctx, client); err != nil {
+		return nil, fmt.Errorf("ошибка при обновлении клиента: %w", err)
+	}
+
+	return client, nil
+}
+
+// DeleteClient удаляет клиента; его проекты сохраняются без клиента
+func (s *Service) DeleteClient(ctx context.Context, id, userID uint) error {
+	if _, err := s.getOwnedClient(ctx, id, userID); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteClient(ctx, id); err != nil {
+		return fmt.Errorf("ошибка при удалении клиента: %w", err)
+	}
+
+	return nil
+}
+
+// CreateProject создает новый проект пользователя
+func (s *Service) CreateProject(ctx context.Context, userID uint, input ProjectInput) (*models.Project, error) {
+	project := &models.Project{UserID: userID, Color: DefaultColor}
+	if err := s.applyProjectInput(ctx, project, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateProject(ctx, project); err != nil {
+		return nil, fmt.Errorf("ошибка при создании проекта: %w", err)
+	}
+
+	if len(project.CategoryIDs) > 0 {
+		if err := s.repo.SetProjectCategories(ctx, project.ID, project.CategoryIDs); err != nil {
+			return nil, fmt.Errorf("ошибка при назначении категорий проекта: %w", err)
+		}
+	}
+
+	return project, nil
+}
+
+// GetProjectsByUserID возвращает проекты пользователя; архивные - только при includeArchived
+func (s *Service) GetProjectsByUserID(ctx context.Context, userID uint, includeArchived bool) ([]*models.Project, error) {
+	projects, err := s.repo.GetProjectsByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении проектов пользователя: %w", err)
+	}
+
+	result := []*models.Project{}
+	for _, project := range projects {
+		if includeArchived || !project.Archived {
+			result = append(result, project)
+		}
+	}
+	return result, nil
+}
+
+// GetProjectByID возвращает проект, если он принадлежит пользователю
+func (s *Service) GetProjectByID(ctx context.Context, id, userID uint) (*models.Project, error) {
+	return s.getOwnedProject(ctx, id, userID)
+}
+
+// UpdateProject обновляет существующий проект вместе со списком допустимых категорий
+func (s *Service) UpdateProject(ctx context.Context, id, userID uint, input ProjectInput) (*models.Project, error) {
+	project, err := s.getOwnedProject(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applyProjectInput(ctx, project, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateProject(ctx, project); err != nil {
+		return nil, fmt.Errorf("ошибка при обновлении проекта: %w", err)
+	}
+
+	if err := s.repo.SetProjectCategories(ctx, project.ID, project.CategoryIDs); err != nil {
+		return nil, fmt.Errorf("ошибка при назначении категорий проекта: %w", err)
+	}
+
+	return project, nil
+}
+
+// DeleteProject удаляет проект; записи времени сохраняются без проекта
+func (s *Service) DeleteProject(ctx context.Context, id, userID uint) error {
+	if _, err := s.getOwnedProject(ctx, id, userID); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteProject(ctx, id); err != nil {
+		return fmt.Errorf("ошибка при удалении проекта: %w", err)
+	}
+
+	return nil
+}
+
+// applyClientInput проверяет данные клиента и переносит их в модель
+func applyClientInput(client *models.Client, input ClientInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return ErrEmptyName
+	}
+	if input.HourlyRate != nil && *input.HourlyRate < 0 {
+		return ErrInvalidRate
+	}
+
+	client.Name = name
+	client.HourlyRate = input.HourlyRate
+	client.Archived = input.Archived
+	return nil
+}
+
+// applyProjectInput проверяет данные проекта и переносит их в модель
+func (s *Service) applyProjectInput(ctx context.Context, project *models.Project, input ProjectInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return ErrEmptyName
+	}
+	if input.HourlyRate != nil && *input.HourlyRate < 0 {
+		return ErrInvalidRate
+	}
+	if input.BudgetHours != nil && *input.BudgetHours <= 0 {
+		return ErrInvalidBudget
+	}
+
+	if input.ClientID != nil {
+		if _, err := s.getOwnedClient(ctx, *input.ClientID, project.UserID); err != nil {
+			return err
+		}
+	}
+
+	if len(input.CategoryIDs) > 0 {
+		categories, err := s.repo.GetCategoriesByUserID(ctx, project.UserID)
+		if err != nil {
+			return fmt.Errorf("ошибка при получении категорий: %w", err)
+		}
+		owned := make(map[uint]bool, len(categories))
+		for _, category := range categories {
+			owned[category.ID] = true
+		}
+		for _, id := range input.CategoryIDs {
+			if !owned[id] {
+				return ErrCategoryNotOwned
+			}
+		}
+	}
+
+	project.ClientID = input.ClientID
+	project.Name = name
+	if input.Color != "" {
+		project.Color = input.Color
+	}
+	project.HourlyRate = input.HourlyRate
+	project.BudgetHours = input.BudgetHours
+	project.Archived = input.Archived
+	project.CategoryIDs = append([]uint{}, input.CategoryIDs...)
+	return nil
+}
+
+// getOwnedClient возвращает клиента, если он принадлежит пользователю
+func (s *Service) getOwnedClient(ctx context.Context, id, userID uint) (*models.Client, error) {
+	client, err := s.repo.GetClientByID(ctx, id)
+	if err != nil || client == nil {
+		return nil, ErrClientNotFound
+	}
+
+	if client.UserID != userID {
+		return nil, ErrNotAuthorized
+	}
+
+	return client, nil
+}
+
+// getOwnedProject возвращает проект, если он принадлежит пользователю
+func (s *Service) getOwnedProject(ctx context.Context, id, userID uint) (*models.Project, error) {
+	project, err := s.repo.GetProjectByID(ctx, id)
+	if err != nil || project == nil {
+		return nil, ErrProjectNotFound
+	}
+
+	if project.UserID != userID {
+		return nil, ErrNotAuthorized
+	}
+
+	return project, nil
+}
@@ -0,0 +1,250 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/internal/testutil"
+	"github.com/graywrk/timetracker/backend/pkg/database"
+)
+
+// Mock репозитория для тестирования сервиса
+type MockProjectRepo struct {
+	testutil.Repository
+
+	clients    map[uint]*models.Client
+	projects   map[uint]*models.Project
+	categories []*models.Category
+	nextID     uint
+}
+
+func NewMockProjectRepo() *MockProjectRepo {
+	return &MockProjectRepo{
+		clients:  make(map[uint]*models.Client),
+		projects: make(map[uint]*models.Project),
+		nextID:   1,
+	}
+}
+
+func (m *MockProjectRepo) CreateClient(ctx context.Context, client *models.Client) error {
+	client.ID = m.nextID
+	client.CreatedAt = time.Now()
+	client.UpdatedAt = time.Now()
+	m.clients[client.ID] = client
+	m.nextID++
+	return nil
+}
+
+func (m *MockProjectRepo) GetClientByID(ctx context.Context, id uint) (*models.Client, error) {
+	client, exists := m.clients[id]
+	if !exists {
+		return nil, errors.New("клиент не найден")
+	}
+	return client, nil
+}
+
+func (m *MockProjectRepo) GetClientsByUserID(ctx context.Context, userID uint) ([]*models.Client, error) {
+	var result []*models.Client
+	for _, client := range m.clients {
+		if client.UserID == userID {
+			result = append(result, client)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockProjectRepo) UpdateClient(ctx context.Context, client *models.Client) error {
+	if _, exists := m.clients[client.ID]; !exists {
+		return errors.New("клиент не найден")
+	}
+	m.clients[client.ID] = client
+	return nil
+}
+
+func (m *MockProjectRepo) DeleteClient(ctx context.Context, id uint) error {
+	delete(m.clients, id)
+	return nil
+}
+
+func (m *MockProjectRepo) CreateProject(ctx context.Context, project *models.Project) error {
+	project.ID = m.nextID
+	project.CreatedAt = time.Now()
+	project.UpdatedAt = time.Now()
+	m.projects[project.ID] = project
+	m.nextID++
+	return nil
+}
+
+func (m *MockProjectRepo) GetProjectByID(ctx context.Context, id uint) (*models.Project, error) {
+	project, exists := m.projects[id]
+	if !exists {
+		return nil, errors.New("проект не найден")
+	}
+	return project, nil
+}
+
+func (m *MockProjectRepo) GetProjectsByUserID(ctx context.Context, userID uint) ([]*models.Project, error) {
+	var result []*models.Project
+	for _, project := range m.projects {
+		if project.UserID == userID {
+			result = append(result, project)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockProjectRepo) UpdateProject(ctx context.Context, project *models.Project) error {
+	if _, exists := m.projects[project.ID]; !exists {
+		return errors.New("проект не найден")
+	}
+	m.projects[project.ID] = project
+	return nil
+}
+
+func (m *MockProjectRepo) DeleteProject(ctx context.Context, id uint) error {
+	delete(m.projects, id)
+	return nil
+}
+
+func (m *MockProjectRepo) SetProjectCategories(ctx context.Context, projectID uint, categoryIDs []uint) error {
+	project, exists := m.projects[projectID]
+	if !exists {
+		return errors.New("проект не найден")
+	}
+	project.CategoryIDs = append([]uint{}, categoryIDs...)
+	return nil
+}
+
+func (m *MockProjectRepo) WithTx(ctx context.Context, fn func(repo database.Repository) error) error {
+	return fn(m)
+}
+
+func (m *MockProjectRepo) GetCategoriesByUserID(ctx context.Context, userID uint) ([]*models.Category, error) {
+	var result []*models.Category
+	for _, category := range m.categories {
+		if category.UserID == userID {
+			result = append(result, category)
+		}
+	}
+	return result, nil
+}
+
+// Тесты
+
+func TestClients(t *testing.T) {
+	repo := NewMockProjectRepo()
+	service := NewService(repo)
+	ctx := context.Background()
+
+	rate := models.Money(850000)
+	client, err := service.CreateClient(ctx, 1, ClientInput{Name: " ООО Ромашка ", HourlyRate: &rate})
+	if err != nil {
+		t.Fatalf("Ошибка при создании клиента: %v", err)
+	}
+	if client.Name != "ООО Ромашка" || client.HourlyRate.String() != "8500.00" {
+		t.Errorf("Получен клиент %+v", client)
+	}
+
+	// Пустое название и отрицательная ставка
+	if _, err := service.CreateClient(ctx, 1, ClientInput{Name: " "}); !errors.Is(err, ErrEmptyName) {
+		t.Errorf("Ожидалась ошибка ErrEmptyName, получено %v", err)
+	}
+	negative := models.Money(-1)
+	if _, err := service.CreateClient(ctx, 1, ClientInput{Name: "Клиент", HourlyRate: &negative}); !errors.Is(err, ErrInvalidRate) {
+		t.Errorf("Ожидалась ошибка ErrInvalidRate, получено %v", err)
+	}
+
+	// Архивный клиент скрыт из списка по умолчанию
+	if _, err := service.UpdateClient(ctx, client.ID, 1, ClientInput{Name: client.Name, Archived: true}); err != nil {
+		t.Fatalf("Ошибка при архивировании клиента: %v", err)
+	}
+	clients, _ := service.GetClientsByUserID(ctx, 1, false)
+	if len(clients) != 0 {
+		t.Errorf("Ожидалось 0 активных клиентов, получено %d", len(clients))
+	}
+	clients, _ = service.GetClientsByUserID(ctx, 1, true)
+	if len(clients) != 1 {
+		t.Errorf("Ожидался 1 клиент с учетом архива, получено %d", len(clients))
+	}
+
+	// Чужой клиент
+	if err := service.DeleteClient(ctx, client.ID, 2); !errors.Is(err, ErrNotAuthorized) {
+		t.Errorf("Ожидалась ошибка ErrNotAuthorized, получено %v", err)
+	}
+}
+
+func TestCreateProject(t *testing.T) {
+	repo := NewMockProjectRepo()
+	service := NewService(repo)
+	ctx := context.Background()
+
+	repo.categories = []*models.Category{
+		{ID: 10, UserID: 1, Name: "Разработка"},
+		{ID: 11, UserID: 2, Name: "Чужая"},
+	}
+	client, _ := service.CreateClient(ctx, 1, ClientInput{Name: "Клиент"})
+	foreignClient, _ := service.CreateClient(ctx, 2, ClientInput{Name: "Чужой клиент"})
+
+	budget := 40.0
+	project, err := service.CreateProject(ctx, 1, ProjectInput{
+		ClientID:    &client.ID,
+		Name:        "Сайт",
+		BudgetHours: &budget,
+		CategoryIDs: []uint{10},
+	})
+	if err != nil {
+		t.Fatalf("Ошибка при создании проекта: %v", err)
+	}
+	if project.Color != DefaultColor {
+		t.Errorf("Ожидался цвет по умолчанию '%s', получено '%s'", DefaultColor, project.Color)
+	}
+	if len(repo.projects[project.ID].CategoryIDs) != 1 {
+		t.Errorf("Категории проекта не сохранены: %v", repo.projects[project.ID].CategoryIDs)
+	}
+
+	tests := []struct {
+		name  string
+		input ProjectInput
+		want  error
+	}{
+		{"Чужой клиент", ProjectInput{Name: "П", ClientID: &foreignClient.ID}, ErrNotAuthorized},
+		{"Чужая категория", ProjectInput{Name: "П", CategoryIDs: []uint{11}}, ErrCategoryNotOwned},
+		{"Нулевой бюджет", ProjectInput{Name: "П", BudgetHours: new(float64)}, ErrInvalidBudget},
+		{"Пустое название", ProjectInput{}, ErrEmptyName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.CreateProject(ctx, 1, tt.input); !errors.Is(err, tt.want) {
+				t.Errorf("Ожидалась ошибка %v, получено %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestUpdateProject(t *testing.T) {
+	repo := NewMockProjectRepo()
+	service := NewService(repo)
+	ctx := context.Background()
+
+	repo.categories = []*models.Category{{ID: 10, UserID: 1, Name: "Разработка"}}
+	project, _ := service.CreateProject(ctx, 1, ProjectInput{Name: "Сайт", Color: "#123456", CategoryIDs: []uint{10}})
+
+	// Пустой цвет сохраняет текущий, пустой список категорий снимает ограничение
+	updated, err := service.UpdateProject(ctx, project.ID, 1, ProjectInput{Name: "Сайт 2.0", Archived: true})
+	if err != nil {
+		t.Fatalf("Ошибка при обновлении проекта: %v", err)
+	}
+	if updated.Color != "#123456" || !updated.Archived || len(updated.CategoryIDs) != 0 {
+		t.Errorf("Получен проект %+v", updated)
+	}
+
+	if _, err := service.UpdateProject(ctx, 999, 1, ProjectInput{Name: "Нет"}); !errors.Is(err, ErrProjectNotFound) {
+		t.Errorf("Ожидалась ошибка ErrProjectNotFound, получено %v", err)
+	}
+	if err := service.DeleteProject(ctx, project.ID, 2); !errors.Is(err, ErrNotAuthorized) {
+		t.Errorf("Ожидалась ошибка ErrNotAuthorized, получено %v", err)
+	}
+}
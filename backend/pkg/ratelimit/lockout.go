@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// FailureStore хранит счетчики неудачных попыток. Реализация должна быть безопасна
+// для одновременного использования.
+type FailureStore interface {
+	// AddFailure увеличивает счетчик key и возвращает его новое значение. Счетчик
+	// забывается, если до момента expires не было новых неудачных попыток.
+	AddFailure(ctx context.Context, key string, now, expires time.Time) (int, error)
+	// Failures возвращает значение счетчика key и время последней неудачной попытки
+	Failures(ctx context.Context, key string, now time.Time) (int, time.Time, error)
+	// ResetFailures сбрасывает счетчик key
+	ResetFailures(ctx context.Context, key string) error
+}
+
+// LockoutPolicy - правила блокировки после неудачных попыток
+type LockoutPolicy struct {
+	// Threshold - число неудачных попыток подряд, после которого включается блокировка (0 отключает)
+	Threshold int
+	// Base - длительность первой блокировки; каждая следующая неудачная попытка удваивает ее
+	Base time.Duration
+	// Max - наибольшая длительность блокировки (не меньше Base). Счетчик попыток забывается
+	// через 2*Max после последней неудачной попытки.
+	Max time.Duration
+}
+
+// Enabled сообщает, действует ли блокировка
+func (p LockoutPolicy) Enabled() bool {
+	return p.Threshold > 0 && p.Base > 0
+}
+
+// max возвращает наибольшую длительность блокировки
+func (p LockoutPolicy) max() time.Duration {
+	if p.Max < p.Base {
+		return p.Base
+	}
+	return p.Max
+}
+
+// duration возвращает длительность блокировки после failures неудачных попыток подряд
+func (p LockoutPolicy) duration(failures int) time.Duration {
+	if failures < p.Threshold {
+		return 0
+	}
+
+	d := p.Base
+	for i := p.Threshold; i < failures && d < p.max(); i++ {
+		d *= 2
+	}
+	if d > p.max() {
+		d = p.max()
+	}
+	return d
+}
+
+// Lockout блокирует попытки входа в учетную запись после серии неудачных попыток,
+// с каждой следующей неудачей на все больший срок
+type Lockout struct {
+	store  FailureStore
+	policy LockoutPolicy
+}
+
+// NewLockout создает блокировку с правилами policy
+func NewLockout(store FailureStore, policy LockoutPolicy) *Lockout {
+	return &Lockout{
+		store:  store,
+		policy: policy,
+	}
+}
+
+// Check возвращает оставшееся время блокировки key или 0, если попытка разрешена
+func (l *Lockout) Check(ctx context.Context, key string, now time.Time) (time.Duration, error) {
+	if !l.policy.Enabled() {
+		return 0, nil
+	}
+
+	failures, last, err := l.store.Failures(ctx, key, now)
+	if err != nil {
+		return 0, err
+	}
+
+	if until := last.Add(l.policy.duration(failures)); until.After(now) {
+		return until.Sub(now), nil
+	}
+	return 0, nil
+}
+
+// Fail учитывает неудачную попытку и возвращает длительность наступившей блокировки
+// или 0, если порог еще не достигнут
+func (l *Lockout) Fail(ctx context.Context, key string, now time.Time) (time.Duration, error) {
+	if !l.policy.Enabled() {
+		return 0, nil
+	}
+
+	// Счетчик должен пережить самую долгую блокировку, иначе после нее порог начнется заново
+	failures, err := l.store.AddFailure(ctx, key, now, now.Add(2*l.policy.max()))
+	if err != nil {
+		return 0, err
+	}
+	return l.policy.duration(failures), nil
+}
+
+// Reset сбрасывает счетчик после успешного входа
+func (l *Lockout) Reset(ctx context.Context, key string) error {
+	if !l.policy.Enabled() {
+		return nil
+	}
+	return l.store.ResetFailures(ctx, key)
+}
@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// bucket - корзина разрешений одного клиента
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+// refill пополняет корзину разрешениями, восстановившимися к моменту now
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens += float64(elapsed) / float64(b.limit.interval())
+		if b.tokens > float64(b.limit.Requests) {
+			b.tokens = float64(b.limit.Requests)
+		}
+		b.updated = now
+	}
+}
+
+// failures - счетчик неудачных попыток
+type failures struct {
+	count   int
+	last    time.Time
+	expires time.Time
+}
+
+// MemoryStore хранит корзины и счетчики в памяти процесса. Подходит для одного экземпляра
+// сервера: экземпляры не видят попыток, сделанных через другие экземпляры.
+type MemoryStore struct {
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	failures map[string]*failures
+}
+
+// NewMemoryStore создает хранилище в памяти
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:  make(map[string]*bucket),
+		failures: make(map[string]*failures),
+	}
+}
+
+// Take реализует Store
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok || b.limit != limit {
+		b = &bucket{tokens: float64(limit.Requests), updated: now, limit: limit}
+		s.buckets[key] = b
+	}
+	b.refill(now)
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+
+	retryAfter := time.Duration((1 - b.tokens) * float64(limit.interval()))
+	return false, retryAfter, nil
+}
+
+// AddFailure реализует FailureStore
+func (s *MemoryStore) AddFailure(ctx context.Context, key string, now, expires time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.failures[key]
+	if !ok || !now.Before(f.expires) {
+		f = &failures{}
+		s.failures[key] = f
+	}
+	f.count++
+	f.last = now
+	f.expires = expires
+	return f.count, nil
+}
+
+// Failures реализует FailureStore
+func (s *MemoryStore) Failures(ctx context.Context, key string, now time.Time) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.failures[key]
+	if !ok || !now.Before(f.expires) {
+		return 0, time.Time{}, nil
+	}
+	return f.count, f.last, nil
+}
+
+// ResetFailures реализует FailureStore
+func (s *MemoryStore) ResetFailures(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.failures, key)
+	return nil
+}
+
+// Cleanup удаляет полные корзины и забытые счетчики, чтобы память не росла с числом клиентов.
+// Возвращает число удаленных записей.
+func (s *MemoryStore) Cleanup(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := 0
+	for key, b := range s.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit.Requests) {
+			delete(s.buckets, key)
+			deleted++
+		}
+	}
+	for key, f := range s.failures {
+		if !now.Before(f.expires) {
+			delete(s.failures, key)
+			deleted++
+		}
+	}
+	return deleted
+}
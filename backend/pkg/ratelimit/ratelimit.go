@@ -0,0 +1,102 @@
+// Package ratelimit ограничивает частоту запросов (алгоритм token bucket) и блокирует вход
+// после серии неудачных попыток. Состояние хранится в Store и FailureStore: MemoryStore подходит
+// для одного экземпляра сервера, несколько экземпляров должны использовать общее хранилище.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidLimit возникает, если ограничение задано в неверном формате
+var ErrInvalidLimit = errors.New("неверный формат ограничения, ожидается N/интервал, например 10/1m")
+
+// Limit - ограничение частоты запросов: не больше Requests запросов подряд,
+// после чего разрешения восстанавливаются равномерно, Requests за интервал Per
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// Enabled сообщает, действует ли ограничение
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Per > 0
+}
+
+// String возвращает ограничение в формате ParseLimit
+func (l Limit) String() string {
+	if !l.Enabled() {
+		return "0"
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+// interval возвращает время восстановления одного разрешения
+func (l Limit) interval() time.Duration {
+	return l.Per / time.Duration(l.Requests)
+}
+
+// ParseLimit разбирает ограничение вида "10/1m" (10 запросов в минуту). Пустая строка или "0"
+// отключают ограничение.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" {
+		return Limit{}, nil
+	}
+
+	requests, per, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("%w: %q", ErrInvalidLimit, s)
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("%w: %q", ErrInvalidLimit, s)
+	}
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 || d/time.Duration(n) <= 0 {
+		return Limit{}, fmt.Errorf("%w: %q", ErrInvalidLimit, s)
+	}
+
+	return Limit{Requests: n, Per: d}, nil
+}
+
+// Store хранит корзины разрешений. Реализация должна быть безопасна для одновременного использования.
+type Store interface {
+	// Take забирает разрешение из корзины key. Если корзина пуста, возвращает allowed = false
+	// и время, через которое появится следующее разрешение.
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// Limiter ограничивает частоту запросов в одной группе маршрутов
+type Limiter struct {
+	store Store
+	name  string
+	limit Limit
+}
+
+// NewLimiter создает ограничитель. name отделяет корзины группы от корзин других ограничителей
+// в общем хранилище.
+func NewLimiter(store Store, name string, limit Limit) *Limiter {
+	return &Limiter{
+		store: store,
+		name:  name,
+		limit: limit,
+	}
+}
+
+// Name возвращает название группы ограничителя
+func (l *Limiter) Name() string {
+	return l.name
+}
+
+// Allow забирает разрешение для клиента key. Если ограничение отключено, запрос всегда разрешен.
+func (l *Limiter) Allow(ctx context.Context, key string, now time.Time) (bool, time.Duration, error) {
+	if !l.limit.Enabled() {
+		return true, 0, nil
+	}
+	return l.store.Take(ctx, l.name+":"+key, l.limit, now)
+}
@@ -0,0 +1,181 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Limit
+		err   bool
+	}{
+		{"10/1m", Limit{Requests: 10, Per: time.Minute}, false},
+		{" 5/30s ", Limit{Requests: 5, Per: 30 * time.Second}, false},
+		{"", Limit{}, false},
+		{"0", Limit{}, false},
+		{"10", Limit{}, true},
+		{"0/1m", Limit{}, true},
+		{"-1/1m", Limit{}, true},
+		{"10/минута", Limit{}, true},
+		{"10/0s", Limit{}, true},
+		{"1000/1ns", Limit{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.input)
+		if (err != nil) != tt.err {
+			t.Errorf("ParseLimit(%q) error = %v, ожидалась ошибка: %v", tt.input, err, tt.err)
+			continue
+		}
+		if err != nil && !errors.Is(err, ErrInvalidLimit) {
+			t.Errorf("ParseLimit(%q) error = %v, хотели %v", tt.input, err, ErrInvalidLimit)
+		}
+		if got != tt.want {
+			t.Errorf("ParseLimit(%q) = %+v, хотели %+v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	limiter := NewLimiter(store, "login", Limit{Requests: 3, Per: time.Minute})
+	now := time.Now()
+
+	// Запросы подряд разрешены до исчерпания корзины
+	for i := 0; i < 3; i++ {
+		if allowed, _, err := limiter.Allow(ctx, "192.0.2.1", now); err != nil || !allowed {
+			t.Fatalf("Allow() #%d = %v, %v, хотели разрешение", i+1, allowed, err)
+		}
+	}
+	allowed, retryAfter, err := limiter.Allow(ctx, "192.0.2.1", now)
+	if err != nil || allowed {
+		t.Fatalf("Allow() после исчерпания = %v, %v, хотели отказ", allowed, err)
+	}
+	if retryAfter != 20*time.Second {
+		t.Errorf("retryAfter = %v, хотели 20s", retryAfter)
+	}
+
+	// Другой клиент и другая группа не затронуты
+	if allowed, _, _ := limiter.Allow(ctx, "192.0.2.2", now); !allowed {
+		t.Error("Ограничение одного клиента не должно затрагивать другого")
+	}
+	other := NewLimiter(store, "register", Limit{Requests: 1, Per: time.Minute})
+	if allowed, _, _ := other.Allow(ctx, "192.0.2.1", now); !allowed {
+		t.Error("Корзины групп не должны пересекаться")
+	}
+
+	// Разрешения восстанавливаются равномерно
+	if allowed, _, _ := limiter.Allow(ctx, "192.0.2.1", now.Add(20*time.Second)); !allowed {
+		t.Error("Через 20 секунд должно восстановиться одно разрешение")
+	}
+	if allowed, _, _ := limiter.Allow(ctx, "192.0.2.1", now.Add(21*time.Second)); allowed {
+		t.Error("Восстановилось больше разрешений, чем положено")
+	}
+
+	// Отключенное ограничение пропускает все запросы
+	disabled := NewLimiter(store, "api", Limit{})
+	for i := 0; i < 100; i++ {
+		if allowed, _, _ := disabled.Allow(ctx, "192.0.2.1", now); !allowed {
+			t.Fatal("Отключенное ограничение не должно отклонять запросы")
+		}
+	}
+}
+
+func TestLockout(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	lockout := NewLockout(store, LockoutPolicy{Threshold: 3, Base: time.Minute, Max: 4 * time.Minute})
+	now := time.Now()
+
+	check := func(at time.Time) time.Duration {
+		t.Helper()
+		wait, err := lockout.Check(ctx, "user@example.com", at)
+		if err != nil {
+			t.Fatalf("Check() error = %v", err)
+		}
+		return wait
+	}
+	fail := func(at time.Time) time.Duration {
+		t.Helper()
+		lock, err := lockout.Fail(ctx, "user@example.com", at)
+		if err != nil {
+			t.Fatalf("Fail() error = %v", err)
+		}
+		return lock
+	}
+
+	// До порога попытки не блокируются
+	for i := 0; i < 2; i++ {
+		if lock := fail(now); lock != 0 {
+			t.Fatalf("Fail() #%d = %v, хотели 0", i+1, lock)
+		}
+	}
+	if wait := check(now); wait != 0 {
+		t.Fatalf("Check() до порога = %v, хотели 0", wait)
+	}
+
+	// Каждая следующая неудача удваивает блокировку до Max
+	wants := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 4 * time.Minute}
+	for _, want := range wants {
+		if lock := fail(now); lock != want {
+			t.Errorf("Fail() = %v, хотели %v", lock, want)
+		}
+		if wait := check(now.Add(30 * time.Second)); wait != want-30*time.Second {
+			t.Errorf("Check() = %v, хотели %v", wait, want-30*time.Second)
+		}
+		now = now.Add(want)
+	}
+	if wait := check(now); wait != 0 {
+		t.Errorf("Check() после окончания блокировки = %v, хотели 0", wait)
+	}
+
+	// Успешный вход сбрасывает счетчик
+	if err := lockout.Reset(ctx, "user@example.com"); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if lock := fail(now); lock != 0 {
+		t.Errorf("Fail() после сброса = %v, хотели 0", lock)
+	}
+
+	// Счетчик забывается через 2*Max после последней неудачи
+	fail(now)
+	if lock := fail(now.Add(8 * time.Minute)); lock != 0 {
+		t.Errorf("Fail() после долгого перерыва = %v, хотели 0", lock)
+	}
+
+	// Отключенная блокировка ничего не учитывает
+	disabled := NewLockout(store, LockoutPolicy{})
+	for i := 0; i < 10; i++ {
+		if lock, _ := disabled.Fail(ctx, "other@example.com", now); lock != 0 {
+			t.Fatal("Отключенная блокировка не должна срабатывать")
+		}
+	}
+}
+
+func TestMemoryStoreCleanup(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	limit := Limit{Requests: 2, Per: time.Minute}
+	now := time.Now()
+
+	store.Take(ctx, "a", limit, now)
+	store.Take(ctx, "b", limit, now)
+	store.Take(ctx, "b", limit, now)
+	store.AddFailure(ctx, "user@example.com", now, now.Add(time.Hour))
+
+	// Корзина "a" восстановилась, "b" еще нет
+	if deleted := store.Cleanup(now.Add(30 * time.Second)); deleted != 1 {
+		t.Errorf("Cleanup() удалил %d записей, хотели 1", deleted)
+	}
+	if deleted := store.Cleanup(now.Add(time.Hour)); deleted != 2 {
+		t.Errorf("Cleanup() удалил %d записей, хотели 2", deleted)
+	}
+	if count, _, _ := store.Failures(ctx, "user@example.com", now); count != 0 {
+		t.Errorf("Счетчик после очистки = %d, хотели 0", count)
+	}
+}
@@ -0,0 +1,278 @@
+package statistics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/graywrk/timetracker/backend/internal/models"
+)
+
+// Режимы округления длительности записи в отчете о заработке
+const (
+	RoundUp      = "up"
+	RoundNearest = "nearest"
+	RoundDown    = "down"
+)
+
+// ErrInvalidRounding возникает при некорректных параметрах округления
+var ErrInvalidRounding = errors.New("некорректные параметры округления")
+
+// EarningsOptions задает параметры расчета отчета о заработке
+type EarningsOptions struct {
+	// RoundingMinutes - шаг округления длительности каждой оплачиваемой записи в минутах (0 - без округления)
+	RoundingMinutes int
+	// RoundingMode - направление округления: up (по умолчанию), nearest или down
+	RoundingMode string
+	// Timezone - часовой пояс IANA для границ дней; пустая строка - часовой пояс из профиля пользователя
+	Timezone string
+}
+
+// EarningsTotals содержит оплачиваемую и неоплачиваемую длительность и сумму к оплате
+type EarningsTotals struct {
+	BillableDuration    int64        `json:"billable_duration"`     // в секундах, после округления
+	NonBillableDuration int64        `json:"non_billable_duration"` // в секундах
+	UnratedDuration     int64        `json:"unrated_duration"`      // оплачиваемое время без ставки, в секундах
+	Amount              models.Money `json:"amount"`
+
+	// amount - сумма в копейко-секундах (ставка в копейках за час, умноженная на секунды).
+	// Накопление в целых числах сохраняет точность; в копейки сумма переводится один раз в конце.
+	amount int64
+}
+
+// CategoryEarnings содержит итоги по одной категории; CategoryID = nil - записи без категории
+type CategoryEarnings struct {
+	CategoryID *uint  `json:"category_id"`
+	Name       string `json:"name"`
+	EarningsTotals
+}
+
+// EarningsReport содержит отчет об оплачиваемом времени и заработке за период
+type EarningsReport struct {
+	StartDate       string                    `json:"start_date"`
+	EndDate         string                    `json:"end_date"`
+	RoundingMinutes int                       `json:"rounding_minutes"`
+	RoundingMode    string                    `json:"rounding_mode"`
+	Total           EarningsTotals            `json:"total"`
+	Daily           map[string]EarningsTotals `json:"daily"` // день -> итоги
+	Categories      []CategoryEarnings        `json:"categories"`
+}
+
+// GetEarningsReport рассчитывает оплачиваемое время и заработок за период.
+// Суммы считаются в целых копейках без использования float64; каждая итоговая
+// сумма округляется до копейки половиной вверх независимо от остальных.
+func (s *Service) GetEarningsReport(ctx context.Context, userID uint, startDate, endDate string, opts EarningsOptions) (*EarningsReport, error) {
+	if opts.RoundingMinutes < 0 {
+		return nil, ErrInvalidRounding
+	}
+	if opts.RoundingMode == "" {
+		opts.RoundingMode = RoundUp
+	}
+	if opts.RoundingMode != RoundUp && opts.RoundingMode != RoundNearest && opts.RoundingMode != RoundDown {
+		return nil, ErrInvalidRounding
+	}
+
+	loc, err := s.location(ctx, userID, opts.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	from, to, err := periodBounds(startDate, endDate, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.repo.GetUserStatsByPeriod(ctx, userID, from, to, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rates, err := s.loadRates(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &EarningsReport{
+		StartDate:       startDate,
+		EndDate:         endDate,
+		RoundingMinutes: opts.RoundingMinutes,
+		RoundingMode:    opts.RoundingMode,
+		Daily:           make(map[string]EarningsTotals),
+		Categories:      []CategoryEarnings{},
+	}
+
+	categoryIndex := make(map[uint]int)
+	uncategorized := -1
+
+	for _, entry := range entries {
+		// Для счета запись относится целиком к периоду, в котором она началась,
+		// иначе запись через полночь на границе периодов попала бы в два счета
+		if entry.StartTime.Before(from) {
+			continue
+		}
+
+		duration := entry.CalculateDuration()
+		var amount int64
+		var unrated int64
+		if entry.Billable {
+			duration = roundDuration(duration, opts)
+			if rate := rates.rateFor(entry); rate != nil {
+				amount = int64(*rate) * duration
+			} else {
+				unrated = duration
+			}
+		}
+
+		// Находим строку категории, создавая ее при первой встрече
+		var idx int
+		if entry.CategoryID == nil {
+			if uncategorized < 0 {
+				report.Categories = append(report.Categories, CategoryEarnings{Name: UncategorizedName})
+				uncategorized = len(report.Categories) - 1
+			}
+			idx = uncategorized
+		} else {
+			var ok bool
+			if idx, ok = categoryIndex[*entry.CategoryID]; !ok {
+				row := CategoryEarnings{CategoryID: entry.CategoryID}
+				if category := rates.categories[*entry.CategoryID]; category != nil {
+					row.Name = category.Name
+				}
+				report.Categories = append(report.Categories, row)
+				idx = len(report.Categories) - 1
+				categoryIndex[*entry.CategoryID] = idx
+			}
+		}
+
+		day := entry.StartTime.In(loc).Format("2006-01-02")
+		daily := report.Daily[day]
+		for _, totals := range []*EarningsTotals{&report.Total, &daily, &report.Categories[idx].EarningsTotals} {
+			totals.add(entry.Billable, duration, amount, unrated)
+		}
+		report.Daily[day] = daily
+	}
+
+	// Переводим накопленные суммы в копейки
+	report.Total.finish()
+	for day, daily := range report.Daily {
+		daily.finish()
+		report.Daily[day] = daily
+	}
+	for i := range report.Categories {
+		report.Categories[i].finish()
+	}
+
+	sort.Slice(report.Categories, func(i, j int) bool {
+		if report.Categories[i].Amount != report.Categories[j].Amount {
+			return report.Categories[i].Amount > report.Categories[j].Amount
+		}
+		return report.Categories[i].BillableDuration > report.Categories[j].BillableDuration
+	})
+
+	return report, nil
+}
+
+// add учитывает запись в итогах
+func (t *EarningsTotals) add(billable bool, duration, amount, unrated int64) {
+	if billable {
+		t.BillableDuration += duration
+	} else {
+		t.NonBillableDuration += duration
+	}
+	t.UnratedDuration += unrated
+	t.amount += amount
+}
+
+// finish переводит сумму из копейко-секунд в копейки с округлением половиной вверх
+func (t *EarningsTotals) finish() {
+	t.Amount = models.Money((t.amount + 1800) / 3600)
+}
+
+// roundDuration округляет длительность записи до заданного шага
+func roundDuration(seconds int64, opts EarningsOptions) int64 {
+	if opts.RoundingMinutes == 0 {
+		return seconds
+	}
+
+	step := int64(opts.RoundingMinutes) * 60
+	switch opts.RoundingMode {
+	case RoundDown:
+		return seconds / step * step
+	case RoundNearest:
+		return (seconds + step/2) / step * step
+	default:
+		return (seconds + step - 1) / step * step
+	}
+}
+
+// rateResolver определяет почасовую ставку записи
+type rateResolver struct {
+	user       *models.Money
+	categories map[uint]*models.Category
+	projects   map[uint]*models.Project
+	clients    map[uint]*models.Client
+}
+
+// loadRates загружает ставки пользователя, его категорий, проектов и клиентов
+func (s *Service) loadRates(ctx context.Context, userID uint) (*rateResolver, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	categories, err := s.repo.GetCategoriesByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении категорий: %w", err)
+	}
+	projects, err := s.repo.GetProjectsByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении проектов: %w", err)
+	}
+	clients, err := s.repo.GetClientsByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении клиентов: %w", err)
+	}
+
+	rates := &rateResolver{
+		categories: make(map[uint]*models.Category, len(categories)),
+		projects:   make(map[uint]*models.Project, len(projects)),
+		clients:    make(map[uint]*models.Client, len(clients)),
+	}
+	if user != nil {
+		rates.user = user.HourlyRate
+	}
+	for _, category := range categories {
+		rates.categories[category.ID] = category
+	}
+	for _, project := range projects {
+		rates.projects[project.ID] = project
+	}
+	for _, client := range clients {
+		rates.clients[client.ID] = client
+	}
+
+	return rates, nil
+}
+
+// rateFor возвращает ставку записи по правилу старшинства: ставка проекта,
+// затем клиента проекта, затем категории, затем ставка пользователя по умолчанию.
+// nil означает, что ставка не задана ни на одном уровне.
+func (r *rateResolver) rateFor(entry *models.TimeEntry) *models.Money {
+	if entry.ProjectID != nil {
+		if project := r.projects[*entry.ProjectID]; project != nil {
+			if project.HourlyRate != nil {
+				return project.HourlyRate
+			}
+			if project.ClientID != nil {
+				if client := r.clients[*project.ClientID]; client != nil && client.HourlyRate != nil {
+					return client.HourlyRate
+				}
+			}
+		}
+	}
+	if entry.CategoryID != nil {
+		if category := r.categories[*entry.CategoryID]; category != nil && category.HourlyRate != nil {
+			return category.HourlyRate
+		}
+	}
+	return r.user
+}
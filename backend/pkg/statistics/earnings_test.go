@@ -0,0 +1,188 @@
+package statistics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/internal/models"
+)
+
+func money(value models.Money) *models.Money {
+	return &value
+}
+
+func uintRef(value uint) *uint {
+	return &value
+}
+
+// billableEntry создает завершенную запись заданной длительности
+func billableEntry(id uint, start time.Time, duration time.Duration, categoryID, projectID *uint, billable bool) *models.TimeEntry {
+	return &models.TimeEntry{
+		ID:         id,
+		UserID:     1,
+		StartTime:  start,
+		EndTime:    start.Add(duration),
+		Status:     models.StatusCompleted,
+		CategoryID: categoryID,
+		ProjectID:  projectID,
+		Billable:   billable,
+	}
+}
+
+// TestGetEarningsReport_RatePrecedence проверяет выбор ставки: проект, клиент, категория, пользователь
+func TestGetEarningsReport_RatePrecedence(t *testing.T) {
+	mockRepo := NewMockRepository()
+	service := NewService(mockRepo)
+	day := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	mockRepo.user = &models.User{ID: 1, HourlyRate: money(100000)}
+	mockRepo.categories = []*models.Category{{ID: 1, UserID: 1, Name: "Разработка", HourlyRate: money(150000)}}
+	mockRepo.clients = []*models.Client{{ID: 1, UserID: 1, Name: "ООО Ромашка", HourlyRate: money(200000)}}
+	mockRepo.projects = []*models.Project{
+		{ID: 1, UserID: 1, Name: "Сайт", ClientID: uintRef(1)},
+		{ID: 2, UserID: 1, Name: "Приложение", ClientID: uintRef(1), HourlyRate: money(300000)},
+	}
+	mockRepo.SetEntries([]*models.TimeEntry{
+		billableEntry(1, day, time.Hour, uintRef(1), uintRef(2), true),                      // ставка проекта: 3000.00
+		billableEntry(2, day.Add(time.Hour), 30*time.Minute, uintRef(1), uintRef(1), true),  // ставка клиента: 1000.00
+		billableEntry(3, day.Add(2*time.Hour), 20*time.Minute, uintRef(1), nil, true),       // ставка категории: 500.00
+		billableEntry(4, day.Add(3*time.Hour), 10*time.Minute, nil, nil, true),              // ставка пользователя: 166.67
+		billableEntry(5, day.Add(4*time.Hour), time.Hour, uintRef(1), nil, false),           // не оплачивается
+		billableEntry(6, day.AddDate(0, 0, 1), 6*time.Minute, uintRef(1), uintRef(2), true), // следующий день: 300.00
+	})
+
+	report, err := service.GetEarningsReport(context.Background(), 1, "2025-03-10", "2025-03-11", EarningsOptions{})
+	if err != nil {
+		t.Fatalf("GetEarningsReport() error = %v", err)
+	}
+
+	if report.Total.Amount != 496667 {
+		t.Errorf("Total.Amount = %s, хотели 4966.67", report.Total.Amount)
+	}
+	if report.Total.BillableDuration != 7560 {
+		t.Errorf("Total.BillableDuration = %d, хотели 7560", report.Total.BillableDuration)
+	}
+	if report.Total.NonBillableDuration != 3600 {
+		t.Errorf("Total.NonBillableDuration = %d, хотели 3600", report.Total.NonBillableDuration)
+	}
+	if got := report.Daily["2025-03-10"].Amount; got != 466667 {
+		t.Errorf("Daily[2025-03-10].Amount = %s, хотели 4666.67", got)
+	}
+	if got := report.Daily["2025-03-11"].Amount; got != 30000 {
+		t.Errorf("Daily[2025-03-11].Amount = %s, хотели 300.00", got)
+	}
+
+	if len(report.Categories) != 2 {
+		t.Fatalf("Categories содержит %d строк, хотели 2", len(report.Categories))
+	}
+	if got := report.Categories[0]; got.CategoryID == nil || *got.CategoryID != 1 || got.Amount != 480000 || got.NonBillableDuration != 3600 {
+		t.Errorf("Categories[0] = %+v, хотели Разработка: 4800.00", got)
+	}
+	if got := report.Categories[1]; got.CategoryID != nil || got.Amount != 16667 {
+		t.Errorf("Categories[1] = %+v, хотели Без категории: 166.67", got)
+	}
+}
+
+// TestGetEarningsReport_Rounding проверяет округление длительности каждой записи
+func TestGetEarningsReport_Rounding(t *testing.T) {
+	day := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		opts     EarningsOptions
+		duration int64
+	}{
+		{"без округления", EarningsOptions{}, 1320 + 480},
+		{"вверх по умолчанию", EarningsOptions{RoundingMinutes: 15}, 1800 + 900},
+		{"до ближайшего", EarningsOptions{RoundingMinutes: 15, RoundingMode: RoundNearest}, 1800 + 0},
+		{"вниз", EarningsOptions{RoundingMinutes: 15, RoundingMode: RoundDown}, 900 + 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := NewMockRepository()
+			service := NewService(mockRepo)
+			mockRepo.user = &models.User{ID: 1, HourlyRate: money(360000)}
+			mockRepo.SetEntries([]*models.TimeEntry{
+				billableEntry(1, day, 22*time.Minute, nil, nil, true),
+				billableEntry(2, day.Add(time.Hour), 8*time.Minute, nil, nil, true),
+				// Неоплачиваемые записи не округляются
+				billableEntry(3, day.Add(2*time.Hour), 8*time.Minute, nil, nil, false),
+			})
+
+			report, err := service.GetEarningsReport(context.Background(), 1, "2025-03-10", "2025-03-10", tt.opts)
+			if err != nil {
+				t.Fatalf("GetEarningsReport() error = %v", err)
+			}
+			if report.Total.BillableDuration != tt.duration {
+				t.Errorf("BillableDuration = %d, хотели %d", report.Total.BillableDuration, tt.duration)
+			}
+			if report.Total.NonBillableDuration != 480 {
+				t.Errorf("NonBillableDuration = %d, хотели 480", report.Total.NonBillableDuration)
+			}
+			// Ставка 3600.00 в час - ровно 1.00 в секунду
+			if report.Total.Amount != models.Money(tt.duration*100) {
+				t.Errorf("Amount = %s, хотели %d.00", report.Total.Amount, tt.duration)
+			}
+		})
+	}
+
+	service := NewService(NewMockRepository())
+	for _, opts := range []EarningsOptions{{RoundingMinutes: -5}, {RoundingMinutes: 15, RoundingMode: "sideways"}} {
+		if _, err := service.GetEarningsReport(context.Background(), 1, "2025-03-10", "2025-03-10", opts); err != ErrInvalidRounding {
+			t.Errorf("GetEarningsReport(%+v) error = %v, хотели ErrInvalidRounding", opts, err)
+		}
+	}
+}
+
+// TestGetEarningsReport_ExactAmounts проверяет, что сумма округляется один раз, а не по каждой записи
+func TestGetEarningsReport_ExactAmounts(t *testing.T) {
+	mockRepo := NewMockRepository()
+	service := NewService(mockRepo)
+	day := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	// 33.33 в час за минуту - 0.5555, округление каждой записи дало бы 0.56 * 3 = 1.68
+	mockRepo.user = &models.User{ID: 1, HourlyRate: money(3333)}
+	mockRepo.SetEntries([]*models.TimeEntry{
+		billableEntry(1, day, time.Minute, nil, nil, true),
+		billableEntry(2, day.Add(time.Hour), time.Minute, nil, nil, true),
+		billableEntry(3, day.Add(2*time.Hour), time.Minute, nil, nil, true),
+	})
+
+	report, err := service.GetEarningsReport(context.Background(), 1, "2025-03-10", "2025-03-10", EarningsOptions{})
+	if err != nil {
+		t.Fatalf("GetEarningsReport() error = %v", err)
+	}
+	if report.Total.Amount != 167 {
+		t.Errorf("Total.Amount = %s, хотели 1.67", report.Total.Amount)
+	}
+}
+
+// TestGetEarningsReport_Unrated проверяет учет оплачиваемого времени без ставки
+func TestGetEarningsReport_Unrated(t *testing.T) {
+	mockRepo := NewMockRepository()
+	service := NewService(mockRepo)
+	day := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	mockRepo.user = &models.User{ID: 1}
+	mockRepo.categories = []*models.Category{{ID: 1, UserID: 1, Name: "Консультации", HourlyRate: money(100000)}}
+	mockRepo.SetEntries([]*models.TimeEntry{
+		billableEntry(1, day, time.Hour, uintRef(1), nil, true),
+		billableEntry(2, day.Add(2*time.Hour), 45*time.Minute, nil, nil, true),
+	})
+
+	report, err := service.GetEarningsReport(context.Background(), 1, "2025-03-10", "2025-03-10", EarningsOptions{})
+	if err != nil {
+		t.Fatalf("GetEarningsReport() error = %v", err)
+	}
+	if report.Total.Amount != 100000 {
+		t.Errorf("Total.Amount = %s, хотели 1000.00", report.Total.Amount)
+	}
+	if report.Total.UnratedDuration != 2700 {
+		t.Errorf("Total.UnratedDuration = %d, хотели 2700", report.Total.UnratedDuration)
+	}
+	if report.Total.BillableDuration != 6300 {
+		t.Errorf("Total.BillableDuration = %d, хотели 6300", report.Total.BillableDuration)
+	}
+}
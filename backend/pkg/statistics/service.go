@@ -3,13 +3,25 @@ package statistics
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
+	"sort"
 	"time"
 
 	"github.com/graywrk/timetracker/backend/internal/models"
 	"github.com/graywrk/timetracker/backend/pkg/database"
 )
 
+var (
+	// ErrInvalidTimezone возникает при указании неизвестного часового пояса
+	ErrInvalidTimezone = errors.New("неизвестный часовой пояс")
+	// ErrInvalidPeriod возникает при некорректных датах периода
+	ErrInvalidPeriod = errors.New("некорректный период: ожидаются даты в формате YYYY-MM-DD")
+)
+
+// timeNow возвращает текущее время; подменяется в тестах
+var timeNow = time.Now
+
 // Service предоставляет методы для работы со статистикой
 type Service struct {
 	repo database.Repository
@@ -22,23 +34,93 @@ func NewService(repo database.Repository) *Service {
 	}
 }
 
-// TimeStats содержит статистику по времени
+// TimeStats содержит статистику по времени.
+// Запись, пересекающая полночь в часовом поясе пользователя, делится между днями: каждому дню
+// достается его часть записи за вычетом перерывов, пришедшихся на этот день. Поэтому сумма
+// DailyStats равна TotalDuration, а запись, начатая накануне периода, учитывается своей частью
+// внутри периода. LongestSession, напротив, описывает запись целиком.
 type TimeStats struct {
-	TotalDuration      int64               `json:"total_duration"`      // в секундах
-	DailyStats         map[string]int64    `json:"daily_stats"`         // день -> длительность в секундах
-	AverageDailyHours  float64             `json:"average_daily_hours"` // среднее количество часов в день
-	LongestSessionDate string              `json:"longest_session_date"`
-	LongestSession     int64               `json:"longest_session"`        // в секундах
-	Entries            []*models.TimeEntry `json:"entries"`                // все записи за период
-	ActiveEntry        *models.TimeEntry   `json:"active_entry,omitempty"` // текущая активная запись
+	Timezone           string                `json:"timezone"`             // часовой пояс, в котором считаются дни
+	TotalDuration      int64                 `json:"total_duration"`       // в секундах, только время внутри периода
+	DailyStats         map[string]int64      `json:"daily_stats"`          // день -> отработанное за этот день время в секундах
+	AverageDailyHours  float64               `json:"average_daily_hours"`  // среднее количество часов в день
+	LongestSessionDate string                `json:"longest_session_date"` // день начала самой длинной сессии
+	LongestSession     int64                 `json:"longest_session"`      // длительность самой длинной записи целиком, в секундах
+	DailyBreaks        map[string]BreakStats `json:"daily_breaks"`         // день -> перерывы
+	TotalBreaks        int                   `json:"total_breaks"`         // количество перерывов
+	TotalBreakTime     int64                 `json:"total_break_time"`     // в секундах
+	TagStats           []TagStat             `json:"tag_stats"`            // длительность по меткам
+	// Provisional = true, если в итоги включена незавершенная запись (include_running): значения
+	// верны на момент расчета и будут расти, пока запись активна. RunningDuration - ее вклад в TotalDuration.
+	Provisional        bool                      `json:"provisional"`
+	RunningDuration    int64                     `json:"running_duration"`       // в секундах
+	CategoryStats      []CategoryStat            `json:"category_stats"`         // длительность и доля по категориям за период
+	DailyCategoryStats map[string][]CategoryStat `json:"daily_category_stats"`   // день -> длительность и доля по категориям
+	Entries            []*models.TimeEntry       `json:"entries"`                // все записи за период
+	ActiveEntry        *models.TimeEntry         `json:"active_entry,omitempty"` // текущая активная запись
+}
+
+// BreakStats содержит статистику перерывов за день
+type BreakStats struct {
+	Count    int   `json:"count"`    // количество перерывов (только для записей с интервалами перерывов)
+	Duration int64 `json:"duration"` // длительность перерывов в секундах
+}
+
+// TagStat содержит суммарную длительность записей с меткой.
+// Запись с несколькими метками учитывается в каждой из них.
+type TagStat struct {
+	TagID      uint   `json:"tag_id"`
+	Name       string `json:"name"`
+	Color      string `json:"color"`
+	Duration   int64  `json:"duration"` // в секундах
+	EntryCount int    `json:"entry_count"`
+}
+
+// CategoryStat содержит суммарную длительность записей категории и ее долю в общем времени.
+// CategoryID = nil - записи без категории.
+type CategoryStat struct {
+	CategoryID *uint   `json:"category_id"`
+	Name       string  `json:"name"`
+	Color      string  `json:"color"`
+	Duration   int64   `json:"duration"` // в секундах
+	EntryCount int     `json:"entry_count"`
+	Percent    float64 `json:"percent"` // доля в общей длительности за период (в матрице по дням - за день)
+}
+
+// UncategorizedName - название для записей без категории в статистике и отчетах
+const UncategorizedName = "Без категории"
+
+// StatsOptions содержит дополнительные параметры расчета статистики
+type StatsOptions struct {
+	// TagIDs ограничивает статистику записями, имеющими хотя бы одну из меток
+	TagIDs []uint
+	// Timezone - часовой пояс IANA для границ дней; пустая строка - часовой пояс из профиля пользователя
+	Timezone string
+	// IncludeRunning добавляет в итоги текущую длительность активной или приостановленной записи
+	IncludeRunning bool
 }
 
 // GetUserStats возвращает статистику по пользователю за указанный период
 func (s *Service) GetUserStats(ctx context.Context, userID uint, startDate, endDate string) (*TimeStats, error) {
-	log.Printf("Service.GetUserStats: Запрос статистики для userID=%d, startDate=%s, endDate=%s", userID, startDate, endDate)
+	return s.GetUserStatsWithOptions(ctx, userID, startDate, endDate, StatsOptions{})
+}
+
+// GetUserStatsWithOptions возвращает статистику по пользователю за указанный период с учетом параметров
+func (s *Service) GetUserStatsWithOptions(ctx context.Context, userID uint, startDate, endDate string, opts StatsOptions) (*TimeStats, error) {
+	log.Printf("Service.GetUserStats: Запрос статистики для userID=%d, startDate=%s, endDate=%s, метки=%v",
+		userID, startDate, endDate, opts.TagIDs)
+
+	loc, err := s.location(ctx, userID, opts.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	from, to, err := periodBounds(startDate, endDate, loc)
+	if err != nil {
+		return nil, err
+	}
 
 	// Получаем записи за указанный период
-	entries, err := s.repo.GetUserStatsByPeriod(ctx, userID, startDate, endDate)
+	entries, err := s.repo.GetUserStatsByPeriod(ctx, userID, from, to, opts.TagIDs)
 	if err != nil {
 		log.Printf("Service.GetUserStats: Ошибка получения записей: %v", err)
 		return nil, err
@@ -67,42 +149,115 @@ func (s *Service) GetUserStats(ctx context.Context, userID uint, startDate, endD
 
 	// Подготавливаем статистику
 	stats := &TimeStats{
+		Timezone:    loc.String(),
 		DailyStats:  make(map[string]int64),
+		DailyBreaks: make(map[string]BreakStats),
+		TagStats:    []TagStat{},
 		Entries:     entries,
 		ActiveEntry: activeEntry,
+
+		CategoryStats:      []CategoryStat{},
+		DailyCategoryStats: make(map[string][]CategoryStat),
+	}
+
+	// По запросу добавляем незавершенную запись к завершенным; ее длительность считается на текущий момент
+	now := timeNow()
+	aggregated := entries
+	var running *models.TimeEntry
+	if opts.IncludeRunning && activeEntry != nil && activeEntry.StartTime.Before(to) &&
+		!containsEntry(entries, activeEntry.ID) && hasAnyTag(activeEntry, opts.TagIDs) {
+		running = clipRunning(activeEntry, now, to)
+		aggregated = append(append([]*models.TimeEntry{}, entries...), running)
 	}
 
 	// Если нет записей, возвращаем пустую статистику
-	if len(entries) == 0 {
+	if len(aggregated) == 0 {
 		log.Printf("Service.GetUserStats: Нет записей за указанный период, возвращаем пустую статистику")
 		return stats, nil
 	}
 
-	log.Printf("Service.GetUserStats: Обрабатываем %d записей для статистики", len(entries))
+	log.Printf("Service.GetUserStats: Обрабатываем %d записей для статистики", len(aggregated))
+
+	// Разбивка по категориям агрегируется в базе данных
+	filter := database.StatsFilter{UserID: userID, From: from, To: to, Location: loc, TagIDs: opts.TagIDs}
+	if running != nil {
+		filter.RunningUntil = now
+	}
+	breakdown, err := s.categoryBreakdown(ctx, filter)
+	if err != nil {
+		log.Printf("Service.GetUserStats: Ошибка получения статистики по категориям: %v", err)
+		return nil, err
+	}
+	stats.CategoryStats = breakdown.Total
+	stats.DailyCategoryStats = breakdown.Daily
 
 	var totalDuration int64
 	var longestSession int64
 	var longestSessionDate string
+	tagStats := make(map[uint]*TagStat)
+
+	// Дни периода в часовом поясе пользователя; части записей за пределами периода не учитываются
+	firstDay := from.Format("2006-01-02")
+	lastDay := to.AddDate(0, 0, -1).Format("2006-01-02")
+	inPeriod := func(day string) bool {
+		return day >= firstDay && day <= lastDay
+	}
 
 	// Обрабатываем каждую запись для подсчета статистики
-	for _, entry := range entries {
+	for _, entry := range aggregated {
 		log.Printf("Service.GetUserStats: Обработка записи ID=%d, status=%s, start_time=%v, end_time=%v",
 			entry.ID, entry.Status, entry.StartTime, entry.EndTime)
 
-		duration := entry.CalculateDuration()
-		log.Printf("Service.GetUserStats: Запись ID=%d имеет продолжительность %d секунд", entry.ID, duration)
+		// Запись, пересекающая полночь, делится между днями, которые она затрагивает
+		var duration int64
+		for _, slice := range entry.SplitByDay(loc) {
+			if !inPeriod(slice.Day) {
+				continue
+			}
+			duration += slice.Worked
+			stats.DailyStats[slice.Day] += slice.Worked
+			log.Printf("Service.GetUserStats: Добавлено %d секунд к дню %s, всего за день: %d",
+				slice.Worked, slice.Day, stats.DailyStats[slice.Day])
+
+			breaks := stats.DailyBreaks[slice.Day]
+			breaks.Duration += slice.Paused
+			stats.DailyBreaks[slice.Day] = breaks
+			stats.TotalBreakTime += slice.Paused
+		}
+		log.Printf("Service.GetUserStats: Запись ID=%d имеет продолжительность %d секунд в периоде", entry.ID, duration)
 		totalDuration += duration
+		if entry == running && duration > 0 {
+			stats.Provisional = true
+			stats.RunningDuration = duration
+		}
 
-		// Добавляем в дневную статистику
-		day := entry.StartTime.Format("2006-01-02")
-		stats.DailyStats[day] += duration
-		log.Printf("Service.GetUserStats: Добавлено %d секунд к дню %s, всего за день: %d",
-			duration, day, stats.DailyStats[day])
+		// Перерыв засчитывается в количество за день, в который он начался
+		for _, pause := range entry.Pauses {
+			day := pause.StartTime.In(loc).Format("2006-01-02")
+			if !inPeriod(day) {
+				continue
+			}
+			breaks := stats.DailyBreaks[day]
+			breaks.Count++
+			stats.DailyBreaks[day] = breaks
+			stats.TotalBreaks++
+		}
+
+		// Учитываем метки
+		for _, tag := range entry.Tags {
+			tagStat, ok := tagStats[tag.ID]
+			if !ok {
+				tagStat = &TagStat{TagID: tag.ID, Name: tag.Name, Color: tag.Color}
+				tagStats[tag.ID] = tagStat
+			}
+			tagStat.Duration += duration
+			tagStat.EntryCount++
+		}
 
-		// Проверяем, является ли это самой длинной сессией
-		if duration > longestSession {
-			longestSession = duration
-			longestSessionDate = day
+		// Самая длинная сессия - запись целиком, без деления по дням; датой считается день ее начала
+		if sessionDuration := entry.CalculateDuration(); sessionDuration > longestSession {
+			longestSession = sessionDuration
+			longestSessionDate = entry.StartTime.In(loc).Format("2006-01-02")
 			log.Printf("Service.GetUserStats: Новая самая длинная сессия: %d сек, дата: %s",
 				longestSession, longestSessionDate)
 		}
@@ -112,6 +267,17 @@ func (s *Service) GetUserStats(ctx context.Context, userID uint, startDate, endD
 	stats.LongestSession = longestSession
 	stats.LongestSessionDate = longestSessionDate
 
+	// Метки упорядочиваем по убыванию длительности
+	for _, tagStat := range tagStats {
+		stats.TagStats = append(stats.TagStats, *tagStat)
+	}
+	sort.Slice(stats.TagStats, func(i, j int) bool {
+		if stats.TagStats[i].Duration != stats.TagStats[j].Duration {
+			return stats.TagStats[i].Duration > stats.TagStats[j].Duration
+		}
+		return stats.TagStats[i].Name < stats.TagStats[j].Name
+	})
+
 	log.Printf("Service.GetUserStats: Итоговая статистика: totalDuration=%d, записей=%d, дней=%d",
 		stats.TotalDuration, len(stats.Entries), len(stats.DailyStats))
 
@@ -139,20 +305,195 @@ func (s *Service) GetUserStats(ctx context.Context, userID uint, startDate, endD
 
 // GetWeeklyStats возвращает статистику за последнюю неделю
 func (s *Service) GetWeeklyStats(ctx context.Context, userID uint) (*TimeStats, error) {
-	now := time.Now()
+	return s.GetWeeklyStatsWithOptions(ctx, userID, StatsOptions{})
+}
+
+// GetWeeklyStatsWithOptions возвращает статистику за текущую неделю с понедельника по сегодня;
+// "сегодня" определяется в часовом поясе пользователя
+func (s *Service) GetWeeklyStatsWithOptions(ctx context.Context, userID uint, opts StatsOptions) (*TimeStats, error) {
+	loc, err := s.location(ctx, userID, opts.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	now := timeNow().In(loc)
 	endDate := now.Format("2006-01-02")
-	startDate := now.AddDate(0, 0, -7).Format("2006-01-02")
+	// Weekday считает неделю с воскресенья, а неделя пользователя начинается с понедельника
+	daysSinceMonday := (int(now.Weekday()) + 6) % 7
+	startDate := now.AddDate(0, 0, -daysSinceMonday).Format("2006-01-02")
 
-	return s.GetUserStats(ctx, userID, startDate, endDate)
+	opts.Timezone = loc.String()
+	return s.GetUserStatsWithOptions(ctx, userID, startDate, endDate, opts)
 }
 
 // GetMonthlyStats возвращает статистику за последний месяц
 func (s *Service) GetMonthlyStats(ctx context.Context, userID uint) (*TimeStats, error) {
-	now := time.Now()
+	return s.GetMonthlyStatsWithOptions(ctx, userID, StatsOptions{})
+}
+
+// GetMonthlyStatsWithOptions возвращает статистику за последний месяц; "сегодня" определяется
+// в часовом поясе пользователя
+func (s *Service) GetMonthlyStatsWithOptions(ctx context.Context, userID uint, opts StatsOptions) (*TimeStats, error) {
+	loc, err := s.location(ctx, userID, opts.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	now := timeNow().In(loc)
 	endDate := now.Format("2006-01-02")
 	startDate := now.AddDate(0, -1, 0).Format("2006-01-02")
 
-	return s.GetUserStats(ctx, userID, startDate, endDate)
+	opts.Timezone = loc.String()
+	return s.GetUserStatsWithOptions(ctx, userID, startDate, endDate, opts)
+}
+
+// clipRunning возвращает копию незавершенной записи, завершенную в момент now (для приостановленной -
+// в начале паузы), но не позже конца периода to. Так длительность записи, в том числе в LongestSession
+// и TagStats, считается на один и тот же момент и не выходит за период.
+func clipRunning(entry *models.TimeEntry, now, to time.Time) *models.TimeEntry {
+	clipped := *entry
+	end := now
+	if entry.Status == models.StatusPaused && !entry.PausedAt.IsZero() {
+		end = entry.PausedAt
+	}
+	if end.After(to) {
+		end = to
+	}
+	clipped.EndTime = end
+	return &clipped
+}
+
+// containsEntry сообщает, есть ли среди записей запись с указанным ID
+func containsEntry(entries []*models.TimeEntry, id uint) bool {
+	for _, entry := range entries {
+		if entry.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAnyTag сообщает, есть ли у записи хотя бы одна из меток (пустой список подходит любой записи)
+func hasAnyTag(entry *models.TimeEntry, tagIDs []uint) bool {
+	if len(tagIDs) == 0 {
+		return true
+	}
+	for _, tag := range entry.Tags {
+		for _, id := range tagIDs {
+			if tag.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// location определяет часовой пояс расчета статистики: явно переданный tz,
+// иначе часовой пояс из профиля пользователя
+func (s *Service) location(ctx context.Context, userID uint, tz string) (*time.Location, error) {
+	if tz != "" {
+		loc, err := models.LoadTimezone(tz)
+		if err != nil {
+			return nil, ErrInvalidTimezone
+		}
+		return loc, nil
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return time.UTC, nil
+	}
+	return user.Location(), nil
+}
+
+// periodBounds переводит даты начала и конца периода (включительно) в интервал [from, to)
+// от полуночи первого дня до полуночи дня, следующего за последним, в часовом поясе loc
+func periodBounds(startDate, endDate string, loc *time.Location) (time.Time, time.Time, error) {
+	from, err := time.ParseInLocation("2006-01-02", startDate, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, ErrInvalidPeriod
+	}
+	to, err := time.ParseInLocation("2006-01-02", endDate, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, ErrInvalidPeriod
+	}
+
+	return from, to.AddDate(0, 0, 1), nil
+}
+
+// Period - период отчета: интервал [From, To) и часовой пояс, в котором считаются дни
+type Period struct {
+	From     time.Time
+	To       time.Time
+	Location *time.Location
+}
+
+// ResolvePeriod проверяет даты периода (YYYY-MM-DD, включительно) и часовой пояс tz
+// (пустая строка - часовой пояс из профиля) и возвращает границы периода
+func (s *Service) ResolvePeriod(ctx context.Context, userID uint, startDate, endDate, tz string) (*Period, error) {
+	loc, err := s.location(ctx, userID, tz)
+	if err != nil {
+		return nil, err
+	}
+	from, to, err := periodBounds(startDate, endDate, loc)
+	if err != nil {
+		return nil, err
+	}
+	if !from.Before(to) {
+		return nil, ErrInvalidPeriod
+	}
+	return &Period{From: from, To: to, Location: loc}, nil
+}
+
+// CategoryBreakdown содержит длительность по категориям за период и по дням периода
+type CategoryBreakdown struct {
+	Total []CategoryStat
+	Daily map[string][]CategoryStat // день -> категории
+}
+
+// GetCategoryBreakdown возвращает разбивку завершенных записей по категориям за период.
+// Расчет выполняется в базе данных без загрузки самих записей.
+func (s *Service) GetCategoryBreakdown(ctx context.Context, userID uint, period *Period, tagIDs []uint) (*CategoryBreakdown, error) {
+	return s.categoryBreakdown(ctx, database.StatsFilter{
+		UserID:   userID,
+		From:     period.From,
+		To:       period.To,
+		Location: period.Location,
+		TagIDs:   tagIDs,
+	})
+}
+
+// categoryBreakdown преобразует строки статистики по категориям из базы данных
+func (s *Service) categoryBreakdown(ctx context.Context, filter database.StatsFilter) (*CategoryBreakdown, error) {
+	rows, err := s.repo.GetCategoryStatsByPeriod(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	breakdown := &CategoryBreakdown{Total: []CategoryStat{}, Daily: make(map[string][]CategoryStat)}
+	for _, row := range rows {
+		stat := CategoryStat{
+			CategoryID: row.CategoryID,
+			Name:       row.Name,
+			Color:      row.Color,
+			Duration:   row.Duration,
+			EntryCount: row.EntryCount,
+			Percent:    row.Percent,
+		}
+		if stat.CategoryID == nil {
+			stat.Name = UncategorizedName
+		}
+
+		if row.Day == "" {
+			breakdown.Total = append(breakdown.Total, stat)
+		} else {
+			breakdown.Daily[row.Day] = append(breakdown.Daily[row.Day], stat)
+		}
+	}
+	return breakdown, nil
 }
 
 // FormatDuration форматирует продолжительность в человекочитаемый формат (ЧЧ:ММ:СС)
@@ -6,12 +6,26 @@ import (
 	"time"
 
 	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/internal/testutil"
+	"github.com/graywrk/timetracker/backend/pkg/database"
 )
 
 // MockRepository представляет мок репозитория для тестирования
 type MockRepository struct {
+	testutil.Repository
+
 	entries     []*models.TimeEntry
 	activeEntry *models.TimeEntry
+	user        *models.User
+	categories  []*models.Category
+	projects    []*models.Project
+	clients     []*models.Client
+	// categoryStats возвращается методом GetCategoryStatsByPeriod
+	categoryStats []database.CategoryDuration
+	// from и to - границы периода из последнего вызова GetUserStatsByPeriod
+	from, to time.Time
+	// statsFilter - условия из последнего вызова GetCategoryStatsByPeriod
+	statsFilter database.StatsFilter
 	err         error
 }
 
@@ -39,39 +53,9 @@ func (m *MockRepository) SetActiveEntry(entry *models.TimeEntry) {
 
 // Реализация методов интерфейса Repository
 
-// CreateUser мок метода
-func (m *MockRepository) CreateUser(ctx context.Context, user *models.User) error {
-	return m.err
-}
-
 // GetUserByID мок метода
 func (m *MockRepository) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
-	return nil, m.err
-}
-
-// GetUserByEmail мок метода
-func (m *MockRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
-	return nil, m.err
-}
-
-// UpdateUser мок метода
-func (m *MockRepository) UpdateUser(ctx context.Context, user *models.User) error {
-	return m.err
-}
-
-// DeleteUser мок метода
-func (m *MockRepository) DeleteUser(ctx context.Context, id uint) error {
-	return m.err
-}
-
-// CreateTimeEntry мок метода
-func (m *MockRepository) CreateTimeEntry(ctx context.Context, entry *models.TimeEntry) error {
-	return m.err
-}
-
-// GetTimeEntryByID мок метода
-func (m *MockRepository) GetTimeEntryByID(ctx context.Context, id uint) (*models.TimeEntry, error) {
-	return nil, m.err
+	return m.user, m.err
 }
 
 // GetTimeEntriesByUserID мок метода
@@ -84,43 +68,41 @@ func (m *MockRepository) GetActiveTimeEntryForUser(ctx context.Context, userID u
 	return m.activeEntry, m.err
 }
 
-// UpdateTimeEntry мок метода
-func (m *MockRepository) UpdateTimeEntry(ctx context.Context, entry *models.TimeEntry) error {
-	return m.err
-}
-
-// DeleteTimeEntry мок метода
-func (m *MockRepository) DeleteTimeEntry(ctx context.Context, id uint) error {
-	return m.err
+// ListTimeEntries мок метода
+func (m *MockRepository) ListTimeEntries(ctx context.Context, filter database.TimeEntryFilter) ([]*models.TimeEntry, error) {
+	return m.entries, m.err
 }
 
 // GetUserStatsByPeriod мок метода
-func (m *MockRepository) GetUserStatsByPeriod(ctx context.Context, userID uint, startDate, endDate string) ([]*models.TimeEntry, error) {
+func (m *MockRepository) GetUserStatsByPeriod(ctx context.Context, userID uint, from, to time.Time, tagIDs []uint) ([]*models.TimeEntry, error) {
 	if m.err != nil {
 		return nil, m.err
 	}
+	m.from, m.to = from, to
 	return m.entries, nil
 }
 
-// Методы для работы с категориями
-func (m *MockRepository) CreateCategory(ctx context.Context, category *models.Category) error {
-	return m.err
+// GetCategoryStatsByPeriod мок метода
+func (m *MockRepository) GetCategoryStatsByPeriod(ctx context.Context, filter database.StatsFilter) ([]database.CategoryDuration, error) {
+	m.statsFilter = filter
+	return m.categoryStats, m.err
 }
 
-func (m *MockRepository) GetCategoryByID(ctx context.Context, id uint) (*models.Category, error) {
-	return nil, m.err
+func (m *MockRepository) GetCategoriesByUserID(ctx context.Context, userID uint) ([]*models.Category, error) {
+	return m.categories, m.err
 }
 
-func (m *MockRepository) GetCategoriesByUserID(ctx context.Context, userID uint) ([]*models.Category, error) {
-	return nil, m.err
+func (m *MockRepository) GetClientsByUserID(ctx context.Context, userID uint) ([]*models.Client, error) {
+	return m.clients, m.err
 }
 
-func (m *MockRepository) UpdateCategory(ctx context.Context, category *models.Category) error {
-	return m.err
+func (m *MockRepository) GetProjectsByUserID(ctx context.Context, userID uint) ([]*models.Project, error) {
+	return m.projects, m.err
 }
 
-func (m *MockRepository) DeleteCategory(ctx context.Context, id uint) error {
-	return m.err
+// WithTx мок метода: fn выполняется без транзакции
+func (m *MockRepository) WithTx(ctx context.Context, fn func(repo database.Repository) error) error {
+	return fn(m)
 }
 
 // TestGetUserStats_CurrentDay тестирует функцию GetUserStats для текущего дня
@@ -264,3 +246,491 @@ func TestGetUserStats_CustomPeriod(t *testing.T) {
 		t.Errorf("GetUserStats().LongestSessionDate = %v, хотели %v", stats.LongestSessionDate, expectedLongestSessionDate)
 	}
 }
+
+// TestGetUserStats_Breaks проверяет подсчет перерывов по дням
+func TestGetUserStats_Breaks(t *testing.T) {
+	mockRepo := NewMockRepository()
+	service := NewService(mockRepo)
+	ctx := context.Background()
+	userID := uint(1)
+
+	day := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	mockRepo.SetEntries([]*models.TimeEntry{
+		{
+			ID:        1,
+			UserID:    userID,
+			StartTime: day,
+			EndTime:   day.Add(4 * time.Hour),
+			Status:    models.StatusCompleted,
+			Pauses: []models.Pause{
+				{StartTime: day.Add(time.Hour), EndTime: day.Add(75 * time.Minute)},
+				{StartTime: day.Add(2 * time.Hour), EndTime: day.Add(150 * time.Minute)},
+			},
+		},
+		{
+			// Запись без интервалов учитывается только по длительности
+			ID:          2,
+			UserID:      userID,
+			StartTime:   day.AddDate(0, 0, 1),
+			EndTime:     day.AddDate(0, 0, 1).Add(2 * time.Hour),
+			Status:      models.StatusCompleted,
+			TotalPaused: 600,
+		},
+	})
+
+	stats, err := service.GetUserStats(ctx, userID, "2025-03-10", "2025-03-11")
+	if err != nil {
+		t.Fatalf("GetUserStats() error = %v", err)
+	}
+
+	if got := stats.DailyBreaks["2025-03-10"]; got.Count != 2 || got.Duration != 2700 {
+		t.Errorf("DailyBreaks[2025-03-10] = %+v, хотели {Count:2 Duration:2700}", got)
+	}
+	if got := stats.DailyBreaks["2025-03-11"]; got.Count != 0 || got.Duration != 600 {
+		t.Errorf("DailyBreaks[2025-03-11] = %+v, хотели {Count:0 Duration:600}", got)
+	}
+	if stats.TotalBreaks != 2 || stats.TotalBreakTime != 3300 {
+		t.Errorf("TotalBreaks = %d, TotalBreakTime = %d, хотели 2 и 3300", stats.TotalBreaks, stats.TotalBreakTime)
+	}
+	if stats.DailyStats["2025-03-10"] != 11700 {
+		t.Errorf("DailyStats[2025-03-10] = %d, хотели 11700", stats.DailyStats["2025-03-10"])
+	}
+}
+
+// TestGetUserStats_Tags проверяет агрегацию длительности по меткам
+func TestGetUserStats_Tags(t *testing.T) {
+	mockRepo := NewMockRepository()
+	service := NewService(mockRepo)
+	ctx := context.Background()
+	userID := uint(1)
+
+	urgent := models.Tag{ID: 1, UserID: userID, Name: "срочно", Color: "#ff0000"}
+	meeting := models.Tag{ID: 2, UserID: userID, Name: "встреча", Color: "#00ff00"}
+	day := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	mockRepo.SetEntries([]*models.TimeEntry{
+		{
+			ID:        1,
+			UserID:    userID,
+			StartTime: day,
+			EndTime:   day.Add(2 * time.Hour),
+			Status:    models.StatusCompleted,
+			Tags:      []models.Tag{urgent, meeting},
+		},
+		{
+			ID:        2,
+			UserID:    userID,
+			StartTime: day.Add(3 * time.Hour),
+			EndTime:   day.Add(4 * time.Hour),
+			Status:    models.StatusCompleted,
+			Tags:      []models.Tag{urgent},
+		},
+		{
+			// Запись без меток в TagStats не попадает
+			ID:        3,
+			UserID:    userID,
+			StartTime: day.Add(5 * time.Hour),
+			EndTime:   day.Add(6 * time.Hour),
+			Status:    models.StatusCompleted,
+		},
+	})
+
+	stats, err := service.GetUserStatsWithOptions(ctx, userID, "2025-03-10", "2025-03-10", StatsOptions{})
+	if err != nil {
+		t.Fatalf("GetUserStatsWithOptions() error = %v", err)
+	}
+
+	if len(stats.TagStats) != 2 {
+		t.Fatalf("TagStats содержит %d меток, хотели 2", len(stats.TagStats))
+	}
+	if got := stats.TagStats[0]; got.TagID != 1 || got.Duration != 10800 || got.EntryCount != 2 {
+		t.Errorf("TagStats[0] = %+v, хотели срочно: 10800 сек, 2 записи", got)
+	}
+	if got := stats.TagStats[1]; got.TagID != 2 || got.Duration != 7200 || got.EntryCount != 1 {
+		t.Errorf("TagStats[1] = %+v, хотели встреча: 7200 сек, 1 запись", got)
+	}
+	if stats.TotalDuration != 14400 {
+		t.Errorf("TotalDuration = %d, хотели 14400", stats.TotalDuration)
+	}
+}
+
+// TestGetUserStats_Categories проверяет разбивку статистики по категориям
+func TestGetUserStats_Categories(t *testing.T) {
+	mockRepo := NewMockRepository()
+	service := NewService(mockRepo)
+	ctx := context.Background()
+	userID := uint(1)
+	workID := uint(1)
+	day := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	mockRepo.SetEntries([]*models.TimeEntry{
+		{ID: 1, UserID: userID, StartTime: day, EndTime: day.Add(3 * time.Hour), Status: models.StatusCompleted, CategoryID: &workID},
+		{ID: 2, UserID: userID, StartTime: day.Add(4 * time.Hour), EndTime: day.Add(5 * time.Hour), Status: models.StatusCompleted},
+	})
+	// Агрегаты, которые вернул бы GROUP BY в базе данных
+	mockRepo.categoryStats = []database.CategoryDuration{
+		{CategoryID: &workID, Name: "Работа", Color: "#ff0000", Duration: 10800, EntryCount: 1, Percent: 75},
+		{Name: "", Duration: 3600, EntryCount: 1, Percent: 25},
+		{Day: "2025-03-10", CategoryID: &workID, Name: "Работа", Color: "#ff0000", Duration: 10800, EntryCount: 1, Percent: 75},
+		{Day: "2025-03-10", Duration: 3600, EntryCount: 1, Percent: 25},
+	}
+
+	stats, err := service.GetUserStats(ctx, userID, "2025-03-10", "2025-03-10")
+	if err != nil {
+		t.Fatalf("GetUserStats() error = %v", err)
+	}
+
+	if len(stats.CategoryStats) != 2 {
+		t.Fatalf("CategoryStats содержит %d категорий, хотели 2", len(stats.CategoryStats))
+	}
+	if got := stats.CategoryStats[0]; got.CategoryID == nil || *got.CategoryID != workID || got.Duration != 10800 || got.Percent != 75 {
+		t.Errorf("CategoryStats[0] = %+v, хотели Работа: 10800 сек, 75%%", got)
+	}
+	if got := stats.CategoryStats[1]; got.CategoryID != nil || got.Name != "Без категории" || got.Percent != 25 {
+		t.Errorf("CategoryStats[1] = %+v, хотели Без категории: 25%%", got)
+	}
+
+	daily := stats.DailyCategoryStats["2025-03-10"]
+	if len(daily) != 2 {
+		t.Fatalf("DailyCategoryStats[2025-03-10] содержит %d категорий, хотели 2", len(daily))
+	}
+	if daily[1].Name != "Без категории" || daily[1].Duration != 3600 {
+		t.Errorf("DailyCategoryStats[2025-03-10][1] = %+v, хотели Без категории: 3600 сек", daily[1])
+	}
+	if len(stats.DailyCategoryStats) != 1 {
+		t.Errorf("DailyCategoryStats содержит %d дней, хотели 1", len(stats.DailyCategoryStats))
+	}
+}
+
+// TestGetUserStats_Timezone проверяет границы периода и дней в часовом поясе пользователя
+func TestGetUserStats_Timezone(t *testing.T) {
+	mockRepo := NewMockRepository()
+	service := NewService(mockRepo)
+	ctx := context.Background()
+	userID := uint(1)
+
+	// 22:30 UTC 10 марта - это уже 01:30 11 марта по Москве
+	start := time.Date(2025, 3, 10, 22, 30, 0, 0, time.UTC)
+	mockRepo.SetEntries([]*models.TimeEntry{
+		{ID: 1, UserID: userID, StartTime: start, EndTime: start.Add(time.Hour), Status: models.StatusCompleted},
+	})
+
+	// Без часового пояса в профиле и в запросе дни считаются в UTC
+	stats, err := service.GetUserStats(ctx, userID, "2025-03-10", "2025-03-11")
+	if err != nil {
+		t.Fatalf("GetUserStats() error = %v", err)
+	}
+	if stats.Timezone != "UTC" || stats.DailyStats["2025-03-10"] != 3600 {
+		t.Errorf("Timezone = %s, DailyStats = %v, хотели UTC и 3600 сек за 2025-03-10", stats.Timezone, stats.DailyStats)
+	}
+
+	// Часовой пояс из профиля пользователя
+	mockRepo.user = &models.User{ID: userID, Timezone: "Europe/Moscow"}
+	stats, err = service.GetUserStats(ctx, userID, "2025-03-11", "2025-03-11")
+	if err != nil {
+		t.Fatalf("GetUserStats() error = %v", err)
+	}
+	if stats.DailyStats["2025-03-11"] != 3600 || len(stats.DailyStats) != 1 {
+		t.Errorf("DailyStats = %v, хотели 3600 сек за 2025-03-11", stats.DailyStats)
+	}
+	wantFrom := time.Date(2025, 3, 10, 21, 0, 0, 0, time.UTC)
+	wantTo := time.Date(2025, 3, 11, 21, 0, 0, 0, time.UTC)
+	if !mockRepo.from.Equal(wantFrom) || !mockRepo.to.Equal(wantTo) {
+		t.Errorf("период = [%v, %v), хотели [%v, %v)", mockRepo.from, mockRepo.to, wantFrom, wantTo)
+	}
+
+	// Параметр tz важнее профиля
+	stats, err = service.GetUserStatsWithOptions(ctx, userID, "2025-03-10", "2025-03-10", StatsOptions{Timezone: "America/New_York"})
+	if err != nil {
+		t.Fatalf("GetUserStatsWithOptions() error = %v", err)
+	}
+	if stats.Timezone != "America/New_York" || stats.DailyStats["2025-03-10"] != 3600 {
+		t.Errorf("Timezone = %s, DailyStats = %v, хотели 3600 сек за 2025-03-10 по Нью-Йорку", stats.Timezone, stats.DailyStats)
+	}
+
+	if _, err := service.GetUserStatsWithOptions(ctx, userID, "2025-03-10", "2025-03-10", StatsOptions{Timezone: "Mars/Olympus"}); err != ErrInvalidTimezone {
+		t.Errorf("GetUserStatsWithOptions() с неизвестным часовым поясом: error = %v, хотели ErrInvalidTimezone", err)
+	}
+	if _, err := service.GetUserStats(ctx, userID, "10.03.2025", "2025-03-10"); err != ErrInvalidPeriod {
+		t.Errorf("GetUserStats() с некорректной датой: error = %v, хотели ErrInvalidPeriod", err)
+	}
+}
+
+// TestGetWeeklyStats_Timezone проверяет, что неделя начинается с понедельника
+// в часовом поясе пользователя
+func TestGetWeeklyStats_Timezone(t *testing.T) {
+	mockRepo := NewMockRepository()
+	mockRepo.user = &models.User{ID: 1, Timezone: "Europe/Moscow"}
+	service := NewService(mockRepo)
+	ctx := context.Background()
+
+	previous := timeNow
+	defer func() { timeNow = previous }()
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		wantFrom time.Time
+		wantTo   time.Time
+	}{
+		{
+			// Четверг: неделя с понедельника 10 марта по четверг 13 марта по Москве
+			name:     "MidWeek",
+			now:      time.Date(2025, 3, 13, 12, 0, 0, 0, time.UTC),
+			wantFrom: time.Date(2025, 3, 9, 21, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2025, 3, 13, 21, 0, 0, 0, time.UTC),
+		},
+		{
+			// 22:30 UTC воскресенья 16 марта - это уже понедельник 17 марта по Москве: новая неделя
+			name:     "NewWeekInUserZone",
+			now:      time.Date(2025, 3, 16, 22, 30, 0, 0, time.UTC),
+			wantFrom: time.Date(2025, 3, 16, 21, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2025, 3, 17, 21, 0, 0, 0, time.UTC),
+		},
+		{
+			// 20:30 UTC воскресенья - еще воскресенье по Москве: неделя целиком
+			name:     "SundayInUserZone",
+			now:      time.Date(2025, 3, 16, 20, 30, 0, 0, time.UTC),
+			wantFrom: time.Date(2025, 3, 9, 21, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2025, 3, 16, 21, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeNow = func() time.Time { return tt.now }
+
+			if _, err := service.GetWeeklyStats(ctx, 1); err != nil {
+				t.Fatalf("GetWeeklyStats() error = %v", err)
+			}
+			if !mockRepo.from.Equal(tt.wantFrom) || !mockRepo.to.Equal(tt.wantTo) {
+				t.Errorf("период = [%v, %v), хотели [%v, %v)", mockRepo.from, mockRepo.to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
+
+// TestGetUserStats_MidnightSplit проверяет деление записей, пересекающих полночь, между днями
+func TestGetUserStats_MidnightSplit(t *testing.T) {
+	ctx := context.Background()
+	userID := uint(1)
+	evening := time.Date(2025, 3, 10, 22, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		entry       *models.TimeEntry
+		timezone    string
+		startDate   string
+		endDate     string
+		daily       map[string]int64
+		breaks      map[string]BreakStats
+		total       int64
+		longest     int64
+		longestDate string
+	}{
+		{
+			name:      "22:00-02:00 без перерывов",
+			entry:     &models.TimeEntry{ID: 1, UserID: userID, StartTime: evening, EndTime: evening.Add(4 * time.Hour), Status: models.StatusCompleted},
+			startDate: "2025-03-10", endDate: "2025-03-11",
+			daily:       map[string]int64{"2025-03-10": 7200, "2025-03-11": 7200},
+			total:       14400,
+			longest:     14400,
+			longestDate: "2025-03-10",
+		},
+		{
+			name: "перерыв через полночь вычитается из обоих дней",
+			entry: &models.TimeEntry{
+				ID: 2, UserID: userID, StartTime: evening, EndTime: evening.Add(4 * time.Hour),
+				Status: models.StatusCompleted, TotalPaused: 3600,
+				Pauses: []models.Pause{{StartTime: evening.Add(90 * time.Minute), EndTime: evening.Add(150 * time.Minute)}},
+			},
+			startDate: "2025-03-10", endDate: "2025-03-11",
+			daily: map[string]int64{"2025-03-10": 5400, "2025-03-11": 5400},
+			breaks: map[string]BreakStats{
+				"2025-03-10": {Count: 1, Duration: 1800},
+				"2025-03-11": {Count: 0, Duration: 1800},
+			},
+			total:       10800,
+			longest:     10800,
+			longestDate: "2025-03-10",
+		},
+		{
+			name: "перерывы без интервалов делятся пропорционально",
+			entry: &models.TimeEntry{
+				ID: 3, UserID: userID, StartTime: evening.Add(-2 * time.Hour), EndTime: evening.Add(6 * time.Hour),
+				Status: models.StatusCompleted, TotalPaused: 3600,
+			},
+			startDate: "2025-03-10", endDate: "2025-03-11",
+			daily: map[string]int64{"2025-03-10": 12600, "2025-03-11": 12600},
+			breaks: map[string]BreakStats{
+				"2025-03-10": {Duration: 1800},
+				"2025-03-11": {Duration: 1800},
+			},
+			total:       25200,
+			longest:     25200,
+			longestDate: "2025-03-10",
+		},
+		{
+			name:      "в период попадает только часть записи, начатой накануне",
+			entry:     &models.TimeEntry{ID: 4, UserID: userID, StartTime: evening, EndTime: evening.Add(4 * time.Hour), Status: models.StatusCompleted},
+			startDate: "2025-03-11", endDate: "2025-03-11",
+			daily:       map[string]int64{"2025-03-11": 7200},
+			total:       7200,
+			longest:     14400, // самая длинная сессия - запись целиком
+			longestDate: "2025-03-10",
+		},
+		{
+			name:      "полночь определяется в часовом поясе пользователя",
+			entry:     &models.TimeEntry{ID: 5, UserID: userID, StartTime: evening.Add(-2 * time.Hour), EndTime: evening.Add(time.Hour), Status: models.StatusCompleted},
+			timezone:  "Europe/Moscow", // 23:00-02:00 по Москве
+			startDate: "2025-03-10", endDate: "2025-03-11",
+			daily:       map[string]int64{"2025-03-10": 3600, "2025-03-11": 7200},
+			total:       10800,
+			longest:     10800,
+			longestDate: "2025-03-10",
+		},
+		{
+			name:      "запись на несколько суток",
+			entry:     &models.TimeEntry{ID: 6, UserID: userID, StartTime: evening, EndTime: evening.Add(50 * time.Hour), Status: models.StatusCompleted},
+			startDate: "2025-03-10", endDate: "2025-03-13",
+			daily:       map[string]int64{"2025-03-10": 7200, "2025-03-11": 86400, "2025-03-12": 86400},
+			total:       180000,
+			longest:     180000,
+			longestDate: "2025-03-10",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := NewMockRepository()
+			service := NewService(mockRepo)
+			mockRepo.SetEntries([]*models.TimeEntry{tt.entry})
+
+			stats, err := service.GetUserStatsWithOptions(ctx, userID, tt.startDate, tt.endDate, StatsOptions{Timezone: tt.timezone})
+			if err != nil {
+				t.Fatalf("GetUserStatsWithOptions() error = %v", err)
+			}
+
+			if len(stats.DailyStats) != len(tt.daily) {
+				t.Errorf("DailyStats = %v, хотели %v", stats.DailyStats, tt.daily)
+			}
+			var sum int64
+			for day, want := range tt.daily {
+				if stats.DailyStats[day] != want {
+					t.Errorf("DailyStats[%s] = %d, хотели %d", day, stats.DailyStats[day], want)
+				}
+				sum += stats.DailyStats[day]
+			}
+			if stats.TotalDuration != tt.total || sum != tt.total {
+				t.Errorf("TotalDuration = %d, сумма по дням = %d, хотели %d", stats.TotalDuration, sum, tt.total)
+			}
+			if stats.LongestSession != tt.longest || stats.LongestSessionDate != tt.longestDate {
+				t.Errorf("LongestSession = %d (%s), хотели %d (%s)",
+					stats.LongestSession, stats.LongestSessionDate, tt.longest, tt.longestDate)
+			}
+			for day, want := range tt.breaks {
+				if stats.DailyBreaks[day] != want {
+					t.Errorf("DailyBreaks[%s] = %+v, хотели %+v", day, stats.DailyBreaks[day], want)
+				}
+			}
+		})
+	}
+}
+
+// TestGetUserStats_IncludeRunning проверяет учет незавершенной записи в итогах
+func TestGetUserStats_IncludeRunning(t *testing.T) {
+	mockRepo := NewMockRepository()
+	service := NewService(mockRepo)
+	ctx := context.Background()
+	userID := uint(1)
+	day := time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC)
+
+	mockRepo.SetEntries([]*models.TimeEntry{
+		{ID: 1, UserID: userID, StartTime: day, EndTime: day.Add(2 * time.Hour), Status: models.StatusCompleted},
+	})
+	// Приостановленная запись: час работы до паузы, длительность не зависит от текущего времени
+	paused := &models.TimeEntry{
+		ID: 2, UserID: userID, StartTime: day.Add(3 * time.Hour), PausedAt: day.Add(4 * time.Hour),
+		Status: models.StatusPaused, Pauses: []models.Pause{{StartTime: day.Add(4 * time.Hour)}},
+		Tags: []models.Tag{{ID: 7, Name: "срочно"}},
+	}
+	mockRepo.SetActiveEntry(paused)
+
+	// По умолчанию незавершенная запись в итоги не входит
+	stats, err := service.GetUserStats(ctx, userID, "2025-03-10", "2025-03-10")
+	if err != nil {
+		t.Fatalf("GetUserStats() error = %v", err)
+	}
+	if stats.TotalDuration != 7200 || stats.Provisional || stats.ActiveEntry == nil {
+		t.Errorf("TotalDuration = %d, Provisional = %v, хотели 7200 и false с ActiveEntry", stats.TotalDuration, stats.Provisional)
+	}
+	if !mockRepo.statsFilter.RunningUntil.IsZero() {
+		t.Errorf("RunningUntil = %v, хотели нулевое значение", mockRepo.statsFilter.RunningUntil)
+	}
+
+	stats, err = service.GetUserStatsWithOptions(ctx, userID, "2025-03-10", "2025-03-10", StatsOptions{IncludeRunning: true})
+	if err != nil {
+		t.Fatalf("GetUserStatsWithOptions() error = %v", err)
+	}
+	if stats.TotalDuration != 10800 || stats.DailyStats["2025-03-10"] != 10800 {
+		t.Errorf("TotalDuration = %d, DailyStats = %v, хотели 10800", stats.TotalDuration, stats.DailyStats)
+	}
+	if !stats.Provisional || stats.RunningDuration != 3600 {
+		t.Errorf("Provisional = %v, RunningDuration = %d, хотели true и 3600", stats.Provisional, stats.RunningDuration)
+	}
+	if mockRepo.statsFilter.RunningUntil.IsZero() {
+		t.Error("Разбивка по категориям запрошена без незавершенной записи")
+	}
+	if len(stats.Entries) != 1 {
+		t.Errorf("Entries содержит %d записей, хотели только завершенную", len(stats.Entries))
+	}
+
+	// Незавершенная запись без нужной метки не учитывается
+	stats, err = service.GetUserStatsWithOptions(ctx, userID, "2025-03-10", "2025-03-10", StatsOptions{IncludeRunning: true, TagIDs: []uint{8}})
+	if err != nil {
+		t.Fatalf("GetUserStatsWithOptions() error = %v", err)
+	}
+	if stats.Provisional || stats.RunningDuration != 0 {
+		t.Errorf("Provisional = %v, RunningDuration = %d, хотели false и 0", stats.Provisional, stats.RunningDuration)
+	}
+
+	// Незавершенная запись вне периода не делает итоги предварительными
+	stats, err = service.GetUserStatsWithOptions(ctx, userID, "2025-03-09", "2025-03-09", StatsOptions{IncludeRunning: true})
+	if err != nil {
+		t.Fatalf("GetUserStatsWithOptions() error = %v", err)
+	}
+	if stats.Provisional {
+		t.Error("Provisional = true для периода без незавершенной записи")
+	}
+
+	// Активная запись считается на текущий момент, но не дальше конца периода,
+	// в том числе в самой длинной сессии и статистике меток
+	now := day.Add(50 * time.Hour)
+	previous := timeNow
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = previous }()
+
+	mockRepo.SetActiveEntry(&models.TimeEntry{
+		ID: 3, UserID: userID, StartTime: day.Add(12 * time.Hour), Status: models.StatusActive,
+		Tags: []models.Tag{{ID: 7, Name: "срочно"}},
+	})
+	stats, err = service.GetUserStatsWithOptions(ctx, userID, "2025-03-10", "2025-03-10", StatsOptions{IncludeRunning: true})
+	if err != nil {
+		t.Fatalf("GetUserStatsWithOptions() error = %v", err)
+	}
+	if stats.RunningDuration != 14400 || stats.TotalDuration != 21600 {
+		t.Errorf("RunningDuration = %d, TotalDuration = %d, хотели 14400 и 21600", stats.RunningDuration, stats.TotalDuration)
+	}
+	if stats.LongestSession != 14400 {
+		t.Errorf("LongestSession = %d, хотели 14400", stats.LongestSession)
+	}
+	if len(stats.TagStats) != 1 || stats.TagStats[0].Duration != 14400 {
+		t.Errorf("TagStats = %+v, хотели 14400 секунд по метке", stats.TagStats)
+	}
+	if !mockRepo.statsFilter.RunningUntil.Equal(now) {
+		t.Errorf("RunningUntil = %v, хотели %v", mockRepo.statsFilter.RunningUntil, now)
+	}
+	if !stats.ActiveEntry.EndTime.IsZero() {
+		t.Error("Ограничение периодом не должно менять ActiveEntry")
+	}
+}
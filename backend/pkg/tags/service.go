@@ -0,0 +1,125 @@
+package tags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/pkg/database"
+)
+
+// Определение типовых ошибок
+var (
+	ErrTagNotFound   = errors.New("метка не найдена")
+	ErrNotAuthorized = errors.New("у пользователя нет прав на эту метку")
+	ErrEmptyTagName  = errors.New("название метки не может быть пустым")
+)
+
+// DefaultColor - цвет метки по умолчанию
+const DefaultColor = "#8a8f98"
+
+// Service предоставляет методы для работы с метками
+type Service struct {
+	repo database.Repository
+}
+
+// NewService создает новый сервис меток
+func NewService(repo database.Repository) *Service {
+	return &Service{
+		repo: repo,
+	}
+}
+
+// CreateTag создает новую метку для пользователя
+func (s *Service) CreateTag(ctx context.Context, userID uint, name, color string) (*models.Tag, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrEmptyTagName
+	}
+
+	if color == "" {
+		color = DefaultColor
+	}
+
+	tag := &models.Tag{
+		UserID: userID,
+		Name:   name,
+		Color:  color,
+	}
+
+	if err := s.repo.CreateTag(ctx, tag); err != nil {
+		return nil, fmt.Errorf("ошибка при создании метки: %w", err)
+	}
+
+	return tag, nil
+}
+
+// GetTagsByUserID возвращает все метки пользователя
+func (s *Service) GetTagsByUserID(ctx context.Context, userID uint) ([]*models.Tag, error) {
+	tags, err := s.repo.GetTagsByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении меток пользователя: %w", err)
+	}
+	return tags, nil
+}
+
+// UpdateTag обновляет существующую метку
+func (s *Service) UpdateTag(ctx context.Context, id, userID uint, name, color string) (*models.Tag, error) {
+	// Проверяем наличие метки и права доступа
+	existingTag, err := s.getOwnedTag(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrEmptyTagName
+	}
+
+	if color == "" {
+		color = existingTag.Color // Оставляем текущий цвет
+	}
+
+	tag := &models.Tag{
+		ID:        id,
+		UserID:    userID,
+		Name:      name,
+		Color:     color,
+		CreatedAt: existingTag.CreatedAt,
+	}
+
+	if err := s.repo.UpdateTag(ctx, tag); err != nil {
+		return nil, fmt.Errorf("ошибка при обновлении метки: %w", err)
+	}
+
+	return tag, nil
+}
+
+// DeleteTag удаляет метку; записи времени при этом теряют только эту метку
+func (s *Service) DeleteTag(ctx context.Context, id, userID uint) error {
+	if _, err := s.getOwnedTag(ctx, id, userID); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteTag(ctx, id); err != nil {
+		return fmt.Errorf("ошибка при удалении метки: %w", err)
+	}
+
+	return nil
+}
+
+// getOwnedTag возвращает метку, если она принадлежит пользователю
+func (s *Service) getOwnedTag(ctx context.Context, id, userID uint) (*models.Tag, error) {
+	tag, err := s.repo.GetTagByID(ctx, id)
+	if err != nil || tag == nil {
+		return nil, ErrTagNotFound
+	}
+
+	if tag.UserID != userID {
+		return nil, ErrNotAuthorized
+	}
+
+	return tag, nil
+}
@@ -0,0 +1,179 @@
+package tags
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/internal/testutil"
+	"github.com/graywrk/timetracker/backend/pkg/database"
+)
+
+// Mock репозитория для тестирования сервиса
+type MockTagRepo struct {
+	testutil.Repository
+
+	tags   map[uint]*models.Tag
+	nextID uint
+}
+
+func NewMockTagRepo() *MockTagRepo {
+	return &MockTagRepo{
+		tags:   make(map[uint]*models.Tag),
+		nextID: 1,
+	}
+}
+
+func (m *MockTagRepo) CreateTag(ctx context.Context, tag *models.Tag) error {
+	tag.ID = m.nextID
+	tag.CreatedAt = time.Now()
+	tag.UpdatedAt = time.Now()
+	m.tags[tag.ID] = tag
+	m.nextID++
+	return nil
+}
+
+func (m *MockTagRepo) GetTagByID(ctx context.Context, id uint) (*models.Tag, error) {
+	tag, exists := m.tags[id]
+	if !exists {
+		return nil, errors.New("метка не найдена")
+	}
+	return tag, nil
+}
+
+func (m *MockTagRepo) GetTagsByUserID(ctx context.Context, userID uint) ([]*models.Tag, error) {
+	var result []*models.Tag
+	for _, tag := range m.tags {
+		if tag.UserID == userID {
+			result = append(result, tag)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockTagRepo) UpdateTag(ctx context.Context, tag *models.Tag) error {
+	_, exists := m.tags[tag.ID]
+	if !exists {
+		return errors.New("метка не найдена")
+	}
+	tag.UpdatedAt = time.Now()
+	m.tags[tag.ID] = tag
+	return nil
+}
+
+func (m *MockTagRepo) DeleteTag(ctx context.Context, id uint) error {
+	_, exists := m.tags[id]
+	if !exists {
+		return errors.New("метка не найдена")
+	}
+	delete(m.tags, id)
+	return nil
+}
+
+func (m *MockTagRepo) WithTx(ctx context.Context, fn func(repo database.Repository) error) error {
+	return fn(m)
+}
+
+// Тесты
+
+func TestCreateTag(t *testing.T) {
+	repo := NewMockTagRepo()
+	service := NewService(repo)
+	ctx := context.Background()
+
+	// Тест 1: Успешное создание метки
+	tag, err := service.CreateTag(ctx, 1, "  срочно ", "#ff0000")
+	if err != nil {
+		t.Fatalf("Ошибка при создании метки: %v", err)
+	}
+	if tag.Name != "срочно" {
+		t.Errorf("Ожидалось Name='срочно', получено '%s'", tag.Name)
+	}
+	if tag.UserID != 1 {
+		t.Errorf("Ожидалось UserID=1, получено %d", tag.UserID)
+	}
+
+	// Тест 2: Создание метки с пустым названием
+	_, err = service.CreateTag(ctx, 1, "   ", "#ff0000")
+	if !errors.Is(err, ErrEmptyTagName) {
+		t.Errorf("Ожидалась ошибка ErrEmptyTagName, получено %v", err)
+	}
+
+	// Тест 3: Создание метки без цвета (должен использоваться цвет по умолчанию)
+	tag, err = service.CreateTag(ctx, 1, "встреча", "")
+	if err != nil {
+		t.Fatalf("Ошибка при создании метки: %v", err)
+	}
+	if tag.Color != DefaultColor {
+		t.Errorf("Ожидался цвет по умолчанию '%s', получено '%s'", DefaultColor, tag.Color)
+	}
+}
+
+func TestGetTagsByUserID(t *testing.T) {
+	repo := NewMockTagRepo()
+	service := NewService(repo)
+	ctx := context.Background()
+
+	service.CreateTag(ctx, 1, "срочно", "")
+	service.CreateTag(ctx, 1, "встреча", "")
+	service.CreateTag(ctx, 2, "личное", "")
+
+	tags, err := service.GetTagsByUserID(ctx, 1)
+	if err != nil {
+		t.Errorf("Ошибка при получении меток: %v", err)
+	}
+	if len(tags) != 2 {
+		t.Errorf("Ожидалось 2 метки, получено %d", len(tags))
+	}
+}
+
+func TestUpdateTag(t *testing.T) {
+	repo := NewMockTagRepo()
+	service := NewService(repo)
+	ctx := context.Background()
+
+	tag, _ := service.CreateTag(ctx, 1, "срочно", "#ff0000")
+
+	// Пустой цвет сохраняет текущий
+	updated, err := service.UpdateTag(ctx, tag.ID, 1, "очень срочно", "")
+	if err != nil {
+		t.Fatalf("Ошибка при обновлении метки: %v", err)
+	}
+	if updated.Name != "очень срочно" || updated.Color != "#ff0000" {
+		t.Errorf("Получено Name='%s', Color='%s'", updated.Name, updated.Color)
+	}
+
+	// Несуществующая метка
+	_, err = service.UpdateTag(ctx, 999, 1, "нет", "")
+	if !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("Ожидалась ошибка ErrTagNotFound, получено %v", err)
+	}
+
+	// Метка другого пользователя
+	_, err = service.UpdateTag(ctx, tag.ID, 2, "чужая", "")
+	if !errors.Is(err, ErrNotAuthorized) {
+		t.Errorf("Ожидалась ошибка ErrNotAuthorized, получено %v", err)
+	}
+}
+
+func TestDeleteTag(t *testing.T) {
+	repo := NewMockTagRepo()
+	service := NewService(repo)
+	ctx := context.Background()
+
+	tag, _ := service.CreateTag(ctx, 1, "срочно", "")
+
+	// Чужой пользователь не может удалить метку
+	if err := service.DeleteTag(ctx, tag.ID, 2); !errors.Is(err, ErrNotAuthorized) {
+		t.Errorf("Ожидалась ошибка ErrNotAuthorized, получено %v", err)
+	}
+
+	if err := service.DeleteTag(ctx, tag.ID, 1); err != nil {
+		t.Errorf("Ошибка при удалении метки: %v", err)
+	}
+	if _, err := repo.GetTagByID(ctx, tag.ID); err == nil {
+		t.Error("Метка не была удалена")
+	}
+}
@@ -0,0 +1,108 @@
+package timetracker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/pkg/database"
+	"github.com/graywrk/timetracker/backend/pkg/events"
+)
+
+// AutoStopEntries завершает забытые записи: активные и приостановленные записи, которые длятся
+// дольше наибольшей длительности пользователя или пересекли его ежедневное время отсечки
+// (см. models.User.AutoStopTime). Время окончания устанавливается равным моменту отсечки, а не now;
+// перерывы после этого момента отбрасываются. Такие записи отмечаются признаком AutoStopped.
+// Каждая запись завершается в своей транзакции; запись, измененная пользователем после выборки, пропускается.
+// Возвращает количество завершенных записей; ошибка одной записи не мешает обработать остальные.
+func (s *Service) AutoStopEntries(ctx context.Context, now time.Time) (int, error) {
+	entries, err := s.repo.ListUnfinishedTimeEntries(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	users := make(map[uint]*models.User)
+	stopped := 0
+	for _, entry := range entries {
+		user, ok := users[entry.UserID]
+		if !ok {
+			user, err = s.repo.GetUserByID(ctx, entry.UserID)
+			if err != nil {
+				log.Printf("Service.AutoStopEntries: Ошибка при получении пользователя %d: %v", entry.UserID, err)
+				continue
+			}
+			users[entry.UserID] = user
+		}
+
+		stopAt := user.AutoStopTime(entry.StartTime)
+		if stopAt.IsZero() || stopAt.After(now) {
+			continue
+		}
+
+		err := s.repo.WithTx(ctx, func(repo database.Repository) error {
+			return (&Service{repo: repo}).stopEntryAt(ctx, entry, stopAt)
+		})
+		if err != nil {
+			log.Printf("Service.AutoStopEntries: Ошибка при завершении записи %d: %v", entry.ID, err)
+			continue
+		}
+
+		log.Printf("Service.AutoStopEntries: Запись %d пользователя %d завершена автоматически в %v",
+			entry.ID, entry.UserID, stopAt)
+		s.publish(ctx, events.EntryStopped, entry)
+		stopped++
+	}
+
+	return stopped, nil
+}
+
+// stopEntryAt завершает незавершенную запись в момент endTime и отмечает ее как завершенную автоматически
+func (s *Service) stopEntryAt(ctx context.Context, entry *models.TimeEntry, endTime time.Time) error {
+	hasPauses := len(entry.Pauses) > 0
+	if hasPauses {
+		// Перерывы обрезаются моментом окончания, общая длительность пересчитывается по ним
+		entry.Pauses = clipPauses(entry.Pauses, endTime)
+		entry.TotalPaused = 0
+		for i := range entry.Pauses {
+			entry.TotalPaused += int64(entry.Pauses[i].EndTime.Sub(entry.Pauses[i].StartTime).Seconds())
+		}
+	} else if entry.Status == models.StatusPaused && entry.PausedAt.Before(endTime) {
+		// Записи без интервалов перерывов: добавляем продолжительность текущей паузы
+		entry.TotalPaused += int64(endTime.Sub(entry.PausedAt).Seconds())
+	}
+
+	if elapsed := int64(endTime.Sub(entry.StartTime).Seconds()); entry.TotalPaused > elapsed {
+		entry.TotalPaused = elapsed
+	}
+
+	entry.EndTime = endTime
+	entry.PausedAt = time.Time{}
+	entry.Status = models.StatusCompleted
+	entry.AutoStopped = true
+
+	if err := s.repo.UpdateTimeEntry(ctx, entry); err != nil {
+		return err
+	}
+
+	if hasPauses {
+		return s.repo.ReplacePauses(ctx, entry.ID, entry.Pauses)
+	}
+	return nil
+}
+
+// clipPauses возвращает перерывы, обрезанные моментом end: незавершенные и продолжающиеся
+// после end заканчиваются в end, начавшиеся не раньше end отбрасываются
+func clipPauses(pauses []models.Pause, end time.Time) []models.Pause {
+	result := make([]models.Pause, 0, len(pauses))
+	for _, pause := range pauses {
+		if !pause.StartTime.Before(end) {
+			continue
+		}
+		if pause.IsOpen() || pause.EndTime.After(end) {
+			pause.EndTime = end
+		}
+		result = append(result, pause)
+	}
+	return result
+}
@@ -0,0 +1,27 @@
+package timetracker
+
+import (
+	"context"
+	"log"
+
+	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/pkg/events"
+)
+
+// publish сообщает клиентам пользователя о новом состоянии записи
+func (s *Service) publish(ctx context.Context, eventType string, entry *models.TimeEntry) {
+	s.publishEvent(ctx, events.Event{Type: eventType, UserID: entry.UserID, EntryID: entry.ID, Entry: entry})
+}
+
+// publishEvent публикует событие, если сервис создан с шиной событий. Ошибка публикации
+// не отменяет сохраненное изменение: клиент получит актуальное состояние при следующем запросе.
+func (s *Service) publishEvent(ctx context.Context, event events.Event) {
+	if s.events == nil {
+		return
+	}
+
+	event.Time = timeNow()
+	if err := s.events.Publish(ctx, event); err != nil {
+		log.Printf("Service.publishEvent: Ошибка при публикации события %s пользователя %d: %v", event.Type, event.UserID, err)
+	}
+}
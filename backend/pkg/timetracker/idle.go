@@ -0,0 +1,230 @@
+package timetracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/pkg/database"
+	"github.com/graywrk/timetracker/backend/pkg/events"
+)
+
+// Варианты учета времени бездействия, когда пользователь вернулся после автоматической паузы
+const (
+	IdleKeep    = "keep"    // время бездействия считается рабочим, пауза удаляется
+	IdleDiscard = "discard" // время бездействия остается перерывом, работа продолжается
+	IdleSplit   = "split"   // запись завершается в момент начала бездействия, начинается новая
+)
+
+var (
+	// ErrInvalidIdleAction возникает при передаче неизвестного варианта учета времени бездействия
+	ErrInvalidIdleAction = errors.New("неизвестное действие: ожидается keep, discard или split")
+	// ErrNotIdle возникает, если текущая запись не приостановлена из-за бездействия
+	ErrNotIdle = errors.New("запись не приостановлена из-за бездействия")
+)
+
+// Heartbeat отмечает, что клиент пользователя активен, и возвращает текущую запись.
+// Сигнал для приостановленной записи не сохраняется: если она приостановлена из-за бездействия
+// (IdlePause), клиент должен предложить пользователю выбрать вариант учета в ResolveIdle.
+func (s *Service) Heartbeat(ctx context.Context, userID uint) (*models.TimeEntry, error) {
+	entry, err := s.GetActiveTimeEntry(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении активной записи: %w", err)
+	}
+	if entry == nil {
+		return nil, ErrNoActiveEntry
+	}
+
+	if entry.Status == models.StatusActive {
+		now := timeNow()
+		if err := s.repo.UpdateHeartbeat(ctx, entry.ID, now); err != nil {
+			return nil, err
+		}
+		entry.LastHeartbeat = now
+	}
+
+	return entry, nil
+}
+
+// PauseIdleEntries приостанавливает активные записи, от клиентов которых дольше порога бездействия
+// пользователя не было сигналов активности. Пауза начинается в момент последнего сигнала
+// и отмечается причиной models.PauseReasonIdle. Каждая запись приостанавливается в своей транзакции;
+// запись, измененная пользователем после выборки, пропускается.
+// Возвращает количество приостановленных записей; ошибка одной записи не мешает обработать остальные.
+func (s *Service) PauseIdleEntries(ctx context.Context, now time.Time) (int, error) {
+	entries, err := s.repo.ListIdleTimeEntries(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	paused := 0
+	for _, entry := range entries {
+		if entry.Status != models.StatusActive || entry.LastHeartbeat.IsZero() {
+			continue
+		}
+
+		// Пауза не может начаться раньше начала записи или последнего возобновления
+		at := entry.LastHeartbeat
+		if at.Before(entry.ResumedAt) {
+			at = entry.ResumedAt
+		}
+		if at.Before(entry.StartTime) {
+			at = entry.StartTime
+		}
+
+		err := s.repo.WithTx(ctx, func(repo database.Repository) error {
+			entry.PausedAt = at
+			entry.Status = models.StatusPaused
+			if err := repo.UpdateTimeEntry(ctx, entry); err != nil {
+				return err
+			}
+
+			pause := models.Pause{TimeEntryID: entry.ID, StartTime: at, Reason: models.PauseReasonIdle}
+			if err := repo.CreatePause(ctx, &pause); err != nil {
+				return err
+			}
+			entry.Pauses = append(entry.Pauses, pause)
+			return nil
+		})
+		if err != nil {
+			log.Printf("Service.PauseIdleEntries: Ошибка при приостановке записи %d: %v", entry.ID, err)
+			continue
+		}
+
+		log.Printf("Service.PauseIdleEntries: Запись %d пользователя %d приостановлена с %v из-за бездействия",
+			entry.ID, entry.UserID, at)
+		s.publish(ctx, events.EntryPaused, entry)
+		paused++
+	}
+
+	return paused, nil
+}
+
+// ResolveIdle применяет выбранный пользователем вариант учета времени бездействия к записи,
+// приостановленной автоматически, и возвращает продолжающуюся запись:
+// IdleKeep - пауза удаляется, время бездействия считается рабочим;
+// IdleDiscard - пауза закрывается текущим моментом, как при ResumeWork;
+// IdleSplit - запись завершается в момент начала паузы, а с текущего момента начинается
+// новая запись с той же категорией, проектом, описанием и метками.
+func (s *Service) ResolveIdle(ctx context.Context, userID uint, action string) (*models.TimeEntry, error) {
+	if action != IdleKeep && action != IdleDiscard && action != IdleSplit {
+		return nil, ErrInvalidIdleAction
+	}
+
+	var stopped *models.TimeEntry
+	entry, err := s.transition(ctx, func(tx *Service) (*models.TimeEntry, error) {
+		entry, completed, err := tx.resolveIdle(ctx, userID, action)
+		stopped = completed
+		return entry, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if action == IdleSplit {
+		s.publish(ctx, events.EntryStopped, stopped)
+		s.publish(ctx, events.EntryStarted, entry)
+	} else {
+		s.publish(ctx, events.EntryResumed, entry)
+	}
+	return entry, nil
+}
+
+// resolveIdle применяет вариант учета времени бездействия в текущей транзакции.
+// Возвращает продолженную или новую запись и, при IdleSplit, завершенную запись.
+func (s *Service) resolveIdle(ctx context.Context, userID uint, action string) (*models.TimeEntry, *models.TimeEntry, error) {
+
+	entry, err := s.findEntryWithStatus(ctx, userID, models.StatusPaused)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка при получении записей времени: %w", err)
+	}
+	if entry == nil {
+		return nil, nil, ErrNoActiveEntry
+	}
+	idle := entry.IdlePause()
+	if idle == nil {
+		return nil, nil, ErrNotIdle
+	}
+	idleStart := idle.StartTime
+
+	now := timeNow()
+	switch action {
+	case IdleKeep, IdleSplit:
+		// Перерыв бездействия удаляется: при IdleKeep это время считается рабочим,
+		// при IdleSplit запись заканчивается в момент его начала
+		pauses := withoutPause(entry.Pauses, idle)
+		if err := s.repo.ReplacePauses(ctx, entry.ID, pauses); err != nil {
+			return nil, nil, err
+		}
+		entry.Pauses = pauses
+		entry.PausedAt = time.Time{}
+
+		if action == IdleSplit {
+			entry.EndTime = idleStart
+			entry.Status = models.StatusCompleted
+			if err := s.repo.UpdateTimeEntry(ctx, entry); err != nil {
+				return nil, nil, err
+			}
+			started, err := s.startWork(ctx, userID, StartOptions{
+				CategoryID:  entry.CategoryID,
+				ProjectID:   entry.ProjectID,
+				Billable:    &entry.Billable,
+				Description: entry.Description,
+				TagIDs:      tagIDs(entry.Tags),
+			}, now)
+			if err != nil {
+				return nil, nil, err
+			}
+			return started, entry, nil
+		}
+
+		entry.ResumedAt = now
+		entry.Status = models.StatusActive
+		if err := s.repo.UpdateTimeEntry(ctx, entry); err != nil {
+			return nil, nil, err
+		}
+	case IdleDiscard:
+		entry.TotalPaused += int64(now.Sub(entry.PausedAt).Seconds())
+		entry.ResumedAt = now
+		entry.PausedAt = time.Time{}
+		entry.Status = models.StatusActive
+		if err := s.repo.UpdateTimeEntry(ctx, entry); err != nil {
+			return nil, nil, err
+		}
+		if err := s.closeOpenPause(ctx, entry, now); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Пользователь вернулся: отсчет бездействия начинается заново
+	if err := s.repo.UpdateHeartbeat(ctx, entry.ID, now); err != nil {
+		return nil, nil, err
+	}
+	entry.LastHeartbeat = now
+
+	return entry, nil, nil
+}
+
+// withoutPause возвращает копию списка перерывов без указанного перерыва
+func withoutPause(pauses []models.Pause, removed *models.Pause) []models.Pause {
+	result := make([]models.Pause, 0, len(pauses))
+	for i := range pauses {
+		if &pauses[i] == removed {
+			continue
+		}
+		result = append(result, pauses[i])
+	}
+	return result
+}
+
+// tagIDs возвращает идентификаторы меток
+func tagIDs(tags []models.Tag) []uint {
+	ids := make([]uint, 0, len(tags))
+	for _, tag := range tags {
+		ids = append(ids, tag.ID)
+	}
+	return ids
+}
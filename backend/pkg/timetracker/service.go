@@ -2,12 +2,17 @@ package timetracker
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/graywrk/timetracker/backend/internal/models"
 	"github.com/graywrk/timetracker/backend/pkg/database"
+	"github.com/graywrk/timetracker/backend/pkg/events"
 )
 
 var (
@@ -19,11 +24,67 @@ var (
 	ErrEntryAlreadyPaused = errors.New("запись уже приостановлена")
 	// ErrEntryNotPaused возникает при попытке возобновить не приостановленную запись
 	ErrEntryNotPaused = errors.New("запись не приостановлена")
+	// ErrEntryNotFound возникает, если запись не найдена
+	ErrEntryNotFound = errors.New("запись не найдена")
+	// ErrNotEntryOwner возникает при попытке изменить чужую запись
+	ErrNotEntryOwner = errors.New("у вас нет прав на изменение этой записи")
+	// ErrInvalidTimeRange возникает, если время окончания не позже времени начала
+	ErrInvalidTimeRange = errors.New("время окончания должно быть позже времени начала")
+	// ErrInvalidPause возникает, если длительность пауз некорректна
+	ErrInvalidPause = errors.New("длительность пауз должна быть неотрицательной и не превышать длительность записи")
+	// ErrEntryOverlap возникает, если запись пересекается с другой записью пользователя
+	ErrEntryOverlap = errors.New("запись пересекается с другой записью")
+	// ErrCategoryNotOwned возникает при использовании чужой категории
+	ErrCategoryNotOwned = errors.New("категория не принадлежит пользователю")
+	// ErrTagNotOwned возникает при использовании чужой или несуществующей метки
+	ErrTagNotOwned = errors.New("метка не принадлежит пользователю")
+	// ErrProjectNotOwned возникает при использовании чужого или несуществующего проекта
+	ErrProjectNotOwned = errors.New("проект не принадлежит пользователю")
+	// ErrProjectArchived возникает при попытке записать время в архивный проект
+	ErrProjectArchived = errors.New("проект находится в архиве")
+	// ErrCategoryNotInProject возникает, если категория не входит в типы задач проекта
+	ErrCategoryNotInProject = errors.New("категория не используется в этом проекте")
+	// ErrInvalidCursor возникает при передаче некорректного курсора постраничной выборки
+	ErrInvalidCursor = errors.New("некорректный курсор")
+	// ErrConflict возникает, если запись была изменена параллельным запросом после чтения
+	ErrConflict = errors.New("запись была изменена другим запросом, обновите данные и повторите попытку")
 )
 
+const (
+	// DefaultPageSize - количество записей на странице по умолчанию
+	DefaultPageSize = 50
+	// MaxPageSize - максимальное количество записей на странице
+	MaxPageSize = 200
+)
+
+// EntryPage представляет страницу записей о времени
+type EntryPage struct {
+	Entries    []*models.TimeEntry `json:"entries"`
+	NextCursor string              `json:"next_cursor,omitempty"` // пусто, если страница последняя
+}
+
+// EntryInput содержит данные для ручного создания или редактирования записи
+type EntryInput struct {
+	StartTime   time.Time
+	EndTime     time.Time // для незавершенных записей должно быть пустым
+	TotalPaused int64     // в секундах
+	CategoryID  *uint
+	ProjectID   *uint
+	// Billable - признак оплачиваемой записи; nil означает true при создании
+	// и сохранение текущего значения при редактировании
+	Billable    *bool
+	Description string
+	// TagIDs - метки записи; nil при редактировании оставляет метки без изменений
+	TagIDs []uint
+	// Pauses - интервалы перерывов завершенной записи. Если заданы, заменяют
+	// сохраненные интервалы, а TotalPaused вычисляется по ним.
+	Pauses []models.Pause
+}
+
 // Service предоставляет методы для работы с временем
 type Service struct {
-	repo database.Repository
+	repo   database.Repository
+	events events.Publisher
 }
 
 // NewService создает новый сервис учета времени
@@ -33,53 +94,95 @@ func NewService(repo database.Repository) *Service {
 	}
 }
 
+// NewServiceWithEvents создает сервис учета времени, который сообщает об изменениях записей через publisher
+func NewServiceWithEvents(repo database.Repository, publisher events.Publisher) *Service {
+	return &Service{
+		repo:   repo,
+		events: publisher,
+	}
+}
+
+// transition выполняет изменение состояния записи в транзакции: fn получает сервис, работающий
+// через репозиторий транзакции. Параллельное изменение записи (database.ErrConflict) откатывает
+// транзакцию и возвращается как ErrConflict.
+func (s *Service) transition(ctx context.Context, fn func(tx *Service) (*models.TimeEntry, error)) (*models.TimeEntry, error) {
+	var entry *models.TimeEntry
+	err := s.repo.WithTx(ctx, func(repo database.Repository) error {
+		var err error
+		entry, err = fn(&Service{repo: repo})
+		return err
+	})
+	if errors.Is(err, database.ErrConflict) {
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
 // GetActiveTimeEntry возвращает активную запись времени для пользователя
 func (s *Service) GetActiveTimeEntry(ctx context.Context, userID uint) (*models.TimeEntry, error) {
-	// Получаем записи времени пользователя
-	entries, err := s.repo.GetTimeEntriesByUserID(ctx, userID)
+	// Ищем незавершенную запись (статус active или paused)
+	entry, err := s.findEntryWithStatus(ctx, userID, models.StatusActive, models.StatusPaused)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка при получении записей времени: %w", err)
 	}
 
-	// Находим активную запись (статус active или paused)
-	for _, e := range entries {
-		if e.Status != models.StatusCompleted {
-			return e, nil
-		}
+	return entry, nil
+}
+
+// findEntryWithStatus возвращает последнюю запись пользователя с одним из указанных статусов или nil
+func (s *Service) findEntryWithStatus(ctx context.Context, userID uint, statuses ...models.Status) (*models.TimeEntry, error) {
+	entries, err := s.repo.ListTimeEntries(ctx, database.TimeEntryFilter{
+		UserID:   userID,
+		Statuses: statuses,
+		Limit:    1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 {
+		return nil, nil
 	}
 
-	// Активная запись не найдена
-	return nil, nil
+	return entries[0], nil
+}
+
+// StartOptions содержит необязательные параметры новой записи
+type StartOptions struct {
+	CategoryID  *uint
+	ProjectID   *uint
+	Billable    *bool // nil - запись оплачиваемая
+	Description string
+	TagIDs      []uint
 }
 
 // StartWork начинает новую запись о рабочем времени
 func (s *Service) StartWork(ctx context.Context, userID uint) (*models.TimeEntry, error) {
-	// Проверяем, что у пользователя нет активной записи
-	activeEntry, err := s.GetActiveTimeEntry(ctx, userID)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка при проверке активных записей: %w", err)
-	}
-	if activeEntry != nil {
-		return nil, ErrActiveEntryExists
-	}
+	return s.StartWorkWithOptions(ctx, userID, StartOptions{})
+}
 
-	// Создаем новую запись
-	now := timeNow()
-	entry := &models.TimeEntry{
-		UserID:    userID,
-		StartTime: now,
-		Status:    models.StatusActive,
-	}
+// StartWorkWithCategory начинает новую запись о рабочем времени с указанной категорией
+func (s *Service) StartWorkWithCategory(ctx context.Context, userID, categoryID uint) (*models.TimeEntry, error) {
+	return s.StartWorkWithOptions(ctx, userID, StartOptions{CategoryID: &categoryID})
+}
 
-	if err := s.repo.CreateTimeEntry(ctx, entry); err != nil {
+// StartWorkWithOptions начинает новую запись о рабочем времени с категорией, описанием и метками
+func (s *Service) StartWorkWithOptions(ctx context.Context, userID uint, opts StartOptions) (*models.TimeEntry, error) {
+	entry, err := s.transition(ctx, func(tx *Service) (*models.TimeEntry, error) {
+		return tx.startWork(ctx, userID, opts, timeNow())
+	})
+	if err != nil {
 		return nil, err
 	}
-
+	s.publish(ctx, events.EntryStarted, entry)
 	return entry, nil
 }
 
-// StartWorkWithCategory начинает новую запись о рабочем времени с указанной категорией
-func (s *Service) StartWorkWithCategory(ctx context.Context, userID, categoryID uint) (*models.TimeEntry, error) {
+// startWork начинает новую запись в момент at
+func (s *Service) startWork(ctx context.Context, userID uint, opts StartOptions, at time.Time) (*models.TimeEntry, error) {
 	// Проверяем, что у пользователя нет активной записи
 	activeEntry, err := s.GetActiveTimeEntry(ctx, userID)
 	if err != nil {
@@ -89,30 +192,41 @@ func (s *Service) StartWorkWithCategory(ctx context.Context, userID, categoryID
 		return nil, ErrActiveEntryExists
 	}
 
-	// Проверяем существование категории и права доступа
-	category, err := s.repo.GetCategoryByID(ctx, categoryID)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка при получении категории: %w", err)
-	}
-
-	if category.UserID != userID {
-		return nil, fmt.Errorf("категория не принадлежит пользователю")
+	// Проверяем существование категории и меток и права доступа
+	if err := s.validateStartOptions(ctx, userID, opts); err != nil {
+		return nil, err
 	}
 
 	// Создаем новую запись
-	now := timeNow()
 	entry := &models.TimeEntry{
-		UserID:     userID,
-		StartTime:  now,
-		Status:     models.StatusActive,
-		CategoryID: &categoryID,
+		UserID:      userID,
+		StartTime:   at,
+		Status:      models.StatusActive,
+		CategoryID:  opts.CategoryID,
+		ProjectID:   opts.ProjectID,
+		Billable:    boolOrDefault(opts.Billable, true),
+		Description: strings.TrimSpace(opts.Description),
 	}
 
 	if err := s.repo.CreateTimeEntry(ctx, entry); err != nil {
+		// Параллельный запрос уже начал запись: уникальный индекс допускает только одну незавершенную
+		if errors.Is(err, database.ErrConflict) {
+			return nil, ErrActiveEntryExists
+		}
 		return nil, err
 	}
 
-	// Получаем полную запись с данными категории
+	if len(opts.TagIDs) > 0 {
+		if err := s.repo.SetTimeEntryTags(ctx, entry.ID, opts.TagIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	if entry.CategoryID == nil && len(opts.TagIDs) == 0 {
+		return entry, nil
+	}
+
+	// Получаем полную запись с данными категории и меток
 	fullEntry, err := s.repo.GetTimeEntryByID(ctx, entry.ID)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка при получении созданной записи: %w", err)
@@ -121,21 +235,35 @@ func (s *Service) StartWorkWithCategory(ctx context.Context, userID, categoryID
 	return fullEntry, nil
 }
 
+// validateStartOptions проверяет, что категория, проект и метки новой записи принадлежат пользователю
+func (s *Service) validateStartOptions(ctx context.Context, userID uint, opts StartOptions) error {
+	if err := s.validateCategory(ctx, userID, opts.CategoryID); err != nil {
+		return err
+	}
+	if err := s.validateProject(ctx, userID, opts.ProjectID, opts.CategoryID, false); err != nil {
+		return err
+	}
+	return s.validateTags(ctx, userID, opts.TagIDs)
+}
+
 // PauseWork приостанавливает текущую работу
 func (s *Service) PauseWork(ctx context.Context, userID uint) (*models.TimeEntry, error) {
-	// Получаем записи времени пользователя
-	entries, err := s.repo.GetTimeEntriesByUserID(ctx, userID)
+	entry, err := s.transition(ctx, func(tx *Service) (*models.TimeEntry, error) {
+		return tx.pauseWork(ctx, userID, timeNow())
+	})
 	if err != nil {
-		return nil, fmt.Errorf("ошибка при получении записей времени: %w", err)
+		return nil, err
 	}
+	s.publish(ctx, events.EntryPaused, entry)
+	return entry, nil
+}
 
+// pauseWork приостанавливает текущую работу в момент at в текущей транзакции
+func (s *Service) pauseWork(ctx context.Context, userID uint, at time.Time) (*models.TimeEntry, error) {
 	// Находим активную запись
-	var activeEntry *models.TimeEntry
-	for _, e := range entries {
-		if e.Status == models.StatusActive {
-			activeEntry = e
-			break
-		}
+	activeEntry, err := s.findEntryWithStatus(ctx, userID, models.StatusActive)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении записей времени: %w", err)
 	}
 
 	if activeEntry == nil {
@@ -148,7 +276,7 @@ func (s *Service) PauseWork(ctx context.Context, userID uint) (*models.TimeEntry
 	}
 
 	// Приостанавливаем запись
-	now := timeNow()
+	now := at
 	activeEntry.PausedAt = now
 	activeEntry.Status = models.StatusPaused
 
@@ -157,24 +285,34 @@ func (s *Service) PauseWork(ctx context.Context, userID uint) (*models.TimeEntry
 		return nil, err
 	}
 
+	// Открываем новый интервал перерыва
+	pause := models.Pause{TimeEntryID: activeEntry.ID, StartTime: now}
+	if err := s.repo.CreatePause(ctx, &pause); err != nil {
+		return nil, err
+	}
+	activeEntry.Pauses = append(activeEntry.Pauses, pause)
+
 	return activeEntry, nil
 }
 
 // ResumeWork возобновляет приостановленную работу
 func (s *Service) ResumeWork(ctx context.Context, userID uint) (*models.TimeEntry, error) {
-	// Получаем записи времени пользователя
-	entries, err := s.repo.GetTimeEntriesByUserID(ctx, userID)
+	entry, err := s.transition(ctx, func(tx *Service) (*models.TimeEntry, error) {
+		return tx.resumeWork(ctx, userID)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("ошибка при получении записей времени: %w", err)
+		return nil, err
 	}
+	s.publish(ctx, events.EntryResumed, entry)
+	return entry, nil
+}
 
+// resumeWork возобновляет приостановленную работу в текущей транзакции
+func (s *Service) resumeWork(ctx context.Context, userID uint) (*models.TimeEntry, error) {
 	// Находим приостановленную запись
-	var pausedEntry *models.TimeEntry
-	for _, e := range entries {
-		if e.Status == models.StatusPaused {
-			pausedEntry = e
-			break
-		}
+	pausedEntry, err := s.findEntryWithStatus(ctx, userID, models.StatusPaused)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении записей времени: %w", err)
 	}
 
 	if pausedEntry == nil {
@@ -200,24 +338,40 @@ func (s *Service) ResumeWork(ctx context.Context, userID uint) (*models.TimeEntr
 		return nil, err
 	}
 
+	// Закрываем текущий интервал перерыва
+	if err := s.closeOpenPause(ctx, pausedEntry, now); err != nil {
+		return nil, err
+	}
+
+	// Отсчет бездействия начинается заново, иначе запись сразу снова будет приостановлена
+	if !pausedEntry.LastHeartbeat.IsZero() {
+		if err := s.repo.UpdateHeartbeat(ctx, pausedEntry.ID, now); err != nil {
+			return nil, err
+		}
+		pausedEntry.LastHeartbeat = now
+	}
+
 	return pausedEntry, nil
 }
 
 // StopWork завершает текущую работу
 func (s *Service) StopWork(ctx context.Context, userID uint) (*models.TimeEntry, error) {
-	// Получаем активную запись пользователя
-	entry, err := s.repo.GetTimeEntriesByUserID(ctx, userID)
+	entry, err := s.transition(ctx, func(tx *Service) (*models.TimeEntry, error) {
+		return tx.stopWork(ctx, userID, timeNow())
+	})
 	if err != nil {
-		return nil, fmt.Errorf("ошибка при получении записей времени: %w", err)
+		return nil, err
 	}
+	s.publish(ctx, events.EntryStopped, entry)
+	return entry, nil
+}
 
-	// Находим активную запись
-	var activeEntry *models.TimeEntry
-	for _, e := range entry {
-		if e.Status != models.StatusCompleted {
-			activeEntry = e
-			break
-		}
+// stopWork завершает текущую работу в момент at
+func (s *Service) stopWork(ctx context.Context, userID uint, at time.Time) (*models.TimeEntry, error) {
+	// Находим незавершенную запись пользователя
+	activeEntry, err := s.findEntryWithStatus(ctx, userID, models.StatusActive, models.StatusPaused)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении записей времени: %w", err)
 	}
 
 	if activeEntry == nil {
@@ -225,7 +379,7 @@ func (s *Service) StopWork(ctx context.Context, userID uint) (*models.TimeEntry,
 	}
 
 	// Завершаем запись
-	now := timeNow()
+	now := at
 
 	// Если запись была на паузе, добавляем продолжительность паузы
 	if activeEntry.Status == models.StatusPaused {
@@ -242,12 +396,82 @@ func (s *Service) StopWork(ctx context.Context, userID uint) (*models.TimeEntry,
 		return nil, err
 	}
 
+	// Закрываем перерыв, если запись завершается на паузе
+	if err := s.closeOpenPause(ctx, activeEntry, now); err != nil {
+		return nil, err
+	}
+
 	return activeEntry, nil
 }
 
-// GetUserStats получает статистику пользователя за указанный период
+// SwitchWork переключает работу: текущая запись завершается в момент T, и в тот же момент
+// начинается новая с параметрами opts, так что между ними нет разрыва. Если текущая запись
+// приостановлена, ее перерыв длится до T, а новая запись начинается приостановленной в T -
+// перерыв продолжается, пока пользователь не возобновит работу. Оба изменения выполняются
+// в одной транзакции. Возвращает завершенную и начатую записи.
+func (s *Service) SwitchWork(ctx context.Context, userID uint, opts StartOptions) (*models.TimeEntry, *models.TimeEntry, error) {
+	var stopped *models.TimeEntry
+	started, err := s.transition(ctx, func(tx *Service) (*models.TimeEntry, error) {
+		current, err := tx.GetActiveTimeEntry(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if current == nil {
+			return nil, ErrNoActiveEntry
+		}
+		paused := current.Status == models.StatusPaused
+
+		// Параметры новой записи проверяются до завершения текущей
+		if err := tx.validateStartOptions(ctx, userID, opts); err != nil {
+			return nil, err
+		}
+
+		at := timeNow()
+		stopped, err = tx.stopWork(ctx, userID, at)
+		if err != nil {
+			return nil, err
+		}
+
+		entry, err := tx.startWork(ctx, userID, opts, at)
+		if err != nil {
+			return nil, err
+		}
+		if paused {
+			return tx.pauseWork(ctx, userID, at)
+		}
+		return entry, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	s.publish(ctx, events.EntryStopped, stopped)
+	s.publish(ctx, events.EntryStarted, started)
+	return stopped, started, nil
+}
+
+// closeOpenPause завершает незакрытый интервал перерыва записи, если он есть
+func (s *Service) closeOpenPause(ctx context.Context, entry *models.TimeEntry, endTime time.Time) error {
+	pause := entry.OpenPause()
+	if pause == nil {
+		return nil
+	}
+
+	pause.EndTime = endTime
+	return s.repo.UpdatePause(ctx, pause)
+}
+
+// GetUserStats получает записи пользователя за указанный период (даты YYYY-MM-DD включительно, в UTC)
 func (s *Service) GetUserStats(ctx context.Context, userID uint, startDate, endDate string) ([]*models.TimeEntry, error) {
-	return s.repo.GetUserStatsByPeriod(ctx, userID, startDate, endDate)
+	from, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		return nil, fmt.Errorf("некорректная дата начала периода: %w", err)
+	}
+	to, err := time.Parse("2006-01-02", endDate)
+	if err != nil {
+		return nil, fmt.Errorf("некорректная дата окончания периода: %w", err)
+	}
+
+	return s.repo.GetUserStatsByPeriod(ctx, userID, from, to.AddDate(0, 0, 1), nil)
 }
 
 // GetTotalWorkDuration вычисляет общее отработанное время за указанный период в секундах
@@ -279,5 +503,490 @@ func (s *Service) DeleteTimeEntry(ctx context.Context, entryID, userID uint) err
 	}
 
 	// Удаляем запись
-	return s.repo.DeleteTimeEntry(ctx, entryID)
+	if err := s.repo.DeleteTimeEntry(ctx, entryID); err != nil {
+		return err
+	}
+	s.publishEvent(ctx, events.Event{Type: events.EntryDeleted, UserID: userID, EntryID: entryID})
+	return nil
+}
+
+// CreateManualEntry создает завершенную запись с явно указанными временем начала, окончания и пауз
+func (s *Service) CreateManualEntry(ctx context.Context, userID uint, input EntryInput) (*models.TimeEntry, error) {
+	entry, err := s.transition(ctx, func(tx *Service) (*models.TimeEntry, error) {
+		return tx.createManualEntry(ctx, userID, input)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, events.EntryCreated, entry)
+	return entry, nil
+}
+
+// createManualEntry создает запись в текущей транзакции
+func (s *Service) createManualEntry(ctx context.Context, userID uint, input EntryInput) (*models.TimeEntry, error) {
+	if input.StartTime.IsZero() || input.EndTime.IsZero() || !input.EndTime.After(input.StartTime) {
+		return nil, ErrInvalidTimeRange
+	}
+
+	if err := normalizePauses(&input, input.EndTime); err != nil {
+		return nil, err
+	}
+
+	// Параллельные изменения записей пользователя ждут конца транзакции,
+	// иначе обе могут пройти проверку пересечений
+	if err := s.repo.LockUserEntries(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateEntryInput(ctx, userID, 0, input, input.EndTime, false); err != nil {
+		return nil, err
+	}
+
+	entry := &models.TimeEntry{
+		UserID:      userID,
+		StartTime:   input.StartTime,
+		EndTime:     input.EndTime,
+		TotalPaused: input.TotalPaused,
+		Status:      models.StatusCompleted,
+		CategoryID:  input.CategoryID,
+		ProjectID:   input.ProjectID,
+		Billable:    boolOrDefault(input.Billable, true),
+		Description: strings.TrimSpace(input.Description),
+	}
+
+	if err := s.repo.CreateTimeEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	if len(input.Pauses) > 0 {
+		if err := s.repo.ReplacePauses(ctx, entry.ID, input.Pauses); err != nil {
+			return nil, err
+		}
+		entry.Pauses = input.Pauses
+	}
+
+	if len(input.TagIDs) > 0 {
+		if err := s.repo.SetTimeEntryTags(ctx, entry.ID, input.TagIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	// Получаем полную запись с данными категории и меток
+	if entry.CategoryID != nil || len(input.TagIDs) > 0 {
+		fullEntry, err := s.repo.GetTimeEntryByID(ctx, entry.ID)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при получении созданной записи: %w", err)
+		}
+		entry = fullEntry
+	}
+
+	return entry, nil
+}
+
+// UpdateTimeEntry изменяет время начала, окончания, паузы и категорию существующей записи
+func (s *Service) UpdateTimeEntry(ctx context.Context, entryID, userID uint, input EntryInput) (*models.TimeEntry, error) {
+	entry, err := s.transition(ctx, func(tx *Service) (*models.TimeEntry, error) {
+		return tx.updateTimeEntry(ctx, entryID, userID, input)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, events.EntryUpdated, entry)
+	return entry, nil
+}
+
+// getTimeEntry получает запись по ID; ErrEntryNotFound возвращается, только если записи нет,
+// а ошибки базы данных передаются дальше
+func (s *Service) getTimeEntry(ctx context.Context, entryID uint) (*models.TimeEntry, error) {
+	entry, err := s.repo.GetTimeEntryByID(ctx, entryID)
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, ErrEntryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении записи: %w", err)
+	}
+	if entry == nil {
+		return nil, ErrEntryNotFound
+	}
+	return entry, nil
+}
+
+// updateTimeEntry изменяет запись в текущей транзакции
+func (s *Service) updateTimeEntry(ctx context.Context, entryID, userID uint, input EntryInput) (*models.TimeEntry, error) {
+	// Получаем запись по ID
+	entry, err := s.getTimeEntry(ctx, entryID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Проверяем, что запись принадлежит пользователю
+	if entry.UserID != userID {
+		return nil, ErrNotEntryOwner
+	}
+
+	if input.StartTime.IsZero() {
+		return nil, ErrInvalidTimeRange
+	}
+
+	if err := s.repo.LockUserEntries(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	// Для незавершенной записи время окончания не задается, а длительность
+	// считается до текущего момента (или до начала текущей паузы)
+	endTime := input.EndTime
+	if entry.Status == models.StatusCompleted {
+		if endTime.IsZero() || !endTime.After(input.StartTime) {
+			return nil, ErrInvalidTimeRange
+		}
+	} else {
+		if !endTime.IsZero() {
+			return nil, ErrInvalidTimeRange
+		}
+		endTime = timeNow()
+		if entry.Status == models.StatusPaused && !entry.PausedAt.IsZero() {
+			endTime = entry.PausedAt
+		}
+		if endTime.Before(input.StartTime) {
+			return nil, ErrInvalidTimeRange
+		}
+	}
+
+	// Интервалы перерывов можно задавать только для завершенной записи
+	if input.Pauses != nil && entry.Status != models.StatusCompleted {
+		return nil, ErrInvalidPause
+	}
+
+	// Решаем, что делать с сохраненными интервалами перерывов: заменить переданными,
+	// оставить как есть или удалить, если пользователь указал другую общую длительность
+	replacePauses := input.Pauses != nil
+	if input.Pauses != nil {
+		if err := normalizePauses(&input, endTime); err != nil {
+			return nil, err
+		}
+	} else if len(entry.Pauses) > 0 {
+		if input.TotalPaused == entry.TotalPaused {
+			input.TotalPaused = entry.PausedDurationUntil(endTime)
+		} else if entry.Status == models.StatusCompleted {
+			replacePauses = true
+		} else {
+			// У незавершенной записи интервалы отражают реальные перерывы
+			return nil, ErrInvalidPause
+		}
+	}
+
+	// Запись, уже привязанная к архивному проекту, может оставаться в нем
+	keepsProject := entry.ProjectID != nil && input.ProjectID != nil && *entry.ProjectID == *input.ProjectID
+	if err := s.validateEntryInput(ctx, userID, entry.ID, input, endTime, keepsProject); err != nil {
+		return nil, err
+	}
+
+	// Исправленное пользователем время автоматически завершенной записи считается проверенным
+	if entry.AutoStopped && (!input.StartTime.Equal(entry.StartTime) || !input.EndTime.Equal(entry.EndTime)) {
+		entry.AutoStopped = false
+	}
+
+	entry.StartTime = input.StartTime
+	entry.EndTime = input.EndTime
+	entry.TotalPaused = input.TotalPaused
+	entry.CategoryID = input.CategoryID
+	entry.ProjectID = input.ProjectID
+	entry.Billable = boolOrDefault(input.Billable, entry.Billable)
+	entry.Description = strings.TrimSpace(input.Description)
+	if input.CategoryID == nil {
+		entry.Category = nil
+	}
+
+	if err := s.repo.UpdateTimeEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	if replacePauses {
+		if err := s.repo.ReplacePauses(ctx, entry.ID, input.Pauses); err != nil {
+			return nil, err
+		}
+	}
+
+	if input.TagIDs != nil {
+		if err := s.repo.SetTimeEntryTags(ctx, entry.ID, input.TagIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	// Получаем полную запись с актуальными данными категории
+	fullEntry, err := s.repo.GetTimeEntryByID(ctx, entry.ID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении обновленной записи: %w", err)
+	}
+
+	return fullEntry, nil
+}
+
+// normalizePauses проверяет интервалы перерывов и вычисляет по ним TotalPaused.
+// Интервалы должны быть завершены, не пересекаться и лежать внутри записи.
+func normalizePauses(input *EntryInput, endTime time.Time) error {
+	if len(input.Pauses) == 0 {
+		return nil
+	}
+
+	pauses := make([]models.Pause, len(input.Pauses))
+	copy(pauses, input.Pauses)
+	sort.Slice(pauses, func(i, j int) bool {
+		return pauses[i].StartTime.Before(pauses[j].StartTime)
+	})
+
+	var total int64
+	for i, pause := range pauses {
+		if pause.IsOpen() || !pause.EndTime.After(pause.StartTime) {
+			return ErrInvalidPause
+		}
+		if pause.StartTime.Before(input.StartTime) || pause.EndTime.After(endTime) {
+			return ErrInvalidPause
+		}
+		if i > 0 && pause.StartTime.Before(pauses[i-1].EndTime) {
+			return ErrInvalidPause
+		}
+		pauses[i].ID = 0
+		total += int64(pause.EndTime.Sub(pause.StartTime).Seconds())
+	}
+
+	input.Pauses = pauses
+	input.TotalPaused = total
+	return nil
+}
+
+// validateEntryInput проверяет паузы, категорию, проект и пересечения с другими записями пользователя
+func (s *Service) validateEntryInput(ctx context.Context, userID, excludeID uint, input EntryInput, endTime time.Time, allowArchivedProject bool) error {
+	if input.TotalPaused < 0 || input.TotalPaused > int64(endTime.Sub(input.StartTime).Seconds()) {
+		return ErrInvalidPause
+	}
+
+	// Проверяем существование категории и меток и права доступа
+	if err := s.validateCategory(ctx, userID, input.CategoryID); err != nil {
+		return err
+	}
+	if err := s.validateProject(ctx, userID, input.ProjectID, input.CategoryID, allowArchivedProject); err != nil {
+		return err
+	}
+	if err := s.validateTags(ctx, userID, input.TagIDs); err != nil {
+		return err
+	}
+
+	// Проверяем пересечения с другими записями пользователя
+	entries, err := s.repo.ListTimeEntries(ctx, database.TimeEntryFilter{
+		UserID: userID,
+		From:   input.StartTime,
+		To:     endTime,
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка при получении записей времени: %w", err)
+	}
+
+	for _, e := range entries {
+		if e.ID != excludeID {
+			return ErrEntryOverlap
+		}
+	}
+
+	return nil
+}
+
+// validateCategory проверяет, что категория существует и принадлежит пользователю
+func (s *Service) validateCategory(ctx context.Context, userID uint, categoryID *uint) error {
+	if categoryID == nil {
+		return nil
+	}
+
+	category, err := s.repo.GetCategoryByID(ctx, *categoryID)
+	if err != nil {
+		return fmt.Errorf("ошибка при получении категории: %w", err)
+	}
+	if category == nil || category.UserID != userID {
+		return ErrCategoryNotOwned
+	}
+
+	return nil
+}
+
+// boolOrDefault возвращает значение необязательного флага или значение по умолчанию
+func boolOrDefault(value *bool, def bool) bool {
+	if value == nil {
+		return def
+	}
+	return *value
+}
+
+// validateProject проверяет, что проект принадлежит пользователю, не находится в архиве
+// (если это не разрешено явно) и допускает выбранную категорию
+func (s *Service) validateProject(ctx context.Context, userID uint, projectID, categoryID *uint, allowArchived bool) error {
+	if projectID == nil {
+		return nil
+	}
+
+	project, err := s.repo.GetProjectByID(ctx, *projectID)
+	if err != nil || project == nil || project.UserID != userID {
+		return ErrProjectNotOwned
+	}
+	if project.Archived && !allowArchived {
+		return ErrProjectArchived
+	}
+	if !project.AllowsCategory(categoryID) {
+		return ErrCategoryNotInProject
+	}
+
+	return nil
+}
+
+// validateTags проверяет, что все метки существуют и принадлежат пользователю
+func (s *Service) validateTags(ctx context.Context, userID uint, tagIDs []uint) error {
+	if len(tagIDs) == 0 {
+		return nil
+	}
+
+	tags, err := s.repo.GetTagsByUserID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("ошибка при получении меток: %w", err)
+	}
+
+	owned := make(map[uint]bool, len(tags))
+	for _, tag := range tags {
+		owned[tag.ID] = true
+	}
+
+	for _, id := range tagIDs {
+		if !owned[id] {
+			return ErrTagNotOwned
+		}
+	}
+
+	return nil
+}
+
+// UpdateEntryDetails изменяет описание и метки записи, в том числе незавершенной.
+// Если tagIDs равен nil, метки остаются без изменений.
+func (s *Service) UpdateEntryDetails(ctx context.Context, entryID, userID uint, description string, tagIDs []uint) (*models.TimeEntry, error) {
+	entry, err := s.transition(ctx, func(tx *Service) (*models.TimeEntry, error) {
+		return tx.updateEntryDetails(ctx, entryID, userID, description, tagIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, events.EntryUpdated, entry)
+	return entry, nil
+}
+
+// updateEntryDetails изменяет описание и метки записи в текущей транзакции
+func (s *Service) updateEntryDetails(ctx context.Context, entryID, userID uint, description string, tagIDs []uint) (*models.TimeEntry, error) {
+	entry, err := s.getTimeEntry(ctx, entryID)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.UserID != userID {
+		return nil, ErrNotEntryOwner
+	}
+
+	if err := s.validateTags(ctx, userID, tagIDs); err != nil {
+		return nil, err
+	}
+
+	entry.Description = strings.TrimSpace(description)
+	if err := s.repo.UpdateTimeEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	if tagIDs != nil {
+		if err := s.repo.SetTimeEntryTags(ctx, entry.ID, tagIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	fullEntry, err := s.repo.GetTimeEntryByID(ctx, entry.ID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении обновленной записи: %w", err)
+	}
+
+	return fullEntry, nil
+}
+
+// ListTimeEntries возвращает страницу записей пользователя, удовлетворяющих фильтру.
+// Курсор берется из NextCursor предыдущей страницы; пустой курсор означает первую страницу.
+func (s *Service) ListTimeEntries(ctx context.Context, filter database.TimeEntryFilter, cursor string) (*EntryPage, error) {
+	if cursor != "" {
+		after, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter.After = after
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultPageSize
+	}
+	if filter.Limit > MaxPageSize {
+		filter.Limit = MaxPageSize
+	}
+
+	// Запрашиваем на одну запись больше, чтобы понять, есть ли следующая страница
+	pageSize := filter.Limit
+	filter.Limit++
+
+	entries, err := s.repo.ListTimeEntries(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении записей времени: %w", err)
+	}
+
+	page := &EntryPage{Entries: entries}
+	if entries == nil {
+		page.Entries = []*models.TimeEntry{}
+	}
+	if len(entries) > pageSize {
+		page.Entries = entries[:pageSize]
+		last := page.Entries[pageSize-1]
+		page.NextCursor = encodeCursor(database.TimeEntryCursor{StartTime: last.StartTime, ID: last.ID})
+	}
+
+	return page, nil
+}
+
+// UserLocation возвращает часовой пояс из профиля пользователя, в котором задаются даты фильтра записей
+func (s *Service) UserLocation(ctx context.Context, userID uint) (*time.Location, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return time.UTC, nil
+	}
+	return user.Location(), nil
+}
+
+// encodeCursor кодирует позицию записи в непрозрачную строку
+func encodeCursor(cursor database.TimeEntryCursor) string {
+	raw := fmt.Sprintf("%d:%d", cursor.StartTime.UnixNano(), cursor.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor восстанавливает позицию записи из строки, полученной от encodeCursor
+func decodeCursor(cursor string) (*database.TimeEntryCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return nil, ErrInvalidCursor
+	}
+
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &database.TimeEntryCursor{StartTime: time.Unix(0, nanos), ID: uint(id)}, nil
 }
@@ -3,26 +3,43 @@ package timetracker
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
 	"testing"
 	"time"
 
 	"github.com/graywrk/timetracker/backend/internal/models"
+	"github.com/graywrk/timetracker/backend/internal/testutil"
+	"github.com/graywrk/timetracker/backend/pkg/database"
+	"github.com/graywrk/timetracker/backend/pkg/events"
 	"github.com/stretchr/testify/assert"
 )
 
 // MockRepository представляет мок репозитория для тестирования
 type MockRepository struct {
+	testutil.Repository
+
 	activeTimeEntry *models.TimeEntry
+	users           map[uint]*models.User
 	entries         map[uint]*models.TimeEntry
+	categories      map[uint]*models.Category
+	tags            map[uint]*models.Tag
+	projects        map[uint]*models.Project
 	nextID          uint
+	nextPauseID     uint
+	idleTimeout     time.Duration
 	err             error
 }
 
 // NewMockRepository создает новый мок репозитория
 func NewMockRepository() *MockRepository {
 	return &MockRepository{
-		entries: make(map[uint]*models.TimeEntry),
-		nextID:  1,
+		users:      make(map[uint]*models.User),
+		entries:    make(map[uint]*models.TimeEntry),
+		categories: make(map[uint]*models.Category),
+		tags:       make(map[uint]*models.Tag),
+		projects:   make(map[uint]*models.Project),
+		nextID:     1,
 	}
 }
 
@@ -31,29 +48,16 @@ func (m *MockRepository) SetError(err error) {
 	m.err = err
 }
 
-// CreateUser мок метода
-func (m *MockRepository) CreateUser(ctx context.Context, user *models.User) error {
-	return m.err
-}
-
 // GetUserByID мок метода
 func (m *MockRepository) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
-	return nil, m.err
-}
-
-// GetUserByEmail мок метода
-func (m *MockRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
-	return nil, m.err
-}
-
-// UpdateUser мок метода
-func (m *MockRepository) UpdateUser(ctx context.Context, user *models.User) error {
-	return m.err
-}
-
-// DeleteUser мок метода
-func (m *MockRepository) DeleteUser(ctx context.Context, id uint) error {
-	return m.err
+	if m.err != nil {
+		return nil, m.err
+	}
+	user, exists := m.users[id]
+	if !exists {
+		return nil, errors.New("пользователь не найден")
+	}
+	return user, nil
 }
 
 // CreateTimeEntry мок метода
@@ -61,6 +65,15 @@ func (m *MockRepository) CreateTimeEntry(ctx context.Context, entry *models.Time
 	if m.err != nil {
 		return m.err
 	}
+	// Как и PostgresRepository, незавершенные записи сохраняются активными и без перерывов,
+	// время начала берется текущее, только если сервис его не задал
+	if entry.Status != models.StatusCompleted {
+		if entry.StartTime.IsZero() {
+			entry.StartTime = time.Now()
+		}
+		entry.Status = models.StatusActive
+		entry.TotalPaused = 0
+	}
 	entry.ID = m.nextID
 	m.nextID++
 	m.entries[entry.ID] = entry
@@ -77,7 +90,7 @@ func (m *MockRepository) GetTimeEntryByID(ctx context.Context, id uint) (*models
 	}
 	entry, exists := m.entries[id]
 	if !exists {
-		return nil, errors.New("запись не найдена")
+		return nil, database.ErrNotFound
 	}
 	return entry, nil
 }
@@ -107,6 +120,59 @@ func (m *MockRepository) GetActiveTimeEntryForUser(ctx context.Context, userID u
 	return nil, nil
 }
 
+// ListTimeEntries мок метода
+func (m *MockRepository) ListTimeEntries(ctx context.Context, filter database.TimeEntryFilter) ([]*models.TimeEntry, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	var result []*models.TimeEntry
+	for _, entry := range m.entries {
+		if entry.UserID != filter.UserID {
+			continue
+		}
+		if len(filter.Statuses) > 0 && !containsStatus(filter.Statuses, entry.Status) {
+			continue
+		}
+		if !filter.From.IsZero() && !entry.EndTime.IsZero() && !entry.EndTime.After(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && !entry.StartTime.Before(filter.To) {
+			continue
+		}
+		if filter.After != nil {
+			before := entry.StartTime.Before(filter.After.StartTime) ||
+				(entry.StartTime.Equal(filter.After.StartTime) && entry.ID < filter.After.ID)
+			if before == filter.SortAsc || entry.ID == filter.After.ID {
+				continue
+			}
+		}
+		result = append(result, entry)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		less := result[i].StartTime.Before(result[j].StartTime) ||
+			(result[i].StartTime.Equal(result[j].StartTime) && result[i].ID < result[j].ID)
+		return less == filter.SortAsc
+	})
+
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+
+	return result, nil
+}
+
+// containsStatus проверяет, входит ли статус в список
+func containsStatus(statuses []models.Status, status models.Status) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
 // UpdateTimeEntry мок метода
 func (m *MockRepository) UpdateTimeEntry(ctx context.Context, entry *models.TimeEntry) error {
 	if m.err != nil {
@@ -139,8 +205,71 @@ func (m *MockRepository) DeleteTimeEntry(ctx context.Context, id uint) error {
 	return nil
 }
 
+// UpdateHeartbeat мок метода
+func (m *MockRepository) UpdateHeartbeat(ctx context.Context, entryID uint, at time.Time) error {
+	if m.err != nil {
+		return m.err
+	}
+	if entry, exists := m.entries[entryID]; exists {
+		entry.LastHeartbeat = at
+	}
+	return nil
+}
+
+// ListIdleTimeEntries мок метода: порог бездействия idleTimeout общий для всех пользователей
+func (m *MockRepository) ListIdleTimeEntries(ctx context.Context, now time.Time) ([]*models.TimeEntry, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	var result []*models.TimeEntry
+	for _, entry := range m.entries {
+		if entry.Status == models.StatusActive && !entry.LastHeartbeat.IsZero() &&
+			entry.LastHeartbeat.Add(m.idleTimeout).Before(now) {
+			result = append(result, entry)
+		}
+	}
+	return result, nil
+}
+
+// ListUnfinishedTimeEntries мок метода
+func (m *MockRepository) ListUnfinishedTimeEntries(ctx context.Context) ([]*models.TimeEntry, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	var result []*models.TimeEntry
+	for _, entry := range m.entries {
+		if entry.Status != models.StatusCompleted {
+			result = append(result, entry)
+		}
+	}
+	return result, nil
+}
+
+// CreatePause мок метода
+func (m *MockRepository) CreatePause(ctx context.Context, pause *models.Pause) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.nextPauseID++
+	pause.ID = m.nextPauseID
+	return nil
+}
+
+// ReplacePauses мок метода
+func (m *MockRepository) ReplacePauses(ctx context.Context, entryID uint, pauses []models.Pause) error {
+	if m.err != nil {
+		return m.err
+	}
+	entry, exists := m.entries[entryID]
+	if !exists {
+		return errors.New("запись не найдена")
+	}
+	entry.Pauses = append([]models.Pause(nil), pauses...)
+	return nil
+}
+
 // GetUserStatsByPeriod мок метода
-func (m *MockRepository) GetUserStatsByPeriod(ctx context.Context, userID uint, startDate, endDate string) ([]*models.TimeEntry, error) {
+func (m *MockRepository) GetUserStatsByPeriod(ctx context.Context, userID uint, from, to time.Time, tagIDs []uint) ([]*models.TimeEntry, error) {
 	if m.err != nil {
 		return nil, m.err
 	}
@@ -155,25 +284,87 @@ func (m *MockRepository) GetUserStatsByPeriod(ctx context.Context, userID uint,
 	return result, nil
 }
 
-// Методы для работы с категориями
-func (m *MockRepository) CreateCategory(ctx context.Context, category *models.Category) error {
-	return m.err
-}
-
 func (m *MockRepository) GetCategoryByID(ctx context.Context, id uint) (*models.Category, error) {
-	return nil, m.err
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.categories[id], nil
 }
 
 func (m *MockRepository) GetCategoriesByUserID(ctx context.Context, userID uint) ([]*models.Category, error) {
-	return nil, m.err
+	if m.err != nil {
+		return nil, m.err
+	}
+	var result []*models.Category
+	for _, category := range m.categories {
+		if category.UserID == userID {
+			result = append(result, category)
+		}
+	}
+	return result, nil
+}
+
+// Методы для работы с метками
+func (m *MockRepository) CreateTag(ctx context.Context, tag *models.Tag) error {
+	if m.err != nil {
+		return m.err
+	}
+	tag.ID = uint(len(m.tags) + 1)
+	m.tags[tag.ID] = tag
+	return nil
+}
+
+func (m *MockRepository) GetTagByID(ctx context.Context, id uint) (*models.Tag, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.tags[id], nil
+}
+
+func (m *MockRepository) GetTagsByUserID(ctx context.Context, userID uint) ([]*models.Tag, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	var result []*models.Tag
+	for _, tag := range m.tags {
+		if tag.UserID == userID {
+			result = append(result, tag)
+		}
+	}
+	return result, nil
 }
 
-func (m *MockRepository) UpdateCategory(ctx context.Context, category *models.Category) error {
-	return m.err
+func (m *MockRepository) SetTimeEntryTags(ctx context.Context, entryID uint, tagIDs []uint) error {
+	if m.err != nil {
+		return m.err
+	}
+	entry, exists := m.entries[entryID]
+	if !exists {
+		return errors.New("запись не найдена")
+	}
+	entry.Tags = nil
+	for _, id := range tagIDs {
+		if tag, ok := m.tags[id]; ok {
+			entry.Tags = append(entry.Tags, *tag)
+		}
+	}
+	return nil
 }
 
-func (m *MockRepository) DeleteCategory(ctx context.Context, id uint) error {
-	return m.err
+// WithTx мок метода: fn выполняется без транзакции
+func (m *MockRepository) WithTx(ctx context.Context, fn func(repo database.Repository) error) error {
+	return fn(m)
+}
+
+func (m *MockRepository) GetProjectByID(ctx context.Context, id uint) (*models.Project, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	project, exists := m.projects[id]
+	if !exists {
+		return nil, errors.New("проект не найден")
+	}
+	return project, nil
 }
 
 // TestStartWork тестирует функцию StartWork
@@ -428,3 +619,869 @@ func TestDeleteTimeEntry(t *testing.T) {
 		mockRepo.SetError(nil)
 	})
 }
+
+// TestCreateManualEntry проверяет ручное создание завершенной записи
+func TestCreateManualEntry(t *testing.T) {
+	ctx := context.Background()
+	userID := uint(1)
+	base := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := NewMockRepository()
+		service := NewService(mockRepo)
+
+		entry, err := service.CreateManualEntry(ctx, userID, EntryInput{
+			StartTime:   base,
+			EndTime:     base.Add(2 * time.Hour),
+			TotalPaused: 600,
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.StatusCompleted, entry.Status)
+		assert.Equal(t, int64(6600), entry.CalculateDuration())
+		assert.Nil(t, mockRepo.activeTimeEntry)
+	})
+
+	t.Run("EndBeforeStart", func(t *testing.T) {
+		service := NewService(NewMockRepository())
+
+		_, err := service.CreateManualEntry(ctx, userID, EntryInput{
+			StartTime: base,
+			EndTime:   base.Add(-time.Hour),
+		})
+
+		assert.Equal(t, ErrInvalidTimeRange, err)
+	})
+
+	t.Run("PauseLongerThanEntry", func(t *testing.T) {
+		service := NewService(NewMockRepository())
+
+		_, err := service.CreateManualEntry(ctx, userID, EntryInput{
+			StartTime:   base,
+			EndTime:     base.Add(time.Hour),
+			TotalPaused: 7200,
+		})
+
+		assert.Equal(t, ErrInvalidPause, err)
+	})
+
+	t.Run("Overlap", func(t *testing.T) {
+		mockRepo := NewMockRepository()
+		service := NewService(mockRepo)
+		mockRepo.entries[1] = &models.TimeEntry{
+			ID:        1,
+			UserID:    userID,
+			StartTime: base.Add(time.Hour),
+			EndTime:   base.Add(3 * time.Hour),
+			Status:    models.StatusCompleted,
+		}
+		// Запись другого пользователя не должна мешать
+		mockRepo.entries[2] = &models.TimeEntry{
+			ID:        2,
+			UserID:    2,
+			StartTime: base,
+			EndTime:   base.Add(time.Hour),
+			Status:    models.StatusCompleted,
+		}
+
+		_, err := service.CreateManualEntry(ctx, userID, EntryInput{
+			StartTime: base,
+			EndTime:   base.Add(2 * time.Hour),
+		})
+		assert.Equal(t, ErrEntryOverlap, err)
+
+		// Запись, заканчивающаяся ровно в момент начала другой, не пересекается
+		_, err = service.CreateManualEntry(ctx, userID, EntryInput{
+			StartTime: base,
+			EndTime:   base.Add(time.Hour),
+		})
+		assert.NoError(t, err)
+	})
+}
+
+// TestUpdateTimeEntry проверяет редактирование существующей записи
+func TestUpdateTimeEntry(t *testing.T) {
+	ctx := context.Background()
+	userID := uint(1)
+	base := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	newRepo := func() *MockRepository {
+		mockRepo := NewMockRepository()
+		mockRepo.entries[1] = &models.TimeEntry{
+			ID:        1,
+			UserID:    userID,
+			StartTime: base,
+			EndTime:   base.Add(time.Hour),
+			Status:    models.StatusCompleted,
+		}
+		mockRepo.entries[2] = &models.TimeEntry{
+			ID:        2,
+			UserID:    userID,
+			StartTime: base.Add(2 * time.Hour),
+			EndTime:   base.Add(3 * time.Hour),
+			Status:    models.StatusCompleted,
+		}
+		return mockRepo
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		service := NewService(newRepo())
+
+		entry, err := service.UpdateTimeEntry(ctx, 1, userID, EntryInput{
+			StartTime:   base.Add(-30 * time.Minute),
+			EndTime:     base.Add(90 * time.Minute),
+			TotalPaused: 1200,
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, base.Add(-30*time.Minute), entry.StartTime)
+		assert.Equal(t, int64(6000), entry.CalculateDuration())
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		service := NewService(newRepo())
+
+		_, err := service.UpdateTimeEntry(ctx, 99, userID, EntryInput{
+			StartTime: base,
+			EndTime:   base.Add(time.Hour),
+		})
+
+		assert.Equal(t, ErrEntryNotFound, err)
+	})
+
+	t.Run("DatabaseError", func(t *testing.T) {
+		mockRepo := newRepo()
+		dbErr := errors.New("ошибка базы данных")
+		mockRepo.SetError(dbErr)
+		service := NewService(mockRepo)
+
+		_, err := service.UpdateTimeEntry(ctx, 1, userID, EntryInput{
+			StartTime: base,
+			EndTime:   base.Add(time.Hour),
+		})
+		assert.ErrorIs(t, err, dbErr)
+		assert.NotErrorIs(t, err, ErrEntryNotFound)
+
+		_, err = service.UpdateEntryDetails(ctx, 1, userID, "описание", nil)
+		assert.ErrorIs(t, err, dbErr)
+		assert.NotErrorIs(t, err, ErrEntryNotFound)
+	})
+
+	t.Run("WrongUser", func(t *testing.T) {
+		service := NewService(newRepo())
+
+		_, err := service.UpdateTimeEntry(ctx, 1, 2, EntryInput{
+			StartTime: base,
+			EndTime:   base.Add(time.Hour),
+		})
+
+		assert.Equal(t, ErrNotEntryOwner, err)
+	})
+
+	t.Run("EndBeforeStart", func(t *testing.T) {
+		service := NewService(newRepo())
+
+		_, err := service.UpdateTimeEntry(ctx, 1, userID, EntryInput{
+			StartTime: base,
+			EndTime:   base,
+		})
+
+		assert.Equal(t, ErrInvalidTimeRange, err)
+	})
+
+	t.Run("Overlap", func(t *testing.T) {
+		service := NewService(newRepo())
+
+		_, err := service.UpdateTimeEntry(ctx, 1, userID, EntryInput{
+			StartTime: base,
+			EndTime:   base.Add(150 * time.Minute),
+		})
+
+		assert.Equal(t, ErrEntryOverlap, err)
+	})
+}
+
+// TestListTimeEntries проверяет постраничную выборку записей
+func TestListTimeEntries(t *testing.T) {
+	ctx := context.Background()
+	userID := uint(1)
+	base := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	mockRepo := NewMockRepository()
+	service := NewService(mockRepo)
+	for i := uint(1); i <= 5; i++ {
+		mockRepo.entries[i] = &models.TimeEntry{
+			ID:        i,
+			UserID:    userID,
+			StartTime: base.Add(time.Duration(i) * time.Hour),
+			EndTime:   base.Add(time.Duration(i)*time.Hour + 30*time.Minute),
+			Status:    models.StatusCompleted,
+		}
+	}
+
+	t.Run("Pagination", func(t *testing.T) {
+		var ids []uint
+		cursor := ""
+		for pages := 0; pages < 10; pages++ {
+			page, err := service.ListTimeEntries(ctx, database.TimeEntryFilter{UserID: userID, Limit: 2}, cursor)
+			assert.NoError(t, err)
+			for _, entry := range page.Entries {
+				ids = append(ids, entry.ID)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			cursor = page.NextCursor
+		}
+
+		assert.Equal(t, []uint{5, 4, 3, 2, 1}, ids)
+	})
+
+	t.Run("Ascending", func(t *testing.T) {
+		page, err := service.ListTimeEntries(ctx, database.TimeEntryFilter{UserID: userID, SortAsc: true, Limit: 3}, "")
+		assert.NoError(t, err)
+		assert.Len(t, page.Entries, 3)
+		assert.Equal(t, uint(1), page.Entries[0].ID)
+		assert.NotEmpty(t, page.NextCursor)
+	})
+
+	t.Run("InvalidCursor", func(t *testing.T) {
+		_, err := service.ListTimeEntries(ctx, database.TimeEntryFilter{UserID: userID}, "не-курсор")
+		assert.Equal(t, ErrInvalidCursor, err)
+	})
+}
+
+// TestPauseIntervals проверяет запись интервалов перерывов при паузе, возобновлении и завершении
+func TestPauseIntervals(t *testing.T) {
+	mockRepo := NewMockRepository()
+	service := NewService(mockRepo)
+	ctx := context.Background()
+	userID := uint(1)
+
+	originalTimeNow := timeNow
+	defer func() { timeNow = originalTimeNow }()
+
+	base := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) {
+		timeNow = func() time.Time { return base.Add(d) }
+	}
+
+	at(0)
+	_, err := service.StartWork(ctx, userID)
+	assert.NoError(t, err)
+
+	at(time.Hour)
+	_, err = service.PauseWork(ctx, userID)
+	assert.NoError(t, err)
+
+	at(75 * time.Minute)
+	_, err = service.ResumeWork(ctx, userID)
+	assert.NoError(t, err)
+
+	at(2 * time.Hour)
+	_, err = service.PauseWork(ctx, userID)
+	assert.NoError(t, err)
+
+	at(150 * time.Minute)
+	entry, err := service.StopWork(ctx, userID)
+	assert.NoError(t, err)
+
+	assert.Len(t, entry.Pauses, 2)
+	assert.Equal(t, base.Add(time.Hour), entry.Pauses[0].StartTime)
+	assert.Equal(t, base.Add(75*time.Minute), entry.Pauses[0].EndTime)
+	assert.Equal(t, base.Add(2*time.Hour), entry.Pauses[1].StartTime)
+	assert.Equal(t, base.Add(150*time.Minute), entry.Pauses[1].EndTime)
+	assert.Nil(t, entry.OpenPause())
+	assert.Equal(t, int64(2700), entry.TotalPaused)
+	assert.Equal(t, int64(6300), entry.CalculateDuration())
+}
+
+// TestUpdateTimeEntryPauses проверяет редактирование интервалов перерывов
+func TestUpdateTimeEntryPauses(t *testing.T) {
+	ctx := context.Background()
+	userID := uint(1)
+	base := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	newRepo := func() *MockRepository {
+		mockRepo := NewMockRepository()
+		mockRepo.entries[1] = &models.TimeEntry{
+			ID:          1,
+			UserID:      userID,
+			StartTime:   base,
+			EndTime:     base.Add(4 * time.Hour),
+			Status:      models.StatusCompleted,
+			TotalPaused: 900,
+			Pauses: []models.Pause{
+				{ID: 1, TimeEntryID: 1, StartTime: base.Add(time.Hour), EndTime: base.Add(75 * time.Minute)},
+			},
+		}
+		return mockRepo
+	}
+
+	t.Run("ReplaceIntervals", func(t *testing.T) {
+		service := NewService(newRepo())
+
+		entry, err := service.UpdateTimeEntry(ctx, 1, userID, EntryInput{
+			StartTime: base,
+			EndTime:   base.Add(4 * time.Hour),
+			Pauses: []models.Pause{
+				{StartTime: base.Add(3 * time.Hour), EndTime: base.Add(210 * time.Minute)},
+				{StartTime: base.Add(time.Hour), EndTime: base.Add(80 * time.Minute)},
+			},
+		})
+
+		assert.NoError(t, err)
+		assert.Len(t, entry.Pauses, 2)
+		assert.Equal(t, base.Add(time.Hour), entry.Pauses[0].StartTime)
+		assert.Equal(t, int64(3000), entry.TotalPaused)
+	})
+
+	t.Run("KeepIntervals", func(t *testing.T) {
+		service := NewService(newRepo())
+
+		entry, err := service.UpdateTimeEntry(ctx, 1, userID, EntryInput{
+			StartTime:   base.Add(-time.Hour),
+			EndTime:     base.Add(4 * time.Hour),
+			TotalPaused: 900,
+		})
+
+		assert.NoError(t, err)
+		assert.Len(t, entry.Pauses, 1)
+		assert.Equal(t, int64(17100), entry.CalculateDuration())
+	})
+
+	t.Run("OverlappingIntervals", func(t *testing.T) {
+		service := NewService(newRepo())
+
+		_, err := service.UpdateTimeEntry(ctx, 1, userID, EntryInput{
+			StartTime: base,
+			EndTime:   base.Add(4 * time.Hour),
+			Pauses: []models.Pause{
+				{StartTime: base.Add(time.Hour), EndTime: base.Add(2 * time.Hour)},
+				{StartTime: base.Add(90 * time.Minute), EndTime: base.Add(3 * time.Hour)},
+			},
+		})
+
+		assert.Equal(t, ErrInvalidPause, err)
+	})
+
+	t.Run("IntervalOutsideEntry", func(t *testing.T) {
+		service := NewService(newRepo())
+
+		_, err := service.UpdateTimeEntry(ctx, 1, userID, EntryInput{
+			StartTime: base,
+			EndTime:   base.Add(4 * time.Hour),
+			Pauses: []models.Pause{
+				{StartTime: base.Add(3 * time.Hour), EndTime: base.Add(5 * time.Hour)},
+			},
+		})
+
+		assert.Equal(t, ErrInvalidPause, err)
+	})
+}
+
+// TestEntryDescriptionAndTags проверяет описание и метки при старте и последующем редактировании
+func TestEntryDescriptionAndTags(t *testing.T) {
+	mockRepo := NewMockRepository()
+	service := NewService(mockRepo)
+	ctx := context.Background()
+	userID := uint(1)
+
+	mockRepo.tags[1] = &models.Tag{ID: 1, UserID: userID, Name: "срочно"}
+	mockRepo.tags[2] = &models.Tag{ID: 2, UserID: userID, Name: "встреча"}
+	mockRepo.tags[3] = &models.Tag{ID: 3, UserID: 2, Name: "чужая"}
+
+	t.Run("ForeignTag", func(t *testing.T) {
+		_, err := service.StartWorkWithOptions(ctx, userID, StartOptions{TagIDs: []uint{3}})
+		assert.Equal(t, ErrTagNotOwned, err)
+		assert.Nil(t, mockRepo.activeTimeEntry)
+	})
+
+	entry, err := service.StartWorkWithOptions(ctx, userID, StartOptions{
+		Description: "  Ревью задач  ",
+		TagIDs:      []uint{1},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Ревью задач", entry.Description)
+	assert.Len(t, entry.Tags, 1)
+	assert.Equal(t, "срочно", entry.Tags[0].Name)
+
+	// nil оставляет метки без изменений
+	entry, err = service.UpdateEntryDetails(ctx, entry.ID, userID, "Созвон", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "Созвон", entry.Description)
+	assert.Len(t, entry.Tags, 1)
+
+	// Пустой список снимает все метки
+	entry, err = service.UpdateEntryDetails(ctx, entry.ID, userID, "Созвон", []uint{})
+	assert.NoError(t, err)
+	assert.Empty(t, entry.Tags)
+
+	_, err = service.UpdateEntryDetails(ctx, entry.ID, 2, "Чужая запись", nil)
+	assert.Equal(t, ErrNotEntryOwner, err)
+}
+
+// TestEntryProject проверяет привязку записей к проекту
+func TestEntryProject(t *testing.T) {
+	mockRepo := NewMockRepository()
+	service := NewService(mockRepo)
+	ctx := context.Background()
+	userID := uint(1)
+	base := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	mockRepo.projects[1] = &models.Project{ID: 1, UserID: userID, Name: "Сайт", CategoryIDs: []uint{10}}
+	mockRepo.projects[2] = &models.Project{ID: 2, UserID: userID, Name: "Старый", Archived: true}
+	mockRepo.projects[3] = &models.Project{ID: 3, UserID: 2, Name: "Чужой"}
+
+	mockRepo.categories[10] = &models.Category{ID: 10, UserID: userID, Name: "Разработка"}
+	mockRepo.categories[11] = &models.Category{ID: 11, UserID: userID, Name: "Встречи"}
+
+	project := func(id uint) *uint { return &id }
+
+	t.Run("StartErrors", func(t *testing.T) {
+		_, err := service.StartWorkWithOptions(ctx, userID, StartOptions{ProjectID: project(3)})
+		assert.Equal(t, ErrProjectNotOwned, err)
+
+		_, err = service.StartWorkWithOptions(ctx, userID, StartOptions{ProjectID: project(2)})
+		assert.Equal(t, ErrProjectArchived, err)
+
+		// В проекте разрешена только категория 10
+		_, err = service.StartWorkWithOptions(ctx, userID, StartOptions{ProjectID: project(1), CategoryID: project(11)})
+		assert.Equal(t, ErrCategoryNotInProject, err)
+	})
+
+	entry, err := service.CreateManualEntry(ctx, userID, EntryInput{
+		StartTime: base,
+		EndTime:   base.Add(time.Hour),
+		ProjectID: project(1),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), *entry.ProjectID)
+
+	// Запись, уже находящаяся в проекте, остается в нем после архивирования
+	mockRepo.projects[1].Archived = true
+	entry, err = service.UpdateTimeEntry(ctx, entry.ID, userID, EntryInput{
+		StartTime: base,
+		EndTime:   base.Add(2 * time.Hour),
+		ProjectID: project(1),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, base.Add(2*time.Hour), entry.EndTime)
+
+	// Перенос в другой архивный проект запрещен
+	_, err = service.UpdateTimeEntry(ctx, entry.ID, userID, EntryInput{
+		StartTime: base,
+		EndTime:   base.Add(2 * time.Hour),
+		ProjectID: project(2),
+	})
+	assert.Equal(t, ErrProjectArchived, err)
+}
+
+// TestIdleDetection проверяет сигналы активности, автоматическую паузу и варианты учета бездействия
+func TestIdleDetection(t *testing.T) {
+	ctx := context.Background()
+	userID := uint(1)
+
+	originalTimeNow := timeNow
+	defer func() { timeNow = originalTimeNow }()
+
+	base := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) {
+		timeNow = func() time.Time { return base.Add(d) }
+	}
+
+	// Запись начата в 9:00, последний сигнал в 9:30, автопауза в 9:50 при пороге 15 минут
+	setup := func(t *testing.T) (*Service, *MockRepository) {
+		mockRepo := NewMockRepository()
+		mockRepo.idleTimeout = 15 * time.Minute
+		service := NewService(mockRepo)
+
+		at(0)
+		_, err := service.StartWork(ctx, userID)
+		assert.NoError(t, err)
+		at(30 * time.Minute)
+		_, err = service.Heartbeat(ctx, userID)
+		assert.NoError(t, err)
+
+		paused, err := service.PauseIdleEntries(ctx, base.Add(50*time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, paused)
+		return service, mockRepo
+	}
+
+	t.Run("NoActiveEntry", func(t *testing.T) {
+		service := NewService(NewMockRepository())
+		_, err := service.Heartbeat(ctx, userID)
+		assert.Equal(t, ErrNoActiveEntry, err)
+	})
+
+	t.Run("RecentHeartbeatNotPaused", func(t *testing.T) {
+		mockRepo := NewMockRepository()
+		mockRepo.idleTimeout = 15 * time.Minute
+		service := NewService(mockRepo)
+
+		at(0)
+		_, err := service.StartWork(ctx, userID)
+		assert.NoError(t, err)
+		at(40 * time.Minute)
+		entry, err := service.Heartbeat(ctx, userID)
+		assert.NoError(t, err)
+		assert.Equal(t, base.Add(40*time.Minute), entry.LastHeartbeat)
+
+		paused, err := service.PauseIdleEntries(ctx, base.Add(50*time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, 0, paused)
+		assert.Equal(t, models.StatusActive, entry.Status)
+	})
+
+	t.Run("PauseBackdated", func(t *testing.T) {
+		service, _ := setup(t)
+
+		at(55 * time.Minute)
+		entry, err := service.Heartbeat(ctx, userID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.StatusPaused, entry.Status)
+		assert.Equal(t, base.Add(30*time.Minute), entry.PausedAt)
+		assert.Equal(t, base.Add(30*time.Minute), entry.LastHeartbeat, "сигнал приостановленной записи не сохраняется")
+		if assert.NotNil(t, entry.IdlePause()) {
+			assert.Equal(t, models.PauseReasonIdle, entry.IdlePause().Reason)
+			assert.Equal(t, base.Add(30*time.Minute), entry.IdlePause().StartTime)
+		}
+		assert.Equal(t, int64(1800), entry.CalculateDuration())
+	})
+
+	t.Run("Keep", func(t *testing.T) {
+		service, _ := setup(t)
+
+		at(time.Hour)
+		entry, err := service.ResolveIdle(ctx, userID, IdleKeep)
+		assert.NoError(t, err)
+		assert.Equal(t, models.StatusActive, entry.Status)
+		assert.Empty(t, entry.Pauses)
+		assert.Equal(t, base.Add(time.Hour), entry.LastHeartbeat)
+		assert.Equal(t, int64(0), entry.PausedDurationUntil(base.Add(time.Hour)))
+	})
+
+	t.Run("Discard", func(t *testing.T) {
+		service, _ := setup(t)
+
+		at(time.Hour)
+		entry, err := service.ResolveIdle(ctx, userID, IdleDiscard)
+		assert.NoError(t, err)
+		assert.Equal(t, models.StatusActive, entry.Status)
+		assert.Len(t, entry.Pauses, 1)
+		assert.Equal(t, base.Add(time.Hour), entry.Pauses[0].EndTime)
+		assert.Equal(t, int64(1800), entry.TotalPaused)
+		assert.Equal(t, int64(1800), entry.PausedDurationUntil(base.Add(time.Hour)))
+
+		// После возвращения отсчет бездействия начинается заново
+		paused, err := service.PauseIdleEntries(ctx, base.Add(70*time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, 0, paused)
+	})
+
+	t.Run("Split", func(t *testing.T) {
+		_, mockRepo := setup(t)
+		first := mockRepo.entries[1]
+
+		hub := events.NewHub()
+		received, cancel := hub.Subscribe(userID)
+		defer cancel()
+		service := NewServiceWithEvents(mockRepo, hub)
+
+		at(time.Hour)
+		entry, err := service.ResolveIdle(ctx, userID, IdleSplit)
+		assert.NoError(t, err)
+		// Клиенты узнают и о завершении прежней записи, и о начале новой
+		if assert.Len(t, received, 2) {
+			stopped := <-received
+			assert.Equal(t, events.EntryStopped, stopped.Type)
+			assert.Equal(t, first.ID, stopped.EntryID)
+			started := <-received
+			assert.Equal(t, events.EntryStarted, started.Type)
+			assert.Equal(t, entry.ID, started.EntryID)
+		}
+		assert.NotEqual(t, first.ID, entry.ID)
+		assert.Equal(t, models.StatusActive, entry.Status)
+		assert.Equal(t, base.Add(time.Hour), entry.StartTime)
+		assert.Equal(t, first.Billable, entry.Billable)
+
+		assert.Equal(t, models.StatusCompleted, first.Status)
+		assert.Equal(t, base.Add(30*time.Minute), first.EndTime)
+		assert.Empty(t, first.Pauses)
+		assert.Equal(t, int64(1800), first.CalculateDuration())
+	})
+
+	t.Run("InvalidAction", func(t *testing.T) {
+		service, _ := setup(t)
+		_, err := service.ResolveIdle(ctx, userID, "forget")
+		assert.Equal(t, ErrInvalidIdleAction, err)
+	})
+
+	t.Run("ManualPauseNotIdle", func(t *testing.T) {
+		service := NewService(NewMockRepository())
+		at(0)
+		_, err := service.StartWork(ctx, userID)
+		assert.NoError(t, err)
+		_, err = service.PauseWork(ctx, userID)
+		assert.NoError(t, err)
+
+		_, err = service.ResolveIdle(ctx, userID, IdleKeep)
+		assert.Equal(t, ErrNotIdle, err)
+	})
+}
+
+// TestAutoStopEntries проверяет автоматическое завершение забытых записей в момент отсечки
+func TestAutoStopEntries(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	mockRepo := NewMockRepository()
+	mockRepo.users[1] = &models.User{ID: 1, MaxEntryDuration: 8 * 60}
+	mockRepo.users[2] = &models.User{ID: 2, DailyCutoff: "18:00"}
+	mockRepo.users[3] = &models.User{ID: 3}
+	service := NewService(mockRepo)
+
+	// Активная запись дольше наибольшей длительности
+	mockRepo.entries[1] = &models.TimeEntry{ID: 1, UserID: 1, StartTime: base, Status: models.StatusActive}
+	// Запись на паузе с 17:00, пересекшая время отсечки; перерыв после отсечки отбрасывается
+	mockRepo.entries[2] = &models.TimeEntry{
+		ID: 2, UserID: 2, StartTime: base, Status: models.StatusPaused,
+		PausedAt: base.Add(8 * time.Hour),
+		Pauses: []models.Pause{
+			{ID: 1, TimeEntryID: 2, StartTime: base.Add(2 * time.Hour), EndTime: base.Add(3 * time.Hour)},
+			{ID: 2, TimeEntryID: 2, StartTime: base.Add(8 * time.Hour)},
+		},
+	}
+	// Пользователь без ограничений
+	mockRepo.entries[3] = &models.TimeEntry{ID: 3, UserID: 3, StartTime: base, Status: models.StatusActive}
+
+	stopped, err := service.AutoStopEntries(ctx, base.Add(30*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, stopped)
+
+	first := mockRepo.entries[1]
+	assert.Equal(t, models.StatusCompleted, first.Status)
+	assert.True(t, first.AutoStopped)
+	assert.Equal(t, base.Add(8*time.Hour), first.EndTime)
+	assert.Equal(t, int64(8*3600), first.CalculateDuration())
+
+	second := mockRepo.entries[2]
+	assert.Equal(t, models.StatusCompleted, second.Status)
+	assert.True(t, second.AutoStopped)
+	assert.Equal(t, base.Add(9*time.Hour), second.EndTime)
+	assert.True(t, second.PausedAt.IsZero())
+	assert.Len(t, second.Pauses, 2)
+	assert.Equal(t, base.Add(9*time.Hour), second.Pauses[1].EndTime)
+	assert.Equal(t, int64(2*3600), second.TotalPaused)
+	assert.Equal(t, int64(7*3600), second.CalculateDuration())
+
+	assert.Equal(t, models.StatusActive, mockRepo.entries[3].Status)
+	assert.False(t, mockRepo.entries[3].AutoStopped)
+
+	// До наступления отсечки запись не завершается
+	mockRepo.entries[4] = &models.TimeEntry{ID: 4, UserID: 1, StartTime: base.Add(25 * time.Hour), Status: models.StatusActive}
+	stopped, err = service.AutoStopEntries(ctx, base.Add(30*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, stopped)
+	assert.Equal(t, models.StatusActive, mockRepo.entries[4].Status)
+}
+
+// conflictRepo имитирует параллельный запрос: запись изменена после чтения,
+// а незавершенная запись уже создана
+type conflictRepo struct {
+	*MockRepository
+}
+
+func (r conflictRepo) UpdateTimeEntry(ctx context.Context, entry *models.TimeEntry) error {
+	return fmt.Errorf("%w: запись %d изменена параллельно", database.ErrConflict, entry.ID)
+}
+
+func (r conflictRepo) CreateTimeEntry(ctx context.Context, entry *models.TimeEntry) error {
+	return fmt.Errorf("%w: у пользователя уже есть активная запись времени", database.ErrConflict)
+}
+
+func (r conflictRepo) WithTx(ctx context.Context, fn func(repo database.Repository) error) error {
+	return fn(r)
+}
+
+// TestSwitchWork проверяет переключение работы без разрыва между записями
+func TestSwitchWork(t *testing.T) {
+	ctx := context.Background()
+	userID := uint(1)
+
+	originalTimeNow := timeNow
+	defer func() { timeNow = originalTimeNow }()
+
+	base := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) {
+		timeNow = func() time.Time { return base.Add(d) }
+	}
+
+	t.Run("NoActiveEntry", func(t *testing.T) {
+		service := NewService(NewMockRepository())
+		_, _, err := service.SwitchWork(ctx, userID, StartOptions{Description: "Ревью"})
+		assert.Equal(t, ErrNoActiveEntry, err)
+	})
+
+	t.Run("Active", func(t *testing.T) {
+		mockRepo := NewMockRepository()
+		service := NewService(mockRepo)
+		mockRepo.tags[1] = &models.Tag{ID: 1, UserID: userID, Name: "срочно"}
+
+		at(0)
+		_, err := service.StartWorkWithOptions(ctx, userID, StartOptions{Description: "Разработка"})
+		assert.NoError(t, err)
+
+		at(time.Hour)
+		stopped, started, err := service.SwitchWork(ctx, userID, StartOptions{Description: "Ревью", TagIDs: []uint{1}})
+		assert.NoError(t, err)
+		assert.Equal(t, models.StatusCompleted, stopped.Status)
+		assert.Equal(t, "Разработка", stopped.Description)
+		assert.Equal(t, int64(3600), stopped.CalculateDuration())
+
+		assert.Equal(t, models.StatusActive, started.Status)
+		assert.Equal(t, stopped.EndTime, started.StartTime, "новая запись начинается в момент завершения предыдущей")
+		assert.Equal(t, base.Add(time.Hour), mockRepo.entries[started.ID].StartTime, "репозиторий сохраняет время начала, заданное сервисом")
+		assert.Equal(t, "Ревью", started.Description)
+		assert.Len(t, started.Tags, 1)
+
+		current, err := service.GetActiveTimeEntry(ctx, userID)
+		assert.NoError(t, err)
+		assert.Equal(t, started.ID, current.ID)
+	})
+
+	t.Run("Paused", func(t *testing.T) {
+		mockRepo := NewMockRepository()
+		service := NewService(mockRepo)
+
+		at(0)
+		_, err := service.StartWork(ctx, userID)
+		assert.NoError(t, err)
+		at(30 * time.Minute)
+		_, err = service.PauseWork(ctx, userID)
+		assert.NoError(t, err)
+
+		at(time.Hour)
+		stopped, started, err := service.SwitchWork(ctx, userID, StartOptions{Description: "Ревью"})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1800), stopped.TotalPaused, "перерыв предыдущей записи длится до переключения")
+		assert.Equal(t, int64(1800), stopped.CalculateDuration())
+		assert.Nil(t, stopped.OpenPause())
+
+		// Перерыв продолжается в новой записи
+		assert.Equal(t, models.StatusPaused, started.Status)
+		assert.Equal(t, base.Add(time.Hour), started.StartTime)
+		assert.Equal(t, base.Add(time.Hour), started.PausedAt)
+		assert.NotNil(t, started.OpenPause())
+
+		at(90 * time.Minute)
+		resumed, err := service.ResumeWork(ctx, userID)
+		assert.NoError(t, err)
+		assert.Equal(t, started.ID, resumed.ID)
+		assert.Equal(t, int64(1800), resumed.TotalPaused)
+	})
+
+	t.Run("InvalidOptionsKeepCurrentEntry", func(t *testing.T) {
+		mockRepo := NewMockRepository()
+		service := NewService(mockRepo)
+		mockRepo.tags[3] = &models.Tag{ID: 3, UserID: 2, Name: "чужая"}
+
+		at(0)
+		entry, err := service.StartWork(ctx, userID)
+		assert.NoError(t, err)
+
+		at(time.Hour)
+		_, _, err = service.SwitchWork(ctx, userID, StartOptions{TagIDs: []uint{3}})
+		assert.Equal(t, ErrTagNotOwned, err)
+		assert.Equal(t, entry.ID, mockRepo.activeTimeEntry.ID)
+	})
+}
+
+// TestTransitionConflicts проверяет, что параллельные изменения возвращаются как конфликт
+func TestTransitionConflicts(t *testing.T) {
+	ctx := context.Background()
+	userID := uint(1)
+
+	mockRepo := NewMockRepository()
+	service := NewService(conflictRepo{mockRepo})
+
+	_, err := service.StartWork(ctx, userID)
+	assert.Equal(t, ErrActiveEntryExists, err)
+
+	mockRepo.entries[1] = &models.TimeEntry{ID: 1, UserID: userID, StartTime: time.Now().Add(-time.Hour), Status: models.StatusActive}
+
+	_, err = service.PauseWork(ctx, userID)
+	assert.Equal(t, ErrConflict, err)
+	assert.Empty(t, mockRepo.entries[1].Pauses, "перерыв не создается при конфликте")
+
+	_, err = service.StopWork(ctx, userID)
+	assert.Equal(t, ErrConflict, err)
+
+	_, err = service.UpdateEntryDetails(ctx, 1, userID, "Созвон", nil)
+	assert.Equal(t, ErrConflict, err)
+
+	start := time.Now().Add(-5 * time.Hour)
+	_, err = service.CreateManualEntry(ctx, userID, EntryInput{StartTime: start, EndTime: start.Add(time.Hour)})
+	assert.Equal(t, ErrConflict, err)
+}
+
+// TestEvents проверяет публикацию событий об изменении записей
+func TestEvents(t *testing.T) {
+	ctx := context.Background()
+	userID := uint(1)
+
+	hub := events.NewHub()
+	received, cancel := hub.Subscribe(userID)
+	defer cancel()
+
+	mockRepo := NewMockRepository()
+	service := NewServiceWithEvents(mockRepo, hub)
+
+	expectEvent := func(t *testing.T, eventType string) events.Event {
+		t.Helper()
+		select {
+		case event := <-received:
+			assert.Equal(t, eventType, event.Type)
+			assert.Equal(t, userID, event.UserID)
+			return event
+		default:
+			t.Fatalf("ожидалось событие %s", eventType)
+			return events.Event{}
+		}
+	}
+
+	entry, err := service.StartWork(ctx, userID)
+	assert.NoError(t, err)
+	event := expectEvent(t, events.EntryStarted)
+	assert.Equal(t, entry.ID, event.EntryID)
+	assert.Equal(t, models.StatusActive, event.Entry.Status)
+
+	_, err = service.PauseWork(ctx, userID)
+	assert.NoError(t, err)
+	expectEvent(t, events.EntryPaused)
+
+	_, err = service.ResumeWork(ctx, userID)
+	assert.NoError(t, err)
+	expectEvent(t, events.EntryResumed)
+
+	_, _, err = service.SwitchWork(ctx, userID, StartOptions{Description: "Ревью"})
+	assert.NoError(t, err)
+	assert.Equal(t, entry.ID, expectEvent(t, events.EntryStopped).EntryID)
+	started := expectEvent(t, events.EntryStarted)
+	assert.Equal(t, "Ревью", started.Entry.Description)
+
+	_, err = service.StopWork(ctx, userID)
+	assert.NoError(t, err)
+	expectEvent(t, events.EntryStopped)
+
+	// Неудачные операции событий не публикуют
+	_, err = service.StopWork(ctx, userID)
+	assert.Equal(t, ErrNoActiveEntry, err)
+	assert.Len(t, received, 0)
+
+	assert.NoError(t, service.DeleteTimeEntry(ctx, entry.ID, userID))
+	event = expectEvent(t, events.EntryDeleted)
+	assert.Equal(t, entry.ID, event.EntryID)
+	assert.Nil(t, event.Entry)
+}
@@ -0,0 +1,106 @@
+// Package totp реализует одноразовые пароли по времени (TOTP, RFC 6238) с параметрами,
+// которые поддерживают все распространенные приложения-аутентификаторы: HMAC-SHA1, 6 цифр, шаг 30 секунд.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Digits - количество цифр кода
+	Digits = 6
+	// Period - время действия одного кода
+	Period = 30 * time.Second
+	// Skew - на сколько шагов в каждую сторону допускается расхождение часов сервера и устройства
+	Skew = 1
+	// secretSize - длина секрета в байтах (160 бит, рекомендация RFC 4226)
+	secretSize = 20
+)
+
+// ErrInvalidSecret возникает, если секрет не является строкой base32
+var ErrInvalidSecret = errors.New("некорректный секрет TOTP")
+
+// encoding - base32 без дополнения, как в otpauth:// URI
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret создает случайный секрет в кодировке base32
+func GenerateSecret() (string, error) {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("ошибка при создании секрета TOTP: %w", err)
+	}
+	return encoding.EncodeToString(b), nil
+}
+
+// URI возвращает ссылку otpauth:// для добавления секрета в приложение-аутентификатор
+// (обычно показывается QR-кодом)
+func URI(issuer, account, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(Digits))
+	params.Set("period", fmt.Sprint(int(Period.Seconds())))
+
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// Step возвращает номер шага времени, к которому относится момент t
+func Step(t time.Time) int64 {
+	return t.Unix() / int64(Period.Seconds())
+}
+
+// Code вычисляет код для шага step (RFC 4226, 5.3)
+func Code(secret string, step int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", ErrInvalidSecret
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", Digits, value%mod), nil
+}
+
+// Validate проверяет код на момент t с допуском Skew шагов и возвращает шаг, которому код
+// соответствует. Вызывающий должен запоминать шаг, чтобы один код нельзя было использовать дважды.
+func Validate(secret, code string, t time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != Digits {
+		return 0, false
+	}
+
+	current := Step(t)
+	for step := current - Skew; step <= current+Skew; step++ {
+		expected, err := Code(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
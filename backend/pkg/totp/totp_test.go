@@ -0,0 +1,81 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rfcSecret - секрет из тестовых векторов RFC 6238 (приложение B) для HMAC-SHA1
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	// Последние 6 цифр 8-значных кодов из RFC 6238
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		code, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code() error = %v", err)
+		}
+		if code != tt.code {
+			t.Errorf("Code() для %d = %s, хотели %s", tt.unix, code, tt.code)
+		}
+	}
+
+	if _, err := Code("не base32!", 1); err != ErrInvalidSecret {
+		t.Errorf("Code() с некорректным секретом error = %v, хотели %v", err, ErrInvalidSecret)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	code, _ := Code(secret, Step(now))
+
+	step, ok := Validate(secret, code, now)
+	if !ok || step != Step(now) {
+		t.Errorf("Validate() = %d, %v, хотели %d, true", step, ok, Step(now))
+	}
+
+	// Допускается расхождение часов на один шаг
+	if step, ok := Validate(secret, " "+code+" ", now.Add(Period)); !ok || step != Step(now) {
+		t.Errorf("Validate() через шаг = %d, %v, хотели %d, true", step, ok, Step(now))
+	}
+	if _, ok := Validate(secret, code, now.Add(2*Period)); ok {
+		t.Error("Validate() принял код через два шага")
+	}
+	if _, ok := Validate(secret, "12345", now); ok {
+		t.Error("Validate() принял код неверной длины")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("TimeTracker", "user@example.com", "JBSWY3DPEHPK3PXP")
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("Некорректный URI %q: %v", uri, err)
+	}
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" || parsed.Path != "/TimeTracker:user@example.com" {
+		t.Errorf("URI = %q", uri)
+	}
+	query := parsed.Query()
+	if query.Get("secret") != "JBSWY3DPEHPK3PXP" || query.Get("issuer") != "TimeTracker" || query.Get("digits") != "6" {
+		t.Errorf("Параметры URI = %v", query)
+	}
+}
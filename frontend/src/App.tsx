@@ -4,6 +4,10 @@ import Header from './components/Header/Header';
 import Login from './components/Auth/Login';
 import Register from './components/Auth/Register';
 import ChangePassword from './components/Auth/ChangePassword';
+import ForgotPassword from './components/Auth/ForgotPassword';
+import ResetPassword from './components/Auth/ResetPassword';
+import VerifyEmail from './components/Auth/VerifyEmail';
+import TwoFactor from './components/Auth/TwoFactor';
 import TimeTracker from './components/TimeTracker/TimeTracker';
 import Statistics from './components/Statistics/Statistics';
 import Categories from './components/Categories/Categories';
@@ -52,12 +56,16 @@ const App: React.FC = () => {
           {/* Публичные маршруты */}
           <Route path="/login" element={<Login />} />
           <Route path="/register" element={<Register />} />
+          <Route path="/forgot-password" element={<ForgotPassword />} />
+          <Route path="/reset-password" element={<ResetPassword />} />
+          <Route path="/verify-email" element={<VerifyEmail />} />
           
           {/* Защищенные маршруты */}
           <Route path="/" element={<ProtectedRoute element={<TimeTracker />} />} />
           <Route path="/statistics" element={<ProtectedRoute element={<Statistics />} />} />
           <Route path="/categories" element={<ProtectedRoute element={<Categories />} />} />
           <Route path="/change-password" element={<ProtectedRoute element={<ChangePassword />} />} />
+          <Route path="/two-factor" element={<ProtectedRoute element={<TwoFactor />} />} />
           
           {/* Маршрут для страницы 404 */}
           <Route path="*" element={<NotFound />} />
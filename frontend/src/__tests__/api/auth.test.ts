@@ -1,4 +1,4 @@
-import { login, register, logout, getCurrentUser, changePassword } from '../../api/auth';
+import { login, loginTwoFactor, register, logout, getCurrentUser, changePassword, verifyEmail } from '../../api/auth';
 import { API_BASE_URL } from '../../api/config';
 
 // Мокаем fetch API
@@ -55,7 +55,7 @@ describe('Auth API', () => {
       expect.objectContaining({
         method: 'POST',
         headers: expect.objectContaining({ 'Content-Type': 'application/json' }),
-        body: JSON.stringify({ email: 'test@example.com', password: 'password123' })
+        body: JSON.stringify({ email: 'test@example.com', password: 'password123', remember_me: false })
       })
     );
 
@@ -67,6 +67,54 @@ describe('Auth API', () => {
     expect(localStorage.setItem).toHaveBeenCalledWith('user', JSON.stringify(mockResponse.user));
   });
 
+  test('login не сохраняет токены, если нужен код второго фактора', async () => {
+    const mockResponse = {
+      two_factor_required: true,
+      challenge_token: 'challenge',
+      expires_in: 300
+    };
+
+    (global.fetch as jest.Mock).mockResolvedValue({
+      ok: true,
+      json: async () => mockResponse
+    });
+
+    const result = await login('test@example.com', 'password123');
+
+    expect(result.challenge_token).toBe('challenge');
+    expect(localStorage.setItem).not.toHaveBeenCalled();
+  });
+
+  test('loginTwoFactor передает промежуточный токен и код', async () => {
+    (global.fetch as jest.Mock).mockResolvedValue({
+      ok: true,
+      json: async () => ({ token: 'test_token', refresh_token: 'refresh' })
+    });
+
+    await loginTwoFactor('challenge', '123456');
+
+    expect(global.fetch).toHaveBeenCalledWith(
+      expect.stringContaining('/api/auth/2fa/login'),
+      expect.objectContaining({
+        method: 'POST',
+        body: JSON.stringify({ challenge_token: 'challenge', code: '123456' })
+      })
+    );
+    expect(localStorage.setItem).toHaveBeenCalledWith('token', 'test_token');
+  });
+
+  test('login сообщает, когда можно повторить попытку после блокировки', async () => {
+    (global.fetch as jest.Mock).mockResolvedValue({
+      ok: false,
+      status: 429,
+      headers: { get: (name: string) => (name === 'Retry-After' ? '90' : null) },
+      json: async () => ({})
+    });
+
+    await expect(login('test@example.com', 'wrong')).rejects.toThrow('повторите через 2 мин');
+    expect(localStorage.setItem).not.toHaveBeenCalled();
+  });
+
   test('login выбрасывает ошибку при неуспешной авторизации', async () => {
     // Настраиваем мок fetch для имитации ошибки
     (global.fetch as jest.Mock).mockResolvedValue({
@@ -127,6 +175,35 @@ describe('Auth API', () => {
     expect(localStorage.setItem).toHaveBeenCalledWith('user', JSON.stringify(mockResponse.user));
   });
 
+  test('register не сохраняет токены, если нужно подтвердить email', async () => {
+    const mockResponse = {
+      message: 'На email отправлена ссылка для подтверждения, перейдите по ней и войдите',
+      verification_required: true
+    };
+
+    (global.fetch as jest.Mock).mockResolvedValue({
+      ok: true,
+      json: async () => mockResponse
+    });
+
+    const result = await register('new@example.com', 'new_password', 'New User');
+
+    expect(result.verification_required).toBe(true);
+    expect(localStorage.setItem).not.toHaveBeenCalled();
+  });
+
+  test('verifyEmail передает токен из письма', async () => {
+    (global.fetch as jest.Mock).mockResolvedValue({
+      ok: true,
+      json: async () => ({ message: 'Email подтвержден' })
+    });
+
+    const result = await verifyEmail('token/+=');
+
+    expect(global.fetch).toHaveBeenCalledWith(expect.stringContaining('/api/auth/verify?token=token%2F%2B%3D'));
+    expect(result.message).toBe('Email подтвержден');
+  });
+
   test('logout удаляет данные пользователя из localStorage', () => {
     // Предварительно сохраняем данные в localStorage
     localStorage.setItem('token', 'test_token');
@@ -140,7 +217,17 @@ describe('Auth API', () => {
 
     // Проверяем, что данные были удалены из localStorage
     expect(localStorage.removeItem).toHaveBeenCalledWith('token');
+    expect(localStorage.removeItem).toHaveBeenCalledWith('refresh_token');
     expect(localStorage.removeItem).toHaveBeenCalledWith('user');
+
+    // Сессия завершается на сервере
+    expect(global.fetch).toHaveBeenCalledWith(
+      expect.stringContaining('/api/auth/logout'),
+      expect.objectContaining({
+        method: 'POST',
+        headers: { 'Authorization': 'Bearer test_token' }
+      })
+    );
   });
 
   test('getCurrentUser возвращает данные пользователя из localStorage', () => {
@@ -6,43 +6,170 @@ export interface User {
   name: string;
 }
 
+/**
+ * Сессия входа на устройстве
+ */
+export interface Session {
+  id: number;
+  user_agent: string;
+  ip: string;
+  remember_me: boolean;
+  created_at: string;
+  last_used_at: string;
+  expires_at: string;
+  current: boolean;  // сессия этого устройства
+}
+
+/**
+ * Ответ сервера с токенами
+ */
+interface TokenResponse {
+  token: string;          // токен доступа
+  refresh_token: string;  // одноразовый токен обновления
+  expires_in: number;     // срок действия токена доступа в секундах
+}
+
+/**
+ * Сохраняет выданные сервером токены
+ */
+const saveTokens = (data: TokenResponse): void => {
+  localStorage.setItem('token', data.token);
+  localStorage.setItem('refresh_token', data.refresh_token);
+};
+
+/**
+ * Удаляет сохраненные токены и данные пользователя
+ */
+const clearTokens = (): void => {
+  localStorage.removeItem('token');
+  localStorage.removeItem('refresh_token');
+  localStorage.removeItem('user');
+};
+
+/**
+ * Выполняет запрос к API управления сессиями с текущим токеном доступа
+ */
+const authorizedFetch = async (endpoint: string, method: string, body?: object): Promise<Response> => {
+  const token = localStorage.getItem('token');
+  if (!token) {
+    throw new Error('Вы не авторизованы');
+  }
+
+  const response = await fetch(`${API_BASE_URL}${endpoint}`, {
+    method,
+    headers: {
+      'Content-Type': 'application/json',
+      'Authorization': `Bearer ${token}`
+    },
+    body: body ? JSON.stringify(body) : undefined
+  });
+
+  if (!response.ok) {
+    const errorText = await response.text();
+    throw new Error(errorText || `Ошибка API: ${response.status}`);
+  }
+
+  return response;
+};
+
 /**
  * Авторизация пользователя
  * @param email Email пользователя
  * @param password Пароль пользователя
  * @returns Объект с данными пользователя и токеном
  */
-export const login = async (email: string, password: string): Promise<{ token: string, user: User }> => {
+/**
+ * Сообщение об ответе 429: сервер ограничил частоту запросов или заблокировал вход
+ * после серии неудачных попыток
+ */
+const tooManyRequestsMessage = (response: Response): string => {
+  const seconds = Number(response.headers?.get('Retry-After'));
+  if (!seconds) {
+    return 'Слишком много попыток, повторите позже';
+  }
+  const minutes = Math.ceil(seconds / 60);
+  return seconds < 60
+    ? `Слишком много попыток, повторите через ${seconds} с`
+    : `Слишком много попыток, повторите через ${minutes} мин`;
+};
+
+export const login = async (
+  email: string,
+  password: string,
+  rememberMe: boolean = false
+): Promise<{ token: string, user: User, two_factor_required?: boolean, challenge_token?: string }> => {
   const response = await fetch(`${API_BASE_URL}/api/auth/login`, {
     method: 'POST',
     headers: {
       'Content-Type': 'application/json'
     },
-    body: JSON.stringify({ email, password })
+    body: JSON.stringify({ email, password, remember_me: rememberMe })
   });
   
   if (!response.ok) {
     const errorData = await response.json().catch(() => ({}));
+    if (response.status === 403) {
+      throw new Error('Подтвердите email по ссылке из письма');
+    }
+    if (response.status === 429) {
+      throw new Error(tooManyRequestsMessage(response));
+    }
     throw new Error(errorData.message || 'Неверный email или пароль');
   }
   
   const data = await response.json();
+  if (data.two_factor_required) {
+    // Токены выдаются после ввода кода (loginTwoFactor)
+    return data;
+  }
   
-  // Сохраняем токен и данные пользователя
-  localStorage.setItem('token', data.token);
+  // Сохраняем токены и данные пользователя
+  saveTokens(data);
   localStorage.setItem('user', JSON.stringify(data.user));
   
   return data;
 };
 
+/**
+ * Второй шаг входа с двухфакторной аутентификацией
+ * @param challengeToken Промежуточный токен из ответа login
+ * @param code Код из приложения-аутентификатора или код восстановления
+ */
+export const loginTwoFactor = async (challengeToken: string, code: string): Promise<{ token: string }> => {
+  const response = await fetch(`${API_BASE_URL}/api/auth/2fa/login`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json'
+    },
+    body: JSON.stringify({ challenge_token: challengeToken, code })
+  });
+
+  if (!response.ok) {
+    if (response.status === 429) {
+      throw new Error(tooManyRequestsMessage(response));
+    }
+    const errorText = await response.text();
+    throw new Error(errorText || 'Неверный код подтверждения');
+  }
+
+  const data = await response.json();
+  saveTokens(data);
+  return data;
+};
+
 /**
  * Регистрация нового пользователя
  * @param email Email пользователя
  * @param password Пароль пользователя
  * @param name Имя пользователя
- * @returns Объект с данными пользователя и токеном
+ * @returns Объект с данными пользователя и токеном; если сервер не пускает пользователей
+ * до подтверждения email, токена нет, а verification_required равно true
  */
-export const register = async (email: string, password: string, name: string): Promise<{ user: User; token: string }> => {
+export const register = async (
+  email: string,
+  password: string,
+  name: string
+): Promise<{ user: User; token: string; verification_required?: boolean; message?: string }> => {
   const response = await fetch(`${API_BASE_URL}/api/auth/register`, {
     method: 'POST',
     headers: {
@@ -60,9 +187,12 @@ export const register = async (email: string, password: string, name: string): P
   }
   
   const data = await response.json();
+  if (data.verification_required) {
+    return data;
+  }
   
-  // Сохраняем токен и данные пользователя в localStorage
-  localStorage.setItem('token', data.token);
+  // Сохраняем токены и данные пользователя в localStorage
+  saveTokens(data);
   localStorage.setItem('user', JSON.stringify(data.user));
   
   return data;
@@ -98,11 +228,213 @@ export const changePassword = async (oldPassword: string, newPassword: string):
 };
 
 /**
- * Выход из системы
+ * Запрос ссылки для сброса пароля на email
+ * @param email Email пользователя
+ * @returns Сообщение сервера; оно не сообщает, зарегистрирован ли email
+ */
+export const forgotPassword = async (email: string): Promise<{ message: string }> => {
+  const response = await fetch(`${API_BASE_URL}/api/auth/forgot-password`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json'
+    },
+    body: JSON.stringify({ email })
+  });
+
+  if (!response.ok) {
+    const errorText = await response.text();
+    throw new Error(errorText || 'Не удалось отправить ссылку для сброса пароля');
+  }
+
+  return response.json();
+};
+
+/**
+ * Сброс пароля по токену из письма
+ * @param token Токен из ссылки в письме
+ * @param newPassword Новый пароль
+ */
+export const resetPassword = async (token: string, newPassword: string): Promise<{ message: string }> => {
+  const response = await fetch(`${API_BASE_URL}/api/auth/reset-password`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json'
+    },
+    body: JSON.stringify({ token, new_password: newPassword })
+  });
+
+  if (!response.ok) {
+    const errorText = await response.text();
+    throw new Error(errorText || 'Ошибка при сбросе пароля');
+  }
+
+  return response.json();
+};
+
+/**
+ * Подтверждение email по токену из письма
+ * @param token Токен из ссылки в письме
+ */
+export const verifyEmail = async (token: string): Promise<{ message: string }> => {
+  const response = await fetch(`${API_BASE_URL}/api/auth/verify?token=${encodeURIComponent(token)}`);
+
+  if (!response.ok) {
+    const errorText = await response.text();
+    throw new Error(errorText || 'Ошибка при подтверждении email');
+  }
+
+  return response.json();
+};
+
+/**
+ * Повторная отправка ссылки для подтверждения email
+ * @param email Email, указанный при регистрации
+ */
+export const resendVerification = async (email: string): Promise<{ message: string }> => {
+  const response = await fetch(`${API_BASE_URL}/api/auth/verify/resend`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json'
+    },
+    body: JSON.stringify({ email })
+  });
+
+  if (!response.ok) {
+    const errorText = await response.text();
+    throw new Error(errorText || 'Не удалось отправить ссылку для подтверждения email');
+  }
+
+  return response.json();
+};
+
+/**
+ * Обменивает токен обновления на новую пару токенов
+ * @returns true, если токены обновлены; false, если нужно войти заново
+ */
+export const refreshTokens = async (): Promise<boolean> => {
+  const refreshToken = localStorage.getItem('refresh_token');
+  if (!refreshToken) {
+    return false;
+  }
+
+  const response = await fetch(`${API_BASE_URL}/api/auth/refresh`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json'
+    },
+    body: JSON.stringify({ refresh_token: refreshToken })
+  });
+
+  if (!response.ok) {
+    return false;
+  }
+
+  saveTokens(await response.json());
+  return true;
+};
+
+/**
+ * Завершает сессию токена на сервере; ошибка не мешает выходу на этом устройстве
+ */
+const endSession = async (token: string): Promise<void> => {
+  try {
+    await fetch(`${API_BASE_URL}/api/auth/logout`, {
+      method: 'POST',
+      headers: { 'Authorization': `Bearer ${token}` }
+    });
+  } catch (error) {
+    console.error('Ошибка при завершении сессии:', error);
+  }
+};
+
+/**
+ * Выход из системы: сессия завершается на сервере, токены удаляются
  */
 export const logout = (): void => {
-  localStorage.removeItem('token');
-  localStorage.removeItem('user');
+  const token = localStorage.getItem('token');
+  clearTokens();
+
+  if (token) {
+    endSession(token);
+  }
+};
+
+/**
+ * Выход на всех устройствах
+ */
+export const logoutAll = async (): Promise<void> => {
+  await authorizedFetch('/api/auth/logout-all', 'POST');
+  clearTokens();
+};
+
+/**
+ * Получение действующих сессий пользователя
+ */
+export const getSessions = async (): Promise<Session[]> => {
+  const response = await authorizedFetch('/api/auth/sessions', 'GET');
+  return response.json();
+};
+
+/**
+ * Завершение сессии на другом устройстве
+ * @param id ID сессии
+ */
+export const revokeSession = async (id: number): Promise<void> => {
+  await authorizedFetch('/api/auth/sessions/revoke', 'POST', { id });
+};
+
+/**
+ * Состояние двухфакторной аутентификации
+ */
+export interface TwoFactorStatus {
+  enabled: boolean;
+  enabled_at?: string;
+  recovery_codes_left: number;
+}
+
+/**
+ * Получение состояния двухфакторной аутентификации
+ */
+export const getTwoFactorStatus = async (): Promise<TwoFactorStatus> => {
+  const response = await authorizedFetch('/api/auth/2fa', 'GET');
+  return response.json();
+};
+
+/**
+ * Начало настройки двухфакторной аутентификации
+ * @returns Секрет и ссылка otpauth:// для приложения-аутентификатора
+ */
+export const setupTwoFactor = async (): Promise<{ secret: string; otpauth_url: string }> => {
+  const response = await authorizedFetch('/api/auth/2fa/setup', 'POST');
+  return response.json();
+};
+
+/**
+ * Включение двухфакторной аутентификации кодом из приложения
+ * @returns Коды восстановления; показываются только один раз
+ */
+export const confirmTwoFactor = async (code: string): Promise<string[]> => {
+  const response = await authorizedFetch('/api/auth/2fa/confirm', 'POST', { code });
+  const data = await response.json();
+  return data.recovery_codes;
+};
+
+/**
+ * Новые коды восстановления вместо прежних
+ * @param password Текущий пароль
+ */
+export const regenerateRecoveryCodes = async (password: string): Promise<string[]> => {
+  const response = await authorizedFetch('/api/auth/2fa/recovery-codes', 'POST', { password });
+  const data = await response.json();
+  return data.recovery_codes;
+};
+
+/**
+ * Отключение двухфакторной аутентификации
+ * @param password Текущий пароль
+ */
+export const disableTwoFactor = async (password: string): Promise<void> => {
+  await authorizedFetch('/api/auth/2fa/disable', 'POST', { password });
 };
 
 /**
@@ -18,9 +18,22 @@ export interface TimeStats {
   longest_session: number;
   longest_session_date: string;
   daily_stats: Record<string, number>;
+  category_stats?: CategoryStat[];
+  daily_category_stats?: Record<string, CategoryStat[]>;
+  provisional?: boolean;
+  running_duration?: number;
   entries: TimeEntry[];
 }
 
+export interface CategoryStat {
+  category_id: number | null;
+  name: string;
+  color: string;
+  duration: number;
+  entry_count: number;
+  percent: number;
+}
+
 /**
  * Получает статистику пользователя за указанный период
  * @param startDate Начальная дата в формате YYYY-MM-DD
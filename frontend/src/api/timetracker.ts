@@ -1,5 +1,6 @@
 import { API_BASE_URL } from './config';
 import { memoryCache, CACHE_TTL } from './cache';
+import { refreshTokens } from './auth';
 
 export interface Category {
   id: number;
@@ -19,6 +20,20 @@ export interface TimeEntry {
   category?: Category;
 }
 
+/**
+ * Событие об изменении записи или категории из потока /api/events
+ */
+export interface ServerEvent {
+  type: 'started' | 'created' | 'paused' | 'resumed' | 'stopped' | 'deleted' | 'updated'
+    | 'category_updated' | 'category_deleted' | 'resync';
+  user_id: number;
+  entry_id?: number;
+  category_id?: number;
+  entry?: TimeEntry;       // состояние записи после изменения
+  category?: Category;     // состояние категории после изменения
+  time: string;
+}
+
 // Типы ошибок API
 export interface ApiError {
   status: number;
@@ -33,6 +48,7 @@ type AuthEventListener = () => void;
  */
 class AuthManager {
   private authListeners: AuthEventListener[] = [];
+  private refreshing: Promise<boolean> | null = null;
 
   /**
    * Получает токен авторизации из localStorage
@@ -41,6 +57,24 @@ class AuthManager {
     return localStorage.getItem('token');
   }
 
+  /**
+   * Обновляет истекший токен доступа. Параллельные запросы ждут одного обновления,
+   * так как токен обновления одноразовый.
+   */
+  refresh(): Promise<boolean> {
+    if (!this.refreshing) {
+      this.refreshing = refreshTokens()
+        .catch(error => {
+          console.error('Ошибка при обновлении токенов:', error);
+          return false;
+        })
+        .finally(() => {
+          this.refreshing = null;
+        });
+    }
+    return this.refreshing;
+  }
+
   /**
    * Проверяет, авторизован ли пользователь
    */
@@ -53,6 +87,7 @@ class AuthManager {
    */
   logout(): void {
     localStorage.removeItem('token');
+    localStorage.removeItem('refresh_token');
     localStorage.removeItem('user');
     // Очищаем все кеши при выходе из системы
     memoryCache.clear();
@@ -134,7 +169,8 @@ class ApiClient {
     endpoint: string, 
     method: string = 'GET', 
     body?: object,
-    options: FetchOptions = {}
+    options: FetchOptions = {},
+    retried: boolean = false
   ): Promise<T> {
     const { useCache = false, cacheTtl, forceRefresh = false } = options;
     const cacheKey = this.generateCacheKey(endpoint, method, body);
@@ -169,6 +205,11 @@ class ApiClient {
     try {
       const response = await fetch(`${this.baseUrl}${endpoint}`, config);
       
+      // Токен доступа истек - обновляем его и повторяем запрос один раз
+      if (response.status === 401 && !retried && await this.authManager.refresh()) {
+        return this.fetch<T>(endpoint, method, body, options, true);
+      }
+
       // Обрабатываем ошибки авторизации
       if (response.status === 401 || response.status === 403) {
         console.error('Ошибка авторизации:', response.status);
@@ -328,6 +369,106 @@ export class TimeEntryApi {
   }
 }
 
+// Задержка перед переподключением к потоку событий (в миллисекундах)
+const EVENTS_RETRY_MS = 5000;
+
+/**
+ * Подписывается на поток изменений записей и категорий пользователя.
+ * EventSource не умеет передавать заголовок Authorization, поэтому поток читается через fetch.
+ * Перед вызовом обработчика сбрасывается кеш затронутых данных; при разрыве соединения
+ * подписка восстанавливается.
+ * @param onEvent Обработчик событий
+ * @returns Функция отмены подписки
+ */
+export function subscribeToEvents(onEvent: (event: ServerEvent) => void): () => void {
+  const controller = new AbortController();
+  let retryTimer: ReturnType<typeof setTimeout> | null = null;
+
+  const handleChunk = (chunk: string) => {
+    const data = chunk
+      .split('\n')
+      .filter(line => line.startsWith('data: '))
+      .map(line => line.slice('data: '.length))
+      .join('\n');
+    if (!data) {
+      return; // комментарий или служебное поле
+    }
+
+    const event = JSON.parse(data) as ServerEvent;
+    if (event.type === 'resync') {
+      memoryCache.clear();
+    } else if (event.type.startsWith('category_')) {
+      apiClient.invalidateCache('/api/categories');
+    } else {
+      apiClient.invalidateCache('/api/time/status');
+    }
+    onEvent(event);
+  };
+
+  const connect = async () => {
+    const token = authManager.getToken();
+    if (!token) {
+      return;
+    }
+
+    try {
+      const response = await fetch(`${API_BASE_URL}/api/events`, {
+        headers: { 'Authorization': `Bearer ${token}` },
+        signal: controller.signal
+      });
+
+      if (response.status === 401 && await authManager.refresh()) {
+        connect();
+        return;
+      }
+      if (response.status === 401 || response.status === 403) {
+        authManager.handleAuthError();
+        return;
+      }
+      if (!response.ok || !response.body) {
+        throw new Error(`Ошибка API: ${response.status}`);
+      }
+
+      const reader = response.body.getReader();
+      const decoder = new TextDecoder();
+      let buffer = '';
+      while (true) {
+        const { done, value } = await reader.read();
+        if (done) {
+          break;
+        }
+        buffer += decoder.decode(value, { stream: true });
+
+        // События отделяются друг от друга пустой строкой
+        let boundary = buffer.indexOf('\n\n');
+        while (boundary !== -1) {
+          handleChunk(buffer.slice(0, boundary));
+          buffer = buffer.slice(boundary + 2);
+          boundary = buffer.indexOf('\n\n');
+        }
+      }
+    } catch (error) {
+      if (controller.signal.aborted) {
+        return;
+      }
+      console.error('Ошибка потока событий:', error);
+    }
+
+    if (!controller.signal.aborted) {
+      retryTimer = setTimeout(connect, EVENTS_RETRY_MS);
+    }
+  };
+
+  connect();
+
+  return () => {
+    controller.abort();
+    if (retryTimer) {
+      clearTimeout(retryTimer);
+    }
+  };
+}
+
 /**
  * API для работы с категориями
  */
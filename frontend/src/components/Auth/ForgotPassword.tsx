@@ -0,0 +1,82 @@
+import React, { useState } from 'react';
+import { Link } from 'react-router-dom';
+import { forgotPassword } from '../../api/auth';
+import '../../App.css';
+
+const ForgotPassword: React.FC = () => {
+  const [email, setEmail] = useState('');
+  const [error, setError] = useState<string | null>(null);
+  const [success, setSuccess] = useState<string | null>(null);
+  const [isLoading, setIsLoading] = useState(false);
+
+  // Обработчик отправки формы
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+
+    if (!email) {
+      setError('Пожалуйста, укажите email');
+      return;
+    }
+
+    try {
+      setIsLoading(true);
+      setError(null);
+      setSuccess(null);
+
+      const response = await forgotPassword(email);
+      setSuccess(response.message);
+    } catch (err) {
+      console.error('Ошибка при запросе сброса пароля:', err);
+      if (err instanceof Error) {
+        setError(err.message);
+      } else {
+        setError('Не удалось отправить ссылку для сброса пароля');
+      }
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
+  return (
+    <div className="container auth-container">
+      <div className="card auth-form">
+        <h1 className="page-title">Восстановление пароля</h1>
+
+        {error && <div className="alert alert-danger">{error}</div>}
+        {success && <div className="alert alert-success">{success}</div>}
+
+        <form onSubmit={handleSubmit}>
+          <div className="form-group">
+            <label htmlFor="email">Email</label>
+            <input
+              type="email"
+              id="email"
+              className="form-control"
+              value={email}
+              onChange={(e) => setEmail(e.target.value)}
+              disabled={isLoading}
+              placeholder="Введите email, указанный при регистрации"
+              required
+            />
+          </div>
+
+          <button
+            type="submit"
+            className="btn btn-primary btn-block mt-4"
+            disabled={isLoading}
+          >
+            {isLoading ? "Отправка..." : "Отправить ссылку"}
+          </button>
+        </form>
+
+        <div className="auth-links mt-3">
+          <Link to="/login" className="auth-link">
+            Вернуться ко входу
+          </Link>
+        </div>
+      </div>
+    </div>
+  );
+};
+
+export default ForgotPassword;
@@ -12,7 +12,7 @@ interface LocationState {
 const Login: React.FC = () => {
   const navigate = useNavigate();
   const location = useLocation();
-  const { login, error: authError, isLoading: authLoading, isAuthenticated } = useAuth();
+  const { login, completeTwoFactor, error: authError, isLoading: authLoading, isAuthenticated } = useAuth();
   
   const [email, setEmail] = useState('');
   const [password, setPassword] = useState('');
@@ -20,6 +20,9 @@ const Login: React.FC = () => {
   const [showPassword, setShowPassword] = useState(false);
   const [error, setError] = useState<string | null>(null);
   const [isLoading, setIsLoading] = useState(false);
+  // Пароль принят, ждем код второго фактора
+  const [twoFactorStep, setTwoFactorStep] = useState(false);
+  const [code, setCode] = useState('');
 
   // Если пользователь уже аутентифицирован, перенаправляем на главную страницу
   useEffect(() => {
@@ -47,7 +50,10 @@ const Login: React.FC = () => {
       console.log('Попытка авторизации с email:', email, 'Запомнить меня:', rememberMe);
       
       // Вызов метода контекста авторизации с передачей флага "Запомнить меня"
-      await login(email, password, rememberMe);
+      const needsCode = await login(email, password, rememberMe);
+      if (needsCode) {
+        setTwoFactorStep(true);
+      }
       
       // Перенаправление происходит в useEffect
     } catch (err) {
@@ -62,11 +68,90 @@ const Login: React.FC = () => {
     }
   };
 
+  // Обработчик формы ввода кода второго фактора
+  const handleCodeSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+
+    if (!code.trim()) {
+      setError('Введите код подтверждения');
+      return;
+    }
+
+    try {
+      setIsLoading(true);
+      setError(null);
+      await completeTwoFactor(code.trim());
+      // Перенаправление происходит в useEffect
+    } catch (err) {
+      console.error('Ошибка при проверке кода в компоненте Login:', err);
+      if (err instanceof Error) {
+        setError(err.message);
+      } else {
+        setError('Неверный код подтверждения');
+      }
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
+  // Возврат к вводу email и пароля
+  const cancelTwoFactor = () => {
+    setTwoFactorStep(false);
+    setCode('');
+    setPassword('');
+    setError(null);
+  };
+
   // Переключение видимости пароля
   const togglePasswordVisibility = () => {
     setShowPassword(!showPassword);
   };
 
+  if (twoFactorStep) {
+    return (
+      <div className="container auth-container">
+        <div className="card auth-form">
+          <h1 className="page-title">Подтверждение входа</h1>
+
+          {(error || authError) && <div className="alert alert-danger">{error || authError}</div>}
+
+          <p>Введите код из приложения-аутентификатора или один из кодов восстановления.</p>
+
+          <form onSubmit={handleCodeSubmit}>
+            <div className="form-group">
+              <label htmlFor="code">Код подтверждения</label>
+              <input
+                type="text"
+                id="code"
+                className="form-control"
+                value={code}
+                onChange={(e) => setCode(e.target.value)}
+                disabled={isLoading || authLoading}
+                autoComplete="one-time-code"
+                autoFocus
+                required
+              />
+            </div>
+
+            <button
+              type="submit"
+              className="btn btn-primary btn-block mt-4"
+              disabled={isLoading || authLoading}
+            >
+              {isLoading || authLoading ? "Проверка..." : "Подтвердить"}
+            </button>
+          </form>
+
+          <div className="auth-links mt-3">
+            <button type="button" className="btn btn-link auth-link" onClick={cancelTwoFactor}>
+              Вернуться ко входу
+            </button>
+          </div>
+        </div>
+      </div>
+    );
+  }
+
   return (
     <div className="container auth-container">
       <div className="card auth-form">
@@ -141,6 +226,9 @@ const Login: React.FC = () => {
           <Link to="/forgot-password" className="auth-link">
             Забыли пароль?
           </Link>
+          <Link to="/verify-email" className="auth-link">
+            Не пришло письмо с подтверждением?
+          </Link>
         </div>
       </div>
     </div>
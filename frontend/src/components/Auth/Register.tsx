@@ -9,6 +9,7 @@ const Register: React.FC = () => {
   const [confirmPassword, setConfirmPassword] = useState('');
   const [showPassword, setShowPassword] = useState(false);
   const [formError, setFormError] = useState('');
+  const [verificationSent, setVerificationSent] = useState(false);
   const { register, error, isLoading } = useAuth();
   const navigate = useNavigate();
 
@@ -31,13 +32,33 @@ const Register: React.FC = () => {
     }
 
     try {
-      await register(email, password);
+      const verificationRequired = await register(email, password);
+      if (verificationRequired) {
+        setVerificationSent(true);
+        return;
+      }
       navigate('/');
     } catch (err) {
       // Ошибка уже обрабатывается в контексте аутентификации
     }
   };
 
+  if (verificationSent) {
+    return (
+      <div className="container flex-center">
+        <div className="card">
+          <h1 className="page-title">Подтвердите email</h1>
+          <div className="alert alert-success">
+            На {email} отправлена ссылка для подтверждения. Перейдите по ней и войдите.
+          </div>
+          <div className="mt-3 text-center">
+            <p><Link to="/verify-email">Письмо не пришло?</Link> <Link to="/login">Войти</Link></p>
+          </div>
+        </div>
+      </div>
+    );
+  }
+
   return (
     <div className="container flex-center">
       <div className="card">
@@ -0,0 +1,138 @@
+import React, { useState } from 'react';
+import { Link, useNavigate, useSearchParams } from 'react-router-dom';
+import { resetPassword } from '../../api/auth';
+import '../../App.css';
+
+const ResetPassword: React.FC = () => {
+  const navigate = useNavigate();
+  const [searchParams] = useSearchParams();
+  const token = searchParams.get('token') || '';
+
+  const [newPassword, setNewPassword] = useState('');
+  const [confirmPassword, setConfirmPassword] = useState('');
+  const [showPassword, setShowPassword] = useState(false);
+  const [error, setError] = useState<string | null>(null);
+  const [success, setSuccess] = useState<string | null>(null);
+  const [isLoading, setIsLoading] = useState(false);
+
+  // Обработчик отправки формы
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+
+    if (!newPassword || !confirmPassword) {
+      setError('Пожалуйста, заполните все поля');
+      return;
+    }
+
+    if (newPassword !== confirmPassword) {
+      setError('Новый пароль и подтверждение не совпадают');
+      return;
+    }
+
+    try {
+      setIsLoading(true);
+      setError(null);
+
+      const response = await resetPassword(token, newPassword);
+      setSuccess(response.message);
+      setNewPassword('');
+      setConfirmPassword('');
+
+      // Через 3 секунды переходим на страницу входа
+      setTimeout(() => {
+        navigate('/login');
+      }, 3000);
+    } catch (err) {
+      console.error('Ошибка при сбросе пароля:', err);
+      if (err instanceof Error) {
+        setError(err.message);
+      } else {
+        setError('Ошибка при сбросе пароля');
+      }
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
+  if (!token) {
+    return (
+      <div className="container auth-container">
+        <div className="card auth-form">
+          <h1 className="page-title">Сброс пароля</h1>
+          <div className="alert alert-danger">Ссылка для сброса пароля неполная</div>
+          <div className="auth-links mt-3">
+            <Link to="/forgot-password" className="auth-link">
+              Запросить новую ссылку
+            </Link>
+          </div>
+        </div>
+      </div>
+    );
+  }
+
+  return (
+    <div className="container auth-container">
+      <div className="card auth-form">
+        <h1 className="page-title">Сброс пароля</h1>
+
+        {error && <div className="alert alert-danger">{error}</div>}
+        {success && <div className="alert alert-success">{success}</div>}
+
+        <form onSubmit={handleSubmit}>
+          <div className="form-group">
+            <label htmlFor="newPassword">Новый пароль</label>
+            <div className="password-input-wrapper">
+              <input
+                type={showPassword ? "text" : "password"}
+                id="newPassword"
+                className="form-control"
+                value={newPassword}
+                onChange={(e) => setNewPassword(e.target.value)}
+                disabled={isLoading || !!success}
+                placeholder="Введите новый пароль"
+                required
+              />
+              <button
+                type="button"
+                className="password-toggle"
+                onClick={() => setShowPassword(!showPassword)}
+              >
+                {showPassword ? "Скрыть" : "Показать"}
+              </button>
+            </div>
+          </div>
+
+          <div className="form-group">
+            <label htmlFor="confirmPassword">Подтверждение пароля</label>
+            <input
+              type={showPassword ? "text" : "password"}
+              id="confirmPassword"
+              className="form-control"
+              value={confirmPassword}
+              onChange={(e) => setConfirmPassword(e.target.value)}
+              disabled={isLoading || !!success}
+              placeholder="Подтвердите новый пароль"
+              required
+            />
+          </div>
+
+          <button
+            type="submit"
+            className="btn btn-primary btn-block mt-4"
+            disabled={isLoading || !!success}
+          >
+            {isLoading ? "Сохранение..." : "Сохранить пароль"}
+          </button>
+        </form>
+
+        <div className="auth-links mt-3">
+          <Link to="/forgot-password" className="auth-link">
+            Запросить новую ссылку
+          </Link>
+        </div>
+      </div>
+    </div>
+  );
+};
+
+export default ResetPassword;
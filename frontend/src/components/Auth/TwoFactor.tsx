@@ -0,0 +1,196 @@
+import React, { useEffect, useState } from 'react';
+import {
+  getTwoFactorStatus,
+  setupTwoFactor,
+  confirmTwoFactor,
+  disableTwoFactor,
+  regenerateRecoveryCodes,
+  TwoFactorStatus
+} from '../../api/auth';
+import '../../App.css';
+
+const TwoFactor: React.FC = () => {
+  const [status, setStatus] = useState<TwoFactorStatus | null>(null);
+  const [setup, setSetup] = useState<{ secret: string; otpauth_url: string } | null>(null);
+  const [code, setCode] = useState('');
+  const [password, setPassword] = useState('');
+  // Коды восстановления показываются один раз, сразу после выдачи
+  const [recoveryCodes, setRecoveryCodes] = useState<string[] | null>(null);
+  const [error, setError] = useState<string | null>(null);
+  const [success, setSuccess] = useState<string | null>(null);
+  const [isLoading, setIsLoading] = useState(false);
+
+  // Загрузка состояния двухфакторной аутентификации
+  const loadStatus = async () => {
+    try {
+      setStatus(await getTwoFactorStatus());
+    } catch (err) {
+      console.error('Ошибка при загрузке состояния 2FA:', err);
+      setError(err instanceof Error ? err.message : 'Не удалось загрузить настройки');
+    }
+  };
+
+  useEffect(() => {
+    loadStatus();
+  }, []);
+
+  // Выполняет действие с общей обработкой загрузки и ошибок
+  const run = async (action: () => Promise<void>) => {
+    try {
+      setIsLoading(true);
+      setError(null);
+      setSuccess(null);
+      await action();
+    } catch (err) {
+      console.error('Ошибка при изменении настроек 2FA:', err);
+      setError(err instanceof Error ? err.message : 'Произошла ошибка');
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
+  const handleSetup = () => run(async () => {
+    setRecoveryCodes(null);
+    setSetup(await setupTwoFactor());
+  });
+
+  const handleConfirm = (e: React.FormEvent) => {
+    e.preventDefault();
+    run(async () => {
+      const codes = await confirmTwoFactor(code.trim());
+      setRecoveryCodes(codes);
+      setSetup(null);
+      setCode('');
+      setSuccess('Двухфакторная аутентификация включена');
+      await loadStatus();
+    });
+  };
+
+  const handleRegenerate = () => run(async () => {
+    if (!password) {
+      throw new Error('Введите текущий пароль');
+    }
+    setRecoveryCodes(await regenerateRecoveryCodes(password));
+    setPassword('');
+    setSuccess('Выданы новые коды восстановления, прежние больше не действуют');
+    await loadStatus();
+  });
+
+  const handleDisable = () => run(async () => {
+    if (!password) {
+      throw new Error('Введите текущий пароль');
+    }
+    await disableTwoFactor(password);
+    setPassword('');
+    setRecoveryCodes(null);
+    setSuccess('Двухфакторная аутентификация отключена');
+    await loadStatus();
+  });
+
+  return (
+    <div className="container auth-container">
+      <div className="card auth-form">
+        <h1 className="page-title">Двухфакторная аутентификация</h1>
+
+        {error && <div className="alert alert-danger">{error}</div>}
+        {success && <div className="alert alert-success">{success}</div>}
+
+        {recoveryCodes && (
+          <div className="alert alert-warning">
+            <p>Сохраните коды восстановления. Каждый код можно использовать для входа один раз, больше они показаны не будут.</p>
+            <ul className="recovery-codes">
+              {recoveryCodes.map((recoveryCode) => (
+                <li key={recoveryCode}><code>{recoveryCode}</code></li>
+              ))}
+            </ul>
+          </div>
+        )}
+
+        {!status && !error && <p>Загрузка...</p>}
+
+        {status && !status.enabled && !setup && (
+          <>
+            <p>При входе кроме пароля потребуется код из приложения-аутентификатора.</p>
+            <button
+              type="button"
+              className="btn btn-primary btn-block mt-4"
+              onClick={handleSetup}
+              disabled={isLoading}
+            >
+              Включить
+            </button>
+          </>
+        )}
+
+        {status && !status.enabled && setup && (
+          <form onSubmit={handleConfirm}>
+            <p>
+              Добавьте учетную запись в приложение-аутентификатор по{' '}
+              <a href={setup.otpauth_url}>ссылке</a> или введите ключ вручную:
+            </p>
+            <p><code>{setup.secret}</code></p>
+
+            <div className="form-group">
+              <label htmlFor="code">Код из приложения</label>
+              <input
+                type="text"
+                id="code"
+                className="form-control"
+                value={code}
+                onChange={(e) => setCode(e.target.value)}
+                disabled={isLoading}
+                autoComplete="one-time-code"
+                required
+              />
+            </div>
+
+            <button type="submit" className="btn btn-primary btn-block mt-4" disabled={isLoading}>
+              {isLoading ? "Проверка..." : "Подтвердить"}
+            </button>
+          </form>
+        )}
+
+        {status && status.enabled && (
+          <>
+            <p>
+              Двухфакторная аутентификация включена
+              {status.enabled_at && ` с ${new Date(status.enabled_at).toLocaleDateString()}`}.
+              Осталось кодов восстановления: {status.recovery_codes_left}.
+            </p>
+
+            <div className="form-group">
+              <label htmlFor="password">Текущий пароль</label>
+              <input
+                type="password"
+                id="password"
+                className="form-control"
+                value={password}
+                onChange={(e) => setPassword(e.target.value)}
+                disabled={isLoading}
+              />
+            </div>
+
+            <button
+              type="button"
+              className="btn btn-secondary btn-block mt-4"
+              onClick={handleRegenerate}
+              disabled={isLoading}
+            >
+              Новые коды восстановления
+            </button>
+            <button
+              type="button"
+              className="btn btn-danger btn-block mt-3"
+              onClick={handleDisable}
+              disabled={isLoading}
+            >
+              Отключить
+            </button>
+          </>
+        )}
+      </div>
+    </div>
+  );
+};
+
+export default TwoFactor;
@@ -0,0 +1,133 @@
+import React, { useEffect, useRef, useState } from 'react';
+import { Link, useSearchParams } from 'react-router-dom';
+import { verifyEmail, resendVerification } from '../../api/auth';
+import '../../App.css';
+
+const VerifyEmail: React.FC = () => {
+  const [searchParams] = useSearchParams();
+  const token = searchParams.get('token') || '';
+
+  const [isVerifying, setIsVerifying] = useState(!!token);
+  const [verified, setVerified] = useState<string | null>(null);
+  const [email, setEmail] = useState('');
+  const [error, setError] = useState<string | null>(null);
+  const [success, setSuccess] = useState<string | null>(null);
+  const [isLoading, setIsLoading] = useState(false);
+
+  // Токен одноразовый, поэтому не отправляем его повторно при повторном монтировании
+  const requested = useRef(false);
+
+  useEffect(() => {
+    if (!token || requested.current) {
+      return;
+    }
+    requested.current = true;
+
+    verifyEmail(token)
+      .then((response) => setVerified(response.message))
+      .catch((err) => {
+        console.error('Ошибка при подтверждении email:', err);
+        setError(err instanceof Error ? err.message : 'Ошибка при подтверждении email');
+      })
+      .finally(() => setIsVerifying(false));
+  }, [token]);
+
+  // Обработчик формы повторной отправки ссылки
+  const handleResend = async (e: React.FormEvent) => {
+    e.preventDefault();
+
+    if (!email) {
+      setError('Пожалуйста, укажите email');
+      return;
+    }
+
+    try {
+      setIsLoading(true);
+      setError(null);
+      setSuccess(null);
+
+      const response = await resendVerification(email);
+      setSuccess(response.message);
+    } catch (err) {
+      console.error('Ошибка при повторной отправке подтверждения:', err);
+      if (err instanceof Error) {
+        setError(err.message);
+      } else {
+        setError('Не удалось отправить ссылку для подтверждения email');
+      }
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
+  if (isVerifying) {
+    return (
+      <div className="container auth-container">
+        <div className="card auth-form">
+          <h1 className="page-title">Подтверждение email</h1>
+          <p>Проверяем ссылку...</p>
+        </div>
+      </div>
+    );
+  }
+
+  if (verified) {
+    return (
+      <div className="container auth-container">
+        <div className="card auth-form">
+          <h1 className="page-title">Подтверждение email</h1>
+          <div className="alert alert-success">{verified}</div>
+          <div className="auth-links mt-3">
+            <Link to="/login" className="auth-link">
+              Войти
+            </Link>
+          </div>
+        </div>
+      </div>
+    );
+  }
+
+  return (
+    <div className="container auth-container">
+      <div className="card auth-form">
+        <h1 className="page-title">Подтверждение email</h1>
+
+        {error && <div className="alert alert-danger">{error}</div>}
+        {success && <div className="alert alert-success">{success}</div>}
+
+        <p>Укажите email, указанный при регистрации, и мы отправим новую ссылку для подтверждения.</p>
+
+        <form onSubmit={handleResend}>
+          <div className="form-group">
+            <label htmlFor="email">Email</label>
+            <input
+              type="email"
+              id="email"
+              className="form-control"
+              value={email}
+              onChange={(e) => setEmail(e.target.value)}
+              disabled={isLoading}
+              required
+            />
+          </div>
+
+          <button
+            type="submit"
+            className="btn btn-primary btn-block mt-4"
+            disabled={isLoading}
+          >
+            {isLoading ? "Отправка..." : "Отправить ссылку"}
+          </button>
+        </form>
+
+        <div className="auth-links mt-3">
+          <Link to="/login" className="auth-link">
+            Вернуться ко входу
+          </Link>
+        </div>
+      </div>
+    </div>
+  );
+};
+
+export default VerifyEmail;
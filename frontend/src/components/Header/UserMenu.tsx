@@ -61,6 +61,9 @@ const UserMenu: React.FC = () => {
           <Link to="/change-password" className="dropdown-item">
             Сменить пароль
           </Link>
+          <Link to="/two-factor" className="dropdown-item">
+            Двухфакторная аутентификация
+          </Link>
           <div className="dropdown-divider"></div>
           <button onClick={handleLogout} className="dropdown-item logout-button">
             Выйти
@@ -1,5 +1,5 @@
 import React, { useState, useEffect } from 'react';
-import { startTimeEntry as startWork, startTimeEntryWithCategory, pauseTimeEntry as pauseWork, resumeTimeEntry as resumeWork, stopTimeEntry as stopWork, getActiveTimeEntry, TimeEntry, getCategories, Category } from '../../api/timetracker';
+import { startTimeEntry as startWork, startTimeEntryWithCategory, pauseTimeEntry as pauseWork, resumeTimeEntry as resumeWork, stopTimeEntry as stopWork, getActiveTimeEntry, TimeEntry, getCategories, Category, subscribeToEvents } from '../../api/timetracker';
 import '../../App.css';
 import './TimeTracker.css';
 
@@ -268,6 +268,23 @@ const TimeTracker: React.FC = () => {
     };
   }, []);
 
+  // Обновляем состояние, когда запись или категории изменены на другом устройстве
+  useEffect(() => {
+    const unsubscribe = subscribeToEvents(event => {
+      console.log('Получено событие:', event.type);
+      if (event.type === 'resync') {
+        loadActiveEntry();
+        loadCategories();
+      } else if (event.type.startsWith('category_')) {
+        loadCategories();
+      } else {
+        loadActiveEntry();
+      }
+    });
+    
+    return unsubscribe;
+  }, [loadActiveEntry]);
+
   return (
     <div className="container">
       <div className="card">
@@ -1,5 +1,11 @@
-import React, { createContext, useContext, useState, useEffect, ReactNode } from 'react';
-import { login as apiLogin, register as apiRegister, changePassword as apiChangePassword } from '../api/auth';
+import React, { createContext, useContext, useState, useEffect, useRef, ReactNode } from 'react';
+import {
+  login as apiLogin,
+  loginTwoFactor as apiLoginTwoFactor,
+  register as apiRegister,
+  changePassword as apiChangePassword,
+  logout as apiLogout
+} from '../api/auth';
 
 interface User {
   id: number;
@@ -10,8 +16,11 @@ interface AuthContextType {
   user: User | null;
   isAuthenticated: boolean;
   isLoading: boolean;
-  login: (email: string, password: string, rememberMe?: boolean) => Promise<void>;
-  register: (email: string, password: string, name?: string) => Promise<void>;
+  // Возвращает true, если для входа нужен код второго фактора (completeTwoFactor)
+  login: (email: string, password: string, rememberMe?: boolean) => Promise<boolean>;
+  completeTwoFactor: (code: string) => Promise<void>;
+  // Возвращает true, если перед входом нужно подтвердить email
+  register: (email: string, password: string, name?: string) => Promise<boolean>;
   logout: () => void;
   changePassword: (oldPassword: string, newPassword: string) => Promise<void>;
   error: string | null;
@@ -28,6 +37,8 @@ export const AuthProvider: React.FC<AuthProviderProps> = ({ children }) => {
   const [user, setUser] = useState<User | null>(null);
   const [isLoading, setIsLoading] = useState<boolean>(true);
   const [error, setError] = useState<string | null>(null);
+  // Вход, ожидающий кода второго фактора
+  const pendingLogin = useRef<{ challenge: string; email: string; rememberMe: boolean } | null>(null);
 
   useEffect(() => {
     // Проверяем, есть ли сохраненный токен и пользователь
@@ -55,40 +66,52 @@ export const AuthProvider: React.FC<AuthProviderProps> = ({ children }) => {
     setIsLoading(false);
   }, []);
 
+  // Сохраняет токен и данные пользователя после успешного входа
+  const startSession = (token: string, email: string, rememberMe: boolean) => {
+    // Сохраняем токен в нужное хранилище в зависимости от флага "Запомнить меня"
+    const storage = rememberMe ? localStorage : sessionStorage;
+    storage.setItem('token', token);
+    
+    // Получение ID пользователя из токена (декодирование JWT)
+    try {
+      const payload = JSON.parse(atob(token.split('.')[1]));
+      console.log('Декодированные данные из токена:', payload);
+      const user = { 
+        id: payload.user_id || 0, 
+        email: email 
+      };
+      
+      // Сохраняем данные пользователя
+      storage.setItem('user', JSON.stringify(user));
+      if (rememberMe) {
+        localStorage.setItem('rememberMe', 'true');
+      } else {
+        localStorage.removeItem('rememberMe');
+      }
+      
+      setUser(user);
+    } catch (e) {
+      console.error('Ошибка при декодировании токена:', e);
+      throw new Error('Недействительный токен авторизации');
+    }
+  };
+
   const login = async (email: string, password: string, rememberMe: boolean = false) => {
     try {
       setError(null);
       setIsLoading(true);
       console.log('Попытка авторизации для:', email, 'Запомнить меня:', rememberMe);
-      const response = await apiLogin(email, password);
-      console.log('Успешная авторизация, получен ответ:', response);
-      
-      // Сохраняем токен в нужное хранилище в зависимости от флага "Запомнить меня"
-      const storage = rememberMe ? localStorage : sessionStorage;
-      storage.setItem('token', response.token);
-      
-      // Получение ID пользователя из токена (декодирование JWT)
-      try {
-        const payload = JSON.parse(atob(response.token.split('.')[1]));
-        console.log('Декодированные данные из токена:', payload);
-        const user = { 
-          id: payload.user_id || 0, 
-          email: email 
-        };
-        
-        // Сохраняем данные пользователя
-        storage.setItem('user', JSON.stringify(user));
-        if (rememberMe) {
-          localStorage.setItem('rememberMe', 'true');
-        } else {
-          localStorage.removeItem('rememberMe');
-        }
-        
-        setUser(user);
-      } catch (e) {
-        console.error('Ошибка при декодировании токена:', e);
-        throw new Error('Недействительный токен авторизации');
+      const response = await apiLogin(email, password, rememberMe);
+
+      if (response.two_factor_required && response.challenge_token) {
+        console.log('Пароль верен, требуется код второго фактора');
+        pendingLogin.current = { challenge: response.challenge_token, email, rememberMe };
+        return true;
       }
+
+      console.log('Успешная авторизация, получен ответ:', response);
+      startSession(response.token, email, rememberMe);
+      return false;
     } catch (err) {
       console.error('Ошибка при авторизации:', err);
       if (err instanceof Error) {
@@ -107,6 +130,9 @@ export const AuthProvider: React.FC<AuthProviderProps> = ({ children }) => {
       setError(null);
       setIsLoading(true);
       const response = await apiRegister(email, password, name);
+      if (response.verification_required) {
+        return true;
+      }
       localStorage.setItem('token', response.token);
       
       // Получение ID пользователя из токена (декодирование JWT)
@@ -118,6 +144,7 @@ export const AuthProvider: React.FC<AuthProviderProps> = ({ children }) => {
       
       localStorage.setItem('user', JSON.stringify(user));
       setUser(user);
+      return false;
     } catch (err) {
       if (err instanceof Error) {
         setError(err.message);
@@ -130,6 +157,30 @@ export const AuthProvider: React.FC<AuthProviderProps> = ({ children }) => {
     }
   };
 
+  const completeTwoFactor = async (code: string) => {
+    const pending = pendingLogin.current;
+    try {
+      setError(null);
+      setIsLoading(true);
+      if (!pending) {
+        throw new Error('Время на ввод кода истекло, войдите заново');
+      }
+      const response = await apiLoginTwoFactor(pending.challenge, code);
+      pendingLogin.current = null;
+      startSession(response.token, pending.email, pending.rememberMe);
+    } catch (err) {
+      console.error('Ошибка при проверке кода:', err);
+      if (err instanceof Error) {
+        setError(err.message);
+      } else {
+        setError('Неверный код подтверждения');
+      }
+      throw err;
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
   const changePassword = async (oldPassword: string, newPassword: string) => {
     try {
       setError(null);
@@ -149,6 +200,8 @@ export const AuthProvider: React.FC<AuthProviderProps> = ({ children }) => {
   };
 
   const logout = () => {
+    // Завершаем сессию на сервере, чтобы токены нельзя было использовать повторно
+    apiLogout();
     localStorage.removeItem('token');
     localStorage.removeItem('user');
     localStorage.removeItem('rememberMe');
@@ -162,6 +215,7 @@ export const AuthProvider: React.FC<AuthProviderProps> = ({ children }) => {
     isAuthenticated: !!user,
     isLoading,
     login,
+    completeTwoFactor,
     register,
     logout,
     changePassword,